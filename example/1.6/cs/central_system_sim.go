@@ -546,7 +546,7 @@ func exampleRoutine(chargePointID string, handler *CentralSystemHandler) {
 			logDefault(chargePointID, confirmation.GetFeatureName()).Infof("%v trigger was rejected", core.HeartbeatFeatureName)
 		}
 	}
-	e = centralSystem.TriggerMessage(chargePointID, cb5, core.HeartbeatFeatureName)
+	e = centralSystem.TriggerMessage(chargePointID, cb5, remotetrigger.MessageTriggerHeartbeat)
 	if e != nil {
 		logDefault(chargePointID, remotetrigger.TriggerMessageFeatureName).Errorf("couldn't send message: %v", e)
 		return
@@ -564,7 +564,7 @@ func exampleRoutine(chargePointID string, handler *CentralSystemHandler) {
 			logDefault(chargePointID, confirmation.GetFeatureName()).Infof("%v trigger was rejected", firmware.GetDiagnosticsFeatureName)
 		}
 	}
-	e = centralSystem.TriggerMessage(chargePointID, cb6, firmware.DiagnosticsStatusNotificationFeatureName)
+	e = centralSystem.TriggerMessage(chargePointID, cb6, remotetrigger.MessageTriggerDiagnosticsStatusNotification)
 	if e != nil {
 		logDefault(chargePointID, remotetrigger.TriggerMessageFeatureName).Errorf("couldn't send message: %v", e)
 		return
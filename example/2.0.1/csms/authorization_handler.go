@@ -8,5 +8,13 @@ import (
 func (c *CSMSHandler) OnAuthorize(chargingStationID string, request *authorization.AuthorizeRequest) (response *authorization.AuthorizeResponse, err error) {
 	logDefault(chargingStationID, request.GetFeatureName()).Infof("client with token %v authorized", request.IdToken)
 	response = authorization.NewAuthorizationResponse(*types.NewIdTokenInfo(types.AuthorizationStatusAccepted))
+	if request.HasContractCertificate() {
+		// Plug & Charge authorization. A real CSMS would verify the contract certificate chain,
+		// e.g. by querying the OCSP responders contained in the certificate hash data.
+		for _, hashData := range request.CertificateHashData {
+			logDefault(chargingStationID, request.GetFeatureName()).Infof("contract certificate %v (responder %v) considered valid", hashData.SerialNumber, hashData.ResponderURL)
+		}
+		response.CertificateStatus = authorization.CertificateStatusAccepted
+	}
 	return
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/devicemodel"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/diagnostics"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/display"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/localauth"
@@ -136,18 +137,8 @@ func exampleRoutine(chargingStationID string, handler *CSMSHandler) {
 	// Wait for some time
 	time.Sleep(5 * time.Second)
 	setVariableData := []provisioning.SetVariableData{
-		{
-			AttributeType:  types.AttributeTarget,
-			AttributeValue: "10",
-			Component:      types.Component{Name: "OCPPCommCtrlr"},
-			Variable:       types.Variable{Name: "HeartbeatInterval"},
-		},
-		{
-			AttributeType:  types.AttributeTarget,
-			AttributeValue: "true",
-			Component:      types.Component{Name: "AuthCtrlr"},
-			Variable:       types.Variable{Name: "Enabled"},
-		},
+		devicemodel.Var("OCPPCommCtrlr", "HeartbeatInterval").SetAttribute(types.AttributeTarget, "10"),
+		devicemodel.Var("AuthCtrlr", "Enabled").SetAttribute(types.AttributeTarget, "true"),
 	}
 	// Change meter sampling values time
 	cb4 := func(response *provisioning.SetVariablesResponse, err error) {
@@ -243,6 +234,65 @@ func exampleRoutine(chargingStationID string, handler *CSMSHandler) {
 		logDefault(chargingStationID, display.SetDisplayMessageFeatureName).Errorf("couldn't send message: %v", e)
 		return
 	}
+	// Wait for some time
+	time.Sleep(5 * time.Second)
+	// Start a transaction remotely, limiting the charging current via a TxProfile
+	remoteStartID := 1
+	evseID := 1
+	remoteTxID := make(chan string, 1)
+	cb8 := func(response *remotecontrol.RequestStartTransactionResponse, err error) {
+		if err != nil {
+			logDefault(chargingStationID, remotecontrol.RequestStartTransactionFeatureName).Errorf("error on request: %v", err)
+			remoteTxID <- ""
+		} else if response.Status == remotecontrol.RequestStartStopStatusAccepted {
+			logDefault(chargingStationID, response.GetFeatureName()).Infof("transaction %v started remotely (remote start ID %v)", response.TransactionID, remoteStartID)
+			remoteTxID <- response.TransactionID
+		} else {
+			logDefault(chargingStationID, response.GetFeatureName()).Infof("remote start %v was rejected", remoteStartID)
+			remoteTxID <- ""
+		}
+	}
+	e = csms.RequestStartTransaction(chargingStationID, cb8, remoteStartID, clientIDTokenType, func(request *remotecontrol.RequestStartTransactionRequest) {
+		request.EvseID = &evseID
+		request.ChargingProfile = &types.ChargingProfile{
+			ID:                     remoteStartID,
+			StackLevel:             0,
+			ChargingProfilePurpose: types.ChargingProfilePurposeTxProfile,
+			ChargingProfileKind:    types.ChargingProfileKindRelative,
+			ChargingSchedule: []types.ChargingSchedule{
+				{
+					ID:                     1,
+					ChargingRateUnit:       types.ChargingRateUnitAmperes,
+					ChargingSchedulePeriod: []types.ChargingSchedulePeriod{types.NewChargingSchedulePeriod(0, 16.0)},
+				},
+			},
+		}
+	})
+	if e != nil {
+		logDefault(chargingStationID, remotecontrol.RequestStartTransactionFeatureName).Errorf("couldn't send message: %v", e)
+		return
+	}
+	transactionID := <-remoteTxID
+	if transactionID == "" {
+		return
+	}
+	// Wait for some time
+	time.Sleep(5 * time.Second)
+	// Stop the remotely started transaction
+	cb9 := func(response *remotecontrol.RequestStopTransactionResponse, err error) {
+		if err != nil {
+			logDefault(chargingStationID, remotecontrol.RequestStopTransactionFeatureName).Errorf("error on request: %v", err)
+		} else if response.Status == remotecontrol.RequestStartStopStatusAccepted {
+			logDefault(chargingStationID, response.GetFeatureName()).Infof("transaction %v stopped remotely", transactionID)
+		} else {
+			logDefault(chargingStationID, response.GetFeatureName()).Infof("couldn't stop transaction %v remotely", transactionID)
+		}
+	}
+	e = csms.RequestStopTransaction(chargingStationID, cb9, transactionID)
+	if e != nil {
+		logDefault(chargingStationID, remotecontrol.RequestStopTransactionFeatureName).Errorf("couldn't send message: %v", e)
+		return
+	}
 	// Finish simulation
 }
 
@@ -60,3 +60,65 @@ func (cq *CallbackQueue) Dequeue(id string) (func(confirmation ocpp.Response, er
 
 	return callback, ok
 }
+
+// DropTail invokes the drop function, which is expected to discard the most recent requests for the given id
+// and to return the amount of discarded requests. The callbacks of the discarded requests are removed
+// from the queue and returned in queue order.
+//
+// No callbacks can be queued or dequeued while the drop function is running.
+func (cq *CallbackQueue) DropTail(id string, drop func() (int, error)) ([]func(confirmation ocpp.Response, err error), error) {
+	cq.callbacksMutex.Lock()
+	defer cq.callbacksMutex.Unlock()
+
+	n, err := drop()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	callbacks := cq.callbacks[id]
+	if n > len(callbacks) {
+		panic("Internal CallbackQueue inconsistency")
+	}
+	dropped := append([]func(confirmation ocpp.Response, err error){}, callbacks[len(callbacks)-n:]...)
+	if n == len(callbacks) {
+		delete(cq.callbacks, id)
+	} else {
+		cq.callbacks[id] = callbacks[:len(callbacks)-n]
+	}
+	return dropped, nil
+}
+
+// DropAt invokes the drop function, which is expected to discard arbitrary requests for the given id
+// and to return the positions of the discarded requests, in ascending order.
+// The callbacks at the returned positions are removed from the queue and returned in queue order.
+//
+// No callbacks can be queued or dequeued while the drop function is running.
+func (cq *CallbackQueue) DropAt(id string, drop func() ([]int, error)) ([]func(confirmation ocpp.Response, err error), error) {
+	cq.callbacksMutex.Lock()
+	defer cq.callbacksMutex.Unlock()
+
+	positions, err := drop()
+	if err != nil || len(positions) == 0 {
+		return nil, err
+	}
+	callbacks := cq.callbacks[id]
+	dropped := make([]func(confirmation ocpp.Response, err error), 0, len(positions))
+	remaining := make([]func(confirmation ocpp.Response, err error), 0, len(callbacks))
+	next := 0
+	for i, callback := range callbacks {
+		if next < len(positions) && positions[next] == i {
+			dropped = append(dropped, callback)
+			next++
+			continue
+		}
+		remaining = append(remaining, callback)
+	}
+	if next < len(positions) {
+		panic("Internal CallbackQueue inconsistency")
+	}
+	if len(remaining) == 0 {
+		delete(cq.callbacks, id)
+	} else {
+		cq.callbacks[id] = remaining
+	}
+	return dropped, nil
+}
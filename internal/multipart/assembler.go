@@ -0,0 +1,163 @@
+// Package multipart provides the assembly of multipart messages, which a Charging Station sends in response
+// to a single request, e.g. NotifyReport or NotifyMonitoringReport.
+//
+// Parts are correlated via the requestId of the original request, and ordered via their sequence number.
+// The assembler is agnostic of the message types, hence typed wrappers convert parts and results.
+package multipart
+
+import (
+	"sort"
+	"sync"
+)
+
+type pendingMessage struct {
+	parts    map[int]interface{}
+	lastPart int
+	deliver  func(result interface{}, ok bool)
+}
+
+// Assembler collects the parts of multipart messages. A message is complete once the part with tbc=false
+// and all previous parts were received, at which point the parts are joined in sequence number order.
+//
+// The most recently completed results are retained, until they are collected via Await,
+// so the result of a message that completed before Await was invoked is still delivered.
+//
+// An Assembler is safe for concurrent use.
+type Assembler struct {
+	mutex          sync.Mutex
+	join           func(parts []interface{}) interface{}
+	maxCompleted   int
+	pending        map[int]*pendingMessage
+	completed      map[int]interface{}
+	completedOrder []int
+}
+
+// NewAssembler creates a new Assembler, which joins the ordered parts of a message via the join function
+// and retains at most maxCompleted results.
+func NewAssembler(join func(parts []interface{}) interface{}, maxCompleted int) *Assembler {
+	return &Assembler{
+		join:         join,
+		maxCompleted: maxCompleted,
+		pending:      map[int]*pendingMessage{},
+		completed:    map[int]interface{}{},
+	}
+}
+
+func (a *Assembler) getOrCreate(requestID int) *pendingMessage {
+	message, ok := a.pending[requestID]
+	if !ok {
+		message = &pendingMessage{parts: map[int]interface{}{}, lastPart: -1}
+		a.pending[requestID] = message
+	}
+	return message
+}
+
+// Await registers the deliver function, which is invoked with the result and ok=true once the message is complete.
+// If the message was completed recently, deliver is invoked right away and the result is no longer retained.
+//
+// If the message is discarded, or another deliver function is registered for the same message,
+// deliver is invoked with a nil result and ok=false instead, so that a waiting caller is always released.
+// The deliver function is invoked while holding the lock of the assembler, hence it must not block.
+func (a *Assembler) Await(requestID int, deliver func(result interface{}, ok bool)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if result, ok := a.completed[requestID]; ok {
+		a.forgetCompleted(requestID)
+		deliver(result, true)
+		return
+	}
+	message := a.getOrCreate(requestID)
+	if message.deliver != nil {
+		message.deliver(nil, false)
+	}
+	message.deliver = deliver
+}
+
+// Pending returns true, if the message is awaited or was received partially, but isn't complete yet.
+func (a *Assembler) Pending(requestID int) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	_, ok := a.pending[requestID]
+	return ok
+}
+
+// Completed returns the result of a recently completed message, which wasn't collected via Await yet.
+func (a *Assembler) Completed(requestID int) (interface{}, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result, ok := a.completed[requestID]
+	return result, ok
+}
+
+// Add stores a part of a message. If the part completes the message, the joined result is returned,
+// and the message is no longer pending.
+func (a *Assembler) Add(requestID int, seqNo int, tbc bool, part interface{}) (result interface{}, complete bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	message := a.getOrCreate(requestID)
+	message.parts[seqNo] = part
+	if !tbc {
+		message.lastPart = seqNo
+	}
+	if message.lastPart < 0 {
+		return nil, false
+	}
+	for i := 0; i <= message.lastPart; i++ {
+		if _, ok := message.parts[i]; !ok {
+			return nil, false
+		}
+	}
+	seqNos := make([]int, 0, len(message.parts))
+	for i := range message.parts {
+		seqNos = append(seqNos, i)
+	}
+	sort.Ints(seqNos)
+	parts := make([]interface{}, 0, len(seqNos))
+	for _, i := range seqNos {
+		parts = append(parts, message.parts[i])
+	}
+	result = a.join(parts)
+	delete(a.pending, requestID)
+	if message.deliver != nil {
+		message.deliver(result, true)
+	} else {
+		a.retainCompleted(requestID, result)
+	}
+	return result, true
+}
+
+// Retains the result of a completed message, dropping the oldest one if too many are retained.
+func (a *Assembler) retainCompleted(requestID int, result interface{}) {
+	a.forgetCompleted(requestID)
+	a.completed[requestID] = result
+	a.completedOrder = append(a.completedOrder, requestID)
+	if len(a.completedOrder) > a.maxCompleted {
+		delete(a.completed, a.completedOrder[0])
+		a.completedOrder = a.completedOrder[1:]
+	}
+}
+
+func (a *Assembler) forgetCompleted(requestID int) {
+	if _, ok := a.completed[requestID]; !ok {
+		return
+	}
+	delete(a.completed, requestID)
+	for i, id := range a.completedOrder {
+		if id == requestID {
+			a.completedOrder = append(a.completedOrder[:i], a.completedOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// Discard removes all received parts of a message, e.g. after a timeout, as well as the result of a completed message.
+// A deliver function registered via Await is invoked with ok=false.
+func (a *Assembler) Discard(requestID int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if message, ok := a.pending[requestID]; ok && message.deliver != nil {
+		message.deliver(nil, false)
+	}
+	delete(a.pending, requestID)
+	a.forgetCompleted(requestID)
+}
@@ -1,8 +1,11 @@
 package ocpp16
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"reflect"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/internal/callbackqueue"
 	"github.com/lorenzodonini/ocpp-go/ocpp"
@@ -26,6 +29,9 @@ type centralSystem struct {
 	remoteTriggerHandler remotetrigger.CentralSystemHandler
 	smartChargingHandler smartcharging.CentralSystemHandler
 	callbackQueue        callbackqueue.CallbackQueue
+	resets               *resetCorrelator
+	profileRegistry      *smartcharging.ProfileRegistry
+	dataTransferRegistry *core.DataTransferRegistry
 	errC                 chan error
 }
 
@@ -37,6 +43,7 @@ func newCentralSystem(server *ocppj.Server) centralSystem {
 	return centralSystem{
 		server:        server,
 		callbackQueue: callbackqueue.New(),
+		resets:        newResetCorrelator(),
 	}
 }
 
@@ -103,9 +110,23 @@ func (cs *centralSystem) DataTransfer(clientId string, callback func(confirmatio
 	for _, fn := range props {
 		fn(request)
 	}
+	var codec core.DataTransferCodec
+	if registry := cs.dataTransferRegistry; registry != nil {
+		var err error
+		if codec, err = registry.EncodeRequest(request); err != nil {
+			return err
+		}
+	}
 	genericCallback := func(confirmation ocpp.Response, protoError error) {
 		if confirmation != nil {
-			callback(confirmation.(*core.DataTransferConfirmation), protoError)
+			dataTransferConfirmation := confirmation.(*core.DataTransferConfirmation)
+			if codec != nil {
+				if err := codec.DecodeConfirmation(dataTransferConfirmation); err != nil {
+					callback(nil, err)
+					return
+				}
+			}
+			callback(dataTransferConfirmation, protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -173,6 +194,23 @@ func (cs *centralSystem) Reset(clientId string, callback func(*core.ResetConfirm
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *centralSystem) ResetAndAwaitBoot(clientId string, callback func(result ResetResult, err error), resetType core.ResetType, timeout time.Duration, props ...func(request *core.ResetRequest)) error {
+	result := ResetResult{ChargePointID: clientId, Type: resetType}
+	return cs.Reset(clientId, func(confirmation *core.ResetConfirmation, err error) {
+		if err != nil {
+			callback(result, err)
+			return
+		}
+		result.Status = confirmation.Status
+		if confirmation.Status != core.ResetStatusAccepted {
+			// The charge point won't reset
+			callback(result, nil)
+			return
+		}
+		cs.resets.await(result, timeout, callback)
+	}, resetType, props...)
+}
+
 func (cs *centralSystem) UnlockConnector(clientId string, callback func(*core.UnlockConnectorConfirmation, error), connectorId int, props ...func(*core.UnlockConnectorRequest)) error {
 	request := core.NewUnlockConnectorRequest(connectorId)
 	for _, fn := range props {
@@ -279,10 +317,19 @@ func (cs *centralSystem) CancelReservation(clientId string, callback func(*reser
 }
 
 func (cs *centralSystem) TriggerMessage(clientId string, callback func(*remotetrigger.TriggerMessageConfirmation, error), requestedMessage remotetrigger.MessageTrigger, props ...func(request *remotetrigger.TriggerMessageRequest)) error {
+	if !requestedMessage.IsValid() {
+		return fmt.Errorf("cannot trigger %v message, allowed messages are %v, %v, %v, %v, %v and %v", requestedMessage,
+			remotetrigger.MessageTriggerBootNotification, remotetrigger.MessageTriggerDiagnosticsStatusNotification, remotetrigger.MessageTriggerFirmwareStatusNotification,
+			remotetrigger.MessageTriggerHeartbeat, remotetrigger.MessageTriggerMeterValues, remotetrigger.MessageTriggerStatusNotification)
+	}
 	request := remotetrigger.NewTriggerMessageRequest(requestedMessage)
 	for _, fn := range props {
 		fn(request)
 	}
+	if request.ConnectorId != nil && !requestedMessage.SupportsConnectorId() {
+		return fmt.Errorf("cannot trigger %v message for connector %v, connectorId is only applicable to %v and %v messages", requestedMessage, *request.ConnectorId,
+			remotetrigger.MessageTriggerMeterValues, remotetrigger.MessageTriggerStatusNotification)
+	}
 	genericCallback := func(confirmation ocpp.Response, protoError error) {
 		if confirmation != nil {
 			callback(confirmation.(*remotetrigger.TriggerMessageConfirmation), protoError)
@@ -298,9 +345,14 @@ func (cs *centralSystem) SetChargingProfile(clientId string, callback func(*smar
 	for _, fn := range props {
 		fn(request)
 	}
+	registry := cs.profileRegistry
 	genericCallback := func(confirmation ocpp.Response, protoError error) {
 		if confirmation != nil {
-			callback(confirmation.(*smartcharging.SetChargingProfileConfirmation), protoError)
+			response := confirmation.(*smartcharging.SetChargingProfileConfirmation)
+			if registry != nil && response.Status == smartcharging.ChargingProfileStatusAccepted {
+				registry.Add(clientId, request.ConnectorId, request.ChargingProfile)
+			}
+			callback(response, protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -313,9 +365,14 @@ func (cs *centralSystem) ClearChargingProfile(clientId string, callback func(*sm
 	for _, fn := range props {
 		fn(request)
 	}
+	registry := cs.profileRegistry
 	genericCallback := func(confirmation ocpp.Response, protoError error) {
 		if confirmation != nil {
-			callback(confirmation.(*smartcharging.ClearChargingProfileConfirmation), protoError)
+			response := confirmation.(*smartcharging.ClearChargingProfileConfirmation)
+			if registry != nil && response.Status == smartcharging.ClearChargingProfileStatusAccepted {
+				registry.Clear(clientId, request)
+			}
+			callback(response, protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -338,6 +395,14 @@ func (cs *centralSystem) GetCompositeSchedule(clientId string, callback func(*sm
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *centralSystem) SetChargingProfileRegistry(registry *smartcharging.ProfileRegistry) {
+	cs.profileRegistry = registry
+}
+
+func (cs *centralSystem) SetDataTransferRegistry(registry *core.DataTransferRegistry) {
+	cs.dataTransferRegistry = registry
+}
+
 func (cs *centralSystem) SetCoreHandler(handler core.CentralSystemHandler) {
 	cs.coreHandler = handler
 }
@@ -409,6 +474,18 @@ func (cs *centralSystem) Start(listenPort int, listenPath string) {
 	cs.server.Start(listenPort, listenPath)
 }
 
+func (cs *centralSystem) StartWithContext(ctx context.Context, listenPort int, listenPath string) error {
+	return cs.server.StartWithContext(ctx, listenPort, listenPath)
+}
+
+func (cs *centralSystem) StartOnListener(listener net.Listener, listenPath string) error {
+	return cs.server.StartOnListener(listener, listenPath)
+}
+
+func (cs *centralSystem) AddListenPath(listenPath string) {
+	cs.server.AddListenPath(listenPath)
+}
+
 func (cs *centralSystem) Stop() {
 	cs.server.Stop()
 }
@@ -512,11 +589,12 @@ func (cs *centralSystem) handleIncomingRequest(chargePoint ChargePointConnection
 	go func() {
 		switch action {
 		case core.BootNotificationFeatureName:
+			cs.resets.onBootNotification(chargePoint.ID(), request.(*core.BootNotificationRequest))
 			confirmation, err = cs.coreHandler.OnBootNotification(chargePoint.ID(), request.(*core.BootNotificationRequest))
 		case core.AuthorizeFeatureName:
 			confirmation, err = cs.coreHandler.OnAuthorize(chargePoint.ID(), request.(*core.AuthorizeRequest))
 		case core.DataTransferFeatureName:
-			confirmation, err = cs.coreHandler.OnDataTransfer(chargePoint.ID(), request.(*core.DataTransferRequest))
+			confirmation, err = cs.handleDataTransfer(chargePoint.ID(), request.(*core.DataTransferRequest), requestId)
 		case core.HeartbeatFeatureName:
 			confirmation, err = cs.coreHandler.OnHeartbeat(chargePoint.ID(), request.(*core.HeartbeatRequest))
 		case core.MeterValuesFeatureName:
@@ -539,6 +617,26 @@ func (cs *centralSystem) handleIncomingRequest(chargePoint ChargePointConnection
 	}()
 }
 
+// Passes an incoming DataTransfer request to the core handler, decoding it with the codec registered for its vendor (if any).
+// If the request was encoded, the confirmation is encoded as well.
+func (cs *centralSystem) handleDataTransfer(chargePointId string, request *core.DataTransferRequest, requestId string) (*core.DataTransferConfirmation, error) {
+	var codec core.DataTransferCodec
+	if registry := cs.dataTransferRegistry; registry != nil {
+		var err error
+		if codec, err = registry.DecodeRequest(request); err != nil {
+			return nil, ocpp.NewError(ocppj.FormatViolationV16, err.Error(), requestId)
+		}
+	}
+	confirmation, err := cs.coreHandler.OnDataTransfer(chargePointId, request)
+	if err != nil || codec == nil || confirmation == nil {
+		return confirmation, err
+	}
+	if err = codec.EncodeConfirmation(confirmation); err != nil {
+		return nil, err
+	}
+	return confirmation, nil
+}
+
 func (cs *centralSystem) handleIncomingConfirmation(chargePoint ChargePointConnection, confirmation ocpp.Response, requestId string) {
 	if callback, ok := cs.callbackQueue.Dequeue(chargePoint.ID()); ok {
 		// Execute in separate goroutine, so the caller goroutine is available
@@ -550,9 +648,14 @@ func (cs *centralSystem) handleIncomingConfirmation(chargePoint ChargePointConne
 }
 
 func (cs *centralSystem) handleIncomingError(chargePoint ChargePointConnection, err *ocpp.Error, details interface{}) {
+	var callbackErr error = err
+	if invalidResponse, ok := details.(*ocppj.InvalidResponseError); ok {
+		// Expose the raw payload of the invalid response
+		callbackErr = invalidResponse
+	}
 	if callback, ok := cs.callbackQueue.Dequeue(chargePoint.ID()); ok {
 		// Execute in separate goroutine, so the caller goroutine is available
-		go callback(nil, err)
+		go callback(nil, callbackErr)
 	} else {
 		err := fmt.Errorf("no handler available for call error %w from client %s", err, chargePoint.ID())
 		cs.error(err)
@@ -24,6 +24,7 @@ type chargePoint struct {
 	reservationHandler   reservation.ChargePointHandler
 	remoteTriggerHandler remotetrigger.ChargePointHandler
 	smartChargingHandler smartcharging.ChargePointHandler
+	dataTransferRegistry *core.DataTransferRegistry
 	confirmationHandler  chan ocpp.Response
 	errorHandler         chan error
 	callbacks            callbackqueue.CallbackQueue
@@ -82,12 +83,24 @@ func (cp *chargePoint) DataTransfer(vendorId string, props ...func(request *core
 	for _, fn := range props {
 		fn(request)
 	}
+	var codec core.DataTransferCodec
+	if registry := cp.dataTransferRegistry; registry != nil {
+		var err error
+		if codec, err = registry.EncodeRequest(request); err != nil {
+			return nil, err
+		}
+	}
 	confirmation, err := cp.SendRequest(request)
 	if err != nil {
 		return nil, err
-	} else {
-		return confirmation.(*core.DataTransferConfirmation), err
 	}
+	dataTransferConfirmation := confirmation.(*core.DataTransferConfirmation)
+	if codec != nil {
+		if err = codec.DecodeConfirmation(dataTransferConfirmation); err != nil {
+			return nil, err
+		}
+	}
+	return dataTransferConfirmation, nil
 }
 
 func (cp *chargePoint) Heartbeat(props ...func(request *core.HeartbeatRequest)) (*core.HeartbeatConfirmation, error) {
@@ -185,6 +198,10 @@ func (cp *chargePoint) SetCoreHandler(handler core.ChargePointHandler) {
 	cp.coreHandler = handler
 }
 
+func (cp *chargePoint) SetDataTransferRegistry(registry *core.DataTransferRegistry) {
+	cp.dataTransferRegistry = registry
+}
+
 func (cp *chargePoint) SetLocalAuthListHandler(handler localauth.ChargePointHandler) {
 	cp.localAuthListHandler = handler
 }
@@ -373,6 +390,26 @@ func (cp *chargePoint) notSupportedError(requestId string, action string) {
 	}
 }
 
+// Passes an incoming DataTransfer request to the core handler, decoding it with the codec registered for its vendor (if any).
+// If the request was encoded, the confirmation is encoded as well.
+func (cp *chargePoint) handleDataTransfer(request *core.DataTransferRequest, requestId string) (*core.DataTransferConfirmation, error) {
+	var codec core.DataTransferCodec
+	if registry := cp.dataTransferRegistry; registry != nil {
+		var err error
+		if codec, err = registry.DecodeRequest(request); err != nil {
+			return nil, ocpp.NewError(ocppj.FormatViolationV16, err.Error(), requestId)
+		}
+	}
+	confirmation, err := cp.coreHandler.OnDataTransfer(request)
+	if err != nil || codec == nil || confirmation == nil {
+		return confirmation, err
+	}
+	if err = codec.EncodeConfirmation(confirmation); err != nil {
+		return nil, err
+	}
+	return confirmation, nil
+}
+
 func (cp *chargePoint) handleIncomingRequest(request ocpp.Request, requestId string, action string) {
 	profile, found := cp.client.GetProfileForFeature(action)
 	// Check whether action is supported and a handler for it exists
@@ -425,7 +462,7 @@ func (cp *chargePoint) handleIncomingRequest(request ocpp.Request, requestId str
 	case core.ClearCacheFeatureName:
 		confirmation, err = cp.coreHandler.OnClearCache(request.(*core.ClearCacheRequest))
 	case core.DataTransferFeatureName:
-		confirmation, err = cp.coreHandler.OnDataTransfer(request.(*core.DataTransferRequest))
+		confirmation, err = cp.handleDataTransfer(request.(*core.DataTransferRequest), requestId)
 	case core.GetConfigurationFeatureName:
 		confirmation, err = cp.coreHandler.OnGetConfiguration(request.(*core.GetConfigurationRequest))
 	case core.RemoteStartTransactionFeatureName:
@@ -32,11 +32,14 @@ func isValidAvailabilityType(fl validator.FieldLevel) bool {
 type AvailabilityStatus string
 
 const (
-	AvailabilityStatusAccepted  AvailabilityStatus = "Accepted"
-	AvailabilityStatusRejected  AvailabilityStatus = "Rejected"
-	AvailabilityStatusScheduled AvailabilityStatus = "Scheduled"
+	AvailabilityStatusAccepted  AvailabilityStatus = "Accepted"  // Request has been accepted and will be executed.
+	AvailabilityStatusRejected  AvailabilityStatus = "Rejected"  // Request has not been accepted and will not be executed.
+	AvailabilityStatusScheduled AvailabilityStatus = "Scheduled" // Request has been accepted and will be executed when transaction(s) in progress have finished.
 )
 
+// ChargePointConnectorId is the connector ID referring to the Charge Point as a whole, rather than to a single connector.
+const ChargePointConnectorId = 0
+
 func isValidAvailabilityStatus(fl validator.FieldLevel) bool {
 	status := AvailabilityStatus(fl.Field().String())
 	switch status {
@@ -49,8 +52,13 @@ func isValidAvailabilityStatus(fl validator.FieldLevel) bool {
 
 // The field definition of the ChangeAvailability request payload sent by the Central System to the Charge Point.
 type ChangeAvailabilityRequest struct {
-	ConnectorId int              `json:"connectorId" validate:"gte=0"`
-	Type        AvailabilityType `json:"type" validate:"required,availabilityType"`
+	ConnectorId int              `json:"connectorId" validate:"gte=0"`              // The id of the connector for which availability needs to change. Id '0' (zero) is used if the availability of the Charge Point and all its connectors needs to change.
+	Type        AvailabilityType `json:"type" validate:"required,availabilityType"` // This contains the type of availability change that the Charge Point should perform.
+}
+
+// IsChargePointWide returns true, if the request targets the whole Charge Point (i.e. connector 0) instead of a single connector.
+func (r ChangeAvailabilityRequest) IsChargePointWide() bool {
+	return r.ConnectorId == ChargePointConnectorId
 }
 
 // This field definition of the ChangeAvailability confirmation payload, sent by the Charge Point to the Central System in response to a ChangeAvailabilityRequest.
@@ -64,6 +72,10 @@ type ChangeAvailabilityConfirmation struct {
 // A Charge Point is considered unavailable when it does not allow any charging.
 // The Central System SHALL send a ChangeAvailabilityRequest for requesting a Charge Point to change its availability.
 // The Central System can change the availability to available or unavailable.
+//
+// A request for connector 0 targets the Charge Point as a whole, including all of its connectors.
+// If a transaction is in progress on an affected connector, the Charge Point SHALL respond with the Scheduled status
+// and change the availability once the transaction(s) have finished.
 type ChangeAvailabilityFeature struct{}
 
 func (f ChangeAvailabilityFeature) GetFeatureName() string {
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
 	"github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
 	"gopkg.in/go-playground/validator.v9"
-	"reflect"
 )
 
 // -------------------- Data Transfer (CP -> CS / CS -> CP) --------------------
@@ -44,6 +47,43 @@ type DataTransferConfirmation struct {
 	Data   interface{}        `json:"data,omitempty"`
 }
 
+// DataTransferError is returned by DataTransferConfirmation.Err, when the other endpoint didn't accept a data transfer.
+// It carries the returned status, as well as the optional data payload, which may contain vendor-specific error details.
+type DataTransferError struct {
+	Status DataTransferStatus
+	Data   interface{}
+}
+
+func (e *DataTransferError) Error() string {
+	if e.Data != nil {
+		return fmt.Sprintf("data transfer not accepted: %v (data: %v)", e.Status, e.Data)
+	}
+	return fmt.Sprintf("data transfer not accepted: %v", e.Status)
+}
+
+// Err returns nil if the data transfer was accepted, or a DataTransferError containing the returned status and data otherwise.
+func (c *DataTransferConfirmation) Err() error {
+	if c.Status == DataTransferStatusAccepted {
+		return nil
+	}
+	return &DataTransferError{Status: c.Status, Data: c.Data}
+}
+
+// UnmarshalData decodes the data payload of the confirmation into v, which should be a pointer to a custom struct.
+// Since the payload is vendor-specific, it is parsed into generic maps and slices by default.
+//
+// Returns an error if the confirmation carries no data, or the data couldn't be decoded.
+func (c *DataTransferConfirmation) UnmarshalData(v interface{}) error {
+	if c.Data == nil {
+		return fmt.Errorf("data transfer confirmation contains no data")
+	}
+	raw, err := json.Marshal(c.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
 // If a Charge Point needs to send information to the Central System for a function not supported by OCPP, it SHALL use a DataTransfer message.
 // The same functionality may also be offered the other way around, allowing a Central System to send arbitrary custom commands to a Charge Point.
 type DataTransferFeature struct{}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompressedMessageIdSuffix is appended to the messageId of a DataTransferRequest, whose data was compressed
+// by a CompressedDataTransferCodec. It signals to the receiving endpoint, that the data needs to be decoded.
+const CompressedMessageIdSuffix = "+gzip"
+
+// The maximum length of the messageId of a DataTransferRequest, including CompressedMessageIdSuffix.
+const maxMessageIdLength = 50
+
+// The default upper bound for the size of decompressed payloads, see CompressedDataTransferCodec.MaxDecodedSize.
+const DefaultMaxDecodedSize = 10 * 1024 * 1024
+
+// CompressedDataTransferCodec transparently compresses the data of DataTransfer messages.
+//
+// The data is serialized to JSON, compressed via gzip and finally encoded as a base64 string,
+// which is then sent as the data field of the message.
+// Compressed requests are marked by appending CompressedMessageIdSuffix to their messageId.
+// Since confirmations carry no messageId, the data of a confirmation is expected to be compressed
+// if and only if the data of the corresponding request was compressed.
+//
+// Both endpoints need to register the codec for the vendor in a DataTransferRegistry, which applies it transparently:
+//
+//	registry := core.NewDataTransferRegistry()
+//	registry.Register("vendor", core.CompressedDataTransferCodec{MinSize: 1024})
+//	chargePoint.SetDataTransferRegistry(registry)
+//
+// Alternatively, the codec may be invoked explicitly, e.g. via EncodeRequest before sending a request
+// and DecodeRequest within the DataTransfer handler.
+type CompressedDataTransferCodec struct {
+	// Payloads whose JSON representation is smaller than MinSize bytes are sent uncompressed. If 0, all payloads are compressed.
+	MinSize int
+	// Incoming payloads decompressing to more than MaxDecodedSize bytes are rejected. If 0, DefaultMaxDecodedSize is used.
+	MaxDecodedSize int
+}
+
+// Returns true if the request was marked as compressed by the sender.
+func (c CompressedDataTransferCodec) IsCompressed(request *DataTransferRequest) bool {
+	return strings.HasSuffix(request.MessageId, CompressedMessageIdSuffix)
+}
+
+// Compresses the data of an outgoing request in place, marking the request as compressed.
+// Requests without data, or with data smaller than MinSize, are left untouched.
+//
+// An error is returned if the marked messageId would exceed the maximum length of 50 characters.
+func (c CompressedDataTransferCodec) EncodeRequest(request *DataTransferRequest) error {
+	if request.Data == nil || c.IsCompressed(request) {
+		return nil
+	}
+	if len(request.MessageId)+len(CompressedMessageIdSuffix) > maxMessageIdLength {
+		return fmt.Errorf("messageId %v too long to be marked as compressed, max length is %d", request.MessageId, maxMessageIdLength-len(CompressedMessageIdSuffix))
+	}
+	encoded, ok, err := c.encode(request.Data)
+	if err != nil || !ok {
+		return err
+	}
+	request.Data = encoded
+	request.MessageId = request.MessageId + CompressedMessageIdSuffix
+	return nil
+}
+
+// Decompresses the data of an incoming request in place, if the request was marked as compressed.
+// The marker is removed from the messageId, so that the original messageId is restored.
+//
+// Returns true if the request was compressed.
+func (c CompressedDataTransferCodec) DecodeRequest(request *DataTransferRequest) (bool, error) {
+	if !c.IsCompressed(request) {
+		return false, nil
+	}
+	data, err := c.decode(request.Data)
+	if err != nil {
+		return true, err
+	}
+	request.Data = data
+	request.MessageId = strings.TrimSuffix(request.MessageId, CompressedMessageIdSuffix)
+	return true, nil
+}
+
+// Compresses the data of an outgoing confirmation in place.
+// Should only be invoked if the corresponding request was compressed.
+func (c CompressedDataTransferCodec) EncodeConfirmation(confirmation *DataTransferConfirmation) error {
+	if confirmation.Data == nil {
+		return nil
+	}
+	encoded, _, err := CompressedDataTransferCodec{}.encode(confirmation.Data)
+	if err != nil {
+		return err
+	}
+	confirmation.Data = encoded
+	return nil
+}
+
+// Decompresses the data of an incoming confirmation in place.
+// Should only be invoked if the corresponding request was compressed.
+func (c CompressedDataTransferCodec) DecodeConfirmation(confirmation *DataTransferConfirmation) error {
+	if confirmation.Data == nil {
+		return nil
+	}
+	data, err := c.decode(confirmation.Data)
+	if err != nil {
+		return err
+	}
+	confirmation.Data = data
+	return nil
+}
+
+func (c CompressedDataTransferCodec) encode(data interface{}) (string, bool, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", false, fmt.Errorf("couldn't serialize data transfer payload: %w", err)
+	}
+	if len(raw) < c.MinSize {
+		return "", false, nil
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err = writer.Write(raw); err != nil {
+		return "", false, fmt.Errorf("couldn't compress data transfer payload: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return "", false, fmt.Errorf("couldn't compress data transfer payload: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), true, nil
+}
+
+func (c CompressedDataTransferCodec) decode(data interface{}) (interface{}, error) {
+	encoded, ok := data.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid compressed data transfer payload, expected string but got %T", data)
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode compressed data transfer payload: %w", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decompress data transfer payload: %w", err)
+	}
+	defer reader.Close()
+	maxSize := c.MaxDecodedSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecodedSize
+	}
+	// Reading one more byte than allowed detects oversized payloads, without decompressing them entirely
+	raw, err := io.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decompress data transfer payload: %w", err)
+	}
+	if len(raw) > maxSize {
+		return nil, fmt.Errorf("decompressed data transfer payload exceeds %d bytes", maxSize)
+	}
+	var result interface{}
+	if err = json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("couldn't deserialize data transfer payload: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package core
+
+import "sync"
+
+// DataTransferCodec encodes and decodes the data of DataTransfer messages exchanged with a vendor,
+// e.g. for compressing large payloads. CompressedDataTransferCodec implements this interface.
+type DataTransferCodec interface {
+	// Returns true if the data of the request was encoded by the sender.
+	IsCompressed(request *DataTransferRequest) bool
+	// Encodes the data of an outgoing request in place, marking the request as encoded.
+	EncodeRequest(request *DataTransferRequest) error
+	// Decodes the data of an incoming request in place. Returns true if the request was encoded.
+	DecodeRequest(request *DataTransferRequest) (bool, error)
+	// Encodes the data of an outgoing confirmation in place.
+	EncodeConfirmation(confirmation *DataTransferConfirmation) error
+	// Decodes the data of an incoming confirmation in place.
+	DecodeConfirmation(confirmation *DataTransferConfirmation) error
+}
+
+// DataTransferRegistry keeps track of the codecs applied to the DataTransfer messages of each vendor.
+//
+// Once registered with a central system or charge point, codecs are applied transparently:
+// requests sent via DataTransfer are encoded and their confirmations decoded, while incoming requests
+// are decoded before being passed to the core handler and their confirmations encoded.
+// Confirmations are only encoded or decoded, if the corresponding request was encoded.
+//
+// A DataTransferRegistry is safe for concurrent use.
+type DataTransferRegistry struct {
+	mutex  sync.RWMutex
+	codecs map[string]DataTransferCodec
+}
+
+// NewDataTransferRegistry creates an empty registry.
+func NewDataTransferRegistry() *DataTransferRegistry {
+	return &DataTransferRegistry{codecs: map[string]DataTransferCodec{}}
+}
+
+// Register sets the codec for the DataTransfer messages of a vendor, replacing any previous one.
+// Passing a nil codec removes the codec of the vendor.
+func (r *DataTransferRegistry) Register(vendorId string, codec DataTransferCodec) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if codec == nil {
+		delete(r.codecs, vendorId)
+		return
+	}
+	r.codecs[vendorId] = codec
+}
+
+// Codec returns the codec registered for a vendor, if any.
+func (r *DataTransferRegistry) Codec(vendorId string) (DataTransferCodec, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	codec, ok := r.codecs[vendorId]
+	return codec, ok
+}
+
+// EncodeRequest encodes an outgoing request with the codec registered for its vendor.
+// Returns the codec if the request was encoded, in which case its confirmation needs to be decoded with it.
+func (r *DataTransferRegistry) EncodeRequest(request *DataTransferRequest) (DataTransferCodec, error) {
+	codec, ok := r.Codec(request.VendorId)
+	if !ok {
+		return nil, nil
+	}
+	if err := codec.EncodeRequest(request); err != nil {
+		return nil, err
+	}
+	if !codec.IsCompressed(request) {
+		return nil, nil
+	}
+	return codec, nil
+}
+
+// DecodeRequest decodes an incoming request with the codec registered for its vendor.
+// Returns the codec if the request was encoded, in which case its confirmation needs to be encoded with it.
+func (r *DataTransferRegistry) DecodeRequest(request *DataTransferRequest) (DataTransferCodec, error) {
+	codec, ok := r.Codec(request.VendorId)
+	if !ok {
+		return nil, nil
+	}
+	encoded, err := codec.DecodeRequest(request)
+	if err != nil || !encoded {
+		return nil, err
+	}
+	return codec, nil
+}
@@ -35,16 +35,35 @@ func isValidTriggerMessageStatus(fl validator.FieldLevel) bool {
 	}
 }
 
-func isValidMessageTrigger(fl validator.FieldLevel) bool {
-	trigger := MessageTrigger(fl.Field().String())
-	switch trigger {
-	case core.BootNotificationFeatureName, firmware.DiagnosticsStatusNotificationFeatureName, firmware.FirmwareStatusNotificationFeatureName, core.HeartbeatFeatureName, core.MeterValuesFeatureName, core.StatusNotificationFeatureName:
+const (
+	MessageTriggerBootNotification              MessageTrigger = core.BootNotificationFeatureName
+	MessageTriggerDiagnosticsStatusNotification MessageTrigger = firmware.DiagnosticsStatusNotificationFeatureName
+	MessageTriggerFirmwareStatusNotification    MessageTrigger = firmware.FirmwareStatusNotificationFeatureName
+	MessageTriggerHeartbeat                     MessageTrigger = core.HeartbeatFeatureName
+	MessageTriggerMeterValues                   MessageTrigger = core.MeterValuesFeatureName
+	MessageTriggerStatusNotification            MessageTrigger = core.StatusNotificationFeatureName
+)
+
+// Returns true if the message may be requested via a TriggerMessageRequest, according to the OCPP 1.6 specification.
+func (t MessageTrigger) IsValid() bool {
+	switch t {
+	case MessageTriggerBootNotification, MessageTriggerDiagnosticsStatusNotification, MessageTriggerFirmwareStatusNotification, MessageTriggerHeartbeat, MessageTriggerMeterValues, MessageTriggerStatusNotification:
 		return true
 	default:
 		return false
 	}
 }
 
+// Returns true if the triggered message refers to a specific connector, i.e. a connectorId may be set in the TriggerMessageRequest.
+// For all other messages, the connectorId is not relevant and is ignored by the Charge Point.
+func (t MessageTrigger) SupportsConnectorId() bool {
+	return t == MessageTriggerMeterValues || t == MessageTriggerStatusNotification
+}
+
+func isValidMessageTrigger(fl validator.FieldLevel) bool {
+	return MessageTrigger(fl.Field().String()).IsValid()
+}
+
 // The field definition of the TriggerMessage request payload sent by the Central System to the Charge Point.
 type TriggerMessageRequest struct {
 	RequestedMessage MessageTrigger `json:"requestedMessage" validate:"required,messageTrigger16"`
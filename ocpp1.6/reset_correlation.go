@@ -0,0 +1,88 @@
+package ocpp16
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/core"
+)
+
+// ResetResult contains the outcome of a reset, correlated with the subsequent BootNotification of the charge point.
+// See CentralSystem.ResetAndAwaitBoot.
+type ResetResult struct {
+	ChargePointID    string
+	Type             core.ResetType
+	Status           core.ResetStatus              // Response status of the Reset request.
+	BootNotification *core.BootNotificationRequest // The BootNotification sent after resetting. Nil if the reset wasn't accepted, or no BootNotification was received in time.
+	Duration         time.Duration                 // Time elapsed between the accepted response and the BootNotification.
+}
+
+// Completed returns true, if the reset was accepted and the charge point booted afterwards.
+func (r ResetResult) Completed() bool {
+	return r.Status == core.ResetStatusAccepted && r.BootNotification != nil
+}
+
+// An accepted reset, waiting for the charge point to boot.
+type pendingReset struct {
+	result     ResetResult
+	acceptedAt time.Time
+	timer      *time.Timer
+	callback   func(result ResetResult, err error)
+}
+
+// resetCorrelator matches accepted resets with the next BootNotification sent by the same charge point.
+type resetCorrelator struct {
+	mutex   sync.Mutex
+	pending map[string][]*pendingReset
+}
+
+func newResetCorrelator() *resetCorrelator {
+	return &resetCorrelator{pending: map[string][]*pendingReset{}}
+}
+
+// Waits for the next BootNotification of a charge point. The callback is invoked once, either on boot or after the timeout.
+func (c *resetCorrelator) await(result ResetResult, timeout time.Duration, callback func(result ResetResult, err error)) {
+	reset := &pendingReset{result: result, acceptedAt: time.Now(), callback: callback}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pending[result.ChargePointID] = append(c.pending[result.ChargePointID], reset)
+	reset.timer = time.AfterFunc(timeout, func() {
+		if c.remove(reset) {
+			callback(reset.result, nil)
+		}
+	})
+}
+
+// Removes a pending reset. Returns false, if the reset was already completed.
+func (c *resetCorrelator) remove(reset *pendingReset) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	pending := c.pending[reset.result.ChargePointID]
+	for i, r := range pending {
+		if r == reset {
+			pending = append(pending[:i], pending[i+1:]...)
+			if len(pending) == 0 {
+				delete(c.pending, reset.result.ChargePointID)
+			} else {
+				c.pending[reset.result.ChargePointID] = pending
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Completes all pending resets of a charge point.
+func (c *resetCorrelator) onBootNotification(chargePointID string, request *core.BootNotificationRequest) {
+	c.mutex.Lock()
+	pending := c.pending[chargePointID]
+	delete(c.pending, chargePointID)
+	c.mutex.Unlock()
+	now := time.Now()
+	for _, reset := range pending {
+		reset.timer.Stop()
+		reset.result.BootNotification = request
+		reset.result.Duration = now.Sub(reset.acceptedAt)
+		reset.callback(reset.result, nil)
+	}
+}
@@ -0,0 +1,111 @@
+package smartcharging
+
+import (
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
+)
+
+// InstalledChargingProfile is a charging profile, which was accepted by a charge point on a specific connector.
+type InstalledChargingProfile struct {
+	ConnectorId int
+	Profile     *types.ChargingProfile
+}
+
+// ProfileRegistry keeps track of the charging profiles installed on charge points, as known by the central system.
+// It mirrors the rules applied by a charge point when receiving a SetChargingProfileRequest or a ClearChargingProfileRequest,
+// so conflicts may be detected before sending a new profile.
+//
+// The registry is only aware of changes reported to it. Profiles removed by the charge point itself,
+// e.g. a TxProfile after the transaction ended, must be removed via Clear.
+//
+// A ProfileRegistry is safe for concurrent use.
+type ProfileRegistry struct {
+	mutex    sync.RWMutex
+	profiles map[string][]InstalledChargingProfile
+}
+
+// NewProfileRegistry creates an empty registry.
+func NewProfileRegistry() *ProfileRegistry {
+	return &ProfileRegistry{profiles: map[string][]InstalledChargingProfile{}}
+}
+
+// Returns true, if setting the new profile on the given connector replaces the installed profile, without the two having the same ID.
+func replacesOther(installed InstalledChargingProfile, connectorId int, profile *types.ChargingProfile) bool {
+	return installed.Profile.ChargingProfileId != profile.ChargingProfileId &&
+		installed.ConnectorId == connectorId &&
+		installed.Profile.StackLevel == profile.StackLevel &&
+		installed.Profile.ChargingProfilePurpose == profile.ChargingProfilePurpose
+}
+
+// WouldConflict returns the installed profiles of a charge point, which would be replaced when setting the given profile on a connector,
+// because they have a different chargingProfileId but share the same connector, stackLevel and chargingProfilePurpose.
+// Replacing a profile with the same chargingProfileId is an intended update, hence it isn't considered a conflict.
+//
+// An empty result means the profile may be set without overriding other profiles.
+func (r *ProfileRegistry) WouldConflict(chargePointId string, connectorId int, profile *types.ChargingProfile) []InstalledChargingProfile {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var conflicts []InstalledChargingProfile
+	for _, installed := range r.profiles[chargePointId] {
+		if replacesOther(installed, connectorId, profile) {
+			conflicts = append(conflicts, installed)
+		}
+	}
+	return conflicts
+}
+
+// Add records a profile, which was accepted by a charge point on the given connector.
+// As on the charge point, installed profiles with the same chargingProfileId, or the same stackLevel and chargingProfilePurpose
+// on the same connector, are replaced.
+func (r *ProfileRegistry) Add(chargePointId string, connectorId int, profile *types.ChargingProfile) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var profiles []InstalledChargingProfile
+	for _, installed := range r.profiles[chargePointId] {
+		if installed.Profile.ChargingProfileId == profile.ChargingProfileId || replacesOther(installed, connectorId, profile) {
+			continue
+		}
+		profiles = append(profiles, installed)
+	}
+	r.profiles[chargePointId] = append(profiles, InstalledChargingProfile{ConnectorId: connectorId, Profile: profile})
+}
+
+// Clear removes the profiles matching a ClearChargingProfileRequest, which was accepted by a charge point,
+// and returns the amount of removed profiles.
+// If the request contains an ID, only the profile with that ID is removed. Otherwise all profiles matching the connectorId,
+// chargingProfilePurpose and stackLevel are removed, where omitted fields match any profile.
+func (r *ProfileRegistry) Clear(chargePointId string, request *ClearChargingProfileRequest) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	matches := func(installed InstalledChargingProfile) bool {
+		if request.Id != nil {
+			return installed.Profile.ChargingProfileId == *request.Id
+		}
+		return (request.ConnectorId == nil || installed.ConnectorId == *request.ConnectorId) &&
+			(request.ChargingProfilePurpose == "" || installed.Profile.ChargingProfilePurpose == request.ChargingProfilePurpose) &&
+			(request.StackLevel == nil || installed.Profile.StackLevel == *request.StackLevel)
+	}
+	var profiles []InstalledChargingProfile
+	removed := 0
+	for _, installed := range r.profiles[chargePointId] {
+		if matches(installed) {
+			removed++
+			continue
+		}
+		profiles = append(profiles, installed)
+	}
+	if len(profiles) == 0 {
+		delete(r.profiles, chargePointId)
+	} else {
+		r.profiles[chargePointId] = profiles
+	}
+	return removed
+}
+
+// Profiles returns all profiles installed on a charge point, in the order they were added.
+func (r *ProfileRegistry) Profiles(chargePointId string) []InstalledChargingProfile {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]InstalledChargingProfile(nil), r.profiles[chargePointId]...)
+}
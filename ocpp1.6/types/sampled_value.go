@@ -0,0 +1,168 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Correctly spelled alias of MeasueandSoC.
+const MeasurandSoC = MeasueandSoC
+
+// Default values of optional SampledValue fields, as defined by the OCPP 1.6 specification.
+const (
+	DefaultReadingContext = ReadingContextSamplePeriodic
+	DefaultValueFormat    = ValueFormatRaw
+	DefaultMeasurand      = MeasurandEnergyActiveImportRegister
+	DefaultLocation       = LocationOutlet
+	DefaultUnitOfMeasure  = UnitOfMeasureWh
+)
+
+// Units of measure, which may be used in combination with a measurand.
+// Measurands which are not listed here (e.g. Frequency, Power.Factor, RPM) are dimensionless and take no unit.
+var measurandUnits = map[Measurand][]UnitOfMeasure{
+	MeasurandCurrentExport:                {UnitOfMeasureA},
+	MeasurandCurrentImport:                {UnitOfMeasureA},
+	MeasurandCurrentOffered:               {UnitOfMeasureA},
+	MeasurandEnergyActiveExportRegister:   {UnitOfMeasureWh, UnitOfMeasureKWh},
+	MeasurandEnergyActiveImportRegister:   {UnitOfMeasureWh, UnitOfMeasureKWh},
+	MeasurandEnergyActiveExportInterval:   {UnitOfMeasureWh, UnitOfMeasureKWh},
+	MeasurandEnergyActiveImportInterval:   {UnitOfMeasureWh, UnitOfMeasureKWh},
+	MeasurandEnergyReactiveExportRegister: {UnitOfMeasureVarh, UnitOfMeasureKvarh},
+	MeasurandEnergyReactiveImportRegister: {UnitOfMeasureVarh, UnitOfMeasureKvarh},
+	MeasurandEnergyReactiveExportInterval: {UnitOfMeasureVarh, UnitOfMeasureKvarh},
+	MeasurandEnergyReactiveImportInterval: {UnitOfMeasureVarh, UnitOfMeasureKvarh},
+	MeasurandPowerActiveExport:            {UnitOfMeasureW, UnitOfMeasureKW},
+	MeasurandPowerActiveImport:            {UnitOfMeasureW, UnitOfMeasureKW},
+	MeasurandPowerOffered:                 {UnitOfMeasureW, UnitOfMeasureKW},
+	MeasurandPowerReactiveExport:          {UnitOfMeasureVar, UnitOfMeasureKvar},
+	MeasurandPowerReactiveImport:          {UnitOfMeasureVar, UnitOfMeasureKvar},
+	MeasurandSoC:                          {UnitOfMeasurePercent},
+	MeasurandTemperature:                  {UnitOfMeasureCelsius, UnitOfMeasureCelcius, UnitOfMeasureFahrenheit, UnitOfMeasureK},
+	MeasurandVoltage:                      {UnitOfMeasureV},
+}
+
+// IsValidUnit returns true, if the unit of measure may be used for reporting values of the measurand.
+func (m Measurand) IsValidUnit(unit UnitOfMeasure) bool {
+	for _, u := range measurandUnits[m] {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContext returns the reading context of the sampled value, or the default context if none was set.
+func (sv SampledValue) GetContext() ReadingContext {
+	if sv.Context == "" {
+		return DefaultReadingContext
+	}
+	return sv.Context
+}
+
+// GetFormat returns the value format of the sampled value, or the default format if none was set.
+func (sv SampledValue) GetFormat() ValueFormat {
+	if sv.Format == "" {
+		return DefaultValueFormat
+	}
+	return sv.Format
+}
+
+// GetMeasurand returns the measurand of the sampled value, or the default measurand if none was set.
+func (sv SampledValue) GetMeasurand() Measurand {
+	if sv.Measurand == "" {
+		return DefaultMeasurand
+	}
+	return sv.Measurand
+}
+
+// GetLocation returns the location of the sampled value, or the default location if none was set.
+func (sv SampledValue) GetLocation() Location {
+	if sv.Location == "" {
+		return DefaultLocation
+	}
+	return sv.Location
+}
+
+// GetUnit returns the unit of the sampled value, or the default unit if none was set.
+func (sv SampledValue) GetUnit() UnitOfMeasure {
+	if sv.Unit == "" {
+		return DefaultUnitOfMeasure
+	}
+	return sv.Unit
+}
+
+// FloatValue parses the value of a sampled value in raw format. No unit conversion is performed.
+//
+// An error is returned for signed data, or if the value is not a number.
+func (sv SampledValue) FloatValue() (float64, error) {
+	if sv.GetFormat() != ValueFormatRaw {
+		return 0, fmt.Errorf("cannot parse sampled value in %v format", sv.GetFormat())
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(sv.Value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sampled value %q: %w", sv.Value, err)
+	}
+	return value, nil
+}
+
+// EnergyWh returns the value of an active energy reading in Wh, converting it from kWh if needed.
+//
+// An error is returned, if the measurand isn't an active energy register or interval, or if the value cannot be parsed.
+func (sv SampledValue) EnergyWh() (float64, error) {
+	return sv.convertedValue(map[UnitOfMeasure]float64{UnitOfMeasureWh: 1, UnitOfMeasureKWh: 1000},
+		MeasurandEnergyActiveImportRegister, MeasurandEnergyActiveExportRegister, MeasurandEnergyActiveImportInterval, MeasurandEnergyActiveExportInterval)
+}
+
+// PowerW returns the value of an active power reading in W, converting it from kW if needed.
+//
+// An error is returned, if the measurand isn't an active or offered power, or if the value cannot be parsed.
+func (sv SampledValue) PowerW() (float64, error) {
+	return sv.convertedValue(map[UnitOfMeasure]float64{UnitOfMeasureW: 1, UnitOfMeasureKW: 1000},
+		MeasurandPowerActiveImport, MeasurandPowerActiveExport, MeasurandPowerOffered)
+}
+
+func (sv SampledValue) convertedValue(factors map[UnitOfMeasure]float64, measurands ...Measurand) (float64, error) {
+	measurand := sv.GetMeasurand()
+	supported := false
+	for _, m := range measurands {
+		if m == measurand {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return 0, fmt.Errorf("cannot convert sampled value of measurand %v, expected one of %v", measurand, measurands)
+	}
+	// For the default measurand, a missing unit implies the default unit
+	unit := sv.Unit
+	if unit == "" && measurand == DefaultMeasurand {
+		unit = DefaultUnitOfMeasure
+	}
+	factor, ok := factors[unit]
+	if !ok {
+		return 0, fmt.Errorf("cannot convert sampled value of measurand %v with unit %q", measurand, unit)
+	}
+	value, err := sv.FloatValue()
+	if err != nil {
+		return 0, err
+	}
+	return value * factor, nil
+}
+
+// Rejects units of measure which cannot be used with an explicitly set measurand (e.g. Voltage in kWh).
+func isValidSampledValue(sl validator.StructLevel) {
+	sampledValue := sl.Current().Interface().(SampledValue)
+	if sampledValue.Measurand == "" || sampledValue.Unit == "" {
+		return
+	}
+	if !sampledValue.Measurand.IsValidUnit(sampledValue.Unit) {
+		sl.ReportError(sampledValue.Unit, "Unit", "unit", "unitForMeasurand16", string(sampledValue.Measurand))
+	}
+}
+
+func init() {
+	Validate.RegisterStructValidation(isValidSampledValue, SampledValue{})
+}
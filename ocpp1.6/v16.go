@@ -2,8 +2,11 @@
 package ocpp16
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
+	"net/url"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/internal/callbackqueue"
 	"github.com/lorenzodonini/ocpp-go/ocpp"
@@ -22,6 +25,15 @@ type ChargePointConnection interface {
 	ID() string
 	RemoteAddr() net.Addr
 	TLSConnectionState() *tls.ConnectionState
+	// Stores a custom value for the lifetime of the connection. Safe for concurrent use.
+	Set(key string, value interface{})
+	// Retrieves a custom value, previously stored via Set.
+	// Values are cleared automatically once the connection was closed.
+	Get(key string) (interface{}, bool)
+	// Removes a custom value, previously stored via Set.
+	Delete(key string)
+	// Returns the query parameters of the URL, on which the charge point connected (e.g. a firmware version or a session token).
+	QueryParams() url.Values
 }
 
 type ChargePointConnectionHandler func(chargePoint ChargePointConnection)
@@ -51,6 +63,7 @@ type ChargePoint interface {
 	// Requests explicit authorization to the central system, provided a valid IdTag (typically the client's). The central system may either authorize or reject the client.
 	Authorize(idTag string, props ...func(request *core.AuthorizeRequest)) (*core.AuthorizeConfirmation, error)
 	// Starts a custom data transfer request. Every vendor may implement their own proprietary logic for this message.
+	// A non-accepted status is not returned as an error, but may be converted into one via DataTransferConfirmation.Err.
 	DataTransfer(vendorId string, props ...func(request *core.DataTransferRequest)) (*core.DataTransferConfirmation, error)
 	// Notifies the central system that the charge point is still online. The central system's response is used for time synchronization purposes. It is recommended to perform this operation once every 24 hours.
 	Heartbeat(props ...func(request *core.HeartbeatRequest)) (*core.HeartbeatConfirmation, error)
@@ -69,6 +82,10 @@ type ChargePoint interface {
 
 	// Registers a handler for incoming core profile messages
 	SetCoreHandler(listener core.ChargePointHandler)
+	// Registers a registry of codecs, which are applied transparently to DataTransfer messages of the respective vendors,
+	// e.g. a core.CompressedDataTransferCodec. Incoming requests failing to decode are rejected with a FormationViolation.
+	// See core.DataTransferRegistry for more details. Pass nil to disable the codecs.
+	SetDataTransferRegistry(registry *core.DataTransferRegistry)
 	// Registers a handler for incoming local authorization profile messages
 	SetLocalAuthListHandler(listener localauth.ChargePointHandler)
 	// Registers a handler for incoming firmware management profile messages
@@ -191,6 +208,8 @@ func NewChargePoint(id string, endpoint *ocppj.Client, client ws.WsClient) Charg
 // All messages are sent asynchronously and do not block the caller.
 type CentralSystem interface {
 	// Instructs a charge point to change its availability. The target availability can be set for a single connector of for the whole charge point.
+	// Pass core.ChargePointConnectorId (i.e. connector 0) to change the availability of the whole charge point.
+	// If a transaction is in progress, the charge point responds with the Scheduled status and applies the change once the transaction finished.
 	ChangeAvailability(clientId string, callback func(*core.ChangeAvailabilityConfirmation, error), connectorId int, availabilityType core.AvailabilityType, props ...func(*core.ChangeAvailabilityRequest)) error
 	// Changes the configuration of a charge point, by setting a specific key-value pair.
 	// The configuration key must be supported by the target charge point, in order for the configuration to be accepted.
@@ -198,6 +217,7 @@ type CentralSystem interface {
 	// Instructs the charge point to clear its current authorization cache. All authorization saved locally will be invalidated.
 	ClearCache(clientId string, callback func(*core.ClearCacheConfirmation, error), props ...func(*core.ClearCacheRequest)) error
 	// Starts a custom data transfer request. Every vendor may implement their own proprietary logic for this message.
+	// A non-accepted status is not reported as an error to the callback, but may be converted into one via DataTransferConfirmation.Err.
 	DataTransfer(clientId string, callback func(*core.DataTransferConfirmation, error), vendorId string, props ...func(*core.DataTransferRequest)) error
 	// Retrieves the configuration values for the provided configuration keys.
 	GetConfiguration(clientId string, callback func(*core.GetConfigurationConfirmation, error), keys []string, props ...func(*core.GetConfigurationRequest)) error
@@ -208,6 +228,12 @@ type CentralSystem interface {
 	RemoteStopTransaction(clientId string, callback func(*core.RemoteStopTransactionConfirmation, error), transactionId int, props ...func(request *core.RemoteStopTransactionRequest)) error
 	// Forces a charge point to perform an internal hard or soft reset. In both cases, all ongoing transactions are stopped.
 	Reset(clientId string, callback func(*core.ResetConfirmation, error), resetType core.ResetType, props ...func(*core.ResetRequest)) error
+	// Resets a charge point and waits for its subsequent BootNotification, which confirms that the reset was performed.
+	//
+	// The callback is invoked once the BootNotification was received, or once the timeout expired, see ResetResult.Completed.
+	// The timeout starts after the charge point accepted the reset. If the reset was rejected, the callback is invoked right away.
+	// Errors are only returned for a failed Reset request.
+	ResetAndAwaitBoot(clientId string, callback func(result ResetResult, err error), resetType core.ResetType, timeout time.Duration, props ...func(*core.ResetRequest)) error
 	// Attempts to unlock a specific connector on a charge point. Used for remote support purposes.
 	UnlockConnector(clientId string, callback func(*core.UnlockConnectorConfirmation, error), connectorId int, props ...func(*core.UnlockConnectorRequest)) error
 	// Queries the current version of the local authorization list from a charge point.
@@ -223,6 +249,9 @@ type CentralSystem interface {
 	// Cancels a previously reserved charge point or connector, given the reservation ID.
 	CancelReservation(clientId string, callback func(*reservation.CancelReservationConfirmation, error), reservationId int, props ...func(request *reservation.CancelReservationRequest)) error
 	// Instructs a charge point to send a specific message to the central system. This is used for forcefully triggering status updates, when the last known state is either too old or not clear to the central system.
+	//
+	// An error is returned without sending the request, if the requested message cannot be triggered,
+	// or if a connectorId is set for a message which doesn't refer to a connector (see MessageTrigger.SupportsConnectorId).
 	TriggerMessage(clientId string, callback func(*remotetrigger.TriggerMessageConfirmation, error), requestedMessage remotetrigger.MessageTrigger, props ...func(request *remotetrigger.TriggerMessageRequest)) error
 	// Sends a smart charging profile to a charge point. Refer to the smart charging documentation for more information.
 	SetChargingProfile(clientId string, callback func(*smartcharging.SetChargingProfileConfirmation, error), connectorId int, chargingProfile *types.ChargingProfile, props ...func(request *smartcharging.SetChargingProfileRequest)) error
@@ -230,6 +259,14 @@ type CentralSystem interface {
 	ClearChargingProfile(clientId string, callback func(*smartcharging.ClearChargingProfileConfirmation, error), props ...func(request *smartcharging.ClearChargingProfileRequest)) error
 	// Queries a charge point to the composite smart charging schedules and rules for a specified time interval.
 	GetCompositeSchedule(clientId string, callback func(*smartcharging.GetCompositeScheduleConfirmation, error), connectorId int, duration int, props ...func(request *smartcharging.GetCompositeScheduleRequest)) error
+	// Registers a registry, which keeps track of the charging profiles installed on each charge point.
+	// Profiles accepted via SetChargingProfile are added to the registry, while profiles cleared via ClearChargingProfile are removed.
+	// Use ProfileRegistry.WouldConflict to detect conflicts before sending a new profile. Pass nil to stop tracking profiles.
+	SetChargingProfileRegistry(registry *smartcharging.ProfileRegistry)
+	// Registers a registry of codecs, which are applied transparently to DataTransfer messages of the respective vendors,
+	// e.g. a core.CompressedDataTransferCodec. Incoming requests failing to decode are rejected with a FormationViolation.
+	// See core.DataTransferRegistry for more details. Pass nil to disable the codecs.
+	SetDataTransferRegistry(registry *core.DataTransferRegistry)
 
 	// Registers a handler for incoming core profile messages.
 	SetCoreHandler(handler core.CentralSystemHandler)
@@ -259,6 +296,19 @@ type CentralSystem interface {
 
 	// The function blocks forever, so it is suggested to wrap it in a goroutine, in case other functionality needs to be executed on the main program thread.
 	Start(listenPort int, listenPath string)
+	// Starts running the central system on the specified port and URL, until the passed context is done.
+	// Once the context is canceled, the central system is shut down gracefully, clearing all pending requests.
+	//
+	// The function blocks until the central system stopped. An error is returned if the server couldn't listen on the specified port,
+	// while nil is returned after a graceful shutdown.
+	StartWithContext(ctx context.Context, listenPort int, listenPath string) error
+	// Starts the central system on a pre-created listener, e.g. a Unix domain socket, instead of binding a TCP port.
+	//
+	// The function blocks until the central system stopped and returns nil after a graceful shutdown.
+	StartOnListener(listener net.Listener, listenPath string) error
+	// Registers an additional URL pattern, on which charge points may connect, besides the listen path passed on start.
+	// Charge points connected on any path share the same handlers and are notified via the new charge point handler.
+	AddListenPath(listenPath string)
 	// Stops the central system, clearing all pending requests.
 	Stop()
 	// Errors returns a channel for error messages. If it doesn't exist it es created.
@@ -74,6 +74,53 @@ func (suite *OcppV16TestSuite) TestChangeAvailabilityE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV16TestSuite) testChangeAvailability(connectorId int, availabilityType core.AvailabilityType, status core.AvailabilityStatus) {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"connectorId":%v,"type":"%v"}]`, messageId, core.ChangeAvailabilityFeatureName, connectorId, availabilityType)
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	channel := NewMockWebSocket(wsId)
+	// Setting handlers
+	coreListener := &MockChargePointCoreListener{}
+	coreListener.On("OnChangeAvailability", mock.Anything).Return(core.NewChangeAvailabilityConfirmation(status), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(0).(*core.ChangeAvailabilityRequest)
+		require.True(t, ok)
+		assert.Equal(t, connectorId, request.ConnectorId)
+		assert.Equal(t, connectorId == core.ChargePointConnectorId, request.IsChargePointWide())
+		assert.Equal(t, availabilityType, request.Type)
+	})
+	setupDefaultCentralSystemHandlers(suite, nil, expectedCentralSystemOptions{clientId: wsId, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	setupDefaultChargePointHandlers(suite, coreListener, expectedChargePointOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true})
+	// Run Test
+	suite.centralSystem.Start(8887, "somePath")
+	err := suite.chargePoint.Start(wsUrl)
+	require.Nil(t, err)
+	resultChannel := make(chan bool, 1)
+	err = suite.centralSystem.ChangeAvailability(wsId, func(confirmation *core.ChangeAvailabilityConfirmation, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, confirmation)
+		assert.Equal(t, status, confirmation.Status)
+		resultChannel <- true
+	}, connectorId, availabilityType)
+	require.Nil(t, err)
+	result := <-resultChannel
+	assert.True(t, result)
+}
+
+func (suite *OcppV16TestSuite) TestChangeAvailabilityWholeChargePoint() {
+	suite.testChangeAvailability(core.ChargePointConnectorId, core.AvailabilityTypeInoperative, core.AvailabilityStatusAccepted)
+}
+
+func (suite *OcppV16TestSuite) TestChangeAvailabilityScheduled() {
+	suite.testChangeAvailability(core.ChargePointConnectorId, core.AvailabilityTypeInoperative, core.AvailabilityStatusScheduled)
+}
+
+func (suite *OcppV16TestSuite) TestChangeAvailabilityConnectorScheduled() {
+	suite.testChangeAvailability(2, core.AvailabilityTypeOperative, core.AvailabilityStatusScheduled)
+}
+
 func (suite *OcppV16TestSuite) TestChangeAvailabilityInvalidEndpoint() {
 	messageId := defaultMessageId
 	connectorId := 1
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/relvacode/iso8601"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
 )
@@ -105,10 +107,61 @@ func (suite *OcppV16TestSuite) TestSampledValueValidation() {
 		{types.SampledValue{Value: "value", Phase: "invalidPhase"}, false},
 		{types.SampledValue{Value: "value", Location: "invalidLocation"}, false},
 		{types.SampledValue{Value: "value", Unit: "invalidUnit"}, false},
+		{types.SampledValue{Value: "1.5", Measurand: types.MeasurandEnergyActiveImportRegister, Phase: types.PhaseL1, Unit: types.UnitOfMeasureKWh}, true},
+		{types.SampledValue{Value: "230.1", Measurand: types.MeasurandVoltage, Phase: types.PhaseL1N, Unit: types.UnitOfMeasureV}, true},
+		{types.SampledValue{Value: "80", Measurand: types.MeasurandSoC, Location: types.LocationEV, Unit: types.UnitOfMeasurePercent}, true},
+		{types.SampledValue{Value: "50", Measurand: types.MeasurandFrequency}, true},
+		{types.SampledValue{Value: "230.1", Measurand: types.MeasurandVoltage, Unit: types.UnitOfMeasureKWh}, false},
+		{types.SampledValue{Value: "1.5", Measurand: types.MeasurandEnergyActiveImportRegister, Unit: types.UnitOfMeasureKW}, false},
+		{types.SampledValue{Value: "1.5", Measurand: types.MeasurandEnergyReactiveImportRegister, Unit: types.UnitOfMeasureKWh}, false},
+		{types.SampledValue{Value: "50", Measurand: types.MeasurandFrequency, Unit: types.UnitOfMeasurePercent}, false},
 	}
 	ExecuteGenericTestTable(t, testTable)
 }
 
+func (suite *OcppV16TestSuite) TestSampledValueConversion() {
+	t := suite.T()
+	// Typical energy register reading in kWh
+	sampledValue := types.SampledValue{Value: "12.345", Context: types.ReadingContextSamplePeriodic, Measurand: types.MeasurandEnergyActiveImportRegister, Phase: types.PhaseL1, Location: types.LocationOutlet, Unit: types.UnitOfMeasureKWh}
+	energy, err := sampledValue.EnergyWh()
+	require.NoError(t, err)
+	assert.InDelta(t, 12345.0, energy, 0.0001)
+	// Default measurand and unit are Energy.Active.Import.Register in Wh
+	sampledValue = types.SampledValue{Value: "1500"}
+	assert.Equal(t, types.MeasurandEnergyActiveImportRegister, sampledValue.GetMeasurand())
+	assert.Equal(t, types.UnitOfMeasureWh, sampledValue.GetUnit())
+	assert.Equal(t, types.ReadingContextSamplePeriodic, sampledValue.GetContext())
+	assert.Equal(t, types.ValueFormatRaw, sampledValue.GetFormat())
+	assert.Equal(t, types.LocationOutlet, sampledValue.GetLocation())
+	energy, err = sampledValue.EnergyWh()
+	require.NoError(t, err)
+	assert.Equal(t, 1500.0, energy)
+	// Power readings
+	sampledValue = types.SampledValue{Value: "7.4", Measurand: types.MeasurandPowerActiveImport, Unit: types.UnitOfMeasureKW}
+	power, err := sampledValue.PowerW()
+	require.NoError(t, err)
+	assert.InDelta(t, 7400.0, power, 0.0001)
+	_, err = sampledValue.EnergyWh()
+	assert.Error(t, err)
+	// Missing unit for non-default measurand
+	sampledValue = types.SampledValue{Value: "7400", Measurand: types.MeasurandPowerActiveImport}
+	_, err = sampledValue.PowerW()
+	assert.Error(t, err)
+	// Non-convertible values
+	sampledValue = types.SampledValue{Value: "230", Measurand: types.MeasurandVoltage, Unit: types.UnitOfMeasureV}
+	_, err = sampledValue.EnergyWh()
+	assert.Error(t, err)
+	value, err := sampledValue.FloatValue()
+	require.NoError(t, err)
+	assert.Equal(t, 230.0, value)
+	sampledValue = types.SampledValue{Value: "deadbeef", Format: types.ValueFormatSignedData, Unit: types.UnitOfMeasureKWh}
+	_, err = sampledValue.EnergyWh()
+	assert.Error(t, err)
+	sampledValue = types.SampledValue{Value: "notANumber", Unit: types.UnitOfMeasureKWh}
+	_, err = sampledValue.EnergyWh()
+	assert.Error(t, err)
+}
+
 func (suite *OcppV16TestSuite) TestMeterValueValidation() {
 	testTable := []GenericTestEntry{
 		{types.MeterValue{Timestamp: types.NewDateTime(time.Now()), SampledValue: []types.SampledValue{{Value: "value"}, {Value: "value2", Unit: types.UnitOfMeasureKW}}}, true},
@@ -2,9 +2,15 @@ package ocpp16_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/lorenzodonini/ocpp-go/ocpp"
 	"github.com/lorenzodonini/ocpp-go/ocpp1.6/core"
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -134,3 +140,234 @@ func (suite *OcppV16TestSuite) TestDataTransferFromCentralSystemE2EMocked() {
 	result := <-resultChannel
 	assert.True(t, result)
 }
+
+func (suite *OcppV16TestSuite) TestDataTransferConfirmationStatus() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	vendorId := "vendor1"
+	testTable := []struct {
+		status core.DataTransferStatus
+		data   *CustomData
+	}{
+		{core.DataTransferStatusAccepted, &CustomData{Field1: "ok", Field2: 1}},
+		{core.DataTransferStatusRejected, &CustomData{Field1: "quota exceeded", Field2: 42}},
+		{core.DataTransferStatusUnknownMessageId, nil},
+		{core.DataTransferStatusUnknownVendorId, nil},
+	}
+	for _, tc := range testTable {
+		suite.SetupTest()
+		dataTransferConfirmation := core.NewDataTransferConfirmation(tc.status)
+		if tc.data != nil {
+			dataTransferConfirmation.Data = tc.data
+		}
+		channel := NewMockWebSocket(wsId)
+		coreListener := &MockChargePointCoreListener{}
+		coreListener.On("OnDataTransfer", mock.Anything).Return(dataTransferConfirmation, nil)
+		setupDefaultCentralSystemHandlers(suite, nil, expectedCentralSystemOptions{clientId: wsId, forwardWrittenMessage: true})
+		setupDefaultChargePointHandlers(suite, coreListener, expectedChargePointOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+		// Run Test
+		suite.centralSystem.Start(8887, "somePath")
+		err := suite.chargePoint.Start(wsUrl)
+		require.Nil(t, err)
+		resultC := make(chan *core.DataTransferConfirmation, 1)
+		err = suite.centralSystem.DataTransfer(wsId, func(confirmation *core.DataTransferConfirmation, err error) {
+			assert.Nil(t, err)
+			resultC <- confirmation
+		}, vendorId)
+		require.Nil(t, err)
+		var confirmation *core.DataTransferConfirmation
+		select {
+		case confirmation = <-resultC:
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %v confirmation", tc.status)
+		}
+		require.NotNil(t, confirmation)
+		assert.Equal(t, tc.status, confirmation.Status)
+		// Non-accepted transfers are turned into a typed error, carrying the status and data
+		if tc.status == core.DataTransferStatusAccepted {
+			assert.NoError(t, confirmation.Err())
+		} else {
+			var transferErr *core.DataTransferError
+			require.True(t, errors.As(confirmation.Err(), &transferErr), tc.status)
+			assert.Equal(t, tc.status, transferErr.Status)
+			assert.Equal(t, confirmation.Data, transferErr.Data)
+		}
+		if tc.data == nil {
+			assert.Nil(t, confirmation.Data)
+			assert.Error(t, confirmation.UnmarshalData(&CustomData{}))
+			continue
+		}
+		var customData CustomData
+		require.NoError(t, confirmation.UnmarshalData(&customData))
+		assert.Equal(t, *tc.data, customData)
+	}
+}
+
+func (suite *OcppV16TestSuite) TestCompressedDataTransferCodec() {
+	t := suite.T()
+	codec := core.CompressedDataTransferCodec{MinSize: 100}
+	telemetry := map[string]interface{}{
+		"samples": strings.Repeat("0123456789abcdef", 512),
+		"count":   float64(512),
+	}
+	// Small payloads are left untouched
+	request := core.NewDataTransferRequest("vendor1")
+	request.MessageId = "telemetry"
+	request.Data = "small"
+	err := codec.EncodeRequest(request)
+	require.NoError(t, err)
+	assert.Equal(t, "telemetry", request.MessageId)
+	assert.Equal(t, "small", request.Data)
+	assert.False(t, codec.IsCompressed(request))
+	// Large payloads are compressed
+	request.Data = telemetry
+	err = codec.EncodeRequest(request)
+	require.NoError(t, err)
+	assert.Equal(t, "telemetry"+core.CompressedMessageIdSuffix, request.MessageId)
+	assert.True(t, codec.IsCompressed(request))
+	encoded, ok := request.Data.(string)
+	require.True(t, ok)
+	raw, _ := json.Marshal(telemetry)
+	assert.Less(t, len(encoded), len(raw)/10)
+	// Encoding twice is a no-op
+	err = codec.EncodeRequest(request)
+	require.NoError(t, err)
+	assert.Equal(t, encoded, request.Data)
+	err = types.Validate.Struct(request)
+	require.NoError(t, err)
+	// Round-trip over JSON
+	serialized, err := json.Marshal(request)
+	require.NoError(t, err)
+	var received core.DataTransferRequest
+	err = json.Unmarshal(serialized, &received)
+	require.NoError(t, err)
+	compressed, err := codec.DecodeRequest(&received)
+	require.NoError(t, err)
+	assert.True(t, compressed)
+	assert.Equal(t, "telemetry", received.MessageId)
+	assert.Equal(t, telemetry, received.Data)
+	// Uncompressed requests are not decoded
+	compressed, err = codec.DecodeRequest(&received)
+	require.NoError(t, err)
+	assert.False(t, compressed)
+	assert.Equal(t, telemetry, received.Data)
+	// Confirmations
+	confirmation := core.NewDataTransferConfirmation(core.DataTransferStatusAccepted)
+	confirmation.Data = telemetry
+	err = codec.EncodeConfirmation(confirmation)
+	require.NoError(t, err)
+	assert.IsType(t, "", confirmation.Data)
+	err = codec.DecodeConfirmation(confirmation)
+	require.NoError(t, err)
+	assert.Equal(t, telemetry, confirmation.Data)
+	// Invalid compressed payloads
+	received = core.DataTransferRequest{VendorId: "vendor1", MessageId: "telemetry" + core.CompressedMessageIdSuffix, Data: "not base64!"}
+	_, err = codec.DecodeRequest(&received)
+	assert.Error(t, err)
+	received.Data = 42
+	_, err = codec.DecodeRequest(&received)
+	assert.Error(t, err)
+	// The marked messageId must not exceed the maximum length
+	request = core.NewDataTransferRequest("vendor1")
+	request.MessageId = strings.Repeat("m", 50-len(core.CompressedMessageIdSuffix)+1)
+	request.Data = telemetry
+	err = codec.EncodeRequest(request)
+	assert.Error(t, err)
+	assert.Equal(t, telemetry, request.Data)
+	request.MessageId = strings.Repeat("m", 50-len(core.CompressedMessageIdSuffix))
+	err = codec.EncodeRequest(request)
+	require.NoError(t, err)
+	assert.NoError(t, types.Validate.Struct(request))
+	// Payloads exceeding the maximum decoded size are rejected
+	limitedCodec := core.CompressedDataTransferCodec{MaxDecodedSize: len(raw) - 1}
+	received = core.DataTransferRequest{VendorId: "vendor1", MessageId: request.MessageId, Data: request.Data}
+	_, err = limitedCodec.DecodeRequest(&received)
+	assert.Error(t, err)
+	limitedCodec.MaxDecodedSize = len(raw)
+	_, err = limitedCodec.DecodeRequest(&received)
+	require.NoError(t, err)
+	assert.Equal(t, telemetry, received.Data)
+}
+
+func (suite *OcppV16TestSuite) TestDataTransferRegistryCompression() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	vendorId := "vendor1"
+	messageId := "telemetry"
+	telemetry := map[string]interface{}{
+		"samples": strings.Repeat("0123456789abcdef", 512),
+		"count":   float64(512),
+	}
+	raw, _ := json.Marshal(telemetry)
+	channel := NewMockWebSocket(wsId)
+	registry := core.NewDataTransferRegistry()
+	registry.Register(vendorId, core.CompressedDataTransferCodec{MinSize: 100})
+	suite.centralSystem.SetDataTransferRegistry(registry)
+	suite.chargePoint.SetDataTransferRegistry(registry)
+	// Both handlers receive the decoded data and reply with uncompressed data
+	centralSystemListener := &MockCentralSystemCoreListener{}
+	centralSystemConfirmation := core.NewDataTransferConfirmation(core.DataTransferStatusAccepted)
+	centralSystemConfirmation.Data = telemetry
+	centralSystemListener.On("OnDataTransfer", mock.AnythingOfType("string"), mock.Anything).Return(centralSystemConfirmation, nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*core.DataTransferRequest)
+		assert.Equal(t, messageId, request.MessageId)
+		assert.Equal(t, telemetry, request.Data)
+	})
+	chargePointListener := &MockChargePointCoreListener{}
+	chargePointConfirmation := core.NewDataTransferConfirmation(core.DataTransferStatusAccepted)
+	chargePointConfirmation.Data = telemetry
+	chargePointListener.On("OnDataTransfer", mock.Anything).Return(chargePointConfirmation, nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*core.DataTransferRequest)
+		assert.Equal(t, messageId, request.MessageId)
+		assert.Equal(t, telemetry, request.Data)
+	})
+	setupDefaultCentralSystemHandlers(suite, centralSystemListener, expectedCentralSystemOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargePointHandlers(suite, chargePointListener, expectedChargePointOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.centralSystem.Start(8887, "somePath")
+	err := suite.chargePoint.Start(wsUrl)
+	require.Nil(t, err)
+	confirmation, err := suite.chargePoint.DataTransfer(vendorId, func(request *core.DataTransferRequest) {
+		request.MessageId = messageId
+		request.Data = telemetry
+	})
+	require.NoError(t, err)
+	require.NotNil(t, confirmation)
+	assert.Equal(t, telemetry, confirmation.Data)
+	resultChannel := make(chan *core.DataTransferConfirmation, 1)
+	err = suite.centralSystem.DataTransfer(wsId, func(confirmation *core.DataTransferConfirmation, err error) {
+		require.NoError(t, err)
+		resultChannel <- confirmation
+	}, vendorId, func(request *core.DataTransferRequest) {
+		request.MessageId = messageId
+		request.Data = telemetry
+	})
+	require.NoError(t, err)
+	confirmation = <-resultChannel
+	require.NotNil(t, confirmation)
+	assert.Equal(t, telemetry, confirmation.Data)
+	// All payloads were compressed on the wire
+	var written [][]byte
+	for _, call := range append(suite.mockWsClient.Calls, suite.mockWsServer.Calls...) {
+		if call.Method == "Write" {
+			written = append(written, call.Arguments.Get(len(call.Arguments)-1).([]byte))
+		}
+	}
+	require.Len(t, written, 4)
+	for _, message := range written {
+		assert.NotContains(t, string(message), "0123456789abcdef")
+		assert.Less(t, len(message), len(raw)/10)
+	}
+	// Requests failing to decode are rejected
+	invalidRequest := core.NewDataTransferRequest(vendorId)
+	invalidRequest.MessageId = messageId + core.CompressedMessageIdSuffix
+	invalidRequest.Data = "not base64!"
+	_, err = suite.chargePoint.SendRequest(invalidRequest)
+	require.Error(t, err)
+	protoErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.FormatViolationV16, protoErr.Code)
+	centralSystemListener.AssertNumberOfCalls(t, "OnDataTransfer", 1)
+}
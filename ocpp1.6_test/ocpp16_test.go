@@ -1,10 +1,12 @@
 package ocpp16_test
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -47,6 +49,24 @@ func (websocket MockWebSocket) TLSConnectionState() *tls.ConnectionState {
 	return nil
 }
 
+func (websocket MockWebSocket) Set(key string, value interface{}) {
+}
+
+func (websocket MockWebSocket) Get(key string) (interface{}, bool) {
+	return nil, false
+}
+
+func (websocket MockWebSocket) Delete(key string) {
+}
+
+func (websocket MockWebSocket) Context() context.Context {
+	return context.Background()
+}
+
+func (websocket MockWebSocket) QueryParams() url.Values {
+	return url.Values{}
+}
+
 func NewMockWebSocket(id string) MockWebSocket {
 	return MockWebSocket{id: id}
 }
@@ -56,6 +76,7 @@ type MockWebsocketServer struct {
 	mock.Mock
 	ws.WsServer
 	MessageHandler            func(ws ws.Channel, data []byte) error
+	BinaryMessageHandler      func(ws ws.Channel, data []byte) error
 	NewClientHandler          func(ws ws.Channel)
 	CheckClientHandler        ws.CheckClientHandler
 	DisconnectedClientHandler func(ws ws.Channel)
@@ -78,6 +99,15 @@ func (websocketServer *MockWebsocketServer) SetMessageHandler(handler func(ws ws
 	websocketServer.MessageHandler = handler
 }
 
+func (websocketServer *MockWebsocketServer) WriteBinary(webSocketId string, data []byte) error {
+	args := websocketServer.MethodCalled("WriteBinary", webSocketId, data)
+	return args.Error(0)
+}
+
+func (websocketServer *MockWebsocketServer) SetBinaryMessageHandler(handler func(ws ws.Channel, data []byte) error) {
+	websocketServer.BinaryMessageHandler = handler
+}
+
 func (websocketServer *MockWebsocketServer) SetNewClientHandler(handler func(ws ws.Channel)) {
 	websocketServer.NewClientHandler = handler
 }
@@ -101,10 +131,11 @@ func (websocketServer *MockWebsocketServer) SetCheckClientHandler(handler func(i
 type MockWebsocketClient struct {
 	mock.Mock
 	ws.WsClient
-	MessageHandler      func(data []byte) error
-	ReconnectedHandler  func()
-	DisconnectedHandler func(err error)
-	errC                chan error
+	MessageHandler       func(data []byte) error
+	BinaryMessageHandler func(data []byte) error
+	ReconnectedHandler   func()
+	DisconnectedHandler  func(err error)
+	errC                 chan error
 }
 
 func (websocketClient *MockWebsocketClient) Start(url string) error {
@@ -120,6 +151,15 @@ func (websocketClient *MockWebsocketClient) SetMessageHandler(handler func(data
 	websocketClient.MessageHandler = handler
 }
 
+func (websocketClient *MockWebsocketClient) WriteBinary(data []byte) error {
+	args := websocketClient.MethodCalled("WriteBinary", data)
+	return args.Error(0)
+}
+
+func (websocketClient *MockWebsocketClient) SetBinaryMessageHandler(handler func(data []byte) error) {
+	websocketClient.BinaryMessageHandler = handler
+}
+
 func (websocketClient *MockWebsocketClient) SetReconnectedHandler(handler func()) {
 	websocketClient.ReconnectedHandler = handler
 }
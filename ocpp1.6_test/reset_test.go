@@ -2,8 +2,11 @@ package ocpp16_test
 
 import (
 	"fmt"
+	"time"
 
+	ocpp16 "github.com/lorenzodonini/ocpp-go/ocpp1.6"
 	"github.com/lorenzodonini/ocpp-go/ocpp1.6/core"
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -69,6 +72,86 @@ func (suite *OcppV16TestSuite) TestResetE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV16TestSuite) TestResetAndAwaitBoot() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	resetType := core.ResetTypeSoft
+	chargePointModel := "model1"
+	chargePointVendor := "ABL"
+	channel := NewMockWebSocket(wsId)
+	// Setting handlers
+	coreListener := &MockChargePointCoreListener{}
+	resetC := make(chan bool, 1)
+	coreListener.On("OnReset", mock.Anything).Return(core.NewResetConfirmation(core.ResetStatusAccepted), nil).Run(func(args mock.Arguments) {
+		resetC <- true
+	})
+	centralSystemListener := &MockCentralSystemCoreListener{}
+	centralSystemListener.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(core.NewBootNotificationConfirmation(types.NewDateTime(time.Now()), 60, core.RegistrationStatusAccepted), nil)
+	setupDefaultCentralSystemHandlers(suite, centralSystemListener, expectedCentralSystemOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargePointHandlers(suite, coreListener, expectedChargePointOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.centralSystem.Start(8887, "somePath")
+	err := suite.chargePoint.Start(wsUrl)
+	require.Nil(t, err)
+	resultC := make(chan ocpp16.ResetResult, 1)
+	err = suite.centralSystem.ResetAndAwaitBoot(wsId, func(result ocpp16.ResetResult, err error) {
+		require.Nil(t, err)
+		resultC <- result
+	}, resetType, 5*time.Second)
+	require.Nil(t, err)
+	// Charge point boots after resetting. Wait for the accepted response to be processed first.
+	<-resetC
+	time.Sleep(100 * time.Millisecond)
+	confirmation, err := suite.chargePoint.BootNotification(chargePointModel, chargePointVendor)
+	require.Nil(t, err)
+	require.NotNil(t, confirmation)
+	select {
+	case result := <-resultC:
+		assert.True(t, result.Completed())
+		assert.Equal(t, wsId, result.ChargePointID)
+		assert.Equal(t, resetType, result.Type)
+		assert.Equal(t, core.ResetStatusAccepted, result.Status)
+		require.NotNil(t, result.BootNotification)
+		assert.Equal(t, chargePointModel, result.BootNotification.ChargePointModel)
+		assert.Equal(t, chargePointVendor, result.BootNotification.ChargePointVendor)
+	case <-time.After(time.Second):
+		t.Fatal("reset wasn't correlated with boot notification")
+	}
+}
+
+func (suite *OcppV16TestSuite) TestResetAndAwaitBootTimeout() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	resetType := core.ResetTypeHard
+	channel := NewMockWebSocket(wsId)
+	// Setting handlers
+	coreListener := &MockChargePointCoreListener{}
+	coreListener.On("OnReset", mock.Anything).Return(core.NewResetConfirmation(core.ResetStatusAccepted), nil)
+	setupDefaultCentralSystemHandlers(suite, nil, expectedCentralSystemOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargePointHandlers(suite, coreListener, expectedChargePointOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.centralSystem.Start(8887, "somePath")
+	err := suite.chargePoint.Start(wsUrl)
+	require.Nil(t, err)
+	resultC := make(chan ocpp16.ResetResult, 1)
+	err = suite.centralSystem.ResetAndAwaitBoot(wsId, func(result ocpp16.ResetResult, err error) {
+		require.Nil(t, err)
+		resultC <- result
+	}, resetType, 50*time.Millisecond)
+	require.Nil(t, err)
+	// Charge point never boots
+	select {
+	case result := <-resultC:
+		assert.False(t, result.Completed())
+		assert.Equal(t, core.ResetStatusAccepted, result.Status)
+		assert.Nil(t, result.BootNotification)
+	case <-time.After(time.Second):
+		t.Fatal("reset didn't time out")
+	}
+}
+
 func (suite *OcppV16TestSuite) TestResetInvalidEndpoint() {
 	messageId := defaultMessageId
 	resetType := core.ResetTypeSoft
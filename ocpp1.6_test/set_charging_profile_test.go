@@ -108,6 +108,79 @@ func (suite *OcppV16TestSuite) TestSetChargingProfileE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV16TestSuite) TestSetChargingProfileRegistryConflicts() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	smartChargingListener := &MockChargePointSmartChargingListener{}
+	smartChargingListener.On("OnSetChargingProfile", mock.Anything).Return(smartcharging.NewSetChargingProfileConfirmation(smartcharging.ChargingProfileStatusAccepted), nil)
+	smartChargingListener.On("OnClearChargingProfile", mock.Anything).Return(smartcharging.NewClearChargingProfileConfirmation(smartcharging.ClearChargingProfileStatusAccepted), nil)
+	setupDefaultCentralSystemHandlers(suite, nil, expectedCentralSystemOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargePointHandlers(suite, nil, expectedChargePointOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.chargePoint.SetSmartChargingHandler(smartChargingListener)
+	registry := smartcharging.NewProfileRegistry()
+	suite.centralSystem.SetChargingProfileRegistry(registry)
+	// Run Test
+	suite.centralSystem.Start(8887, "somePath")
+	err := suite.chargePoint.Start(wsUrl)
+	require.Nil(t, err)
+	newProfile := func(id int, stackLevel int) *types.ChargingProfile {
+		schedule := types.NewChargingSchedule(types.ChargingRateUnitAmperes, types.NewChargingSchedulePeriod(0, 16.0))
+		return types.NewChargingProfile(id, stackLevel, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute, schedule)
+	}
+	setProfile := func(connectorId int, profile *types.ChargingProfile) {
+		resultC := make(chan *smartcharging.SetChargingProfileConfirmation, 1)
+		err := suite.centralSystem.SetChargingProfile(wsId, func(confirmation *smartcharging.SetChargingProfileConfirmation, err error) {
+			require.Nil(t, err)
+			resultC <- confirmation
+		}, connectorId, profile)
+		require.Nil(t, err)
+		confirmation := <-resultC
+		require.NotNil(t, confirmation)
+		assert.Equal(t, smartcharging.ChargingProfileStatusAccepted, confirmation.Status)
+	}
+	setProfile(1, newProfile(1, 1))
+	require.Len(t, registry.Profiles(wsId), 1)
+	// Same stack level and purpose on the same connector conflicts
+	conflicts := registry.WouldConflict(wsId, 1, newProfile(2, 1))
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, 1, conflicts[0].ConnectorId)
+	assert.Equal(t, 1, conflicts[0].Profile.ChargingProfileId)
+	// Updates of the same profile, other stack levels, purposes, connectors and charge points don't conflict
+	assert.Empty(t, registry.WouldConflict(wsId, 1, newProfile(1, 1)))
+	assert.Empty(t, registry.WouldConflict(wsId, 1, newProfile(2, 2)))
+	assert.Empty(t, registry.WouldConflict(wsId, 2, newProfile(2, 1)))
+	maxProfile := newProfile(2, 1)
+	maxProfile.ChargingProfilePurpose = types.ChargingProfilePurposeChargePointMaxProfile
+	assert.Empty(t, registry.WouldConflict(wsId, 1, maxProfile))
+	assert.Empty(t, registry.WouldConflict("otherId", 1, newProfile(2, 1)))
+	// Non-conflicting profile is added
+	setProfile(1, newProfile(2, 2))
+	require.Len(t, registry.Profiles(wsId), 2)
+	// A conflicting profile replaces the installed one, as on the charge point
+	setProfile(1, newProfile(3, 1))
+	profiles := registry.Profiles(wsId)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, 2, profiles[0].Profile.ChargingProfileId)
+	assert.Equal(t, 3, profiles[1].Profile.ChargingProfileId)
+	// Cleared profiles are removed
+	resultC := make(chan bool, 1)
+	err = suite.centralSystem.ClearChargingProfile(wsId, func(confirmation *smartcharging.ClearChargingProfileConfirmation, err error) {
+		require.Nil(t, err)
+		resultC <- true
+	}, func(request *smartcharging.ClearChargingProfileRequest) {
+		request.StackLevel = newInt(2)
+	})
+	require.Nil(t, err)
+	<-resultC
+	profiles = registry.Profiles(wsId)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, 3, profiles[0].Profile.ChargingProfileId)
+	assert.Equal(t, 1, registry.Clear(wsId, &smartcharging.ClearChargingProfileRequest{Id: newInt(3)}))
+	assert.Empty(t, registry.Profiles(wsId))
+}
+
 func (suite *OcppV16TestSuite) TestSetChargingProfileInvalidEndpoint() {
 	messageId := defaultMessageId
 	connectorId := 1
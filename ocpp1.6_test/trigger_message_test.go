@@ -84,3 +84,37 @@ func (suite *OcppV16TestSuite) TestTriggerMessageInvalidEndpoint() {
 	requestJson := fmt.Sprintf(`[2,"%v","%v",{"requestedMessage":"%v","connectorId":%v}]`, messageId, remotetrigger.TriggerMessageFeatureName, requestedMessage, connectorId)
 	testUnsupportedRequestFromChargePoint(suite, TriggerMessageRequest, requestJson, messageId)
 }
+
+func (suite *OcppV16TestSuite) TestTriggerMessageRequestedMessageValidation() {
+	t := suite.T()
+	for _, trigger := range []remotetrigger.MessageTrigger{
+		remotetrigger.MessageTriggerBootNotification,
+		remotetrigger.MessageTriggerDiagnosticsStatusNotification,
+		remotetrigger.MessageTriggerFirmwareStatusNotification,
+		remotetrigger.MessageTriggerHeartbeat,
+		remotetrigger.MessageTriggerMeterValues,
+		remotetrigger.MessageTriggerStatusNotification,
+	} {
+		assert.True(t, trigger.IsValid())
+	}
+	assert.True(t, remotetrigger.MessageTriggerMeterValues.SupportsConnectorId())
+	assert.True(t, remotetrigger.MessageTriggerStatusNotification.SupportsConnectorId())
+	assert.False(t, remotetrigger.MessageTriggerHeartbeat.SupportsConnectorId())
+	// Invalid triggers are rejected before sending
+	wsId := "test_id"
+	callback := func(confirmation *remotetrigger.TriggerMessageConfirmation, err error) {
+		t.Fatal("callback should not be invoked")
+	}
+	for _, trigger := range []remotetrigger.MessageTrigger{core.StartTransactionFeatureName, core.AuthorizeFeatureName, "invalidTrigger", ""} {
+		assert.False(t, trigger.IsValid())
+		err := suite.centralSystem.TriggerMessage(wsId, callback, trigger)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("cannot trigger %v message", trigger))
+	}
+	// ConnectorId is rejected for messages not referring to a connector
+	err := suite.centralSystem.TriggerMessage(wsId, callback, remotetrigger.MessageTriggerHeartbeat, func(request *remotetrigger.TriggerMessageRequest) {
+		request.ConnectorId = newInt(1)
+	})
+	require.Error(t, err)
+	assert.Equal(t, "cannot trigger Heartbeat message for connector 1, connectorId is only applicable to MeterValues and StatusNotification messages", err.Error())
+}
@@ -0,0 +1,162 @@
+package ocpp2
+
+import (
+	"fmt"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// AtomicVariableResult is the outcome of setting a single variable via SetVariablesAtomic.
+type AtomicVariableResult struct {
+	Component     types.Component
+	Variable      types.Variable
+	AttributeType types.Attribute
+	PreviousValue string                         // The value read before writing.
+	SetStatus     provisioning.SetVariableStatus // The status returned for the write. Empty, if the write wasn't answered.
+	Verified      bool                           // True, if the written value was read back successfully.
+	RestoreStatus provisioning.SetVariableStatus // The status returned when restoring the previous value. Empty, if the value wasn't restored.
+}
+
+// Returns true, if the write was applied by the charging station.
+func (r AtomicVariableResult) applied() bool {
+	return r.SetStatus == provisioning.SetVariableStatusAccepted || r.SetStatus == provisioning.SetVariableStatusRebootRequired
+}
+
+// AtomicSetVariablesResult is the outcome of SetVariablesAtomic.
+type AtomicSetVariablesResult struct {
+	ChargingStationID string
+	Variables         []AtomicVariableResult // One entry per requested variable, in the order of the request.
+	Committed         bool                   // True, if all variables were set successfully.
+	RolledBack        bool                   // True, if a rollback was necessary and all previous values were restored successfully, or no value had to be restored.
+}
+
+// Returns true, if the result of a variable refers to the given component, variable and attribute type.
+func (r AtomicVariableResult) matches(component types.Component, variable types.Variable, attributeType types.Attribute) bool {
+	data := provisioning.ReportData{Component: r.Component, Variable: r.Variable}
+	return data.MatchesVariable(component, variable) && provisioning.NormalizeAttributeType(r.AttributeType) == provisioning.NormalizeAttributeType(attributeType)
+}
+
+func (cs *csms) SetVariablesAtomic(clientId string, callback func(result *AtomicSetVariablesResult, err error), data []provisioning.SetVariableData) error {
+	if len(data) == 0 {
+		return fmt.Errorf("no variables to set")
+	}
+	result := &AtomicSetVariablesResult{ChargingStationID: clientId}
+	getData := make([]provisioning.GetVariableData, len(data))
+	for i, d := range data {
+		result.Variables = append(result.Variables, AtomicVariableResult{Component: d.Component, Variable: d.Variable, AttributeType: d.AttributeType})
+		getData[i] = provisioning.GetVariableData{Component: d.Component, Variable: d.Variable, AttributeType: d.AttributeType}
+	}
+	// Restores the previous values of all variables, whose write was applied, then reports the result
+	rollback := func(cause error) {
+		var restoreData []provisioning.SetVariableData
+		for _, v := range result.Variables {
+			// If the write wasn't answered, it may still have been applied
+			if v.applied() || v.SetStatus == "" {
+				restoreData = append(restoreData, provisioning.SetVariableData{Component: v.Component, Variable: v.Variable, AttributeType: v.AttributeType, AttributeValue: v.PreviousValue})
+			}
+		}
+		if len(restoreData) == 0 {
+			// No write was applied, hence there is nothing to restore
+			result.RolledBack = true
+			callback(result, cause)
+			return
+		}
+		err := cs.SetVariables(clientId, func(response *provisioning.SetVariablesResponse, err error) {
+			if err != nil {
+				callback(result, fmt.Errorf("rollback failed: %w", err))
+				return
+			}
+			result.RolledBack = true
+			for i := range result.Variables {
+				v := &result.Variables[i]
+				for _, r := range response.SetVariableResult {
+					if v.matches(r.Component, r.Variable, r.AttributeType) {
+						v.RestoreStatus = r.AttributeStatus
+					}
+				}
+				if (v.applied() || v.SetStatus == "") && v.RestoreStatus != provisioning.SetVariableStatusAccepted && v.RestoreStatus != provisioning.SetVariableStatusRebootRequired {
+					result.RolledBack = false
+				}
+			}
+			callback(result, cause)
+		}, restoreData)
+		if err != nil {
+			callback(result, fmt.Errorf("rollback failed: %w", err))
+		}
+	}
+	// Reads back all values, which were accepted by the charging station
+	verify := func() {
+		err := cs.GetVariables(clientId, func(response *provisioning.GetVariablesResponse, err error) {
+			if err != nil {
+				rollback(err)
+				return
+			}
+			committed := true
+			for i := range result.Variables {
+				v := &result.Variables[i]
+				if v.SetStatus == provisioning.SetVariableStatusAccepted {
+					for _, r := range response.GetVariableResult {
+						if v.matches(r.Component, r.Variable, r.AttributeType) {
+							v.Verified = r.AttributeStatus == provisioning.GetVariableStatusAccepted && r.AttributeValue == data[i].AttributeValue
+						}
+					}
+					committed = committed && v.Verified
+				} else if !v.applied() {
+					committed = false
+				}
+			}
+			if !committed {
+				rollback(nil)
+				return
+			}
+			result.Committed = true
+			callback(result, nil)
+		}, getData)
+		if err != nil {
+			rollback(err)
+		}
+	}
+	write := func() {
+		err := cs.SetVariables(clientId, func(response *provisioning.SetVariablesResponse, err error) {
+			if err != nil {
+				rollback(err)
+				return
+			}
+			for i := range result.Variables {
+				v := &result.Variables[i]
+				for _, r := range response.SetVariableResult {
+					if v.matches(r.Component, r.Variable, r.AttributeType) {
+						v.SetStatus = r.AttributeStatus
+					}
+				}
+			}
+			verify()
+		}, data)
+		if err != nil {
+			callback(result, err)
+		}
+	}
+	// Read the previous values first, as a rollback is impossible without them
+	return cs.GetVariables(clientId, func(response *provisioning.GetVariablesResponse, err error) {
+		if err != nil {
+			callback(result, err)
+			return
+		}
+		for i := range result.Variables {
+			v := &result.Variables[i]
+			found := false
+			for _, r := range response.GetVariableResult {
+				if v.matches(r.Component, r.Variable, r.AttributeType) && r.AttributeStatus == provisioning.GetVariableStatusAccepted {
+					v.PreviousValue = r.AttributeValue
+					found = true
+				}
+			}
+			if !found {
+				callback(result, fmt.Errorf("couldn't read previous value of %v.%v, no variables were set", v.Component.Name, v.Variable.Name))
+				return
+			}
+		}
+		write()
+	}, getData)
+}
@@ -13,16 +13,18 @@ import (
 const AuthorizeFeatureName = "Authorize"
 
 // The Certificate status information.
+// It is returned by the CSMS when authorizing via an ISO 15118 contract certificate (Plug & Charge),
+// i.e. if the AuthorizeRequest contained a certificate or iso15118CertificateHashData.
 type AuthorizeCertificateStatus string
 
 const (
-	CertificateStatusAccepted               AuthorizeCertificateStatus = "Accepted"
-	CertificateStatusSignatureError         AuthorizeCertificateStatus = "SignatureError"
-	CertificateStatusCertificateExpired     AuthorizeCertificateStatus = "CertificateExpired"
-	CertificateStatusCertificateRevoked     AuthorizeCertificateStatus = "CertificateRevoked"
-	CertificateStatusNoCertificateAvailable AuthorizeCertificateStatus = "NoCertificateAvailable"
-	CertificateStatusCertChainError         AuthorizeCertificateStatus = "CertChainError"
-	CertificateStatusContractCancelled      AuthorizeCertificateStatus = "ContractCancelled"
+	CertificateStatusAccepted               AuthorizeCertificateStatus = "Accepted"               // The contract certificate is valid.
+	CertificateStatusSignatureError         AuthorizeCertificateStatus = "SignatureError"         // The signature of the contract certificate could not be verified.
+	CertificateStatusCertificateExpired     AuthorizeCertificateStatus = "CertificateExpired"     // The contract certificate has expired.
+	CertificateStatusCertificateRevoked     AuthorizeCertificateStatus = "CertificateRevoked"     // The contract certificate was revoked.
+	CertificateStatusNoCertificateAvailable AuthorizeCertificateStatus = "NoCertificateAvailable" // No certificate could be found, e.g. the OCSP responder is unknown.
+	CertificateStatusCertChainError         AuthorizeCertificateStatus = "CertChainError"         // The certificate chain could not be verified up to a trusted root.
+	CertificateStatusContractCancelled      AuthorizeCertificateStatus = "ContractCancelled"      // The contract related to the certificate was cancelled.
 )
 
 func isValidAuthorizeCertificateStatus(fl validator.FieldLevel) bool {
@@ -42,6 +44,14 @@ type AuthorizeRequest struct {
 	CertificateHashData []types.OCSPRequestDataType `json:"iso15118CertificateHashData,omitempty" validate:"max=4,dive"`
 }
 
+// Returns true, if the request contains contract certificate information for ISO 15118 Plug & Charge authorization,
+// either as a full certificate chain or as OCSP request data of the contract certificate chain.
+//
+// In this case, the CSMS should also validate the certificate and return the result via the certificateStatus of the response.
+func (r AuthorizeRequest) HasContractCertificate() bool {
+	return r.Certificate != "" || len(r.CertificateHashData) > 0
+}
+
 // This field definition of the Authorize response payload, sent by the Charging Station to the CSMS in response to an AuthorizeRequest.
 // In case the request was invalid, or couldn't be processed, an error will be sent instead.
 type AuthorizeResponse struct {
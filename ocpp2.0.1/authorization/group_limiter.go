@@ -0,0 +1,133 @@
+package authorization
+
+import (
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Identifies a group, as group ID tokens are only unique per type.
+type groupKey struct {
+	idToken   string
+	tokenType types.IdTokenType
+}
+
+func newGroupKey(group *types.GroupIdToken) groupKey {
+	return groupKey{idToken: group.IdToken, tokenType: group.Type}
+}
+
+// Identifies a transaction, as transaction IDs are only unique per charging station.
+type groupTransactionKey struct {
+	chargingStationID string
+	transactionID     string
+}
+
+// GroupLimiter enforces a maximum number of concurrent transactions per group ID token on the CSMS side,
+// e.g. to limit the number of vehicles of a fleet charging at the same time.
+//
+// The limiter is meant to be invoked from the CSMS handlers: Check when responding to an Authorize request,
+// Start when accepting the first TransactionEvent of a transaction and End once the transaction ended.
+// If a group reached its limit, the status of the returned IdTokenInfo is set to ConcurrentTx.
+//
+// A GroupLimiter is safe for concurrent use. Use NewGroupLimiter to create one.
+type GroupLimiter struct {
+	mutex        sync.Mutex
+	defaultLimit int
+	limits       map[groupKey]int
+	active       map[groupKey]map[groupTransactionKey]struct{}
+	groups       map[groupTransactionKey]groupKey
+}
+
+// NewGroupLimiter creates a limiter, which applies the default limit to every group without an explicit limit.
+// A limit lower than 1 means unlimited.
+func NewGroupLimiter(defaultLimit int) *GroupLimiter {
+	return &GroupLimiter{
+		defaultLimit: defaultLimit,
+		limits:       map[groupKey]int{},
+		active:       map[groupKey]map[groupTransactionKey]struct{}{},
+		groups:       map[groupTransactionKey]groupKey{},
+	}
+}
+
+// SetLimit sets the maximum number of concurrent transactions of a group. A limit lower than 1 means unlimited.
+func (l *GroupLimiter) SetLimit(group types.GroupIdToken, limit int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limits[newGroupKey(&group)] = limit
+}
+
+// Active returns the number of active transactions of a group.
+func (l *GroupLimiter) Active(group types.GroupIdToken) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.active[newGroupKey(&group)])
+}
+
+// Check sets the status of an accepted IdTokenInfo to ConcurrentTx, if its group already reached the limit.
+// Returns false, if the status was changed. IdTokenInfo without group are not affected.
+func (l *GroupLimiter) Check(info *types.IdTokenInfo) bool {
+	if info == nil || info.GroupIdToken == nil || info.Status != types.AuthorizationStatusAccepted {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	key := newGroupKey(info.GroupIdToken)
+	if l.reachedLimit(key) {
+		info.Status = types.AuthorizationStatusConcurrentTx
+		return false
+	}
+	return true
+}
+
+// Start records a transaction for the group of an accepted IdTokenInfo.
+// If the group already reached the limit, the transaction is not recorded and the status is set to ConcurrentTx.
+// Returns false, if the status was changed.
+//
+// Starting an already recorded transaction has no effect.
+func (l *GroupLimiter) Start(chargingStationID string, transactionID string, info *types.IdTokenInfo) bool {
+	if info == nil || info.GroupIdToken == nil || info.Status != types.AuthorizationStatusAccepted {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	txKey := groupTransactionKey{chargingStationID: chargingStationID, transactionID: transactionID}
+	if _, ok := l.groups[txKey]; ok {
+		return true
+	}
+	key := newGroupKey(info.GroupIdToken)
+	if l.reachedLimit(key) {
+		info.Status = types.AuthorizationStatusConcurrentTx
+		return false
+	}
+	if l.active[key] == nil {
+		l.active[key] = map[groupTransactionKey]struct{}{}
+	}
+	l.active[key][txKey] = struct{}{}
+	l.groups[txKey] = key
+	return true
+}
+
+// End releases a transaction previously recorded via Start. Ending an unknown transaction has no effect.
+func (l *GroupLimiter) End(chargingStationID string, transactionID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	txKey := groupTransactionKey{chargingStationID: chargingStationID, transactionID: transactionID}
+	key, ok := l.groups[txKey]
+	if !ok {
+		return
+	}
+	delete(l.groups, txKey)
+	delete(l.active[key], txKey)
+	if len(l.active[key]) == 0 {
+		delete(l.active, key)
+	}
+}
+
+// Must be invoked while holding the mutex.
+func (l *GroupLimiter) reachedLimit(key groupKey) bool {
+	limit, ok := l.limits[key]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	return limit > 0 && len(l.active[key]) >= limit
+}
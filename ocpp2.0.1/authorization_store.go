@@ -0,0 +1,93 @@
+package ocpp2
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/authorization"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// AuthorizationStore abstracts an external database of idTokens and charging sessions, e.g. a billing backend.
+// Instead of implementing the authorization and transactions handlers, integrators may implement this interface
+// and register an AuthorizationStoreHandler, which delegates to the store.
+//
+// All methods may be invoked concurrently, for different charging stations and transactions.
+type AuthorizationStore interface {
+	// Authorize returns the authorization status of an idToken, presented on the given charging station.
+	Authorize(ctx context.Context, chargingStationID string, idToken types.IdToken) (types.IdTokenInfo, error)
+	// BeginTransaction records the start of a transaction, as reported by a TransactionEvent of type Started.
+	BeginTransaction(ctx context.Context, chargingStationID string, event *transactions.TransactionEventRequest) error
+	// EndTransaction records the end of a transaction, as reported by a TransactionEvent of type Ended.
+	EndTransaction(ctx context.Context, chargingStationID string, event *transactions.TransactionEventRequest) error
+}
+
+// AuthorizationStoreHandler is an authorization.CSMSHandler and a transactions.CSMSHandler, which delegates to an AuthorizationStore.
+// It may be registered on a CSMS via:
+//
+//	handler := ocpp2.NewAuthorizationStoreHandler(store, 5*time.Second)
+//	csms.SetAuthorizationHandler(handler)
+//	csms.SetTransactionsHandler(handler)
+//
+// Incoming messages are processed as follows:
+//   - AuthorizeRequest: the idToken is authorized via the store
+//   - TransactionEventRequest: the idToken is authorized via the store, if the event contains one.
+//     Started events begin a transaction in the store, while Ended events end it. Updated events aren't forwarded.
+//
+// If the store returns an error, an InternalError is sent to the charging station.
+type AuthorizationStoreHandler struct {
+	store   AuthorizationStore
+	timeout time.Duration
+}
+
+// NewAuthorizationStoreHandler creates a handler delegating to the given store.
+// The context passed to the store is canceled after the timeout elapsed. A timeout of zero or less disables the timeout.
+func NewAuthorizationStoreHandler(store AuthorizationStore, timeout time.Duration) *AuthorizationStoreHandler {
+	if store == nil {
+		panic("store must not be nil")
+	}
+	return &AuthorizationStoreHandler{store: store, timeout: timeout}
+}
+
+func (h *AuthorizationStoreHandler) context() (context.Context, context.CancelFunc) {
+	if h.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), h.timeout)
+}
+
+func (h *AuthorizationStoreHandler) OnAuthorize(chargingStationID string, request *authorization.AuthorizeRequest) (*authorization.AuthorizeResponse, error) {
+	ctx, cancel := h.context()
+	defer cancel()
+	info, err := h.store.Authorize(ctx, chargingStationID, request.IdToken)
+	if err != nil {
+		return nil, fmt.Errorf("authorizing idToken on %s: %w", chargingStationID, err)
+	}
+	return authorization.NewAuthorizationResponse(info), nil
+}
+
+func (h *AuthorizationStoreHandler) OnTransactionEvent(chargingStationID string, request *transactions.TransactionEventRequest) (*transactions.TransactionEventResponse, error) {
+	ctx, cancel := h.context()
+	defer cancel()
+	response := transactions.NewTransactionEventResponse()
+	if request.IDToken != nil {
+		info, err := h.store.Authorize(ctx, chargingStationID, *request.IDToken)
+		if err != nil {
+			return nil, fmt.Errorf("authorizing idToken for transaction %s on %s: %w", request.TransactionInfo.TransactionID, chargingStationID, err)
+		}
+		response.IDTokenInfo = &info
+	}
+	var err error
+	switch request.EventType {
+	case transactions.TransactionEventStarted:
+		err = h.store.BeginTransaction(ctx, chargingStationID, request)
+	case transactions.TransactionEventEnded:
+		err = h.store.EndTransaction(ctx, chargingStationID, request)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("recording %v event of transaction %s on %s: %w", request.EventType, request.TransactionInfo.TransactionID, chargingStationID, err)
+	}
+	return response, nil
+}
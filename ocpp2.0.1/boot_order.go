@@ -0,0 +1,51 @@
+package ocpp2
+
+import "sync"
+
+// BootOrderPolicy defines how the CSMS treats requests, which a charging station sends before its BootNotification
+// was accepted. See CSMS.SetBootOrderPolicy.
+type BootOrderPolicy int
+
+const (
+	// All requests are processed, regardless of whether a BootNotification was accepted (default).
+	BootOrderAllow BootOrderPolicy = iota
+	// All requests are processed, but every request received before the BootNotification was accepted is reported via the Errors channel.
+	BootOrderWarnOnly
+	// Requests received before the BootNotification was accepted are rejected with a SecurityError, without invoking any handler.
+	BootOrderRequireBootFirst
+)
+
+// bootedStations keeps track of the charging stations, whose most recent BootNotification was accepted.
+// The state is cleared when a charging station disconnects, hence every new connection must boot again.
+type bootedStations struct {
+	mutex  sync.RWMutex
+	booted map[string]bool
+}
+
+func newBootedStations() *bootedStations {
+	return &bootedStations{booted: map[string]bool{}}
+}
+
+// Records the registration status sent in response to a BootNotification of a charging station.
+func (b *bootedStations) set(chargingStationID string, accepted bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if accepted {
+		b.booted[chargingStationID] = true
+	} else {
+		delete(b.booted, chargingStationID)
+	}
+}
+
+// Clears the boot state of a charging station, e.g. after it disconnected.
+func (b *bootedStations) remove(chargingStationID string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.booted, chargingStationID)
+}
+
+func (b *bootedStations) isBooted(chargingStationID string) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.booted[chargingStationID]
+}
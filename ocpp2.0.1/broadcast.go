@@ -0,0 +1,58 @@
+package ocpp2
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// BroadcastOptions configures the pacing of a request broadcast via Broadcast.
+type BroadcastOptions struct {
+	// Maximum amount of in-flight requests. Further requests are sent once a response for a previous one was received.
+	// If not positive, a default concurrency of 20 is used.
+	Concurrency int
+	// Minimum time between sending two consecutive requests. Zero disables rate limiting.
+	Interval time.Duration
+}
+
+func (cs *csms) Broadcast(filter func(stationID string) bool, build func(stationID string) ocpp.Request, opts BroadcastOptions, onResult func(stationID string, response ocpp.Response, err error)) []string {
+	var stationIDs []string
+	for _, id := range cs.connectedChargingStationIDs() {
+		if filter == nil || filter(id) {
+			stationIDs = append(stationIDs, id)
+		}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = bulkRequestConcurrency
+	}
+	go func() {
+		// Each in-flight request holds a slot until its callback is invoked
+		slots := make(chan struct{}, concurrency)
+		var lastSent time.Time
+		for _, id := range stationIDs {
+			stationID := id
+			slots <- struct{}{}
+			if wait := opts.Interval - time.Since(lastSent); opts.Interval > 0 && wait > 0 {
+				time.Sleep(wait)
+			}
+			lastSent = time.Now()
+			request := build(stationID)
+			if request == nil {
+				<-slots
+				onResult(stationID, nil, fmt.Errorf("no request built for charging station %v", stationID))
+				continue
+			}
+			err := cs.SendRequestAsync(stationID, request, func(response ocpp.Response, err error) {
+				<-slots
+				onResult(stationID, response, err)
+			})
+			if err != nil {
+				<-slots
+				onResult(stationID, nil, err)
+			}
+		}
+	}()
+	return stationIDs
+}
@@ -0,0 +1,229 @@
+package ocpp2
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/smartcharging"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Names of the standardized components and variables, from which StationCapabilities are populated.
+const (
+	componentChargingStation    = "ChargingStation"
+	componentEVSE               = "EVSE"
+	componentSmartChargingCtrlr = "SmartChargingCtrlr"
+	variablePower               = "Power"        // The maxLimit contains the maximum power in W.
+	variableCurrent             = "Current"      // The maxLimit contains the maximum current in A.
+	variableSupplyPhases        = "SupplyPhases" // The actual value contains the number of phases.
+	instanceChargingProfiles    = "ChargingProfiles"
+)
+
+// ElectricalCapabilities describes the electrical limits of a charging station or one of its EVSEs.
+// Limits that weren't reported are nil.
+type ElectricalCapabilities struct {
+	MaxCurrent *float64 // Maximum current in A.
+	MaxPower   *float64 // Maximum power in W.
+	Phases     *int     // Number of phases the charging station or EVSE is supplied with.
+}
+
+// StationCapabilities contains the capabilities of a charging station, as learned from the NotifyReport requests it sent.
+//
+// Electrical capabilities are read from the ChargingStation and EVSE components:
+//   - the maxLimit of the Power variable
+//   - the maxLimit of the Current variable
+//   - the actual value of the SupplyPhases variable
+//
+// Smart charging limits are read from the SmartChargingCtrlr component, see smartcharging.NewChargingProfileLimits.
+// Values that cannot be parsed are ignored.
+type StationCapabilities struct {
+	ChargingStationID string
+	Station           ElectricalCapabilities              // Capabilities of the charging station as a whole.
+	EVSEs             map[int]ElectricalCapabilities      // Capabilities of the single EVSEs, keyed by EVSE ID.
+	ProfileLimits     smartcharging.ChargingProfileLimits // Smart charging limits, which apply to the whole charging station.
+}
+
+// LimitsFor returns the limits for a charging profile installed on the given EVSE.
+// Electrical limits not reported for the EVSE are taken from the charging station as a whole. An EVSE ID of 0 refers to the charging station.
+func (c StationCapabilities) LimitsFor(evseID int) smartcharging.ChargingProfileLimits {
+	limits := c.ProfileLimits
+	electrical := c.Station
+	if evse, ok := c.EVSEs[evseID]; ok && evseID > 0 {
+		if evse.MaxCurrent != nil {
+			electrical.MaxCurrent = evse.MaxCurrent
+		}
+		if evse.MaxPower != nil {
+			electrical.MaxPower = evse.MaxPower
+		}
+		if evse.Phases != nil {
+			electrical.Phases = evse.Phases
+		}
+	}
+	limits.MaxCurrent = electrical.MaxCurrent
+	limits.MaxPower = electrical.MaxPower
+	limits.MaxPhases = electrical.Phases
+	return limits
+}
+
+// Returns a copy, which may be modified without affecting the cached capabilities.
+// Pointers are never modified in place by the cache, hence they don't need to be copied.
+func (c StationCapabilities) clone() StationCapabilities {
+	clone := c
+	clone.EVSEs = make(map[int]ElectricalCapabilities, len(c.EVSEs))
+	for id, evse := range c.EVSEs {
+		clone.EVSEs[id] = evse
+	}
+	clone.ProfileLimits.RateUnits = append([]types.ChargingRateUnitType(nil), c.ProfileLimits.RateUnits...)
+	return clone
+}
+
+// capabilitiesCache holds the StationCapabilities of all charging stations, which reported at least one relevant variable.
+// Capabilities are kept when a charging station disconnects, since they are unlikely to change.
+type capabilitiesCache struct {
+	mutex    sync.RWMutex
+	stations map[string]*StationCapabilities
+}
+
+func newCapabilitiesCache() *capabilitiesCache {
+	return &capabilitiesCache{stations: map[string]*StationCapabilities{}}
+}
+
+func (c *capabilitiesCache) get(chargingStationID string) (StationCapabilities, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	capabilities, ok := c.stations[chargingStationID]
+	if !ok {
+		return StationCapabilities{}, false
+	}
+	return capabilities.clone(), true
+}
+
+// Updates the capabilities of a charging station with the variables contained in a (partial) report.
+func (c *capabilitiesCache) update(chargingStationID string, reportData []provisioning.ReportData) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, data := range reportData {
+		capabilities, ok := c.stations[chargingStationID]
+		if !ok {
+			capabilities = &StationCapabilities{ChargingStationID: chargingStationID, EVSEs: map[int]ElectricalCapabilities{}}
+		}
+		if !capabilities.apply(data) {
+			continue
+		}
+		c.stations[chargingStationID] = capabilities
+	}
+}
+
+// Applies a single reported variable. Returns false, if the variable doesn't describe a capability.
+func (c *StationCapabilities) apply(data provisioning.ReportData) bool {
+	component := data.Component
+	switch {
+	case strings.EqualFold(component.Name, componentChargingStation) && (component.EVSE == nil || component.EVSE.ID == 0):
+		return applyElectrical(&c.Station, data)
+	case strings.EqualFold(component.Name, componentEVSE) && component.EVSE != nil && component.EVSE.ID > 0 && component.EVSE.ConnectorID == nil:
+		evse := c.EVSEs[component.EVSE.ID]
+		if !applyElectrical(&evse, data) {
+			return false
+		}
+		c.EVSEs[component.EVSE.ID] = evse
+		return true
+	case strings.EqualFold(component.Name, componentSmartChargingCtrlr):
+		return applySmartCharging(&c.ProfileLimits, data)
+	}
+	return false
+}
+
+func applyElectrical(capabilities *ElectricalCapabilities, data provisioning.ReportData) bool {
+	switch {
+	case strings.EqualFold(data.Variable.Name, variablePower):
+		if maxLimit := reportedMaxLimit(data); maxLimit != nil {
+			capabilities.MaxPower = maxLimit
+			return true
+		}
+	case strings.EqualFold(data.Variable.Name, variableCurrent):
+		if maxLimit := reportedMaxLimit(data); maxLimit != nil {
+			capabilities.MaxCurrent = maxLimit
+			return true
+		}
+	case strings.EqualFold(data.Variable.Name, variableSupplyPhases):
+		if phases := reportedInt(data); phases != nil {
+			capabilities.Phases = phases
+			return true
+		}
+	}
+	return false
+}
+
+func applySmartCharging(limits *smartcharging.ChargingProfileLimits, data provisioning.ReportData) bool {
+	name := data.Variable.Name
+	switch {
+	case strings.EqualFold(name, smartcharging.VariablePeriodsPerSchedule):
+		if value := reportedInt(data); value != nil {
+			limits.PeriodsPerSchedule = value
+			return true
+		}
+	case strings.EqualFold(name, smartcharging.VariableProfileStackLevel):
+		if value := reportedInt(data); value != nil {
+			limits.ProfileStackLevel = value
+			return true
+		}
+	case strings.EqualFold(name, smartcharging.VariableEntries) && strings.EqualFold(data.Variable.Instance, instanceChargingProfiles):
+		updated := false
+		if value := reportedInt(data); value != nil {
+			limits.InstalledProfiles = *value
+			updated = true
+		}
+		if maxLimit := reportedMaxLimit(data); maxLimit != nil {
+			maxProfiles := int(*maxLimit)
+			limits.MaxProfiles = &maxProfiles
+			updated = true
+		}
+		return updated
+	case strings.EqualFold(name, smartcharging.VariableRateUnit):
+		attribute, ok := data.Attribute(types.AttributeActual)
+		if !ok {
+			return false
+		}
+		parsed, err := smartcharging.NewChargingProfileLimits(map[string]string{smartcharging.VariableRateUnit: attribute.Value})
+		if err != nil {
+			return false
+		}
+		limits.RateUnits = parsed.RateUnits
+		return true
+	}
+	return false
+}
+
+func reportedMaxLimit(data provisioning.ReportData) *float64 {
+	if data.VariableCharacteristics == nil || data.VariableCharacteristics.MaxLimit == nil {
+		return nil
+	}
+	maxLimit := *data.VariableCharacteristics.MaxLimit
+	return &maxLimit
+}
+
+func reportedInt(data provisioning.ReportData) *int {
+	attribute, ok := data.Attribute(types.AttributeActual)
+	if !ok {
+		return nil
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(attribute.Value))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+func (cs *csms) Capabilities(stationID string) (StationCapabilities, bool) {
+	return cs.capabilities.get(stationID)
+}
+
+func (cs *csms) ValidateChargingProfile(stationID string, evseID int, profile *types.ChargingProfile) error {
+	capabilities, ok := cs.capabilities.get(stationID)
+	if !ok {
+		return nil
+	}
+	return smartcharging.ValidateProfileAgainstLimits(profile, capabilities.LimitsFor(evseID))
+}
@@ -0,0 +1,57 @@
+package ocpp2
+
+import (
+	"fmt"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/smartcharging"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// ChargingNeedsScheduler computes a charging profile, which accommodates the charging needs of an EV. See CSMS.ProcessChargingNeeds.
+//
+// The transactionID refers to the active transaction on the EVSE of the request, or is empty if unknown.
+// Returning an error aborts the processing, without sending any profile to the charging station.
+type ChargingNeedsScheduler func(chargingStationID string, request *smartcharging.NotifyEVChargingNeedsRequest, transactionID string) (*types.ChargingProfile, error)
+
+// Returns the ID of the most recently started transaction on an EVSE, if transaction tracking is enabled.
+func (cs *csms) evseTransactionID(chargingStationID string, evseID int) string {
+	transactionID := ""
+	tracker := cs.currentFeatures().transactionTracker
+	if tracker == nil {
+		return transactionID
+	}
+	for _, info := range tracker.activeTransactions(chargingStationID) {
+		if info.Evse != nil && info.Evse.ID == evseID {
+			transactionID = info.TransactionID
+		}
+	}
+	return transactionID
+}
+
+func (cs *csms) ProcessChargingNeeds(chargingStationID string, request *smartcharging.NotifyEVChargingNeedsRequest, scheduler ChargingNeedsScheduler, callback func(*smartcharging.SetChargingProfileResponse, error)) *smartcharging.NotifyEVChargingNeedsResponse {
+	transactionID := cs.evseTransactionID(chargingStationID, request.EvseID)
+	schedule := func() {
+		profile, err := scheduler(chargingStationID, request, transactionID)
+		if err == nil && profile == nil {
+			err = fmt.Errorf("no charging profile computed for EVSE %d of %s", request.EvseID, chargingStationID)
+		}
+		if err == nil {
+			if profile.ChargingProfilePurpose == types.ChargingProfilePurposeTxProfile && profile.TransactionID == "" {
+				profile.TransactionID = transactionID
+			}
+			err = cs.SetChargingProfile(chargingStationID, callback, request.EvseID, profile)
+		}
+		if err != nil {
+			callback(nil, err)
+		}
+	}
+	// The profile is only computed once the charging station received the Processing response
+	cs.responseHooks.add(request, func(sent bool) {
+		if !sent {
+			callback(nil, fmt.Errorf("NotifyEVChargingNeeds response wasn't sent to %s, charging needs of EVSE %d not processed", chargingStationID, request.EvseID))
+			return
+		}
+		go schedule()
+	})
+	return smartcharging.NewNotifyEVChargingNeedsResponse(smartcharging.EVChargingNeedsStatusProcessing)
+}
@@ -1,8 +1,11 @@
 package ocpp2
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/internal/callbackqueue"
 	"github.com/lorenzodonini/ocpp-go/ocpp"
@@ -23,6 +26,8 @@ import (
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/tariffcost"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/batteryswap"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/der"
 	"github.com/lorenzodonini/ocpp-go/ocppj"
 )
 
@@ -44,13 +49,27 @@ type chargingStation struct {
 	diagnosticsHandler   diagnostics.ChargingStationHandler
 	displayHandler       display.ChargingStationHandler
 	dataHandler          data.ChargingStationHandler
+	batterySwapHandler   batteryswap.ChargingStationHandler
+	derControlHandler    der.ChargingStationHandler
 	responseHandler      chan ocpp.Response
 	errorHandler         chan error
 	callbacks            callbackqueue.CallbackQueue
 	stopC                chan struct{}
 	errC                 chan error // external error channel
+	// Reconnect handling
+	reconnectedHandler func()
+	queueFlushFilter   ocppj.QueueFlushFilter
+	// Boot interval handling
+	autoApplyBootInterval bool
+	bootRetryHandler      func(response *provisioning.BootNotificationResponse, err error)
+	bootIntervalMutex     sync.Mutex
+	bootIntervalTimer     *time.Timer
+	bootIntervalStopped   bool
 }
 
+// Interval used for re-sending a BootNotification, if the CSMS didn't provide an interval in a Pending/Rejected response.
+const defaultBootRetryInterval = 60 * time.Second
+
 func (cs *chargingStation) error(err error) {
 	if cs.errC != nil {
 		cs.errC <- err
@@ -76,12 +95,111 @@ func (cs *chargingStation) BootNotification(reason provisioning.BootReason, mode
 	for _, fn := range props {
 		fn(request)
 	}
+	return cs.sendBootNotification(request)
+}
+
+func (cs *chargingStation) sendBootNotification(request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
 	response, err := cs.SendRequest(request)
 	if err != nil {
 		return nil, err
-	} else {
-		return response.(*provisioning.BootNotificationResponse), err
 	}
+	bootResponse := response.(*provisioning.BootNotificationResponse)
+	cs.bootIntervalMutex.Lock()
+	autoApply := cs.autoApplyBootInterval
+	cs.bootIntervalMutex.Unlock()
+	if autoApply {
+		cs.applyBootInterval(request, bootResponse)
+	}
+	return bootResponse, nil
+}
+
+// Applies the interval contained in a BootNotificationResponse.
+// Accepted stations start sending periodic heartbeats, while pending/rejected stations re-send the original request.
+// Any previously scheduled heartbeat or retry is canceled.
+func (cs *chargingStation) applyBootInterval(request *provisioning.BootNotificationRequest, response *provisioning.BootNotificationResponse) {
+	cs.bootIntervalMutex.Lock()
+	defer cs.bootIntervalMutex.Unlock()
+	cs.cancelBootInterval()
+	if cs.bootIntervalStopped {
+		return
+	}
+	interval := time.Duration(response.Interval) * time.Second
+	switch response.Status {
+	case provisioning.RegistrationStatusAccepted:
+		// An interval of 0 means the charging station may choose its own heartbeat interval
+		if interval > 0 {
+			cs.scheduleHeartbeat(interval)
+		}
+	case provisioning.RegistrationStatusPending, provisioning.RegistrationStatusRejected:
+		if interval <= 0 {
+			interval = defaultBootRetryInterval
+		}
+		cs.scheduleBootInterval(interval, func() {
+			response, err := cs.sendBootNotification(request)
+			cs.bootIntervalMutex.Lock()
+			retryHandler := cs.bootRetryHandler
+			cs.bootIntervalMutex.Unlock()
+			if retryHandler != nil {
+				retryHandler(response, err)
+			} else if err != nil {
+				cs.error(fmt.Errorf("retrying boot notification failed: %w", err))
+			}
+		})
+	}
+}
+
+// Schedules the next automatic heartbeat. Once the heartbeat was sent, the following one is scheduled.
+// Must be called while holding bootIntervalMutex.
+func (cs *chargingStation) scheduleHeartbeat(interval time.Duration) {
+	var timer *time.Timer
+	timer = cs.scheduleBootInterval(interval, func() {
+		if _, err := cs.Heartbeat(); err != nil {
+			cs.error(fmt.Errorf("sending automatic heartbeat failed: %w", err))
+		}
+		cs.bootIntervalMutex.Lock()
+		defer cs.bootIntervalMutex.Unlock()
+		// The heartbeat may have been canceled while it was being sent
+		if cs.bootIntervalTimer == timer && !cs.bootIntervalStopped {
+			cs.scheduleHeartbeat(interval)
+		}
+	})
+}
+
+// Schedules fn to be invoked after the interval, replacing any previously scheduled heartbeat or retry.
+// The function is skipped, if it was canceled or the charging station was stopped in the meantime.
+// Must be called while holding bootIntervalMutex.
+func (cs *chargingStation) scheduleBootInterval(interval time.Duration, fn func()) *time.Timer {
+	var timer *time.Timer
+	timer = time.AfterFunc(interval, func() {
+		cs.bootIntervalMutex.Lock()
+		current := cs.bootIntervalTimer == timer && !cs.bootIntervalStopped
+		cs.bootIntervalMutex.Unlock()
+		if current {
+			fn()
+		}
+	})
+	cs.bootIntervalTimer = timer
+	return timer
+}
+
+// Stops any scheduled heartbeat or boot notification retry. Must be called while holding bootIntervalMutex.
+func (cs *chargingStation) cancelBootInterval() {
+	if cs.bootIntervalTimer != nil {
+		cs.bootIntervalTimer.Stop()
+		cs.bootIntervalTimer = nil
+	}
+}
+
+func (cs *chargingStation) SetAutoApplyBootInterval(enabled bool) {
+	cs.bootIntervalMutex.Lock()
+	defer cs.bootIntervalMutex.Unlock()
+	cs.autoApplyBootInterval = enabled
+}
+
+func (cs *chargingStation) SetBootNotificationRetryHandler(handler func(response *provisioning.BootNotificationResponse, err error)) {
+	cs.bootIntervalMutex.Lock()
+	defer cs.bootIntervalMutex.Unlock()
+	cs.bootRetryHandler = handler
 }
 
 func (cs *chargingStation) Authorize(idToken string, tokenType types.IdTokenType, props ...func(request *authorization.AuthorizeRequest)) (*authorization.AuthorizeResponse, error) {
@@ -97,6 +215,19 @@ func (cs *chargingStation) Authorize(idToken string, tokenType types.IdTokenType
 	}
 }
 
+func (cs *chargingStation) BatterySwap(requestID int, eventType batteryswap.BatterySwapEvent, idToken types.IdToken, batteryData []batteryswap.BatteryData, props ...func(request *batteryswap.BatterySwapRequest)) (*batteryswap.BatterySwapResponse, error) {
+	request := batteryswap.NewBatterySwapRequest(requestID, eventType, idToken, batteryData)
+	for _, fn := range props {
+		fn(request)
+	}
+	response, err := cs.SendRequest(request)
+	if err != nil {
+		return nil, err
+	} else {
+		return response.(*batteryswap.BatterySwapResponse), err
+	}
+}
+
 func (cs *chargingStation) ClearedChargingLimit(chargingLimitSource types.ChargingLimitSourceType, props ...func(request *smartcharging.ClearedChargingLimitRequest)) (*smartcharging.ClearedChargingLimitResponse, error) {
 	request := smartcharging.NewClearedChargingLimitRequest(chargingLimitSource)
 	for _, fn := range props {
@@ -331,6 +462,19 @@ func (cs *chargingStation) ReportChargingProfiles(requestID int, chargingLimitSo
 	}
 }
 
+func (cs *chargingStation) ReportDERControl(requestID int, props ...func(request *der.ReportDERControlRequest)) (*der.ReportDERControlResponse, error) {
+	request := der.NewReportDERControlRequest(requestID)
+	for _, fn := range props {
+		fn(request)
+	}
+	response, err := cs.SendRequest(request)
+	if err != nil {
+		return nil, err
+	} else {
+		return response.(*der.ReportDERControlResponse), err
+	}
+}
+
 func (cs *chargingStation) ReservationStatusUpdate(reservationID int, status reservation.ReservationUpdateStatus, props ...func(request *reservation.ReservationStatusUpdateRequest)) (*reservation.ReservationStatusUpdateResponse, error) {
 	request := reservation.NewReservationStatusUpdateRequest(reservationID, status)
 	for _, fn := range props {
@@ -460,33 +604,98 @@ func (cs *chargingStation) SetDataHandler(handler data.ChargingStationHandler) {
 	cs.dataHandler = handler
 }
 
+func (cs *chargingStation) SetBatterySwapHandler(handler batteryswap.ChargingStationHandler) {
+	cs.batterySwapHandler = handler
+}
+
+func (cs *chargingStation) SetDERControlHandler(handler der.ChargingStationHandler) {
+	cs.derControlHandler = handler
+}
+
 func (cs *chargingStation) SendRequest(request ocpp.Request) (ocpp.Response, error) {
-	featureName := request.GetFeatureName()
-	if _, found := cs.client.GetProfileForFeature(featureName); !found {
-		return nil, fmt.Errorf("feature %v is unsupported on charging station (missing profile), cannot send request", featureName)
-	}
+	return cs.SendRequestSync(context.Background(), request)
+}
 
+func (cs *chargingStation) SendRequestSync(ctx context.Context, request ocpp.Request) (ocpp.Response, error) {
 	// Wraps an asynchronous response
 	type asyncResponse struct {
 		r ocpp.Response
 		e error
 	}
-	// Create channel and pass it to a callback function, for retrieving asynchronous response
 	asyncResponseC := make(chan asyncResponse, 1)
-	send := func() error {
-		return cs.client.SendRequest(request)
-	}
-	err := cs.callbacks.TryQueue("main", send, func(confirmation ocpp.Response, err error) {
-		asyncResponseC <- asyncResponse{r: confirmation, e: err}
+	err := cs.SendRequestAsync(request, func(response ocpp.Response, err error) {
+		asyncResponseC <- asyncResponse{r: response, e: err}
 	})
 	if err != nil {
 		return nil, err
 	}
-	asyncResult, ok := <-asyncResponseC
-	if !ok {
-		return nil, fmt.Errorf("internal error while receiving result for %v request", request.GetFeatureName())
+	select {
+	case asyncResult := <-asyncResponseC:
+		return asyncResult.r, asyncResult.e
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (cs *chargingStation) SendBinary(data []byte) error {
+	return cs.client.SendBinary(data)
+}
+
+func (cs *chargingStation) SetBinaryMessageHandler(handler func(data []byte)) {
+	cs.client.SetBinaryMessageHandler(handler)
+}
+
+func (cs *chargingStation) SetRequestObserver(observer ocppj.RequestObserver) {
+	cs.client.SetRequestObserver(observer)
+}
+
+func (cs *chargingStation) SetAuditLog(auditLog *ocppj.AuditLog) {
+	cs.client.SetAuditLog(auditLog)
+}
+
+func (cs *chargingStation) SetDisconnectedHandler(handler func(err error)) {
+	cs.client.SetOnDisconnectedHandler(handler)
+}
+
+func (cs *chargingStation) SetReconnectedHandler(handler func()) {
+	cs.reconnectedHandler = handler
+	cs.client.SetOnReconnectedHandler(cs.onReconnected)
+}
+
+func (cs *chargingStation) SetQueueFlushFilter(filter ocppj.QueueFlushFilter) {
+	cs.queueFlushFilter = filter
+	cs.client.SetOnReconnectedHandler(cs.onReconnected)
+}
+
+// Callback invoked once the client reconnected, before the queued requests are sent to the CSMS.
+// Requests dropped by the queue flush filter are removed together with their callbacks, to keep both queues aligned.
+func (cs *chargingStation) onReconnected() {
+	if cs.queueFlushFilter != nil {
+		callbacks, err := cs.callbacks.DropAt("main", func() ([]int, error) {
+			return cs.client.FilterQueue(cs.queueFlushFilter)
+		})
+		if err != nil {
+			cs.error(err)
+		}
+		for _, callback := range callbacks {
+			callback(nil, ocpp.NewError(ocppj.GenericError, "Request dropped by queue flush filter", ""))
+		}
+	}
+	if cs.reconnectedHandler != nil {
+		cs.reconnectedHandler()
 	}
-	return asyncResult.r, asyncResult.e
+}
+
+// Every restored request gets a callback, which forwards the result to the handler, to keep the callbacks aligned with the queue.
+func (cs *chargingStation) RestoreRequests(queue *ocppj.PersistentQueue, handler func(request ocpp.Request, response ocpp.Response, err error)) error {
+	return queue.Restore(func(call *ocppj.Call) {
+		request := call.Payload
+		_ = cs.callbacks.TryQueue("main", func() error { return nil }, func(response ocpp.Response, err error) {
+			if handler != nil {
+				handler(request, response, err)
+			}
+		})
+	})
 }
 
 func (cs *chargingStation) SendRequestAsync(request ocpp.Request, callback func(response ocpp.Response, err error)) error {
@@ -496,6 +705,7 @@ func (cs *chargingStation) SendRequestAsync(request ocpp.Request, callback func(
 	}
 	switch featureName {
 	case authorization.AuthorizeFeatureName,
+		batteryswap.BatterySwapFeatureName,
 		provisioning.BootNotificationFeatureName,
 		smartcharging.ClearedChargingLimitFeatureName,
 		data.DataTransferFeatureName,
@@ -515,6 +725,7 @@ func (cs *chargingStation) SendRequestAsync(request ocpp.Request, callback func(
 		provisioning.NotifyReportFeatureName,
 		firmware.PublishFirmwareStatusNotificationFeatureName,
 		smartcharging.ReportChargingProfilesFeatureName,
+		der.ReportDERControlFeatureName,
 		reservation.ReservationStatusUpdateFeatureName,
 		security.SecurityEventNotificationFeatureName,
 		security.SignCertificateFeatureName,
@@ -593,6 +804,7 @@ func (cs *chargingStation) sendResponse(response ocpp.Response, err error, reque
 }
 
 func (cs *chargingStation) Start(csmsUrl string) error {
+	cs.resumeBootInterval()
 	// Start client
 	cs.stopC = make(chan struct{}, 1)
 	err := cs.client.Start(csmsUrl)
@@ -604,6 +816,7 @@ func (cs *chargingStation) Start(csmsUrl string) error {
 }
 
 func (cs *chargingStation) StartWithRetries(csmsUrl string) {
+	cs.resumeBootInterval()
 	// Start client
 	cs.stopC = make(chan struct{}, 1)
 	cs.client.StartWithRetries(csmsUrl)
@@ -612,9 +825,20 @@ func (cs *chargingStation) StartWithRetries(csmsUrl string) {
 }
 
 func (cs *chargingStation) Stop() {
+	cs.bootIntervalMutex.Lock()
+	cs.bootIntervalStopped = true
+	cs.cancelBootInterval()
+	cs.bootIntervalMutex.Unlock()
 	cs.client.Stop()
 }
 
+// Allows scheduling heartbeats and boot notification retries again, after the charging station was stopped.
+func (cs *chargingStation) resumeBootInterval() {
+	cs.bootIntervalMutex.Lock()
+	cs.bootIntervalStopped = false
+	cs.bootIntervalMutex.Unlock()
+}
+
 func (cs *chargingStation) IsConnected() bool {
 	return cs.client.IsConnected()
 }
@@ -650,10 +874,18 @@ func (cs *chargingStation) handleIncomingRequest(request ocpp.Request, requestId
 			if cs.availabilityHandler == nil {
 				supported = false
 			}
+		case batteryswap.ProfileName:
+			if cs.batterySwapHandler == nil {
+				supported = false
+			}
 		case data.ProfileName:
 			if cs.dataHandler == nil {
 				supported = false
 			}
+		case der.ProfileName:
+			if cs.derControlHandler == nil {
+				supported = false
+			}
 		case diagnostics.ProfileName:
 			if cs.diagnosticsHandler == nil {
 				supported = false
@@ -726,6 +958,8 @@ func (cs *chargingStation) handleIncomingRequest(request ocpp.Request, requestId
 		response, err = cs.authorizationHandler.OnClearCache(request.(*authorization.ClearCacheRequest))
 	case smartcharging.ClearChargingProfileFeatureName:
 		response, err = cs.smartChargingHandler.OnClearChargingProfile(request.(*smartcharging.ClearChargingProfileRequest))
+	case der.ClearDERControlFeatureName:
+		response, err = cs.derControlHandler.OnClearDERControl(request.(*der.ClearDERControlRequest))
 	case display.ClearDisplayMessageFeatureName:
 		response, err = cs.displayHandler.OnClearDisplay(request.(*display.ClearDisplayRequest))
 	case diagnostics.ClearVariableMonitoringFeatureName:
@@ -744,6 +978,8 @@ func (cs *chargingStation) handleIncomingRequest(request ocpp.Request, requestId
 		response, err = cs.smartChargingHandler.OnGetChargingProfiles(request.(*smartcharging.GetChargingProfilesRequest))
 	case smartcharging.GetCompositeScheduleFeatureName:
 		response, err = cs.smartChargingHandler.OnGetCompositeSchedule(request.(*smartcharging.GetCompositeScheduleRequest))
+	case der.GetDERControlFeatureName:
+		response, err = cs.derControlHandler.OnGetDERControl(request.(*der.GetDERControlRequest))
 	case display.GetDisplayMessagesFeatureName:
 		response, err = cs.displayHandler.OnGetDisplayMessages(request.(*display.GetDisplayMessagesRequest))
 	case iso15118.GetInstalledCertificateIdsFeatureName:
@@ -762,8 +998,12 @@ func (cs *chargingStation) handleIncomingRequest(request ocpp.Request, requestId
 		response, err = cs.provisioningHandler.OnGetVariables(request.(*provisioning.GetVariablesRequest))
 	case iso15118.InstallCertificateFeatureName:
 		response, err = cs.iso15118Handler.OnInstallCertificate(request.(*iso15118.InstallCertificateRequest))
+	case batteryswap.NotifyAllowedEnergyTransferFeatureName:
+		response, err = cs.batterySwapHandler.OnNotifyAllowedEnergyTransfer(request.(*batteryswap.NotifyAllowedEnergyTransferRequest))
 	case firmware.PublishFirmwareFeatureName:
 		response, err = cs.firmwareHandler.OnPublishFirmware(request.(*firmware.PublishFirmwareRequest))
+	case batteryswap.RequestBatterySwapFeatureName:
+		response, err = cs.batterySwapHandler.OnRequestBatterySwap(request.(*batteryswap.RequestBatterySwapRequest))
 	case remotecontrol.RequestStartTransactionFeatureName:
 		response, err = cs.remoteControlHandler.OnRequestStartTransaction(request.(*remotecontrol.RequestStartTransactionRequest))
 	case remotecontrol.RequestStopTransactionFeatureName:
@@ -776,6 +1016,8 @@ func (cs *chargingStation) handleIncomingRequest(request ocpp.Request, requestId
 		response, err = cs.localAuthListHandler.OnSendLocalList(request.(*localauth.SendLocalListRequest))
 	case smartcharging.SetChargingProfileFeatureName:
 		response, err = cs.smartChargingHandler.OnSetChargingProfile(request.(*smartcharging.SetChargingProfileRequest))
+	case der.SetDERControlFeatureName:
+		response, err = cs.derControlHandler.OnSetDERControl(request.(*der.SetDERControlRequest))
 	case display.SetDisplayMessageFeatureName:
 		response, err = cs.displayHandler.OnSetDisplayMessage(request.(*display.SetDisplayMessageRequest))
 	case diagnostics.SetMonitoringBaseFeatureName:
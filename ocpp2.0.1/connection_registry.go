@@ -0,0 +1,142 @@
+package ocpp2
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConnectionInfo is a point-in-time snapshot of a charging station connection, see CSMS.ConnectionsSnapshot.
+type ConnectionInfo struct {
+	ChargingStationID  string
+	Protocol           string              // The negotiated websocket subprotocol. Empty, if the connection doesn't expose it.
+	RemoteAddr         net.Addr            // The remote address of the charging station.
+	ConnectedAt        time.Time           // Time at which the charging station connected.
+	LastActivity       time.Time           // Time of the most recent message received from or sent to the charging station.
+	MessagesReceived   uint64              // Requests, responses and errors received from the charging station.
+	MessagesSent       uint64              // Requests, responses and errors sent to the charging station.
+	Labels             map[string]string   // Custom labels, see CSMS.SetConnectionLabels.
+	RoundTripTime      time.Duration       // The smoothed round-trip time, if measured on the connection (see ws.Server.SetRTTMeasurement).
+	ActiveTransactions []TransactionInfo   // The active transactions. Only set if transaction tracking is enabled.
+	NetworkDiagnostics *NetworkDiagnostics // The known network information. Only set if network diagnostics tracking is enabled.
+}
+
+// Optional interfaces of a connection, which are implemented by the default websocket.
+type (
+	subprotocolProvider interface {
+		Subprotocol() string
+	}
+	roundTripTimeProvider interface {
+		AverageRTT() time.Duration
+	}
+)
+
+type connectionEntry struct {
+	connection       ChargingStationConnection
+	connectedAt      time.Time
+	lastActivity     time.Time
+	messagesReceived uint64
+	messagesSent     uint64
+	labels           map[string]string
+}
+
+// connectionRegistry keeps metadata about all connected charging stations.
+// Entries are removed as soon as a charging station disconnects.
+type connectionRegistry struct {
+	mutex   sync.RWMutex
+	entries map[string]*connectionEntry
+}
+
+func newConnectionRegistry() *connectionRegistry {
+	return &connectionRegistry{entries: map[string]*connectionEntry{}}
+}
+
+func (r *connectionRegistry) add(connection ChargingStationConnection) {
+	now := time.Now()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries[connection.ID()] = &connectionEntry{connection: connection, connectedAt: now, lastActivity: now}
+}
+
+func (r *connectionRegistry) remove(chargingStationID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.entries, chargingStationID)
+}
+
+func (r *connectionRegistry) messageReceived(chargingStationID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if entry, ok := r.entries[chargingStationID]; ok {
+		entry.messagesReceived++
+		entry.lastActivity = time.Now()
+	}
+}
+
+func (r *connectionRegistry) messageSent(chargingStationID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if entry, ok := r.entries[chargingStationID]; ok {
+		entry.messagesSent++
+		entry.lastActivity = time.Now()
+	}
+}
+
+// Replaces the labels of a connection. Returns false, if the charging station isn't connected.
+func (r *connectionRegistry) setLabels(chargingStationID string, labels map[string]string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry, ok := r.entries[chargingStationID]
+	if !ok {
+		return false
+	}
+	entry.labels = copyLabels(labels)
+	return true
+}
+
+// Returns the metadata of all connections, ordered by charging station ID.
+// All entries are copied while holding the lock, hence they refer to the same point in time.
+//
+// The optional enrich function is invoked for every entry while holding the lock,
+// so that additional information is gathered for the same set of connections.
+func (r *connectionRegistry) snapshot(enrich func(info *ConnectionInfo)) []ConnectionInfo {
+	r.mutex.RLock()
+	result := make([]ConnectionInfo, 0, len(r.entries))
+	for id, entry := range r.entries {
+		result = append(result, ConnectionInfo{
+			ChargingStationID: id,
+			RemoteAddr:        entry.connection.RemoteAddr(),
+			ConnectedAt:       entry.connectedAt,
+			LastActivity:      entry.lastActivity,
+			MessagesReceived:  entry.messagesReceived,
+			MessagesSent:      entry.messagesSent,
+			Labels:            copyLabels(entry.labels),
+		})
+		if p, ok := entry.connection.(subprotocolProvider); ok {
+			result[len(result)-1].Protocol = p.Subprotocol()
+		}
+		if p, ok := entry.connection.(roundTripTimeProvider); ok {
+			result[len(result)-1].RoundTripTime = p.AverageRTT()
+		}
+		if enrich != nil {
+			enrich(&result[len(result)-1])
+		}
+	}
+	r.mutex.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ChargingStationID < result[j].ChargingStationID
+	})
+	return result
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
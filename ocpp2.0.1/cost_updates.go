@@ -0,0 +1,76 @@
+package ocpp2
+
+import (
+	"sync"
+	"time"
+)
+
+type costUpdateKey struct {
+	chargingStationID string
+	transactionID     string
+}
+
+// costUpdateStreams manages the tickers of all running cost update streams, see CSMS.StartCostUpdates.
+type costUpdateStreams struct {
+	mutex   sync.Mutex
+	streams map[costUpdateKey]chan struct{}
+}
+
+func newCostUpdateStreams() *costUpdateStreams {
+	return &costUpdateStreams{streams: map[costUpdateKey]chan struct{}{}}
+}
+
+// Starts invoking send at every interval, until the stream is stopped. A running stream for the same transaction is replaced.
+func (s *costUpdateStreams) start(chargingStationID string, transactionID string, interval time.Duration, send func()) {
+	key := costUpdateKey{chargingStationID: chargingStationID, transactionID: transactionID}
+	stopC := make(chan struct{})
+	s.mutex.Lock()
+	if previous, ok := s.streams[key]; ok {
+		close(previous)
+	}
+	s.streams[key] = stopC
+	s.mutex.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopC:
+				return
+			case <-ticker.C:
+				// Stopping takes precedence over a pending tick
+				select {
+				case <-stopC:
+					return
+				default:
+					send()
+				}
+			}
+		}
+	}()
+}
+
+// Stops the stream of a transaction. Returns false, if no stream was running.
+func (s *costUpdateStreams) stop(chargingStationID string, transactionID string) bool {
+	key := costUpdateKey{chargingStationID: chargingStationID, transactionID: transactionID}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stopC, ok := s.streams[key]
+	if ok {
+		close(stopC)
+		delete(s.streams, key)
+	}
+	return ok
+}
+
+// Stops all streams of a charging station, e.g. after it disconnected.
+func (s *costUpdateStreams) stopAll(chargingStationID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, stopC := range s.streams {
+		if key.chargingStationID == chargingStationID {
+			close(stopC)
+			delete(s.streams, key)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package ocpp2
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/internal/callbackqueue"
 	"github.com/lorenzodonini/ocpp-go/ocpp"
@@ -23,12 +28,14 @@ import (
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/tariffcost"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/batteryswap"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/der"
 	"github.com/lorenzodonini/ocpp-go/ocppj"
 	"github.com/lorenzodonini/ocpp-go/ws"
 )
 
-type csms struct {
-	server               *ocppj.Server
+// The handlers of all profiles. Handlers may be replaced at any time, hence a copy is taken for processing each incoming request.
+type csmsHandlers struct {
 	securityHandler      security.CSMSHandler
 	provisioningHandler  provisioning.CSMSHandler
 	authorizationHandler authorization.CSMSHandler
@@ -45,18 +52,113 @@ type csms struct {
 	diagnosticsHandler   diagnostics.CSMSHandler
 	displayHandler       display.CSMSHandler
 	dataHandler          data.CSMSHandler
-	callbackQueue        callbackqueue.CallbackQueue
-	errC                 chan error
+	batterySwapHandler   batteryswap.CSMSHandler
+	derControlHandler    der.CSMSHandler
+	binaryHandler        BinaryMessageHandler
+}
+
+// The optional features of the CSMS. Like handlers, features may be enabled or replaced at any time,
+// hence a copy is taken for processing each incoming request.
+type csmsFeatures struct {
+	// Optional tracking of active transactions
+	transactionTracker *transactionTracker
+	// Optional ordered processing of transaction events
+	transactionSerializer *transactionSerializer
+	// Optional tracking of network information, e.g. the modem reported on boot
+	networkDiagnostics *networkDiagnosticsStore
+	// Optional coalescing of StatusNotifications
+	statusDebouncer *statusNotificationDebouncer
+	// Optional batching of MeterValues
+	meterValuesBatcher *meterValuesBatcher
+	// Optional automatic cost calculation for transaction events
+	tariffEngine   TariffEngine
+	tariffSessions *tariffSessions
+	// Optional consistency checks for reported variable characteristics
+	reportWarningHandler ReportWarningHandler
+	// Optional notification of accepted BootNotifications
+	stationBootedHandler StationBootedHandler
+	// Optional capturing of incoming requests, which are responded to manually
+	requestCaptureHandler RequestCaptureHandler
+	// Optional suppression of duplicate outgoing requests
+	requestDeduplicator *requestDeduplicator
+	// Treatment of requests received before the BootNotification
+	bootOrderPolicy BootOrderPolicy
+	// Truncation of over-length identity fields in BootNotifications
+	lenientIdentityFields bool
 }
 
+type csms struct {
+	server        *ocppj.Server
+	handlers      csmsHandlers
+	features      csmsFeatures
+	handlersMutex sync.RWMutex
+	callbackQueue callbackqueue.CallbackQueue
+	errC          chan error
+	// Connected charging stations
+	newChargingStationHandler          ChargingStationConnectionHandler
+	chargingStationDisconnectedHandler ChargingStationConnectionHandler
+	chargingStations                   map[string]ChargingStationConnection
+	chargingStationsMutex              sync.RWMutex
+	connections                        *connectionRegistry
+	// Accepted log upload requests, per charging station and requestId
+	logRequests      map[string]map[int]*diagnostics.GetLogRequest
+	logRequestsMutex sync.RWMutex
+	// Periodic CostUpdated requests for ongoing transactions
+	costUpdates *costUpdateStreams
+	// Maximum execution time of incoming request handlers, per feature
+	handlerTimeouts *handlerTimeouts
+	// Capabilities learned from the device model of the charging stations
+	capabilities *capabilitiesCache
+	// Charging profiles known to be installed on the charging stations
+	installedProfiles *installedProfilesStore
+	// Charging stations whose BootNotification was accepted
+	bootedStations *bootedStations
+	// Functions to run once incoming requests were responded to
+	responseHooks *responseHooks
+}
+
+// Handler interfaces for all profiles, used for determining which features are handled by the CSMS.
+var csmsHandlerTypes = map[string]reflect.Type{
+	authorization.ProfileName: reflect.TypeOf((*authorization.CSMSHandler)(nil)).Elem(),
+	availability.ProfileName:  reflect.TypeOf((*availability.CSMSHandler)(nil)).Elem(),
+	batteryswap.ProfileName:   reflect.TypeOf((*batteryswap.CSMSHandler)(nil)).Elem(),
+	data.ProfileName:          reflect.TypeOf((*data.CSMSHandler)(nil)).Elem(),
+	der.ProfileName:           reflect.TypeOf((*der.CSMSHandler)(nil)).Elem(),
+	diagnostics.ProfileName:   reflect.TypeOf((*diagnostics.CSMSHandler)(nil)).Elem(),
+	display.ProfileName:       reflect.TypeOf((*display.CSMSHandler)(nil)).Elem(),
+	firmware.ProfileName:      reflect.TypeOf((*firmware.CSMSHandler)(nil)).Elem(),
+	iso15118.ProfileName:      reflect.TypeOf((*iso15118.CSMSHandler)(nil)).Elem(),
+	localauth.ProfileName:     reflect.TypeOf((*localauth.CSMSHandler)(nil)).Elem(),
+	meter.ProfileName:         reflect.TypeOf((*meter.CSMSHandler)(nil)).Elem(),
+	provisioning.ProfileName:  reflect.TypeOf((*provisioning.CSMSHandler)(nil)).Elem(),
+	remotecontrol.ProfileName: reflect.TypeOf((*remotecontrol.CSMSHandler)(nil)).Elem(),
+	reservation.ProfileName:   reflect.TypeOf((*reservation.CSMSHandler)(nil)).Elem(),
+	security.ProfileName:      reflect.TypeOf((*security.CSMSHandler)(nil)).Elem(),
+	smartcharging.ProfileName: reflect.TypeOf((*smartcharging.CSMSHandler)(nil)).Elem(),
+	tariffcost.ProfileName:    reflect.TypeOf((*tariffcost.CSMSHandler)(nil)).Elem(),
+	transactions.ProfileName:  reflect.TypeOf((*transactions.CSMSHandler)(nil)).Elem(),
+}
+
+// Maximum amount of in-flight requests, when sending a request to multiple charging stations at once.
+const bulkRequestConcurrency = 20
+
 func newCSMS(server *ocppj.Server) csms {
 	if server == nil {
 		panic("server must not be nil")
 	}
 	server.SetDialect(ocpp.V2)
 	return csms{
-		server:        server,
-		callbackQueue: callbackqueue.New(),
+		server:            server,
+		callbackQueue:     callbackqueue.New(),
+		chargingStations:  map[string]ChargingStationConnection{},
+		connections:       newConnectionRegistry(),
+		logRequests:       map[string]map[int]*diagnostics.GetLogRequest{},
+		costUpdates:       newCostUpdateStreams(),
+		handlerTimeouts:   newHandlerTimeouts(),
+		capabilities:      newCapabilitiesCache(),
+		installedProfiles: newInstalledProfilesStore(),
+		bootedStations:    newBootedStations(),
+		responseHooks:     newResponseHooks(),
 	}
 }
 
@@ -140,7 +242,26 @@ func (cs *csms) ClearChargingProfile(clientId string, callback func(*smartchargi
 	}
 	genericCallback := func(response ocpp.Response, protoError error) {
 		if response != nil {
-			callback(response.(*smartcharging.ClearChargingProfileResponse), protoError)
+			clearResponse := response.(*smartcharging.ClearChargingProfileResponse)
+			if clearResponse.Status == smartcharging.ClearChargingProfileStatusAccepted {
+				cs.installedProfiles.clear(clientId, request)
+			}
+			callback(clearResponse, protoError)
+		} else {
+			callback(nil, protoError)
+		}
+	}
+	return cs.SendRequestAsync(clientId, request, genericCallback)
+}
+
+func (cs *csms) ClearDERControl(clientId string, callback func(*der.ClearDERControlResponse, error), isDefault bool, props ...func(request *der.ClearDERControlRequest)) error {
+	request := der.NewClearDERControlRequest(isDefault)
+	for _, fn := range props {
+		fn(request)
+	}
+	genericCallback := func(response ocpp.Response, protoError error) {
+		if response != nil {
+			callback(response.(*der.ClearDERControlResponse), protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -260,7 +381,9 @@ func (cs *csms) GetChargingProfiles(clientId string, callback func(*smartchargin
 	}
 	genericCallback := func(response ocpp.Response, protoError error) {
 		if response != nil {
-			callback(response.(*smartcharging.GetChargingProfilesResponse), protoError)
+			getResponse := response.(*smartcharging.GetChargingProfilesResponse)
+			cs.installedProfiles.requested(clientId, request, getResponse.Status)
+			callback(getResponse, protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -283,6 +406,21 @@ func (cs *csms) GetCompositeSchedule(clientId string, callback func(*smartchargi
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *csms) GetDERControl(clientId string, callback func(*der.GetDERControlResponse, error), requestID int, props ...func(request *der.GetDERControlRequest)) error {
+	request := der.NewGetDERControlRequest(requestID)
+	for _, fn := range props {
+		fn(request)
+	}
+	genericCallback := func(response ocpp.Response, protoError error) {
+		if response != nil {
+			callback(response.(*der.GetDERControlResponse), protoError)
+		} else {
+			callback(nil, protoError)
+		}
+	}
+	return cs.SendRequestAsync(clientId, request, genericCallback)
+}
+
 func (cs *csms) GetDisplayMessages(clientId string, callback func(*display.GetDisplayMessagesResponse, error), requestId int, props ...func(*display.GetDisplayMessagesRequest)) error {
 	request := display.NewGetDisplayMessagesRequest(requestId)
 	for _, fn := range props {
@@ -335,7 +473,12 @@ func (cs *csms) GetLog(clientId string, callback func(*diagnostics.GetLogRespons
 	}
 	genericCallback := func(response ocpp.Response, protoError error) {
 		if response != nil {
-			callback(response.(*diagnostics.GetLogResponse), protoError)
+			logResponse := response.(*diagnostics.GetLogResponse)
+			if logResponse.Status != diagnostics.LogStatusRejected {
+				// Upload was accepted, subsequent status notifications will refer to this request
+				cs.addLogRequest(clientId, request)
+			}
+			callback(logResponse, protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -343,6 +486,38 @@ func (cs *csms) GetLog(clientId string, callback func(*diagnostics.GetLogRespons
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *csms) PendingLogRequest(clientId string, requestID int) (*diagnostics.GetLogRequest, bool) {
+	cs.logRequestsMutex.RLock()
+	defer cs.logRequestsMutex.RUnlock()
+	request, ok := cs.logRequests[clientId][requestID]
+	return request, ok
+}
+
+func (cs *csms) addLogRequest(clientId string, request *diagnostics.GetLogRequest) {
+	cs.logRequestsMutex.Lock()
+	defer cs.logRequestsMutex.Unlock()
+	if _, ok := cs.logRequests[clientId]; !ok {
+		cs.logRequests[clientId] = map[int]*diagnostics.GetLogRequest{}
+	}
+	cs.logRequests[clientId][request.RequestID] = request
+}
+
+// Forgets all pending log requests of a charging station, e.g. once it disconnected.
+func (cs *csms) removeLogRequests(clientId string) {
+	cs.logRequestsMutex.Lock()
+	defer cs.logRequestsMutex.Unlock()
+	delete(cs.logRequests, clientId)
+}
+
+func (cs *csms) removeLogRequest(clientId string, requestID int) {
+	cs.logRequestsMutex.Lock()
+	defer cs.logRequestsMutex.Unlock()
+	delete(cs.logRequests[clientId], requestID)
+	if len(cs.logRequests[clientId]) == 0 {
+		delete(cs.logRequests, clientId)
+	}
+}
+
 func (cs *csms) GetMonitoringReport(clientId string, callback func(*diagnostics.GetMonitoringReportResponse, error), props ...func(*diagnostics.GetMonitoringReportRequest)) error {
 	request := diagnostics.NewGetMonitoringReportRequest()
 	for _, fn := range props {
@@ -418,6 +593,21 @@ func (cs *csms) InstallCertificate(clientId string, callback func(*iso15118.Inst
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *csms) NotifyAllowedEnergyTransfer(clientId string, callback func(*batteryswap.NotifyAllowedEnergyTransferResponse, error), transactionID string, allowedEnergyTransfer []batteryswap.EnergyTransferMode, props ...func(request *batteryswap.NotifyAllowedEnergyTransferRequest)) error {
+	request := batteryswap.NewNotifyAllowedEnergyTransferRequest(transactionID, allowedEnergyTransfer)
+	for _, fn := range props {
+		fn(request)
+	}
+	genericCallback := func(response ocpp.Response, protoError error) {
+		if response != nil {
+			callback(response.(*batteryswap.NotifyAllowedEnergyTransferResponse), protoError)
+		} else {
+			callback(nil, protoError)
+		}
+	}
+	return cs.SendRequestAsync(clientId, request, genericCallback)
+}
+
 func (cs *csms) PublishFirmware(clientId string, callback func(*firmware.PublishFirmwareResponse, error), location string, checksum string, requestID int, props ...func(request *firmware.PublishFirmwareRequest)) error {
 	request := firmware.NewPublishFirmwareRequest(location, checksum, requestID)
 	for _, fn := range props {
@@ -433,6 +623,21 @@ func (cs *csms) PublishFirmware(clientId string, callback func(*firmware.Publish
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *csms) RequestBatterySwap(clientId string, callback func(*batteryswap.RequestBatterySwapResponse, error), requestID int, idToken types.IdToken, props ...func(request *batteryswap.RequestBatterySwapRequest)) error {
+	request := batteryswap.NewRequestBatterySwapRequest(requestID, idToken)
+	for _, fn := range props {
+		fn(request)
+	}
+	genericCallback := func(response ocpp.Response, protoError error) {
+		if response != nil {
+			callback(response.(*batteryswap.RequestBatterySwapResponse), protoError)
+		} else {
+			callback(nil, protoError)
+		}
+	}
+	return cs.SendRequestAsync(clientId, request, genericCallback)
+}
+
 func (cs *csms) RequestStartTransaction(clientId string, callback func(*remotecontrol.RequestStartTransactionResponse, error), remoteStartID int, IdToken types.IdToken, props ...func(request *remotecontrol.RequestStartTransactionRequest)) error {
 	request := remotecontrol.NewRequestStartTransactionRequest(remoteStartID, IdToken)
 	for _, fn := range props {
@@ -515,7 +720,26 @@ func (cs *csms) SetChargingProfile(clientId string, callback func(*smartcharging
 	}
 	genericCallback := func(response ocpp.Response, protoError error) {
 		if response != nil {
-			callback(response.(*smartcharging.SetChargingProfileResponse), protoError)
+			setResponse := response.(*smartcharging.SetChargingProfileResponse)
+			if setResponse.Status == smartcharging.ChargingProfileStatusAccepted {
+				cs.installedProfiles.set(clientId, request.EvseID, request.ChargingProfile)
+			}
+			callback(setResponse, protoError)
+		} else {
+			callback(nil, protoError)
+		}
+	}
+	return cs.SendRequestAsync(clientId, request, genericCallback)
+}
+
+func (cs *csms) SetDERControl(clientId string, callback func(*der.SetDERControlResponse, error), isDefault bool, controlID string, controlType der.DERControlType, props ...func(request *der.SetDERControlRequest)) error {
+	request := der.NewSetDERControlRequest(isDefault, controlID, controlType)
+	for _, fn := range props {
+		fn(request)
+	}
+	genericCallback := func(response ocpp.Response, protoError error) {
+		if response != nil {
+			callback(response.(*der.SetDERControlResponse), protoError)
 		} else {
 			callback(nil, protoError)
 		}
@@ -628,6 +852,23 @@ func (cs *csms) TriggerMessage(clientId string, callback func(*remotecontrol.Tri
 	return cs.SendRequestAsync(clientId, request, genericCallback)
 }
 
+func (cs *csms) TriggerMessageAll(callback func(clientId string, response *remotecontrol.TriggerMessageResponse, err error), requestedMessage remotecontrol.MessageTrigger, props ...func(request *remotecontrol.TriggerMessageRequest)) []string {
+	build := func(clientId string) ocpp.Request {
+		request := remotecontrol.NewTriggerMessageRequest(requestedMessage)
+		for _, fn := range props {
+			fn(request)
+		}
+		return request
+	}
+	return cs.Broadcast(nil, build, BroadcastOptions{}, func(clientId string, response ocpp.Response, err error) {
+		if response != nil {
+			callback(clientId, response.(*remotecontrol.TriggerMessageResponse), err)
+		} else {
+			callback(clientId, nil, err)
+		}
+	})
+}
+
 func (cs *csms) UnlockConnector(clientId string, callback func(*remotecontrol.UnlockConnectorResponse, error), evseID int, connectorID int, props ...func(request *remotecontrol.UnlockConnectorRequest)) error {
 	request := remotecontrol.NewUnlockConnectorRequest(evseID, connectorID)
 	for _, fn := range props {
@@ -674,67 +915,420 @@ func (cs *csms) UpdateFirmware(clientId string, callback func(*firmware.UpdateFi
 }
 
 func (cs *csms) SetSecurityHandler(handler security.CSMSHandler) {
-	cs.securityHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.securityHandler = handler
 }
 
 func (cs *csms) SetProvisioningHandler(handler provisioning.CSMSHandler) {
-	cs.provisioningHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.provisioningHandler = handler
 }
 
 func (cs *csms) SetAuthorizationHandler(handler authorization.CSMSHandler) {
-	cs.authorizationHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.authorizationHandler = handler
 }
 
 func (cs *csms) SetLocalAuthListHandler(handler localauth.CSMSHandler) {
-	cs.localAuthListHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.localAuthListHandler = handler
 }
 
 func (cs *csms) SetTransactionsHandler(handler transactions.CSMSHandler) {
-	cs.transactionsHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.transactionsHandler = handler
 }
 
 func (cs *csms) SetRemoteControlHandler(handler remotecontrol.CSMSHandler) {
-	cs.remoteControlHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.remoteControlHandler = handler
 }
 
 func (cs *csms) SetAvailabilityHandler(handler availability.CSMSHandler) {
-	cs.availabilityHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.availabilityHandler = handler
 }
 
 func (cs *csms) SetReservationHandler(handler reservation.CSMSHandler) {
-	cs.reservationHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.reservationHandler = handler
 }
 
 func (cs *csms) SetTariffCostHandler(handler tariffcost.CSMSHandler) {
-	cs.tariffCostHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.tariffCostHandler = handler
 }
 
 func (cs *csms) SetMeterHandler(handler meter.CSMSHandler) {
-	cs.meterHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.meterHandler = handler
 }
 
 func (cs *csms) SetSmartChargingHandler(handler smartcharging.CSMSHandler) {
-	cs.smartChargingHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.smartChargingHandler = handler
 }
 
 func (cs *csms) SetFirmwareHandler(handler firmware.CSMSHandler) {
-	cs.firmwareHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.firmwareHandler = handler
 }
 
 func (cs *csms) SetISO15118Handler(handler iso15118.CSMSHandler) {
-	cs.iso15118Handler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.iso15118Handler = handler
 }
 
 func (cs *csms) SetDiagnosticsHandler(handler diagnostics.CSMSHandler) {
-	cs.diagnosticsHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.diagnosticsHandler = handler
 }
 
 func (cs *csms) SetDisplayHandler(handler display.CSMSHandler) {
-	cs.displayHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.displayHandler = handler
 }
 
 func (cs *csms) SetDataHandler(handler data.CSMSHandler) {
-	cs.dataHandler = handler
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.dataHandler = handler
+}
+
+func (cs *csms) SetBatterySwapHandler(handler batteryswap.CSMSHandler) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.batterySwapHandler = handler
+}
+
+func (cs *csms) SetDERControlHandler(handler der.CSMSHandler) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.derControlHandler = handler
+}
+
+func (cs *csms) SetBinaryMessageHandler(handler BinaryMessageHandler) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.handlers.binaryHandler = handler
+}
+
+func (cs *csms) SetTransactionTracking(enabled bool) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	if enabled && cs.features.transactionTracker == nil {
+		cs.features.transactionTracker = newTransactionTracker()
+	} else if !enabled {
+		cs.features.transactionTracker = nil
+	}
+}
+
+func (cs *csms) SetOrderedTransactionEvents(enabled bool) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	if enabled && cs.features.transactionSerializer == nil {
+		cs.features.transactionSerializer = newTransactionSerializer()
+	} else if !enabled {
+		cs.features.transactionSerializer = nil
+	}
+}
+
+func (cs *csms) SetNetworkDiagnosticsTracking(enabled bool) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	if enabled && cs.features.networkDiagnostics == nil {
+		cs.features.networkDiagnostics = newNetworkDiagnosticsStore()
+	} else if !enabled {
+		cs.features.networkDiagnostics = nil
+	}
+}
+
+func (cs *csms) UpdateNetworkDiagnostics(clientId string, update func(diagnostics *NetworkDiagnostics)) error {
+	store := cs.currentFeatures().networkDiagnostics
+	if store == nil {
+		return fmt.Errorf("network diagnostics tracking is disabled, cannot update %s", clientId)
+	}
+	store.update(clientId, update)
+	return nil
+}
+
+func (cs *csms) NetworkDiagnostics(clientId string) (NetworkDiagnostics, bool) {
+	store := cs.currentFeatures().networkDiagnostics
+	if store == nil {
+		return NetworkDiagnostics{}, false
+	}
+	return store.get(clientId)
+}
+
+func (cs *csms) SetRequestCaptureHandler(handler RequestCaptureHandler) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.features.requestCaptureHandler = handler
+}
+
+func (cs *csms) SetReportWarningHandler(handler ReportWarningHandler) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.features.reportWarningHandler = handler
+}
+
+func (cs *csms) SetStationBootedHandler(handler StationBootedHandler) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.features.stationBootedHandler = handler
+}
+
+func (cs *csms) SetTariffEngine(engine TariffEngine) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.features.tariffEngine = engine
+	if engine == nil {
+		cs.features.tariffSessions = nil
+	} else if cs.features.tariffSessions == nil {
+		cs.features.tariffSessions = newTariffSessions()
+	}
+}
+
+func (cs *csms) SetHandlerTimeout(featureName string, d time.Duration) {
+	cs.handlerTimeouts.set(featureName, d)
+}
+
+func (cs *csms) SetLenientIdentityFields(enabled bool) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.features.lenientIdentityFields = enabled
+}
+
+// Truncates over-length identity fields of an incoming BootNotification, if enabled.
+// Invoked by the endpoint before the request is validated.
+func (cs *csms) normalizeBootNotification(request ocpp.Request) []string {
+	bootNotification, ok := request.(*provisioning.BootNotificationRequest)
+	if !ok || !cs.currentFeatures().lenientIdentityFields {
+		return nil
+	}
+	var adjustments []string
+	for _, field := range bootNotification.ChargingStation.TruncateIdentityFields() {
+		adjustments = append(adjustments, fmt.Sprintf("truncated over-length field chargingStation.%v", field))
+	}
+	return adjustments
+}
+
+func (cs *csms) SetBootOrderPolicy(policy BootOrderPolicy) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	cs.features.bootOrderPolicy = policy
+}
+
+func (cs *csms) SetStatusNotificationDebounce(d time.Duration) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	if d <= 0 {
+		cs.features.statusDebouncer = nil
+		return
+	}
+	cs.features.statusDebouncer = newStatusNotificationDebouncer(d, cs.deliverStatusNotifications)
+}
+
+func (cs *csms) SetRequestDeduplication(window time.Duration, keyFunc RequestKeyFunc) {
+	cs.handlersMutex.Lock()
+	defer cs.handlersMutex.Unlock()
+	if window <= 0 {
+		cs.features.requestDeduplicator = nil
+		return
+	}
+	cs.features.requestDeduplicator = newRequestDeduplicator(window, keyFunc)
+}
+
+func (cs *csms) SetMeterValuesBatch(maxCount int, maxWait time.Duration, handler MeterValuesBatchHandler) {
+	var batcher *meterValuesBatcher
+	if handler != nil {
+		batcher = newMeterValuesBatcher(maxCount, maxWait, handler)
+	}
+	cs.handlersMutex.Lock()
+	previous := cs.features.meterValuesBatcher
+	cs.features.meterValuesBatcher = batcher
+	cs.handlersMutex.Unlock()
+	// Pending batches are delivered outside the lock, as the handler may access the CSMS
+	if previous != nil {
+		previous.flushAll()
+	}
+}
+
+// Invokes the availability handler with debounced StatusNotifications.
+// The responses were already sent, hence errors returned by the handler are only reported on the error channel.
+func (cs *csms) deliverStatusNotifications(chargingStationID string, requests []*availability.StatusNotificationRequest) {
+	handler := cs.currentHandlers().availabilityHandler
+	if handler == nil {
+		return
+	}
+	if sequenceHandler, ok := handler.(StatusNotificationSequenceHandler); ok {
+		sequenceHandler.OnStatusNotificationSequence(chargingStationID, requests)
+		return
+	}
+	if _, err := handler.OnStatusNotification(chargingStationID, requests[len(requests)-1]); err != nil {
+		cs.error(fmt.Errorf("debounced status notification handler failed for %s: %w", chargingStationID, err))
+	}
+}
+
+// Computes the cost of a transaction via the tariff engine and adds it to the response.
+// Values explicitly set by the transactions handler are not overwritten.
+// Errors returned by the engine are reported on the error channel, without affecting the response.
+func (cs *csms) applyTariff(features csmsFeatures, chargingStationID string, event *transactions.TransactionEventRequest, response *transactions.TransactionEventResponse) {
+	engine, sessions := features.tariffEngine, features.tariffSessions
+	if engine == nil || sessions == nil || response == nil {
+		return
+	}
+	session := SessionState{
+		ChargingStationID: chargingStationID,
+		TransactionID:     event.TransactionInfo.TransactionID,
+		MeterValues:       sessions.apply(chargingStationID, event),
+	}
+	if features.transactionTracker != nil {
+		for _, info := range features.transactionTracker.activeTransactions(chargingStationID) {
+			if info.TransactionID == session.TransactionID {
+				transaction := info
+				session.Transaction = &transaction
+				break
+			}
+		}
+	}
+	cost, personalMessage, err := engine(*event, session)
+	if err != nil {
+		cs.error(fmt.Errorf("tariff engine failed for transaction %s of %s: %w", session.TransactionID, chargingStationID, err))
+		return
+	}
+	// The total cost shall only be sent once charging has ended
+	if event.EventType == transactions.TransactionEventEnded && response.TotalCost == nil {
+		response.TotalCost = &cost
+	}
+	if personalMessage != nil && response.UpdatedPersonalMessage == nil {
+		response.UpdatedPersonalMessage = personalMessage
+	}
+}
+
+func (cs *csms) ConnectionsSnapshot() []ConnectionInfo {
+	features := cs.currentFeatures()
+	return cs.connections.snapshot(func(info *ConnectionInfo) {
+		if features.transactionTracker != nil {
+			info.ActiveTransactions = features.transactionTracker.activeTransactions(info.ChargingStationID)
+		}
+		if features.networkDiagnostics != nil {
+			if diagnostics, ok := features.networkDiagnostics.get(info.ChargingStationID); ok {
+				info.NetworkDiagnostics = &diagnostics
+			}
+		}
+	})
+}
+
+func (cs *csms) SetConnectionLabels(clientId string, labels map[string]string) error {
+	if !cs.connections.setLabels(clientId, labels) {
+		return fmt.Errorf("charging station %v is not connected", clientId)
+	}
+	return nil
+}
+
+func (cs *csms) ActiveTransactions(clientId string) []TransactionInfo {
+	tracker := cs.currentFeatures().transactionTracker
+	if tracker == nil {
+		return nil
+	}
+	return tracker.activeTransactions(clientId)
+}
+
+func (cs *csms) RegisteredFeatures() map[string][]string {
+	result := map[string][]string{}
+	handlers := cs.currentHandlers()
+	for _, profile := range cs.server.Profiles {
+		handlerType, ok := csmsHandlerTypes[profile.Name]
+		if !ok || handlers.handlerForProfile(profile.Name) == nil {
+			continue
+		}
+		var features []string
+		for name := range profile.Features {
+			// Features initiated by the charging station are handled by a corresponding On<FeatureName> method
+			if _, ok := handlerType.MethodByName("On" + name); ok {
+				features = append(features, name)
+			}
+		}
+		if len(features) > 0 {
+			sort.Strings(features)
+			result[profile.Name] = features
+		}
+	}
+	return result
+}
+
+// Returns the handler registered for a profile, or nil if no handler was set.
+func (h csmsHandlers) handlerForProfile(profileName string) interface{} {
+	var handler interface{}
+	switch profileName {
+	case authorization.ProfileName:
+		handler = h.authorizationHandler
+	case availability.ProfileName:
+		handler = h.availabilityHandler
+	case batteryswap.ProfileName:
+		handler = h.batterySwapHandler
+	case data.ProfileName:
+		handler = h.dataHandler
+	case der.ProfileName:
+		handler = h.derControlHandler
+	case diagnostics.ProfileName:
+		handler = h.diagnosticsHandler
+	case display.ProfileName:
+		handler = h.displayHandler
+	case firmware.ProfileName:
+		handler = h.firmwareHandler
+	case iso15118.ProfileName:
+		handler = h.iso15118Handler
+	case localauth.ProfileName:
+		handler = h.localAuthListHandler
+	case meter.ProfileName:
+		handler = h.meterHandler
+	case provisioning.ProfileName:
+		handler = h.provisioningHandler
+	case remotecontrol.ProfileName:
+		handler = h.remoteControlHandler
+	case reservation.ProfileName:
+		handler = h.reservationHandler
+	case security.ProfileName:
+		handler = h.securityHandler
+	case smartcharging.ProfileName:
+		handler = h.smartChargingHandler
+	case tariffcost.ProfileName:
+		handler = h.tariffCostHandler
+	case transactions.ProfileName:
+		handler = h.transactionsHandler
+	}
+	return handler
+}
+
+// Returns a copy of the currently registered handlers.
+func (cs *csms) currentHandlers() csmsHandlers {
+	cs.handlersMutex.RLock()
+	defer cs.handlersMutex.RUnlock()
+	return cs.handlers
+}
+
+// Returns a copy of the currently enabled optional features.
+func (cs *csms) currentFeatures() csmsFeatures {
+	cs.handlersMutex.RLock()
+	defer cs.handlersMutex.RUnlock()
+	return cs.features
 }
 
 func (cs *csms) SetNewChargingStationValidationHandler(handler ws.CheckClientHandler) {
@@ -742,15 +1336,69 @@ func (cs *csms) SetNewChargingStationValidationHandler(handler ws.CheckClientHan
 }
 
 func (cs *csms) SetNewChargingStationHandler(handler ChargingStationConnectionHandler) {
-	cs.server.SetNewClientHandler(func(chargingStation ws.Channel) {
-		handler(chargingStation)
-	})
+	cs.newChargingStationHandler = handler
 }
 
 func (cs *csms) SetChargingStationDisconnectedHandler(handler ChargingStationConnectionHandler) {
-	cs.server.SetDisconnectedClientHandler(func(chargingStation ws.Channel) {
-		handler(chargingStation)
-	})
+	cs.chargingStationDisconnectedHandler = handler
+}
+
+func (cs *csms) onChargingStationConnected(chargingStation ChargingStationConnection) {
+	cs.chargingStationsMutex.Lock()
+	cs.chargingStations[chargingStation.ID()] = chargingStation
+	cs.chargingStationsMutex.Unlock()
+	cs.connections.add(chargingStation)
+	if cs.newChargingStationHandler != nil {
+		cs.newChargingStationHandler(chargingStation)
+	}
+}
+
+func (cs *csms) onChargingStationDisconnected(chargingStation ChargingStationConnection) {
+	cs.chargingStationsMutex.Lock()
+	delete(cs.chargingStations, chargingStation.ID())
+	cs.chargingStationsMutex.Unlock()
+	cs.connections.remove(chargingStation.ID())
+	cs.bootedStations.remove(chargingStation.ID())
+	cs.removeLogRequests(chargingStation.ID())
+	features := cs.currentFeatures()
+	if batcher := features.meterValuesBatcher; batcher != nil {
+		batcher.flush(chargingStation.ID())
+	}
+	if debouncer := features.statusDebouncer; debouncer != nil {
+		debouncer.flush(chargingStation.ID())
+	}
+	cs.costUpdates.stopAll(chargingStation.ID())
+	if cs.chargingStationDisconnectedHandler != nil {
+		cs.chargingStationDisconnectedHandler(chargingStation)
+	}
+}
+
+// Returns the IDs of all currently connected charging stations.
+func (cs *csms) connectedChargingStationIDs() []string {
+	cs.chargingStationsMutex.RLock()
+	defer cs.chargingStationsMutex.RUnlock()
+	ids := make([]string, 0, len(cs.chargingStations))
+	for id := range cs.chargingStations {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (cs *csms) SendBinary(clientId string, data []byte) error {
+	if err := cs.server.SendBinary(clientId, data); err != nil {
+		return err
+	}
+	cs.connections.messageSent(clientId)
+	return nil
+}
+
+func (cs *csms) handleIncomingBinaryMessage(chargingStation ChargingStationConnection, data []byte) {
+	handler := cs.currentHandlers().binaryHandler
+	if handler == nil {
+		cs.error(fmt.Errorf("no handler for binary message from %s, discarding", chargingStation.ID()))
+		return
+	}
+	handler(chargingStation.ID(), data)
 }
 
 func (cs *csms) SendRequestAsync(clientId string, request ocpp.Request, callback func(response ocpp.Response, err error)) error {
@@ -765,6 +1413,7 @@ func (cs *csms) SendRequestAsync(clientId string, request ocpp.Request, callback
 		authorization.ClearCacheFeatureName,
 		smartcharging.ClearChargingProfileFeatureName,
 		display.ClearDisplayMessageFeatureName,
+		der.ClearDERControlFeatureName,
 		diagnostics.ClearVariableMonitoringFeatureName,
 		tariffcost.CostUpdatedFeatureName,
 		diagnostics.CustomerInformationFeatureName,
@@ -773,6 +1422,7 @@ func (cs *csms) SendRequestAsync(clientId string, request ocpp.Request, callback
 		provisioning.GetBaseReportFeatureName,
 		smartcharging.GetChargingProfilesFeatureName,
 		smartcharging.GetCompositeScheduleFeatureName,
+		der.GetDERControlFeatureName,
 		display.GetDisplayMessagesFeatureName,
 		iso15118.GetInstalledCertificateIdsFeatureName,
 		localauth.GetLocalListVersionFeatureName,
@@ -782,13 +1432,16 @@ func (cs *csms) SendRequestAsync(clientId string, request ocpp.Request, callback
 		transactions.GetTransactionStatusFeatureName,
 		provisioning.GetVariablesFeatureName,
 		iso15118.InstallCertificateFeatureName,
+		batteryswap.NotifyAllowedEnergyTransferFeatureName,
 		firmware.PublishFirmwareFeatureName,
+		batteryswap.RequestBatterySwapFeatureName,
 		remotecontrol.RequestStartTransactionFeatureName,
 		remotecontrol.RequestStopTransactionFeatureName,
 		reservation.ReserveNowFeatureName,
 		provisioning.ResetFeatureName,
 		localauth.SendLocalListFeatureName,
 		smartcharging.SetChargingProfileFeatureName,
+		der.SetDERControlFeatureName,
 		display.SetDisplayMessageFeatureName,
 		diagnostics.SetMonitoringBaseFeatureName,
 		diagnostics.SetMonitoringLevelFeatureName,
@@ -804,7 +1457,24 @@ func (cs *csms) SendRequestAsync(clientId string, request ocpp.Request, callback
 		return fmt.Errorf("unsupported action %v on CSMS, cannot send request", featureName)
 	}
 
+	if deduplicator := cs.currentFeatures().requestDeduplicator; deduplicator != nil {
+		wrapped, release, send := deduplicator.register(clientId, request, callback)
+		if !send {
+			// Duplicate request, the callback receives the result of the original request
+			return nil
+		}
+		if err := cs.queueRequest(clientId, request, wrapped); err != nil {
+			release(err)
+			return err
+		}
+		return nil
+	}
+	return cs.queueRequest(clientId, request, callback)
+}
+
+func (cs *csms) queueRequest(clientId string, request ocpp.Request, callback func(ocpp.Response, error)) error {
 	send := func() error {
+		cs.connections.messageSent(clientId)
 		return cs.server.SendRequest(clientId, request)
 	}
 	return cs.callbackQueue.TryQueue(clientId, send, callback)
@@ -815,11 +1485,97 @@ func (cs *csms) Start(listenPort int, listenPath string) {
 	cs.server.Start(listenPort, listenPath)
 }
 
+func (cs *csms) StartWithContext(ctx context.Context, listenPort int, listenPath string) error {
+	return cs.server.StartWithContext(ctx, listenPort, listenPath)
+}
+
+func (cs *csms) StartOnListener(listener net.Listener, listenPath string) error {
+	return cs.server.StartOnListener(listener, listenPath)
+}
+
+func (cs *csms) SetStationTimeout(clientId string, timeout time.Duration) error {
+	return cs.server.SetClientTimeout(clientId, timeout)
+}
+
+func (cs *csms) FlushQueue(clientId string) error {
+	return cs.server.FlushQueue(clientId)
+}
+
+func (cs *csms) StartCostUpdates(clientId string, transactionId string, interval time.Duration, compute func() float64) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid cost update interval %v", interval)
+	}
+	if compute == nil {
+		return fmt.Errorf("cost update function must not be nil")
+	}
+	cs.chargingStationsMutex.RLock()
+	_, connected := cs.chargingStations[clientId]
+	cs.chargingStationsMutex.RUnlock()
+	if !connected {
+		return fmt.Errorf("cannot start cost updates for transaction %s, charging station %s is not connected", transactionId, clientId)
+	}
+	cs.costUpdates.start(clientId, transactionId, interval, func() {
+		err := cs.CostUpdated(clientId, func(response *tariffcost.CostUpdatedResponse, err error) {
+			if err != nil {
+				cs.error(fmt.Errorf("cost update for transaction %s on charging station %s failed: %w", transactionId, clientId, err))
+			}
+		}, compute(), transactionId)
+		if err != nil {
+			cs.error(fmt.Errorf("couldn't send cost update for transaction %s to charging station %s: %w", transactionId, clientId, err))
+		}
+	})
+	return nil
+}
+
+func (cs *csms) StopCostUpdates(clientId string, transactionId string) bool {
+	return cs.costUpdates.stop(clientId, transactionId)
+}
+
+func (cs *csms) PauseStation(clientId string) error {
+	return cs.server.PauseClient(clientId)
+}
+
+func (cs *csms) ResumeStation(clientId string) error {
+	return cs.server.ResumeClient(clientId)
+}
+
+func (cs *csms) DropQueue(clientId string) error {
+	// Queued requests are always the most recent ones, hence their callbacks are at the end of the callback queue
+	var dropped []ocppj.RequestBundle
+	callbacks, err := cs.callbackQueue.DropTail(clientId, func() (int, error) {
+		var err error
+		dropped, err = cs.server.DropQueue(clientId)
+		return len(dropped), err
+	})
+	if err != nil {
+		return err
+	}
+	// Callbacks and dropped requests are both in queue order
+	for i, callback := range callbacks {
+		go callback(nil, ocpp.NewError(ocppj.GenericError, "Request dropped", dropped[i].Call.UniqueId))
+	}
+	return nil
+}
+
+func (cs *csms) AddListenPath(listenPath string) {
+	cs.server.AddListenPath(listenPath)
+}
+
 func (cs *csms) Stop() {
 	cs.server.Stop()
+	features := cs.currentFeatures()
+	if batcher := features.meterValuesBatcher; batcher != nil {
+		batcher.flushAll()
+	}
+	if debouncer := features.statusDebouncer; debouncer != nil {
+		debouncer.flushAll()
+	}
 }
 
-func (cs *csms) sendResponse(chargingStationID string, response ocpp.Response, err error, requestId string) {
+// Replies to an incoming request. Returns true if the response was sent to the charging station,
+// false if an error was replied instead or sending failed.
+func (cs *csms) sendResponse(chargingStationID string, response ocpp.Response, err error, requestId string) bool {
+	cs.connections.messageSent(chargingStationID)
 	if err != nil {
 		// Send error response
 		if ocppError, ok := err.(*ocpp.Error); ok {
@@ -834,7 +1590,7 @@ func (cs *csms) sendResponse(chargingStationID string, response ocpp.Response, e
 			err = fmt.Errorf("error replying cp %s to request %s with 'internal error': %w", chargingStationID, requestId, err)
 			cs.error(err)
 		}
-		return
+		return false
 	}
 
 	if response == nil || reflect.ValueOf(response).IsNil() {
@@ -842,7 +1598,7 @@ func (cs *csms) sendResponse(chargingStationID string, response ocpp.Response, e
 		// Sending a dummy error to server instead, then notify client implementation
 		_ = cs.server.SendError(chargingStationID, requestId, ocppj.GenericError, err.Error(), nil)
 		cs.error(err)
-		return
+		return false
 	}
 
 	// send confirmation response
@@ -853,10 +1609,13 @@ func (cs *csms) sendResponse(chargingStationID string, response ocpp.Response, e
 		// Notify client implementation
 		err = fmt.Errorf("error replying cp %s to request %s: %w", chargingStationID, requestId, err)
 		cs.error(err)
+		return false
 	}
+	return true
 }
 
 func (cs *csms) notImplementedError(chargingStationID string, requestId string, action string) {
+	cs.connections.messageSent(chargingStationID)
 	err := cs.server.SendError(chargingStationID, requestId, ocppj.NotImplemented, fmt.Sprintf("no handler for action %v implemented", action), nil)
 	if err != nil {
 		err = fmt.Errorf("replying cs %s to request %s with 'not implemented': %w", chargingStationID, requestId, err)
@@ -865,6 +1624,7 @@ func (cs *csms) notImplementedError(chargingStationID string, requestId string,
 }
 
 func (cs *csms) notSupportedError(chargingStationID string, requestId string, action string) {
+	cs.connections.messageSent(chargingStationID)
 	err := cs.server.SendError(chargingStationID, requestId, ocppj.NotSupported, fmt.Sprintf("unsupported action %v on CSMS", action), nil)
 	if err != nil {
 		err = fmt.Errorf("replying cs %s to request %s with 'not supported': %w", chargingStationID, requestId, err)
@@ -872,146 +1632,158 @@ func (cs *csms) notSupportedError(chargingStationID string, requestId string, ac
 	}
 }
 
+func (cs *csms) bootRequiredError(chargingStationID string, requestId string, action string) {
+	cs.connections.messageSent(chargingStationID)
+	err := cs.server.SendError(chargingStationID, requestId, ocppj.SecurityError, fmt.Sprintf("BootNotification required before %v", action), nil)
+	if err != nil {
+		err = fmt.Errorf("replying cs %s to request %s with 'security error': %w", chargingStationID, requestId, err)
+		cs.error(err)
+	}
+}
+
 func (cs *csms) handleIncomingRequest(chargingStation ChargingStationConnection, request ocpp.Request, requestId string, action string) {
+	// Use the same handlers and features for the whole request, even if they are replaced in the meantime
+	handlers := cs.currentHandlers()
+	features := cs.currentFeatures()
+	if captureHandler := features.requestCaptureHandler; captureHandler != nil {
+		if captureHandler(capturedConnection{ChargingStationConnection: chargingStation, cs: cs}, request, requestId, action) {
+			return
+		}
+	}
+	if policy := features.bootOrderPolicy; policy != BootOrderAllow && action != provisioning.BootNotificationFeatureName && !cs.bootedStations.isBooted(chargingStation.ID()) {
+		if policy == BootOrderRequireBootFirst {
+			cs.bootRequiredError(chargingStation.ID(), requestId, action)
+			return
+		}
+		cs.error(fmt.Errorf("received %v from charging station %s before BootNotification", action, chargingStation.ID()))
+	}
 	profile, found := cs.server.GetProfileForFeature(action)
 	// Check whether action is supported and a listener for it exists
 	if !found {
 		cs.notImplementedError(chargingStation.ID(), requestId, action)
 		return
-	} else {
-		supported := true
-		switch profile.Name {
-		case authorization.ProfileName:
-			if cs.authorizationHandler == nil {
-				supported = false
-			}
-		case availability.ProfileName:
-			if cs.availabilityHandler == nil {
-				supported = false
-			}
-		case data.ProfileName:
-			if cs.dataHandler == nil {
-				supported = false
-			}
-		case diagnostics.ProfileName:
-			if cs.diagnosticsHandler == nil {
-				supported = false
-			}
-		case display.ProfileName:
-			if cs.displayHandler == nil {
-				supported = false
-			}
-		case firmware.ProfileName:
-			if cs.firmwareHandler == nil {
-				supported = false
-			}
-		case iso15118.ProfileName:
-			if cs.iso15118Handler == nil {
-				supported = false
-			}
-		case localauth.ProfileName:
-			if cs.localAuthListHandler == nil {
-				supported = false
-			}
-		case meter.ProfileName:
-			if cs.meterHandler == nil {
-				supported = false
-			}
-		case provisioning.ProfileName:
-			if cs.provisioningHandler == nil {
-				supported = false
-			}
-		case remotecontrol.ProfileName:
-			if cs.remoteControlHandler == nil {
-				supported = false
-			}
-		case reservation.ProfileName:
-			if cs.reservationHandler == nil {
-				supported = false
-			}
-		case security.ProfileName:
-			if cs.securityHandler == nil {
-				supported = false
-			}
-		case smartcharging.ProfileName:
-			if cs.smartChargingHandler == nil {
-				supported = false
-			}
-		case tariffcost.ProfileName:
-			if cs.tariffCostHandler == nil {
-				supported = false
-			}
-		case transactions.ProfileName:
-			if cs.transactionsHandler == nil {
-				supported = false
-			}
-		}
-		if !supported {
-			cs.notSupportedError(chargingStation.ID(), requestId, action)
-			return
-		}
+	} else if batcher := features.meterValuesBatcher; batcher != nil && action == meter.MeterValuesFeatureName {
+		// Batched MeterValues don't require a meter handler and are acknowledged immediately.
+		// Adding to the batch synchronously preserves the order in which messages were received.
+		cs.sendResponse(chargingStation.ID(), meter.NewMeterValuesResponse(), nil, requestId)
+		batcher.add(chargingStation.ID(), request.(*meter.MeterValuesRequest))
+		return
+	} else if handlers.handlerForProfile(profile.Name) == nil {
+		cs.notSupportedError(chargingStation.ID(), requestId, action)
+		return
 	}
-	var response ocpp.Response
-	var err error
-	// Execute in separate goroutine, so the caller goroutine is available
-	go func() {
+	respond := cs.timedResponder(chargingStation.ID(), requestId, action)
+	process := func() {
+		var response ocpp.Response
+		var err error
 		switch action {
 		case provisioning.BootNotificationFeatureName:
-			response, err = cs.provisioningHandler.OnBootNotification(chargingStation.ID(), request.(*provisioning.BootNotificationRequest))
+			bootNotification := request.(*provisioning.BootNotificationRequest)
+			response, err = handlers.provisioningHandler.OnBootNotification(chargingStation.ID(), bootNotification)
+			if err == nil && features.networkDiagnostics != nil {
+				features.networkDiagnostics.applyBootNotification(chargingStation.ID(), bootNotification)
+			}
 		case authorization.AuthorizeFeatureName:
-			response, err = cs.authorizationHandler.OnAuthorize(chargingStation.ID(), request.(*authorization.AuthorizeRequest))
+			response, err = handlers.authorizationHandler.OnAuthorize(chargingStation.ID(), request.(*authorization.AuthorizeRequest))
+		case batteryswap.BatterySwapFeatureName:
+			response, err = handlers.batterySwapHandler.OnBatterySwap(chargingStation.ID(), request.(*batteryswap.BatterySwapRequest))
 		case smartcharging.ClearedChargingLimitFeatureName:
-			response, err = cs.smartChargingHandler.OnClearedChargingLimit(chargingStation.ID(), request.(*smartcharging.ClearedChargingLimitRequest))
+			response, err = handlers.smartChargingHandler.OnClearedChargingLimit(chargingStation.ID(), request.(*smartcharging.ClearedChargingLimitRequest))
 		case data.DataTransferFeatureName:
-			response, err = cs.dataHandler.OnDataTransfer(chargingStation.ID(), request.(*data.DataTransferRequest))
+			response, err = handlers.dataHandler.OnDataTransfer(chargingStation.ID(), request.(*data.DataTransferRequest))
 		case firmware.FirmwareStatusNotificationFeatureName:
-			response, err = cs.firmwareHandler.OnFirmwareStatusNotification(chargingStation.ID(), request.(*firmware.FirmwareStatusNotificationRequest))
+			response, err = handlers.firmwareHandler.OnFirmwareStatusNotification(chargingStation.ID(), request.(*firmware.FirmwareStatusNotificationRequest))
 		case iso15118.Get15118EVCertificateFeatureName:
-			response, err = cs.iso15118Handler.OnGet15118EVCertificate(chargingStation.ID(), request.(*iso15118.Get15118EVCertificateRequest))
+			response, err = handlers.iso15118Handler.OnGet15118EVCertificate(chargingStation.ID(), request.(*iso15118.Get15118EVCertificateRequest))
 		case iso15118.GetCertificateStatusFeatureName:
-			response, err = cs.iso15118Handler.OnGetCertificateStatus(chargingStation.ID(), request.(*iso15118.GetCertificateStatusRequest))
+			response, err = handlers.iso15118Handler.OnGetCertificateStatus(chargingStation.ID(), request.(*iso15118.GetCertificateStatusRequest))
 		case availability.HeartbeatFeatureName:
-			response, err = cs.availabilityHandler.OnHeartbeat(chargingStation.ID(), request.(*availability.HeartbeatRequest))
+			response, err = handlers.availabilityHandler.OnHeartbeat(chargingStation.ID(), request.(*availability.HeartbeatRequest))
 		case diagnostics.LogStatusNotificationFeatureName:
-			response, err = cs.diagnosticsHandler.OnLogStatusNotification(chargingStation.ID(), request.(*diagnostics.LogStatusNotificationRequest))
+			notification := request.(*diagnostics.LogStatusNotificationRequest)
+			response, err = handlers.diagnosticsHandler.OnLogStatusNotification(chargingStation.ID(), notification)
+			if notification.Status.IsFinal() {
+				cs.removeLogRequest(chargingStation.ID(), notification.RequestID)
+			}
 		case meter.MeterValuesFeatureName:
-			response, err = cs.meterHandler.OnMeterValues(chargingStation.ID(), request.(*meter.MeterValuesRequest))
+			response, err = handlers.meterHandler.OnMeterValues(chargingStation.ID(), request.(*meter.MeterValuesRequest))
 		case smartcharging.NotifyChargingLimitFeatureName:
-			response, err = cs.smartChargingHandler.OnNotifyChargingLimit(chargingStation.ID(), request.(*smartcharging.NotifyChargingLimitRequest))
+			response, err = handlers.smartChargingHandler.OnNotifyChargingLimit(chargingStation.ID(), request.(*smartcharging.NotifyChargingLimitRequest))
 		case diagnostics.NotifyCustomerInformationFeatureName:
-			response, err = cs.diagnosticsHandler.OnNotifyCustomerInformation(chargingStation.ID(), request.(*diagnostics.NotifyCustomerInformationRequest))
+			response, err = handlers.diagnosticsHandler.OnNotifyCustomerInformation(chargingStation.ID(), request.(*diagnostics.NotifyCustomerInformationRequest))
 		case display.NotifyDisplayMessagesFeatureName:
-			response, err = cs.displayHandler.OnNotifyDisplayMessages(chargingStation.ID(), request.(*display.NotifyDisplayMessagesRequest))
+			response, err = handlers.displayHandler.OnNotifyDisplayMessages(chargingStation.ID(), request.(*display.NotifyDisplayMessagesRequest))
 		case smartcharging.NotifyEVChargingNeedsFeatureName:
-			response, err = cs.smartChargingHandler.OnNotifyEVChargingNeeds(chargingStation.ID(), request.(*smartcharging.NotifyEVChargingNeedsRequest))
+			response, err = handlers.smartChargingHandler.OnNotifyEVChargingNeeds(chargingStation.ID(), request.(*smartcharging.NotifyEVChargingNeedsRequest))
 		case smartcharging.NotifyEVChargingScheduleFeatureName:
-			response, err = cs.smartChargingHandler.OnNotifyEVChargingSchedule(chargingStation.ID(), request.(*smartcharging.NotifyEVChargingScheduleRequest))
+			response, err = handlers.smartChargingHandler.OnNotifyEVChargingSchedule(chargingStation.ID(), request.(*smartcharging.NotifyEVChargingScheduleRequest))
 		case diagnostics.NotifyEventFeatureName:
-			response, err = cs.diagnosticsHandler.OnNotifyEvent(chargingStation.ID(), request.(*diagnostics.NotifyEventRequest))
+			response, err = handlers.diagnosticsHandler.OnNotifyEvent(chargingStation.ID(), request.(*diagnostics.NotifyEventRequest))
 		case diagnostics.NotifyMonitoringReportFeatureName:
-			response, err = cs.diagnosticsHandler.OnNotifyMonitoringReport(chargingStation.ID(), request.(*diagnostics.NotifyMonitoringReportRequest))
+			response, err = handlers.diagnosticsHandler.OnNotifyMonitoringReport(chargingStation.ID(), request.(*diagnostics.NotifyMonitoringReportRequest))
 		case provisioning.NotifyReportFeatureName:
-			response, err = cs.provisioningHandler.OnNotifyReport(chargingStation.ID(), request.(*provisioning.NotifyReportRequest))
+			report := request.(*provisioning.NotifyReportRequest)
+			if warningHandler := features.reportWarningHandler; warningHandler != nil {
+				if warnings := report.CheckCharacteristics(); len(warnings) > 0 {
+					warningHandler(chargingStation.ID(), report.RequestID, warnings)
+				}
+			}
+			cs.capabilities.update(chargingStation.ID(), report.ReportData)
+			response, err = handlers.provisioningHandler.OnNotifyReport(chargingStation.ID(), report)
 		case firmware.PublishFirmwareStatusNotificationFeatureName:
-			response, err = cs.firmwareHandler.OnPublishFirmwareStatusNotification(chargingStation.ID(), request.(*firmware.PublishFirmwareStatusNotificationRequest))
+			response, err = handlers.firmwareHandler.OnPublishFirmwareStatusNotification(chargingStation.ID(), request.(*firmware.PublishFirmwareStatusNotificationRequest))
+		case der.ReportDERControlFeatureName:
+			response, err = handlers.derControlHandler.OnReportDERControl(chargingStation.ID(), request.(*der.ReportDERControlRequest))
 		case smartcharging.ReportChargingProfilesFeatureName:
-			response, err = cs.smartChargingHandler.OnReportChargingProfiles(chargingStation.ID(), request.(*smartcharging.ReportChargingProfilesRequest))
+			report := request.(*smartcharging.ReportChargingProfilesRequest)
+			cs.installedProfiles.report(chargingStation.ID(), report)
+			response, err = handlers.smartChargingHandler.OnReportChargingProfiles(chargingStation.ID(), report)
 		case reservation.ReservationStatusUpdateFeatureName:
-			response, err = cs.reservationHandler.OnReservationStatusUpdate(chargingStation.ID(), request.(*reservation.ReservationStatusUpdateRequest))
+			response, err = handlers.reservationHandler.OnReservationStatusUpdate(chargingStation.ID(), request.(*reservation.ReservationStatusUpdateRequest))
 		case security.SecurityEventNotificationFeatureName:
-			response, err = cs.securityHandler.OnSecurityEventNotification(chargingStation.ID(), request.(*security.SecurityEventNotificationRequest))
+			response, err = handlers.securityHandler.OnSecurityEventNotification(chargingStation.ID(), request.(*security.SecurityEventNotificationRequest))
 		case security.SignCertificateFeatureName:
-			response, err = cs.securityHandler.OnSignCertificate(chargingStation.ID(), request.(*security.SignCertificateRequest))
+			response, err = handlers.securityHandler.OnSignCertificate(chargingStation.ID(), request.(*security.SignCertificateRequest))
 		case availability.StatusNotificationFeatureName:
-			response, err = cs.availabilityHandler.OnStatusNotification(chargingStation.ID(), request.(*availability.StatusNotificationRequest))
+			if debouncer := features.statusDebouncer; debouncer != nil {
+				// Acknowledge immediately, the handler is invoked once the debounce window expires
+				debouncer.add(chargingStation.ID(), request.(*availability.StatusNotificationRequest))
+				response = availability.NewStatusNotificationResponse()
+			} else {
+				response, err = handlers.availabilityHandler.OnStatusNotification(chargingStation.ID(), request.(*availability.StatusNotificationRequest))
+			}
 		case transactions.TransactionEventFeatureName:
-			response, err = cs.transactionsHandler.OnTransactionEvent(chargingStation.ID(), request.(*transactions.TransactionEventRequest))
+			event := request.(*transactions.TransactionEventRequest)
+			if event.EventType == transactions.TransactionEventEnded {
+				cs.costUpdates.stop(chargingStation.ID(), event.TransactionInfo.TransactionID)
+			}
+			response, err = handlers.transactionsHandler.OnTransactionEvent(chargingStation.ID(), event)
+			if err == nil && features.transactionTracker != nil {
+				features.transactionTracker.apply(chargingStation.ID(), event)
+			}
+			if transactionResponse, ok := response.(*transactions.TransactionEventResponse); ok && err == nil && features.tariffEngine != nil {
+				cs.applyTariff(features, chargingStation.ID(), event, transactionResponse)
+			}
 		default:
 			cs.notSupportedError(chargingStation.ID(), requestId, action)
 			return
 		}
-		cs.sendResponse(chargingStation.ID(), response, err, requestId)
-	}()
+		var sent bool
+		if action == provisioning.BootNotificationFeatureName {
+			sent = cs.respondToBoot(features, chargingStation.ID(), request.(*provisioning.BootNotificationRequest), response, err, respond)
+		} else {
+			sent = respond(response, err)
+		}
+		cs.responseHooks.run(request, sent)
+	}
+	if serializer := features.transactionSerializer; serializer != nil && action == transactions.TransactionEventFeatureName {
+		// Events of the same transaction are processed one at a time, in the order they were received
+		event := request.(*transactions.TransactionEventRequest)
+		serializer.run(chargingStation.ID(), event.TransactionInfo.TransactionID, process)
+		return
+	}
+	// Execute in separate goroutine, so the caller goroutine is available
+	go process()
 }
 
 func (cs *csms) handleIncomingResponse(chargingStation ChargingStationConnection, response ocpp.Response, requestId string) {
@@ -1025,9 +1797,14 @@ func (cs *csms) handleIncomingResponse(chargingStation ChargingStationConnection
 }
 
 func (cs *csms) handleIncomingError(chargingStation ChargingStationConnection, err *ocpp.Error, details interface{}) {
+	var callbackErr error = err
+	if invalidResponse, ok := details.(*ocppj.InvalidResponseError); ok {
+		// Expose the raw payload of the invalid response
+		callbackErr = invalidResponse
+	}
 	if callback, ok := cs.callbackQueue.Dequeue(chargingStation.ID()); ok {
 		// Execute in separate goroutine, so the caller goroutine is available
-		go callback(nil, err)
+		go callback(nil, callbackErr)
 	} else {
 		cs.error(fmt.Errorf("no handler available for call error %w from client %s", err, chargingStation.ID()))
 	}
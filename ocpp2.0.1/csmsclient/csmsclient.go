@@ -0,0 +1,249 @@
+// Package csmsclient provides a synchronous facade on top of an OCPP 2.0.1 CSMS.
+//
+// The CSMS API is asynchronous: every request to a charging station takes a callback, which is invoked once
+// the response was received. This is the preferred model for long-running servers, but requires some plumbing
+// for one-off operational tasks and scripts. A Client blocks instead, until the response was received:
+//
+//	client := csmsclient.New(csms)
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	response, err := client.Reset(ctx, "station1", provisioning.ResetTypeImmediate)
+//
+// Canceling the context only stops waiting for the response. The request itself is not withdrawn,
+// and will still be completed or timed out by the underlying CSMS.
+package csmsclient
+
+import (
+	"context"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/authorization"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/data"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/remotecontrol"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Client issues requests to charging stations via a CSMS, blocking until the response was received.
+// All methods are safe for concurrent use.
+type Client struct {
+	csms ocpp2.CSMS
+}
+
+// New creates a new Client on top of a CSMS. The CSMS needs to be started separately.
+func New(csms ocpp2.CSMS) *Client {
+	return &Client{csms: csms}
+}
+
+// Sends a request via the passed function and waits for the callback to be invoked, or for the context to be done.
+func wait(ctx context.Context, send func(done func(err error)) error) error {
+	errC := make(chan error, 1)
+	if err := send(func(err error) { errC <- err }); err != nil {
+		return err
+	}
+	select {
+	case err := <-errC:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendRequest sends an arbitrary request to a charging station and returns its response.
+func (c *Client) SendRequest(ctx context.Context, clientId string, request ocpp.Request) (ocpp.Response, error) {
+	var response ocpp.Response
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.SendRequestAsync(clientId, request, func(r ocpp.Response, err error) {
+			response = r
+			done(err)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ChangeAvailability requests a charging station to change its availability and returns its response.
+func (c *Client) ChangeAvailability(ctx context.Context, clientId string, operationalStatus availability.OperationalStatus, props ...func(*availability.ChangeAvailabilityRequest)) (*availability.ChangeAvailabilityResponse, error) {
+	var response *availability.ChangeAvailabilityResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.ChangeAvailability(clientId, func(r *availability.ChangeAvailabilityResponse, err error) {
+			response = r
+			done(err)
+		}, operationalStatus, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ClearCache requests a charging station to clear its authorization cache and returns its response.
+func (c *Client) ClearCache(ctx context.Context, clientId string, props ...func(*authorization.ClearCacheRequest)) (*authorization.ClearCacheResponse, error) {
+	var response *authorization.ClearCacheResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.ClearCache(clientId, func(r *authorization.ClearCacheResponse, err error) {
+			response = r
+			done(err)
+		}, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DataTransfer sends vendor-specific data to a charging station and returns its response.
+func (c *Client) DataTransfer(ctx context.Context, clientId string, vendorId string, props ...func(*data.DataTransferRequest)) (*data.DataTransferResponse, error) {
+	var response *data.DataTransferResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.DataTransfer(clientId, func(r *data.DataTransferResponse, err error) {
+			response = r
+			done(err)
+		}, vendorId, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetBaseReport requests a charging station to send a base report and returns its response.
+// The report itself is sent by the charging station via NotifyReport messages.
+func (c *Client) GetBaseReport(ctx context.Context, clientId string, requestId int, reportBase provisioning.ReportBaseType, props ...func(*provisioning.GetBaseReportRequest)) (*provisioning.GetBaseReportResponse, error) {
+	var response *provisioning.GetBaseReportResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.GetBaseReport(clientId, func(r *provisioning.GetBaseReportResponse, err error) {
+			response = r
+			done(err)
+		}, requestId, reportBase, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetTransactionStatus requests the status of a transaction from a charging station and returns its response.
+func (c *Client) GetTransactionStatus(ctx context.Context, clientId string, props ...func(*transactions.GetTransactionStatusRequest)) (*transactions.GetTransactionStatusResponse, error) {
+	var response *transactions.GetTransactionStatusResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.GetTransactionStatus(clientId, func(r *transactions.GetTransactionStatusResponse, err error) {
+			response = r
+			done(err)
+		}, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetVariables requests the values of variables from a charging station and returns its response.
+func (c *Client) GetVariables(ctx context.Context, clientId string, variableData []provisioning.GetVariableData, props ...func(*provisioning.GetVariablesRequest)) (*provisioning.GetVariablesResponse, error) {
+	var response *provisioning.GetVariablesResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.GetVariables(clientId, func(r *provisioning.GetVariablesResponse, err error) {
+			response = r
+			done(err)
+		}, variableData, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RequestStartTransaction requests a charging station to start a transaction and returns its response.
+func (c *Client) RequestStartTransaction(ctx context.Context, clientId string, remoteStartID int, idToken types.IdToken, props ...func(*remotecontrol.RequestStartTransactionRequest)) (*remotecontrol.RequestStartTransactionResponse, error) {
+	var response *remotecontrol.RequestStartTransactionResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.RequestStartTransaction(clientId, func(r *remotecontrol.RequestStartTransactionResponse, err error) {
+			response = r
+			done(err)
+		}, remoteStartID, idToken, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RequestStopTransaction requests a charging station to stop a transaction and returns its response.
+func (c *Client) RequestStopTransaction(ctx context.Context, clientId string, transactionID string, props ...func(*remotecontrol.RequestStopTransactionRequest)) (*remotecontrol.RequestStopTransactionResponse, error) {
+	var response *remotecontrol.RequestStopTransactionResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.RequestStopTransaction(clientId, func(r *remotecontrol.RequestStopTransactionResponse, err error) {
+			response = r
+			done(err)
+		}, transactionID, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Reset requests a charging station (or a single EVSE) to reset and returns its response.
+func (c *Client) Reset(ctx context.Context, clientId string, t provisioning.ResetType, props ...func(*provisioning.ResetRequest)) (*provisioning.ResetResponse, error) {
+	var response *provisioning.ResetResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.Reset(clientId, func(r *provisioning.ResetResponse, err error) {
+			response = r
+			done(err)
+		}, t, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SetVariables requests a charging station to set the values of variables and returns its response.
+func (c *Client) SetVariables(ctx context.Context, clientId string, variableData []provisioning.SetVariableData, props ...func(*provisioning.SetVariablesRequest)) (*provisioning.SetVariablesResponse, error) {
+	var response *provisioning.SetVariablesResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.SetVariables(clientId, func(r *provisioning.SetVariablesResponse, err error) {
+			response = r
+			done(err)
+		}, variableData, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// TriggerMessage requests a charging station to send a specific message and returns its response.
+func (c *Client) TriggerMessage(ctx context.Context, clientId string, requestedMessage remotecontrol.MessageTrigger, props ...func(*remotecontrol.TriggerMessageRequest)) (*remotecontrol.TriggerMessageResponse, error) {
+	var response *remotecontrol.TriggerMessageResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.TriggerMessage(clientId, func(r *remotecontrol.TriggerMessageResponse, err error) {
+			response = r
+			done(err)
+		}, requestedMessage, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UnlockConnector requests a charging station to unlock a connector and returns its response.
+func (c *Client) UnlockConnector(ctx context.Context, clientId string, evseID int, connectorID int, props ...func(*remotecontrol.UnlockConnectorRequest)) (*remotecontrol.UnlockConnectorResponse, error) {
+	var response *remotecontrol.UnlockConnectorResponse
+	err := wait(ctx, func(done func(err error)) error {
+		return c.csms.UnlockConnector(clientId, func(r *remotecontrol.UnlockConnectorResponse, err error) {
+			response = r
+			done(err)
+		}, evseID, connectorID, props...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
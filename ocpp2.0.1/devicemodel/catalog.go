@@ -0,0 +1,202 @@
+package devicemodel
+
+import "github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+
+// Location of a standardized component within the charging station.
+type componentLocation int
+
+const (
+	locationStation   componentLocation = iota // The component exists once, at charging station level.
+	locationEVSE                               // The component exists once per EVSE.
+	locationConnector                          // The component exists once per connector.
+)
+
+// A standardized variable of the OCPP 2.0.1 device model (see part 2, appendix 3).
+type catalogVariable struct {
+	name     string
+	instance string
+	dataType provisioning.DataType
+	required bool
+}
+
+// A standardized component of the OCPP 2.0.1 device model (see part 2, appendix 3).
+//
+// Variables of an optional component are only required, if the component is part of the report,
+// i.e. if the charging station supports the corresponding functional block.
+type catalogComponent struct {
+	name      string
+	location  componentLocation
+	optional  bool
+	variables []catalogVariable
+}
+
+func required(name string, instance string, dataType provisioning.DataType) catalogVariable {
+	return catalogVariable{name: name, instance: instance, dataType: dataType, required: true}
+}
+
+func optional(name string, instance string, dataType provisioning.DataType) catalogVariable {
+	return catalogVariable{name: name, instance: instance, dataType: dataType}
+}
+
+// The standardized components and variables, against which a device model is checked by CheckConformance.
+var catalog = []catalogComponent{
+	{name: "ChargingStation", location: locationStation, variables: []catalogVariable{
+		required("AvailabilityState", "", provisioning.TypeOptionList),
+		required("Available", "", provisioning.TypeBoolean),
+		required("SupplyPhases", "", provisioning.TypeInteger),
+		optional("Model", "", provisioning.TypeString),
+		optional("VendorName", "", provisioning.TypeString),
+	}},
+	{name: "EVSE", location: locationEVSE, variables: []catalogVariable{
+		required("AvailabilityState", "", provisioning.TypeOptionList),
+		required("Available", "", provisioning.TypeBoolean),
+		required("Power", "", provisioning.TypeDecimal),
+		required("SupplyPhases", "", provisioning.TypeInteger),
+		optional("AllowReset", "", provisioning.TypeBoolean),
+		optional("EvseId", "", provisioning.TypeString),
+	}},
+	{name: "Connector", location: locationConnector, variables: []catalogVariable{
+		required("AvailabilityState", "", provisioning.TypeOptionList),
+		required("Available", "", provisioning.TypeBoolean),
+		required("ConnectorType", "", provisioning.TypeOptionList),
+		required("SupplyPhases", "", provisioning.TypeInteger),
+	}},
+	{name: "DeviceDataCtrlr", location: locationStation, variables: []catalogVariable{
+		required("BytesPerMessage", "GetReport", provisioning.TypeInteger),
+		required("BytesPerMessage", "GetVariables", provisioning.TypeInteger),
+		required("BytesPerMessage", "SetVariables", provisioning.TypeInteger),
+		required("ItemsPerMessage", "GetReport", provisioning.TypeInteger),
+		required("ItemsPerMessage", "GetVariables", provisioning.TypeInteger),
+		required("ItemsPerMessage", "SetVariables", provisioning.TypeInteger),
+		optional("ConfigurationValueSize", "", provisioning.TypeInteger),
+		optional("ReportingValueSize", "", provisioning.TypeInteger),
+		optional("ValueSize", "", provisioning.TypeInteger),
+	}},
+	{name: "ClockCtrlr", location: locationStation, variables: []catalogVariable{
+		required("DateTime", "", provisioning.TypeDateTime),
+		required("TimeSource", "", provisioning.TypeSequenceList),
+		optional("NtpServerUri", "", provisioning.TypeString),
+		optional("TimeOffset", "", provisioning.TypeString),
+		optional("TimeZone", "", provisioning.TypeString),
+	}},
+	{name: "OCPPCommCtrlr", location: locationStation, variables: []catalogVariable{
+		required("FileTransferProtocols", "", provisioning.TypeMemberList),
+		required("MessageAttemptInterval", "TransactionEvent", provisioning.TypeInteger),
+		required("MessageAttempts", "TransactionEvent", provisioning.TypeInteger),
+		required("MessageTimeout", "Default", provisioning.TypeInteger),
+		required("NetworkConfigurationPriority", "", provisioning.TypeSequenceList),
+		required("NetworkProfileConnectionAttempts", "", provisioning.TypeInteger),
+		required("OfflineThreshold", "", provisioning.TypeInteger),
+		required("ResetRetries", "", provisioning.TypeInteger),
+		required("UnlockOnEVSideDisconnect", "", provisioning.TypeBoolean),
+		optional("ActiveNetworkProfile", "", provisioning.TypeInteger),
+		optional("HeartbeatInterval", "", provisioning.TypeInteger),
+		optional("QueueAllMessages", "", provisioning.TypeBoolean),
+		optional("RetryBackOffRandomRange", "", provisioning.TypeInteger),
+		optional("RetryBackOffRepeatTimes", "", provisioning.TypeInteger),
+		optional("RetryBackOffWaitMinimum", "", provisioning.TypeInteger),
+		optional("WebSocketPingInterval", "", provisioning.TypeInteger),
+	}},
+	{name: "SecurityCtrlr", location: locationStation, variables: []catalogVariable{
+		required("CertificateEntries", "", provisioning.TypeInteger),
+		required("OrganizationName", "", provisioning.TypeString),
+		required("SecurityProfile", "", provisioning.TypeInteger),
+		optional("AdditionalRootCertificateCheck", "", provisioning.TypeBoolean),
+		optional("CertSigningRepeatTimes", "", provisioning.TypeInteger),
+		optional("CertSigningWaitMinimum", "", provisioning.TypeInteger),
+		optional("Identity", "", provisioning.TypeString),
+		optional("MaxCertificateChainSize", "", provisioning.TypeInteger),
+	}},
+	{name: "AuthCtrlr", location: locationStation, variables: []catalogVariable{
+		required("AuthorizeRemoteStart", "", provisioning.TypeBoolean),
+		required("LocalAuthorizeOffline", "", provisioning.TypeBoolean),
+		required("LocalPreAuthorize", "", provisioning.TypeBoolean),
+		optional("AdditionalInfoItemsPerMessage", "", provisioning.TypeInteger),
+		optional("DisableRemoteAuthorization", "", provisioning.TypeBoolean),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("MasterPassGroupId", "", provisioning.TypeString),
+		optional("OfflineTxForUnknownIdEnabled", "", provisioning.TypeBoolean),
+	}},
+	{name: "TxCtrlr", location: locationStation, variables: []catalogVariable{
+		required("EVConnectionTimeOut", "", provisioning.TypeInteger),
+		required("StopTxOnEVSideDisconnect", "", provisioning.TypeBoolean),
+		required("StopTxOnInvalidId", "", provisioning.TypeBoolean),
+		required("TxStartPoint", "", provisioning.TypeMemberList),
+		required("TxStopPoint", "", provisioning.TypeMemberList),
+		optional("MaxEnergyOnInvalidId", "", provisioning.TypeInteger),
+		optional("TxBeforeAcceptedEnabled", "", provisioning.TypeBoolean),
+	}},
+	{name: "SampledDataCtrlr", location: locationStation, variables: []catalogVariable{
+		required("TxEndedInterval", "", provisioning.TypeInteger),
+		required("TxEndedMeasurands", "", provisioning.TypeMemberList),
+		required("TxStartedMeasurands", "", provisioning.TypeMemberList),
+		required("TxUpdatedInterval", "", provisioning.TypeInteger),
+		required("TxUpdatedMeasurands", "", provisioning.TypeMemberList),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("RegisterValuesWithoutPhases", "", provisioning.TypeBoolean),
+		optional("SignReadings", "", provisioning.TypeBoolean),
+	}},
+	{name: "AlignedDataCtrlr", location: locationStation, variables: []catalogVariable{
+		required("Interval", "", provisioning.TypeInteger),
+		required("Measurands", "", provisioning.TypeMemberList),
+		required("TxEndedInterval", "", provisioning.TypeInteger),
+		required("TxEndedMeasurands", "", provisioning.TypeMemberList),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("SendDuringIdle", "", provisioning.TypeBoolean),
+		optional("SignReadings", "", provisioning.TypeBoolean),
+	}},
+	{name: "LocalAuthListCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		required("BytesPerMessage", "", provisioning.TypeInteger),
+		required("Entries", "", provisioning.TypeInteger),
+		required("ItemsPerMessage", "", provisioning.TypeInteger),
+		optional("Available", "", provisioning.TypeBoolean),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("Storage", "", provisioning.TypeInteger),
+	}},
+	{name: "AuthCacheCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		optional("Available", "", provisioning.TypeBoolean),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("LifeTime", "", provisioning.TypeInteger),
+		optional("Policy", "", provisioning.TypeOptionList),
+		optional("Storage", "", provisioning.TypeInteger),
+	}},
+	{name: "SmartChargingCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		required("Entries", "ChargingProfiles", provisioning.TypeInteger),
+		required("LimitChangeSignificance", "", provisioning.TypeDecimal),
+		required("PeriodsPerSchedule", "", provisioning.TypeInteger),
+		required("ProfileStackLevel", "", provisioning.TypeInteger),
+		required("RateUnit", "", provisioning.TypeMemberList),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("ExternalControlSignalsEnabled", "", provisioning.TypeBoolean),
+		optional("NotifyChargingLimitWithSchedules", "", provisioning.TypeBoolean),
+		optional("Phases3to1", "", provisioning.TypeBoolean),
+	}},
+	{name: "ReservationCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		optional("Available", "", provisioning.TypeBoolean),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("NonEvseSpecific", "", provisioning.TypeBoolean),
+	}},
+	{name: "TariffCostCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		required("Currency", "", provisioning.TypeString),
+		required("TariffFallbackMessage", "", provisioning.TypeString),
+		required("TotalCostFallbackMessage", "", provisioning.TypeString),
+		optional("Enabled", "Cost", provisioning.TypeBoolean),
+		optional("Enabled", "Tariff", provisioning.TypeBoolean),
+	}},
+	{name: "DisplayMessageCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		required("DisplayMessages", "", provisioning.TypeInteger),
+		required("SupportedFormats", "", provisioning.TypeMemberList),
+		required("SupportedPriorities", "", provisioning.TypeMemberList),
+		optional("Available", "", provisioning.TypeBoolean),
+		optional("Enabled", "", provisioning.TypeBoolean),
+	}},
+	{name: "MonitoringCtrlr", location: locationStation, optional: true, variables: []catalogVariable{
+		required("BytesPerMessage", "ClearVariableMonitoring", provisioning.TypeInteger),
+		required("BytesPerMessage", "SetVariableMonitoring", provisioning.TypeInteger),
+		required("ItemsPerMessage", "ClearVariableMonitoring", provisioning.TypeInteger),
+		required("ItemsPerMessage", "SetVariableMonitoring", provisioning.TypeInteger),
+		optional("Available", "", provisioning.TypeBoolean),
+		optional("Enabled", "", provisioning.TypeBoolean),
+		optional("OfflineQueuingSeverity", "", provisioning.TypeInteger),
+	}},
+}
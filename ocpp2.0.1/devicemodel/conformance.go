@@ -0,0 +1,153 @@
+package devicemodel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// FindingKind describes the type of a conformance issue found by CheckConformance.
+type FindingKind string
+
+const (
+	FindingMissingVariable  FindingKind = "MissingVariable"  // A mandatory standardized variable isn't part of the report.
+	FindingDataTypeMismatch FindingKind = "DataTypeMismatch" // The declared data type differs from the standardized data type.
+	FindingInvalidValue     FindingKind = "InvalidValue"     // The actual value cannot be parsed as the standardized data type.
+)
+
+// Finding is a single conformance issue of a device model.
+type Finding struct {
+	Kind      FindingKind
+	Component types.Component
+	Variable  types.Variable
+	Reason    string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v.%v: %v", f.Component.Name, f.Variable.Name, f.Reason)
+}
+
+// CheckConformance validates an assembled device model report against the standardized components and variables
+// of the OCPP 2.0.1 specification, and returns all findings. Returns nil, if the report is conformant.
+//
+// The following issues are reported:
+//   - mandatory variables, which aren't part of the report (FindingMissingVariable)
+//   - standardized variables, declaring a different data type in their characteristics (FindingDataTypeMismatch)
+//   - standardized variables, whose actual value isn't valid for the standardized data type (FindingInvalidValue)
+//
+// EVSE and Connector variables are checked for every EVSE and connector referenced by the report.
+// Variables of optional controllers (e.g. LocalAuthListCtrlr) are only mandatory, if the controller is part of the report.
+// Non-standardized components and variables are ignored.
+func CheckConformance(report []provisioning.ReportData) []Finding {
+	evses, connectors := referencedEVSEs(report)
+	var findings []Finding
+	for _, catalogComponent := range catalog {
+		if catalogComponent.optional && len(provisioning.FindComponents(report, catalogComponent.name)) == 0 {
+			// Functional block isn't supported by the charging station
+			continue
+		}
+		var components []types.Component
+		switch catalogComponent.location {
+		case locationStation:
+			components = []types.Component{{Name: catalogComponent.name}}
+		case locationEVSE:
+			for _, evse := range evses {
+				components = append(components, types.Component{Name: catalogComponent.name, EVSE: &types.EVSE{ID: evse.ID}})
+			}
+		case locationConnector:
+			for _, connector := range connectors {
+				connectorID := *connector.ConnectorID
+				components = append(components, types.Component{Name: catalogComponent.name, EVSE: &types.EVSE{ID: connector.ID, ConnectorID: &connectorID}})
+			}
+		}
+		for _, component := range components {
+			for _, catalogVariable := range catalogComponent.variables {
+				variable := types.Variable{Name: catalogVariable.name, Instance: catalogVariable.instance}
+				data, ok := provisioning.FindVariable(report, component, variable)
+				if !ok {
+					if catalogVariable.required {
+						findings = append(findings, Finding{Kind: FindingMissingVariable, Component: component, Variable: variable, Reason: "mandatory variable is missing"})
+					}
+					continue
+				}
+				if finding, ok := checkDataType(data, catalogVariable.dataType); ok {
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// Returns all EVSEs and connectors referenced by any component of the report, sorted by ID.
+func referencedEVSEs(report []provisioning.ReportData) (evses []types.EVSE, connectors []types.EVSE) {
+	evseIDs := map[int]bool{}
+	connectorIDs := map[[2]int]bool{}
+	for _, data := range report {
+		evse := data.Component.EVSE
+		if evse == nil {
+			continue
+		}
+		evseIDs[evse.ID] = true
+		if evse.ConnectorID != nil {
+			connectorIDs[[2]int{evse.ID, *evse.ConnectorID}] = true
+		}
+	}
+	for id := range evseIDs {
+		evses = append(evses, types.EVSE{ID: id})
+	}
+	for key := range connectorIDs {
+		connectorID := key[1]
+		connectors = append(connectors, types.EVSE{ID: key[0], ConnectorID: &connectorID})
+	}
+	sort.Slice(evses, func(i, j int) bool {
+		return evses[i].ID < evses[j].ID
+	})
+	sort.Slice(connectors, func(i, j int) bool {
+		if connectors[i].ID != connectors[j].ID {
+			return connectors[i].ID < connectors[j].ID
+		}
+		return *connectors[i].ConnectorID < *connectors[j].ConnectorID
+	})
+	return evses, connectors
+}
+
+// Checks the declared data type and the actual value of a reported variable against the standardized data type.
+func checkDataType(data provisioning.ReportData, expected provisioning.DataType) (Finding, bool) {
+	finding := Finding{Component: data.Component, Variable: data.Variable}
+	if data.VariableCharacteristics != nil && data.VariableCharacteristics.DataType != expected {
+		finding.Kind = FindingDataTypeMismatch
+		finding.Reason = fmt.Sprintf("declared data type %v, expected %v", data.VariableCharacteristics.DataType, expected)
+		return finding, true
+	}
+	attribute, ok := data.Attribute(types.AttributeActual)
+	if !ok || attribute.Value == "" {
+		// Nothing to check, e.g. for write-only variables
+		return Finding{}, false
+	}
+	if !isValidValue(attribute.Value, expected) {
+		finding.Kind = FindingInvalidValue
+		finding.Reason = fmt.Sprintf("actual value %q is not a valid %v", attribute.Value, expected)
+		return finding, true
+	}
+	return Finding{}, false
+}
+
+func isValidValue(value string, dataType provisioning.DataType) bool {
+	var err error
+	switch dataType {
+	case provisioning.TypeInteger:
+		_, err = strconv.Atoi(value)
+	case provisioning.TypeDecimal:
+		_, err = strconv.ParseFloat(value, 64)
+	case provisioning.TypeBoolean:
+		return value == "true" || value == "false"
+	case provisioning.TypeDateTime:
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	return err == nil
+}
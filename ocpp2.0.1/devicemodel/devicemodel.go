@@ -0,0 +1,101 @@
+// Package devicemodel provides a fluent builder for referencing variables of the OCPP 2.0.1 device model.
+//
+// Requests such as GetVariables and SetVariables reference a variable via a nested component (name, instance, EVSE)
+// and variable (name, instance). A VariableRef builds these structures from a single expression:
+//
+//	setVariableData := []provisioning.SetVariableData{
+//		devicemodel.Var("OCPPCommCtrlr", "HeartbeatInterval").Set("10"),
+//		devicemodel.Var("Connector", "Available").OnConnector(1, 2).Set("true"),
+//	}
+//	getVariableData := []provisioning.GetVariableData{
+//		devicemodel.Var("EVSE", "Power").OnEVSE(1).GetAttribute(types.AttributeMaxSet),
+//	}
+//
+// CheckConformance validates a device model reported by a charging station against the standardized
+// components and variables of the OCPP 2.0.1 specification.
+package devicemodel
+
+import (
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// VariableRef references a variable of a component in the device model.
+//
+// A VariableRef is immutable: every method returns a modified copy, hence a reference may be reused as a template.
+type VariableRef struct {
+	component types.Component
+	variable  types.Variable
+}
+
+// Var creates a reference to a variable of a component, located at charging station level.
+func Var(componentName string, variableName string) VariableRef {
+	return VariableRef{
+		component: types.Component{Name: componentName},
+		variable:  types.Variable{Name: variableName},
+	}
+}
+
+// OnEVSE locates the component at the specified EVSE.
+func (r VariableRef) OnEVSE(evseID int) VariableRef {
+	r.component.EVSE = &types.EVSE{ID: evseID}
+	return r
+}
+
+// OnConnector locates the component at the specified connector of an EVSE.
+func (r VariableRef) OnConnector(evseID int, connectorID int) VariableRef {
+	r.component.EVSE = &types.EVSE{ID: evseID, ConnectorID: &connectorID}
+	return r
+}
+
+// ComponentInstance sets the instance of the component, in case multiple instances of the component exist.
+func (r VariableRef) ComponentInstance(instance string) VariableRef {
+	r.component.Instance = instance
+	return r
+}
+
+// Instance sets the instance of the variable, in case multiple instances of the variable exist.
+func (r VariableRef) Instance(instance string) VariableRef {
+	r.variable.Instance = instance
+	return r
+}
+
+// Component returns the referenced component.
+func (r VariableRef) Component() types.Component {
+	component := r.component
+	if component.EVSE != nil {
+		// Don't share the EVSE with the caller
+		evse := *component.EVSE
+		component.EVSE = &evse
+	}
+	return component
+}
+
+// Variable returns the referenced variable.
+func (r VariableRef) Variable() types.Variable {
+	return r.variable
+}
+
+// Get returns the data for requesting the Actual value of the variable via GetVariables.
+func (r VariableRef) Get() provisioning.GetVariableData {
+	return provisioning.GetVariableData{Component: r.Component(), Variable: r.Variable()}
+}
+
+// GetAttribute returns the data for requesting a specific attribute of the variable via GetVariables.
+func (r VariableRef) GetAttribute(attribute types.Attribute) provisioning.GetVariableData {
+	data := r.Get()
+	data.AttributeType = attribute
+	return data
+}
+
+// Set returns the data for setting the Actual value of the variable via SetVariables.
+func (r VariableRef) Set(value string) provisioning.SetVariableData {
+	return provisioning.SetVariableData{AttributeValue: value, Component: r.Component(), Variable: r.Variable()}
+}
+
+// SetAttribute returns the data for setting a specific attribute of the variable via SetVariables.
+func (r VariableRef) SetAttribute(attribute types.Attribute, value string) provisioning.SetVariableData {
+	data := r.Set(value)
+	data.AttributeType = attribute
+	return data
+}
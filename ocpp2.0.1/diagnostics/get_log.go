@@ -46,6 +46,8 @@ func isValidLogStatus(fl validator.FieldLevel) bool {
 }
 
 // LogParameters specifies the requested log and the location to which the log should be sent. It is used in GetLogRequest.
+//
+// If both timestamps are set, the oldest timestamp must not be after the latest timestamp.
 type LogParameters struct {
 	RemoteLocation  string          `json:"remoteLocation" validate:"required,max=512,url"`
 	OldestTimestamp *types.DateTime `json:"oldestTimestamp,omitempty" validate:"omitempty"`
@@ -104,7 +106,15 @@ func NewGetLogResponse(status LogStatus) *GetLogResponse {
 	return &GetLogResponse{Status: status}
 }
 
+func validateLogParameters(sl validator.StructLevel) {
+	parameters := sl.Current().Interface().(LogParameters)
+	if parameters.OldestTimestamp != nil && parameters.LatestTimestamp != nil && parameters.OldestTimestamp.After(parameters.LatestTimestamp.Time) {
+		sl.ReportError(parameters.OldestTimestamp, "OldestTimestamp", "oldestTimestamp", "ltefield", "LatestTimestamp")
+	}
+}
+
 func init() {
 	_ = types.Validate.RegisterValidation("logType", isValidLogType)
 	_ = types.Validate.RegisterValidation("logStatus", isValidLogStatus)
+	types.Validate.RegisterStructValidation(validateLogParameters, LogParameters{})
 }
@@ -48,6 +48,7 @@ type GetMonitoringReportResponse struct {
 // The CSMS sends a GetMonitoringReportRequest to the Charging Station.
 // The Charging Station then responds with a GetMonitoringReportResponse.
 // Asynchronously, the Charging Station will then send a NotifyMonitoringReportRequest to the CSMS for each report part.
+// The parts may be collected via a MonitoringReportAssembler, which signals once the full report was received.
 type GetMonitoringReportFeature struct{}
 
 func (f GetMonitoringReportFeature) GetFeatureName() string {
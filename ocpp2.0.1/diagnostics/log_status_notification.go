@@ -34,6 +34,16 @@ func isValidUploadLogStatus(fl validator.FieldLevel) bool {
 	}
 }
 
+// Returns true if the status is final, i.e. no further LogStatusNotification is expected for the same log upload request.
+func (s UploadLogStatus) IsFinal() bool {
+	switch s {
+	case UploadLogStatusIdle, UploadLogStatusUploading:
+		return false
+	default:
+		return true
+	}
+}
+
 // The field definition of the LogStatusNotification request payload sent by a Charging Station to the CSMS.
 type LogStatusNotificationRequest struct {
 	Status    UploadLogStatus `json:"status" validate:"required,uploadLogStatus"`
@@ -0,0 +1,116 @@
+package diagnostics
+
+import (
+	"github.com/lorenzodonini/ocpp-go/internal/multipart"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Number of completed reports, which a MonitoringReportAssembler retains for late calls to Await and Completed.
+const maxCompletedMonitoringReports = 32
+
+// ConfiguredMonitor is a single monitor configured on a Charging Station, along with the component and variable it monitors.
+// It is a flattened view of the MonitoringData contained in a monitoring report.
+type ConfiguredMonitor struct {
+	Component   types.Component
+	Variable    types.Variable
+	ID          int
+	Type        MonitorType
+	Value       float64
+	Severity    int
+	Transaction bool
+}
+
+// Monitors flattens the passed monitoring data into a list of configured monitors, preserving their order.
+func Monitors(monitoringData []MonitoringData) []ConfiguredMonitor {
+	var monitors []ConfiguredMonitor
+	for _, data := range monitoringData {
+		for _, monitoring := range data.VariableMonitoring {
+			monitors = append(monitors, ConfiguredMonitor{
+				Component:   data.Component,
+				Variable:    data.Variable,
+				ID:          monitoring.ID,
+				Type:        monitoring.Type,
+				Value:       monitoring.Value,
+				Severity:    monitoring.Severity,
+				Transaction: monitoring.Transaction,
+			})
+		}
+	}
+	return monitors
+}
+
+// MonitoringReportAssembler collects the parts of multipart NotifyMonitoringReport messages,
+// which a Charging Station sends in response to a GetMonitoringReport request.
+//
+// Parts are correlated via the requestId of the original request, and ordered via their sequence number.
+// A report is complete once the part with tbc=false and all previous parts were received.
+//
+// The most recently completed reports are retained, so the result of a report that completed
+// before Await was invoked is still delivered. Use Pending and Completed to query the state of a report.
+//
+// A MonitoringReportAssembler is safe for concurrent use.
+type MonitoringReportAssembler struct {
+	assembler *multipart.Assembler
+}
+
+// NewMonitoringReportAssembler creates a new MonitoringReportAssembler without any pending reports.
+func NewMonitoringReportAssembler() *MonitoringReportAssembler {
+	return &MonitoringReportAssembler{assembler: multipart.NewAssembler(joinMonitoringData, maxCompletedMonitoringReports)}
+}
+
+func joinMonitoringData(parts []interface{}) interface{} {
+	monitoringData := []MonitoringData{}
+	for _, part := range parts {
+		monitoringData = append(monitoringData, part.([]MonitoringData)...)
+	}
+	return monitoringData
+}
+
+// Await returns a channel, on which the assembled MonitoringData for the given requestId is delivered once the report is complete.
+// The function may be invoked before or after the first part of the report was received.
+// If the report was completed recently, the channel delivers its result right away.
+//
+// The channel is closed without delivering a result, if the report is discarded or Await is invoked again for the same requestId.
+func (a *MonitoringReportAssembler) Await(requestID int) <-chan []MonitoringData {
+	doneC := make(chan []MonitoringData, 1)
+	a.assembler.Await(requestID, func(result interface{}, ok bool) {
+		if !ok {
+			close(doneC)
+			return
+		}
+		doneC <- result.([]MonitoringData)
+	})
+	return doneC
+}
+
+// Pending returns true, if the monitoring report for the given requestId is awaited or was received partially, but isn't complete yet.
+func (a *MonitoringReportAssembler) Pending(requestID int) bool {
+	return a.assembler.Pending(requestID)
+}
+
+// Completed returns the assembled MonitoringData of a recently completed monitoring report, which wasn't collected via Await yet.
+// Returns false, if the report isn't complete.
+func (a *MonitoringReportAssembler) Completed(requestID int) ([]MonitoringData, bool) {
+	result, ok := a.assembler.Completed(requestID)
+	if !ok {
+		return nil, false
+	}
+	return result.([]MonitoringData), true
+}
+
+// Add stores a part of a monitoring report.
+// If the part completes the report, the assembled MonitoringData of all parts is returned, ordered by sequence number,
+// and the report is removed from the assembler.
+func (a *MonitoringReportAssembler) Add(request *NotifyMonitoringReportRequest) (monitoringData []MonitoringData, complete bool) {
+	result, complete := a.assembler.Add(request.RequestID, request.SeqNo, request.Tbc, request.Monitor)
+	if !complete {
+		return nil, false
+	}
+	return result.([]MonitoringData), true
+}
+
+// Discard removes all received parts of a monitoring report, e.g. after a timeout, as well as the result of a completed report.
+// A channel returned by Await for the report is closed.
+func (a *MonitoringReportAssembler) Discard(requestID int) {
+	a.assembler.Discard(requestID)
+}
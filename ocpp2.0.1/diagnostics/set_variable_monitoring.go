@@ -1,7 +1,9 @@
 package diagnostics
 
 import (
+	"math"
 	"reflect"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 	"gopkg.in/go-playground/validator.v9"
@@ -44,6 +46,47 @@ type SetMonitoringData struct {
 	Variable    types.Variable  `json:"variable" validate:"required"`         // Variable for which monitor is set.
 }
 
+// Creates a new periodic monitor, which makes the Charging Station report the value of a variable every interval.
+// If clockAligned is true, the reports are aligned to the clock (e.g. every full hour for a 1h interval),
+// otherwise the interval starts once the monitor was set. The interval is rounded to whole seconds.
+func NewPeriodicMonitoringData(interval time.Duration, clockAligned bool, severity int, component types.Component, variable types.Variable) SetMonitoringData {
+	monitorType := MonitorPeriodic
+	if clockAligned {
+		monitorType = MonitorPeriodicClockAligned
+	}
+	return SetMonitoringData{Value: math.Round(interval.Seconds()), Type: monitorType, Severity: severity, Component: component, Variable: variable}
+}
+
+// Creates a new delta monitor, which makes the Charging Station report the value of a variable,
+// whenever it changed more than plus or minus delta since the last report.
+func NewDeltaMonitoringData(delta float64, severity int, component types.Component, variable types.Variable) SetMonitoringData {
+	return SetMonitoringData{Value: delta, Type: MonitorDelta, Severity: severity, Component: component, Variable: variable}
+}
+
+// Interval returns the reporting interval of a Periodic or PeriodicClockAligned monitor.
+// For all other monitor types, zero is returned.
+func (d SetMonitoringData) Interval() time.Duration {
+	if d.Type != MonitorPeriodic && d.Type != MonitorPeriodicClockAligned {
+		return 0
+	}
+	return time.Duration(d.Value * float64(time.Second))
+}
+
+// Periodic monitors require a positive interval in whole seconds, while delta monitors require a positive delta.
+func validateSetMonitoringData(sl validator.StructLevel) {
+	data := sl.Current().Interface().(SetMonitoringData)
+	switch data.Type {
+	case MonitorPeriodic, MonitorPeriodicClockAligned:
+		if data.Value < 1 || data.Value != math.Trunc(data.Value) {
+			sl.ReportError(data.Value, "Value", "value", "periodicInterval", "")
+		}
+	case MonitorDelta:
+		if data.Value <= 0 {
+			sl.ReportError(data.Value, "Value", "value", "gt", "0")
+		}
+	}
+}
+
 // Holds the result of SetVariableMonitoring request.
 type SetMonitoringResult struct {
 	ID         *int                `json:"id,omitempty" validate:"omitempty"`              // Id given to the VariableMonitor by the Charging Station. The Id is only returned when status is accepted.
@@ -98,6 +141,12 @@ func NewSetVariableMonitoringRequest(data []SetMonitoringData) *SetVariableMonit
 	return &SetVariableMonitoringRequest{MonitoringData: data}
 }
 
+// Creates a new SetMonitoringResult for the monitor described by data, e.g. to be returned by a Charging Station.
+// The ID of the monitor should only be set when the status is accepted.
+func NewSetMonitoringResult(data SetMonitoringData, status SetMonitoringStatus) SetMonitoringResult {
+	return SetMonitoringResult{ID: data.ID, Status: status, Type: data.Type, Severity: data.Severity, Component: data.Component, Variable: data.Variable}
+}
+
 // Creates a new SetVariableMonitoringResponse, containing all required fields. There are no optional fields for this message.
 func NewSetVariableMonitoringResponse(result []SetMonitoringResult) *SetVariableMonitoringResponse {
 	return &SetVariableMonitoringResponse{MonitoringResult: result}
@@ -105,4 +154,5 @@ func NewSetVariableMonitoringResponse(result []SetMonitoringResult) *SetVariable
 
 func init() {
 	_ = types.Validate.RegisterValidation("setMonitoringStatus", isValidSetMonitoringStatus)
+	types.Validate.RegisterStructValidation(validateSetMonitoringData, SetMonitoringData{})
 }
@@ -64,14 +64,26 @@ type MessageInfo struct {
 	Priority      MessagePriority      `json:"priority" validate:"required,messagePriority"`        // With what priority should this message be shown
 	State         MessageState         `json:"state,omitempty" validate:"omitempty,messageState"`   // During what state should this message be shown. When omitted this message should be shown in any state of the Charging Station.
 	StartDateTime *types.DateTime      `json:"startDateTime,omitempty" validate:"omitempty"`        // From what date-time should this message be shown. If omitted: directly.
-	EndDateTime   *types.DateTime      `json:"endDateTime,omitempty" validate:"omitempty"`          // Until what date-time should this message be shown, after this date/time this message SHALL be removed.
+	EndDateTime   *types.DateTime      `json:"endDateTime,omitempty" validate:"omitempty"`          // Until what date-time should this message be shown, after this date/time this message SHALL be removed. Must not be before StartDateTime.
 	TransactionID string               `json:"transactionId,omitempty" validate:"omitempty,max=36"` // During which transaction shall this message be shown. Message SHALL be removed by the Charging Station after transaction has ended.
 	Message       types.MessageContent `json:"message" validate:"required"`                         // Contains message details for the message to be displayed on a Charging Station.
 	Display       *types.Component     `json:"display,omitempty" validate:"omitempty"`              // When a Charging Station has multiple Displays, this field can be used to define to which Display this message belongs.
 }
 
+// A time-windowed message must not end before it starts.
+func isValidMessageInfo(sl validator.StructLevel) {
+	info := sl.Current().Interface().(MessageInfo)
+	if info.StartDateTime == nil || info.EndDateTime == nil {
+		return
+	}
+	if info.EndDateTime.Before(info.StartDateTime.Time) {
+		sl.ReportError(info.EndDateTime, "EndDateTime", "endDateTime", "gtefield", "StartDateTime")
+	}
+}
+
 func init() {
 	_ = types.Validate.RegisterValidation("messagePriority", isValidMessagePriority)
 	_ = types.Validate.RegisterValidation("messageState", isValidMessageState)
 	_ = types.Validate.RegisterValidation("messageStatus", isValidMessageStatus)
+	types.Validate.RegisterStructValidation(isValidMessageInfo, MessageInfo{})
 }
@@ -0,0 +1,63 @@
+package ocpp2
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+// handlerTimeouts holds the maximum execution time of the handlers for incoming requests, per feature.
+type handlerTimeouts struct {
+	mutex    sync.RWMutex
+	timeouts map[string]time.Duration
+}
+
+func newHandlerTimeouts() *handlerTimeouts {
+	return &handlerTimeouts{timeouts: map[string]time.Duration{}}
+}
+
+func (h *handlerTimeouts) set(featureName string, d time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if d <= 0 {
+		delete(h.timeouts, featureName)
+	} else {
+		h.timeouts[featureName] = d
+	}
+}
+
+func (h *handlerTimeouts) get(featureName string) time.Duration {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.timeouts[featureName]
+}
+
+// Returns a function for responding to an incoming request. If a handler timeout is set for the feature,
+// an InternalError is sent to the charging station once the timeout elapsed, and a later response is discarded.
+// The returned function reports whether the response was actually sent to the charging station.
+func (cs *csms) timedResponder(chargingStationID string, requestId string, action string) func(response ocpp.Response, err error) bool {
+	timeout := cs.handlerTimeouts.get(action)
+	if timeout <= 0 {
+		return func(response ocpp.Response, err error) bool {
+			return cs.sendResponse(chargingStationID, response, err, requestId)
+		}
+	}
+	var responded int32
+	timer := time.AfterFunc(timeout, func() {
+		if atomic.CompareAndSwapInt32(&responded, 0, 1) {
+			cs.sendResponse(chargingStationID, nil, ocpp.NewHandlerError(ocppj.InternalError, fmt.Sprintf("handler for %v timed out", action)), requestId)
+			cs.error(fmt.Errorf("handler for %v request %s from %s timed out after %v", action, requestId, chargingStationID, timeout))
+		}
+	})
+	return func(response ocpp.Response, err error) bool {
+		timer.Stop()
+		if !atomic.CompareAndSwapInt32(&responded, 0, 1) {
+			return false
+		}
+		return cs.sendResponse(chargingStationID, response, err, requestId)
+	}
+}
@@ -0,0 +1,176 @@
+package ocpp2
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/smartcharging"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// InstalledChargingProfile is a charging profile, which the CSMS knows to be installed on a charging station.
+type InstalledChargingProfile struct {
+	EvseID  int                           // The EVSE the profile is installed on. 0 refers to the charging station as a whole.
+	Source  types.ChargingLimitSourceType // The source of the profile. Profiles set by the CSMS itself have source CSO.
+	Profile types.ChargingProfile
+}
+
+// Tracks a full report of the installed profiles, requested via GetChargingProfiles without any criteria.
+type profilesReport struct {
+	evseID   *int
+	reported map[int]struct{} // IDs of the profiles reported so far.
+}
+
+// installedProfilesStore keeps the charging profiles installed on each charging station, as known by the CSMS.
+// It is updated from accepted SetChargingProfile and ClearChargingProfile requests, and from ReportChargingProfiles requests.
+// Profiles are kept when a charging station disconnects, since they remain installed.
+type installedProfilesStore struct {
+	mutex    sync.RWMutex
+	stations map[string]map[int]InstalledChargingProfile // Keyed by charging profile ID.
+	reports  map[string]map[int]*profilesReport          // Pending full reports, keyed by request ID.
+}
+
+func newInstalledProfilesStore() *installedProfilesStore {
+	return &installedProfilesStore{
+		stations: map[string]map[int]InstalledChargingProfile{},
+		reports:  map[string]map[int]*profilesReport{},
+	}
+}
+
+func (s *installedProfilesStore) get(chargingStationID string) []InstalledChargingProfile {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	profiles := make([]InstalledChargingProfile, 0, len(s.stations[chargingStationID]))
+	for _, installed := range s.stations[chargingStationID] {
+		profiles = append(profiles, installed)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		a, b := profiles[i], profiles[j]
+		if a.EvseID != b.EvseID {
+			return a.EvseID < b.EvseID
+		}
+		if a.Profile.ChargingProfilePurpose != b.Profile.ChargingProfilePurpose {
+			return a.Profile.ChargingProfilePurpose < b.Profile.ChargingProfilePurpose
+		}
+		if a.Profile.StackLevel != b.Profile.StackLevel {
+			return a.Profile.StackLevel < b.Profile.StackLevel
+		}
+		return a.Profile.ID < b.Profile.ID
+	})
+	return profiles
+}
+
+// Adds a profile, replacing a previously installed profile with the same ID. Must be called with the mutex held.
+func (s *installedProfilesStore) put(chargingStationID string, installed InstalledChargingProfile) {
+	profiles, ok := s.stations[chargingStationID]
+	if !ok {
+		profiles = map[int]InstalledChargingProfile{}
+		s.stations[chargingStationID] = profiles
+	}
+	profiles[installed.Profile.ID] = installed
+}
+
+// Removes all profiles of a charging station matching the filter. Must be called with the mutex held.
+func (s *installedProfilesStore) remove(chargingStationID string, matches func(installed InstalledChargingProfile) bool) {
+	profiles := s.stations[chargingStationID]
+	for id, installed := range profiles {
+		if matches(installed) {
+			delete(profiles, id)
+		}
+	}
+	if len(profiles) == 0 {
+		delete(s.stations, chargingStationID)
+	}
+}
+
+// Records a profile, which was accepted by the charging station via SetChargingProfile.
+func (s *installedProfilesStore) set(chargingStationID string, evseID int, profile *types.ChargingProfile) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.put(chargingStationID, InstalledChargingProfile{EvseID: evseID, Source: types.ChargingLimitSourceCSO, Profile: *profile})
+}
+
+// Removes the profiles matching an accepted ClearChargingProfileRequest.
+// If the request contains a profile ID, only that profile is removed. Otherwise all profiles matching the criteria are removed,
+// where omitted criteria match any profile.
+func (s *installedProfilesStore) clear(chargingStationID string, request *smartcharging.ClearChargingProfileRequest) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.remove(chargingStationID, func(installed InstalledChargingProfile) bool {
+		if request.ChargingProfileID != nil {
+			return installed.Profile.ID == *request.ChargingProfileID
+		}
+		criteria := request.ChargingProfileCriteria
+		if criteria == nil {
+			return true
+		}
+		return (criteria.EvseID == nil || installed.EvseID == *criteria.EvseID) &&
+			(criteria.ChargingProfilePurpose == "" || installed.Profile.ChargingProfilePurpose == criteria.ChargingProfilePurpose) &&
+			(criteria.StackLevel == nil || installed.Profile.StackLevel == *criteria.StackLevel)
+	})
+}
+
+// Returns true, if the criterion matches all installed profiles, hence the resulting report contains the full set of profiles.
+func isFullReportCriterion(criterion smartcharging.ChargingProfileCriterion) bool {
+	return criterion.ChargingProfilePurpose == "" && criterion.StackLevel == nil &&
+		len(criterion.ChargingProfileID) == 0 && len(criterion.ChargingLimitSource) == 0
+}
+
+func inReportScope(evseID *int, installed InstalledChargingProfile) bool {
+	return evseID == nil || installed.EvseID == *evseID
+}
+
+// Processes the response to a GetChargingProfilesRequest. If the request covers all installed profiles (of an EVSE),
+// profiles missing from the subsequent report are removed once the report is complete.
+func (s *installedProfilesStore) requested(chargingStationID string, request *smartcharging.GetChargingProfilesRequest, status smartcharging.GetChargingProfileStatus) {
+	if !isFullReportCriterion(request.ChargingProfile) {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch status {
+	case smartcharging.GetChargingProfileStatusNoProfiles:
+		s.remove(chargingStationID, func(installed InstalledChargingProfile) bool {
+			return inReportScope(request.EvseID, installed)
+		})
+	case smartcharging.GetChargingProfileStatusAccepted:
+		reports, ok := s.reports[chargingStationID]
+		if !ok {
+			reports = map[int]*profilesReport{}
+			s.reports[chargingStationID] = reports
+		}
+		reports[request.RequestID] = &profilesReport{evseID: request.EvseID, reported: map[int]struct{}{}}
+	}
+}
+
+// Records the profiles contained in a ReportChargingProfilesRequest, replacing profiles with the same ID.
+func (s *installedProfilesStore) report(chargingStationID string, request *smartcharging.ReportChargingProfilesRequest) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, profile := range request.ChargingProfile {
+		s.put(chargingStationID, InstalledChargingProfile{EvseID: request.EvseID, Source: request.ChargingLimitSource, Profile: profile})
+	}
+	report, ok := s.reports[chargingStationID][request.RequestID]
+	if !ok {
+		return
+	}
+	for _, profile := range request.ChargingProfile {
+		report.reported[profile.ID] = struct{}{}
+	}
+	if request.Tbc {
+		return
+	}
+	// The report is complete: profiles which weren't reported aren't installed anymore
+	delete(s.reports[chargingStationID], request.RequestID)
+	if len(s.reports[chargingStationID]) == 0 {
+		delete(s.reports, chargingStationID)
+	}
+	s.remove(chargingStationID, func(installed InstalledChargingProfile) bool {
+		_, reported := report.reported[installed.Profile.ID]
+		return !reported && inReportScope(report.evseID, installed)
+	})
+}
+
+func (cs *csms) InstalledProfiles(stationID string) []InstalledChargingProfile {
+	return cs.installedProfiles.get(stationID)
+}
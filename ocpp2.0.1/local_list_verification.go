@@ -0,0 +1,51 @@
+package ocpp2
+
+import (
+	"fmt"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/localauth"
+)
+
+// LocalListVerificationResult contains the outcome of a verified local list update, see CSMS.SendLocalListVerified.
+type LocalListVerificationResult struct {
+	ChargingStationID string
+	Status            localauth.SendLocalListStatus // Response status of the SendLocalList request.
+	ExpectedVersion   int                           // The version number sent to the charging station.
+	ReportedVersion   *int                          // The version number returned by GetLocalListVersion. Only set if the update was accepted.
+}
+
+// Verified returns true, if the update was accepted and the charging station reported the expected version afterwards.
+func (r LocalListVerificationResult) Verified() bool {
+	return r.Status == localauth.SendLocalListStatusAccepted && r.ReportedVersion != nil && *r.ReportedVersion == r.ExpectedVersion
+}
+
+func (cs *csms) SendLocalListVerified(clientId string, callback func(result LocalListVerificationResult, err error), version int, updateType localauth.UpdateType, entries []localauth.AuthorizationData, props ...func(request *localauth.SendLocalListRequest)) error {
+	result := LocalListVerificationResult{ChargingStationID: clientId, ExpectedVersion: version}
+	setEntries := func(request *localauth.SendLocalListRequest) {
+		request.LocalAuthorizationList = entries
+	}
+	return cs.SendLocalList(clientId, func(response *localauth.SendLocalListResponse, err error) {
+		if err != nil {
+			callback(result, err)
+			return
+		}
+		result.Status = response.Status
+		if response.Status != localauth.SendLocalListStatusAccepted {
+			// Nothing to verify
+			callback(result, nil)
+			return
+		}
+		err = cs.GetLocalListVersion(clientId, func(response *localauth.GetLocalListVersionResponse, err error) {
+			if err != nil {
+				callback(result, err)
+				return
+			}
+			reportedVersion := response.VersionNumber
+			result.ReportedVersion = &reportedVersion
+			callback(result, nil)
+		})
+		if err != nil {
+			callback(result, fmt.Errorf("couldn't read back local list version: %w", err))
+		}
+	}, version, updateType, append([]func(request *localauth.SendLocalListRequest){setEntries}, props...)...)
+}
@@ -0,0 +1,108 @@
+package ocpp2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/meter"
+)
+
+// MeterValuesBatchHandler receives the MeterValues sent by a charging station in batches.
+// See CSMS.SetMeterValuesBatch.
+type MeterValuesBatchHandler func(chargingStationID string, batch []meter.MeterValuesRequest)
+
+// Pending batch of a single charging station.
+type meterValuesBatch struct {
+	requests []meter.MeterValuesRequest
+	timer    *time.Timer
+}
+
+// meterValuesBatcher collects MeterValues per charging station.
+// The first MeterValues message for a station opens a window, during which further messages are collected.
+// The collected messages are delivered once the window expires, or as soon as the batch is full, whichever happens first.
+type meterValuesBatcher struct {
+	maxCount int
+	maxWait  time.Duration
+	deliver  MeterValuesBatchHandler
+	mutex    sync.Mutex
+	pending  map[string]*meterValuesBatch
+}
+
+func newMeterValuesBatcher(maxCount int, maxWait time.Duration, deliver MeterValuesBatchHandler) *meterValuesBatcher {
+	if maxCount <= 0 && maxWait <= 0 {
+		// Neither a size nor a time limit: deliver every message on its own
+		maxCount = 1
+	}
+	return &meterValuesBatcher{
+		maxCount: maxCount,
+		maxWait:  maxWait,
+		deliver:  deliver,
+		pending:  map[string]*meterValuesBatch{},
+	}
+}
+
+func (b *meterValuesBatcher) add(chargingStationID string, request *meter.MeterValuesRequest) {
+	b.mutex.Lock()
+	batch, ok := b.pending[chargingStationID]
+	if !ok {
+		batch = &meterValuesBatch{}
+		b.pending[chargingStationID] = batch
+		if b.maxWait > 0 {
+			batch.timer = time.AfterFunc(b.maxWait, func() {
+				b.flushBatch(chargingStationID, batch)
+			})
+		}
+	}
+	batch.requests = append(batch.requests, *request)
+	if b.maxCount <= 0 || len(batch.requests) < b.maxCount {
+		b.mutex.Unlock()
+		return
+	}
+	// Batch is full
+	delete(b.pending, chargingStationID)
+	if batch.timer != nil {
+		batch.timer.Stop()
+	}
+	b.mutex.Unlock()
+	b.deliver(chargingStationID, batch.requests)
+}
+
+// Delivers the batch, unless it was already delivered in the meantime.
+func (b *meterValuesBatcher) flushBatch(chargingStationID string, batch *meterValuesBatch) {
+	b.mutex.Lock()
+	if b.pending[chargingStationID] != batch {
+		b.mutex.Unlock()
+		return
+	}
+	delete(b.pending, chargingStationID)
+	b.mutex.Unlock()
+	if len(batch.requests) > 0 {
+		b.deliver(chargingStationID, batch.requests)
+	}
+}
+
+// Delivers the pending batch of a charging station right away, e.g. after the station disconnected.
+func (b *meterValuesBatcher) flush(chargingStationID string) {
+	b.mutex.Lock()
+	batch, ok := b.pending[chargingStationID]
+	if ok && batch.timer != nil {
+		batch.timer.Stop()
+	}
+	b.mutex.Unlock()
+	if ok {
+		b.flushBatch(chargingStationID, batch)
+	}
+}
+
+// Delivers the pending batches of all charging stations right away.
+func (b *meterValuesBatcher) flushAll() {
+	b.mutex.Lock()
+	stationIDs := make([]string, 0, len(b.pending))
+	for stationID := range b.pending {
+		stationIDs = append(stationIDs, stationID)
+	}
+	b.mutex.Unlock()
+	for _, stationID := range stationIDs {
+		b.flush(stationID)
+	}
+}
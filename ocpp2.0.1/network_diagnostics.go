@@ -0,0 +1,85 @@
+package ocpp2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+)
+
+// NetworkDiagnostics is a read-only snapshot of the network information known about a charging station.
+//
+// The modem information is taken from the most recent BootNotification of the station.
+// All other fields aren't part of a dedicated OCPP message, and may be filled in by the application
+// (e.g. from a vendor-specific DataTransfer or a reported variable), see CSMS.UpdateNetworkDiagnostics.
+type NetworkDiagnostics struct {
+	Modem          *provisioning.ModemType // The modem (ICCID/IMSI) reported in the last BootNotification, if any.
+	BootedAt       time.Time               // Time at which the last BootNotification was received.
+	SignalStrength *int                    // The last known signal strength, as reported by the station (unit is vendor-specific, usually dBm).
+	Attributes     map[string]string       // Further network information, e.g. the network operator or the access technology.
+	UpdatedAt      time.Time               // Time of the most recent update.
+}
+
+func (d NetworkDiagnostics) clone() NetworkDiagnostics {
+	if d.Modem != nil {
+		modem := *d.Modem
+		d.Modem = &modem
+	}
+	if d.SignalStrength != nil {
+		signalStrength := *d.SignalStrength
+		d.SignalStrength = &signalStrength
+	}
+	if d.Attributes != nil {
+		attributes := make(map[string]string, len(d.Attributes))
+		for k, v := range d.Attributes {
+			attributes[k] = v
+		}
+		d.Attributes = attributes
+	}
+	return d
+}
+
+// networkDiagnosticsStore keeps the network information per charging station.
+// The information is retained after a station disconnects, as it is mostly useful for diagnosing connectivity issues.
+type networkDiagnosticsStore struct {
+	mutex    sync.RWMutex
+	stations map[string]*NetworkDiagnostics
+}
+
+func newNetworkDiagnosticsStore() *networkDiagnosticsStore {
+	return &networkDiagnosticsStore{stations: map[string]*NetworkDiagnostics{}}
+}
+
+func (s *networkDiagnosticsStore) applyBootNotification(chargingStationID string, request *provisioning.BootNotificationRequest) {
+	var modem *provisioning.ModemType
+	if request.ChargingStation.Modem != nil {
+		m := *request.ChargingStation.Modem
+		modem = &m
+	}
+	s.update(chargingStationID, func(diagnostics *NetworkDiagnostics) {
+		diagnostics.Modem = modem
+		diagnostics.BootedAt = time.Now()
+	})
+}
+
+func (s *networkDiagnosticsStore) update(chargingStationID string, fn func(diagnostics *NetworkDiagnostics)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	diagnostics, ok := s.stations[chargingStationID]
+	if !ok {
+		diagnostics = &NetworkDiagnostics{}
+		s.stations[chargingStationID] = diagnostics
+	}
+	fn(diagnostics)
+	diagnostics.UpdatedAt = time.Now()
+}
+
+func (s *networkDiagnosticsStore) get(chargingStationID string) (NetworkDiagnostics, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	diagnostics, ok := s.stations[chargingStationID]
+	if !ok {
+		return NetworkDiagnostics{}, false
+	}
+	return diagnostics.clone(), true
+}
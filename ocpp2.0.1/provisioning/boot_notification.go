@@ -2,6 +2,7 @@ package provisioning
 
 import (
 	"reflect"
+	"unicode/utf8"
 
 	"gopkg.in/go-playground/validator.v9"
 
@@ -66,6 +67,34 @@ type ChargingStationType struct {
 	VendorName      string     `json:"vendorName" validate:"required,max=50"`
 	FirmwareVersion string     `json:"firmwareVersion,omitempty" validate:"max=50"`
 	Modem           *ModemType `json:"modem,omitempty"`
+	// Names of the identity fields which exceeded their maximum length and were truncated while parsing.
+	// Only set if the CSMS tolerates over-length identity fields, see TruncateIdentityFields.
+	TruncatedFields []string `json:"-"`
+}
+
+// TruncateIdentityFields truncates all identity fields (serialNumber, model, vendorName, firmwareVersion,
+// modem.iccid and modem.imsi) exceeding their maximum length, so the charging station passes validation.
+// Returns the names of the truncated fields, which are also appended to TruncatedFields.
+func (c *ChargingStationType) TruncateIdentityFields() []string {
+	n := len(c.TruncatedFields)
+	c.truncate(&c.SerialNumber, "serialNumber", 25)
+	c.truncate(&c.Model, "model", 20)
+	c.truncate(&c.VendorName, "vendorName", 50)
+	c.truncate(&c.FirmwareVersion, "firmwareVersion", 50)
+	if c.Modem != nil {
+		c.truncate(&c.Modem.Iccid, "modem.iccid", 20)
+		c.truncate(&c.Modem.Imsi, "modem.imsi", 20)
+	}
+	return c.TruncatedFields[n:]
+}
+
+// Truncates a field to the max amount of characters, recording its name if it was truncated.
+func (c *ChargingStationType) truncate(field *string, name string, max int) {
+	if utf8.RuneCountInString(*field) <= max {
+		return
+	}
+	*field = string([]rune(*field)[:max])
+	c.TruncatedFields = append(c.TruncatedFields, name)
 }
 
 // The field definition of the BootNotification request payload sent by the Charging Station to the CSMS.
@@ -0,0 +1,122 @@
+package provisioning
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// CharacteristicsWarning describes a reported variable, whose characteristics are inconsistent,
+// either with themselves or with the reported attribute values.
+//
+// Such reports are syntactically valid and are therefore accepted, but typically hint at a buggy device model.
+type CharacteristicsWarning struct {
+	Component types.Component
+	Variable  types.Variable
+	Reason    string
+}
+
+func (w CharacteristicsWarning) String() string {
+	return fmt.Sprintf("%v.%v: %v", w.Component.Name, w.Variable.Name, w.Reason)
+}
+
+func (dataType DataType) isList() bool {
+	return dataType == TypeOptionList || dataType == TypeSequenceList || dataType == TypeMemberList
+}
+
+func (dataType DataType) isNumeric() bool {
+	return dataType == TypeInteger || dataType == TypeDecimal
+}
+
+// Check verifies the consistency of the characteristics and returns the reasons for all detected violations:
+//   - minLimit must not be greater than maxLimit
+//   - list types require a non-empty valuesList, which doesn't contain empty values
+//   - valuesList is only allowed for list types
+//   - limits are not allowed for boolean and dateTime types
+func (c VariableCharacteristics) Check() []string {
+	var reasons []string
+	if c.MinLimit != nil && c.MaxLimit != nil && *c.MinLimit > *c.MaxLimit {
+		reasons = append(reasons, fmt.Sprintf("minLimit %v is greater than maxLimit %v", *c.MinLimit, *c.MaxLimit))
+	}
+	if c.DataType.isList() {
+		if strings.TrimSpace(c.ValuesList) == "" {
+			reasons = append(reasons, fmt.Sprintf("valuesList is required for data type %v", c.DataType))
+		} else {
+			for _, value := range strings.Split(c.ValuesList, ",") {
+				if strings.TrimSpace(value) == "" {
+					reasons = append(reasons, fmt.Sprintf("valuesList %q contains empty values", c.ValuesList))
+					break
+				}
+			}
+		}
+	} else if c.ValuesList != "" {
+		reasons = append(reasons, fmt.Sprintf("valuesList is not allowed for data type %v", c.DataType))
+	}
+	if (c.DataType == TypeBoolean || c.DataType == TypeDateTime) && (c.MinLimit != nil || c.MaxLimit != nil) {
+		reasons = append(reasons, fmt.Sprintf("limits are not allowed for data type %v", c.DataType))
+	}
+	return reasons
+}
+
+// Verifies that a reported attribute value complies with the characteristics.
+func (c VariableCharacteristics) checkValue(attribute VariableAttribute) []string {
+	if attribute.Value == "" {
+		return nil
+	}
+	var reasons []string
+	attributeType := attribute.Type
+	if attributeType == "" {
+		attributeType = types.AttributeActual
+	}
+	switch {
+	case c.DataType.isNumeric():
+		value, err := strconv.ParseFloat(attribute.Value, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("%v value %q is not a valid %v", attributeType, attribute.Value, c.DataType)}
+		}
+		if c.MinLimit != nil && value < *c.MinLimit {
+			reasons = append(reasons, fmt.Sprintf("%v value %v is lower than minLimit %v", attributeType, value, *c.MinLimit))
+		}
+		if c.MaxLimit != nil && value > *c.MaxLimit {
+			reasons = append(reasons, fmt.Sprintf("%v value %v is greater than maxLimit %v", attributeType, value, *c.MaxLimit))
+		}
+	case c.DataType == TypeOptionList && c.ValuesList != "":
+		for _, allowed := range strings.Split(c.ValuesList, ",") {
+			if strings.TrimSpace(allowed) == attribute.Value {
+				return nil
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("%v value %q is not contained in valuesList", attributeType, attribute.Value))
+	}
+	return reasons
+}
+
+// CheckCharacteristics verifies the consistency of the reported variable characteristics (see VariableCharacteristics.Check),
+// as well as the compliance of the reported attribute values with the characteristics.
+// Returns nil, if no characteristics were reported or no violations were detected.
+func (d ReportData) CheckCharacteristics() []CharacteristicsWarning {
+	if d.VariableCharacteristics == nil {
+		return nil
+	}
+	reasons := d.VariableCharacteristics.Check()
+	for _, attribute := range d.VariableAttribute {
+		reasons = append(reasons, d.VariableCharacteristics.checkValue(attribute)...)
+	}
+	var warnings []CharacteristicsWarning
+	for _, reason := range reasons {
+		warnings = append(warnings, CharacteristicsWarning{Component: d.Component, Variable: d.Variable, Reason: reason})
+	}
+	return warnings
+}
+
+// CheckCharacteristics verifies the variable characteristics of all the report data contained in the request.
+// See ReportData.CheckCharacteristics for details.
+func (r NotifyReportRequest) CheckCharacteristics() []CharacteristicsWarning {
+	var warnings []CharacteristicsWarning
+	for _, data := range r.ReportData {
+		warnings = append(warnings, data.CheckCharacteristics()...)
+	}
+	return warnings
+}
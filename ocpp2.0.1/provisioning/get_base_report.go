@@ -48,6 +48,7 @@ type GetBaseReportResponse struct {
 // The CSMS shall then request a Charging Station to send a predefined report as defined in ReportBase.
 // The Charging Station responds with GetBaseReportResponse.
 // The result will be returned asynchronously in one or more NotifyReportRequest messages (one for each report part).
+// The parts may be collected via a ReportAssembler, which signals once the full report was received.
 type GetBaseReportFeature struct{}
 
 func (f GetBaseReportFeature) GetFeatureName() string {
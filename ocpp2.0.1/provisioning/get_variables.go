@@ -1,7 +1,11 @@
 package provisioning
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 	"gopkg.in/go-playground/validator.v9"
@@ -44,6 +48,98 @@ type GetVariableResult struct {
 	AttributeValue  string            `json:"attributeValue,omitempty" validate:"omitempty,max=1000"`
 	Component       types.Component   `json:"component" validate:"required"`
 	Variable        types.Variable    `json:"variable" validate:"required"`
+	// Characteristics of the variable, used by the typed accessors. Not part of the OCPP 2.0.1 message, hence never serialized.
+	// May be set by the CSMS, e.g. from a previously received NotifyReport, before using the typed accessors.
+	VariableCharacteristics *VariableCharacteristics `json:"-" validate:"-"`
+}
+
+// ErrAttributeNotSupported is returned by the typed accessors of a GetVariableResult,
+// if the charging station reported a NotSupportedAttributeType status.
+var ErrAttributeNotSupported = errors.New("attribute type not supported by variable")
+
+// Returns the attribute value, if it was retrieved successfully and is compatible with the expected data type.
+func (r GetVariableResult) value(expected DataType) (string, error) {
+	switch r.AttributeStatus {
+	case GetVariableStatusAccepted:
+	case GetVariableStatusNotSupported:
+		return "", fmt.Errorf("%v.%v: %w", r.Component.Name, r.Variable.Name, ErrAttributeNotSupported)
+	default:
+		return "", fmt.Errorf("%v.%v: no value available, attribute status %v", r.Component.Name, r.Variable.Name, r.AttributeStatus)
+	}
+	if r.VariableCharacteristics != nil && r.VariableCharacteristics.DataType != expected {
+		return "", fmt.Errorf("%v.%v: declared data type %v, cannot parse as %v", r.Component.Name, r.Variable.Name, r.VariableCharacteristics.DataType, expected)
+	}
+	return r.AttributeValue, nil
+}
+
+// Parses the attribute value as an integer.
+// An error is returned if no value was retrieved, the declared data type is not integer, or the value cannot be parsed.
+func (r GetVariableResult) AsInt() (int, error) {
+	value, err := r.value(TypeInteger)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%v.%v: invalid integer value %q", r.Component.Name, r.Variable.Name, value)
+	}
+	return i, nil
+}
+
+// Parses the attribute value as a boolean.
+// An error is returned if no value was retrieved, the declared data type is not boolean, or the value cannot be parsed.
+func (r GetVariableResult) AsBool() (bool, error) {
+	value, err := r.value(TypeBoolean)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%v.%v: invalid boolean value %q", r.Component.Name, r.Variable.Name, value)
+	}
+}
+
+// Parses the attribute value as a decimal.
+// An error is returned if no value was retrieved, the declared data type is not decimal, or the value cannot be parsed.
+// Integer values are accepted as well, as they are a subset of decimal values.
+func (r GetVariableResult) AsDecimal() (float64, error) {
+	expected := TypeDecimal
+	if r.VariableCharacteristics != nil && r.VariableCharacteristics.DataType == TypeInteger {
+		expected = TypeInteger
+	}
+	value, err := r.value(expected)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%v.%v: invalid decimal value %q", r.Component.Name, r.Variable.Name, value)
+	}
+	return f, nil
+}
+
+// NormalizeAttributeType returns the attribute type a charging station applies, if none is specified: Actual.
+// Other attribute types are returned unchanged.
+func NormalizeAttributeType(attributeType types.Attribute) types.Attribute {
+	if attributeType == "" {
+		return types.AttributeActual
+	}
+	return attributeType
+}
+
+// WithDefaultAttributeTypes sets the attribute type of every requested variable without one to Actual.
+// Some charging stations don't return any value for variables without attribute type, although the specification mandates Actual.
+// The function may be passed as property when sending a GetVariablesRequest:
+//
+//	csms.GetVariables(stationID, callback, variableData, provisioning.WithDefaultAttributeTypes)
+func WithDefaultAttributeTypes(request *GetVariablesRequest) {
+	for i := range request.GetVariableData {
+		request.GetVariableData[i].AttributeType = NormalizeAttributeType(request.GetVariableData[i].AttributeType)
+	}
 }
 
 // The field definition of the GetVariables request payload sent by the CSMS to the Charging Station.
@@ -57,6 +153,26 @@ type GetVariablesResponse struct {
 	GetVariableResult []GetVariableResult `json:"getVariableResult" validate:"required,min=1,dive"`
 }
 
+// NormalizeAttributeTypes sets the attribute type of every result without one to Actual,
+// as a charging station omitting the attribute type refers to the Actual attribute.
+func (r *GetVariablesResponse) NormalizeAttributeTypes() {
+	for i := range r.GetVariableResult {
+		r.GetVariableResult[i].AttributeType = NormalizeAttributeType(r.GetVariableResult[i].AttributeType)
+	}
+}
+
+// Result returns the result for the given attribute of a variable. Missing attribute types are treated as Actual on both sides.
+// See ReportData.MatchesVariable for details on how components and variables are compared.
+func (r *GetVariablesResponse) Result(component types.Component, variable types.Variable, attributeType types.Attribute) (GetVariableResult, bool) {
+	for _, result := range r.GetVariableResult {
+		data := ReportData{Component: result.Component, Variable: result.Variable}
+		if data.MatchesVariable(component, variable) && NormalizeAttributeType(result.AttributeType) == NormalizeAttributeType(attributeType) {
+			return result, true
+		}
+	}
+	return GetVariableResult{}, false
+}
+
 // The CSO may trigger the CSMS to request to request for a number of variables in a Charging Station.
 // The CSMS request the Charging Station for a number of variables (of one or more components) with GetVariablesRequest with a list of requested variables.
 // The Charging Station responds with a GetVariablesResponse with the requested variables.
@@ -0,0 +1,86 @@
+package provisioning
+
+import (
+	"github.com/lorenzodonini/ocpp-go/internal/multipart"
+)
+
+// Number of completed reports, which a ReportAssembler retains for late calls to Await and Completed.
+const maxCompletedReports = 32
+
+// ReportAssembler collects the parts of multipart NotifyReport messages,
+// which a Charging Station sends in response to a GetBaseReport or GetReport request.
+//
+// Parts are correlated via the requestId of the original request, and ordered via their sequence number.
+// A report is complete once the part with tbc=false and all previous parts were received.
+// A single part with tbc=false and no reportData, which some charging stations send if a report matches nothing,
+// completes the report with an empty, non-nil ReportData slice.
+//
+// The most recently completed reports are retained, so the result of a report that completed
+// before Await was invoked is still delivered. Use Pending and Completed to query the state of a report.
+//
+// A ReportAssembler is safe for concurrent use.
+type ReportAssembler struct {
+	assembler *multipart.Assembler
+}
+
+// NewReportAssembler creates a new ReportAssembler without any pending reports.
+func NewReportAssembler() *ReportAssembler {
+	return &ReportAssembler{assembler: multipart.NewAssembler(joinReportData, maxCompletedReports)}
+}
+
+func joinReportData(parts []interface{}) interface{} {
+	reportData := []ReportData{}
+	for _, part := range parts {
+		reportData = append(reportData, part.([]ReportData)...)
+	}
+	return reportData
+}
+
+// Await returns a channel, on which the assembled ReportData for the given requestId is delivered once the report is complete.
+// The function may be invoked before or after the first part of the report was received.
+// If the report was completed recently, the channel delivers its result right away.
+//
+// The channel is closed without delivering a result, if the report is discarded or Await is invoked again for the same requestId.
+func (a *ReportAssembler) Await(requestID int) <-chan []ReportData {
+	doneC := make(chan []ReportData, 1)
+	a.assembler.Await(requestID, func(result interface{}, ok bool) {
+		if !ok {
+			close(doneC)
+			return
+		}
+		doneC <- result.([]ReportData)
+	})
+	return doneC
+}
+
+// Pending returns true, if the report for the given requestId is awaited or was received partially, but isn't complete yet.
+func (a *ReportAssembler) Pending(requestID int) bool {
+	return a.assembler.Pending(requestID)
+}
+
+// Completed returns the assembled ReportData of a recently completed report, which wasn't collected via Await yet.
+// The returned slice is empty but non-nil for a report without any data. Returns false, if the report isn't complete.
+func (a *ReportAssembler) Completed(requestID int) ([]ReportData, bool) {
+	result, ok := a.assembler.Completed(requestID)
+	if !ok {
+		return nil, false
+	}
+	return result.([]ReportData), true
+}
+
+// Add stores a part of a report.
+// If the part completes the report, the assembled ReportData of all parts is returned, ordered by sequence number,
+// and the report is removed from the assembler.
+func (a *ReportAssembler) Add(request *NotifyReportRequest) (reportData []ReportData, complete bool) {
+	result, complete := a.assembler.Add(request.RequestID, request.SeqNo, request.Tbc, request.ReportData)
+	if !complete {
+		return nil, false
+	}
+	return result.([]ReportData), true
+}
+
+// Discard removes all received parts of a report, e.g. after a timeout, as well as the result of a completed report.
+// A channel returned by Await for the report is closed.
+func (a *ReportAssembler) Discard(requestID int) {
+	a.assembler.Discard(requestID)
+}
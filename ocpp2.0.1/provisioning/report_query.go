@@ -0,0 +1,116 @@
+package provisioning
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Compares two EVSE references. A nil EVSE refers to the charging station as a whole.
+// A nil connector ID refers to the EVSE as a whole, hence it only matches another nil connector ID.
+func sameEVSE(a *types.EVSE, b *types.EVSE) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.ID != b.ID {
+		return false
+	}
+	if a.ConnectorID == nil || b.ConnectorID == nil {
+		return a.ConnectorID == b.ConnectorID
+	}
+	return *a.ConnectorID == *b.ConnectorID
+}
+
+// MatchesComponent returns true, if the report data refers to the given component.
+// Names and instances are compared case-insensitively, as mandated by the device model.
+// The EVSE and connector must match exactly: a component on EVSE level doesn't match any of its connectors, and vice versa.
+func (d ReportData) MatchesComponent(component types.Component) bool {
+	return strings.EqualFold(d.Component.Name, component.Name) &&
+		strings.EqualFold(d.Component.Instance, component.Instance) &&
+		sameEVSE(d.Component.EVSE, component.EVSE)
+}
+
+// MatchesVariable returns true, if the report data refers to the given variable of the given component.
+// See MatchesComponent for details on how components are compared.
+func (d ReportData) MatchesVariable(component types.Component, variable types.Variable) bool {
+	return d.MatchesComponent(component) &&
+		strings.EqualFold(d.Variable.Name, variable.Name) &&
+		strings.EqualFold(d.Variable.Instance, variable.Instance)
+}
+
+// Attribute returns the variable attribute of the given type. An attribute without type is treated as Actual.
+func (d ReportData) Attribute(attributeType types.Attribute) (VariableAttribute, bool) {
+	for _, attribute := range d.VariableAttribute {
+		t := attribute.Type
+		if t == "" {
+			t = types.AttributeActual
+		}
+		if t == attributeType {
+			return attribute, true
+		}
+	}
+	return VariableAttribute{}, false
+}
+
+// FindVariable looks up a variable of a specific component within assembled report data,
+// e.g. the AvailabilityState of the Connector component on EVSE 2, connector 1.
+// See ReportData.MatchesVariable for details on how components and variables are compared.
+func FindVariable(reportData []ReportData, component types.Component, variable types.Variable) (ReportData, bool) {
+	for _, data := range reportData {
+		if data.MatchesVariable(component, variable) {
+			return data, true
+		}
+	}
+	return ReportData{}, false
+}
+
+// FindComponents returns all report data of components with the given name, regardless of their instance or EVSE,
+// preserving the order of the report. Names are compared case-insensitively.
+func FindComponents(reportData []ReportData, name string) []ReportData {
+	var result []ReportData
+	for _, data := range reportData {
+		if strings.EqualFold(data.Component.Name, name) {
+			result = append(result, data)
+		}
+	}
+	return result
+}
+
+// FilterByEVSE returns all report data of components located at the given EVSE, preserving the order of the report.
+// If connectorID is nil, components on EVSE level and on any of its connectors are returned.
+// Otherwise only components of the given connector are returned.
+func FilterByEVSE(reportData []ReportData, evseID int, connectorID *int) []ReportData {
+	var result []ReportData
+	for _, data := range reportData {
+		evse := data.Component.EVSE
+		if evse == nil || evse.ID != evseID {
+			continue
+		}
+		if connectorID != nil && (evse.ConnectorID == nil || *evse.ConnectorID != *connectorID) {
+			continue
+		}
+		result = append(result, data)
+	}
+	return result
+}
+
+// MessageTimeout returns the timeout for messages declared by a charging station in its device model,
+// i.e. the actual value of the OCPPCommCtrlr.MessageTimeout variable (instance "Default"), in seconds.
+// Returns false, if the variable isn't contained in the report data or its value isn't a positive integer.
+func MessageTimeout(reportData []ReportData) (time.Duration, bool) {
+	data, ok := FindVariable(reportData, types.Component{Name: "OCPPCommCtrlr"}, types.Variable{Name: "MessageTimeout", Instance: "Default"})
+	if !ok {
+		return 0, false
+	}
+	attribute, ok := data.Attribute(types.AttributeActual)
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(attribute.Value))
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -31,11 +31,25 @@ func isValidRequestStartStopStatus(fl validator.FieldLevel) bool {
 
 // The field definition of the RequestStartTransaction request payload sent by the CSMS to the Charging Station.
 type RequestStartTransactionRequest struct {
-	EvseID          *int                   `json:"evseId,omitempty" validate:"omitempty,gt=0"`
-	RemoteStartID   int                    `json:"remoteStartId" validate:"gte=0"`
-	IDToken         types.IdToken          `json:"idToken"`
-	ChargingProfile *types.ChargingProfile `json:"chargingProfile,omitempty"`
-	GroupIdToken    *types.IdToken         `json:"groupIdToken,omitempty" validate:"omitempty,dive"`
+	EvseID          *int                   `json:"evseId,omitempty" validate:"omitempty,gt=0"`       // Number of the EVSE on which to start the transaction. EvseId SHALL be > 0.
+	RemoteStartID   int                    `json:"remoteStartId" validate:"gte=0"`                   // Id given by the server to this start request. The Charging Station will add this Id to the TransactionEventRequest.
+	IDToken         types.IdToken          `json:"idToken"`                                          // The identifier that the Charging Station must use to start a transaction.
+	ChargingProfile *types.ChargingProfile `json:"chargingProfile,omitempty"`                        // Charging Profile to be used by the Charging Station for the requested transaction. Purpose MUST be TxProfile.
+	GroupIdToken    *types.IdToken         `json:"groupIdToken,omitempty" validate:"omitempty,dive"` // The group identifier that the Charging Station must use to start a transaction.
+}
+
+// The charging profile of a remote start must be a TxProfile, which cannot reference a transaction yet.
+func isValidRequestStartTransactionRequest(sl validator.StructLevel) {
+	request := sl.Current().Interface().(RequestStartTransactionRequest)
+	if request.ChargingProfile == nil {
+		return
+	}
+	if request.ChargingProfile.ChargingProfilePurpose != types.ChargingProfilePurposeTxProfile {
+		sl.ReportError(request.ChargingProfile.ChargingProfilePurpose, "ChargingProfilePurpose", "chargingProfilePurpose", "eq", string(types.ChargingProfilePurposeTxProfile))
+	}
+	if request.ChargingProfile.TransactionID != "" {
+		sl.ReportError(request.ChargingProfile.TransactionID, "TransactionID", "transactionId", "isdefault", "")
+	}
 }
 
 // This field definition of the RequestStartTransaction response payload, sent by the Charging Station to the CSMS in response to a RequestStartTransactionRequest.
@@ -88,4 +102,5 @@ func NewRequestStartTransactionResponse(status RequestStartStopStatus) *RequestS
 
 func init() {
 	_ = types.Validate.RegisterValidation("requestStartStopStatus", isValidRequestStartStopStatus)
+	types.Validate.RegisterStructValidation(isValidRequestStartTransactionRequest, RequestStartTransactionRequest{})
 }
@@ -0,0 +1,41 @@
+package ocpp2
+
+import (
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// CapturedConnection is passed to a RequestCaptureHandler, and allows to respond manually to a captured request.
+//
+// Responses are sent as is, identified by the unique ID of the original request.
+// Any uniqueID may be passed, as the CSMS doesn't keep track of captured requests.
+type CapturedConnection interface {
+	ChargingStationConnection
+	// Sends a CALLRESULT with the given payload. The payload is validated before being sent.
+	SendResult(uniqueID string, payload ocpp.Response) error
+	// Sends a CALLERROR with a custom error code, description and details.
+	SendError(uniqueID string, code ocpp.ErrorCode, description string, details interface{}) error
+}
+
+// RequestCaptureHandler is invoked for every incoming request, before it is routed to a profile handler.
+//
+// Returning true captures the request: the profile handlers aren't invoked and no response is sent automatically.
+// The application is then in charge of responding, by means of the passed connection.
+// Returning false processes the request as usual.
+//
+// The handler is invoked synchronously, in the order in which requests were received, and must return quickly.
+type RequestCaptureHandler func(conn CapturedConnection, request ocpp.Request, uniqueID string, action string) bool
+
+type capturedConnection struct {
+	ChargingStationConnection
+	cs *csms
+}
+
+func (c capturedConnection) SendResult(uniqueID string, payload ocpp.Response) error {
+	c.cs.connections.messageSent(c.ID())
+	return c.cs.server.SendResponse(c.ID(), uniqueID, payload)
+}
+
+func (c capturedConnection) SendError(uniqueID string, code ocpp.ErrorCode, description string, details interface{}) error {
+	c.cs.connections.messageSent(c.ID())
+	return c.cs.server.SendError(c.ID(), uniqueID, code, description, details)
+}
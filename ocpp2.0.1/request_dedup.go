@@ -0,0 +1,124 @@
+package ocpp2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// RequestKeyFunc returns the idempotency key of a request sent to a charging station, see CSMS.SetRequestDeduplication.
+// Requests with the same key, sent to the same station within the deduplication window, are considered duplicates.
+// An empty key excludes the request from deduplication.
+type RequestKeyFunc func(clientId string, request ocpp.Request) string
+
+// PayloadRequestKey is the default RequestKeyFunc, which considers requests with the same feature and payload as duplicates.
+func PayloadRequestKey(clientId string, request ocpp.Request) string {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(payload)
+	return hex.EncodeToString(hash[:])
+}
+
+type dedupKey struct {
+	chargingStationID string
+	featureName       string
+	key               string
+}
+
+// An outgoing request, along with the callbacks of its duplicates.
+type dedupEntry struct {
+	done     bool
+	expired  bool
+	response ocpp.Response
+	err      error
+	waiters  []func(ocpp.Response, error)
+}
+
+// requestDeduplicator suppresses duplicate outgoing requests. The first request is sent to the station,
+// while the callbacks of its duplicates receive the result of the first request.
+//
+// An entry is kept for the duration of the window, starting when the first request is sent,
+// or until the first request completes, whichever happens later.
+type requestDeduplicator struct {
+	window  time.Duration
+	keyFunc RequestKeyFunc
+	mutex   sync.Mutex
+	entries map[dedupKey]*dedupEntry
+}
+
+func newRequestDeduplicator(window time.Duration, keyFunc RequestKeyFunc) *requestDeduplicator {
+	if keyFunc == nil {
+		keyFunc = PayloadRequestKey
+	}
+	return &requestDeduplicator{window: window, keyFunc: keyFunc, entries: map[dedupKey]*dedupEntry{}}
+}
+
+// Registers an outgoing request. If the request is a duplicate, the callback is registered to receive the result
+// of the original request and false is returned. Otherwise, a wrapped callback is returned, which must be used
+// for sending the request, and release must be invoked if the request couldn't be sent.
+func (d *requestDeduplicator) register(clientId string, request ocpp.Request, callback func(ocpp.Response, error)) (wrapped func(ocpp.Response, error), release func(err error), send bool) {
+	k := d.keyFunc(clientId, request)
+	if k == "" {
+		return callback, func(error) {}, true
+	}
+	key := dedupKey{chargingStationID: clientId, featureName: request.GetFeatureName(), key: k}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if entry, ok := d.entries[key]; ok && !entry.expired {
+		if entry.done {
+			go callback(entry.response, entry.err)
+		} else {
+			entry.waiters = append(entry.waiters, callback)
+		}
+		return nil, nil, false
+	}
+	entry := &dedupEntry{}
+	d.entries[key] = entry
+	time.AfterFunc(d.window, func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		entry.expired = true
+		if entry.done && d.entries[key] == entry {
+			delete(d.entries, key)
+		}
+	})
+	wrapped = func(response ocpp.Response, err error) {
+		callback(response, err)
+		for _, waiter := range d.complete(key, entry, response, err) {
+			waiter(response, err)
+		}
+	}
+	release = func(err error) {
+		for _, waiter := range d.complete(key, entry, nil, err) {
+			go waiter(nil, err)
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if d.entries[key] == entry {
+			// A request that was never sent cannot be deduplicated
+			delete(d.entries, key)
+		}
+	}
+	return wrapped, release, true
+}
+
+// Stores the result of a request and returns the callbacks of its duplicates.
+func (d *requestDeduplicator) complete(key dedupKey, entry *dedupEntry, response ocpp.Response, err error) []func(ocpp.Response, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	entry.done = true
+	entry.response = response
+	entry.err = err
+	waiters := entry.waiters
+	entry.waiters = nil
+	if entry.expired && d.entries[key] == entry {
+		delete(d.entries, key)
+	}
+	return waiters
+}
@@ -0,0 +1,84 @@
+package ocpp2
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+// RetryPolicy defines when a failed request is resent to a charging station, see CSMS.SendRequestWithRetry.
+//
+// Requests which timed out are always resent, while CALLERROR responses are only resent if their
+// error code is contained in RetryableErrors. Any response, including a response with a rejected status,
+// completes the request.
+type RetryPolicy struct {
+	MaxAttempts     int                           // The maximum number of attempts, including the first one. Values lower than 1 are treated as 1.
+	Backoff         func(retry int) time.Duration // Returns the delay before the given retry, starting at 1. If nil, failed requests are resent immediately.
+	RetryableErrors []ocpp.ErrorCode              // The CALLERROR codes, which cause a request to be resent, e.g. InternalError.
+}
+
+// ExponentialBackoff returns a RetryPolicy backoff, which doubles the delay for every retry, starting from initial.
+// The delay never exceeds max.
+func ExponentialBackoff(initial time.Duration, max time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		delay := initial
+		for i := 1; i < retry && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// IsRequestTimeout returns true, if the error was returned because no response to a request was received in time.
+func IsRequestTimeout(err error) bool {
+	var ocppErr *ocpp.Error
+	return errors.As(err, &ocppErr) && ocppErr.Code == ocppj.GenericError && ocppErr.Description == ocppj.RequestTimedOut
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if IsRequestTimeout(err) {
+		return true
+	}
+	var ocppErr *ocpp.Error
+	if !errors.As(err, &ocppErr) {
+		return false
+	}
+	for _, code := range p.RetryableErrors {
+		if ocppErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	if p.Backoff == nil {
+		return 0
+	}
+	return p.Backoff(retry)
+}
+
+func (cs *csms) SendRequestWithRetry(clientId string, request ocpp.Request, policy RetryPolicy, callback func(ocpp.Response, error)) error {
+	attempt := 1
+	var retryCallback func(ocpp.Response, error)
+	retryCallback = func(response ocpp.Response, err error) {
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			callback(response, err)
+			return
+		}
+		delay := policy.delay(attempt)
+		attempt++
+		time.AfterFunc(delay, func() {
+			// Retries bypass deduplication, which would return the result of the failed attempt
+			if err := cs.queueRequest(clientId, request, retryCallback); err != nil {
+				callback(nil, err)
+			}
+		})
+	}
+	return cs.SendRequestAsync(clientId, request, retryCallback)
+}
@@ -0,0 +1,37 @@
+package ocpp2
+
+import (
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// responseHooks holds functions, which are run once the response to an incoming request was sent.
+// Hooks are registered by the handler of a request, and identified by the request itself.
+type responseHooks struct {
+	mutex sync.Mutex
+	hooks map[ocpp.Request][]func(sent bool)
+}
+
+func newResponseHooks() *responseHooks {
+	return &responseHooks{hooks: map[ocpp.Request][]func(sent bool){}}
+}
+
+// Registers a function, which is run once the request was responded to.
+func (h *responseHooks) add(request ocpp.Request, hook func(sent bool)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.hooks[request] = append(h.hooks[request], hook)
+}
+
+// Runs and removes all functions registered for the request. The sent flag reports whether the response
+// returned by the handler was sent to the charging station, or an error was replied instead.
+func (h *responseHooks) run(request ocpp.Request, sent bool) {
+	h.mutex.Lock()
+	hooks := h.hooks[request]
+	delete(h.hooks, request)
+	h.mutex.Unlock()
+	for _, hook := range hooks {
+		hook(sent)
+	}
+}
@@ -1,6 +1,11 @@
 package security
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"reflect"
 
 	"gopkg.in/go-playground/validator.v9"
@@ -68,6 +73,56 @@ func (c CertificateSignedResponse) GetFeatureName() string {
 	return CertificateSignedFeatureName
 }
 
+// Maximum amount of certificates in a certificate chain: the leaf certificate, followed by up to two sub-CA certificates.
+const MaxCertificateChainLength = 3
+
+// ParseCertificateChain decodes the PEM encoded certificate chain, starting with the leaf certificate.
+// An error is returned if the chain is empty, contains more than MaxCertificateChainLength certificates,
+// or if a certificate wasn't signed by the following certificate in the chain.
+//
+// The charging station should invoke this before installing the certificate, and return a Rejected status if an error is returned.
+func (r CertificateSignedRequest) ParseCertificateChain() ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	rest := []byte(r.CertificateChain)
+	for len(bytes.TrimSpace(rest)) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("invalid certificate chain, no PEM data found")
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("invalid certificate chain, unexpected PEM block type %v", block.Type)
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate at position %v: %w", len(chain), err)
+		}
+		chain = append(chain, certificate)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("invalid certificate chain, no certificate found")
+	}
+	if len(chain) > MaxCertificateChainLength {
+		return nil, fmt.Errorf("invalid certificate chain, contains %v certificates, maximum is %v", len(chain), MaxCertificateChainLength)
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		if err := chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return nil, fmt.Errorf("invalid certificate chain, certificate at position %v not signed by its successor: %w", i, err)
+		}
+	}
+	return chain, nil
+}
+
+// EncodeCertificateChain PEM encodes a certificate chain, for sending it within a CertificateSignedRequest.
+// The chain must start with the leaf certificate, followed by the sub-CA certificates.
+func EncodeCertificateChain(chain []*x509.Certificate) string {
+	var buf bytes.Buffer
+	for _, certificate := range chain {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	}
+	return buf.String()
+}
+
 // Creates a new CertificateSignedRequest, containing all required fields. Additional optional fields may be set afterwards.
 func NewCertificateSignedRequest(certificateChain string) *CertificateSignedRequest {
 	return &CertificateSignedRequest{CertificateChain: certificateChain}
@@ -10,9 +10,71 @@ import (
 
 const SecurityEventNotificationFeatureName = "SecurityEventNotification"
 
+// Standardized security event types, as defined by the Security events list of the OCPP 2.0.1 specification.
+// Charging stations may also report custom, vendor-specific event types.
+const (
+	SecurityEventFirmwareUpdated                     = "FirmwareUpdated"                     // Critical. The Charging Station firmware is updated.
+	SecurityEventFailedToAuthenticateAtCsms          = "FailedToAuthenticateAtCsms"          // The authentication credentials provided by the Charging Station were rejected by the CSMS.
+	SecurityEventCsmsFailedToAuthenticate            = "CsmsFailedToAuthenticate"            // The authentication credentials provided by the CSMS were rejected by the Charging Station.
+	SecurityEventSettingSystemTime                   = "SettingSystemTime"                   // Critical. The system time on the Charging Station was changed.
+	SecurityEventStartupOfTheDevice                  = "StartupOfTheDevice"                  // Critical. The Charging Station has booted.
+	SecurityEventResetOrReboot                       = "ResetOrReboot"                       // Critical. The Charging Station was rebooted or reset.
+	SecurityEventSecurityLogWasCleared               = "SecurityLogWasCleared"               // Critical. The security log was cleared.
+	SecurityEventReconfigurationOfSecurityParameters = "ReconfigurationOfSecurityParameters" // Security parameters, such as keys or the security profile used, were changed.
+	SecurityEventMemoryExhaustion                    = "MemoryExhaustion"                    // Critical. The Flash or RAM memory of the Charging Station is getting full.
+	SecurityEventInvalidMessages                     = "InvalidMessages"                     // The Charging Station has received messages that are not valid OCPP messages.
+	SecurityEventAttemptedReplayAttacks              = "AttemptedReplayAttacks"              // The Charging Station has received a replayed message.
+	SecurityEventTamperDetectionActivated            = "TamperDetectionActivated"            // Critical. The physical tamper detection sensor was triggered.
+	SecurityEventInvalidFirmwareSignature            = "InvalidFirmwareSignature"            // The firmware signature is not valid.
+	SecurityEventInvalidFirmwareSigningCertificate   = "InvalidFirmwareSigningCertificate"   // The certificate used to verify the firmware signature is not valid.
+	SecurityEventInvalidCsmsCertificate              = "InvalidCsmsCertificate"              // The certificate that the CSMS uses was not valid or could not be verified.
+	SecurityEventInvalidChargingStationCertificate   = "InvalidChargingStationCertificate"   // The certificate sent to the Charging Station via CertificateSigned was not valid.
+	SecurityEventInvalidTLSVersion                   = "InvalidTLSVersion"                   // The TLS version used by the CSMS is lower than 1.2 and is not allowed by the security specification.
+	SecurityEventInvalidTLSCipherSuite               = "InvalidTLSCipherSuite"               // The CSMS did only allow connections using TLS cipher suites that are not allowed by the security specification.
+	SecurityEventMaintenanceLoginAccepted            = "MaintenanceLoginAccepted"            // Critical. A successful login to a local maintenance interface.
+	SecurityEventMaintenanceLoginFailed              = "MaintenanceLoginFailed"              // Critical. A failed login attempt to a local maintenance interface.
+)
+
+// Standardized security event types, mapped to whether they are critical.
+// Critical events are pushed by the charging station via SecurityEventNotification as soon as they occur.
+var standardSecurityEvents = map[string]bool{
+	SecurityEventFirmwareUpdated:                     true,
+	SecurityEventFailedToAuthenticateAtCsms:          false,
+	SecurityEventCsmsFailedToAuthenticate:            false,
+	SecurityEventSettingSystemTime:                   true,
+	SecurityEventStartupOfTheDevice:                  true,
+	SecurityEventResetOrReboot:                       true,
+	SecurityEventSecurityLogWasCleared:               true,
+	SecurityEventReconfigurationOfSecurityParameters: false,
+	SecurityEventMemoryExhaustion:                    true,
+	SecurityEventInvalidMessages:                     false,
+	SecurityEventAttemptedReplayAttacks:              false,
+	SecurityEventTamperDetectionActivated:            true,
+	SecurityEventInvalidFirmwareSignature:            false,
+	SecurityEventInvalidFirmwareSigningCertificate:   false,
+	SecurityEventInvalidCsmsCertificate:              false,
+	SecurityEventInvalidChargingStationCertificate:   false,
+	SecurityEventInvalidTLSVersion:                   false,
+	SecurityEventInvalidTLSCipherSuite:               false,
+	SecurityEventMaintenanceLoginAccepted:            true,
+	SecurityEventMaintenanceLoginFailed:              true,
+}
+
+// IsStandardSecurityEvent returns true, if the event type is part of the standardized security events list.
+func IsStandardSecurityEvent(typ string) bool {
+	_, ok := standardSecurityEvents[typ]
+	return ok
+}
+
+// IsCriticalSecurityEvent returns true, if the event type is a standardized security event, which is marked as critical.
+// Custom event types are never considered critical.
+func IsCriticalSecurityEvent(typ string) bool {
+	return standardSecurityEvents[typ]
+}
+
 // The field definition of the SecurityEventNotification request payload sent by the Charging Station to the CSMS.
 type SecurityEventNotificationRequest struct {
-	Type      string          `json:"type" validate:"required,max=50"`                 // Type of the security event. This value should be taken from the Security events list.
+	Type      string          `json:"type" validate:"required,max=50"`                 // Type of the security event. This value should be taken from the Security events list, but custom types are allowed.
 	Timestamp *types.DateTime `json:"timestamp" validate:"required"`                   // Date and time at which the event occurred.
 	TechInfo  string          `json:"techInfo,omitempty" validate:"omitempty,max=255"` // Additional information about the occurred security event.
 }
@@ -47,6 +109,17 @@ func (c SecurityEventNotificationResponse) GetFeatureName() string {
 	return SecurityEventNotificationFeatureName
 }
 
+// IsStandard returns true, if the reported event type is part of the standardized security events list.
+// Custom event types are valid, but should be handled as opaque strings.
+func (r SecurityEventNotificationRequest) IsStandard() bool {
+	return IsStandardSecurityEvent(r.Type)
+}
+
+// IsCritical returns true, if the reported event type is a critical standardized security event.
+func (r SecurityEventNotificationRequest) IsCritical() bool {
+	return IsCriticalSecurityEvent(r.Type)
+}
+
 // Creates a new SecurityEventNotificationRequest, containing all required fields. Optional fields may be set afterwards.
 func NewSecurityEventNotificationRequest(typ string, timestamp *types.DateTime) *SecurityEventNotificationRequest {
 	return &SecurityEventNotificationRequest{Type: typ, Timestamp: timestamp}
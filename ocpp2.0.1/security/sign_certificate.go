@@ -1,6 +1,11 @@
 package security
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"reflect"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
@@ -51,6 +56,29 @@ func (c SignCertificateResponse) GetFeatureName() string {
 	return SignCertificateFeatureName
 }
 
+// ParseCSR decodes the PEM encoded certificate signing request and verifies its signature.
+// The CSMS should invoke this before forwarding the CSR to a certificate authority, and reject the request if an error is returned.
+func (r SignCertificateRequest) ParseCSR() (*x509.CertificateRequest, error) {
+	block, rest := pem.Decode([]byte(r.CSR))
+	if block == nil {
+		return nil, errors.New("invalid CSR, no PEM data found")
+	}
+	if block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("invalid CSR, unexpected PEM block type %v", block.Type)
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, errors.New("invalid CSR, unexpected data after PEM block")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSR: %w", err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid CSR signature: %w", err)
+	}
+	return csr, nil
+}
+
 // Creates a new SignCertificateRequest, containing all required fields. Optional fields may be set afterwards.
 func NewSignCertificateRequest(csr string) *SignCertificateRequest {
 	return &SignCertificateRequest{CSR: csr}
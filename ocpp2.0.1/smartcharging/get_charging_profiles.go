@@ -16,8 +16,8 @@ const GetChargingProfilesFeatureName = "GetChargingProfiles"
 type GetChargingProfileStatus string
 
 const (
-	GetChargingProfileStatusAccepted   GetChargingProfileStatus = "Accepted"
-	GetChargingProfileStatusNoProfiles GetChargingProfileStatus = "NoProfiles"
+	GetChargingProfileStatusAccepted   GetChargingProfileStatus = "Accepted"   // Matching profiles will be reported via ReportChargingProfiles messages.
+	GetChargingProfileStatusNoProfiles GetChargingProfileStatus = "NoProfiles" // No profiles matched the request, hence none will be reported.
 )
 
 func isValidGetChargingProfileStatus(fl validator.FieldLevel) bool {
@@ -41,9 +41,9 @@ type ChargingProfileCriterion struct {
 
 // The field definition of the GetChargingProfiles request payload sent by the CSMS to the Charging Station.
 type GetChargingProfilesRequest struct {
-	RequestID       int                      `json:"requestId"`
-	EvseID          *int                     `json:"evseId,omitempty" validate:"omitempty,gte=0"`
-	ChargingProfile ChargingProfileCriterion `json:"chargingProfile" validate:"required"`
+	RequestID       int                      `json:"requestId"`                                   // Reference identification that is to be used by the Charging Station in the ReportChargingProfilesRequest.
+	EvseID          *int                     `json:"evseId,omitempty" validate:"omitempty,gte=0"` // For which EVSE installed charging profiles SHALL be reported. If 0, only station-wide profiles are reported. If omitted, all installed profiles are reported.
+	ChargingProfile ChargingProfileCriterion `json:"chargingProfile" validate:"required"`         // Specifies the charging profile criteria.
 }
 
 // This field definition of the GetChargingProfiles response payload, sent by the Charging Station to the CSMS in response to a GetChargingProfilesRequest.
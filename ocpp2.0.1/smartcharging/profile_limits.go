@@ -0,0 +1,130 @@
+package smartcharging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Names of the SmartChargingCtrlr variables, from which ChargingProfileLimits are populated.
+const (
+	VariablePeriodsPerSchedule = "PeriodsPerSchedule" // Maximum number of periods that may be defined per ChargingSchedule.
+	VariableProfileStackLevel  = "ProfileStackLevel"  // Maximum acceptable value for stackLevel in a ChargingProfile.
+	VariableRateUnit           = "RateUnit"           // Comma-separated list of supported quantities for use in a ChargingSchedule.
+	VariableEntries            = "Entries"            // Amount of ChargingProfiles currently installed on the Charging Station (instance "ChargingProfiles").
+)
+
+// ChargingProfileLimits contains the smart charging capabilities of a charging station, as learned from its device model.
+// Limits that are unknown may be left empty, in which case they are not enforced.
+type ChargingProfileLimits struct {
+	PeriodsPerSchedule *int                         // Maximum number of periods per charging schedule.
+	ProfileStackLevel  *int                         // Maximum stack level of a charging profile.
+	RateUnits          []types.ChargingRateUnitType // Supported charging rate units.
+	MaxProfiles        *int                         // Maximum number of charging profiles that may be installed (maxLimit of the Entries variable).
+	InstalledProfiles  int                          // Number of charging profiles currently installed, not counting a profile that would be replaced.
+	MaxCurrent         *float64                     // Maximum current in A, which a schedule period may allow.
+	MaxPower           *float64                     // Maximum power in W, which a schedule period may allow.
+	MaxPhases          *int                         // Maximum number of phases, which a schedule period may use.
+}
+
+// Creates ChargingProfileLimits from the values of SmartChargingCtrlr variables, keyed by variable name.
+// The maximum number of installed profiles is not part of the variable values and must be set separately, if known.
+// Unknown variables are ignored.
+func NewChargingProfileLimits(variables map[string]string) (*ChargingProfileLimits, error) {
+	limits := &ChargingProfileLimits{}
+	parseInt := func(name string) (*int, error) {
+		value, ok := variables[name]
+		if !ok {
+			return nil, nil
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for variable %v: %w", value, name, err)
+		}
+		return &i, nil
+	}
+	var err error
+	if limits.PeriodsPerSchedule, err = parseInt(VariablePeriodsPerSchedule); err != nil {
+		return nil, err
+	}
+	if limits.ProfileStackLevel, err = parseInt(VariableProfileStackLevel); err != nil {
+		return nil, err
+	}
+	if installed, err := parseInt(VariableEntries); err != nil {
+		return nil, err
+	} else if installed != nil {
+		limits.InstalledProfiles = *installed
+	}
+	if value, ok := variables[VariableRateUnit]; ok {
+		for _, unit := range strings.Split(value, ",") {
+			unit = strings.TrimSpace(unit)
+			if unit != "" {
+				limits.RateUnits = append(limits.RateUnits, types.ChargingRateUnitType(unit))
+			}
+		}
+	}
+	return limits, nil
+}
+
+// ChargingProfileLimitsError is returned when a charging profile exceeds the limits of a charging station.
+// It contains a description of every violated limit.
+type ChargingProfileLimitsError struct {
+	ProfileID  int
+	Violations []string
+}
+
+func (e *ChargingProfileLimitsError) Error() string {
+	return fmt.Sprintf("charging profile %v exceeds charging station limits: %v", e.ProfileID, strings.Join(e.Violations, "; "))
+}
+
+// ValidateProfileAgainstLimits checks whether a charging profile can be accepted by a charging station with the given limits.
+// It is meant to be invoked before sending a SetChargingProfileRequest, to catch rejections client-side.
+//
+// If one or more limits are violated, a ChargingProfileLimitsError is returned.
+func ValidateProfileAgainstLimits(profile *types.ChargingProfile, limits ChargingProfileLimits) error {
+	var violations []string
+	if limits.ProfileStackLevel != nil && profile.StackLevel > *limits.ProfileStackLevel {
+		violations = append(violations, fmt.Sprintf("stackLevel %v exceeds maximum stack level %v", profile.StackLevel, *limits.ProfileStackLevel))
+	}
+	if limits.MaxProfiles != nil && limits.InstalledProfiles >= *limits.MaxProfiles {
+		violations = append(violations, fmt.Sprintf("%v charging profiles are already installed, maximum is %v", limits.InstalledProfiles, *limits.MaxProfiles))
+	}
+	for i, schedule := range profile.ChargingSchedule {
+		if limits.PeriodsPerSchedule != nil && len(schedule.ChargingSchedulePeriod) > *limits.PeriodsPerSchedule {
+			violations = append(violations, fmt.Sprintf("chargingSchedule[%v] contains %v periods, maximum is %v", i, len(schedule.ChargingSchedulePeriod), *limits.PeriodsPerSchedule))
+		}
+		if len(limits.RateUnits) > 0 && !isSupportedRateUnit(schedule.ChargingRateUnit, limits.RateUnits) {
+			violations = append(violations, fmt.Sprintf("chargingSchedule[%v] uses unsupported chargingRateUnit %v, supported are %v", i, schedule.ChargingRateUnit, limits.RateUnits))
+		}
+		var maxLimit *float64
+		switch schedule.ChargingRateUnit {
+		case types.ChargingRateUnitAmperes:
+			maxLimit = limits.MaxCurrent
+		case types.ChargingRateUnitWatts:
+			maxLimit = limits.MaxPower
+		}
+		for j, period := range schedule.ChargingSchedulePeriod {
+			if maxLimit != nil && period.Limit > *maxLimit {
+				violations = append(violations, fmt.Sprintf("chargingSchedule[%v].chargingSchedulePeriod[%v] limit %v%v exceeds maximum %v%v", i, j, period.Limit, schedule.ChargingRateUnit, *maxLimit, schedule.ChargingRateUnit))
+			}
+			if limits.MaxPhases != nil && period.NumberPhases != nil && *period.NumberPhases > *limits.MaxPhases {
+				violations = append(violations, fmt.Sprintf("chargingSchedule[%v].chargingSchedulePeriod[%v] uses %v phases, maximum is %v", i, j, *period.NumberPhases, *limits.MaxPhases))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &ChargingProfileLimitsError{ProfileID: profile.ID, Violations: violations}
+	}
+	return nil
+}
+
+func isSupportedRateUnit(unit types.ChargingRateUnitType, supported []types.ChargingRateUnitType) bool {
+	for _, u := range supported {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package smartcharging
+
+import (
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// SchedulePoint is a time interval of a resolved charging schedule, during which a constant limit applies.
+type SchedulePoint struct {
+	Start            time.Time                  // Time at which the limit starts to apply.
+	End              time.Time                  // Time at which the limit stops to apply (exclusive).
+	Limit            float64                    // The charging rate limit, in the unit of the schedule.
+	NumberPhases     *int                       // The number of phases that can be used for charging, if set by the schedule.
+	ChargingRateUnit types.ChargingRateUnitType // The unit of the limit.
+}
+
+// Returns the length of a recurrence, or zero for unknown recurrency kinds.
+func recurrencePeriod(kind types.RecurrencyKindType) time.Duration {
+	switch kind {
+	case types.RecurrencyKindDaily:
+		return 24 * time.Hour
+	case types.RecurrencyKindWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// ResolveSchedule resolves the charging schedule of a profile into the concrete limits that apply within the window [from, to).
+// The returned points are ordered by time and don't overlap. Intervals during which the schedule defines no limit
+// (e.g. after the duration of the schedule elapsed) are not covered by any point.
+//
+// The start of the schedule depends on the kind of the profile:
+//   - Absolute: the schedule starts at its startSchedule
+//   - Relative: the schedule starts at from, which should hence be set to the start of the transaction
+//   - Recurring: the schedule starts at its startSchedule and repeats every day or week, depending on the recurrency kind.
+//     A duration longer than the recurrence is cut off by the next occurrence.
+//
+// The validFrom and validTo fields of the profile restrict the window further.
+// Only the first charging schedule of the profile is resolved, as additional schedules are alternatives offered to an EV via ISO 15118.
+//
+// Returns nil, if no limit applies within the window, or an absolute or recurring schedule has no startSchedule.
+func ResolveSchedule(profile *types.ChargingProfile, from time.Time, to time.Time) []SchedulePoint {
+	if profile == nil || len(profile.ChargingSchedule) == 0 {
+		return nil
+	}
+	schedule := profile.ChargingSchedule[0]
+	windowStart, windowEnd := from, to
+	if profile.ValidFrom != nil && profile.ValidFrom.After(windowStart) {
+		windowStart = profile.ValidFrom.Time
+	}
+	if profile.ValidTo != nil && profile.ValidTo.Before(windowEnd) {
+		windowEnd = profile.ValidTo.Time
+	}
+	if !windowStart.Before(windowEnd) {
+		return nil
+	}
+	// Determine the start of every occurrence of the schedule, as well as its maximum length
+	var occurrences []time.Time
+	var maxLength time.Duration
+	switch profile.ChargingProfileKind {
+	case types.ChargingProfileKindRelative:
+		occurrences = []time.Time{from}
+	case types.ChargingProfileKindAbsolute:
+		if schedule.StartSchedule == nil {
+			return nil
+		}
+		occurrences = []time.Time{schedule.StartSchedule.Time}
+	case types.ChargingProfileKindRecurring:
+		period := recurrencePeriod(profile.RecurrencyKind)
+		if schedule.StartSchedule == nil || period == 0 {
+			return nil
+		}
+		maxLength = period
+		start := schedule.StartSchedule.Time
+		if start.Before(windowStart) {
+			// Skip all occurrences which ended before the window
+			start = start.Add(windowStart.Sub(start) / period * period)
+		}
+		for ; start.Before(windowEnd); start = start.Add(period) {
+			occurrences = append(occurrences, start)
+		}
+	default:
+		return nil
+	}
+	var points []SchedulePoint
+	for _, occurrenceStart := range occurrences {
+		occurrenceEnd := windowEnd
+		length := maxLength
+		if schedule.Duration != nil && (length == 0 || time.Duration(*schedule.Duration)*time.Second < length) {
+			length = time.Duration(*schedule.Duration) * time.Second
+		}
+		if length > 0 && occurrenceStart.Add(length).Before(occurrenceEnd) {
+			occurrenceEnd = occurrenceStart.Add(length)
+		}
+		for i, period := range schedule.ChargingSchedulePeriod {
+			start := occurrenceStart.Add(time.Duration(period.StartPeriod) * time.Second)
+			end := occurrenceEnd
+			if i+1 < len(schedule.ChargingSchedulePeriod) {
+				if next := occurrenceStart.Add(time.Duration(schedule.ChargingSchedulePeriod[i+1].StartPeriod) * time.Second); next.Before(end) {
+					end = next
+				}
+			}
+			if start.Before(windowStart) {
+				start = windowStart
+			}
+			if !start.Before(end) {
+				continue
+			}
+			points = appendSchedulePoint(points, SchedulePoint{
+				Start:            start,
+				End:              end,
+				Limit:            period.Limit,
+				NumberPhases:     period.NumberPhases,
+				ChargingRateUnit: schedule.ChargingRateUnit,
+			})
+		}
+	}
+	return points
+}
+
+// Appends a point to a resolved schedule, merging it with the previous point if the limit stays the same.
+func appendSchedulePoint(points []SchedulePoint, point SchedulePoint) []SchedulePoint {
+	if n := len(points); n > 0 {
+		last := &points[n-1]
+		if last.End.Equal(point.Start) && last.Limit == point.Limit && samePhases(last.NumberPhases, point.NumberPhases) {
+			last.End = point.End
+			return points
+		}
+	}
+	return append(points, point)
+}
+
+func samePhases(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
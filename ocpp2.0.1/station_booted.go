@@ -0,0 +1,58 @@
+package ocpp2
+
+import (
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+)
+
+// BootInfo contains the identity of a charging station, as reported in a BootNotification accepted by the CSMS.
+type BootInfo struct {
+	VendorName      string                  // The vendor of the charging station.
+	Model           string                  // The model of the charging station.
+	SerialNumber    string                  // The serial number of the charging station, if reported.
+	FirmwareVersion string                  // The firmware version of the charging station, if reported.
+	Modem           *provisioning.ModemType // The wireless modem of the charging station, if reported.
+	Reason          provisioning.BootReason // The reason for the boot.
+	Interval        time.Duration           // The heartbeat interval granted by the CSMS in the BootNotificationResponse.
+}
+
+// StationBootedHandler is invoked whenever the CSMS accepted the BootNotification of a charging station. See CSMS.SetStationBootedHandler.
+type StationBootedHandler func(stationID string, info BootInfo)
+
+func newBootInfo(request *provisioning.BootNotificationRequest, response *provisioning.BootNotificationResponse) BootInfo {
+	station := request.ChargingStation
+	info := BootInfo{
+		VendorName:      station.VendorName,
+		Model:           station.Model,
+		SerialNumber:    station.SerialNumber,
+		FirmwareVersion: station.FirmwareVersion,
+		Reason:          request.Reason,
+		Interval:        time.Duration(response.Interval) * time.Second,
+	}
+	if station.Modem != nil {
+		modem := *station.Modem
+		info.Modem = &modem
+	}
+	return info
+}
+
+// Sends the response to a BootNotification and records whether the charging station booted.
+// A charging station is considered booted only if an accepted response was actually sent to it, in which case
+// the station booted handler is notified. The station is marked as booted before sending the response,
+// so that requests sent right after receiving it aren't rejected by the boot order policy.
+// Returns true, if the response was sent.
+func (cs *csms) respondToBoot(features csmsFeatures, stationID string, request *provisioning.BootNotificationRequest, response ocpp.Response, err error, respond func(response ocpp.Response, err error) bool) bool {
+	bootResponse, _ := response.(*provisioning.BootNotificationResponse)
+	accepted := err == nil && bootResponse != nil && bootResponse.Status == provisioning.RegistrationStatusAccepted
+	if accepted {
+		cs.bootedStations.set(stationID, true)
+	}
+	sent := respond(response, err)
+	cs.bootedStations.set(stationID, accepted && sent)
+	if handler := features.stationBootedHandler; handler != nil && accepted && sent {
+		handler(stationID, newBootInfo(request, bootResponse))
+	}
+	return sent
+}
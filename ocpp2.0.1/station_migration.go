@@ -0,0 +1,126 @@
+package ocpp2
+
+import (
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// Component and variable names, used for activating a network connection profile on a charging station.
+const (
+	componentOCPPCommCtrlr               = "OCPPCommCtrlr"
+	variableNetworkConfigurationPriority = "NetworkConfigurationPriority"
+)
+
+// StationMigrationOptions contains optional parameters for migrating charging stations via MigrateStations.
+type StationMigrationOptions struct {
+	// If set, the OCPPCommCtrlr.NetworkConfigurationPriority variable is set to this value after the new profile was accepted,
+	// e.g. "1,0" to prefer the profile stored in slot 1 over the one in slot 0.
+	NetworkConfigurationPriority string
+	// If true, the charging station is reset after the new profile was accepted, causing it to reconnect.
+	Reset bool
+	// The type of reset to request. Defaults to OnIdle, to avoid interrupting ongoing transactions.
+	ResetType provisioning.ResetType
+}
+
+// StationMigrationResult contains the outcome of migrating a single charging station.
+//
+// The migration of a station stops at the first failed or rejected step. Steps which were not executed have an empty status.
+type StationMigrationResult struct {
+	ChargingStationID string
+	ProfileStatus     provisioning.SetNetworkProfileStatus // Response status of the SetNetworkProfile request.
+	PriorityStatus    provisioning.SetVariableStatus       // Result of setting the network configuration priority, if requested.
+	ResetStatus       provisioning.ResetStatus             // Response status of the Reset request, if requested.
+	Err               error                                // Set if one of the requests couldn't be sent or failed with an error.
+}
+
+// Migrated returns true, if all requested migration steps were accepted by the charging station.
+func (r StationMigrationResult) Migrated() bool {
+	return r.Err == nil && r.ProfileStatus == provisioning.SetNetworkProfileStatusAccepted &&
+		(r.PriorityStatus == "" || r.PriorityStatus == provisioning.SetVariableStatusAccepted || r.PriorityStatus == provisioning.SetVariableStatusRebootRequired) &&
+		(r.ResetStatus == "" || r.ResetStatus == provisioning.ResetStatusAccepted || r.ResetStatus == provisioning.ResetStatusScheduled)
+}
+
+func (cs *csms) MigrateStations(stationIDs []string, configurationSlot int, newProfile provisioning.NetworkConnectionProfile, callback func(result StationMigrationResult), props ...func(options *StationMigrationOptions)) {
+	options := StationMigrationOptions{ResetType: provisioning.ResetTypeOnIdle}
+	for _, fn := range props {
+		fn(&options)
+	}
+	go func() {
+		// Each migrating station holds a slot until all of its steps completed
+		slots := make(chan struct{}, bulkRequestConcurrency)
+		for _, id := range stationIDs {
+			slots <- struct{}{}
+			cs.migrateStation(id, configurationSlot, newProfile, options, func(result StationMigrationResult) {
+				<-slots
+				callback(result)
+			})
+		}
+	}()
+}
+
+func (cs *csms) migrateStation(clientId string, configurationSlot int, newProfile provisioning.NetworkConnectionProfile, options StationMigrationOptions, done func(result StationMigrationResult)) {
+	result := StationMigrationResult{ChargingStationID: clientId}
+	fail := func(err error) {
+		result.Err = err
+		done(result)
+	}
+	reset := func() {
+		if !options.Reset {
+			done(result)
+			return
+		}
+		err := cs.Reset(clientId, func(response *provisioning.ResetResponse, err error) {
+			if response != nil {
+				result.ResetStatus = response.Status
+			}
+			result.Err = err
+			done(result)
+		}, options.ResetType)
+		if err != nil {
+			fail(err)
+		}
+	}
+	setPriority := func() {
+		if options.NetworkConfigurationPriority == "" {
+			reset()
+			return
+		}
+		data := provisioning.SetVariableData{
+			AttributeValue: options.NetworkConfigurationPriority,
+			Component:      types.Component{Name: componentOCPPCommCtrlr},
+			Variable:       types.Variable{Name: variableNetworkConfigurationPriority},
+		}
+		err := cs.SetVariables(clientId, func(response *provisioning.SetVariablesResponse, err error) {
+			if err != nil {
+				fail(err)
+				return
+			}
+			if len(response.SetVariableResult) > 0 {
+				result.PriorityStatus = response.SetVariableResult[0].AttributeStatus
+			}
+			if !result.Migrated() {
+				done(result)
+				return
+			}
+			reset()
+		}, []provisioning.SetVariableData{data})
+		if err != nil {
+			fail(err)
+		}
+	}
+	err := cs.SetNetworkProfile(clientId, func(response *provisioning.SetNetworkProfileResponse, err error) {
+		if err != nil {
+			fail(err)
+			return
+		}
+		result.ProfileStatus = response.Status
+		if response.Status != provisioning.SetNetworkProfileStatusAccepted {
+			done(result)
+			return
+		}
+		setPriority()
+	}, configurationSlot, newProfile)
+	if err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,124 @@
+package ocpp2
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+)
+
+// StatusNotificationSequenceHandler may optionally be implemented by an availability handler.
+// When StatusNotification debouncing is enabled, such a handler receives the full sequence of coalesced notifications
+// for a connector, in the order they were received, instead of only the most recent one.
+type StatusNotificationSequenceHandler interface {
+	OnStatusNotificationSequence(chargingStationID string, requests []*availability.StatusNotificationRequest)
+}
+
+type connectorKey struct {
+	chargingStationID string
+	evseID            int
+	connectorID       int
+}
+
+// statusNotificationDebouncer coalesces StatusNotifications per connector.
+// Every notification for a connector restarts its quiet period. Once no further notification was received
+// for the connector within the quiet period, the collected notifications are delivered at once.
+type statusNotificationDebouncer struct {
+	window  time.Duration
+	deliver func(chargingStationID string, requests []*availability.StatusNotificationRequest)
+	mutex   sync.Mutex
+	pending map[connectorKey]*pendingStatusNotifications
+}
+
+// The notifications collected for a connector, which weren't delivered yet.
+type pendingStatusNotifications struct {
+	requests []*availability.StatusNotificationRequest
+	timer    *time.Timer
+	sequence int // Incremented whenever the timer is restarted.
+}
+
+func newStatusNotificationDebouncer(window time.Duration, deliver func(chargingStationID string, requests []*availability.StatusNotificationRequest)) *statusNotificationDebouncer {
+	return &statusNotificationDebouncer{
+		window:  window,
+		deliver: deliver,
+		pending: map[connectorKey]*pendingStatusNotifications{},
+	}
+}
+
+func (d *statusNotificationDebouncer) add(chargingStationID string, request *availability.StatusNotificationRequest) {
+	key := connectorKey{chargingStationID: chargingStationID, evseID: request.EvseID, connectorID: request.ConnectorID}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	pending, ok := d.pending[key]
+	if ok {
+		pending.timer.Stop()
+	} else {
+		pending = &pendingStatusNotifications{}
+		d.pending[key] = pending
+	}
+	pending.requests = append(pending.requests, request)
+	pending.sequence++
+	sequence := pending.sequence
+	pending.timer = time.AfterFunc(d.window, func() {
+		d.expire(key, pending, sequence)
+	})
+}
+
+// Delivers the notifications of a connector, once its quiet period expired.
+// Timers, which were restarted or flushed in the meantime, are ignored.
+func (d *statusNotificationDebouncer) expire(key connectorKey, pending *pendingStatusNotifications, sequence int) {
+	d.mutex.Lock()
+	if d.pending[key] != pending || pending.sequence != sequence {
+		d.mutex.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	d.mutex.Unlock()
+	d.deliver(key.chargingStationID, pending.requests)
+}
+
+// Delivers the pending notifications of all connectors of a charging station right away, e.g. once it disconnected.
+func (d *statusNotificationDebouncer) flush(chargingStationID string) {
+	d.flushMatching(func(key connectorKey) bool {
+		return key.chargingStationID == chargingStationID
+	})
+}
+
+// Delivers the pending notifications of all charging stations right away.
+func (d *statusNotificationDebouncer) flushAll() {
+	d.flushMatching(func(key connectorKey) bool {
+		return true
+	})
+}
+
+func (d *statusNotificationDebouncer) flushMatching(match func(key connectorKey) bool) {
+	d.mutex.Lock()
+	var keys []connectorKey
+	var flushed []*pendingStatusNotifications
+	for key := range d.pending {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.chargingStationID != b.chargingStationID {
+			return a.chargingStationID < b.chargingStationID
+		}
+		if a.evseID != b.evseID {
+			return a.evseID < b.evseID
+		}
+		return a.connectorID < b.connectorID
+	})
+	for _, key := range keys {
+		pending := d.pending[key]
+		pending.timer.Stop()
+		delete(d.pending, key)
+		flushed = append(flushed, pending)
+	}
+	d.mutex.Unlock()
+	for i, pending := range flushed {
+		d.deliver(keys[i].chargingStationID, pending.requests)
+	}
+}
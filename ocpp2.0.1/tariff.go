@@ -0,0 +1,57 @@
+package ocpp2
+
+import (
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// SessionState contains the information collected by the CSMS about an ongoing transaction,
+// which is passed to a TariffEngine for computing the cost of the transaction.
+type SessionState struct {
+	ChargingStationID string             // The ID of the charging station, on which the transaction takes place.
+	TransactionID     string             // The ID of the transaction.
+	Transaction       *TransactionInfo   // The tracked state of the transaction, after applying the current event. Nil if transaction tracking is disabled.
+	MeterValues       []types.MeterValue // All meter values received for the transaction so far, including the ones contained in the current event.
+}
+
+// TariffEngine computes the cost of a transaction, whenever a TransactionEvent is received by the CSMS.
+//
+// The returned cost is the total cost of the transaction so far, including taxes.
+// The optional personal message may be used to show tariff information to the EV driver.
+type TariffEngine func(event transactions.TransactionEventRequest, session SessionState) (cost float64, personalMessage *types.MessageContent, err error)
+
+// tariffSessions collects the meter values of ongoing transactions, per charging station and transaction ID.
+type tariffSessions struct {
+	mutex       sync.Mutex
+	meterValues map[string]map[string][]types.MeterValue
+}
+
+func newTariffSessions() *tariffSessions {
+	return &tariffSessions{meterValues: map[string]map[string][]types.MeterValue{}}
+}
+
+// Adds the meter values of an event to the session and returns all meter values collected so far.
+// The session is discarded once the transaction ended.
+func (s *tariffSessions) apply(chargingStationID string, event *transactions.TransactionEventRequest) []types.MeterValue {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	transactionID := event.TransactionInfo.TransactionID
+	station, ok := s.meterValues[chargingStationID]
+	if !ok {
+		station = map[string][]types.MeterValue{}
+		s.meterValues[chargingStationID] = station
+	}
+	meterValues := append(station[transactionID], event.MeterValue...)
+	if event.EventType == transactions.TransactionEventEnded {
+		delete(station, transactionID)
+		if len(station) == 0 {
+			delete(s.meterValues, chargingStationID)
+		}
+	} else {
+		station[transactionID] = meterValues
+	}
+	// Return a copy, so the engine may not alter the collected values
+	return append([]types.MeterValue{}, meterValues...)
+}
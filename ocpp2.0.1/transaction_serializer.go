@@ -0,0 +1,50 @@
+package ocpp2
+
+import "sync"
+
+// Identifies a transaction on a specific charging station, as transaction IDs are only unique per station.
+type transactionKey struct {
+	chargingStationID string
+	transactionID     string
+}
+
+// transactionSerializer processes TransactionEvent messages of the same transaction one at a time,
+// in the order in which they were received. Events of different transactions are processed in parallel.
+//
+// A dedicated goroutine is started for each transaction with pending events, and exits once all events were processed.
+type transactionSerializer struct {
+	mutex   sync.Mutex
+	pending map[transactionKey][]func()
+}
+
+func newTransactionSerializer() *transactionSerializer {
+	return &transactionSerializer{pending: map[transactionKey][]func(){}}
+}
+
+// Schedules the processing of an event. Must be invoked in the order in which events were received.
+func (s *transactionSerializer) run(chargingStationID string, transactionID string, process func()) {
+	key := transactionKey{chargingStationID: chargingStationID, transactionID: transactionID}
+	s.mutex.Lock()
+	queue, running := s.pending[key]
+	s.pending[key] = append(queue, process)
+	s.mutex.Unlock()
+	if !running {
+		go s.drain(key)
+	}
+}
+
+func (s *transactionSerializer) drain(key transactionKey) {
+	for {
+		s.mutex.Lock()
+		queue := s.pending[key]
+		if len(queue) == 0 {
+			delete(s.pending, key)
+			s.mutex.Unlock()
+			return
+		}
+		process := queue[0]
+		s.pending[key] = queue[1:]
+		s.mutex.Unlock()
+		process()
+	}
+}
@@ -0,0 +1,114 @@
+package ocpp2
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// TransactionInfo is a read-only snapshot of an active transaction on a charging station,
+// as derived by the CSMS from incoming TransactionEvent messages.
+type TransactionInfo struct {
+	TransactionID string                     // The unique ID of the transaction, as assigned by the charging station.
+	ChargingState transactions.ChargingState // The last reported charging state, if any.
+	Evse          *types.EVSE                // The EVSE (and connector) used by the transaction, if reported.
+	IDToken       *types.IdToken             // The last reported IdToken of the transaction, if any.
+	RemoteStartID *int                       // The ID of the remote start request, if the transaction was started remotely.
+	StartedAt     *types.DateTime            // Timestamp of the Started event, or of the first received event if the Started event is missing.
+	UpdatedAt     *types.DateTime            // Timestamp of the most recently applied event.
+	SequenceNo    int                        // Sequence number of the most recently applied event.
+}
+
+// Maximum amount of ended transaction IDs remembered per charging station, for discarding late events.
+const maxEndedTransactions = 100
+
+type stationTransactions struct {
+	active     map[string]*TransactionInfo
+	ended      map[string]struct{}
+	endedOrder []string
+}
+
+// transactionTracker keeps the set of active transactions per charging station.
+// Events with a sequence number lower than the last applied one are considered stale and are discarded,
+// as are events for transactions which already ended.
+type transactionTracker struct {
+	mutex    sync.RWMutex
+	stations map[string]*stationTransactions
+}
+
+func newTransactionTracker() *transactionTracker {
+	return &transactionTracker{stations: map[string]*stationTransactions{}}
+}
+
+func (t *transactionTracker) apply(chargingStationID string, event *transactions.TransactionEventRequest) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	station, ok := t.stations[chargingStationID]
+	if !ok {
+		station = &stationTransactions{active: map[string]*TransactionInfo{}, ended: map[string]struct{}{}}
+		t.stations[chargingStationID] = station
+	}
+	transactionID := event.TransactionInfo.TransactionID
+	if _, ended := station.ended[transactionID]; ended {
+		return
+	}
+	if event.EventType == transactions.TransactionEventEnded {
+		delete(station.active, transactionID)
+		station.ended[transactionID] = struct{}{}
+		station.endedOrder = append(station.endedOrder, transactionID)
+		if len(station.endedOrder) > maxEndedTransactions {
+			delete(station.ended, station.endedOrder[0])
+			station.endedOrder = station.endedOrder[1:]
+		}
+		return
+	}
+	info, ok := station.active[transactionID]
+	if !ok {
+		info = &TransactionInfo{TransactionID: transactionID, StartedAt: event.Timestamp, SequenceNo: event.SequenceNo}
+		station.active[transactionID] = info
+	} else if event.SequenceNo < info.SequenceNo {
+		// Stale event
+		return
+	}
+	if event.EventType == transactions.TransactionEventStarted {
+		info.StartedAt = event.Timestamp
+	}
+	info.SequenceNo = event.SequenceNo
+	info.UpdatedAt = event.Timestamp
+	if event.TransactionInfo.ChargingState != "" {
+		info.ChargingState = event.TransactionInfo.ChargingState
+	}
+	if event.TransactionInfo.RemoteStartID != nil {
+		info.RemoteStartID = event.TransactionInfo.RemoteStartID
+	}
+	if event.Evse != nil {
+		info.Evse = event.Evse
+	}
+	if event.IDToken != nil {
+		info.IDToken = event.IDToken
+	}
+}
+
+// Returns a copy of the active transactions for a charging station, ordered by start time.
+func (t *transactionTracker) activeTransactions(chargingStationID string) []TransactionInfo {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	station, ok := t.stations[chargingStationID]
+	if !ok {
+		return nil
+	}
+	result := make([]TransactionInfo, 0, len(station.active))
+	for _, info := range station.active {
+		result = append(result, *info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].StartedAt, result[j].StartedAt
+		if a != nil && b != nil && !a.Equal(b.Time) {
+			return a.Before(b.Time)
+		}
+		return result[i].TransactionID < result[j].TransactionID
+	})
+	return result
+}
@@ -0,0 +1,141 @@
+package transactions
+
+import (
+	"fmt"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// EnergyReading is the value of the Energy.Active.Import.Register measurand at a specific point in time.
+type EnergyReading struct {
+	Timestamp types.DateTime
+	Wh        float64
+}
+
+// Phases, whose readings add up to the overall value of an energy register.
+var energyPhases = []types.Phase{types.PhaseL1, types.PhaseL2, types.PhaseL3}
+
+// Extracts the reading of the active energy import register from a single meter value.
+// Only sampled values with the given context are considered, unless the context is empty.
+//
+// Readings at the default location (Outlet) are preferred over other locations. Within a location,
+// the overall value is used if present, otherwise the sum of the L1, L2 and L3 phase values.
+func energyRegister(meterValue types.MeterValue, context types.ReadingContext) (float64, bool) {
+	var locations []types.Location
+	overall := map[types.Location]float64{}
+	phases := map[types.Location]map[types.Phase]float64{}
+	for _, sv := range meterValue.SampledValue {
+		if sv.GetMeasurand() != types.MeasurandEnergyActiveImportRegister || (context != "" && sv.GetContext() != context) {
+			continue
+		}
+		wh, err := sv.EnergyWh()
+		if err != nil {
+			continue
+		}
+		location := sv.GetLocation()
+		if _, ok := overall[location]; !ok && phases[location] == nil {
+			locations = append(locations, location)
+		}
+		if sv.Phase == "" {
+			overall[location] = wh
+			continue
+		}
+		if phases[location] == nil {
+			phases[location] = map[types.Phase]float64{}
+		}
+		phases[location][sv.Phase] = wh
+	}
+	// Outlet first, then in order of appearance
+	for i, location := range locations {
+		if location == types.DefaultLocation {
+			locations[0], locations[i] = locations[i], locations[0]
+			break
+		}
+	}
+	for _, location := range locations {
+		if wh, ok := overall[location]; ok {
+			return wh, true
+		}
+		sum, found := 0.0, false
+		for _, phase := range energyPhases {
+			if wh, ok := phases[location][phase]; ok {
+				sum += wh
+				found = true
+			}
+		}
+		if found {
+			return sum, true
+		}
+	}
+	return 0, false
+}
+
+// EnergyReadings returns the readings of the active energy import register contained in the request,
+// in the order of the meter values. Only sampled values with the given reading context are considered,
+// unless the context is empty.
+//
+// If a meter value contains multiple readings of the register, a reading at the Outlet location is preferred.
+// Readings without phase are preferred over per-phase readings, which are otherwise summed up.
+// Values are converted to Wh, applying the unit of measure and its multiplier.
+func (r *TransactionEventRequest) EnergyReadings(context types.ReadingContext) []EnergyReading {
+	var readings []EnergyReading
+	for _, meterValue := range r.MeterValue {
+		if wh, ok := energyRegister(meterValue, context); ok {
+			readings = append(readings, EnergyReading{Timestamp: meterValue.Timestamp, Wh: wh})
+		}
+	}
+	return readings
+}
+
+// StartEnergy returns the energy register at the start of a transaction, as reported in a TransactionEvent.
+// The reading with context Transaction.Begin is used. If the event contains no such reading,
+// the first reading of the register with any other context is used instead.
+//
+// Returns false, if the event contains no reading of the active energy import register.
+func StartEnergy(event *TransactionEventRequest) (EnergyReading, bool) {
+	readings := event.EnergyReadings(types.ReadingContextTransactionBegin)
+	if len(readings) == 0 {
+		readings = event.EnergyReadings("")
+	}
+	if len(readings) == 0 {
+		return EnergyReading{}, false
+	}
+	return readings[0], true
+}
+
+// EndEnergy returns the energy register at the end of a transaction, as reported in a TransactionEvent.
+// The reading with context Transaction.End is used. If the event contains no such reading,
+// the last reading of the register with any other context is used instead.
+//
+// Returns false, if the event contains no reading of the active energy import register.
+func EndEnergy(event *TransactionEventRequest) (EnergyReading, bool) {
+	readings := event.EnergyReadings(types.ReadingContextTransactionEnd)
+	if len(readings) == 0 {
+		readings = event.EnergyReadings("")
+	}
+	if len(readings) == 0 {
+		return EnergyReading{}, false
+	}
+	return readings[len(readings)-1], true
+}
+
+// ConsumedEnergyWh computes the energy consumed during a transaction in Wh, as the difference between
+// the register at the end and at the start of the transaction. Typically, the start event is the Started
+// TransactionEvent and the end event is the Ended TransactionEvent of the same transaction.
+//
+// An error is returned, if either event contains no reading of the register, or if the register decreased.
+func ConsumedEnergyWh(start *TransactionEventRequest, end *TransactionEventRequest) (float64, error) {
+	startReading, ok := StartEnergy(start)
+	if !ok {
+		return 0, fmt.Errorf("no energy register reading in %v event of transaction %v", start.EventType, start.TransactionInfo.TransactionID)
+	}
+	endReading, ok := EndEnergy(end)
+	if !ok {
+		return 0, fmt.Errorf("no energy register reading in %v event of transaction %v", end.EventType, end.TransactionInfo.TransactionID)
+	}
+	consumed := endReading.Wh - startReading.Wh
+	if consumed < 0 {
+		return 0, fmt.Errorf("energy register decreased from %v Wh to %v Wh", startReading.Wh, endReading.Wh)
+	}
+	return consumed, nil
+}
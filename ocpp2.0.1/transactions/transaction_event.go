@@ -198,7 +198,23 @@ func NewTransactionEventResponse() *TransactionEventResponse {
 	return &TransactionEventResponse{}
 }
 
+// Validates that the personal messages contained in the response match their declared format.
+func validateTransactionEventResponse(sl validator.StructLevel) {
+	response := sl.Current().Interface().(TransactionEventResponse)
+	if message := response.UpdatedPersonalMessage; message != nil {
+		if tag := message.FormatViolation(); tag != "" {
+			sl.ReportError(message.Content, "UpdatedPersonalMessage.Content", "content", tag, "")
+		}
+	}
+	if response.IDTokenInfo != nil && response.IDTokenInfo.PersonalMessage != nil {
+		if tag := response.IDTokenInfo.PersonalMessage.FormatViolation(); tag != "" {
+			sl.ReportError(response.IDTokenInfo.PersonalMessage.Content, "IDTokenInfo.PersonalMessage.Content", "content", tag, "")
+		}
+	}
+}
+
 func init() {
+	types.Validate.RegisterStructValidation(validateTransactionEventResponse, TransactionEventResponse{})
 	_ = types.Validate.RegisterValidation("transactionEvent", isValidTransactionEvent)
 	_ = types.Validate.RegisterValidation("triggerReason", isValidTriggerReason)
 	_ = types.Validate.RegisterValidation("chargingState", isValidChargingState)
@@ -0,0 +1,93 @@
+package transactions
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+// TransactionEventHandlerFunc handles a single TransactionEventRequest, see TransactionEventRouter.
+type TransactionEventHandlerFunc func(chargingStationID string, request *TransactionEventRequest) (response *TransactionEventResponse, err error)
+
+// A route matches either a specific event type, or any event type if eventType is empty.
+type transactionEventRoute struct {
+	eventType     TransactionEvent
+	triggerReason TriggerReason
+}
+
+// TransactionEventRouter is a CSMSHandler, which dispatches every TransactionEventRequest
+// to a handler registered for its trigger reason, and optionally its event type.
+//
+// Handlers are looked up in the following order:
+//   - the handler registered for the event type and trigger reason of the request, see HandleEvent
+//   - the handler registered for the trigger reason of the request, regardless of the event type, see Handle
+//   - the fallback handler passed to NewTransactionEventRouter
+//
+// If no handler matches, a NotSupported error is returned to the charging station.
+//
+// The router is safe for concurrent use, hence handlers may be registered while the CSMS is running.
+// Pass it to CSMS.SetTransactionsHandler to enable it.
+type TransactionEventRouter struct {
+	mutex    sync.RWMutex
+	routes   map[transactionEventRoute]TransactionEventHandlerFunc
+	fallback CSMSHandler
+}
+
+// NewTransactionEventRouter creates a router without any registered handlers.
+// The fallback handler receives all events not matched by any other handler, and may be nil.
+func NewTransactionEventRouter(fallback CSMSHandler) *TransactionEventRouter {
+	return &TransactionEventRouter{
+		routes:   map[transactionEventRoute]TransactionEventHandlerFunc{},
+		fallback: fallback,
+	}
+}
+
+// Handle registers a handler for all events with the given trigger reason, regardless of their event type.
+// Passing a nil handler removes a previously registered one.
+func (r *TransactionEventRouter) Handle(reason TriggerReason, handler TransactionEventHandlerFunc) {
+	r.setRoute(transactionEventRoute{triggerReason: reason}, handler)
+}
+
+// HandleEvent registers a handler for all events with the given event type and trigger reason,
+// e.g. only for Ended events triggered by an EVCommunicationLost.
+// Takes precedence over a handler registered via Handle for the same trigger reason.
+// Passing a nil handler removes a previously registered one.
+func (r *TransactionEventRouter) HandleEvent(eventType TransactionEvent, reason TriggerReason, handler TransactionEventHandlerFunc) {
+	r.setRoute(transactionEventRoute{eventType: eventType, triggerReason: reason}, handler)
+}
+
+func (r *TransactionEventRouter) setRoute(route transactionEventRoute, handler TransactionEventHandlerFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if handler == nil {
+		delete(r.routes, route)
+		return
+	}
+	r.routes[route] = handler
+}
+
+func (r *TransactionEventRouter) lookup(request *TransactionEventRequest) TransactionEventHandlerFunc {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if handler, ok := r.routes[transactionEventRoute{eventType: request.EventType, triggerReason: request.TriggerReason}]; ok {
+		return handler
+	}
+	if handler, ok := r.routes[transactionEventRoute{triggerReason: request.TriggerReason}]; ok {
+		return handler
+	}
+	if r.fallback != nil {
+		return r.fallback.OnTransactionEvent
+	}
+	return nil
+}
+
+// OnTransactionEvent dispatches the request to the matching handler.
+func (r *TransactionEventRouter) OnTransactionEvent(chargingStationID string, request *TransactionEventRequest) (response *TransactionEventResponse, err error) {
+	handler := r.lookup(request)
+	if handler == nil {
+		return nil, ocpp.NewError(ocppj.NotSupported, fmt.Sprintf("no handler for %v event with trigger reason %v", request.EventType, request.TriggerReason), "")
+	}
+	return handler(chargingStationID, request)
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	// Register the hash functions supported by HashAlgorithmType
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Returns the hash function corresponding to the algorithm.
+func (h HashAlgorithmType) hash() (crypto.Hash, error) {
+	switch h {
+	case SHA256:
+		return crypto.SHA256, nil
+	case SHA384:
+		return crypto.SHA384, nil
+	case SHA512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported hash algorithm %v", h)
+	}
+}
+
+// NewCertificateHashData computes the hash data identifying a certificate, as used e.g. by DeleteCertificate and GetInstalledCertificateIds.
+//
+// The issuer name hash is computed over the DER encoded issuer name of the certificate,
+// while the issuer key hash is computed over the public key of the issuer certificate.
+// For self-signed certificates, the certificate itself must be passed as issuer.
+// Hashes are hex encoded in lower case. The serial number is hex encoded as well, without leading zeroes.
+func NewCertificateHashData(certificate *x509.Certificate, issuer *x509.Certificate, hashAlgorithm HashAlgorithmType) (*CertificateHashData, error) {
+	h, err := hashAlgorithm.hash()
+	if err != nil {
+		return nil, err
+	}
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err = asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, fmt.Errorf("invalid issuer public key: %w", err)
+	}
+	nameHash := h.New()
+	nameHash.Write(certificate.RawIssuer)
+	keyHash := h.New()
+	keyHash.Write(publicKeyInfo.PublicKey.RightAlign())
+	return &CertificateHashData{
+		HashAlgorithm:  hashAlgorithm,
+		IssuerNameHash: hex.EncodeToString(nameHash.Sum(nil)),
+		IssuerKeyHash:  hex.EncodeToString(keyHash.Sum(nil)),
+		SerialNumber:   certificate.SerialNumber.Text(16),
+	}, nil
+}
+
+// Equals returns true, if both hash data identify the same certificate.
+// Hex encoded values are compared case-insensitively, and leading zeroes of the serial number are ignored.
+func (c CertificateHashData) Equals(other CertificateHashData) bool {
+	normalizeSerial := func(serial string) string {
+		return strings.TrimLeft(strings.ToLower(serial), "0")
+	}
+	return c.HashAlgorithm == other.HashAlgorithm &&
+		strings.EqualFold(c.IssuerNameHash, other.IssuerNameHash) &&
+		strings.EqualFold(c.IssuerKeyHash, other.IssuerKeyHash) &&
+		normalizeSerial(c.SerialNumber) == normalizeSerial(other.SerialNumber)
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"fmt"
+	"math"
+)
+
+// Default values of optional SampledValue fields, as defined by the OCPP 2.0.1 specification.
+const (
+	DefaultReadingContext = ReadingContextSamplePeriodic
+	DefaultMeasurand      = MeasurandEnergyActiveImportRegister
+	DefaultLocation       = LocationOutlet
+	DefaultUnitOfMeasure  = "Wh"
+)
+
+// GetContext returns the reading context of the sampled value, or the default context if none was set.
+func (sv SampledValue) GetContext() ReadingContext {
+	if sv.Context == "" {
+		return DefaultReadingContext
+	}
+	return sv.Context
+}
+
+// GetMeasurand returns the measurand of the sampled value, or the default measurand if none was set.
+func (sv SampledValue) GetMeasurand() Measurand {
+	if sv.Measurand == "" {
+		return DefaultMeasurand
+	}
+	return sv.Measurand
+}
+
+// GetLocation returns the location of the sampled value, or the default location if none was set.
+func (sv SampledValue) GetLocation() Location {
+	if sv.Location == "" {
+		return DefaultLocation
+	}
+	return sv.Location
+}
+
+// GetUnit returns the unit of the sampled value, or the default unit if none was set.
+func (sv SampledValue) GetUnit() string {
+	if sv.UnitOfMeasure == nil || sv.UnitOfMeasure.Unit == "" {
+		return DefaultUnitOfMeasure
+	}
+	return sv.UnitOfMeasure.Unit
+}
+
+// ScaledValue returns the value of the sampled value, multiplied by 10 to the power of the multiplier of its unit of measure.
+func (sv SampledValue) ScaledValue() float64 {
+	if sv.UnitOfMeasure == nil || sv.UnitOfMeasure.Multiplier == nil {
+		return sv.Value
+	}
+	return sv.Value * math.Pow10(*sv.UnitOfMeasure.Multiplier)
+}
+
+// EnergyWh returns the value of an active energy reading in Wh, applying the multiplier and converting it from kWh if needed.
+//
+// An error is returned, if the measurand isn't an active energy register or interval, or if the unit isn't an energy unit.
+func (sv SampledValue) EnergyWh() (float64, error) {
+	measurand := sv.GetMeasurand()
+	switch measurand {
+	case MeasurandEnergyActiveImportRegister, MeasurandEnergyActiveExportRegister, MeasurandEnergyActiveImportInterval, MeasurandEnergyActiveExportInterval, MeasurandEnergyActiveNet:
+	default:
+		return 0, fmt.Errorf("cannot convert sampled value of measurand %v to energy", measurand)
+	}
+	switch unit := sv.GetUnit(); unit {
+	case "Wh":
+		return sv.ScaledValue(), nil
+	case "kWh":
+		return sv.ScaledValue() * 1000, nil
+	default:
+		return 0, fmt.Errorf("cannot convert sampled value of measurand %v with unit %q", measurand, unit)
+	}
+}
@@ -2,6 +2,10 @@
 package types
 
 import (
+	"net/url"
+	"unicode"
+	"unicode/utf8"
+
 	"gopkg.in/go-playground/validator.v9"
 
 	"github.com/lorenzodonini/ocpp-go/ocppj"
@@ -30,7 +34,7 @@ const (
 	AuthorizationStatusInvalid            AuthorizationStatus = "Invalid"
 	AuthorizationStatusConcurrentTx       AuthorizationStatus = "ConcurrentTx"
 	AuthorizationStatusNoCredit           AuthorizationStatus = "NoCredit"
-	AuthorizationStatusNotAllowedTypeEVSE AuthorizationStatus = "NotAllowedTypeEVS"
+	AuthorizationStatusNotAllowedTypeEVSE AuthorizationStatus = "NotAllowedTypeEVSE"
 	AuthorizationStatusNotAtThisLocation  AuthorizationStatus = "NotAtThisLocation"
 	AuthorizationStatusNotAtThisTime      AuthorizationStatus = "NotAtThisTime"
 	AuthorizationStatusUnknown            AuthorizationStatus = "Unknown"
@@ -261,6 +265,35 @@ type MessageContent struct {
 	Content  string            `json:"content" validate:"required,max=512"`
 }
 
+// NewMessageContent creates a MessageContent. The optional language may be set afterwards on the initialized struct.
+func NewMessageContent(format MessageFormatType, content string) *MessageContent {
+	return &MessageContent{Format: format, Content: content}
+}
+
+// FormatViolation returns the name of the format check (ascii, utf8 or uri), which the content doesn't pass.
+// Returns an empty string, if the content matches the declared format.
+//
+// The check isn't part of the generic validation of MessageContent, but is applied by the messages requiring it.
+func (m MessageContent) FormatViolation() string {
+	switch m.Format {
+	case MessageFormatASCII:
+		for i := 0; i < len(m.Content); i++ {
+			if m.Content[i] > unicode.MaxASCII {
+				return "ascii"
+			}
+		}
+	case MessageFormatUTF8, MessageFormatHTML:
+		if !utf8.ValidString(m.Content) {
+			return "utf8"
+		}
+	case MessageFormatURI:
+		if u, err := url.Parse(m.Content); err != nil || u.Scheme == "" {
+			return "uri"
+		}
+	}
+	return ""
+}
+
 type GroupIdToken struct {
 	IdToken string      `json:"idToken" validate:"max=36"`
 	Type    IdTokenType `json:"type" validate:"required,idTokenType"`
@@ -2,8 +2,11 @@
 package ocpp2
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
+	"net/url"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/internal/callbackqueue"
 	"github.com/lorenzodonini/ocpp-go/ocpp"
@@ -24,6 +27,8 @@ import (
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/tariffcost"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/batteryswap"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/der"
 	"github.com/lorenzodonini/ocpp-go/ocppj"
 	"github.com/lorenzodonini/ocpp-go/ws"
 )
@@ -32,11 +37,22 @@ type ChargingStationConnection interface {
 	ID() string
 	RemoteAddr() net.Addr
 	TLSConnectionState() *tls.ConnectionState
+	// Stores a custom value for the lifetime of the connection. Safe for concurrent use.
+	Set(key string, value interface{})
+	// Retrieves a custom value, previously stored via Set.
+	// Values are cleared automatically once the connection was closed.
+	Get(key string) (interface{}, bool)
+	// Removes a custom value, previously stored via Set.
+	Delete(key string)
+	// Returns the query parameters of the URL, on which the station connected (e.g. a firmware version or a session token).
+	QueryParams() url.Values
 }
 
 type (
 	ChargingStationValidationHandler ws.CheckClientHandler
 	ChargingStationConnectionHandler func(chargePoint ChargingStationConnection)
+	BinaryMessageHandler             func(chargingStationID string, data []byte)
+	ReportWarningHandler             func(chargingStationID string, requestID int, warnings []provisioning.CharacteristicsWarning)
 )
 
 // -------------------- v2.0 Charging Station --------------------
@@ -54,6 +70,7 @@ type (
 // Refer to the ChargingStationHandler interface of each profile for the implementation requirements.
 //
 // If a handler for a profile is not set, the OCPP library will reply to incoming messages for that profile with a NotImplemented error.
+// Handlers aren't synchronized with incoming messages, hence they must be registered before starting the charging station.
 //
 // A charging station can be started and stopped using the Start and Stop functions.
 // While running, messages can be sent to the CSMS by calling the Charging Station's functions, e.g.
@@ -67,6 +84,8 @@ type ChargingStation interface {
 	BootNotification(reason provisioning.BootReason, model string, chargePointVendor string, props ...func(request *provisioning.BootNotificationRequest)) (*provisioning.BootNotificationResponse, error)
 	// Requests explicit authorization to the CSMS, provided a valid IdToken (typically the customer's). The CSMS may either authorize or reject the token.
 	Authorize(idToken string, tokenType types.IdTokenType, props ...func(request *authorization.AuthorizeRequest)) (*authorization.AuthorizeResponse, error)
+	// Notifies the CSMS of batteries inserted into or taken out of a battery swap station. Requires the OCPP 2.1 battery swap profile.
+	BatterySwap(requestID int, eventType batteryswap.BatterySwapEvent, idToken types.IdToken, batteryData []batteryswap.BatteryData, props ...func(request *batteryswap.BatterySwapRequest)) (*batteryswap.BatterySwapResponse, error)
 	// Notifies the CSMS, that a previously set charging limit was cleared.
 	ClearedChargingLimit(chargingLimitSource types.ChargingLimitSourceType, props ...func(request *smartcharging.ClearedChargingLimitRequest)) (*smartcharging.ClearedChargingLimitResponse, error)
 	// Performs a custom data transfer to the CSMS. The message payload is not pre-defined and must be supported by the CSMS. Every vendor may implement their own proprietary logic for this message.
@@ -103,6 +122,8 @@ type ChargingStation interface {
 	PublishFirmwareStatusNotification(status firmware.PublishFirmwareStatus, props ...func(request *firmware.PublishFirmwareStatusNotificationRequest)) (*firmware.PublishFirmwareStatusNotificationResponse, error)
 	// Reports charging profiles installed in the Charging Station, as requested previously by the CSMS.
 	ReportChargingProfiles(requestID int, chargingLimitSource types.ChargingLimitSourceType, evseID int, chargingProfile []types.ChargingProfile, props ...func(request *smartcharging.ReportChargingProfilesRequest)) (*smartcharging.ReportChargingProfilesResponse, error)
+	// Reports the DER controls matching a previous GetDERControl request, identified by its requestId. Requires the OCPP 2.1 DER control profile.
+	ReportDERControl(requestID int, props ...func(request *der.ReportDERControlRequest)) (*der.ReportDERControlResponse, error)
 	// Notifies the CSMS about a reservation status having changed (i.e. the reservation has expired)
 	ReservationStatusUpdate(reservationID int, status reservation.ReservationUpdateStatus, props ...func(request *reservation.ReservationStatusUpdateRequest)) (*reservation.ReservationStatusUpdateResponse, error)
 	// Informs the CSMS about critical security events.
@@ -145,6 +166,20 @@ type ChargingStation interface {
 	SetDisplayHandler(handler display.ChargingStationHandler)
 	// Registers a handler for incoming data transfer messages
 	SetDataHandler(handler data.ChargingStationHandler)
+	// Registers a handler for incoming battery swap messages. Requires the OCPP 2.1 battery swap profile.
+	SetBatterySwapHandler(handler batteryswap.ChargingStationHandler)
+	// Registers a handler for incoming DER control messages. Requires the OCPP 2.1 DER control profile.
+	SetDERControlHandler(handler der.ChargingStationHandler)
+	// Enables or disables the automatic application of the interval returned by the CSMS in a BootNotificationResponse.
+	// Disabled by default.
+	//
+	// When enabled and the charging station was accepted, a Heartbeat is sent automatically at the returned interval.
+	// When the charging station is pending or rejected, the same BootNotificationRequest is re-sent automatically after the returned interval.
+	// Sending a new BootNotification or stopping the charging station cancels any scheduled heartbeat or retry.
+	SetAutoApplyBootInterval(enabled bool)
+	// Registers a handler, which is invoked with the outcome of every automatically re-sent BootNotificationRequest.
+	// See SetAutoApplyBootInterval.
+	SetBootNotificationRetryHandler(handler func(response *provisioning.BootNotificationResponse, err error))
 	// Sends a request to the CSMS.
 	// The CSMS will respond with a confirmation, or with an error if the request was invalid or could not be processed.
 	// In case of network issues (i.e. the remote host couldn't be reached), the function also returns an error.
@@ -156,7 +191,45 @@ type ChargingStation interface {
 	// This result is propagated via a callback, called asynchronously.
 	//
 	// In case of network issues (i.e. the remote host couldn't be reached), the function returns an error directly. In this case, the callback is never invoked.
+	//
+	// If the response of the CSMS couldn't be parsed or validated, the callback receives an *ocppj.InvalidResponseError,
+	// which exposes the raw payload. Otherwise, errors received from the CSMS are passed as *ocpp.Error.
 	SendRequestAsync(request ocpp.Request, callback func(confirmation ocpp.Response, protoError error)) error
+	// Sends a request to the CSMS and blocks until the response was received, or until the context is done.
+	// In the latter case, the context error is returned.
+	//
+	// Canceling the context only stops waiting for the response. The request itself is not withdrawn,
+	// and will still be completed or timed out by the charging station.
+	SendRequestSync(ctx context.Context, request ocpp.Request) (ocpp.Response, error)
+	// Sends a binary message to the CSMS, e.g. for OCPP 2.1 binary data transfers.
+	// The message is queued together with outgoing requests, and sent once all previously queued requests were completed.
+	// No response is awaited. Returns an error if the charging station wasn't started, or the queue is full.
+	SendBinary(data []byte) error
+	// Registers a handler for binary messages received from the CSMS. Binary messages aren't OCPP-J messages,
+	// hence they are passed to the handler as is. Binary messages received without a handler are reported via the Errors channel.
+	SetBinaryMessageHandler(handler func(data []byte))
+	// Registers an optional observer, which is notified about the lifecycle of every request
+	// sent to or received from the CSMS, e.g. for collecting metrics. See ocppj.RequestObserver.
+	SetRequestObserver(observer ocppj.RequestObserver)
+	// Enables recording every request sent to the CSMS, together with its raw response or cancellation,
+	// in the given audit log. Pass nil to disable recording. See ocppj.AuditLog.
+	SetAuditLog(auditLog *ocppj.AuditLog)
+	// Registers a handler, which is invoked whenever the connection to the CSMS was lost.
+	// The charging station keeps attempting to reconnect in the background, until it is stopped.
+	SetDisconnectedHandler(handler func(err error))
+	// Registers a handler, which is invoked once the charging station reconnected to the CSMS after a connection loss.
+	SetReconnectedHandler(handler func())
+	// Registers an optional filter, which is applied to every request queued while the charging station was disconnected,
+	// right before the queue is flushed on reconnect. Returning false drops a request,
+	// in which case its callback receives a GenericError. A returned request replaces the original one.
+	// See ocppj.QueueFlushFilter for more details. Pass nil to remove the filter.
+	SetQueueFlushFilter(filter ocppj.QueueFlushFilter)
+	// Restores the requests persisted by a previous process (see ocppj.PersistentQueue) into the queue,
+	// which must be the request queue of the charging station's dispatcher.
+	// The original callers of restored requests are gone, hence their responses or errors are passed to the handler instead.
+	//
+	// Requests must be restored before starting the charging station and before sending any other request.
+	RestoreRequests(queue *ocppj.PersistentQueue, handler func(request ocpp.Request, response ocpp.Response, err error)) error
 	// Connects to the CSMS and starts the charging station routine.
 	// The function doesn't block and returns right away, after having attempted to open a connection to the CSMS.
 	// If the connection couldn't be opened, an error is returned.
@@ -234,6 +307,11 @@ func NewChargingStation(id string, endpoint *ocppj.Client, client ws.WsClient) C
 		cs.responseHandler <- confirmation
 	})
 	cs.client.SetErrorHandler(func(err *ocpp.Error, details interface{}) {
+		if invalidResponse, ok := details.(*ocppj.InvalidResponseError); ok {
+			// Expose the raw payload of the invalid response
+			cs.errorHandler <- invalidResponse
+			return
+		}
 		cs.errorHandler <- err
 	})
 	cs.client.SetRequestHandler(cs.handleIncomingRequest)
@@ -256,6 +334,13 @@ func NewChargingStation(id string, endpoint *ocppj.Client, client ws.WsClient) C
 //
 // If a handler for a profile is not set, the OCPP library will reply to incoming messages for that profile with a NotImplemented error.
 //
+// Profile handlers may be replaced at any time, also while the CSMS is running, e.g. for rolling out new business logic.
+// Each incoming request is processed entirely by the handlers registered when it was received;
+// requests received after replacing a handler are passed to the new handler.
+// The same applies to optional features, e.g. SetTransactionTracking or SetBootOrderPolicy.
+// This guarantee is specific to the CSMS: the handlers of a ChargingStation, as well as those of the OCPP 1.6
+// central system and charge point, must be registered before starting the endpoint.
+//
 // A CSMS can be started by using the Start function.
 // To be notified of incoming (dis)connections from charging stations refer to the SetNewChargingStationHandler and SetChargingStationDisconnectedHandler functions.
 //
@@ -278,6 +363,8 @@ type CSMS interface {
 	ClearCache(clientId string, callback func(*authorization.ClearCacheResponse, error), props ...func(*authorization.ClearCacheRequest)) error
 	// Instructs a charging station to clear some or all charging profiles, previously sent to the charging station.
 	ClearChargingProfile(clientId string, callback func(*smartcharging.ClearChargingProfileResponse, error), props ...func(request *smartcharging.ClearChargingProfileRequest)) error
+	// Removes default or scheduled DER controls from a charging station. Requires the OCPP 2.1 DER control profile.
+	ClearDERControl(clientId string, callback func(*der.ClearDERControlResponse, error), isDefault bool, props ...func(request *der.ClearDERControlRequest)) error
 	// Removes a specific display message, currently configured in a charging station.
 	ClearDisplay(clientId string, callback func(*display.ClearDisplayResponse, error), id int, props ...func(*display.ClearDisplayRequest)) error
 	// Removes one or more monitoring settings from a charging station for the given variable IDs.
@@ -296,6 +383,8 @@ type CSMS interface {
 	GetChargingProfiles(clientId string, callback func(*smartcharging.GetChargingProfilesResponse, error), chargingProfile smartcharging.ChargingProfileCriterion, props ...func(*smartcharging.GetChargingProfilesRequest)) error
 	// Requests a charging station to report the composite charging schedule for the indicated duration and evseID.
 	GetCompositeSchedule(clientId string, callback func(*smartcharging.GetCompositeScheduleResponse, error), duration int, evseId int, props ...func(*smartcharging.GetCompositeScheduleRequest)) error
+	// Requests the DER controls configured on a charging station, which are reported asynchronously via ReportDERControl. Requires the OCPP 2.1 DER control profile.
+	GetDERControl(clientId string, callback func(*der.GetDERControlResponse, error), requestID int, props ...func(request *der.GetDERControlRequest)) error
 	// Retrieves all messages currently configured on a charging station.
 	GetDisplayMessages(clientId string, callback func(*display.GetDisplayMessagesResponse, error), requestId int, props ...func(*display.GetDisplayMessagesRequest)) error
 	// Retrieves all installed certificates on a charging station.
@@ -304,6 +393,11 @@ type CSMS interface {
 	GetLocalListVersion(clientId string, callback func(*localauth.GetLocalListVersionResponse, error), props ...func(*localauth.GetLocalListVersionRequest)) error
 	// Instructs a charging station to upload a diagnostics or security logfile to the CSMS.
 	GetLog(clientId string, callback func(*diagnostics.GetLogResponse, error), logType diagnostics.LogType, requestID int, logParameters diagnostics.LogParameters, props ...func(*diagnostics.GetLogRequest)) error
+	// Returns the GetLogRequest with the given requestID, previously accepted by a charging station, for which no final LogStatusNotification was received yet.
+	// It may be used for correlating incoming LogStatusNotification messages with the original request.
+	// The request is forgotten after the diagnostics handler processed a final upload status for it,
+	// or once the charging station disconnected.
+	PendingLogRequest(clientId string, requestID int) (*diagnostics.GetLogRequest, bool)
 	// Requests a report about configured monitoring settings per component and variable from a charging station. The reports will be uploaded asynchronously using NotifyMonitoringReport messages.
 	GetMonitoringReport(clientId string, callback func(*diagnostics.GetMonitoringReportResponse, error), props ...func(*diagnostics.GetMonitoringReportRequest)) error
 	// Requests a custom report about configured monitoring settings per criteria, component and variable from a charging station. The reports will be uploaded asynchronously using NotifyMonitoringReport messages.
@@ -314,8 +408,21 @@ type CSMS interface {
 	GetVariables(clientId string, callback func(*provisioning.GetVariablesResponse, error), variableData []provisioning.GetVariableData, props ...func(*provisioning.GetVariablesRequest)) error
 	// Installs a new CA certificate on a Charging station.
 	InstallCertificate(clientId string, callback func(*iso15118.InstallCertificateResponse, error), certificateType types.CertificateUse, certificate string, props ...func(*iso15118.InstallCertificateRequest)) error
+	// Migrates a set of charging stations to a new CSMS endpoint, e.g. for a blue/green deployment of the CSMS.
+	//
+	// Each charging station is sent the new network connection profile, to be stored in the given configuration slot.
+	// Once accepted, the network configuration priority may be updated and the station may be reset, causing it to reconnect
+	// to the new endpoint. These optional steps are configured via StationMigrationOptions.
+	// Stations are migrated asynchronously, with a bounded number of stations being migrated at the same time.
+	//
+	// The callback is invoked exactly once for each charging station, containing the outcome of its migration.
+	MigrateStations(stationIDs []string, configurationSlot int, newProfile provisioning.NetworkConnectionProfile, callback func(result StationMigrationResult), props ...func(options *StationMigrationOptions))
+	// Notifies a charging station of the energy transfer modes allowed for an ongoing transaction. Requires the OCPP 2.1 battery swap profile.
+	NotifyAllowedEnergyTransfer(clientId string, callback func(*batteryswap.NotifyAllowedEnergyTransferResponse, error), transactionID string, allowedEnergyTransfer []batteryswap.EnergyTransferMode, props ...func(request *batteryswap.NotifyAllowedEnergyTransferRequest)) error
 	// Publishes a firmware to a local controller, allowing charging stations to download the same firmware from the local controller directly.
 	PublishFirmware(clientId string, callback func(*firmware.PublishFirmwareResponse, error), location string, checksum string, requestID int, props ...func(request *firmware.PublishFirmwareRequest)) error
+	// Requests a charging station to start a battery swap for the given IdToken. Requires the OCPP 2.1 battery swap profile.
+	RequestBatterySwap(clientId string, callback func(*batteryswap.RequestBatterySwapResponse, error), requestID int, idToken types.IdToken, props ...func(request *batteryswap.RequestBatterySwapRequest)) error
 	// Remotely triggers a transaction to be started on a charging station.
 	RequestStartTransaction(clientId string, callback func(*remotecontrol.RequestStartTransactionResponse, error), remoteStartID int, IdToken types.IdToken, props ...func(request *remotecontrol.RequestStartTransactionRequest)) error
 	// Remotely triggers an ongoing transaction to be stopped on a charging station.
@@ -326,8 +433,16 @@ type CSMS interface {
 	Reset(clientId string, callback func(*provisioning.ResetResponse, error), t provisioning.ResetType, props ...func(request *provisioning.ResetRequest)) error
 	// Sends a local authorization list to a charging station, which can be used for the authorization of idTokens.
 	SendLocalList(clientId string, callback func(*localauth.SendLocalListResponse, error), version int, updateType localauth.UpdateType, props ...func(request *localauth.SendLocalListRequest)) error
+	// Sends a local authorization list to a charging station and, if the update was accepted, reads back the list version via GetLocalListVersion.
+	// This detects stations which accept an update without applying it. See LocalListVerificationResult.Verified.
+	//
+	// The callback is invoked once, after both requests completed or as soon as one of them failed.
+	SendLocalListVerified(clientId string, callback func(result LocalListVerificationResult, err error), version int, updateType localauth.UpdateType, entries []localauth.AuthorizationData, props ...func(request *localauth.SendLocalListRequest)) error
 	// Sends a charging profile to a charging station, to influence the power/current drawn by EVs.
 	SetChargingProfile(clientId string, callback func(*smartcharging.SetChargingProfileResponse, error), evseID int, chargingProfile *types.ChargingProfile, props ...func(request *smartcharging.SetChargingProfileRequest)) error
+	// Configures a DER control function on a charging station. The parameters matching the control type must be set via props.
+	// Requires the OCPP 2.1 DER control profile.
+	SetDERControl(clientId string, callback func(*der.SetDERControlResponse, error), isDefault bool, controlID string, controlType der.DERControlType, props ...func(request *der.SetDERControlRequest)) error
 	// Asks a charging station to configure a new display message, that should be displayed (in the future).
 	SetDisplayMessage(clientId string, callback func(*display.SetDisplayMessageResponse, error), message display.MessageInfo, props ...func(request *display.SetDisplayMessageRequest)) error
 	// Requests a charging station to activate a set of preconfigured monitoring settings, as denoted by the value of MonitoringBase.
@@ -340,8 +455,35 @@ type CSMS interface {
 	SetVariableMonitoring(clientId string, callback func(*diagnostics.SetVariableMonitoringResponse, error), data []diagnostics.SetMonitoringData, props ...func(request *diagnostics.SetVariableMonitoringRequest)) error
 	// Configures/changes the values of a set of variables on a charging station.
 	SetVariables(clientId string, callback func(*provisioning.SetVariablesResponse, error), data []provisioning.SetVariableData, props ...func(request *provisioning.SetVariablesRequest)) error
+	// Sets a group of variables on a charging station, which should either all be changed or not at all, e.g. coordinated network settings.
+	//
+	// The previous values are read first. If any of them can't be read, no variable is set. Otherwise, all variables are set
+	// and the accepted values are verified by reading them back. A variable requiring a reboot is considered set, without being verified.
+	// If any variable couldn't be set or verified, the previous values of all applied variables are restored.
+	//
+	// OCPP doesn't support transactional writes, hence atomicity is best-effort only: other writes may interleave,
+	// a charging station may apply some values before rejecting others, and the rollback itself may fail.
+	// Write-only variables can't be set via this function, as their previous value can't be read.
+	//
+	// The callback is invoked once, with the outcome of every variable. An error is passed if any of the requests failed.
+	SetVariablesAtomic(clientId string, callback func(result *AtomicSetVariablesResult, err error), data []provisioning.SetVariableData) error
 	// Requests a Charging Station to send a charging station-initiated message.
 	TriggerMessage(clientId string, callback func(*remotecontrol.TriggerMessageResponse, error), requestedMessage remotecontrol.MessageTrigger, props ...func(request *remotecontrol.TriggerMessageRequest)) error
+	// Requests all currently connected Charging Stations to send a charging station-initiated message.
+	// Requests are sent asynchronously, with a bounded number of requests being in-flight at the same time.
+	//
+	// The function returns the IDs of the charging stations the request is sent to. The callback is invoked exactly once for each of them,
+	// either with the station's response or with an error (e.g. if the station disconnected in the meantime).
+	TriggerMessageAll(callback func(clientId string, response *remotecontrol.TriggerMessageResponse, err error), requestedMessage remotecontrol.MessageTrigger, props ...func(request *remotecontrol.TriggerMessageRequest)) []string
+	// Sends a request to all currently connected Charging Stations, which match the filter. A nil filter matches all stations.
+	// The request for each station is created via the build function, right before it is sent.
+	//
+	// Requests are sent asynchronously, with the amount of in-flight requests and the sending rate being limited
+	// according to the passed options.
+	//
+	// The function returns the IDs of the charging stations the request is sent to. The onResult callback is invoked exactly once for each of them,
+	// either with the station's response or with an error (e.g. if the station disconnected in the meantime).
+	Broadcast(filter func(stationID string) bool, build func(stationID string) ocpp.Request, opts BroadcastOptions, onResult func(stationID string, response ocpp.Response, err error)) []string
 	// Instructs the Charging Station to unlock a connector, to help out an EV-driver.
 	UnlockConnector(clientId string, callback func(*remotecontrol.UnlockConnectorResponse, error), evseID int, connectorID int, props ...func(request *remotecontrol.UnlockConnectorRequest)) error
 	// Instructs a Local Controller to stops serving a firmware update to connected Charging Stations.
@@ -357,7 +499,8 @@ type CSMS interface {
 	SetAuthorizationHandler(handler authorization.CSMSHandler)
 	// Registers a handler for incoming local authorization list profile messages.
 	SetLocalAuthListHandler(handler localauth.CSMSHandler)
-	// Registers a handler for incoming transactions profile messages
+	// Registers a handler for incoming transactions profile messages.
+	// Use a transactions.TransactionEventRouter to route events to separate handlers, depending on their trigger reason.
 	SetTransactionsHandler(handler transactions.CSMSHandler)
 	// Registers a handler for incoming remote control profile messages
 	SetRemoteControlHandler(handler remotecontrol.CSMSHandler)
@@ -381,8 +524,142 @@ type CSMS interface {
 	SetDisplayHandler(handler display.CSMSHandler)
 	// Registers a handler for incoming data transfer messages
 	SetDataHandler(handler data.CSMSHandler)
+	// Registers a handler for incoming battery swap messages. Requires the OCPP 2.1 battery swap profile.
+	SetBatterySwapHandler(handler batteryswap.CSMSHandler)
+	// Registers a handler for incoming DER control messages. Requires the OCPP 2.1 DER control profile.
+	SetDERControlHandler(handler der.CSMSHandler)
+	// Returns the features which may currently be handled by the CSMS, grouped by profile name.
+	// A feature is reported if it is initiated by charging stations and a handler for its profile was set,
+	// e.g. after calling SetProvisioningHandler, the provisioning profile reports BootNotification and NotifyReport.
+	// Profiles without a registered handler are omitted. Feature names are sorted alphabetically.
+	RegisteredFeatures() map[string][]string
 	// Registers a handler for new incoming Charging station connections.
 	SetNewChargingStationValidationHandler(handler ws.CheckClientHandler)
+	// Enables or disables the tracking of active transactions, based on the TransactionEvent messages received from charging stations.
+	// Disabled by default. Disabling the tracking discards all tracked transactions.
+	//
+	// Events are applied after the transactions handler processed them successfully.
+	// Stale events (i.e. with a lower sequence number than the last applied one) and events for already ended transactions are ignored.
+	SetTransactionTracking(enabled bool)
+	// Enables or disables the ordered processing of TransactionEvent messages. Disabled by default.
+	//
+	// Incoming requests are usually processed in parallel, each in a dedicated goroutine.
+	// When enabled, the transactions handler is invoked for one event of a transaction at a time,
+	// in the order in which the events were received (e.g. an Ended event is never processed before a preceding Updated event).
+	// Events of different transactions, identified by charging station and transaction ID, are still processed in parallel.
+	SetOrderedTransactionEvents(enabled bool)
+	// Enables coalescing of StatusNotifications, for stations emitting bursts of notifications during state transitions.
+	//
+	// Every StatusNotification for a connector (re)starts a quiet period of the given duration. Notifications received
+	// for the same connector are coalesced, until no further notification arrives within the quiet period: the availability
+	// handler is then invoked once with the most recent notification only. If the handler implements StatusNotificationSequenceHandler,
+	// it receives the full sequence of coalesced notifications instead.
+	//
+	// Debounced notifications are acknowledged immediately, without waiting for the handler.
+	// Pending notifications are delivered right away, when the charging station disconnects or the CSMS is stopped.
+	// A zero duration disables debouncing (default).
+	SetStatusNotificationDebounce(d time.Duration)
+	// Sets how requests are treated, which a charging station sends before its BootNotification was accepted.
+	// Some firmware sends e.g. a Heartbeat or StatusNotification right after connecting, which violates the specification.
+	//
+	// The policy is enforced per charging station connection, until a BootNotification is accepted by the CSMS. The boot state
+	// is cleared when the charging station disconnects, and is reset by any BootNotification that isn't accepted (e.g. Pending or Rejected).
+	// By default, all requests are processed (BootOrderAllow). See BootOrderPolicy for the available policies.
+	SetBootOrderPolicy(policy BootOrderPolicy)
+	// Enables or disables the lenient parsing of charging station identity fields. Disabled by default.
+	//
+	// When enabled, identity fields of an incoming BootNotificationRequest (serialNumber, model, vendorName, firmwareVersion,
+	// modem.iccid and modem.imsi) exceeding their maximum length are truncated, instead of causing the whole request to be rejected.
+	// A message is logged by the ocppj package for every truncated field, which is also reported in ChargingStationType.TruncatedFields.
+	// The setting only applies to this CSMS and to requests parsed after the call.
+	SetLenientIdentityFields(enabled bool)
+	// Sets the maximum execution time of the handler for incoming requests of a feature, e.g. transactions.TransactionEventFeatureName.
+	//
+	// If the handler doesn't return within the timeout, an InternalError is sent to the charging station and reported via the Errors channel.
+	// The handler isn't interrupted, but its result is discarded once it completes. The timeout starts when the request is received.
+	// A duration of zero or less removes the timeout for the feature (default).
+	SetHandlerTimeout(featureName string, d time.Duration)
+	// Enables batched delivery of MeterValues, for high-frequency metering consumers.
+	//
+	// MeterValues are collected per charging station and passed to the handler once maxCount messages were received,
+	// or maxWait elapsed since the first message of the batch, whichever happens first. A maxCount <= 0 only limits batches by time,
+	// while a maxWait <= 0 only limits them by size. Pending batches are delivered early when a station disconnects or the CSMS is stopped.
+	//
+	// Batched messages are acknowledged immediately and the meter handler isn't invoked for them.
+	// The batch handler should return quickly, as it may be invoked while processing incoming messages.
+	// Passing a nil handler disables batching (default), after delivering all pending batches.
+	SetMeterValuesBatch(maxCount int, maxWait time.Duration, handler MeterValuesBatchHandler)
+	// Enables the suppression of duplicate requests sent to a charging station, e.g. a ChangeAvailability
+	// accidentally issued twice in quick succession. Disabled by default.
+	//
+	// Requests for the same station and feature with the same idempotency key, as returned by keyFunc, are considered duplicates
+	// while the first request was sent less than window ago, or is still pending. Only the first request is sent to the station:
+	// the callbacks of its duplicates are invoked with the result of the first request. A nil keyFunc defaults to PayloadRequestKey,
+	// i.e. requests with identical payloads are duplicates. A zero window disables deduplication.
+	SetRequestDeduplication(window time.Duration, keyFunc RequestKeyFunc)
+	// Registers a handler, which is invoked with the warnings for nonconformant variable characteristics
+	// contained in a NotifyReport message (e.g. minLimit greater than maxLimit). See provisioning.NotifyReportRequest.CheckCharacteristics.
+	//
+	// Nonconformant reports are still accepted and passed to the provisioning handler, after the warning handler returned.
+	// Passing nil disables the checks (default).
+	SetReportWarningHandler(handler ReportWarningHandler)
+	// Registers a handler, which is invoked whenever the provisioning handler accepted the BootNotification of a charging station.
+	// The handler receives the identity reported by the station, along with the heartbeat interval granted in the response.
+	// BootNotifications with status Pending or Rejected, or failing with an error, don't invoke the handler.
+	//
+	// The handler is invoked after the response was sent to the charging station. Passing nil disables the notifications (default).
+	SetStationBootedHandler(handler StationBootedHandler)
+	// Registers a handler, which may capture incoming requests before they are routed to the profile handlers.
+	// Captured requests aren't responded to automatically. Instead, a CALLRESULT or CALLERROR may be sent manually
+	// at any later time via the passed connection, e.g. for building proxies or test harnesses.
+	// See RequestCaptureHandler for more details. Passing nil disables capturing (default).
+	SetRequestCaptureHandler(handler RequestCaptureHandler)
+	// Registers a tariff engine, which computes the cost of a transaction whenever a TransactionEvent is received.
+	// Passing nil disables the automatic cost calculation (default).
+	//
+	// The engine is invoked after the transactions handler returned a response. The computed cost is set as totalCost
+	// of the response to the Ended event, while the personal message (if any) is set for every event.
+	// Values explicitly set by the transactions handler take precedence.
+	SetTariffEngine(engine TariffEngine)
+	// Returns a snapshot of the currently active transactions on a charging station, ordered by start time.
+	// Returns nil, if transaction tracking is disabled. See SetTransactionTracking.
+	ActiveTransactions(clientId string) []TransactionInfo
+	// Processes the charging needs of an EV asynchronously, and returns a NotifyEVChargingNeedsResponse with status Processing,
+	// which should be returned by the OnNotifyEVChargingNeeds handler.
+	//
+	// The function must be invoked by the OnNotifyEVChargingNeeds handler for the passed request.
+	// The scheduler is invoked in a separate goroutine, once the response was sent to the charging station.
+	// The resulting profile is sent via SetChargingProfile to the EVSE of the request.
+	// TxProfiles without a transaction ID are bound to the active transaction on the EVSE, if known (see SetTransactionTracking).
+	// The callback is invoked with the result of the SetChargingProfile request, or with the error returned by the scheduler.
+	// If the handler's response couldn't be sent, the scheduler isn't invoked and the callback receives an error.
+	ProcessChargingNeeds(chargingStationID string, request *smartcharging.NotifyEVChargingNeedsRequest, scheduler ChargingNeedsScheduler, callback func(*smartcharging.SetChargingProfileResponse, error)) *smartcharging.NotifyEVChargingNeedsResponse
+	// Enables or disables the tracking of network information per charging station. Disabled by default.
+	// Disabling the tracking discards all tracked information.
+	//
+	// The modem information (ICCID and IMSI) is recorded from every BootNotification, after the provisioning handler processed it successfully.
+	// The information of a charging station is retained after it disconnected.
+	SetNetworkDiagnosticsTracking(enabled bool)
+	// Updates the tracked network information of a charging station, e.g. with a signal strength reported via a vendor-specific
+	// DataTransfer or a device model variable. The update function is invoked synchronously and must not retain the passed struct.
+	//
+	// Returns an error, if network diagnostics tracking is disabled. See SetNetworkDiagnosticsTracking.
+	UpdateNetworkDiagnostics(clientId string, update func(diagnostics *NetworkDiagnostics)) error
+	// Returns a snapshot of the network information known about a charging station.
+	// Returns false, if no information is known or if network diagnostics tracking is disabled.
+	NetworkDiagnostics(clientId string) (NetworkDiagnostics, bool)
+	// Returns a snapshot of all connected charging stations, ordered by charging station ID.
+	//
+	// The connection metadata of all stations (e.g. message counts and labels) is captured at the same point in time.
+	// Transactions and network information are gathered while capturing the connection metadata,
+	// hence a station cannot disconnect in between. They are only included if the respective tracking is enabled.
+	// Messages rejected by the OCPP-J layer (e.g. invalid messages) aren't counted.
+	ConnectionsSnapshot() []ConnectionInfo
+	// Sets custom labels on a connected charging station, e.g. its site or operator, replacing any previous labels.
+	// Labels are included in ConnectionsSnapshot and discarded once the station disconnects.
+	//
+	// Returns an error, if the charging station isn't connected.
+	SetConnectionLabels(clientId string, labels map[string]string) error
 	// Registers a handler for new incoming Charging station connections.
 	SetNewChargingStationHandler(handler ChargingStationConnectionHandler)
 	// Registers a handler for Charging station disconnections.
@@ -392,11 +669,78 @@ type CSMS interface {
 	// This result is propagated via a callback, called asynchronously.
 	// In case of network issues (i.e. the remote host couldn't be reached), the function returns an error directly. In this case, the callback is never invoked.
 	SendRequestAsync(clientId string, request ocpp.Request, callback func(ocpp.Response, error)) error
+	// Sends an asynchronous request to a Charging Station like SendRequestAsync, resending the request according to the policy
+	// if it times out or fails with a retryable CALLERROR. Retries are delayed by the backoff of the policy.
+	// The callback is only invoked with the final result, i.e. the response, a non-retryable error,
+	// or the error of the last attempt. Errors while resending the request are also passed to the callback.
+	SendRequestWithRetry(clientId string, request ocpp.Request, policy RetryPolicy, callback func(ocpp.Response, error)) error
+	// Sends a binary message to a Charging Station, e.g. for OCPP 2.1 binary data transfers.
+	// The message is queued together with outgoing requests, and sent once all previously queued requests were completed.
+	// No response is awaited. Returns an error if the CSMS wasn't started, or the charging station isn't connected.
+	SendBinary(clientId string, data []byte) error
+	// Registers a handler for binary messages received from charging stations. Binary messages aren't OCPP-J messages,
+	// hence they are passed to the handler as is. Binary messages received without a handler are reported via the Errors channel.
+	SetBinaryMessageHandler(handler BinaryMessageHandler)
 	// Starts running the CSMS on the specified port and URL.
 	// The central system runs as a daemon and handles incoming charge point connections and messages.
 
 	// The function blocks forever, so it is suggested to wrap it in a goroutine, in case other functionality needs to be executed on the main program thread.
 	Start(listenPort int, listenPath string)
+	// Starts running the CSMS on the specified port and URL, until the passed context is done.
+	// Once the context is canceled, the CSMS is shut down gracefully, clearing all pending requests.
+	//
+	// The function blocks until the CSMS stopped. An error is returned if the server couldn't listen on the specified port,
+	// while nil is returned after a graceful shutdown.
+	StartWithContext(ctx context.Context, listenPort int, listenPath string) error
+	// Starts the CSMS on a pre-created listener, e.g. a Unix domain socket, instead of binding a TCP port.
+	//
+	// The function blocks until the CSMS stopped and returns nil after a graceful shutdown.
+	StartOnListener(listener net.Listener, listenPath string) error
+	// Sets the timeout for requests sent to a charging station, overriding the default timeout of the dispatcher.
+	// This avoids false timeouts on slow stations, e.g. by applying the OCPPCommCtrlr.MessageTimeout variable
+	// reported in the device model of the station (see provisioning.MessageTimeout).
+	//
+	// A zero timeout falls back to the default timeout. The setting is retained across reconnections of the station.
+	// Returns an error if the dispatcher doesn't support per-station timeouts.
+	SetStationTimeout(clientId string, timeout time.Duration) error
+	// Dispatches all requests queued for a charging station as fast as possible, ignoring any configured outbound pacing.
+	// Requests are still sent one at a time, as mandated by OCPP-J. Returns an error if no queue exists for the station.
+	FlushQueue(clientId string) error
+	// Discards all requests queued for a charging station, which weren't sent yet.
+	// The callback of each dropped request is invoked with an error. A request that was already sent is not affected.
+	DropQueue(clientId string) error
+	// Temporarily stops processing messages of a connected charging station, e.g. during a maintenance operation,
+	// without closing the connection. Incoming requests are buffered and no requests are sent to the station,
+	// while the websocket connection is kept alive. See ocppj.Server.PauseClient for more details.
+	PauseStation(clientId string) error
+	// Resumes a charging station paused via PauseStation. Buffered requests are passed to the handlers in the order
+	// in which they were received, then queued requests are sent to the station. Returns an error if the station isn't paused.
+	ResumeStation(clientId string) error
+	// Periodically sends a CostUpdated request for an ongoing transaction, so the charging station can display the running cost.
+	// At every interval, compute is invoked to obtain the total cost of the transaction so far.
+	//
+	// The updates stop when StopCostUpdates is invoked, a TransactionEvent ending the transaction is received,
+	// or the charging station disconnects. Starting updates for a transaction, which already receives updates, replaces the previous interval and function.
+	// Failed updates are reported via the Errors channel, without stopping the stream.
+	//
+	// Returns an error if the charging station isn't connected, or the interval isn't positive.
+	StartCostUpdates(clientId string, transactionId string, interval time.Duration, compute func() float64) error
+	// Stops the periodic cost updates started via StartCostUpdates. Returns false, if no updates were running for the transaction.
+	StopCostUpdates(clientId string, transactionId string) bool
+	// Returns the capabilities of a charging station, as learned from the NotifyReport requests it sent, e.g. after a GetBaseReport.
+	// Returns false, if no capabilities were reported by the charging station yet.
+	Capabilities(stationID string) (StationCapabilities, bool)
+	// Checks whether a charging profile may be installed on the given EVSE of a charging station,
+	// by validating it against the station's capabilities via smartcharging.ValidateProfileAgainstLimits.
+	// An EVSE ID of 0 refers to the charging station as a whole. If no capabilities are known for the charging station, nil is returned.
+	ValidateChargingProfile(stationID string, evseID int, profile *types.ChargingProfile) error
+	// Returns the charging profiles known to be installed on a charging station, ordered by EVSE, purpose, stack level and ID.
+	// The set is updated from accepted SetChargingProfile and ClearChargingProfile requests, as well as from ReportChargingProfiles requests.
+	// A GetChargingProfiles request without criteria synchronizes the set with the charging station, removing profiles which weren't reported.
+	InstalledProfiles(stationID string) []InstalledChargingProfile
+	// Registers an additional URL pattern, on which charging stations may connect, besides the listen path passed on start.
+	// Stations connected on any path share the same handlers and are notified via the new charging station handler.
+	AddListenPath(listenPath string)
 	// Stops the CSMS, clearing all pending requests.
 	Stop()
 	// Errors returns a channel for error messages. If it doesn't exist it es created.
@@ -425,17 +769,31 @@ func NewCSMS(endpoint *ocppj.Server, server ws.WsServer) CSMS {
 		endpoint = ocppj.NewServer(server, dispatcher, nil, authorization.Profile, availability.Profile, data.Profile, diagnostics.Profile, display.Profile, firmware.Profile, iso15118.Profile, localauth.Profile, meter.Profile, provisioning.Profile, remotecontrol.Profile, reservation.Profile, security.Profile, smartcharging.Profile, tariffcost.Profile, transactions.Profile)
 	}
 	cs := newCSMS(endpoint)
+	cs.server.SetNewClientHandler(func(client ws.Channel) {
+		cs.onChargingStationConnected(client)
+	})
+	cs.server.SetDisconnectedClientHandler(func(client ws.Channel) {
+		cs.onChargingStationDisconnected(client)
+	})
 	cs.server.SetRequestHandler(func(client ws.Channel, request ocpp.Request, requestId string, action string) {
+		cs.connections.messageReceived(client.ID())
 		cs.handleIncomingRequest(client, request, requestId, action)
 	})
 	cs.server.SetResponseHandler(func(client ws.Channel, response ocpp.Response, requestId string) {
+		cs.connections.messageReceived(client.ID())
 		cs.handleIncomingResponse(client, response, requestId)
 	})
 	cs.server.SetErrorHandler(func(client ws.Channel, err *ocpp.Error, details interface{}) {
+		cs.connections.messageReceived(client.ID())
 		cs.handleIncomingError(client, err, details)
 	})
 	cs.server.SetCanceledRequestHandler(func(clientID string, requestID string, request ocpp.Request, err *ocpp.Error) {
 		cs.handleCanceledRequest(clientID, request, err)
 	})
+	cs.server.SetBinaryMessageHandler(func(client ws.Channel, data []byte) {
+		cs.connections.messageReceived(client.ID())
+		cs.handleIncomingBinaryMessage(client, data)
+	})
+	cs.server.SetRequestNormalizer(provisioning.BootNotificationFeatureName, cs.normalizeBootNotification)
 	return &cs
 }
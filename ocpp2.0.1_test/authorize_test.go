@@ -1,14 +1,23 @@
 package ocpp2_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	ocpp2 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/authorization"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
 )
 
 // Test
@@ -34,7 +43,17 @@ func (suite *OcppV2TestSuite) TestAuthorizeConfirmationValidation() {
 	var confirmationTable = []GenericTestEntry{
 		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusAccepted, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusAccepted}}, true},
 		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusAccepted, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusAccepted}}, true},
+		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusSignatureError, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusInvalid}}, true},
+		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusCertificateExpired, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusInvalid}}, true},
+		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusCertificateRevoked, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusInvalid}}, true},
+		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusNoCertificateAvailable, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusInvalid}}, true},
+		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusCertChainError, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusInvalid}}, true},
+		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusContractCancelled, IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusInvalid}}, true},
 		{authorization.AuthorizeResponse{IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusAccepted}}, true},
+		{authorization.AuthorizeResponse{IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusConcurrentTx, GroupIdToken: &types.GroupIdToken{IdToken: "fleet1", Type: types.IdTokenTypeCentral}}}, true},
+		{authorization.AuthorizeResponse{IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusNotAllowedTypeEVSE}}, true},
+		{authorization.AuthorizeResponse{IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusAccepted, GroupIdToken: &types.GroupIdToken{IdToken: "fleet1", Type: "invalidType"}}}, false},
+		{authorization.AuthorizeResponse{IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusAccepted, ChargingPriority: 10}}, false},
 		{authorization.AuthorizeResponse{}, false},
 		{authorization.AuthorizeResponse{CertificateStatus: "invalidCertificateStatus", IdTokenInfo: types.IdTokenInfo{Status: types.AuthorizationStatusAccepted}}, false},
 		{authorization.AuthorizeResponse{CertificateStatus: authorization.CertificateStatusAccepted, IdTokenInfo: types.IdTokenInfo{Status: "invalidTokenInfoStatus"}}, false},
@@ -96,6 +115,153 @@ func (suite *OcppV2TestSuite) TestAuthorizeE2EMocked() {
 	assert.Equal(t, status, response.IdTokenInfo.Status)
 }
 
+func (suite *OcppV2TestSuite) TestAuthorizeContractCertificateExpiredE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	idToken := types.IdToken{IdToken: "DE8AAA000001", Type: types.IdTokenTypeEMAID}
+	leafHashData := types.OCSPRequestDataType{HashAlgorithm: types.SHA256, IssuerNameHash: "h0", IssuerKeyHash: "h0.1", SerialNumber: "s0", ResponderURL: "http://ocsp.mo.org"}
+	subCAHashData := types.OCSPRequestDataType{HashAlgorithm: types.SHA384, IssuerNameHash: "h1", IssuerKeyHash: "h1.1", SerialNumber: "s1", ResponderURL: "http://ocsp.v2g.org"}
+	status := types.AuthorizationStatusInvalid
+	certificateStatus := authorization.CertificateStatusCertificateExpired
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"idToken":{"idToken":"%v","type":"%v"},"iso15118CertificateHashData":[{"hashAlgorithm":"%v","issuerNameHash":"%v","issuerKeyHash":"%v","serialNumber":"%v","responderURL":"%v"},{"hashAlgorithm":"%v","issuerNameHash":"%v","issuerKeyHash":"%v","serialNumber":"%v","responderURL":"%v"}]}]`,
+		messageId, authorization.AuthorizeFeatureName, idToken.IdToken, idToken.Type,
+		leafHashData.HashAlgorithm, leafHashData.IssuerNameHash, leafHashData.IssuerKeyHash, leafHashData.SerialNumber, leafHashData.ResponderURL,
+		subCAHashData.HashAlgorithm, subCAHashData.IssuerNameHash, subCAHashData.IssuerKeyHash, subCAHashData.SerialNumber, subCAHashData.ResponderURL)
+	responseJson := fmt.Sprintf(`[3,"%v",{"certificateStatus":"%v","idTokenInfo":{"status":"%v"}}]`,
+		messageId, certificateStatus, status)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSAuthorizationHandler{}
+	// Contract certificate is verified by the CSMS, reporting the result via the certificate status
+	authorizeConfirmation := authorization.NewAuthorizationResponse(*types.NewIdTokenInfo(status))
+	authorizeConfirmation.CertificateStatus = certificateStatus
+	handler.On("OnAuthorize", mock.AnythingOfType("string"), mock.Anything).Return(authorizeConfirmation, nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*authorization.AuthorizeRequest)
+		assert.True(t, request.HasContractCertificate())
+		assert.Empty(t, request.Certificate)
+		assert.Equal(t, idToken.IdToken, request.IdToken.IdToken)
+		assert.Equal(t, idToken.Type, request.IdToken.Type)
+		require.Len(t, request.CertificateHashData, 2)
+		assert.Equal(t, leafHashData, request.CertificateHashData[0])
+		assert.Equal(t, subCAHashData, request.CertificateHashData[1])
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	response, err := suite.chargingStation.Authorize(idToken.IdToken, idToken.Type, func(request *authorization.AuthorizeRequest) {
+		request.CertificateHashData = []types.OCSPRequestDataType{leafHashData, subCAHashData}
+	})
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, certificateStatus, response.CertificateStatus)
+	assert.Equal(t, status, response.IdTokenInfo.Status)
+	// Requests without contract certificate information
+	assert.False(t, authorization.NewAuthorizationRequest(idToken.IdToken, idToken.Type).HasContractCertificate())
+}
+
+func (suite *OcppV2TestSuite) TestAuthorizeGroupIdTokenE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	idToken := types.IdToken{IdToken: "tok1", Type: types.IdTokenTypeISO14443}
+	cacheExpiry := types.NewDateTime(time.Now().Add(time.Hour))
+	idTokenInfo := types.IdTokenInfo{
+		Status:              types.AuthorizationStatusAccepted,
+		CacheExpiryDateTime: cacheExpiry,
+		ChargingPriority:    -3,
+		Language1:           "en",
+		Language2:           "de",
+		GroupIdToken:        &types.GroupIdToken{IdToken: "fleet1", Type: types.IdTokenTypeCentral},
+		PersonalMessage:     &types.MessageContent{Format: types.MessageFormatUTF8, Language: "en", Content: "welcome"},
+	}
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"idToken":{"idToken":"%v","type":"%v"}}]`,
+		messageId, authorization.AuthorizeFeatureName, idToken.IdToken, idToken.Type)
+	responseJson := fmt.Sprintf(`[3,"%v",{"idTokenInfo":{"status":"%v","cacheExpiryDateTime":"%v","chargingPriority":%v,"language1":"%v","language2":"%v","groupIdToken":{"idToken":"%v","type":"%v"},"personalMessage":{"format":"%v","language":"%v","content":"%v"}}}]`,
+		messageId, idTokenInfo.Status, cacheExpiry.FormatTimestamp(), idTokenInfo.ChargingPriority, idTokenInfo.Language1, idTokenInfo.Language2, idTokenInfo.GroupIdToken.IdToken, idTokenInfo.GroupIdToken.Type, idTokenInfo.PersonalMessage.Format, idTokenInfo.PersonalMessage.Language, idTokenInfo.PersonalMessage.Content)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSAuthorizationHandler{}
+	handler.On("OnAuthorize", mock.AnythingOfType("string"), mock.Anything).Return(authorization.NewAuthorizationResponse(idTokenInfo), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	response, err := suite.chargingStation.Authorize(idToken.IdToken, idToken.Type)
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	info := response.IdTokenInfo
+	assert.Equal(t, idTokenInfo.Status, info.Status)
+	assertDateTimeEquality(t, cacheExpiry, info.CacheExpiryDateTime)
+	assert.Equal(t, idTokenInfo.ChargingPriority, info.ChargingPriority)
+	assert.Equal(t, idTokenInfo.Language1, info.Language1)
+	assert.Equal(t, idTokenInfo.Language2, info.Language2)
+	require.NotNil(t, info.GroupIdToken)
+	assert.Equal(t, *idTokenInfo.GroupIdToken, *info.GroupIdToken)
+	require.NotNil(t, info.PersonalMessage)
+	assert.Equal(t, *idTokenInfo.PersonalMessage, *info.PersonalMessage)
+}
+
+// Accepts every token as part of the same group, enforcing the group limit.
+type groupLimitedAuthorizationHandler struct {
+	group   types.GroupIdToken
+	limiter *authorization.GroupLimiter
+}
+
+func (handler *groupLimitedAuthorizationHandler) OnAuthorize(chargingStationID string, request *authorization.AuthorizeRequest) (*authorization.AuthorizeResponse, error) {
+	info := types.IdTokenInfo{Status: types.AuthorizationStatusAccepted, GroupIdToken: &handler.group}
+	handler.limiter.Check(&info)
+	return authorization.NewAuthorizationResponse(info), nil
+}
+
+func (suite *OcppV2TestSuite) TestAuthorizeGroupConcurrencyLimit() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	group := types.GroupIdToken{IdToken: "fleet1", Type: types.IdTokenTypeCentral}
+	limiter := authorization.NewGroupLimiter(0)
+	limiter.SetLimit(group, 1)
+	// One transaction of the group is already ongoing on another station
+	ongoing := types.IdTokenInfo{Status: types.AuthorizationStatusAccepted, GroupIdToken: &group}
+	require.True(t, limiter.Start("other_station", "tx1", &ongoing))
+	assert.Equal(t, 1, limiter.Active(group))
+	channel := NewMockWebSocket(wsId)
+
+	handler := &groupLimitedAuthorizationHandler{group: group, limiter: limiter}
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	suite.csms.SetAuthorizationHandler(handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Group reached its limit
+	response, err := suite.chargingStation.Authorize("tok2", types.IdTokenTypeISO14443)
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, types.AuthorizationStatusConcurrentTx, response.IdTokenInfo.Status)
+	require.NotNil(t, response.IdTokenInfo.GroupIdToken)
+	assert.Equal(t, group, *response.IdTokenInfo.GroupIdToken)
+	// Starting another transaction of the group is rejected as well
+	rejected := types.IdTokenInfo{Status: types.AuthorizationStatusAccepted, GroupIdToken: &group}
+	assert.False(t, limiter.Start(wsId, "tx2", &rejected))
+	assert.Equal(t, types.AuthorizationStatusConcurrentTx, rejected.Status)
+	// Once the ongoing transaction ended, the group is accepted again
+	limiter.End("other_station", "tx1")
+	assert.Equal(t, 0, limiter.Active(group))
+	response, err = suite.chargingStation.Authorize("tok2", types.IdTokenTypeISO14443)
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, types.AuthorizationStatusAccepted, response.IdTokenInfo.Status)
+}
+
 func (suite *OcppV2TestSuite) TestAuthorizeInvalidEndpoint() {
 	messageId := defaultMessageId
 	certificate := "deadc0de"
@@ -107,3 +273,180 @@ func (suite *OcppV2TestSuite) TestAuthorizeInvalidEndpoint() {
 		messageId, authorization.AuthorizeFeatureName, certificate, idToken.IdToken, idToken.Type, additionalInfo.AdditionalIdToken, additionalInfo.Type, certHashData.HashAlgorithm, certHashData.IssuerNameHash, certHashData.IssuerKeyHash, certHashData.SerialNumber, certHashData.ResponderURL)
 	testUnsupportedRequestFromCentralSystem(suite, authorizeRequest, requestJson, messageId)
 }
+
+type fakeAuthorizationStore struct {
+	mutex   sync.Mutex
+	calls   []string
+	blocked map[string]bool
+	err     error
+}
+
+func (s *fakeAuthorizationStore) record(call string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, call)
+	return s.err
+}
+
+func (s *fakeAuthorizationStore) takeCalls() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+func (s *fakeAuthorizationStore) Authorize(ctx context.Context, chargingStationID string, idToken types.IdToken) (types.IdTokenInfo, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return types.IdTokenInfo{}, errors.New("missing deadline")
+	}
+	err := s.record(fmt.Sprintf("Authorize %v %v", chargingStationID, idToken.IdToken))
+	if s.blocked[idToken.IdToken] {
+		return *types.NewIdTokenInfo(types.AuthorizationStatusBlocked), err
+	}
+	return *types.NewIdTokenInfo(types.AuthorizationStatusAccepted), err
+}
+
+func (s *fakeAuthorizationStore) BeginTransaction(ctx context.Context, chargingStationID string, event *transactions.TransactionEventRequest) error {
+	return s.record(fmt.Sprintf("BeginTransaction %v %v", chargingStationID, event.TransactionInfo.TransactionID))
+}
+
+func (s *fakeAuthorizationStore) EndTransaction(ctx context.Context, chargingStationID string, event *transactions.TransactionEventRequest) error {
+	return s.record(fmt.Sprintf("EndTransaction %v %v", chargingStationID, event.TransactionInfo.TransactionID))
+}
+
+func (suite *OcppV2TestSuite) TestAuthorizationStore() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	transactionID := "42"
+	channel := NewMockWebSocket(wsId)
+	store := &fakeAuthorizationStore{blocked: map[string]bool{"blocked": true}}
+	handler := ocpp2.NewAuthorizationStoreHandler(store, time.Second)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetAuthorizationHandler(handler)
+	suite.csms.SetTransactionsHandler(handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Authorize
+	authorizeResponse, err := suite.chargingStation.Authorize("1234", types.IdTokenTypeISO14443)
+	require.Nil(t, err)
+	assert.Equal(t, types.AuthorizationStatusAccepted, authorizeResponse.IdTokenInfo.Status)
+	authorizeResponse, err = suite.chargingStation.Authorize("blocked", types.IdTokenTypeISO14443)
+	require.Nil(t, err)
+	assert.Equal(t, types.AuthorizationStatusBlocked, authorizeResponse.IdTokenInfo.Status)
+	assert.Equal(t, []string{"Authorize test_id 1234", "Authorize test_id blocked"}, store.takeCalls())
+	// Transaction lifecycle
+	info := transactions.Transaction{TransactionID: transactionID}
+	idToken := types.IdToken{IdToken: "1234", Type: types.IdTokenTypeISO14443}
+	timestamp := types.NewDateTime(time.Now())
+	eventResponse, err := suite.chargingStation.TransactionEvent(transactions.TransactionEventStarted, timestamp, transactions.TriggerReasonAuthorized, 0, info, func(request *transactions.TransactionEventRequest) {
+		request.IDToken = &idToken
+	})
+	require.Nil(t, err)
+	require.NotNil(t, eventResponse.IDTokenInfo)
+	assert.Equal(t, types.AuthorizationStatusAccepted, eventResponse.IDTokenInfo.Status)
+	eventResponse, err = suite.chargingStation.TransactionEvent(transactions.TransactionEventUpdated, timestamp, transactions.TriggerReasonMeterValuePeriodic, 1, info)
+	require.Nil(t, err)
+	assert.Nil(t, eventResponse.IDTokenInfo)
+	_, err = suite.chargingStation.TransactionEvent(transactions.TransactionEventEnded, timestamp, transactions.TriggerReasonEVDeparted, 2, info)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Authorize test_id 1234", "BeginTransaction test_id 42", "EndTransaction test_id 42"}, store.takeCalls())
+	// Store errors are reported to the charging station
+	store.err = errors.New("database unavailable")
+	_, err = suite.chargingStation.Authorize("1234", types.IdTokenTypeISO14443)
+	require.Error(t, err)
+	_, err = suite.chargingStation.TransactionEvent(transactions.TransactionEventStarted, timestamp, transactions.TriggerReasonCablePluggedIn, 0, transactions.Transaction{TransactionID: "43"})
+	require.Error(t, err)
+	assert.Equal(t, []string{"Authorize test_id 1234", "BeginTransaction test_id 43"}, store.takeCalls())
+}
+
+// An authorization handler, which returns the same status for every idToken and counts its invocations.
+type staticAuthorizationHandler struct {
+	status types.AuthorizationStatus
+	calls  int64
+}
+
+func (h *staticAuthorizationHandler) OnAuthorize(chargingStationID string, request *authorization.AuthorizeRequest) (*authorization.AuthorizeResponse, error) {
+	atomic.AddInt64(&h.calls, 1)
+	return authorization.NewAuthorizationResponse(*types.NewIdTokenInfo(h.status)), nil
+}
+
+func (suite *OcppV2TestSuite) TestAuthorizeHandlerHotSwap() {
+	t := suite.T()
+	stationCount := 4
+	requestsPerStation := 100
+	writtenC := make(chan []byte, stationCount*requestsPerStation+1)
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writtenC <- args.Get(1).([]byte)
+	})
+	legacyHandler := &staticAuthorizationHandler{status: types.AuthorizationStatusAccepted}
+	newHandler := &staticAuthorizationHandler{status: types.AuthorizationStatusBlocked}
+	suite.csms.SetAuthorizationHandler(legacyHandler)
+	suite.csms.Start(8887, "somePath")
+	// Swap handlers continuously, while all stations send requests concurrently
+	stopSwapping := make(chan struct{})
+	swapperDone := make(chan struct{})
+	go func() {
+		defer close(swapperDone)
+		handlers := []authorization.CSMSHandler{newHandler, nil, legacyHandler}
+		for i := 0; ; i++ {
+			select {
+			case <-stopSwapping:
+				return
+			default:
+				suite.csms.SetAuthorizationHandler(handlers[i%len(handlers)])
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < stationCount; i++ {
+		station := NewMockWebSocket(fmt.Sprintf("station%d", i))
+		suite.mockWsServer.NewClientHandler(station)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requestsPerStation; j++ {
+				requestJson := fmt.Sprintf(`[2,"%v-%d","%v",{"idToken":{"idToken":"1234","type":"%v"}}]`, station.ID(), j, authorization.AuthorizeFeatureName, types.IdTokenTypeISO14443)
+				assert.NoError(t, suite.mockWsServer.MessageHandler(station, []byte(requestJson)))
+			}
+		}()
+	}
+	wg.Wait()
+	close(stopSwapping)
+	<-swapperDone
+	// Every request was answered consistently by one of the registered handlers, or rejected while no handler was set
+	statusCount := map[string]int64{}
+	for i := 0; i < stationCount*requestsPerStation; i++ {
+		var message []interface{}
+		select {
+		case data := <-writtenC:
+			require.NoError(t, json.Unmarshal(data, &message))
+		case <-time.After(time.Second):
+			t.Fatalf("only %d responses received", i)
+		}
+		switch message[0].(float64) {
+		case 3:
+			payload := message[2].(map[string]interface{})
+			status := payload["idTokenInfo"].(map[string]interface{})["status"].(string)
+			statusCount[status]++
+		case 4:
+			assert.Equal(t, string(ocppj.NotSupported), message[2])
+			statusCount[string(ocppj.NotSupported)]++
+		default:
+			t.Fatalf("unexpected message %v", message)
+		}
+	}
+	assert.Equal(t, atomic.LoadInt64(&legacyHandler.calls), statusCount[string(types.AuthorizationStatusAccepted)])
+	assert.Equal(t, atomic.LoadInt64(&newHandler.calls), statusCount[string(types.AuthorizationStatusBlocked)])
+	// After the rollout, only the new handler is used
+	suite.csms.SetAuthorizationHandler(newHandler)
+	station := NewMockWebSocket("station0")
+	err := suite.mockWsServer.MessageHandler(station, []byte(fmt.Sprintf(`[2,"final","%v",{"idToken":{"idToken":"1234","type":"%v"}}]`, authorization.AuthorizeFeatureName, types.IdTokenTypeISO14443)))
+	require.NoError(t, err)
+	assert.Contains(t, string(<-writtenC), string(types.AuthorizationStatusBlocked))
+}
@@ -0,0 +1,63 @@
+package ocpp2_test
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *OcppV2TestSuite) TestBinaryMessageExchange() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	stationData := []byte{0x00, 0x01, 0xfe, 0xff}
+	csmsData := []byte{0xca, 0xfe}
+	channel := NewMockWebSocket(wsId)
+	mockWsServer := suite.mockWsServer
+	mockWsClient := suite.mockWsClient
+	// Binary frames are forwarded to the other endpoint as is
+	mockWsServer.On("WriteBinary", wsId, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		err := mockWsClient.BinaryMessageHandler(args.Get(1).([]byte))
+		assert.NoError(t, err)
+	})
+	mockWsClient.On("WriteBinary", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		err := mockWsServer.BinaryMessageHandler(channel, args.Get(0).([]byte))
+		assert.NoError(t, err)
+	})
+	csmsC := make(chan []byte, 1)
+	suite.csms.SetBinaryMessageHandler(func(chargingStationID string, data []byte) {
+		assert.Equal(t, wsId, chargingStationID)
+		csmsC <- data
+	})
+	stationC := make(chan []byte, 1)
+	suite.chargingStation.SetBinaryMessageHandler(func(data []byte) {
+		stationC <- data
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	err = suite.chargingStation.SendBinary(stationData)
+	require.NoError(t, err)
+	select {
+	case data := <-csmsC:
+		assert.Equal(t, stationData, data)
+	case <-time.After(time.Second):
+		require.Fail(t, "binary message didn't reach the CSMS")
+	}
+	err = suite.csms.SendBinary(wsId, csmsData)
+	require.NoError(t, err)
+	select {
+	case data := <-stationC:
+		assert.Equal(t, csmsData, data)
+	case <-time.After(time.Second):
+		require.Fail(t, "binary message didn't reach the charging station")
+	}
+	// Binary messages for unknown charging stations are rejected
+	err = suite.csms.SendBinary("unknownId", csmsData)
+	assert.Error(t, err)
+}
@@ -8,8 +8,13 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ws"
 )
 
 // Tests
@@ -87,6 +92,317 @@ func (suite *OcppV2TestSuite) TestBootNotificationE2EMocked() {
 	assertDateTimeEquality(t, currentTime, confirmation.CurrentTime)
 }
 
+func (suite *OcppV2TestSuite) TestBootNotificationAutoApplyHeartbeatInterval() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	interval := 1
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+
+	responseC := make(chan time.Time, 10)
+	provisioningHandler := &MockCSMSProvisioningHandler{}
+	provisioningHandler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, interval, provisioning.RegistrationStatusAccepted), nil)
+	availabilityHandler := &MockCSMSAvailabilityHandler{}
+	availabilityHandler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*currentTime), nil)
+	// Responses are reported once they were delivered to the charging station
+	client := suite.mockWsClient
+	suite.mockWsServer.On("Write", wsId, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		err := client.MessageHandler(args.Get(1).([]byte))
+		assert.Nil(t, err)
+		responseC <- time.Now()
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId}, provisioningHandler, availabilityHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.mockWsClient.On("Stop").Return()
+	suite.mockWsClient.On("IsConnected").Return(false)
+	// Run test
+	suite.chargingStation.SetAutoApplyBootInterval(true)
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	start := time.Now()
+	confirmation, err := suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "ABL")
+	require.Nil(t, err)
+	require.NotNil(t, confirmation)
+	<-responseC
+	// A heartbeat is sent after the interval returned by the CSMS
+	select {
+	case sent := <-responseC:
+		assert.GreaterOrEqual(t, int64(sent.Sub(start)), int64(time.Duration(interval)*time.Second))
+	case <-time.After(time.Duration(interval)*time.Second + time.Second):
+		require.FailNow(t, "no heartbeat was sent")
+	}
+	// Stopping the charging station cancels the next scheduled heartbeat
+	suite.chargingStation.Stop()
+	select {
+	case <-responseC:
+		assert.Fail(t, "unexpected heartbeat after stopping")
+	case <-time.After(time.Duration(interval)*time.Second + 500*time.Millisecond):
+	}
+	availabilityHandler.AssertNumberOfCalls(t, "OnHeartbeat", 1)
+}
+
+func (suite *OcppV2TestSuite) TestBootNotificationAutoApplyRetryInterval() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	interval := 1
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, interval, provisioning.RegistrationStatusPending), nil).Once()
+	handler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, interval, provisioning.RegistrationStatusRejected), nil).Once()
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.mockWsClient.On("Stop").Return()
+	suite.mockWsClient.On("IsConnected").Return(false)
+	type retryResult struct {
+		response *provisioning.BootNotificationResponse
+		err      error
+		sent     time.Time
+	}
+	retryC := make(chan retryResult, 10)
+	suite.chargingStation.SetAutoApplyBootInterval(true)
+	suite.chargingStation.SetBootNotificationRetryHandler(func(response *provisioning.BootNotificationResponse, err error) {
+		retryC <- retryResult{response: response, err: err, sent: time.Now()}
+	})
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	start := time.Now()
+	confirmation, err := suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "ABL")
+	require.Nil(t, err)
+	require.NotNil(t, confirmation)
+	assert.Equal(t, provisioning.RegistrationStatusPending, confirmation.Status)
+	// The request is automatically re-sent after the interval returned by the CSMS
+	select {
+	case result := <-retryC:
+		require.Nil(t, result.err)
+		require.NotNil(t, result.response)
+		assert.Equal(t, provisioning.RegistrationStatusRejected, result.response.Status)
+		assert.GreaterOrEqual(t, int64(result.sent.Sub(start)), int64(time.Duration(interval)*time.Second))
+	case <-time.After(time.Duration(interval)*time.Second + time.Second):
+		require.FailNow(t, "the boot notification wasn't re-sent")
+	}
+	handler.AssertNumberOfCalls(t, "OnBootNotification", 2)
+	// The next retry is skipped once the charging station was stopped
+	suite.chargingStation.Stop()
+	select {
+	case <-retryC:
+		assert.Fail(t, "unexpected retry after stopping")
+	case <-time.After(time.Duration(interval)*time.Second + 500*time.Millisecond):
+	}
+	handler.AssertNumberOfCalls(t, "OnBootNotification", 2)
+}
+
+func (suite *OcppV2TestSuite) TestBootNotificationLenientIdentityFields() {
+	t := suite.T()
+	serialNumber := "SN-0123456789-0123456789-0123456789"
+	requestJson := fmt.Sprintf(`[2,"1234","%v",{"reason":"%v","chargingStation":{"serialNumber":"%v","model":"model1","vendorName":"vendor1","modem":{"iccid":"89014103211118510720123","imsi":"310150123456789"}}}]`,
+		provisioning.BootNotificationFeatureName, provisioning.BootReasonPowerUp, serialNumber)
+	arr, err := ocppj.ParseJsonMessage(requestJson)
+	require.NoError(t, err)
+	// Strict mode rejects the request
+	_, err = suite.ocppjServer.ParseMessage(arr, nil)
+	require.Error(t, err)
+	protoErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.PropertyConstraintViolation, protoErr.Code)
+	// Lenient mode truncates the fields
+	suite.csms.SetLenientIdentityFields(true)
+	message, err := suite.ocppjServer.ParseMessage(arr, nil)
+	require.NoError(t, err)
+	call, ok := message.(*ocppj.Call)
+	require.True(t, ok)
+	request, ok := call.Payload.(*provisioning.BootNotificationRequest)
+	require.True(t, ok)
+	assert.Equal(t, serialNumber[:25], request.ChargingStation.SerialNumber)
+	assert.Equal(t, "model1", request.ChargingStation.Model)
+	require.NotNil(t, request.ChargingStation.Modem)
+	assert.Equal(t, "89014103211118510720", request.ChargingStation.Modem.Iccid)
+	assert.Equal(t, "310150123456789", request.ChargingStation.Modem.Imsi)
+	assert.Equal(t, []string{"serialNumber", "modem.iccid"}, request.ChargingStation.TruncatedFields)
+	// Disabling the setting rejects the request again
+	suite.csms.SetLenientIdentityFields(false)
+	_, err = suite.ocppjServer.ParseMessage(arr, nil)
+	require.Error(t, err)
+	// The setting doesn't affect other endpoints
+	otherServer := ocppj.NewServer(ws.NewServer(), nil, nil, provisioning.Profile)
+	otherCSMS := ocpp2.NewCSMS(otherServer, nil)
+	suite.csms.SetLenientIdentityFields(true)
+	_, err = otherServer.ParseMessage(arr, nil)
+	require.Error(t, err)
+	otherCSMS.SetLenientIdentityFields(true)
+	_, err = otherServer.ParseMessage(arr, nil)
+	require.NoError(t, err)
+}
+
+func (suite *OcppV2TestSuite) TestBootNotificationModemNetworkDiagnostics() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	reason := provisioning.BootReasonPowerUp
+	model := "model1"
+	vendor := "ABL"
+	iccid := "89440000000000000001"
+	imsi := "262010000000001"
+	currentTime := types.NewDateTime(time.Now())
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"reason":"%v","chargingStation":{"model":"%v","vendorName":"%v","modem":{"iccid":"%v","imsi":"%v"}}}]`, messageId, provisioning.BootNotificationFeatureName, reason, model, vendor, iccid, imsi)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, 60, provisioning.RegistrationStatusAccepted), nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*provisioning.BootNotificationRequest)
+		require.NotNil(t, request.ChargingStation.Modem)
+		assert.Equal(t, iccid, request.ChargingStation.Modem.Iccid)
+		assert.Equal(t, imsi, request.ChargingStation.Modem.Imsi)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Tracking is disabled by default
+	_, ok := suite.csms.NetworkDiagnostics(wsId)
+	assert.False(t, ok)
+	assert.Error(t, suite.csms.UpdateNetworkDiagnostics(wsId, func(diagnostics *ocpp2.NetworkDiagnostics) {}))
+	suite.csms.SetNetworkDiagnosticsTracking(true)
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	_, err = suite.chargingStation.BootNotification(reason, model, vendor, func(request *provisioning.BootNotificationRequest) {
+		request.ChargingStation.Modem = &provisioning.ModemType{Iccid: iccid, Imsi: imsi}
+	})
+	require.Nil(t, err)
+	diagnostics, ok := suite.csms.NetworkDiagnostics(wsId)
+	require.True(t, ok)
+	require.NotNil(t, diagnostics.Modem)
+	assert.Equal(t, iccid, diagnostics.Modem.Iccid)
+	assert.Equal(t, imsi, diagnostics.Modem.Imsi)
+	assert.False(t, diagnostics.BootedAt.IsZero())
+	assert.Nil(t, diagnostics.SignalStrength)
+	// Signal strength reported by other means
+	err = suite.csms.UpdateNetworkDiagnostics(wsId, func(diagnostics *ocpp2.NetworkDiagnostics) {
+		signalStrength := -71
+		diagnostics.SignalStrength = &signalStrength
+		diagnostics.Attributes = map[string]string{"operator": "someOperator"}
+	})
+	require.NoError(t, err)
+	diagnostics, ok = suite.csms.NetworkDiagnostics(wsId)
+	require.True(t, ok)
+	require.NotNil(t, diagnostics.SignalStrength)
+	assert.Equal(t, -71, *diagnostics.SignalStrength)
+	assert.Equal(t, "someOperator", diagnostics.Attributes["operator"])
+	assert.Equal(t, iccid, diagnostics.Modem.Iccid)
+	// Snapshots are not affected by later changes
+	diagnostics.Modem.Iccid = "modified"
+	diagnostics.Attributes["operator"] = "modified"
+	snapshot, _ := suite.csms.NetworkDiagnostics(wsId)
+	assert.Equal(t, iccid, snapshot.Modem.Iccid)
+	assert.Equal(t, "someOperator", snapshot.Attributes["operator"])
+	// Disabling the tracking discards the information
+	suite.csms.SetNetworkDiagnosticsTracking(false)
+	_, ok = suite.csms.NetworkDiagnostics(wsId)
+	assert.False(t, ok)
+}
+
+func (suite *OcppV2TestSuite) TestBootNotificationStationBootedHandler() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	interval := 300
+	reason := provisioning.BootReasonPowerUp
+	model := "model1"
+	vendor := "ABL"
+	serialNumber := "serial1"
+	firmwareVersion := "1.2.3"
+	modem := provisioning.ModemType{Iccid: "8944500102198304826", Imsi: "234150999999999"}
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSProvisioningHandler{}
+	// The first boot is pending, the second one is accepted
+	handler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, 10, provisioning.RegistrationStatusPending), nil).Once()
+	handler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, interval, provisioning.RegistrationStatusAccepted), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	bootedC := make(chan ocpp2.BootInfo, 2)
+	suite.csms.SetStationBootedHandler(func(stationID string, info ocpp2.BootInfo) {
+		assert.Equal(t, wsId, stationID)
+		bootedC <- info
+	})
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	boot := func() {
+		_, err := suite.chargingStation.BootNotification(reason, model, vendor, func(request *provisioning.BootNotificationRequest) {
+			request.ChargingStation.SerialNumber = serialNumber
+			request.ChargingStation.FirmwareVersion = firmwareVersion
+			request.ChargingStation.Modem = &modem
+		})
+		require.Nil(t, err)
+	}
+	boot()
+	select {
+	case <-bootedC:
+		t.Fatal("handler shouldn't be invoked for a pending boot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	boot()
+	select {
+	case info := <-bootedC:
+		assert.Equal(t, vendor, info.VendorName)
+		assert.Equal(t, model, info.Model)
+		assert.Equal(t, serialNumber, info.SerialNumber)
+		assert.Equal(t, firmwareVersion, info.FirmwareVersion)
+		require.NotNil(t, info.Modem)
+		assert.Equal(t, modem, *info.Modem)
+		assert.Equal(t, reason, info.Reason)
+		assert.Equal(t, time.Duration(interval)*time.Second, info.Interval)
+	case <-time.After(time.Second):
+		t.Fatal("handler wasn't invoked for an accepted boot")
+	}
+}
+
+func (suite *OcppV2TestSuite) TestBootNotificationStationBootedHandlerTimeout() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	timeout := 100 * time.Millisecond
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	handlerDone := make(chan bool, 1)
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, 300, provisioning.RegistrationStatusAccepted), nil).Run(func(args mock.Arguments) {
+		time.Sleep(3 * timeout)
+		handlerDone <- true
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetHandlerTimeout(provisioning.BootNotificationFeatureName, timeout)
+	bootedC := make(chan ocpp2.BootInfo, 1)
+	suite.csms.SetStationBootedHandler(func(stationID string, info ocpp2.BootInfo) {
+		bootedC <- info
+	})
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// The accepted response is discarded after the timeout, so the station isn't considered booted
+	response, err := suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "ABL")
+	require.Error(t, err)
+	assert.Nil(t, response)
+	<-handlerDone
+	select {
+	case <-bootedC:
+		t.Fatal("handler shouldn't be invoked if the response wasn't sent")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func (suite *OcppV2TestSuite) TestBootNotificationInvalidEndpoint() {
 	messageId := defaultMessageId
 	chargePointModel := "model1"
@@ -0,0 +1,129 @@
+package ocpp2_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+)
+
+func (suite *OcppV2TestSuite) TestBroadcastConcurrencyLimit() {
+	t := suite.T()
+	messageId := defaultMessageId
+	status := provisioning.ResetStatusAccepted
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	connectedIds := []string{"station1", "station2", "station3", "station4", "station5", "station6"}
+	excludedId := "station6"
+	concurrency := 2
+
+	var mutex sync.Mutex
+	inFlight := 0
+	maxInFlight := 0
+	reachedIds := map[string]bool{}
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		clientId := args.String(0)
+		assert.Equal(t, fmt.Sprintf(`[2,"%v","%v",{"type":"%v"}]`, messageId, provisioning.ResetFeatureName, provisioning.ResetTypeOnIdle), string(args.Get(1).([]byte)))
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		reachedIds[clientId] = true
+		mutex.Unlock()
+		// Reply asynchronously on behalf of the charging station, after some processing time
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+			err := suite.mockWsServer.MessageHandler(NewMockWebSocket(clientId), []byte(responseJson))
+			assert.Nil(t, err)
+		}()
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	for _, id := range connectedIds {
+		suite.mockWsServer.NewClientHandler(NewMockWebSocket(id))
+	}
+	filter := func(stationID string) bool {
+		return stationID != excludedId
+	}
+	build := func(stationID string) ocpp.Request {
+		return provisioning.NewResetRequest(provisioning.ResetTypeOnIdle)
+	}
+	resultChannel := make(chan string, len(connectedIds))
+	targetIds := suite.csms.Broadcast(filter, build, ocpp2.BroadcastOptions{Concurrency: concurrency}, func(stationID string, response ocpp.Response, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, response)
+		assert.Equal(t, status, response.(*provisioning.ResetResponse).Status)
+		resultChannel <- stationID
+	})
+	expectedIds := connectedIds[:len(connectedIds)-1]
+	assert.ElementsMatch(t, expectedIds, targetIds)
+	var resultIds []string
+	for range expectedIds {
+		select {
+		case id := <-resultChannel:
+			resultIds = append(resultIds, id)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for broadcast results")
+		}
+	}
+	assert.ElementsMatch(t, expectedIds, resultIds)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Len(t, reachedIds, len(expectedIds))
+	assert.False(t, reachedIds[excludedId])
+	assert.LessOrEqual(t, maxInFlight, concurrency)
+	assert.Greater(t, maxInFlight, 0)
+}
+
+func (suite *OcppV2TestSuite) TestBroadcastInterval() {
+	t := suite.T()
+	messageId := defaultMessageId
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, provisioning.ResetStatusAccepted)
+	connectedIds := []string{"station1", "station2", "station3"}
+	interval := 50 * time.Millisecond
+
+	var mutex sync.Mutex
+	var sentTimes []time.Time
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		clientId := args.String(0)
+		mutex.Lock()
+		sentTimes = append(sentTimes, time.Now())
+		mutex.Unlock()
+		go func() {
+			err := suite.mockWsServer.MessageHandler(NewMockWebSocket(clientId), []byte(responseJson))
+			assert.Nil(t, err)
+		}()
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	for _, id := range connectedIds {
+		suite.mockWsServer.NewClientHandler(NewMockWebSocket(id))
+	}
+	resultChannel := make(chan error, len(connectedIds))
+	suite.csms.Broadcast(nil, func(stationID string) ocpp.Request {
+		return provisioning.NewResetRequest(provisioning.ResetTypeImmediate)
+	}, ocpp2.BroadcastOptions{Interval: interval}, func(stationID string, response ocpp.Response, err error) {
+		resultChannel <- err
+	})
+	for range connectedIds {
+		require.NoError(t, <-resultChannel)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Len(t, sentTimes, len(connectedIds))
+	for i := 1; i < len(sentTimes); i++ {
+		assert.GreaterOrEqual(t, int64(sentTimes[i].Sub(sentTimes[i-1])), int64(interval-5*time.Millisecond))
+	}
+}
@@ -1,7 +1,16 @@
 package ocpp2_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -88,3 +97,115 @@ func (suite *OcppV2TestSuite) TestCertificateSignedInvalidEndpoint() {
 	requestJson := fmt.Sprintf(`[2,"%v","%v",{"certificateChain":"%v","certificateType":"%v"}]`, messageId, security.CertificateSignedFeatureName, certificate, certificateType)
 	testUnsupportedRequestFromChargingStation(suite, certificateSignedRequest, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestCertificateSignedParseCertificateChain() {
+	t := suite.T()
+	rootCA, rootKey := newTestCertificate(t, "RootCA", true, nil, nil)
+	subCA, subKey := newTestCertificate(t, "SubCA", true, rootCA, rootKey)
+	leaf, _ := newTestCertificate(t, "station1", false, subCA, subKey)
+	// Valid chains
+	request := security.NewCertificateSignedRequest(security.EncodeCertificateChain([]*x509.Certificate{leaf, subCA}))
+	chain, err := request.ParseCertificateChain()
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.Equal(t, "station1", chain[0].Subject.CommonName)
+	assert.Equal(t, "SubCA", chain[1].Subject.CommonName)
+	request = security.NewCertificateSignedRequest(security.EncodeCertificateChain([]*x509.Certificate{leaf}))
+	chain, err = request.ParseCertificateChain()
+	require.NoError(t, err)
+	require.Len(t, chain, 1)
+	// Invalid chains
+	request = security.NewCertificateSignedRequest(security.EncodeCertificateChain([]*x509.Certificate{subCA, leaf}))
+	_, err = request.ParseCertificateChain()
+	assert.Error(t, err)
+	request = security.NewCertificateSignedRequest(security.EncodeCertificateChain([]*x509.Certificate{leaf, subCA, rootCA, rootCA}))
+	_, err = request.ParseCertificateChain()
+	assert.EqualError(t, err, "invalid certificate chain, contains 4 certificates, maximum is 3")
+	request = security.NewCertificateSignedRequest("someX509CertificateChain")
+	_, err = request.ParseCertificateChain()
+	assert.Error(t, err)
+	request = security.NewCertificateSignedRequest("")
+	_, err = request.ParseCertificateChain()
+	assert.Error(t, err)
+}
+
+func (suite *OcppV2TestSuite) TestCertificateSignedChainE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	rootCA, rootKey := newTestCertificate(t, "RootCA", true, nil, nil)
+	subCA, subKey := newTestCertificate(t, "SubCA", true, rootCA, rootKey)
+	leaf, _ := newTestCertificate(t, wsId, false, subCA, subKey)
+	certificateChain := security.EncodeCertificateChain([]*x509.Certificate{leaf, subCA})
+	rawChain, _ := json.Marshal(certificateChain)
+	status := security.CertificateSignedStatusAccepted
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"certificateChain":%v,"certificateType":"%v"}]`,
+		messageId, security.CertificateSignedFeatureName, string(rawChain), types.ChargingStationCert)
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	channel := NewMockWebSocket(wsId)
+	// Station verifies the received chain before accepting it
+	handler := &MockChargingStationSecurityHandler{}
+	handler.On("OnCertificateSigned", mock.Anything).Return(security.NewCertificateSignedResponse(status), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(0).(*security.CertificateSignedRequest)
+		require.True(t, ok)
+		chain, err := request.ParseCertificateChain()
+		require.NoError(t, err)
+		require.Len(t, chain, 2)
+		assert.Equal(t, wsId, chain[0].Subject.CommonName)
+		assert.Equal(t, leaf.Raw, chain[0].Raw)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	resultChannel := make(chan bool, 1)
+	err = suite.csms.CertificateSigned(wsId, func(confirmation *security.CertificateSignedResponse, err error) {
+		require.NoError(t, err)
+		require.NotNil(t, confirmation)
+		assert.Equal(t, status, confirmation.Status)
+		resultChannel <- true
+	}, certificateChain, func(request *security.CertificateSignedRequest) {
+		request.TypeOfCertificate = types.ChargingStationCert
+	})
+	require.NoError(t, err)
+	result := <-resultChannel
+	assert.True(t, result)
+}
+
+// Creates a certificate signed by the parent. If parent is nil, the certificate is self-signed.
+func newTestCertificate(t require.TestingT, commonName string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+	return certificate, key
+}
+
+// Creates a PEM encoded certificate signing request.
+func newTestCSR(t require.TestingT, commonName string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: raw}))
+}
@@ -2,6 +2,7 @@ package ocpp2_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
@@ -81,6 +82,65 @@ func (suite *OcppV2TestSuite) TestChangeAvailabilityE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestChangeAvailabilityDeduplication() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	status := availability.ChangeAvailabilityStatusAccepted
+	channel := NewMockWebSocket(wsId)
+	receivedC := make(chan availability.OperationalStatus, 4)
+	handler := &MockChargingStationAvailabilityHandler{}
+	handler.On("OnChangeAvailability", mock.Anything).Return(availability.NewChangeAvailabilityResponse(status), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*availability.ChangeAvailabilityRequest)
+		receivedC <- request.OperationalStatus
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	suite.csms.SetRequestDeduplication(time.Second, nil)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultC := make(chan availability.ChangeAvailabilityStatus, 4)
+	callback := func(confirmation *availability.ChangeAvailabilityResponse, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, confirmation)
+		resultC <- confirmation.Status
+	}
+	awaitResult := func() {
+		select {
+		case result := <-resultC:
+			assert.Equal(t, status, result)
+		case <-time.After(time.Second):
+			t.Fatal("callback wasn't invoked")
+		}
+	}
+	// Identical requests in quick succession only reach the station once
+	for i := 0; i < 2; i++ {
+		err = suite.csms.ChangeAvailability(wsId, callback, availability.OperationalStatusInoperative)
+		require.Nil(t, err)
+	}
+	awaitResult()
+	awaitResult()
+	// A duplicate of a completed request receives the stored result
+	err = suite.csms.ChangeAvailability(wsId, callback, availability.OperationalStatusInoperative)
+	require.Nil(t, err)
+	awaitResult()
+	assert.Equal(t, availability.OperationalStatusInoperative, <-receivedC)
+	assert.Len(t, receivedC, 0)
+	// A request with a different payload is sent
+	err = suite.csms.ChangeAvailability(wsId, callback, availability.OperationalStatusOperative)
+	require.Nil(t, err)
+	awaitResult()
+	assert.Equal(t, availability.OperationalStatusOperative, <-receivedC)
+	// Without deduplication, identical requests are sent
+	suite.csms.SetRequestDeduplication(0, nil)
+	err = suite.csms.ChangeAvailability(wsId, callback, availability.OperationalStatusOperative)
+	require.Nil(t, err)
+	awaitResult()
+	assert.Equal(t, availability.OperationalStatusOperative, <-receivedC)
+}
+
 func (suite *OcppV2TestSuite) TestChangeAvailabilityInvalidEndpoint() {
 	messageId := defaultMessageId
 	evse := types.EVSE{ID: 1, ConnectorID: newInt(1)}
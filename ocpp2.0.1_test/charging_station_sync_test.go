@@ -0,0 +1,404 @@
+package ocpp2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/data"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+type observedRequest struct {
+	clientID  string
+	requestID string
+	action    string
+	direction ocppj.RequestDirection
+	completed bool
+	err       *ocpp.Error
+}
+
+type recordingRequestObserver struct {
+	mutex    sync.Mutex
+	requests []*observedRequest
+}
+
+func (o *recordingRequestObserver) OnRequestStarted(ctx context.Context, clientID string, requestID string, action string, direction ocppj.RequestDirection) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.requests = append(o.requests, &observedRequest{clientID: clientID, requestID: requestID, action: action, direction: direction})
+}
+
+func (o *recordingRequestObserver) OnRequestCompleted(clientID string, requestID string, direction ocppj.RequestDirection, err *ocpp.Error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, r := range o.requests {
+		if r.requestID == requestID && r.direction == direction {
+			r.completed = true
+			r.err = err
+		}
+	}
+}
+
+func (o *recordingRequestObserver) snapshot() []observedRequest {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var result []observedRequest
+	for _, r := range o.requests {
+		result = append(result, *r)
+	}
+	return result
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationSendRequestSync() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*currentTime), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	observer := &recordingRequestObserver{}
+	suite.chargingStation.SetRequestObserver(observer)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := suite.chargingStation.SendRequestSync(ctx, availability.NewHeartbeatRequest())
+	require.Nil(t, err)
+	heartbeatResponse, ok := response.(*availability.HeartbeatResponse)
+	require.True(t, ok)
+	assertDateTimeEquality(t, currentTime, &heartbeatResponse.CurrentTime)
+	// The observer saw the whole lifecycle of the outgoing request
+	requests := observer.snapshot()
+	require.Len(t, requests, 1)
+	assert.Equal(t, wsId, requests[0].clientID)
+	assert.Equal(t, availability.HeartbeatFeatureName, requests[0].action)
+	assert.Equal(t, ocppj.RequestOutgoing, requests[0].direction)
+	assert.True(t, requests[0].completed)
+	assert.Nil(t, requests[0].err)
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationSendRequestSyncContextDone() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	// Requests are never forwarded to the CSMS, hence no response is received
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: false})
+	observer := &recordingRequestObserver{}
+	suite.chargingStation.SetRequestObserver(observer)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	response, err := suite.chargingStation.SendRequestSync(ctx, availability.NewHeartbeatRequest())
+	assert.Nil(t, response)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	// Request is still pending
+	requests := observer.snapshot()
+	require.Len(t, requests, 1)
+	assert.False(t, requests[0].completed)
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationRequestObserverIncoming() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	observer := &recordingRequestObserver{}
+	suite.chargingStation.SetRequestObserver(observer)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// No availability handler is set on the station, hence the request is rejected with a CALL_ERROR
+	resultC := make(chan error, 1)
+	err = suite.csms.ChangeAvailability(wsId, func(response *availability.ChangeAvailabilityResponse, err error) {
+		resultC <- err
+	}, availability.OperationalStatusOperative)
+	require.Nil(t, err)
+	select {
+	case err = <-resultC:
+		require.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for response")
+	}
+	requests := observer.snapshot()
+	require.Len(t, requests, 1)
+	assert.Equal(t, messageId, requests[0].requestID)
+	assert.Equal(t, availability.ChangeAvailabilityFeatureName, requests[0].action)
+	assert.Equal(t, ocppj.RequestIncoming, requests[0].direction)
+	assert.True(t, requests[0].completed)
+	require.NotNil(t, requests[0].err)
+	assert.Equal(t, ocppj.NotSupported, requests[0].err.Code)
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationQueueFlushFilter() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	receivedC := make(chan string, 3)
+	handler := &MockCSMSDataHandler{}
+	handler.On("OnDataTransfer", mock.AnythingOfType("string"), mock.Anything).Return(data.NewDataTransferResponse(data.DataTransferStatusAccepted), nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*data.DataTransferRequest)
+		receivedC <- request.VendorID
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.chargingStation.SetQueueFlushFilter(func(clientID string, request ocpp.Request) (ocpp.Request, bool) {
+		assert.Equal(t, wsId, clientID)
+		switch request.(*data.DataTransferRequest).VendorID {
+		case "drop":
+			return nil, false
+		case "update":
+			return data.NewDataTransferRequest("updated"), true
+		default:
+			return request, true
+		}
+	})
+	reconnectedC := make(chan bool, 1)
+	suite.chargingStation.SetReconnectedHandler(func() {
+		reconnectedC <- true
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	suite.mockWsClient.DisconnectedHandler(fmt.Errorf("some error"))
+	type result struct {
+		vendorID string
+		err      error
+	}
+	resultC := make(chan result, 3)
+	for _, vendorID := range []string{"keep", "drop", "update"} {
+		id := vendorID
+		err = suite.chargingStation.SendRequestAsync(data.NewDataTransferRequest(id), func(response ocpp.Response, err error) {
+			resultC <- result{vendorID: id, err: err}
+		})
+		require.Nil(t, err)
+	}
+	// Dropped request is canceled on reconnect, before the queue is flushed
+	suite.mockWsClient.ReconnectedHandler()
+	_, ok := <-reconnectedC
+	require.True(t, ok)
+	for _, expected := range []string{"drop", "keep", "update"} {
+		r := <-resultC
+		assert.Equal(t, expected, r.vendorID)
+		if expected == "drop" {
+			require.Error(t, r.err)
+			assert.IsType(t, &ocpp.Error{}, r.err)
+			assert.Equal(t, ocppj.GenericError, r.err.(*ocpp.Error).Code)
+		} else {
+			assert.Nil(t, r.err)
+		}
+	}
+	assert.Equal(t, "keep", <-receivedC)
+	assert.Equal(t, "updated", <-receivedC)
+}
+
+type memoryQueueStore struct {
+	mutex   sync.Mutex
+	ids     []string
+	entries map[string][]byte
+}
+
+func (s *memoryQueueStore) Save(requestID string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ids = append(s.ids, requestID)
+	s.entries[requestID] = data
+	return nil
+}
+
+func (s *memoryQueueStore) Delete(requestID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, requestID)
+	return nil
+}
+
+func (s *memoryQueueStore) Load() ([][]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var result [][]byte
+	for _, id := range s.ids {
+		if data, ok := s.entries[id]; ok {
+			result = append(result, data)
+		}
+	}
+	return result, nil
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationRestoreRequests() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	restoredId := "restored1"
+	currentTime := types.NewDateTime(time.Now())
+	// A heartbeat was persisted by a previous process
+	store := &memoryQueueStore{entries: map[string][]byte{}}
+	require.NoError(t, store.Save(restoredId, []byte(fmt.Sprintf(`[2,"%v","%v",{}]`, restoredId, availability.HeartbeatFeatureName))))
+	endpoint := &ocppj.Endpoint{}
+	endpoint.AddProfile(availability.Profile)
+	endpoint.AddProfile(provisioning.Profile)
+	queue := ocppj.NewPersistentQueue(ocppj.NewFIFOClientQueue(queueCapacity), store, endpoint)
+	suite.clientDispatcher = ocppj.NewDefaultClientDispatcher(queue)
+	suite.ocppjClient = ocppj.NewClient(wsId, suite.mockWsClient, suite.clientDispatcher, nil, availability.Profile, provisioning.Profile)
+	suite.chargingStation = ocpp2.NewChargingStation(wsId, suite.ocppjClient, suite.mockWsClient)
+	type restoredResult struct {
+		request  ocpp.Request
+		response ocpp.Response
+		err      error
+	}
+	restoredC := make(chan restoredResult, 1)
+	err := suite.chargingStation.RestoreRequests(queue, func(request ocpp.Request, response ocpp.Response, err error) {
+		restoredC <- restoredResult{request: request, response: response, err: err}
+	})
+	require.NoError(t, err)
+	availabilityHandler := &MockCSMSAvailabilityHandler{}
+	availabilityHandler.On("OnHeartbeat", wsId, mock.Anything).Return(availability.NewHeartbeatResponse(*currentTime), nil)
+	provisioningHandler := &MockCSMSProvisioningHandler{}
+	provisioningHandler.On("OnBootNotification", wsId, mock.Anything).Return(provisioning.NewBootNotificationResponse(currentTime, 60, provisioning.RegistrationStatusAccepted), nil)
+	channel := NewMockWebSocket(wsId)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, availabilityHandler, provisioningHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.Start(8887, "somePath")
+	err = suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	// The restored request is sent first, its response is passed to the restore handler
+	bootResponse, err := suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.NoError(t, err)
+	require.NotNil(t, bootResponse)
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, bootResponse.Status)
+	select {
+	case result := <-restoredC:
+		require.NoError(t, result.err)
+		assert.IsType(t, &availability.HeartbeatRequest{}, result.request)
+		heartbeatResponse, ok := result.response.(*availability.HeartbeatResponse)
+		require.True(t, ok)
+		assertDateTimeEquality(t, currentTime, &heartbeatResponse.CurrentTime)
+	default:
+		t.Fatal("restored request wasn't completed before the boot notification")
+	}
+	// Both requests were completed and removed from the store
+	entries, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+type stationAuditSink struct {
+	mutex   sync.Mutex
+	entries []ocppj.AuditEntry
+}
+
+func (s *stationAuditSink) Append(entry ocppj.AuditEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *stationAuditSink) snapshot() []ocppj.AuditEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ocppj.AuditEntry{}, s.entries...)
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationAuditLog() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*currentTime), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	sink := &stationAuditSink{}
+	suite.chargingStation.SetAuditLog(ocppj.NewAuditLog(sink))
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	_, err = suite.chargingStation.SendRequest(availability.NewHeartbeatRequest())
+	require.Nil(t, err)
+	// The raw request and response frames were recorded
+	entries := sink.snapshot()
+	require.Len(t, entries, 2)
+	assert.Equal(t, ocppj.AuditRequest, entries[0].Kind)
+	assert.Equal(t, wsId, entries[0].ClientID)
+	assert.Equal(t, availability.HeartbeatFeatureName, entries[0].Action)
+	assert.Contains(t, string(entries[0].Message), fmt.Sprintf(`"%v"`, availability.HeartbeatFeatureName))
+	assert.Equal(t, ocppj.AuditResponse, entries[1].Kind)
+	assert.Equal(t, entries[0].RequestID, entries[1].RequestID)
+	assert.Contains(t, string(entries[1].Message), `"currentTime"`)
+	assert.Equal(t, entries[0].Hash, entries[1].PreviousHash)
+}
+
+func (suite *OcppV2TestSuite) TestChargingStationInvalidResponse() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	rawPayload := `{"currentTime":42}`
+	channel := NewMockWebSocket(wsId)
+	writtenC := make(chan []byte, 2)
+	suite.mockWsClient.On("Write", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writtenC <- args.Get(0).([]byte)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultC := make(chan error, 1)
+	err = suite.chargingStation.SendRequestAsync(availability.NewHeartbeatRequest(), func(response ocpp.Response, err error) {
+		assert.Nil(t, response)
+		resultC <- err
+	})
+	require.Nil(t, err)
+	// The CSMS returns a response not matching the HeartbeatResponse type
+	fields, err := ocppj.ParseRawJsonMessage(<-writtenC)
+	require.Nil(t, err)
+	messageId := fields[1].(string)
+	err = suite.mockWsClient.MessageHandler([]byte(fmt.Sprintf(`[3,"%v",%v]`, messageId, rawPayload)))
+	require.Error(t, err)
+	select {
+	case err = <-resultC:
+	case <-time.After(time.Second):
+		t.Fatal("callback wasn't invoked for malformed response")
+	}
+	var invalidResponse *ocppj.InvalidResponseError
+	require.True(t, errors.As(err, &invalidResponse))
+	assert.Equal(t, rawPayload, string(invalidResponse.RawPayload()))
+	assert.Equal(t, availability.HeartbeatFeatureName, invalidResponse.Action)
+	var ocppErr *ocpp.Error
+	require.True(t, errors.As(err, &ocppErr))
+	assert.Equal(t, ocppj.FormatViolationV2, ocppErr.Code)
+	assert.Equal(t, messageId, ocppErr.MessageId)
+	// A CALLERROR is returned to the CSMS
+	assert.Contains(t, string(<-writtenC), fmt.Sprintf(`[4,"%v","%v"`, messageId, ocppj.FormatViolationV2))
+}
@@ -17,6 +17,9 @@ func (suite *OcppV2TestSuite) TestClearedChargingLimitRequestValidation() {
 	var requestTable = []GenericTestEntry{
 		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: types.ChargingLimitSourceEMS, EvseID: newInt(0)}, true},
 		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: types.ChargingLimitSourceEMS}, true},
+		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: types.ChargingLimitSourceOther}, true},
+		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: types.ChargingLimitSourceSO, EvseID: newInt(1)}, true},
+		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: types.ChargingLimitSourceCSO, EvseID: newInt(2)}, true},
 		{smartcharging.ClearedChargingLimitRequest{}, false},
 		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: types.ChargingLimitSourceEMS, EvseID: newInt(-1)}, false},
 		{smartcharging.ClearedChargingLimitRequest{ChargingLimitSource: "invalidChargingLimitSource"}, false},
@@ -65,6 +68,54 @@ func (suite *OcppV2TestSuite) TestClearedChargingLimitE2EMocked() {
 	require.NotNil(t, confirmation)
 }
 
+func (suite *OcppV2TestSuite) TestClearedChargingLimitStationScopeE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	chargingLimitSource := types.ChargingLimitSourceSO
+	// Without an evseId, the cleared limit applies to the whole charging station
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"chargingLimitSource":"%v"}]`, messageId, smartcharging.ClearedChargingLimitFeatureName, chargingLimitSource)
+	responseJson := fmt.Sprintf(`[3,"%v",{}]`, messageId)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSSmartChargingHandler{}
+	handler.On("OnClearedChargingLimit", mock.AnythingOfType("string"), mock.Anything).Return(smartcharging.NewClearedChargingLimitResponse(), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(1).(*smartcharging.ClearedChargingLimitRequest)
+		require.True(t, ok)
+		require.NotNil(t, request)
+		assert.Equal(t, chargingLimitSource, request.ChargingLimitSource)
+		assert.Nil(t, request.EvseID)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	confirmation, err := suite.chargingStation.ClearedChargingLimit(chargingLimitSource)
+	require.Nil(t, err)
+	require.NotNil(t, confirmation)
+	handler.AssertCalled(t, "OnClearedChargingLimit", wsId, mock.Anything)
+}
+
+func (suite *OcppV2TestSuite) TestClearedChargingLimitInvalidSource() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Invalid requests are rejected by the station, before being sent
+	confirmation, err := suite.chargingStation.ClearedChargingLimit("invalidChargingLimitSource")
+	require.NotNil(t, err)
+	assert.Nil(t, confirmation)
+}
+
 func (suite *OcppV2TestSuite) TestClearedChargingLimitInvalidEndpoint() {
 	messageId := defaultMessageId
 	chargingLimitSource := types.ChargingLimitSourceEMS
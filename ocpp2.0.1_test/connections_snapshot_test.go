@@ -0,0 +1,77 @@
+package ocpp2_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+func (suite *OcppV2TestSuite) TestConnectionsSnapshot() {
+	t := suite.T()
+	stationA := NewMockWebSocket("station_a")
+	stationB := NewMockWebSocket("station_b")
+	writtenC := make(chan string, 10)
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writtenC <- args.String(0)
+	})
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil)
+	suite.csms.SetAvailabilityHandler(handler)
+	suite.csms.Start(8887, "somePath")
+	// Connect two stations
+	before := time.Now()
+	suite.mockWsServer.NewClientHandler(stationA)
+	suite.mockWsServer.NewClientHandler(stationB)
+	assert.Error(t, suite.csms.SetConnectionLabels("unknown", map[string]string{"site": "north"}))
+	labels := map[string]string{"site": "north", "operator": "acme"}
+	require.NoError(t, suite.csms.SetConnectionLabels(stationA.ID(), labels))
+	labels["site"] = "changed"
+	// Station A sends a heartbeat and receives a response
+	err := suite.mockWsServer.MessageHandler(stationA, []byte(fmt.Sprintf(`[2,"%v","%v",{}]`, "hb1", availability.HeartbeatFeatureName)))
+	require.NoError(t, err)
+	assert.Equal(t, stationA.ID(), <-writtenC)
+	// Station B receives a request and responds
+	resultC := make(chan bool, 1)
+	err = suite.csms.Reset(stationB.ID(), func(response *provisioning.ResetResponse, err error) {
+		resultC <- err == nil
+	}, provisioning.ResetTypeOnIdle)
+	require.NoError(t, err)
+	assert.Equal(t, stationB.ID(), <-writtenC)
+	err = suite.mockWsServer.MessageHandler(stationB, []byte(fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, defaultMessageId, provisioning.ResetStatusAccepted)))
+	require.NoError(t, err)
+	assert.True(t, <-resultC)
+	after := time.Now()
+	// Verify snapshot
+	snapshot := suite.csms.ConnectionsSnapshot()
+	require.Len(t, snapshot, 2)
+	for _, info := range snapshot {
+		assert.Equal(t, types.V201Subprotocol, info.Protocol)
+		assert.Equal(t, "127.0.0.1:80", info.RemoteAddr.String())
+		assert.True(t, !info.ConnectedAt.Before(before) && !info.ConnectedAt.After(after))
+		assert.True(t, !info.LastActivity.Before(info.ConnectedAt) && !info.LastActivity.After(after))
+		assert.Equal(t, uint64(1), info.MessagesReceived)
+		assert.Equal(t, uint64(1), info.MessagesSent)
+		assert.Nil(t, info.ActiveTransactions)
+		assert.Nil(t, info.NetworkDiagnostics)
+	}
+	assert.Equal(t, stationA.ID(), snapshot[0].ChargingStationID)
+	assert.Equal(t, map[string]string{"site": "north", "operator": "acme"}, snapshot[0].Labels)
+	assert.Equal(t, stationB.ID(), snapshot[1].ChargingStationID)
+	assert.Nil(t, snapshot[1].Labels)
+	// Snapshots are copies
+	snapshot[0].Labels["site"] = "changed"
+	assert.Equal(t, "north", suite.csms.ConnectionsSnapshot()[0].Labels["site"])
+	// Disconnected stations are removed
+	suite.mockWsServer.DisconnectedClientHandler(stationA)
+	snapshot = suite.csms.ConnectionsSnapshot()
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, stationB.ID(), snapshot[0].ChargingStationID)
+}
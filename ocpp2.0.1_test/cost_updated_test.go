@@ -2,12 +2,16 @@ package ocpp2_test
 
 import (
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/tariffcost"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 )
 
 // Test
@@ -68,6 +72,80 @@ func (suite *OcppV2TestSuite) TestCostUpdatedE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestCostUpdatedStreaming() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	transactionId := "1234"
+	interval := 100 * time.Millisecond
+	channel := NewMockWebSocket(wsId)
+	type update struct {
+		cost       float64
+		receivedAt time.Time
+	}
+	updateC := make(chan update, 10)
+	handler := &MockChargingStationTariffCostHandler{}
+	handler.On("OnCostUpdated", mock.Anything).Return(tariffcost.NewCostUpdatedResponse(), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*tariffcost.CostUpdatedRequest)
+		assert.Equal(t, transactionId, request.TransactionID)
+		updateC <- update{cost: request.TotalCost, receivedAt: time.Now()}
+	})
+	transactionsHandler := &MockCSMSTransactionsHandler{}
+	transactionsHandler.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactions.NewTransactionEventResponse(), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, transactionsHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Invalid parameters and unknown stations are rejected
+	var cost int64
+	compute := func() float64 {
+		return float64(atomic.AddInt64(&cost, 1))
+	}
+	assert.Error(t, suite.csms.StartCostUpdates(wsId, transactionId, 0, compute))
+	assert.Error(t, suite.csms.StartCostUpdates("unknown", transactionId, interval, compute))
+	assert.False(t, suite.csms.StopCostUpdates(wsId, transactionId))
+	// Updates are sent at every interval, with the computed cost
+	startedAt := time.Now()
+	err = suite.csms.StartCostUpdates(wsId, transactionId, interval, compute)
+	require.Nil(t, err)
+	previous := startedAt
+	for i := 1; i <= 3; i++ {
+		select {
+		case u := <-updateC:
+			assert.Equal(t, float64(i), u.cost)
+			assert.GreaterOrEqual(t, int64(u.receivedAt.Sub(previous)), int64(interval/2))
+			previous = u.receivedAt
+		case <-time.After(5 * interval):
+			t.Fatalf("cost update %d wasn't received", i)
+		}
+	}
+	// Updates stop once the transaction ended
+	_, err = suite.chargingStation.TransactionEvent(transactions.TransactionEventEnded, types.NewDateTime(time.Now()), transactions.TriggerReasonEVDeparted, 1, transactions.Transaction{TransactionID: transactionId})
+	require.Nil(t, err)
+	assert.False(t, suite.csms.StopCostUpdates(wsId, transactionId))
+	// Drain an update, which may have been in flight while ending the transaction
+	time.Sleep(interval / 2)
+	for len(updateC) > 0 {
+		<-updateC
+	}
+	select {
+	case u := <-updateC:
+		t.Fatalf("unexpected cost update %v after transaction ended", u.cost)
+	case <-time.After(3 * interval):
+	}
+	// Updates can be stopped explicitly
+	err = suite.csms.StartCostUpdates(wsId, "5678", interval, compute)
+	require.Nil(t, err)
+	assert.True(t, suite.csms.StopCostUpdates(wsId, "5678"))
+	select {
+	case u := <-updateC:
+		t.Fatalf("unexpected cost update %v after stopping updates", u.cost)
+	case <-time.After(3 * interval):
+	}
+}
+
 func (suite *OcppV2TestSuite) TestCostUpdatedInvalidEndpoint() {
 	messageId := defaultMessageId
 	totalCost := 24.6
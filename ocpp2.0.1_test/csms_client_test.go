@@ -0,0 +1,80 @@
+package ocpp2_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/csmsclient"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+func (suite *OcppV2TestSuite) TestCSMSClientCommands() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	component := types.Component{Name: "OCPPCommCtrlr"}
+	variable := types.Variable{Name: "HeartbeatInterval"}
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockChargingStationProvisioningHandler{}
+	handler.On("OnReset", mock.Anything).Return(provisioning.NewResetResponse(provisioning.ResetStatusScheduled), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*provisioning.ResetRequest)
+		assert.Equal(t, provisioning.ResetTypeOnIdle, request.Type)
+		require.NotNil(t, request.EvseID)
+		assert.Equal(t, 1, *request.EvseID)
+	})
+	handler.On("OnGetVariables", mock.Anything).Return(provisioning.NewGetVariablesResponse([]provisioning.GetVariableResult{
+		{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeValue: "60", Component: component, Variable: variable},
+	}), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	client := csmsclient.New(suite.csms)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resetResponse, err := client.Reset(ctx, wsId, provisioning.ResetTypeOnIdle, func(request *provisioning.ResetRequest) {
+		request.EvseID = newInt(1)
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resetResponse)
+	assert.Equal(t, provisioning.ResetStatusScheduled, resetResponse.Status)
+	getVariablesResponse, err := client.GetVariables(ctx, wsId, []provisioning.GetVariableData{{Component: component, Variable: variable}})
+	require.NoError(t, err)
+	require.NotNil(t, getVariablesResponse)
+	require.Len(t, getVariablesResponse.GetVariableResult, 1)
+	assert.Equal(t, provisioning.GetVariableStatusAccepted, getVariablesResponse.GetVariableResult[0].AttributeStatus)
+	assert.Equal(t, "60", getVariablesResponse.GetVariableResult[0].AttributeValue)
+	// Unknown station
+	_, err = client.Reset(ctx, "unknown", provisioning.ResetTypeImmediate)
+	assert.Error(t, err)
+}
+
+func (suite *OcppV2TestSuite) TestCSMSClientContextCanceled() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockChargingStationProvisioningHandler{}
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	client := csmsclient.New(suite.csms)
+	// The request is never forwarded to the station, hence the response never arrives
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	response, err := client.Reset(ctx, wsId, provisioning.ResetTypeImmediate)
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
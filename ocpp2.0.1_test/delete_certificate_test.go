@@ -1,7 +1,10 @@
 package ocpp2_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -75,6 +78,78 @@ func (suite *OcppV2TestSuite) TestDeleteCertificateE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestCertificateHashData() {
+	t := suite.T()
+	rootCA, rootKey := newTestCertificate(t, "RootCA", true, nil, nil)
+	leaf, _ := newTestCertificate(t, "station1", false, rootCA, rootKey)
+	hashData, err := types.NewCertificateHashData(leaf, rootCA, types.SHA256)
+	require.NoError(t, err)
+	require.NotNil(t, hashData)
+	assert.Equal(t, types.SHA256, hashData.HashAlgorithm)
+	issuerNameHash := sha256.Sum256(rootCA.RawSubject)
+	assert.Equal(t, hex.EncodeToString(issuerNameHash[:]), hashData.IssuerNameHash)
+	assert.Len(t, hashData.IssuerKeyHash, 64)
+	assert.Equal(t, leaf.SerialNumber.Text(16), hashData.SerialNumber)
+	assert.NoError(t, types.Validate.Struct(hashData))
+	// Self-signed certificates are their own issuer
+	rootHashData, err := types.NewCertificateHashData(rootCA, rootCA, types.SHA512)
+	require.NoError(t, err)
+	assert.Len(t, rootHashData.IssuerNameHash, 128)
+	assert.Len(t, rootHashData.IssuerKeyHash, 128)
+	_, err = types.NewCertificateHashData(leaf, rootCA, "MD5")
+	assert.Error(t, err)
+	// Comparison ignores casing and leading zeroes of the serial number
+	other := *hashData
+	other.IssuerNameHash = strings.ToUpper(other.IssuerNameHash)
+	other.SerialNumber = "00" + strings.ToUpper(other.SerialNumber)
+	assert.True(t, hashData.Equals(other))
+	other.HashAlgorithm = types.SHA384
+	assert.False(t, hashData.Equals(other))
+	assert.False(t, hashData.Equals(*rootHashData))
+}
+
+func (suite *OcppV2TestSuite) TestDeleteCertificateByHashE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	rootCA, rootKey := newTestCertificate(t, "RootCA", true, nil, nil)
+	installed, _ := newTestCertificate(t, "installed", true, rootCA, rootKey)
+	unknown, _ := newTestCertificate(t, "unknown", true, rootCA, rootKey)
+	installedHashData, err := types.NewCertificateHashData(installed, rootCA, types.SHA256)
+	require.NoError(t, err)
+	unknownHashData, err := types.NewCertificateHashData(unknown, rootCA, types.SHA256)
+	require.NoError(t, err)
+	channel := NewMockWebSocket(wsId)
+	// Charging station looks up the certificate by its hash data
+	isInstalled := func(request *iso15118.DeleteCertificateRequest) bool {
+		return request.CertificateHashData.Equals(*installedHashData)
+	}
+	handler := &MockChargingStationIso15118Handler{}
+	handler.On("OnDeleteCertificate", mock.MatchedBy(isInstalled)).Return(iso15118.NewDeleteCertificateResponse(iso15118.DeleteCertificateStatusAccepted), nil)
+	handler.On("OnDeleteCertificate", mock.MatchedBy(func(request *iso15118.DeleteCertificateRequest) bool {
+		return !isInstalled(request)
+	})).Return(iso15118.NewDeleteCertificateResponse(iso15118.DeleteCertificateStatusNotFound), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err = suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultChannel := make(chan iso15118.DeleteCertificateStatus, 1)
+	deleteCertificate := func(hashData types.CertificateHashData) iso15118.DeleteCertificateStatus {
+		err := suite.csms.DeleteCertificate(wsId, func(confirmation *iso15118.DeleteCertificateResponse, err error) {
+			require.Nil(t, err)
+			require.NotNil(t, confirmation)
+			resultChannel <- confirmation.Status
+		}, hashData)
+		require.Nil(t, err)
+		return <-resultChannel
+	}
+	assert.Equal(t, iso15118.DeleteCertificateStatusAccepted, deleteCertificate(*installedHashData))
+	assert.Equal(t, iso15118.DeleteCertificateStatusNotFound, deleteCertificate(*unknownHashData))
+	handler.AssertNumberOfCalls(t, "OnDeleteCertificate", 2)
+}
+
 func (suite *OcppV2TestSuite) TestDeleteCertificateInvalidEndpoint() {
 	messageId := defaultMessageId
 	certificateHashData := types.CertificateHashData{HashAlgorithm: types.SHA256, IssuerNameHash: "hash00", IssuerKeyHash: "hash01", SerialNumber: "serial0"}
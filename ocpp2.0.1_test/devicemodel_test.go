@@ -0,0 +1,184 @@
+package ocpp2_test
+
+import (
+	"fmt"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/devicemodel"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+func (suite *OcppV2TestSuite) TestDeviceModelVariableRef() {
+	t := suite.T()
+	// Charging station level
+	assert.Equal(t, provisioning.GetVariableData{
+		Component: types.Component{Name: "OCPPCommCtrlr"},
+		Variable:  types.Variable{Name: "HeartbeatInterval"},
+	}, devicemodel.Var("OCPPCommCtrlr", "HeartbeatInterval").Get())
+	assert.Equal(t, provisioning.SetVariableData{
+		AttributeType:  types.AttributeTarget,
+		AttributeValue: "10",
+		Component:      types.Component{Name: "OCPPCommCtrlr"},
+		Variable:       types.Variable{Name: "HeartbeatInterval"},
+	}, devicemodel.Var("OCPPCommCtrlr", "HeartbeatInterval").SetAttribute(types.AttributeTarget, "10"))
+	// EVSE level with instances
+	assert.Equal(t, provisioning.GetVariableData{
+		AttributeType: types.AttributeMaxSet,
+		Component:     types.Component{Name: "EVSE", Instance: "main", EVSE: &types.EVSE{ID: 1}},
+		Variable:      types.Variable{Name: "Power", Instance: "x"},
+	}, devicemodel.Var("EVSE", "Power").OnEVSE(1).ComponentInstance("main").Instance("x").GetAttribute(types.AttributeMaxSet))
+	// Connector level
+	assert.Equal(t, provisioning.SetVariableData{
+		AttributeValue: "true",
+		Component:      types.Component{Name: "Connector", EVSE: &types.EVSE{ID: 1, ConnectorID: newInt(2)}},
+		Variable:       types.Variable{Name: "Available"},
+	}, devicemodel.Var("Connector", "Available").OnConnector(1, 2).Set("true"))
+	// References are immutable, hence may be reused as templates
+	template := devicemodel.Var("EVSE", "Available")
+	evse1 := template.OnEVSE(1)
+	evse2 := template.OnEVSE(2)
+	assert.Nil(t, template.Component().EVSE)
+	assert.Equal(t, 1, evse1.Component().EVSE.ID)
+	assert.Equal(t, 2, evse2.Component().EVSE.ID)
+	// Built data passes validation
+	err := types.Validate.Struct(devicemodel.Var("Connector", "Available").OnConnector(1, 2).Set("true"))
+	assert.NoError(t, err)
+}
+
+// Returns a minimal device model with a single EVSE and connector, containing all mandatory variables.
+func conformantDeviceModel() []provisioning.ReportData {
+	var report []provisioning.ReportData
+	add := func(component types.Component, name string, instance string, dataType provisioning.DataType, value string) {
+		report = append(report, provisioning.ReportData{
+			Component:               component,
+			Variable:                types.Variable{Name: name, Instance: instance},
+			VariableAttribute:       []provisioning.VariableAttribute{{Type: types.AttributeActual, Value: value}},
+			VariableCharacteristics: &provisioning.VariableCharacteristics{DataType: dataType, SupportsMonitoring: false},
+		})
+	}
+	station := func(name string) types.Component {
+		return types.Component{Name: name}
+	}
+	for _, component := range []types.Component{station("ChargingStation"), {Name: "EVSE", EVSE: &types.EVSE{ID: 1}}, {Name: "Connector", EVSE: &types.EVSE{ID: 1, ConnectorID: newInt(1)}}} {
+		add(component, "AvailabilityState", "", provisioning.TypeOptionList, "Available")
+		add(component, "Available", "", provisioning.TypeBoolean, "true")
+		add(component, "SupplyPhases", "", provisioning.TypeInteger, "3")
+	}
+	add(types.Component{Name: "EVSE", EVSE: &types.EVSE{ID: 1}}, "Power", "", provisioning.TypeDecimal, "22000.0")
+	add(types.Component{Name: "Connector", EVSE: &types.EVSE{ID: 1, ConnectorID: newInt(1)}}, "ConnectorType", "", provisioning.TypeOptionList, "cType2")
+	for _, instance := range []string{"GetReport", "GetVariables", "SetVariables"} {
+		add(station("DeviceDataCtrlr"), "BytesPerMessage", instance, provisioning.TypeInteger, "4096")
+		add(station("DeviceDataCtrlr"), "ItemsPerMessage", instance, provisioning.TypeInteger, "20")
+	}
+	add(station("ClockCtrlr"), "DateTime", "", provisioning.TypeDateTime, "2021-01-01T12:00:00Z")
+	add(station("ClockCtrlr"), "TimeSource", "", provisioning.TypeSequenceList, "Heartbeat")
+	add(station("OCPPCommCtrlr"), "FileTransferProtocols", "", provisioning.TypeMemberList, "HTTPS")
+	add(station("OCPPCommCtrlr"), "MessageAttemptInterval", "TransactionEvent", provisioning.TypeInteger, "10")
+	add(station("OCPPCommCtrlr"), "MessageAttempts", "TransactionEvent", provisioning.TypeInteger, "3")
+	add(station("OCPPCommCtrlr"), "MessageTimeout", "Default", provisioning.TypeInteger, "30")
+	add(station("OCPPCommCtrlr"), "NetworkConfigurationPriority", "", provisioning.TypeSequenceList, "0")
+	add(station("OCPPCommCtrlr"), "NetworkProfileConnectionAttempts", "", provisioning.TypeInteger, "3")
+	add(station("OCPPCommCtrlr"), "OfflineThreshold", "", provisioning.TypeInteger, "60")
+	add(station("OCPPCommCtrlr"), "ResetRetries", "", provisioning.TypeInteger, "2")
+	add(station("OCPPCommCtrlr"), "UnlockOnEVSideDisconnect", "", provisioning.TypeBoolean, "true")
+	add(station("OCPPCommCtrlr"), "HeartbeatInterval", "", provisioning.TypeInteger, "300")
+	add(station("SecurityCtrlr"), "CertificateEntries", "", provisioning.TypeInteger, "5")
+	add(station("SecurityCtrlr"), "OrganizationName", "", provisioning.TypeString, "ocpp-go")
+	add(station("SecurityCtrlr"), "SecurityProfile", "", provisioning.TypeInteger, "2")
+	add(station("AuthCtrlr"), "AuthorizeRemoteStart", "", provisioning.TypeBoolean, "true")
+	add(station("AuthCtrlr"), "LocalAuthorizeOffline", "", provisioning.TypeBoolean, "true")
+	add(station("AuthCtrlr"), "LocalPreAuthorize", "", provisioning.TypeBoolean, "false")
+	add(station("TxCtrlr"), "EVConnectionTimeOut", "", provisioning.TypeInteger, "120")
+	add(station("TxCtrlr"), "StopTxOnEVSideDisconnect", "", provisioning.TypeBoolean, "true")
+	add(station("TxCtrlr"), "StopTxOnInvalidId", "", provisioning.TypeBoolean, "true")
+	add(station("TxCtrlr"), "TxStartPoint", "", provisioning.TypeMemberList, "Authorized")
+	add(station("TxCtrlr"), "TxStopPoint", "", provisioning.TypeMemberList, "EVConnected")
+	add(station("SampledDataCtrlr"), "TxEndedInterval", "", provisioning.TypeInteger, "60")
+	add(station("SampledDataCtrlr"), "TxEndedMeasurands", "", provisioning.TypeMemberList, "Energy.Active.Import.Register")
+	add(station("SampledDataCtrlr"), "TxStartedMeasurands", "", provisioning.TypeMemberList, "Energy.Active.Import.Register")
+	add(station("SampledDataCtrlr"), "TxUpdatedInterval", "", provisioning.TypeInteger, "60")
+	add(station("SampledDataCtrlr"), "TxUpdatedMeasurands", "", provisioning.TypeMemberList, "Energy.Active.Import.Register")
+	add(station("AlignedDataCtrlr"), "Interval", "", provisioning.TypeInteger, "900")
+	add(station("AlignedDataCtrlr"), "Measurands", "", provisioning.TypeMemberList, "Energy.Active.Import.Register")
+	add(station("AlignedDataCtrlr"), "TxEndedInterval", "", provisioning.TypeInteger, "900")
+	add(station("AlignedDataCtrlr"), "TxEndedMeasurands", "", provisioning.TypeMemberList, "Energy.Active.Import.Register")
+	// Vendor-specific variables are ignored
+	add(station("VendorCtrlr"), "Foo", "", provisioning.TypeString, "bar")
+	return report
+}
+
+func (suite *OcppV2TestSuite) TestDeviceModelConformance() {
+	t := suite.T()
+	report := conformantDeviceModel()
+	assert.Empty(t, devicemodel.CheckConformance(report))
+	// Variable names are compared case-insensitively
+	report[0].Variable.Name = "availabilitystate"
+	assert.Empty(t, devicemodel.CheckConformance(report))
+}
+
+func (suite *OcppV2TestSuite) TestDeviceModelConformanceFindings() {
+	t := suite.T()
+	var report []provisioning.ReportData
+	for _, data := range conformantDeviceModel() {
+		switch {
+		case data.Component.Name == "OCPPCommCtrlr" && data.Variable.Name == "MessageTimeout":
+			// Missing mandatory variable
+			continue
+		case data.Component.Name == "OCPPCommCtrlr" && data.Variable.Name == "HeartbeatInterval":
+			// Wrong data type for a standardized variable
+			data.VariableCharacteristics.DataType = provisioning.TypeString
+		case data.Component.Name == "EVSE" && data.Variable.Name == "Power":
+			// Invalid value
+			data.VariableAttribute[0].Value = "22kW"
+		}
+		report = append(report, data)
+	}
+	// Connector on a second EVSE, without any further variables
+	report = append(report, provisioning.ReportData{
+		Component:         types.Component{Name: "Connector", EVSE: &types.EVSE{ID: 2, ConnectorID: newInt(1)}},
+		Variable:          types.Variable{Name: "ConnectorType"},
+		VariableAttribute: []provisioning.VariableAttribute{{Value: "cType2"}},
+	})
+	// Optional controller is present, but incomplete
+	report = append(report, provisioning.ReportData{
+		Component:         types.Component{Name: "LocalAuthListCtrlr"},
+		Variable:          types.Variable{Name: "Enabled"},
+		VariableAttribute: []provisioning.VariableAttribute{{Value: "true"}},
+	})
+	findings := devicemodel.CheckConformance(report)
+	missing := map[string]bool{}
+	for _, finding := range findings {
+		switch finding.Kind {
+		case devicemodel.FindingMissingVariable:
+			key := finding.Component.Name + "." + finding.Variable.Name
+			if finding.Component.EVSE != nil {
+				key = fmt.Sprintf("%v@%v", key, finding.Component.EVSE.ID)
+			}
+			missing[key] = true
+		case devicemodel.FindingDataTypeMismatch:
+			assert.Equal(t, "HeartbeatInterval", finding.Variable.Name)
+			assert.Equal(t, "declared data type string, expected integer", finding.Reason)
+		case devicemodel.FindingInvalidValue:
+			assert.Equal(t, "Power", finding.Variable.Name)
+			assert.Equal(t, `actual value "22kW" is not a valid decimal`, finding.Reason)
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"OCPPCommCtrlr.MessageTimeout":       true,
+		"EVSE.AvailabilityState@2":           true,
+		"EVSE.Available@2":                   true,
+		"EVSE.Power@2":                       true,
+		"EVSE.SupplyPhases@2":                true,
+		"Connector.AvailabilityState@2":      true,
+		"Connector.Available@2":              true,
+		"Connector.SupplyPhases@2":           true,
+		"LocalAuthListCtrlr.BytesPerMessage": true,
+		"LocalAuthListCtrlr.Entries":         true,
+		"LocalAuthListCtrlr.ItemsPerMessage": true,
+	}, missing)
+	assert.Len(t, findings, len(missing)+2)
+	// Findings are ordered by component, following the standardized catalog
+	assert.Equal(t, `EVSE.Power: actual value "22kW" is not a valid decimal`, findings[0].String())
+}
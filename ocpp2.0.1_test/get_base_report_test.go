@@ -2,6 +2,7 @@ package ocpp2_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,6 +18,8 @@ func (suite *OcppV2TestSuite) TestGetBaseReportRequestValidation() {
 	var requestTable = []GenericTestEntry{
 		{provisioning.GetBaseReportRequest{RequestID: 42, ReportBase: provisioning.ReportTypeConfigurationInventory}, true},
 		{provisioning.GetBaseReportRequest{ReportBase: provisioning.ReportTypeConfigurationInventory}, true},
+		{provisioning.GetBaseReportRequest{RequestID: 42, ReportBase: provisioning.ReportTypeFullInventory}, true},
+		{provisioning.GetBaseReportRequest{RequestID: 42, ReportBase: provisioning.ReportTypeSummaryInventory}, true},
 		{provisioning.GetBaseReportRequest{RequestID: 42}, false},
 		{provisioning.GetBaseReportRequest{}, false},
 		{provisioning.GetBaseReportRequest{RequestID: 42, ReportBase: "invalidReportType"}, false},
@@ -81,3 +84,128 @@ func (suite *OcppV2TestSuite) TestGetBaseReportInvalidEndpoint() {
 	requestJson := fmt.Sprintf(`[2,"%v","%v",{"requestId":%v,"reportBase":"%v"}]`, messageId, provisioning.GetBaseReportFeatureName, requestID, reportBase)
 	testUnsupportedRequestFromChargingStation(suite, getBaseReportRequest, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestGetBaseReportAssembler() {
+	t := suite.T()
+	newPart := func(requestID int, seqNo int, tbc bool, componentName string) *provisioning.NotifyReportRequest {
+		request := provisioning.NewNotifyReportRequest(requestID, types.NewDateTime(time.Now()), seqNo)
+		request.Tbc = tbc
+		request.ReportData = []provisioning.ReportData{
+			{Component: types.Component{Name: componentName}, Variable: types.Variable{Name: "Enabled"}, VariableAttribute: []provisioning.VariableAttribute{{Value: "true"}}},
+		}
+		return request
+	}
+	for i, reportBase := range []provisioning.ReportBaseType{provisioning.ReportTypeConfigurationInventory, provisioning.ReportTypeFullInventory, provisioning.ReportTypeSummaryInventory} {
+		assembler := provisioning.NewReportAssembler()
+		request := provisioning.NewGetBaseReportRequest(i, reportBase)
+		doneC := assembler.Await(request.RequestID)
+		// Parts may arrive out of order, the report is complete once all parts were received
+		reportData, complete := assembler.Add(newPart(request.RequestID, 1, true, "component1"))
+		assert.False(t, complete)
+		assert.Nil(t, reportData)
+		reportData, complete = assembler.Add(newPart(request.RequestID, 2, false, "component2"))
+		assert.False(t, complete)
+		assert.Nil(t, reportData)
+		// Parts of other reports don't interfere
+		_, complete = assembler.Add(newPart(request.RequestID+10, 0, true, "other"))
+		assert.False(t, complete)
+		reportData, complete = assembler.Add(newPart(request.RequestID, 0, true, "component0"))
+		require.True(t, complete, reportBase)
+		require.Len(t, reportData, 3)
+		for j, data := range reportData {
+			assert.Equal(t, fmt.Sprintf("component%v", j), data.Component.Name)
+		}
+		select {
+		case assembled := <-doneC:
+			assert.Equal(t, reportData, assembled)
+		default:
+			t.Fatalf("assembled report for %v not delivered", reportBase)
+		}
+	}
+	// Single-part report
+	assembler := provisioning.NewReportAssembler()
+	reportData, complete := assembler.Add(newPart(1, 0, false, "component0"))
+	assert.True(t, complete)
+	assert.Len(t, reportData, 1)
+	// Discarded report must be received again from scratch
+	discardedC := assembler.Await(2)
+	_, complete = assembler.Add(newPart(2, 0, true, "component0"))
+	assert.False(t, complete)
+	assembler.Discard(2)
+	select {
+	case assembled, ok := <-discardedC:
+		assert.False(t, ok)
+		assert.Nil(t, assembled)
+	default:
+		t.Fatal("channel of discarded report not closed")
+	}
+	_, complete = assembler.Add(newPart(2, 1, false, "component1"))
+	assert.False(t, complete)
+	// Awaiting the same report again releases the previous channel
+	firstC := assembler.Await(3)
+	secondC := assembler.Await(3)
+	_, ok := <-firstC
+	assert.False(t, ok)
+	reportData, complete = assembler.Add(newPart(3, 0, false, "component0"))
+	require.True(t, complete)
+	assert.Equal(t, reportData, <-secondC)
+}
+
+func (suite *OcppV2TestSuite) TestGetBaseReportEmptyReport() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	requestID := 42
+	channel := NewMockWebSocket(wsId)
+	assembler := provisioning.NewReportAssembler()
+	completedC := make(chan bool, 1)
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnNotifyReport", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewNotifyReportResponse(), nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*provisioning.NotifyReportRequest)
+		_, complete := assembler.Add(request)
+		completedC <- complete
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	assert.False(t, assembler.Pending(requestID))
+	// A single part with tbc=false and without reportData completes the report
+	_, err = suite.chargingStation.NotifyReport(requestID, types.NewDateTime(time.Now()), 0)
+	require.Nil(t, err)
+	assert.True(t, <-completedC)
+	assert.False(t, assembler.Pending(requestID))
+	reportData, ok := assembler.Completed(requestID)
+	require.True(t, ok)
+	assert.NotNil(t, reportData)
+	assert.Len(t, reportData, 0)
+	// The result is delivered, even though the report completed before awaiting it
+	select {
+	case assembled := <-assembler.Await(requestID):
+		assert.NotNil(t, assembled)
+		assert.Len(t, assembled, 0)
+	default:
+		t.Fatal("empty report not delivered")
+	}
+	_, ok = assembler.Completed(requestID)
+	assert.False(t, ok)
+	// An awaited report is pending until its last part was received
+	doneC := assembler.Await(requestID + 1)
+	assert.True(t, assembler.Pending(requestID+1))
+	_, err = suite.chargingStation.NotifyReport(requestID+1, types.NewDateTime(time.Now()), 0, func(request *provisioning.NotifyReportRequest) {
+		request.Tbc = true
+	})
+	require.Nil(t, err)
+	assert.False(t, <-completedC)
+	assert.True(t, assembler.Pending(requestID+1))
+	_, err = suite.chargingStation.NotifyReport(requestID+1, types.NewDateTime(time.Now()), 1)
+	require.Nil(t, err)
+	assert.True(t, <-completedC)
+	assert.False(t, assembler.Pending(requestID+1))
+	assert.Len(t, <-doneC, 0)
+	// Awaited reports aren't retained
+	_, ok = assembler.Completed(requestID + 1)
+	assert.False(t, ok)
+}
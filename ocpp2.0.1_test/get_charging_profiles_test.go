@@ -22,6 +22,7 @@ func (suite *OcppV2TestSuite) TestGetChargingProfilesRequestValidation() {
 	}
 	var requestTable = []GenericTestEntry{
 		{smartcharging.GetChargingProfilesRequest{RequestID: 42, EvseID: newInt(1), ChargingProfile: validChargingProfileCriterion}, true},
+		{smartcharging.GetChargingProfilesRequest{RequestID: 42, EvseID: newInt(0), ChargingProfile: validChargingProfileCriterion}, true},
 		{smartcharging.GetChargingProfilesRequest{RequestID: 42, ChargingProfile: validChargingProfileCriterion}, true},
 		{smartcharging.GetChargingProfilesRequest{EvseID: newInt(1), ChargingProfile: validChargingProfileCriterion}, true},
 		{smartcharging.GetChargingProfilesRequest{ChargingProfile: validChargingProfileCriterion}, true},
@@ -107,6 +108,55 @@ func (suite *OcppV2TestSuite) TestGetChargingProfilesE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) testGetChargingProfilesEvseScoped(evseID int, status smartcharging.GetChargingProfileStatus) {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	requestID := 42
+	chargingProfileCriterion := smartcharging.ChargingProfileCriterion{ChargingProfilePurpose: types.ChargingProfilePurposeTxDefaultProfile}
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"requestId":%v,"evseId":%v,"chargingProfile":{"chargingProfilePurpose":"%v"}}]`,
+		messageId, smartcharging.GetChargingProfilesFeatureName, requestID, evseID, chargingProfileCriterion.ChargingProfilePurpose)
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockChargingStationSmartChargingHandler{}
+	handler.On("OnGetChargingProfiles", mock.Anything).Return(smartcharging.NewGetChargingProfilesResponse(status), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(0).(*smartcharging.GetChargingProfilesRequest)
+		require.True(t, ok)
+		require.NotNil(t, request.EvseID)
+		assert.Equal(t, evseID, *request.EvseID)
+		assert.Equal(t, chargingProfileCriterion.ChargingProfilePurpose, request.ChargingProfile.ChargingProfilePurpose)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultChannel := make(chan bool, 1)
+	err = suite.csms.GetChargingProfiles(wsId, func(confirmation *smartcharging.GetChargingProfilesResponse, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, confirmation)
+		assert.Equal(t, status, confirmation.Status)
+		resultChannel <- true
+	}, chargingProfileCriterion, func(request *smartcharging.GetChargingProfilesRequest) {
+		request.EvseID = &evseID
+		request.RequestID = requestID
+	})
+	require.Nil(t, err)
+	result := <-resultChannel
+	assert.True(t, result)
+}
+
+func (suite *OcppV2TestSuite) TestGetChargingProfilesStationWide() {
+	suite.testGetChargingProfilesEvseScoped(0, smartcharging.GetChargingProfileStatusAccepted)
+}
+
+func (suite *OcppV2TestSuite) TestGetChargingProfilesNoProfiles() {
+	suite.testGetChargingProfilesEvseScoped(2, smartcharging.GetChargingProfileStatusNoProfiles)
+}
+
 func (suite *OcppV2TestSuite) TestGetChargingProfilesInvalidEndpoint() {
 	messageId := defaultMessageId
 	requestID := 42
@@ -33,6 +33,10 @@ func (suite *OcppV2TestSuite) TestGetLogRequestValidation() {
 		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeDiagnostics, RequestID: 1, Retries: newInt(-1), RetryInterval: newInt(120), Log: logParameters}, false},
 		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeDiagnostics, RequestID: 1, Retries: newInt(5), RetryInterval: newInt(-1), Log: logParameters}, false},
 		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeDiagnostics, RequestID: 1, Retries: newInt(5), RetryInterval: newInt(120), Log: diagnostics.LogParameters{RemoteLocation: ".invalidUrl.", OldestTimestamp: nil, LatestTimestamp: nil}}, false},
+		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeSecurity, RequestID: 1, Log: diagnostics.LogParameters{RemoteLocation: "ftp://someurl/security/1", OldestTimestamp: logParameters.OldestTimestamp}}, true},
+		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeSecurity, RequestID: 1, Log: diagnostics.LogParameters{RemoteLocation: "ftp://someurl/security/1", LatestTimestamp: logParameters.LatestTimestamp}}, true},
+		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeSecurity, RequestID: 1, Log: diagnostics.LogParameters{RemoteLocation: "ftp://someurl/security/1", OldestTimestamp: logParameters.LatestTimestamp, LatestTimestamp: logParameters.LatestTimestamp}}, true},
+		{diagnostics.GetLogRequest{LogType: diagnostics.LogTypeSecurity, RequestID: 1, Log: diagnostics.LogParameters{RemoteLocation: "ftp://someurl/security/1", OldestTimestamp: logParameters.LatestTimestamp, LatestTimestamp: logParameters.OldestTimestamp}}, false},
 	}
 	ExecuteGenericTestTable(t, requestTable)
 }
@@ -109,6 +113,82 @@ func (suite *OcppV2TestSuite) TestGetLogE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestGetLogSecurityLogStatusCorrelation() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	logParameters := diagnostics.LogParameters{
+		RemoteLocation:  "ftp://someurl/security/1",
+		OldestTimestamp: types.NewDateTime(time.Now().Add(-24 * time.Hour)),
+		LatestTimestamp: types.NewDateTime(time.Now().Add(-1 * time.Hour)),
+	}
+	logType := diagnostics.LogTypeSecurity
+	requestID := 42
+	expectedRequestID := requestID
+	channel := NewMockWebSocket(wsId)
+
+	chargingStationHandler := &MockChargingStationDiagnosticsHandler{}
+	chargingStationHandler.On("OnGetLog", mock.Anything).Return(diagnostics.NewGetLogResponse(diagnostics.LogStatusAccepted), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(0).(*diagnostics.GetLogRequest)
+		require.True(t, ok)
+		assert.Equal(t, logType, request.LogType)
+		assert.Equal(t, expectedRequestID, request.RequestID)
+		assertDateTimeEquality(t, logParameters.OldestTimestamp, request.Log.OldestTimestamp)
+		assertDateTimeEquality(t, logParameters.LatestTimestamp, request.Log.LatestTimestamp)
+	})
+	csmsHandler := &MockCSMSDiagnosticsHandler{}
+	csmsHandler.On("OnLogStatusNotification", mock.AnythingOfType("string"), mock.Anything).Return(diagnostics.NewLogStatusNotificationResponse(), nil).Run(func(args mock.Arguments) {
+		clientId := args.String(0)
+		notification := args.Get(1).(*diagnostics.LogStatusNotificationRequest)
+		// The original request is available while processing the notification
+		request, ok := suite.csms.PendingLogRequest(clientId, notification.RequestID)
+		require.True(t, ok)
+		assert.Equal(t, logType, request.LogType)
+		assert.Equal(t, logParameters.RemoteLocation, request.Log.RemoteLocation)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, csmsHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, chargingStationHandler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultChannel := make(chan bool, 1)
+	err = suite.csms.GetLog(wsId, func(response *diagnostics.GetLogResponse, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, response)
+		assert.Equal(t, diagnostics.LogStatusAccepted, response.Status)
+		resultChannel <- true
+	}, logType, requestID, logParameters)
+	require.Nil(t, err)
+	result := <-resultChannel
+	assert.True(t, result)
+	_, ok := suite.csms.PendingLogRequest(wsId, requestID)
+	assert.True(t, ok)
+	// Intermediate status keeps the request pending
+	_, err = suite.chargingStation.LogStatusNotification(diagnostics.UploadLogStatusUploading, requestID)
+	require.Nil(t, err)
+	_, ok = suite.csms.PendingLogRequest(wsId, requestID)
+	assert.True(t, ok)
+	// Final status completes the request
+	_, err = suite.chargingStation.LogStatusNotification(diagnostics.UploadLogStatusUploaded, requestID)
+	require.Nil(t, err)
+	_, ok = suite.csms.PendingLogRequest(wsId, requestID)
+	assert.False(t, ok)
+	csmsHandler.AssertNumberOfCalls(t, "OnLogStatusNotification", 2)
+	// Pending requests are forgotten once the charging station disconnects
+	expectedRequestID = requestID + 1
+	err = suite.csms.GetLog(wsId, func(response *diagnostics.GetLogResponse, err error) {
+		resultChannel <- err == nil
+	}, logType, requestID+1, logParameters)
+	require.Nil(t, err)
+	require.True(t, <-resultChannel)
+	_, ok = suite.csms.PendingLogRequest(wsId, requestID+1)
+	assert.True(t, ok)
+	suite.mockWsServer.DisconnectedClientHandler(channel)
+	_, ok = suite.csms.PendingLogRequest(wsId, requestID+1)
+	assert.False(t, ok)
+}
+
 func (suite *OcppV2TestSuite) TestGetLogInvalidEndpoint() {
 	messageId := defaultMessageId
 	logParameters := diagnostics.LogParameters{
@@ -1,6 +1,8 @@
 package ocpp2_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/stretchr/testify/assert"
@@ -46,6 +48,7 @@ func (suite *OcppV2TestSuite) TestGetVariablesConfirmationValidation() {
 		{provisioning.GetVariablesResponse{GetVariableResult: []provisioning.GetVariableResult{}}, false},
 		{provisioning.GetVariablesResponse{}, false},
 		{provisioning.GetVariablesResponse{GetVariableResult: []provisioning.GetVariableResult{{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeType: "invalidAttribute", AttributeValue: "dummyValue", Component: component, Variable: variable}}}, false},
+		{provisioning.GetVariablesResponse{GetVariableResult: []provisioning.GetVariableResult{{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeValue: "42", Component: component, Variable: variable, VariableCharacteristics: provisioning.NewVariableCharacteristics(provisioning.TypeInteger, false)}}}, true},
 		{provisioning.GetVariablesResponse{GetVariableResult: []provisioning.GetVariableResult{{AttributeStatus: "invalidStatus", AttributeType: types.AttributeTarget, AttributeValue: "dummyValue", Component: component, Variable: variable}}}, false},
 		{provisioning.GetVariablesResponse{GetVariableResult: []provisioning.GetVariableResult{{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeType: types.AttributeTarget, AttributeValue: ">1000....................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................................", Component: component, Variable: variable}}}, false},
 	}
@@ -120,6 +123,67 @@ func (suite *OcppV2TestSuite) TestGetVariablesE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestGetVariablesDefaultAttributeType() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	component := types.Component{Name: "OCPPCommCtrlr"}
+	variable := types.Variable{Name: "HeartbeatInterval"}
+	// The attribute type is omitted in the request, but set to Actual before sending
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"getVariableData":[{"attributeType":"Actual","component":{"name":"%v"},"variable":{"name":"%v"}},{"attributeType":"Target","component":{"name":"%v"},"variable":{"name":"%v"}}]}]`,
+		messageId, provisioning.GetVariablesFeatureName, component.Name, variable.Name, component.Name, variable.Name)
+	// The attribute type is omitted in the first result
+	responseJson := fmt.Sprintf(`[3,"%v",{"getVariableResult":[{"attributeStatus":"Accepted","attributeValue":"300","component":{"name":"%v"},"variable":{"name":"%v"}},{"attributeStatus":"Accepted","attributeType":"Target","attributeValue":"600","component":{"name":"%v"},"variable":{"name":"%v"}}]}]`,
+		messageId, component.Name, variable.Name, component.Name, variable.Name)
+	getVariablesResponse := provisioning.NewGetVariablesResponse([]provisioning.GetVariableResult{
+		{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeValue: "300", Component: component, Variable: variable},
+		{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeType: types.AttributeTarget, AttributeValue: "600", Component: component, Variable: variable},
+	})
+	channel := NewMockWebSocket(wsId)
+	handler := &MockChargingStationProvisioningHandler{}
+	handler.On("OnGetVariables", mock.Anything).Return(getVariablesResponse, nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(0).(*provisioning.GetVariablesRequest)
+		require.True(t, ok)
+		require.Len(t, request.GetVariableData, 2)
+		assert.Equal(t, types.AttributeActual, request.GetVariableData[0].AttributeType)
+		assert.Equal(t, types.AttributeTarget, request.GetVariableData[1].AttributeType)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	responseC := make(chan *provisioning.GetVariablesResponse, 1)
+	err = suite.csms.GetVariables(wsId, func(response *provisioning.GetVariablesResponse, err error) {
+		require.Nil(t, err)
+		responseC <- response
+	}, []provisioning.GetVariableData{
+		{Component: component, Variable: variable},
+		{AttributeType: types.AttributeTarget, Component: component, Variable: variable},
+	}, provisioning.WithDefaultAttributeTypes)
+	require.Nil(t, err)
+	response := <-responseC
+	require.NotNil(t, response)
+	// A result without attribute type is interpreted as Actual
+	result, ok := response.Result(component, variable, types.AttributeActual)
+	require.True(t, ok)
+	assert.Equal(t, "300", result.AttributeValue)
+	result, ok = response.Result(types.Component{Name: "ocppCommCtrlr"}, variable, "")
+	require.True(t, ok)
+	assert.Equal(t, "300", result.AttributeValue)
+	result, ok = response.Result(component, variable, types.AttributeTarget)
+	require.True(t, ok)
+	assert.Equal(t, "600", result.AttributeValue)
+	_, ok = response.Result(component, variable, types.AttributeMaxSet)
+	assert.False(t, ok)
+	assert.Equal(t, types.Attribute(""), response.GetVariableResult[0].AttributeType)
+	response.NormalizeAttributeTypes()
+	assert.Equal(t, types.AttributeActual, response.GetVariableResult[0].AttributeType)
+	assert.Equal(t, types.AttributeTarget, response.GetVariableResult[1].AttributeType)
+}
+
 func (suite *OcppV2TestSuite) TestGetVariablesInvalidEndpoint() {
 	messageId := defaultMessageId
 	attributeType := types.AttributeTarget
@@ -136,3 +200,62 @@ func (suite *OcppV2TestSuite) TestGetVariablesInvalidEndpoint() {
 
 	testUnsupportedRequestFromChargingStation(suite, getVariablesRequest, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestGetVariableResultTypedAccessors() {
+	t := suite.T()
+	component := types.Component{Name: "SmartChargingCtrlr"}
+	newResult := func(value string, dataType provisioning.DataType) provisioning.GetVariableResult {
+		result := provisioning.GetVariableResult{AttributeStatus: provisioning.GetVariableStatusAccepted, AttributeValue: value, Component: component, Variable: types.Variable{Name: "variable1"}}
+		if dataType != "" {
+			result.VariableCharacteristics = provisioning.NewVariableCharacteristics(dataType, false)
+		}
+		return result
+	}
+	// Integer
+	i, err := newResult("42", provisioning.TypeInteger).AsInt()
+	require.NoError(t, err)
+	assert.Equal(t, 42, i)
+	i, err = newResult("-3", "").AsInt()
+	require.NoError(t, err)
+	assert.Equal(t, -3, i)
+	_, err = newResult("4.2", provisioning.TypeInteger).AsInt()
+	assert.EqualError(t, err, `SmartChargingCtrlr.variable1: invalid integer value "4.2"`)
+	_, err = newResult("42", provisioning.TypeString).AsInt()
+	assert.EqualError(t, err, "SmartChargingCtrlr.variable1: declared data type string, cannot parse as integer")
+	// Boolean
+	b, err := newResult("true", provisioning.TypeBoolean).AsBool()
+	require.NoError(t, err)
+	assert.True(t, b)
+	b, err = newResult("False", "").AsBool()
+	require.NoError(t, err)
+	assert.False(t, b)
+	_, err = newResult("1", provisioning.TypeBoolean).AsBool()
+	assert.Error(t, err)
+	_, err = newResult("true", provisioning.TypeInteger).AsBool()
+	assert.Error(t, err)
+	// Decimal
+	d, err := newResult("22.5", provisioning.TypeDecimal).AsDecimal()
+	require.NoError(t, err)
+	assert.Equal(t, 22.5, d)
+	d, err = newResult("16", provisioning.TypeInteger).AsDecimal()
+	require.NoError(t, err)
+	assert.Equal(t, 16.0, d)
+	_, err = newResult("abc", provisioning.TypeDecimal).AsDecimal()
+	assert.Error(t, err)
+	_, err = newResult("1.5", provisioning.TypeBoolean).AsDecimal()
+	assert.Error(t, err)
+	// Unavailable values
+	result := newResult("", "")
+	result.AttributeStatus = provisioning.GetVariableStatusNotSupported
+	_, err = result.AsInt()
+	assert.True(t, errors.Is(err, provisioning.ErrAttributeNotSupported))
+	result.AttributeStatus = provisioning.GetVariableStatusUnknownVariable
+	_, err = result.AsBool()
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, provisioning.ErrAttributeNotSupported))
+	assert.EqualError(t, err, "SmartChargingCtrlr.variable1: no value available, attribute status UnknownVariable")
+	// Characteristics aren't part of the message
+	data, err := json.Marshal(newResult("42", provisioning.TypeInteger))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "variableCharacteristics")
+}
@@ -8,8 +8,12 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	ocpp2 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
 )
 
 // Test
@@ -59,6 +63,155 @@ func (suite *OcppV2TestSuite) TestHeartbeatE2EMocked() {
 	assertDateTimeEquality(t, currentTime, &response.CurrentTime)
 }
 
+// Sends a Heartbeat before and after booting the charging station, with the given boot order policy.
+// Returns the errors of both heartbeats and the error reported by the CSMS for the first heartbeat, if any.
+func testHeartbeatBeforeBoot(suite *OcppV2TestSuite, policy ocpp2.BootOrderPolicy) (beforeBootErr error, afterBootErr error, reportedErr error) {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	heartbeatC := make(chan bool, 2)
+	availabilityHandler := &MockCSMSAvailabilityHandler{}
+	availabilityHandler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil).Run(func(args mock.Arguments) {
+		heartbeatC <- true
+	})
+	provisioningHandler := &MockCSMSProvisioningHandler{}
+	provisioningHandler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 60, provisioning.RegistrationStatusAccepted), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, availabilityHandler, provisioningHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetBootOrderPolicy(policy)
+	errC := suite.csms.Errors()
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	_, beforeBootErr = suite.chargingStation.Heartbeat()
+	select {
+	case reportedErr = <-errC:
+	default:
+	}
+	_, err = suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.Nil(t, err)
+	_, afterBootErr = suite.chargingStation.Heartbeat()
+	// Only accepted heartbeats reach the handler
+	expectedHeartbeats := 2
+	if beforeBootErr != nil {
+		expectedHeartbeats = 1
+	}
+	assert.Len(t, heartbeatC, expectedHeartbeats)
+	return
+}
+
+func (suite *OcppV2TestSuite) TestHeartbeatBeforeBootAllowed() {
+	t := suite.T()
+	beforeBootErr, afterBootErr, reportedErr := testHeartbeatBeforeBoot(suite, ocpp2.BootOrderAllow)
+	assert.Nil(t, beforeBootErr)
+	assert.Nil(t, afterBootErr)
+	assert.Nil(t, reportedErr)
+}
+
+func (suite *OcppV2TestSuite) TestHeartbeatBeforeBootWarnOnly() {
+	t := suite.T()
+	beforeBootErr, afterBootErr, reportedErr := testHeartbeatBeforeBoot(suite, ocpp2.BootOrderWarnOnly)
+	assert.Nil(t, beforeBootErr)
+	assert.Nil(t, afterBootErr)
+	require.Error(t, reportedErr)
+	assert.Contains(t, reportedErr.Error(), "Heartbeat")
+}
+
+func (suite *OcppV2TestSuite) TestHeartbeatBeforeBootRejected() {
+	t := suite.T()
+	beforeBootErr, afterBootErr, reportedErr := testHeartbeatBeforeBoot(suite, ocpp2.BootOrderRequireBootFirst)
+	require.Error(t, beforeBootErr)
+	ocppErr, ok := beforeBootErr.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.SecurityError, ocppErr.Code)
+	assert.Nil(t, afterBootErr)
+	assert.Nil(t, reportedErr)
+}
+
+func (suite *OcppV2TestSuite) TestHeartbeatBootStateAcrossReconnects() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	availabilityHandler := &MockCSMSAvailabilityHandler{}
+	availabilityHandler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil)
+	provisioningHandler := &MockCSMSProvisioningHandler{}
+	provisioningHandler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 60, provisioning.RegistrationStatusPending), nil).Once()
+	provisioningHandler.On("OnBootNotification", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 60, provisioning.RegistrationStatusAccepted), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, availabilityHandler, provisioningHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetBootOrderPolicy(ocpp2.BootOrderRequireBootFirst)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	assertBootRequired := func(err error) {
+		require.Error(t, err)
+		ocppErr, ok := err.(*ocpp.Error)
+		require.True(t, ok)
+		assert.Equal(t, ocppj.SecurityError, ocppErr.Code)
+	}
+	// A pending boot doesn't allow other requests
+	response, err := suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.Nil(t, err)
+	assert.Equal(t, provisioning.RegistrationStatusPending, response.Status)
+	_, err = suite.chargingStation.Heartbeat()
+	assertBootRequired(err)
+	// An accepted boot does
+	response, err = suite.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.Nil(t, err)
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, response.Status)
+	_, err = suite.chargingStation.Heartbeat()
+	assert.Nil(t, err)
+	// The boot state is cleared after reconnecting
+	suite.mockWsServer.DisconnectedClientHandler(channel)
+	suite.mockWsServer.NewClientHandler(channel)
+	_, err = suite.chargingStation.Heartbeat()
+	assertBootRequired(err)
+}
+
+func (suite *OcppV2TestSuite) TestHeartbeatHandlerTimeout() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	timeout := 100 * time.Millisecond
+	channel := NewMockWebSocket(wsId)
+	handlerDone := make(chan bool, 1)
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil).Run(func(args mock.Arguments) {
+		time.Sleep(3 * timeout)
+		handlerDone <- true
+	}).Once()
+	handler.On("OnHeartbeat", mock.AnythingOfType("string"), mock.Anything).Return(availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetHandlerTimeout(availability.HeartbeatFeatureName, timeout)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// The slow handler times out
+	start := time.Now()
+	response, err := suite.chargingStation.Heartbeat()
+	elapsed := time.Since(start)
+	require.Error(t, err)
+	assert.Nil(t, response)
+	ocppErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.InternalError, ocppErr.Code)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(timeout))
+	assert.Less(t, int64(elapsed), int64(3*timeout))
+	// The late result of the handler is discarded, so it isn't mistaken for the response to the next request
+	<-handlerDone
+	time.Sleep(50 * time.Millisecond)
+	suite.csms.SetHandlerTimeout(availability.HeartbeatFeatureName, 0)
+	response, err = suite.chargingStation.Heartbeat()
+	require.Nil(t, err)
+	assert.NotNil(t, response)
+}
+
 func (suite *OcppV2TestSuite) TestHeartbeatInvalidEndpoint() {
 	messageId := defaultMessageId
 	heartbeatRequest := availability.NewHeartbeatRequest()
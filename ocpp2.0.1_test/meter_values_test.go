@@ -99,3 +99,107 @@ func (suite *OcppV2TestSuite) TestMeterValuesInvalidEndpoint() {
 		messageId, meter.MeterValuesFeatureName, evseId, timestamp.FormatTimestamp(), sampledValue.Value, sampledValue.Context, sampledValue.Measurand, sampledValue.Phase, sampledValue.Location, signedMeterValue.SignedMeterData, signedMeterValue.SigningMethod, signedMeterValue.EncodingMethod, signedMeterValue.PublicKey, unitOfMeasure.Unit, *unitOfMeasure.Multiplier)
 	testUnsupportedRequestFromCentralSystem(suite, meterValuesRequest, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestMeterValuesBatch() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	maxCount := 3
+	maxWait := 300 * time.Millisecond
+	channel := NewMockWebSocket(wsId)
+	batchC := make(chan []meter.MeterValuesRequest, 10)
+	handler := &MockCSMSMeterHandler{}
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetMeterValuesBatch(maxCount, maxWait, func(chargingStationID string, batch []meter.MeterValuesRequest) {
+		assert.Equal(t, wsId, chargingStationID)
+		batchC <- batch
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Send 7 messages: two full batches are delivered right away, the last message once the window expires
+	start := time.Now()
+	for evseID := 1; evseID <= 7; evseID++ {
+		meterValue := types.MeterValue{Timestamp: types.DateTime{Time: time.Now()}, SampledValue: []types.SampledValue{{Value: float64(evseID)}}}
+		response, err := suite.chargingStation.MeterValues(evseID, []types.MeterValue{meterValue})
+		require.Nil(t, err)
+		require.NotNil(t, response)
+	}
+	expectedEvseIDs := [][]int{{1, 2, 3}, {4, 5, 6}, {7}}
+	for i, expected := range expectedEvseIDs {
+		select {
+		case batch := <-batchC:
+			elapsed := time.Since(start)
+			if i < 2 {
+				assert.Less(t, int64(elapsed), int64(maxWait))
+			} else {
+				assert.GreaterOrEqual(t, int64(elapsed), int64(maxWait))
+			}
+			require.Len(t, batch, len(expected))
+			for j, request := range batch {
+				assert.Equal(t, expected[j], request.EvseID)
+				require.Len(t, request.MeterValue, 1)
+				assert.Equal(t, float64(expected[j]), request.MeterValue[0].SampledValue[0].Value)
+			}
+		case <-time.After(2 * maxWait):
+			t.Fatalf("timeout waiting for meter values batch %v", i)
+		}
+	}
+	handler.AssertNotCalled(t, "OnMeterValues", mock.Anything, mock.Anything)
+}
+
+func (suite *OcppV2TestSuite) TestMeterValuesBatchTimeWindow() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	maxWait := 200 * time.Millisecond
+	channel := NewMockWebSocket(wsId)
+	batchC := make(chan []meter.MeterValuesRequest, 10)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// No size limit: batches are only bounded by the time window
+	suite.csms.SetMeterValuesBatch(0, maxWait, func(chargingStationID string, batch []meter.MeterValuesRequest) {
+		batchC <- batch
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	sendMeterValues := func(count int) {
+		for i := 0; i < count; i++ {
+			meterValue := types.MeterValue{Timestamp: types.DateTime{Time: time.Now()}, SampledValue: []types.SampledValue{{Value: 1.0}}}
+			_, err := suite.chargingStation.MeterValues(1, []types.MeterValue{meterValue})
+			require.Nil(t, err)
+		}
+	}
+	start := time.Now()
+	sendMeterValues(20)
+	select {
+	case batch := <-batchC:
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(maxWait))
+		assert.Len(t, batch, 20)
+	case <-time.After(2 * maxWait):
+		t.Fatal("timeout waiting for meter values batch")
+	}
+	// A new window is opened by the next message
+	start = time.Now()
+	sendMeterValues(5)
+	select {
+	case batch := <-batchC:
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(maxWait))
+		assert.Len(t, batch, 5)
+	case <-time.After(2 * maxWait):
+		t.Fatal("timeout waiting for meter values batch")
+	}
+	// Disabling batching delivers pending messages right away
+	sendMeterValues(2)
+	suite.csms.SetMeterValuesBatch(0, 0, nil)
+	select {
+	case batch := <-batchC:
+		assert.Len(t, batch, 2)
+	case <-time.After(maxWait / 2):
+		t.Fatal("pending meter values batch wasn't delivered")
+	}
+}
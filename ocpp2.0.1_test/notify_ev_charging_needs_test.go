@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/smartcharging"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 )
 
@@ -158,6 +159,75 @@ func (suite *OcppV2TestSuite) TestNotifyEVChargingNeedsE2EMocked() {
 	assert.Equal(t, statusInfo.AdditionalInfo, response.StatusInfo.AdditionalInfo)
 }
 
+func (suite *OcppV2TestSuite) TestNotifyEVChargingNeedsProcessing() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	evseID := 1
+	transactionID := "tx1"
+	chargingNeeds := smartcharging.ChargingNeeds{
+		RequestedEnergyTransfer: smartcharging.EnergyTransferModeAC3Phase,
+		ACChargingParameters:    &smartcharging.ACChargingParameters{EnergyAmount: 20000, EVMinCurrent: 6, EVMaxCurrent: 16, EVMaxVoltage: 400},
+	}
+	channel := NewMockWebSocket(wsId)
+	resultC := make(chan *smartcharging.SetChargingProfileResponse, 1)
+	scheduler := func(chargingStationID string, request *smartcharging.NotifyEVChargingNeedsRequest, txID string) (*types.ChargingProfile, error) {
+		assert.Equal(t, wsId, chargingStationID)
+		assert.Equal(t, transactionID, txID)
+		// The profile is only computed after the response was written to the charging station,
+		// following the response to the TransactionEvent
+		suite.mockWsServer.AssertNumberOfCalls(t, "Write", 2)
+		limit := float64(request.ChargingNeeds.ACChargingParameters.EVMaxCurrent)
+		return types.NewChargingProfile(1, 0, types.ChargingProfilePurposeTxProfile, types.ChargingProfileKindRelative,
+			[]types.ChargingSchedule{*types.NewChargingSchedule(1, types.ChargingRateUnitAmperes, types.NewChargingSchedulePeriod(0, limit))}), nil
+	}
+	transactionsHandler := &MockCSMSTransactionsHandler{}
+	transactionsHandler.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactions.NewTransactionEventResponse(), nil)
+	csmsHandler := &MockCSMSSmartChargingHandler{}
+	csmsHandler.On("OnNotifyEVChargingNeeds", mock.AnythingOfType("string"), mock.Anything).Return(smartcharging.NewNotifyEVChargingNeedsResponse(smartcharging.EVChargingNeedsStatusProcessing), nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*smartcharging.NotifyEVChargingNeedsRequest)
+		response := suite.csms.ProcessChargingNeeds(args.String(0), request, scheduler, func(response *smartcharging.SetChargingProfileResponse, err error) {
+			require.Nil(t, err)
+			resultC <- response
+		})
+		assert.Equal(t, smartcharging.EVChargingNeedsStatusProcessing, response.Status)
+		// A slow handler must not let the scheduler run before the response is sent
+		time.Sleep(50 * time.Millisecond)
+	})
+	stationHandler := &MockChargingStationSmartChargingHandler{}
+	stationHandler.On("OnSetChargingProfile", mock.Anything).Return(smartcharging.NewSetChargingProfileResponse(smartcharging.ChargingProfileStatusAccepted), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*smartcharging.SetChargingProfileRequest)
+		assert.Equal(t, evseID, request.EvseID)
+		require.NotNil(t, request.ChargingProfile)
+		assert.Equal(t, transactionID, request.ChargingProfile.TransactionID)
+		assert.Equal(t, 16.0, request.ChargingProfile.ChargingSchedule[0].ChargingSchedulePeriod[0].Limit)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, csmsHandler, transactionsHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, stationHandler)
+	suite.csms.SetTransactionTracking(true)
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	_, err = suite.chargingStation.TransactionEvent(transactions.TransactionEventStarted, types.NewDateTime(time.Now()), transactions.TriggerReasonCablePluggedIn, 0,
+		transactions.Transaction{TransactionID: transactionID}, func(request *transactions.TransactionEventRequest) {
+			request.Evse = &types.EVSE{ID: evseID}
+		})
+	require.Nil(t, err)
+	response, err := suite.chargingStation.NotifyEVChargingNeeds(evseID, chargingNeeds)
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, smartcharging.EVChargingNeedsStatusProcessing, response.Status)
+	select {
+	case result := <-resultC:
+		require.NotNil(t, result)
+		assert.Equal(t, smartcharging.ChargingProfileStatusAccepted, result.Status)
+	case <-time.After(time.Second):
+		t.Fatal("charging profile wasn't sent")
+	}
+	stationHandler.AssertNumberOfCalls(t, "OnSetChargingProfile", 1)
+}
+
 func (suite *OcppV2TestSuite) TestNotifyEVChargingNeedsInvalidEndpoint() {
 	messageId := defaultMessageId
 	maxScheduleTuples := newInt(5)
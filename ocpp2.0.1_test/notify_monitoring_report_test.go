@@ -140,3 +140,101 @@ func (suite *OcppV2TestSuite) TestNotifyMonitoringReportInvalidEndpoint() {
 		messageId, diagnostics.NotifyMonitoringReportFeatureName, requestID, tbc, seqNo, generatedAt.FormatTimestamp(), monitoringData.Component.Name, monitoringData.Variable.Name, varMonitoring.ID, varMonitoring.Transaction, varMonitoring.Value, varMonitoring.Type, varMonitoring.Severity)
 	testUnsupportedRequestFromCentralSystem(suite, req, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestNotifyMonitoringReportAssembler() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	requestID := 42
+	generatedAt := types.NewDateTime(time.Now())
+	parts := [][]diagnostics.MonitoringData{
+		{
+			{Component: types.Component{Name: "EVSE", EVSE: &types.EVSE{ID: 1}}, Variable: types.Variable{Name: "Power"}, VariableMonitoring: []diagnostics.VariableMonitoring{
+				diagnostics.NewVariableMonitoring(1, false, 22000.0, diagnostics.MonitorUpperThreshold, 2),
+				diagnostics.NewVariableMonitoring(2, true, 500.0, diagnostics.MonitorDelta, 7),
+			}},
+		},
+		{
+			{Component: types.Component{Name: "ChargingStation"}, Variable: types.Variable{Name: "Temperature"}, VariableMonitoring: []diagnostics.VariableMonitoring{
+				diagnostics.NewVariableMonitoring(3, false, 60.0, diagnostics.MonitorPeriodic, 9),
+			}},
+		},
+	}
+	channel := NewMockWebSocket(wsId)
+	assembler := diagnostics.NewMonitoringReportAssembler()
+	doneC := assembler.Await(requestID)
+	handler := &MockCSMSDiagnosticsHandler{}
+	handler.On("OnNotifyMonitoringReport", mock.AnythingOfType("string"), mock.Anything).Return(diagnostics.NewNotifyMonitoringReportResponse(), nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*diagnostics.NotifyMonitoringReportRequest)
+		assembler.Add(request)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	for seqNo, part := range parts {
+		tbc := seqNo < len(parts)-1
+		_, err = suite.chargingStation.NotifyMonitoringReport(requestID, seqNo, generatedAt, part, func(request *diagnostics.NotifyMonitoringReportRequest) {
+			request.Tbc = tbc
+		})
+		require.Nil(t, err)
+		if tbc {
+			select {
+			case <-doneC:
+				t.Fatal("monitoring report delivered before all parts were received")
+			default:
+			}
+		}
+	}
+	var monitoringData []diagnostics.MonitoringData
+	select {
+	case monitoringData = <-doneC:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for assembled monitoring report")
+	}
+	require.Len(t, monitoringData, 2)
+	monitors := diagnostics.Monitors(monitoringData)
+	require.Len(t, monitors, 3)
+	expected := []diagnostics.ConfiguredMonitor{
+		{Component: parts[0][0].Component, Variable: parts[0][0].Variable, ID: 1, Type: diagnostics.MonitorUpperThreshold, Value: 22000.0, Severity: 2},
+		{Component: parts[0][0].Component, Variable: parts[0][0].Variable, ID: 2, Type: diagnostics.MonitorDelta, Value: 500.0, Severity: 7, Transaction: true},
+		{Component: parts[1][0].Component, Variable: parts[1][0].Variable, ID: 3, Type: diagnostics.MonitorPeriodic, Value: 60.0, Severity: 9},
+	}
+	assert.Equal(t, expected, monitors)
+	// Awaited reports aren't retained
+	assert.False(t, assembler.Pending(requestID))
+	_, ok := assembler.Completed(requestID)
+	assert.False(t, ok)
+	// A report completed before awaiting it is retained until it is collected
+	_, complete := assembler.Add(diagnostics.NewNotifyMonitoringReportRequest(requestID+1, 0, generatedAt, parts[1]))
+	require.True(t, complete)
+	assert.False(t, assembler.Pending(requestID+1))
+	monitoringData, ok = assembler.Completed(requestID + 1)
+	require.True(t, ok)
+	assert.Equal(t, parts[1], monitoringData)
+	select {
+	case assembled := <-assembler.Await(requestID + 1):
+		assert.Equal(t, parts[1], assembled)
+	default:
+		t.Fatal("completed monitoring report not delivered")
+	}
+	_, ok = assembler.Completed(requestID + 1)
+	assert.False(t, ok)
+	// Discarding an awaited report releases the waiting caller
+	discardedC := assembler.Await(requestID + 2)
+	partial := diagnostics.NewNotifyMonitoringReportRequest(requestID+2, 0, generatedAt, parts[0])
+	partial.Tbc = true
+	_, complete = assembler.Add(partial)
+	assert.False(t, complete)
+	assembler.Discard(requestID + 2)
+	select {
+	case assembled, ok := <-discardedC:
+		assert.False(t, ok)
+		assert.Nil(t, assembled)
+	default:
+		t.Fatal("channel of discarded monitoring report not closed")
+	}
+	assert.False(t, assembler.Pending(requestID+2))
+}
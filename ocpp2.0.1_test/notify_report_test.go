@@ -162,6 +162,203 @@ func (suite *OcppV2TestSuite) TestNotifyReportE2EMocked() {
 	require.NotNil(t, response)
 }
 
+func (suite *OcppV2TestSuite) TestVariableCharacteristicsCheck() {
+	t := suite.T()
+	component := types.Component{Name: "component1"}
+	variable := types.Variable{Name: "variable1"}
+	newReportData := func(value string, characteristics provisioning.VariableCharacteristics) provisioning.ReportData {
+		return provisioning.ReportData{Component: component, Variable: variable, VariableAttribute: []provisioning.VariableAttribute{{Value: value}}, VariableCharacteristics: &characteristics}
+	}
+	var testTable = []struct {
+		reportData provisioning.ReportData
+		warnings   int
+	}{
+		{newReportData("10", provisioning.VariableCharacteristics{DataType: provisioning.TypeInteger, MinLimit: newFloat(0), MaxLimit: newFloat(100)}), 0},
+		{newReportData("2.5", provisioning.VariableCharacteristics{DataType: provisioning.TypeDecimal, MinLimit: newFloat(2.5)}), 0},
+		{newReportData("Auto", provisioning.VariableCharacteristics{DataType: provisioning.TypeOptionList, ValuesList: "Auto,Manual"}), 0},
+		{newReportData("A,B", provisioning.VariableCharacteristics{DataType: provisioning.TypeMemberList, ValuesList: "A,B,C", MaxLimit: newFloat(10)}), 0},
+		{newReportData("true", provisioning.VariableCharacteristics{DataType: provisioning.TypeBoolean}), 0},
+		{provisioning.ReportData{Component: component, Variable: variable, VariableAttribute: []provisioning.VariableAttribute{{Value: "x"}}}, 0},
+		{newReportData("", provisioning.VariableCharacteristics{DataType: provisioning.TypeInteger, MinLimit: newFloat(10), MaxLimit: newFloat(1)}), 1},
+		{newReportData("50", provisioning.VariableCharacteristics{DataType: provisioning.TypeInteger, MinLimit: newFloat(100), MaxLimit: newFloat(10)}), 3},
+		{newReportData("101", provisioning.VariableCharacteristics{DataType: provisioning.TypeInteger, MaxLimit: newFloat(100)}), 1},
+		{newReportData("abc", provisioning.VariableCharacteristics{DataType: provisioning.TypeDecimal}), 1},
+		{newReportData("Auto", provisioning.VariableCharacteristics{DataType: provisioning.TypeOptionList}), 1},
+		{newReportData("Auto", provisioning.VariableCharacteristics{DataType: provisioning.TypeOptionList, ValuesList: "Auto,,Manual"}), 1},
+		{newReportData("Off", provisioning.VariableCharacteristics{DataType: provisioning.TypeOptionList, ValuesList: "Auto,Manual"}), 1},
+		{newReportData("x", provisioning.VariableCharacteristics{DataType: provisioning.TypeString, ValuesList: "x,y"}), 1},
+		{newReportData("true", provisioning.VariableCharacteristics{DataType: provisioning.TypeBoolean, MaxLimit: newFloat(1)}), 1},
+	}
+	for i, entry := range testTable {
+		warnings := entry.reportData.CheckCharacteristics()
+		assert.Len(t, warnings, entry.warnings, "entry %d: %v", i, warnings)
+		for _, warning := range warnings {
+			assert.Equal(t, component, warning.Component)
+			assert.Equal(t, variable, warning.Variable)
+		}
+	}
+}
+
+func (suite *OcppV2TestSuite) TestNotifyReportCharacteristicsWarnings() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	requestID := 42
+	conformant := provisioning.ReportData{
+		Component:               types.Component{Name: "OCPPCommCtrlr"},
+		Variable:                types.Variable{Name: "HeartbeatInterval"},
+		VariableAttribute:       []provisioning.VariableAttribute{{Value: "60"}},
+		VariableCharacteristics: &provisioning.VariableCharacteristics{DataType: provisioning.TypeInteger, MinLimit: newFloat(1), MaxLimit: newFloat(3600)},
+	}
+	nonconformant := provisioning.ReportData{
+		Component:               types.Component{Name: "SmartChargingCtrlr"},
+		Variable:                types.Variable{Name: "LimitChangeSignificance"},
+		VariableAttribute:       []provisioning.VariableAttribute{{Type: types.AttributeActual}},
+		VariableCharacteristics: &provisioning.VariableCharacteristics{DataType: provisioning.TypeDecimal, MinLimit: newFloat(100), MaxLimit: newFloat(0)},
+	}
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnNotifyReport", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewNotifyReportResponse(), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	warningC := make(chan []provisioning.CharacteristicsWarning, 2)
+	suite.csms.SetReportWarningHandler(func(chargingStationID string, reqID int, warnings []provisioning.CharacteristicsWarning) {
+		assert.Equal(t, wsId, chargingStationID)
+		assert.Equal(t, requestID, reqID)
+		warningC <- warnings
+	})
+	// Run test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Conformant report doesn't trigger any warning
+	_, err = suite.chargingStation.NotifyReport(requestID, types.NewDateTime(time.Now()), 0, func(request *provisioning.NotifyReportRequest) {
+		request.ReportData = []provisioning.ReportData{conformant}
+		request.Tbc = true
+	})
+	require.Nil(t, err)
+	assert.Len(t, warningC, 0)
+	// Nonconformant report is accepted, but triggers a warning
+	response, err := suite.chargingStation.NotifyReport(requestID, types.NewDateTime(time.Now()), 1, func(request *provisioning.NotifyReportRequest) {
+		request.ReportData = []provisioning.ReportData{conformant, nonconformant}
+	})
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	require.Len(t, warningC, 1)
+	warnings := <-warningC
+	require.Len(t, warnings, 1)
+	assert.Equal(t, nonconformant.Component, warnings[0].Component)
+	assert.Equal(t, nonconformant.Variable, warnings[0].Variable)
+	assert.Equal(t, "SmartChargingCtrlr.LimitChangeSignificance: minLimit 100 is greater than maxLimit 0", warnings[0].String())
+	handler.AssertNumberOfCalls(t, "OnNotifyReport", 2)
+}
+
+func (suite *OcppV2TestSuite) TestNotifyReportEVSEScopedComponents() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	requestID := 42
+	generatedAt := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	assembler := provisioning.NewReportAssembler()
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnNotifyReport", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewNotifyReportResponse(), nil).Run(func(args mock.Arguments) {
+		assembler.Add(args.Get(1).(*provisioning.NotifyReportRequest))
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	reportC := assembler.Await(requestID)
+	parts := []string{
+		`[{"component":{"name":"ChargingStation"},"variable":{"name":"AvailabilityState"},"variableAttribute":[{"value":"Available"}]},` +
+			`{"component":{"name":"EVSE","evse":{"id":1}},"variable":{"name":"AvailabilityState"},"variableAttribute":[{"value":"Occupied"}]},` +
+			`{"component":{"name":"Connector","evse":{"id":1,"connectorId":1}},"variable":{"name":"AvailabilityState"},"variableAttribute":[{"value":"Occupied"}]},` +
+			`{"component":{"name":"Connector","evse":{"id":1,"connectorId":2}},"variable":{"name":"AvailabilityState"},"variableAttribute":[{"value":"Unavailable"}]}]`,
+		`[{"component":{"name":"Connector","evse":{"id":2,"connectorId":1}},"variable":{"name":"AvailabilityState"},"variableAttribute":[{"value":"Available"}]},` +
+			`{"component":{"name":"Connector","evse":{"id":2,"connectorId":1}},"variable":{"name":"ConnectorType"},"variableAttribute":[{"value":"cType2"}]},` +
+			`{"component":{"name":"TemperatureSensor","instance":"Inlet","evse":{"id":2}},"variable":{"name":"Temperature"},"variableAttribute":[{"value":"21.5"},{"type":"MaxSet","value":"60"}]},` +
+			`{"component":{"name":"TemperatureSensor","instance":"Outlet","evse":{"id":2}},"variable":{"name":"Temperature"},"variableAttribute":[{"value":"35"}]}]`,
+	}
+	for seqNo, reportData := range parts {
+		requestJson := fmt.Sprintf(`[2,"%v","%v",{"requestId":%v,"generatedAt":"%v","tbc":%v,"seqNo":%v,"reportData":%v}]`,
+			seqNo, provisioning.NotifyReportFeatureName, requestID, generatedAt.FormatTimestamp(), seqNo == 0, seqNo, reportData)
+		err = suite.mockWsServer.MessageHandler(channel, []byte(requestJson))
+		require.Nil(t, err)
+	}
+	var report []provisioning.ReportData
+	select {
+	case report = <-reportC:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for report")
+	}
+	require.Len(t, report, 8)
+	availabilityState := types.Variable{Name: "AvailabilityState"}
+	// Connector scoped variables
+	data, ok := provisioning.FindVariable(report, types.Component{Name: "Connector", EVSE: &types.EVSE{ID: 1, ConnectorID: newInt(2)}}, availabilityState)
+	require.True(t, ok)
+	attribute, ok := data.Attribute(types.AttributeActual)
+	require.True(t, ok)
+	assert.Equal(t, "Unavailable", attribute.Value)
+	data, ok = provisioning.FindVariable(report, types.Component{Name: "connector", EVSE: &types.EVSE{ID: 2, ConnectorID: newInt(1)}}, types.Variable{Name: "connectorType"})
+	require.True(t, ok)
+	assert.Equal(t, "cType2", data.VariableAttribute[0].Value)
+	// EVSE and station scoped variables don't match connectors
+	data, ok = provisioning.FindVariable(report, types.Component{Name: "EVSE", EVSE: &types.EVSE{ID: 1}}, availabilityState)
+	require.True(t, ok)
+	assert.Equal(t, "Occupied", data.VariableAttribute[0].Value)
+	_, ok = provisioning.FindVariable(report, types.Component{Name: "Connector", EVSE: &types.EVSE{ID: 1}}, availabilityState)
+	assert.False(t, ok)
+	data, ok = provisioning.FindVariable(report, types.Component{Name: "ChargingStation"}, availabilityState)
+	require.True(t, ok)
+	assert.Equal(t, "Available", data.VariableAttribute[0].Value)
+	// Instanced components
+	data, ok = provisioning.FindVariable(report, types.Component{Name: "TemperatureSensor", Instance: "inlet", EVSE: &types.EVSE{ID: 2}}, types.Variable{Name: "Temperature"})
+	require.True(t, ok)
+	assert.Equal(t, "Inlet", data.Component.Instance)
+	attribute, ok = data.Attribute(types.AttributeMaxSet)
+	require.True(t, ok)
+	assert.Equal(t, "60", attribute.Value)
+	_, ok = data.Attribute(types.AttributeTarget)
+	assert.False(t, ok)
+	_, ok = provisioning.FindVariable(report, types.Component{Name: "TemperatureSensor", EVSE: &types.EVSE{ID: 2}}, types.Variable{Name: "Temperature"})
+	assert.False(t, ok)
+	assert.Len(t, provisioning.FindComponents(report, "TemperatureSensor"), 2)
+	// Filtering by EVSE and connector
+	assert.Len(t, provisioning.FilterByEVSE(report, 1, nil), 3)
+	assert.Len(t, provisioning.FilterByEVSE(report, 2, nil), 4)
+	assert.Len(t, provisioning.FilterByEVSE(report, 2, newInt(1)), 2)
+	assert.Empty(t, provisioning.FilterByEVSE(report, 3, nil))
+}
+
+func (suite *OcppV2TestSuite) TestNotifyReportMessageTimeout() {
+	t := suite.T()
+	messageTimeout := func(value string, attributeType types.Attribute) provisioning.ReportData {
+		return provisioning.ReportData{
+			Component:         types.Component{Name: "OCPPCommCtrlr"},
+			Variable:          types.Variable{Name: "MessageTimeout", Instance: "Default"},
+			VariableAttribute: []provisioning.VariableAttribute{{Type: attributeType, Value: value}},
+		}
+	}
+	timeout, ok := provisioning.MessageTimeout([]provisioning.ReportData{messageTimeout("45", types.AttributeActual)})
+	require.True(t, ok)
+	assert.Equal(t, 45*time.Second, timeout)
+	timeout, ok = provisioning.MessageTimeout([]provisioning.ReportData{messageTimeout("10", "")})
+	require.True(t, ok)
+	assert.Equal(t, 10*time.Second, timeout)
+	_, ok = provisioning.MessageTimeout([]provisioning.ReportData{messageTimeout("45", types.AttributeTarget)})
+	assert.False(t, ok)
+	_, ok = provisioning.MessageTimeout([]provisioning.ReportData{messageTimeout("0", types.AttributeActual)})
+	assert.False(t, ok)
+	_, ok = provisioning.MessageTimeout([]provisioning.ReportData{messageTimeout("abc", types.AttributeActual)})
+	assert.False(t, ok)
+	_, ok = provisioning.MessageTimeout(nil)
+	assert.False(t, ok)
+}
+
 func (suite *OcppV2TestSuite) TestNotifyReportInvalidEndpoint() {
 	messageId := defaultMessageId
 	generatedAt := types.NewDateTime(time.Now())
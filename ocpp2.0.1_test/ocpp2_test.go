@@ -1,14 +1,19 @@
 package ocpp2_test
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -59,6 +64,28 @@ func (websocket MockWebSocket) TLSConnectionState() *tls.ConnectionState {
 	return nil
 }
 
+func (websocket MockWebSocket) Set(key string, value interface{}) {
+}
+
+func (websocket MockWebSocket) Get(key string) (interface{}, bool) {
+	return nil, false
+}
+
+func (websocket MockWebSocket) Delete(key string) {
+}
+
+func (websocket MockWebSocket) Context() context.Context {
+	return context.Background()
+}
+
+func (websocket MockWebSocket) QueryParams() url.Values {
+	return url.Values{}
+}
+
+func (websocket MockWebSocket) Subprotocol() string {
+	return types.V201Subprotocol
+}
+
 func NewMockWebSocket(id string) MockWebSocket {
 	return MockWebSocket{id: id}
 }
@@ -69,6 +96,7 @@ type MockWebsocketServer struct {
 	mock.Mock
 	ws.WsServer
 	MessageHandler            func(ws ws.Channel, data []byte) error
+	BinaryMessageHandler      func(ws ws.Channel, data []byte) error
 	NewClientHandler          func(ws ws.Channel)
 	CheckClientHandler        ws.CheckClientHandler
 	DisconnectedClientHandler func(ws ws.Channel)
@@ -91,6 +119,15 @@ func (websocketServer *MockWebsocketServer) SetMessageHandler(handler func(ws ws
 	websocketServer.MessageHandler = handler
 }
 
+func (websocketServer *MockWebsocketServer) WriteBinary(webSocketId string, data []byte) error {
+	args := websocketServer.MethodCalled("WriteBinary", webSocketId, data)
+	return args.Error(0)
+}
+
+func (websocketServer *MockWebsocketServer) SetBinaryMessageHandler(handler func(ws ws.Channel, data []byte) error) {
+	websocketServer.BinaryMessageHandler = handler
+}
+
 func (websocketServer *MockWebsocketServer) SetNewClientHandler(handler func(ws ws.Channel)) {
 	websocketServer.NewClientHandler = handler
 }
@@ -115,10 +152,11 @@ func (websocketServer *MockWebsocketServer) SetCheckClientHandler(handler func(i
 type MockWebsocketClient struct {
 	mock.Mock
 	ws.WsClient
-	MessageHandler      func(data []byte) error
-	ReconnectedHandler  func()
-	DisconnectedHandler func(err error)
-	errC                chan error
+	MessageHandler       func(data []byte) error
+	BinaryMessageHandler func(data []byte) error
+	ReconnectedHandler   func()
+	DisconnectedHandler  func(err error)
+	errC                 chan error
 }
 
 func (websocketClient *MockWebsocketClient) Start(url string) error {
@@ -134,6 +172,15 @@ func (websocketClient *MockWebsocketClient) SetMessageHandler(handler func(data
 	websocketClient.MessageHandler = handler
 }
 
+func (websocketClient *MockWebsocketClient) WriteBinary(data []byte) error {
+	args := websocketClient.MethodCalled("WriteBinary", data)
+	return args.Error(0)
+}
+
+func (websocketClient *MockWebsocketClient) SetBinaryMessageHandler(handler func(data []byte) error) {
+	websocketClient.BinaryMessageHandler = handler
+}
+
 func (websocketClient *MockWebsocketClient) SetReconnectedHandler(handler func()) {
 	websocketClient.ReconnectedHandler = handler
 }
@@ -1160,6 +1207,86 @@ func (suite *OcppV2TestSuite) TestIsConnected() {
 	assert.False(t, suite.chargingStation.IsConnected())
 }
 
+func (suite *OcppV2TestSuite) TestCSMSStartWithContext() {
+	t := suite.T()
+	csms := ocpp2.NewCSMS(nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	resultC := make(chan error, 1)
+	go func() {
+		resultC <- csms.StartWithContext(ctx, 0, "/ws/{id}")
+	}()
+	// Cancel context, expecting the CSMS to shut down
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-resultC:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("CSMS didn't stop after context cancellation")
+	}
+}
+
+func (suite *OcppV2TestSuite) TestCSMSStartOnUnixListener() {
+	t := suite.T()
+	dir, err := os.MkdirTemp("", "ocpp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "csms.sock")
+	ln, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	csms := ocpp2.NewCSMS(nil, nil)
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnBootNotification", "station1", mock.Anything).Return(provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 60, provisioning.RegistrationStatusAccepted), nil)
+	csms.SetProvisioningHandler(handler)
+	resultC := make(chan error, 1)
+	go func() {
+		resultC <- csms.StartOnListener(ln, "/ws/{id}")
+	}()
+	// Charging station connects through the unix socket
+	wsClient := ws.NewClient()
+	wsClient.AddOption(func(dialer *websocket.Dialer) {
+		dialer.NetDial = func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		}
+	})
+	chargingStation := ocpp2.NewChargingStation("station1", nil, wsClient)
+	err = chargingStation.Start("ws://localhost/ws")
+	require.NoError(t, err)
+	response, err := chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, response.Status)
+	chargingStation.Stop()
+	csms.Stop()
+	select {
+	case err = <-resultC:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("CSMS didn't stop")
+	}
+}
+
+func (suite *OcppV2TestSuite) TestCSMSRegisteredFeatures() {
+	t := suite.T()
+	assert.Empty(t, suite.csms.RegisteredFeatures())
+	suite.csms.SetProvisioningHandler(&MockCSMSProvisioningHandler{})
+	suite.csms.SetSecurityHandler(&MockCSMSSecurityHandler{})
+	suite.csms.SetTransactionsHandler(&MockCSMSTransactionsHandler{})
+	// Profiles without CSMS-handled features are never reported
+	suite.csms.SetLocalAuthListHandler(&MockCSMSLocalAuthHandler{})
+	features := suite.csms.RegisteredFeatures()
+	assert.Equal(t, map[string][]string{
+		provisioning.ProfileName: {provisioning.BootNotificationFeatureName, provisioning.NotifyReportFeatureName},
+		security.ProfileName:     {security.SecurityEventNotificationFeatureName, security.SignCertificateFeatureName},
+		transactions.ProfileName: {transactions.TransactionEventFeatureName},
+	}, features)
+	// Unregistering a handler removes the profile
+	suite.csms.SetSecurityHandler(nil)
+	features = suite.csms.RegisteredFeatures()
+	assert.Len(t, features, 2)
+	assert.NotContains(t, features, security.ProfileName)
+}
+
 //TODO: implement generic protocol tests
 
 func TestOcpp2Protocol(t *testing.T) {
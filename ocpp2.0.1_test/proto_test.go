@@ -161,3 +161,32 @@ func (suite *OcppV2TestSuite) TestCentralSystemSendResponseError() {
 func (suite *OcppV2TestSuite) TestErrorCodes() {
 	suite.Equal(ocppj.FormatViolationV2, ocppj.FormatErrorType(suite.ocppjServer))
 }
+
+func (suite *OcppV2TestSuite) TestValidationErrorFormatter() {
+	t := suite.T()
+	wsId := "test_id"
+	channel := NewMockWebSocket(wsId)
+	ocppj.SetValidationErrorFormatter(ocppj.FieldConstraintFormatter)
+	defer ocppj.SetValidationErrorFormatter(nil)
+	writtenC := make(chan string, 1)
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writtenC <- string(args.Get(1).([]byte))
+	})
+	suite.csms.Start(8887, "somePath")
+	suite.mockWsServer.NewClientHandler(channel)
+	// Value exceeding the maximum length in a nested field
+	secretToken := "0123456789abcdef0123456789abcdef0123456789"
+	request := fmt.Sprintf(`[2,"1234","Authorize",{"idToken":{"idToken":"%v","type":"ISO14443"}}]`, secretToken)
+	err := suite.mockWsServer.MessageHandler(channel, []byte(request))
+	require.Error(t, err)
+	written := <-writtenC
+	assert.Equal(t, `[4,"1234","PropertyConstraintViolation","field 'idToken.idToken' failed 'max' constraint for feature Authorize",{}]`, written)
+	assert.NotContains(t, written, secretToken)
+	// Missing required field within a list
+	request = `[2,"5678","NotifyReport",{"requestId":1,"generatedAt":"2020-01-01T10:00:00Z","seqNo":0,"reportData":[{"component":{"name":"OCPPCommCtrlr"},"variable":{},"variableAttribute":[{"value":"1"}]}]}]`
+	err = suite.mockWsServer.MessageHandler(channel, []byte(request))
+	require.Error(t, err)
+	written = <-writtenC
+	assert.Equal(t, `[4,"5678","OccurrenceConstraintViolation","field 'reportData[0].variable.name' failed 'required' constraint for feature NotifyReport",{}]`, written)
+}
@@ -0,0 +1,114 @@
+package ocpp2_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+func (suite *OcppV2TestSuite) TestCSMSFlushQueue() {
+	t := suite.T()
+	wsId := "test_id"
+	requestsToSend := 3
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, defaultMessageId, provisioning.ResetStatusAccepted)
+	writeC := make(chan struct{}, requestsToSend)
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writeC <- struct{}{}
+		go func() {
+			err := suite.mockWsServer.MessageHandler(NewMockWebSocket(wsId), []byte(responseJson))
+			assert.Nil(t, err)
+		}()
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	suite.mockWsServer.NewClientHandler(NewMockWebSocket(wsId))
+	resultC := make(chan error, requestsToSend)
+	for i := 0; i < requestsToSend; i++ {
+		err := suite.csms.Reset(wsId, func(response *provisioning.ResetResponse, err error) {
+			resultC <- err
+		}, provisioning.ResetTypeOnIdle)
+		require.NoError(t, err)
+	}
+	err := suite.csms.FlushQueue(wsId)
+	require.NoError(t, err)
+	for i := 0; i < requestsToSend; i++ {
+		select {
+		case err = <-resultC:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for flushed requests")
+		}
+	}
+	assert.Len(t, writeC, requestsToSend)
+	// Flushing an unknown station fails
+	err = suite.csms.FlushQueue("unknownStation")
+	assert.Error(t, err)
+}
+
+func (suite *OcppV2TestSuite) TestCSMSDropQueue() {
+	t := suite.T()
+	wsId := "test_id"
+	requestsToSend := 3
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, defaultMessageId, provisioning.ResetStatusAccepted)
+	writeC := make(chan struct{}, requestsToSend)
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		// Don't respond right away, so further requests remain queued
+		writeC <- struct{}{}
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	suite.mockWsServer.NewClientHandler(NewMockWebSocket(wsId))
+	inFlightC := make(chan *provisioning.ResetResponse, 1)
+	err := suite.csms.Reset(wsId, func(response *provisioning.ResetResponse, err error) {
+		assert.NoError(t, err)
+		inFlightC <- response
+	}, provisioning.ResetTypeOnIdle)
+	require.NoError(t, err)
+	<-writeC
+	droppedC := make(chan error, requestsToSend)
+	for i := 1; i < requestsToSend; i++ {
+		err = suite.csms.Reset(wsId, func(response *provisioning.ResetResponse, err error) {
+			assert.Nil(t, response)
+			droppedC <- err
+		}, provisioning.ResetTypeImmediate)
+		require.NoError(t, err)
+	}
+	err = suite.csms.DropQueue(wsId)
+	require.NoError(t, err)
+	// All queued requests fail with a dropped error
+	for i := 1; i < requestsToSend; i++ {
+		select {
+		case err = <-droppedC:
+			require.Error(t, err)
+			ocppErr, ok := err.(*ocpp.Error)
+			require.True(t, ok)
+			assert.Equal(t, ocppj.GenericError, ocppErr.Code)
+			assert.Equal(t, "Request dropped", ocppErr.Description)
+			assert.Equal(t, defaultMessageId, ocppErr.MessageId)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for dropped requests")
+		}
+	}
+	// The in-flight request is still completed
+	err = suite.mockWsServer.MessageHandler(NewMockWebSocket(wsId), []byte(responseJson))
+	require.NoError(t, err)
+	select {
+	case response := <-inFlightC:
+		require.NotNil(t, response)
+		assert.Equal(t, provisioning.ResetStatusAccepted, response.Status)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for in-flight request")
+	}
+	// Dropped requests were never sent
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, writeC, 0)
+}
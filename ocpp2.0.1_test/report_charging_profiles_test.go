@@ -143,3 +143,93 @@ func (suite *OcppV2TestSuite) TestReportChargingProfilesInvalidEndpoint() {
 		messageId, smartcharging.ReportChargingProfilesFeatureName, requestID, chargingLimitSource, evseID, chargingProfile.ID, chargingProfile.StackLevel, chargingProfile.ChargingProfilePurpose, chargingProfile.ChargingProfileKind, chargingSchedule.StartSchedule.FormatTimestamp(), *chargingSchedule.Duration, chargingSchedule.ChargingRateUnit, *chargingSchedule.MinChargingRate, chargingSchedule.ChargingSchedulePeriod[0].StartPeriod, chargingSchedule.ChargingSchedulePeriod[0].Limit)
 	testUnsupportedRequestFromCentralSystem(suite, request, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestReportChargingProfilesInstalledProfiles() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	requestID := 42
+	newProfile := func(id int, stackLevel int, purpose types.ChargingProfilePurposeType) *types.ChargingProfile {
+		return types.NewChargingProfile(id, stackLevel, purpose, types.ChargingProfileKindAbsolute,
+			[]types.ChargingSchedule{*types.NewChargingSchedule(1, types.ChargingRateUnitWatts, types.NewChargingSchedulePeriod(0, 11000.0))})
+	}
+	channel := NewMockWebSocket(wsId)
+	stationHandler := &MockChargingStationSmartChargingHandler{}
+	stationHandler.On("OnSetChargingProfile", mock.Anything).Return(smartcharging.NewSetChargingProfileResponse(smartcharging.ChargingProfileStatusAccepted), nil)
+	stationHandler.On("OnClearChargingProfile", mock.Anything).Return(smartcharging.NewClearChargingProfileResponse(smartcharging.ClearChargingProfileStatusAccepted), nil)
+	stationHandler.On("OnGetChargingProfiles", mock.Anything).Return(smartcharging.NewGetChargingProfilesResponse(smartcharging.GetChargingProfileStatusAccepted), nil)
+	csmsHandler := &MockCSMSSmartChargingHandler{}
+	csmsHandler.On("OnReportChargingProfiles", mock.AnythingOfType("string"), mock.Anything).Return(smartcharging.NewReportChargingProfilesResponse(), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, csmsHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, stationHandler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	assert.Empty(t, suite.csms.InstalledProfiles(wsId))
+	resultC := make(chan struct{}, 1)
+	setProfile := func(evseID int, profile *types.ChargingProfile) {
+		err := suite.csms.SetChargingProfile(wsId, func(response *smartcharging.SetChargingProfileResponse, err error) {
+			require.Nil(t, err)
+			resultC <- struct{}{}
+		}, evseID, profile)
+		require.Nil(t, err)
+		<-resultC
+	}
+	setProfile(1, newProfile(1, 0, types.ChargingProfilePurposeTxDefaultProfile))
+	setProfile(0, newProfile(2, 0, types.ChargingProfilePurposeChargingStationMaxProfile))
+	profiles := suite.csms.InstalledProfiles(wsId)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, 0, profiles[0].EvseID)
+	assert.Equal(t, 2, profiles[0].Profile.ID)
+	assert.Equal(t, types.ChargingLimitSourceCSO, profiles[0].Source)
+	assert.Equal(t, 1, profiles[1].EvseID)
+	assert.Equal(t, 1, profiles[1].Profile.ID)
+	// A profile with the same ID overwrites the installed one
+	setProfile(1, newProfile(1, 3, types.ChargingProfilePurposeTxDefaultProfile))
+	profiles = suite.csms.InstalledProfiles(wsId)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, 3, profiles[1].Profile.StackLevel)
+	// Full report: profiles which aren't reported are removed
+	err = suite.csms.GetChargingProfiles(wsId, func(response *smartcharging.GetChargingProfilesResponse, err error) {
+		require.Nil(t, err)
+		resultC <- struct{}{}
+	}, smartcharging.ChargingProfileCriterion{}, func(request *smartcharging.GetChargingProfilesRequest) {
+		request.RequestID = requestID
+	})
+	require.Nil(t, err)
+	<-resultC
+	_, err = suite.chargingStation.ReportChargingProfiles(requestID, types.ChargingLimitSourceCSO, 1, []types.ChargingProfile{*newProfile(1, 3, types.ChargingProfilePurposeTxDefaultProfile)}, func(request *smartcharging.ReportChargingProfilesRequest) {
+		request.Tbc = true
+	})
+	require.NoError(t, err)
+	// Report is still incomplete
+	assert.Len(t, suite.csms.InstalledProfiles(wsId), 2)
+	_, err = suite.chargingStation.ReportChargingProfiles(requestID, types.ChargingLimitSourceEMS, 1, []types.ChargingProfile{*newProfile(3, 1, types.ChargingProfilePurposeTxDefaultProfile)})
+	require.NoError(t, err)
+	profiles = suite.csms.InstalledProfiles(wsId)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, 3, profiles[0].Profile.ID)
+	assert.Equal(t, types.ChargingLimitSourceEMS, profiles[0].Source)
+	assert.Equal(t, 1, profiles[1].Profile.ID)
+	// Clear by criteria
+	clearProfiles := func(props func(request *smartcharging.ClearChargingProfileRequest)) {
+		err := suite.csms.ClearChargingProfile(wsId, func(response *smartcharging.ClearChargingProfileResponse, err error) {
+			require.Nil(t, err)
+			resultC <- struct{}{}
+		}, props)
+		require.Nil(t, err)
+		<-resultC
+	}
+	clearProfiles(func(request *smartcharging.ClearChargingProfileRequest) {
+		request.ChargingProfileCriteria = &smartcharging.ClearChargingProfileType{EvseID: newInt(1), StackLevel: newInt(3)}
+	})
+	profiles = suite.csms.InstalledProfiles(wsId)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, 3, profiles[0].Profile.ID)
+	// Clear by ID
+	clearProfiles(func(request *smartcharging.ClearChargingProfileRequest) {
+		request.ChargingProfileID = newInt(3)
+	})
+	assert.Empty(t, suite.csms.InstalledProfiles(wsId))
+}
@@ -0,0 +1,88 @@
+package ocpp2_test
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/data"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+type capturedRequest struct {
+	conn     ocpp2.CapturedConnection
+	request  ocpp.Request
+	uniqueID string
+	action   string
+}
+
+func (suite *OcppV2TestSuite) TestCSMSRequestCapture() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	currentTime := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	availabilityHandler := &MockCSMSAvailabilityHandler{}
+	dataHandler := &MockCSMSDataHandler{}
+	dataHandler.On("OnDataTransfer", mock.AnythingOfType("string"), mock.Anything).Return(data.NewDataTransferResponse(data.DataTransferStatusAccepted), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, availabilityHandler, dataHandler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	capturedC := make(chan capturedRequest, 2)
+	suite.csms.SetRequestCaptureHandler(func(conn ocpp2.CapturedConnection, request ocpp.Request, uniqueID string, action string) bool {
+		if action == data.DataTransferFeatureName && request.(*data.DataTransferRequest).VendorID != "capture" {
+			// Processed by the data handler
+			return false
+		}
+		capturedC <- capturedRequest{conn: conn, request: request, uniqueID: uniqueID, action: action}
+		return true
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Capture a request and respond manually with a result
+	resultC := make(chan *availability.HeartbeatResponse, 1)
+	err = suite.chargingStation.SendRequestAsync(availability.NewHeartbeatRequest(), func(response ocpp.Response, err error) {
+		assert.Nil(t, err)
+		resultC <- response.(*availability.HeartbeatResponse)
+	})
+	require.Nil(t, err)
+	captured := <-capturedC
+	assert.Equal(t, wsId, captured.conn.ID())
+	assert.Equal(t, availability.HeartbeatFeatureName, captured.action)
+	assert.Equal(t, defaultMessageId, captured.uniqueID)
+	assert.IsType(t, &availability.HeartbeatRequest{}, captured.request)
+	err = captured.conn.SendResult(captured.uniqueID, availability.NewHeartbeatResponse(*currentTime))
+	require.NoError(t, err)
+	heartbeatResponse := <-resultC
+	assertDateTimeEquality(t, currentTime, &heartbeatResponse.CurrentTime)
+	availabilityHandler.AssertNotCalled(t, "OnHeartbeat", mock.Anything, mock.Anything)
+	// Capture a request and respond manually with a custom error
+	errC := make(chan error, 1)
+	err = suite.chargingStation.SendRequestAsync(data.NewDataTransferRequest("capture"), func(response ocpp.Response, err error) {
+		assert.Nil(t, response)
+		errC <- err
+	})
+	require.Nil(t, err)
+	captured = <-capturedC
+	assert.Equal(t, data.DataTransferFeatureName, captured.action)
+	err = captured.conn.SendError(captured.uniqueID, ocppj.SecurityError, "custom description", map[string]interface{}{"reason": "proxy"})
+	require.NoError(t, err)
+	err = <-errC
+	require.Error(t, err)
+	ocppErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.SecurityError, ocppErr.Code)
+	assert.Equal(t, "custom description", ocppErr.Description)
+	// Requests which aren't captured are processed as usual
+	response, err := suite.chargingStation.DataTransfer("vendor1")
+	require.Nil(t, err)
+	assert.Equal(t, data.DataTransferStatusAccepted, response.Status)
+	dataHandler.AssertNumberOfCalls(t, "OnDataTransfer", 1)
+}
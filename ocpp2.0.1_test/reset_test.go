@@ -1,14 +1,19 @@
 package ocpp2_test
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	ocpp2 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
 )
 
 // Test
@@ -84,6 +89,84 @@ func (suite *OcppV2TestSuite) TestResetE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestResetMalformedResponse() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	rawPayload := `{"status":42,"statusInfo":"accepted"}`
+	responseJson := fmt.Sprintf(`[3,"%v",%v]`, messageId, rawPayload)
+	channel := NewMockWebSocket(wsId)
+	writtenC := make(chan []byte, 2)
+	suite.mockWsServer.On("Write", wsId, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writtenC <- args.Get(1).([]byte)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultChannel := make(chan error, 1)
+	err = suite.csms.Reset(wsId, func(resp *provisioning.ResetResponse, err error) {
+		assert.Nil(t, resp)
+		resultChannel <- err
+	}, provisioning.ResetTypeImmediate)
+	require.Nil(t, err)
+	// Station returns a response not matching the ResetResponse type
+	<-writtenC
+	err = suite.mockWsServer.MessageHandler(channel, []byte(responseJson))
+	require.Error(t, err)
+	select {
+	case err = <-resultChannel:
+	case <-time.After(time.Second):
+		t.Fatal("callback wasn't invoked for malformed response")
+	}
+	var invalidResponse *ocppj.InvalidResponseError
+	require.True(t, errors.As(err, &invalidResponse))
+	assert.Equal(t, rawPayload, string(invalidResponse.RawPayload()))
+	assert.Equal(t, provisioning.ResetFeatureName, invalidResponse.Action)
+	var ocppErr *ocpp.Error
+	require.True(t, errors.As(err, &ocppErr))
+	assert.Equal(t, ocppj.FormatViolationV2, ocppErr.Code)
+	assert.Equal(t, messageId, ocppErr.MessageId)
+	// A CALLERROR is returned to the station
+	assert.Contains(t, string(<-writtenC), fmt.Sprintf(`[4,"%v","%v"`, messageId, ocppj.FormatViolationV2))
+}
+
+func (suite *OcppV2TestSuite) TestResetStationTimeout() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	err = suite.csms.SetStationTimeout(wsId, 100*time.Millisecond)
+	require.Nil(t, err)
+	resultChannel := make(chan error, 1)
+	startTime := time.Now()
+	err = suite.csms.Reset(wsId, func(resp *provisioning.ResetResponse, err error) {
+		assert.Nil(t, resp)
+		resultChannel <- err
+	}, provisioning.ResetTypeImmediate)
+	require.Nil(t, err)
+	// Station never responds, so the request times out according to the station timeout
+	select {
+	case err = <-resultChannel:
+	case <-time.After(time.Second):
+		t.Fatal("request didn't time out")
+	}
+	require.Error(t, err)
+	assert.Less(t, time.Since(startTime), time.Second)
+	var ocppErr *ocpp.Error
+	require.True(t, errors.As(err, &ocppErr))
+	assert.Equal(t, "Request timed out", ocppErr.Description)
+}
+
 func (suite *OcppV2TestSuite) TestResetInvalidEndpoint() {
 	messageId := defaultMessageId
 	resetType := provisioning.ResetTypeImmediate
@@ -95,3 +178,79 @@ func (suite *OcppV2TestSuite) TestResetInvalidEndpoint() {
 
 	testUnsupportedRequestFromChargingStation(suite, resetRequest, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestResetRetryAfterTimeout() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	status := provisioning.ResetStatusAccepted
+	channel := NewMockWebSocket(wsId)
+	handler := &MockChargingStationProvisioningHandler{}
+	handler.On("OnReset", mock.Anything).Return(provisioning.NewResetResponse(status), nil)
+	suite.csms.SetNewChargingStationHandler(func(chargingStation ocpp2.ChargingStationConnection) {
+		assert.Equal(t, wsId, chargingStation.ID())
+	})
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	// The first request is lost, the retry reaches the station
+	suite.mockWsServer.On("Write", wsId, mock.Anything).Return(nil).Once()
+	suite.mockWsServer.On("Write", wsId, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		err := suite.mockWsClient.MessageHandler(args.Get(1).([]byte))
+		assert.Nil(t, err)
+	})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	err = suite.csms.SetStationTimeout(wsId, 100*time.Millisecond)
+	require.Nil(t, err)
+	resultC := make(chan ocpp.Response, 1)
+	policy := ocpp2.RetryPolicy{MaxAttempts: 3, Backoff: ocpp2.ExponentialBackoff(10*time.Millisecond, time.Second)}
+	err = suite.csms.SendRequestWithRetry(wsId, provisioning.NewResetRequest(provisioning.ResetTypeImmediate), policy, func(response ocpp.Response, err error) {
+		require.Nil(t, err)
+		resultC <- response
+	})
+	require.Nil(t, err)
+	select {
+	case response := <-resultC:
+		resetResponse, ok := response.(*provisioning.ResetResponse)
+		require.True(t, ok)
+		assert.Equal(t, status, resetResponse.Status)
+	case <-time.After(time.Second):
+		t.Fatal("request wasn't retried")
+	}
+	suite.mockWsServer.AssertNumberOfCalls(t, "Write", 2)
+	handler.AssertNumberOfCalls(t, "OnReset", 1)
+}
+
+func (suite *OcppV2TestSuite) TestResetRetryNonRetryableError() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	handler := &MockChargingStationProvisioningHandler{}
+	handler.On("OnReset", mock.Anything).Return((*provisioning.ResetResponse)(nil), ocpp.NewHandlerError(ocppj.SecurityError, "not allowed"))
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultC := make(chan error, 1)
+	policy := ocpp2.RetryPolicy{MaxAttempts: 3, RetryableErrors: []ocpp.ErrorCode{ocppj.InternalError}}
+	err = suite.csms.SendRequestWithRetry(wsId, provisioning.NewResetRequest(provisioning.ResetTypeImmediate), policy, func(response ocpp.Response, err error) {
+		assert.Nil(t, response)
+		resultC <- err
+	})
+	require.Nil(t, err)
+	select {
+	case err = <-resultC:
+	case <-time.After(time.Second):
+		t.Fatal("callback wasn't invoked")
+	}
+	var ocppErr *ocpp.Error
+	require.True(t, errors.As(err, &ocppErr))
+	assert.Equal(t, ocppj.SecurityError, ocppErr.Code)
+	assert.False(t, ocpp2.IsRequestTimeout(err))
+	handler.AssertNumberOfCalls(t, "OnReset", 1)
+}
@@ -71,6 +71,60 @@ func (suite *OcppV2TestSuite) TestSecurityEventNotificationE2EMocked() {
 	require.NotNil(t, response)
 }
 
+func (suite *OcppV2TestSuite) TestSecurityEventNotificationEventTypes() {
+	t := suite.T()
+	testTable := []struct {
+		typ      string
+		standard bool
+		critical bool
+	}{
+		{security.SecurityEventFirmwareUpdated, true, true},
+		{security.SecurityEventStartupOfTheDevice, true, true},
+		{security.SecurityEventTamperDetectionActivated, true, true},
+		{security.SecurityEventFailedToAuthenticateAtCsms, true, false},
+		{security.SecurityEventInvalidTLSVersion, true, false},
+		{"VendorCabinetDoorOpened", false, false},
+	}
+	for _, tc := range testTable {
+		request := security.NewSecurityEventNotificationRequest(tc.typ, types.NewDateTime(time.Now()))
+		assert.NoError(t, types.Validate.Struct(request), tc.typ)
+		assert.Equal(t, tc.standard, request.IsStandard(), tc.typ)
+		assert.Equal(t, tc.critical, request.IsCritical(), tc.typ)
+	}
+}
+
+func (suite *OcppV2TestSuite) TestSecurityEventNotificationCustomTypeE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	typ := "VendorCabinetDoorOpened"
+	timestamp := types.NewDateTime(time.Now())
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"type":"%v","timestamp":"%v"}]`,
+		messageId, security.SecurityEventNotificationFeatureName, typ, timestamp.FormatTimestamp())
+	responseJson := fmt.Sprintf(`[3,"%v",{}]`, messageId)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSSecurityHandler{}
+	handler.On("OnSecurityEventNotification", mock.AnythingOfType("string"), mock.Anything).Return(security.NewSecurityEventNotificationResponse(), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(1).(*security.SecurityEventNotificationRequest)
+		require.True(t, ok)
+		require.NotNil(t, request)
+		assert.Equal(t, typ, request.Type)
+		assert.False(t, request.IsStandard())
+		assert.Empty(t, request.TechInfo)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	response, err := suite.chargingStation.SecurityEventNotification(typ, timestamp)
+	require.Nil(t, err)
+	require.NotNil(t, response)
+}
+
 func (suite *OcppV2TestSuite) TestSecurityEventNotificationInvalidEndpoint() {
 	messageId := defaultMessageId
 	typ := "type1"
@@ -3,6 +3,7 @@ package ocpp2_test
 import (
 	"fmt"
 
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/localauth"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 
@@ -111,6 +112,62 @@ func (suite *OcppV2TestSuite) TestSendLocalListE2EMocked() {
 	}
 }
 
+func (suite *OcppV2TestSuite) TestSendLocalListVerified() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	versionNumber := 5
+	entries := []localauth.AuthorizationData{{IdToken: types.IdToken{IdToken: "token1", Type: types.IdTokenTypeKeyCode}, IdTokenInfo: types.NewIdTokenInfo(types.AuthorizationStatusAccepted)}}
+	var testTable = []struct {
+		name            string
+		status          localauth.SendLocalListStatus
+		reportedVersion int
+		verified        bool
+	}{
+		{"applied", localauth.SendLocalListStatusAccepted, versionNumber, true},
+		{"accepted but not applied", localauth.SendLocalListStatusAccepted, versionNumber - 1, false},
+		{"rejected", localauth.SendLocalListStatusVersionMismatch, 0, false},
+	}
+	for _, tc := range testTable {
+		suite.SetupTest()
+		channel := NewMockWebSocket(wsId)
+		handler := &MockChargingStationLocalAuthHandler{}
+		handler.On("OnSendLocalList", mock.Anything).Return(localauth.NewSendLocalListResponse(tc.status), nil).Run(func(args mock.Arguments) {
+			request := args.Get(0).(*localauth.SendLocalListRequest)
+			assert.Equal(t, versionNumber, request.VersionNumber)
+			assert.Equal(t, localauth.UpdateTypeFull, request.UpdateType)
+			require.Len(t, request.LocalAuthorizationList, 1)
+			assert.Equal(t, "token1", request.LocalAuthorizationList[0].IdToken.IdToken)
+		})
+		handler.On("OnGetLocalListVersion", mock.Anything).Return(localauth.NewGetLocalListVersionResponse(tc.reportedVersion), nil)
+		setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+		setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+		// Run Test
+		suite.csms.Start(8887, "somePath")
+		err := suite.chargingStation.Start(wsUrl)
+		require.Nil(t, err)
+		resultC := make(chan ocpp2.LocalListVerificationResult, 1)
+		err = suite.csms.SendLocalListVerified(wsId, func(result ocpp2.LocalListVerificationResult, err error) {
+			assert.Nil(t, err, tc.name)
+			resultC <- result
+		}, versionNumber, localauth.UpdateTypeFull, entries)
+		require.Nil(t, err)
+		result := <-resultC
+		assert.Equal(t, wsId, result.ChargingStationID, tc.name)
+		assert.Equal(t, tc.status, result.Status, tc.name)
+		assert.Equal(t, versionNumber, result.ExpectedVersion, tc.name)
+		assert.Equal(t, tc.verified, result.Verified(), tc.name)
+		if tc.status == localauth.SendLocalListStatusAccepted {
+			require.NotNil(t, result.ReportedVersion, tc.name)
+			assert.Equal(t, tc.reportedVersion, *result.ReportedVersion, tc.name)
+			handler.AssertCalled(t, "OnGetLocalListVersion", mock.Anything)
+		} else {
+			assert.Nil(t, result.ReportedVersion, tc.name)
+			handler.AssertNotCalled(t, "OnGetLocalListVersion", mock.Anything)
+		}
+	}
+}
+
 func (suite *OcppV2TestSuite) TestSendLocalListInvalidEndpoint() {
 	messageId := defaultMessageId
 	versionNumber := 1
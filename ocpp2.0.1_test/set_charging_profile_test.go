@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	ocpp2 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/smartcharging"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 )
@@ -130,3 +132,250 @@ func (suite *OcppV2TestSuite) TestSetChargingProfileInvalidEndpoint() {
 	request := smartcharging.NewSetChargingProfileRequest(evseID, profile)
 	testUnsupportedRequestFromChargingStation(suite, request, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestSetChargingProfileValidateAgainstLimits() {
+	t := suite.T()
+	limits, err := smartcharging.NewChargingProfileLimits(map[string]string{
+		smartcharging.VariablePeriodsPerSchedule: "2",
+		smartcharging.VariableProfileStackLevel:  "3",
+		smartcharging.VariableRateUnit:           "A, W",
+		smartcharging.VariableEntries:            "4",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, limits)
+	assert.Equal(t, 2, *limits.PeriodsPerSchedule)
+	assert.Equal(t, 3, *limits.ProfileStackLevel)
+	assert.Equal(t, []types.ChargingRateUnitType{types.ChargingRateUnitAmperes, types.ChargingRateUnitWatts}, limits.RateUnits)
+	assert.Equal(t, 4, limits.InstalledProfiles)
+	maxProfiles := 5
+	limits.MaxProfiles = &maxProfiles
+	// Profile within limits
+	schedule := types.NewChargingSchedule(1, types.ChargingRateUnitWatts, types.NewChargingSchedulePeriod(0, 200.0), types.NewChargingSchedulePeriod(600, 100.0))
+	profile := types.NewChargingProfile(1, 3, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute, []types.ChargingSchedule{*schedule})
+	err = smartcharging.ValidateProfileAgainstLimits(profile, *limits)
+	assert.NoError(t, err)
+	// Profile exceeding the period count
+	schedule = types.NewChargingSchedule(2, types.ChargingRateUnitAmperes, types.NewChargingSchedulePeriod(0, 16.0), types.NewChargingSchedulePeriod(600, 10.0), types.NewChargingSchedulePeriod(1200, 6.0))
+	profile = types.NewChargingProfile(2, 0, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute, []types.ChargingSchedule{*schedule})
+	err = smartcharging.ValidateProfileAgainstLimits(profile, *limits)
+	require.Error(t, err)
+	limitsErr, ok := err.(*smartcharging.ChargingProfileLimitsError)
+	require.True(t, ok)
+	assert.Equal(t, 2, limitsErr.ProfileID)
+	require.Len(t, limitsErr.Violations, 1)
+	assert.Equal(t, "chargingSchedule[0] contains 3 periods, maximum is 2", limitsErr.Violations[0])
+	// Profile violating multiple limits
+	limits.RateUnits = []types.ChargingRateUnitType{types.ChargingRateUnitAmperes}
+	limits.InstalledProfiles = maxProfiles
+	schedule = types.NewChargingSchedule(3, types.ChargingRateUnitWatts, types.NewChargingSchedulePeriod(0, 200.0))
+	profile = types.NewChargingProfile(3, 4, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute, []types.ChargingSchedule{*schedule})
+	err = smartcharging.ValidateProfileAgainstLimits(profile, *limits)
+	require.Error(t, err)
+	limitsErr, ok = err.(*smartcharging.ChargingProfileLimitsError)
+	require.True(t, ok)
+	assert.Len(t, limitsErr.Violations, 3)
+	// Invalid variable value
+	_, err = smartcharging.NewChargingProfileLimits(map[string]string{smartcharging.VariablePeriodsPerSchedule: "many"})
+	assert.Error(t, err)
+}
+
+func (suite *OcppV2TestSuite) TestSetChargingProfileValidateAgainstCapabilities() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	requestID := 42
+	generatedAt := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	reportC := make(chan struct{}, 1)
+	handler := &MockCSMSProvisioningHandler{}
+	handler.On("OnNotifyReport", mock.AnythingOfType("string"), mock.Anything).Return(provisioning.NewNotifyReportResponse(), nil).Run(func(args mock.Arguments) {
+		reportC <- struct{}{}
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	_, ok := suite.csms.Capabilities(wsId)
+	assert.False(t, ok)
+	profile := types.NewChargingProfile(1, 0, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute,
+		[]types.ChargingSchedule{*types.NewChargingSchedule(1, types.ChargingRateUnitAmperes, types.NewChargingSchedulePeriod(0, 32.0))})
+	// Without known capabilities, any profile is accepted
+	assert.NoError(t, suite.csms.ValidateChargingProfile(wsId, 1, profile))
+	reportData := `[{"component":{"name":"ChargingStation"},"variable":{"name":"Power"},"variableAttribute":[{"value":"0"}],"variableCharacteristics":{"unit":"W","dataType":"decimal","maxLimit":22000,"supportsMonitoring":false}},` +
+		`{"component":{"name":"ChargingStation"},"variable":{"name":"SupplyPhases"},"variableAttribute":[{"value":"3"}]},` +
+		`{"component":{"name":"EVSE","evse":{"id":1}},"variable":{"name":"Current"},"variableAttribute":[{"value":"0"}],"variableCharacteristics":{"unit":"A","dataType":"decimal","maxLimit":16,"supportsMonitoring":false}},` +
+		`{"component":{"name":"EVSE","evse":{"id":2}},"variable":{"name":"SupplyPhases"},"variableAttribute":[{"value":"1"}]},` +
+		`{"component":{"name":"SmartChargingCtrlr"},"variable":{"name":"RateUnit"},"variableAttribute":[{"value":"A,W"}]},` +
+		`{"component":{"name":"SmartChargingCtrlr"},"variable":{"name":"Entries","instance":"ChargingProfiles"},"variableAttribute":[{"value":"2"}],"variableCharacteristics":{"dataType":"integer","maxLimit":10,"supportsMonitoring":false}},` +
+		`{"component":{"name":"SmartChargingCtrlr"},"variable":{"name":"PeriodsPerSchedule"},"variableAttribute":[{"value":"many"}]}]`
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"requestId":%v,"generatedAt":"%v","tbc":false,"seqNo":0,"reportData":%v}]`,
+		"1234", provisioning.NotifyReportFeatureName, requestID, generatedAt.FormatTimestamp(), reportData)
+	err = suite.mockWsServer.MessageHandler(channel, []byte(requestJson))
+	require.Nil(t, err)
+	select {
+	case <-reportC:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for report")
+	}
+	capabilities, ok := suite.csms.Capabilities(wsId)
+	require.True(t, ok)
+	assert.Equal(t, wsId, capabilities.ChargingStationID)
+	require.NotNil(t, capabilities.Station.MaxPower)
+	assert.Equal(t, 22000.0, *capabilities.Station.MaxPower)
+	require.NotNil(t, capabilities.Station.Phases)
+	assert.Equal(t, 3, *capabilities.Station.Phases)
+	require.Len(t, capabilities.EVSEs, 2)
+	require.NotNil(t, capabilities.EVSEs[1].MaxCurrent)
+	assert.Equal(t, 16.0, *capabilities.EVSEs[1].MaxCurrent)
+	assert.Equal(t, []types.ChargingRateUnitType{types.ChargingRateUnitAmperes, types.ChargingRateUnitWatts}, capabilities.ProfileLimits.RateUnits)
+	assert.Equal(t, 2, capabilities.ProfileLimits.InstalledProfiles)
+	require.NotNil(t, capabilities.ProfileLimits.MaxProfiles)
+	assert.Equal(t, 10, *capabilities.ProfileLimits.MaxProfiles)
+	// Unparseable values are ignored
+	assert.Nil(t, capabilities.ProfileLimits.PeriodsPerSchedule)
+	// EVSE limits fall back to the station limits
+	limits := capabilities.LimitsFor(2)
+	require.NotNil(t, limits.MaxPhases)
+	assert.Equal(t, 1, *limits.MaxPhases)
+	require.NotNil(t, limits.MaxPower)
+	assert.Equal(t, 22000.0, *limits.MaxPower)
+	assert.Nil(t, limits.MaxCurrent)
+	// Profile exceeding the maximum current of EVSE 1
+	err = suite.csms.ValidateChargingProfile(wsId, 1, profile)
+	require.Error(t, err)
+	limitsErr, ok := err.(*smartcharging.ChargingProfileLimitsError)
+	require.True(t, ok)
+	require.Len(t, limitsErr.Violations, 1)
+	assert.Equal(t, "chargingSchedule[0].chargingSchedulePeriod[0] limit 32A exceeds maximum 16A", limitsErr.Violations[0])
+	// Same profile is valid on EVSE 2, which has no current limit
+	assert.NoError(t, suite.csms.ValidateChargingProfile(wsId, 2, profile))
+	// Profile exceeding the number of phases of EVSE 2
+	period := types.NewChargingSchedulePeriod(0, 11000.0)
+	period.NumberPhases = newInt(3)
+	profile = types.NewChargingProfile(2, 0, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute,
+		[]types.ChargingSchedule{*types.NewChargingSchedule(2, types.ChargingRateUnitWatts, period)})
+	assert.NoError(t, suite.csms.ValidateChargingProfile(wsId, 0, profile))
+	err = suite.csms.ValidateChargingProfile(wsId, 2, profile)
+	require.Error(t, err)
+	limitsErr, ok = err.(*smartcharging.ChargingProfileLimitsError)
+	require.True(t, ok)
+	require.Len(t, limitsErr.Violations, 1)
+	assert.Equal(t, "chargingSchedule[0].chargingSchedulePeriod[0] uses 3 phases, maximum is 1", limitsErr.Violations[0])
+	// Returned capabilities are a copy
+	capabilities.EVSEs[3] = ocpp2.ElectricalCapabilities{}
+	capabilities, _ = suite.csms.Capabilities(wsId)
+	assert.Len(t, capabilities.EVSEs, 2)
+}
+
+func (suite *OcppV2TestSuite) TestResolveScheduleAbsolute() {
+	t := suite.T()
+	startSchedule := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	schedule := types.NewChargingSchedule(1, types.ChargingRateUnitWatts,
+		types.NewChargingSchedulePeriod(0, 11000.0),
+		types.NewChargingSchedulePeriod(3600, 7400.0),
+		types.NewChargingSchedulePeriod(7200, 3700.0))
+	schedule.StartSchedule = types.NewDateTime(startSchedule)
+	schedule.Duration = newInt(3 * 3600)
+	profile := types.NewChargingProfile(1, 0, types.ChargingProfilePurposeTxDefaultProfile, types.ChargingProfileKindAbsolute, []types.ChargingSchedule{*schedule})
+	// Window covers the whole schedule
+	points := smartcharging.ResolveSchedule(profile, startSchedule.Add(-time.Hour), startSchedule.Add(5*time.Hour))
+	require.Len(t, points, 3)
+	assert.Equal(t, smartcharging.SchedulePoint{Start: startSchedule, End: startSchedule.Add(time.Hour), Limit: 11000.0, ChargingRateUnit: types.ChargingRateUnitWatts}, points[0])
+	assert.Equal(t, startSchedule.Add(time.Hour), points[1].Start)
+	assert.Equal(t, 7400.0, points[1].Limit)
+	assert.Equal(t, startSchedule.Add(2*time.Hour), points[2].Start)
+	assert.Equal(t, startSchedule.Add(3*time.Hour), points[2].End)
+	assert.Equal(t, 3700.0, points[2].Limit)
+	// Window starts within the second period and ends within the third one
+	points = smartcharging.ResolveSchedule(profile, startSchedule.Add(90*time.Minute), startSchedule.Add(150*time.Minute))
+	require.Len(t, points, 2)
+	assert.Equal(t, startSchedule.Add(90*time.Minute), points[0].Start)
+	assert.Equal(t, 7400.0, points[0].Limit)
+	assert.Equal(t, startSchedule.Add(150*time.Minute), points[1].End)
+	// Window outside of the schedule
+	assert.Empty(t, smartcharging.ResolveSchedule(profile, startSchedule.Add(4*time.Hour), startSchedule.Add(5*time.Hour)))
+	// validTo restricts the schedule
+	profile.ValidTo = types.NewDateTime(startSchedule.Add(30 * time.Minute))
+	points = smartcharging.ResolveSchedule(profile, startSchedule, startSchedule.Add(5*time.Hour))
+	require.Len(t, points, 1)
+	assert.Equal(t, startSchedule.Add(30*time.Minute), points[0].End)
+	// Absolute schedule requires a start
+	profile.ChargingSchedule[0].StartSchedule = nil
+	assert.Nil(t, smartcharging.ResolveSchedule(profile, startSchedule, startSchedule.Add(5*time.Hour)))
+}
+
+func (suite *OcppV2TestSuite) TestResolveScheduleRelative() {
+	t := suite.T()
+	transactionStart := time.Date(2021, 6, 1, 18, 30, 0, 0, time.UTC)
+	schedule := types.NewChargingSchedule(1, types.ChargingRateUnitAmperes,
+		types.NewChargingSchedulePeriod(0, 32.0),
+		types.NewChargingSchedulePeriod(1800, 16.0))
+	schedule.ChargingSchedulePeriod[1].NumberPhases = newInt(1)
+	// Start schedule is ignored for relative profiles
+	schedule.StartSchedule = types.NewDateTime(transactionStart.Add(-24 * time.Hour))
+	profile := types.NewChargingProfile(1, 0, types.ChargingProfilePurposeTxProfile, types.ChargingProfileKindRelative, []types.ChargingSchedule{*schedule})
+	points := smartcharging.ResolveSchedule(profile, transactionStart, transactionStart.Add(2*time.Hour))
+	require.Len(t, points, 2)
+	assert.Equal(t, transactionStart, points[0].Start)
+	assert.Equal(t, transactionStart.Add(30*time.Minute), points[0].End)
+	assert.Equal(t, 32.0, points[0].Limit)
+	assert.Nil(t, points[0].NumberPhases)
+	assert.Equal(t, types.ChargingRateUnitAmperes, points[0].ChargingRateUnit)
+	// Last period lasts until the end of the window, as no duration is set
+	assert.Equal(t, transactionStart.Add(30*time.Minute), points[1].Start)
+	assert.Equal(t, transactionStart.Add(2*time.Hour), points[1].End)
+	assert.Equal(t, 16.0, points[1].Limit)
+	require.NotNil(t, points[1].NumberPhases)
+	assert.Equal(t, 1, *points[1].NumberPhases)
+}
+
+func (suite *OcppV2TestSuite) TestResolveScheduleRecurring() {
+	t := suite.T()
+	// Reduced power every night from 22:00 to 06:00
+	startSchedule := time.Date(2021, 6, 1, 22, 0, 0, 0, time.UTC)
+	schedule := types.NewChargingSchedule(1, types.ChargingRateUnitWatts,
+		types.NewChargingSchedulePeriod(0, 3700.0),
+		types.NewChargingSchedulePeriod(8*3600, 11000.0))
+	schedule.StartSchedule = types.NewDateTime(startSchedule)
+	profile := types.NewChargingProfile(1, 0, types.ChargingProfilePurposeChargingStationMaxProfile, types.ChargingProfileKindRecurring, []types.ChargingSchedule{*schedule})
+	profile.RecurrencyKind = types.RecurrencyKindDaily
+	// Window from noon of the third day to noon of the fifth day
+	from := startSchedule.Add(2*24*time.Hour - 10*time.Hour)
+	to := from.Add(48 * time.Hour)
+	points := smartcharging.ResolveSchedule(profile, from, to)
+	require.Len(t, points, 5)
+	expected := []struct {
+		start time.Time
+		end   time.Time
+		limit float64
+	}{
+		{from, from.Add(10 * time.Hour), 11000.0},
+		{from.Add(10 * time.Hour), from.Add(18 * time.Hour), 3700.0},
+		{from.Add(18 * time.Hour), from.Add(34 * time.Hour), 11000.0},
+		{from.Add(34 * time.Hour), from.Add(42 * time.Hour), 3700.0},
+		{from.Add(42 * time.Hour), to, 11000.0},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.start, points[i].Start, "point %v", i)
+		assert.Equal(t, e.end, points[i].End, "point %v", i)
+		assert.Equal(t, e.limit, points[i].Limit, "point %v", i)
+	}
+	// A duration restricts every occurrence
+	profile.ChargingSchedule[0].Duration = newInt(8 * 3600)
+	points = smartcharging.ResolveSchedule(profile, from, to)
+	require.Len(t, points, 2)
+	assert.Equal(t, from.Add(10*time.Hour), points[0].Start)
+	assert.Equal(t, from.Add(18*time.Hour), points[0].End)
+	assert.Equal(t, from.Add(34*time.Hour), points[1].Start)
+	assert.Equal(t, from.Add(42*time.Hour), points[1].End)
+	// Weekly recurrence
+	profile.RecurrencyKind = types.RecurrencyKindWeekly
+	points = smartcharging.ResolveSchedule(profile, startSchedule, startSchedule.Add(14*24*time.Hour))
+	require.Len(t, points, 2)
+	assert.Equal(t, startSchedule, points[0].Start)
+	assert.Equal(t, startSchedule.Add(7*24*time.Hour), points[1].Start)
+	// Schedule doesn't apply before its start
+	assert.Empty(t, smartcharging.ResolveSchedule(profile, startSchedule.Add(-48*time.Hour), startSchedule))
+}
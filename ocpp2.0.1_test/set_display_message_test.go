@@ -19,6 +19,12 @@ func (suite *OcppV2TestSuite) TestSetDisplayMessageRequestValidation() {
 		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityAlwaysFront, State: display.MessageStateIdle, StartDateTime: types.NewDateTime(time.Now()), Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}}}, true},
 		{display.SetDisplayMessageRequest{}, false},
 		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: "invalidPriority", State: display.MessageStateIdle, StartDateTime: types.NewDateTime(time.Now()), Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}}}, false},
+		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityInFront, TransactionID: "1234", Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}, Display: &types.Component{Name: "Display", Instance: "front"}}}, true},
+		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityInFront, TransactionID: ">36..................................", Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}}}, false},
+		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityInFront, Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}, Display: &types.Component{}}}, false},
+		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityNormalCycle, StartDateTime: types.NewDateTime(time.Now()), EndDateTime: types.NewDateTime(time.Now().Add(time.Hour)), Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}}}, true},
+		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityNormalCycle, EndDateTime: types.NewDateTime(time.Now().Add(time.Hour)), Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}}}, true},
+		{display.SetDisplayMessageRequest{Message: display.MessageInfo{ID: 42, Priority: display.MessagePriorityNormalCycle, StartDateTime: types.NewDateTime(time.Now().Add(time.Hour)), EndDateTime: types.NewDateTime(time.Now()), Message: types.MessageContent{Format: types.MessageFormatUTF8, Content: "hello world"}}}, false},
 	}
 	ExecuteGenericTestTable(t, requestTable)
 }
@@ -96,6 +102,83 @@ func (suite *OcppV2TestSuite) TestSetDisplayMessageE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestSetDisplayMessageTransactionScoped() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	message := display.MessageInfo{
+		ID:            43,
+		Priority:      display.MessagePriorityInFront,
+		TransactionID: "tx1234",
+		Message: types.MessageContent{
+			Format:  types.MessageFormatUTF8,
+			Content: "current cost: 4.20 EUR",
+		},
+		Display: &types.Component{Name: "Display", Instance: "front"},
+	}
+	status := display.DisplayMessageStatusAccepted
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"message":{"id":%v,"priority":"%v","transactionId":"%v","message":{"format":"%v","content":"%v"},"display":{"name":"%v","instance":"%v"}}}]`,
+		messageId, display.SetDisplayMessageFeatureName, message.ID, message.Priority, message.TransactionID, message.Message.Format, message.Message.Content, message.Display.Name, message.Display.Instance)
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockChargingStationDisplayHandler{}
+	handler.On("OnSetDisplayMessage", mock.Anything).Return(display.NewSetDisplayMessageResponse(status), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(0).(*display.SetDisplayMessageRequest)
+		require.True(t, ok)
+		require.NotNil(t, request)
+		assert.Equal(t, message.TransactionID, request.Message.TransactionID)
+		assert.Nil(t, request.Message.StartDateTime)
+		assert.Nil(t, request.Message.EndDateTime)
+		require.NotNil(t, request.Message.Display)
+		assert.Equal(t, *message.Display, *request.Message.Display)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	resultChannel := make(chan bool, 1)
+	err = suite.csms.SetDisplayMessage(wsId, func(response *display.SetDisplayMessageResponse, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, response)
+		assert.Equal(t, status, response.Status)
+		resultChannel <- true
+	}, message)
+	require.Nil(t, err)
+	result := <-resultChannel
+	assert.True(t, result)
+}
+
+func (suite *OcppV2TestSuite) TestSetDisplayMessageInvalidTimeWindow() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Message ending before it starts is rejected before sending
+	start := time.Now().Add(time.Hour)
+	message := display.MessageInfo{
+		ID:            44,
+		Priority:      display.MessagePriorityNormalCycle,
+		StartDateTime: types.NewDateTime(start),
+		EndDateTime:   types.NewDateTime(start.Add(-time.Minute)),
+		Message:       types.MessageContent{Format: types.MessageFormatUTF8, Content: "happy hour"},
+	}
+	err = suite.csms.SetDisplayMessage(wsId, func(response *display.SetDisplayMessageResponse, err error) {
+		t.Fatal("callback shouldn't be invoked")
+	}, message)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "EndDateTime")
+	suite.mockWsServer.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+}
+
 func (suite *OcppV2TestSuite) TestSetDisplayMessageInvalidEndpoint() {
 	messageId := defaultMessageId
 	message := display.MessageInfo{
@@ -86,6 +86,29 @@ func (suite *OcppV2TestSuite) TestSetMonitoringLevelE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestSetMonitoringLevelInvalidSeverity() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	handler := &MockChargingStationDiagnosticsHandler{}
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true}, handler)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	for _, severity := range []int{-1, 10} {
+		err = suite.csms.SetMonitoringLevel(wsId, func(response *diagnostics.SetMonitoringLevelResponse, err error) {
+			t.Fatal("callback shouldn't be invoked for invalid requests")
+		}, severity)
+		require.Error(t, err)
+	}
+	// Out-of-range severities never reach the charging station
+	suite.mockWsServer.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	handler.AssertNotCalled(t, "OnSetMonitoringLevel", mock.Anything)
+}
+
 func (suite *OcppV2TestSuite) TestSetMonitoringLevelInvalidEndpoint() {
 	messageId := defaultMessageId
 	severity := 3
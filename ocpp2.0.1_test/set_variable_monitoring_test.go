@@ -1,7 +1,9 @@
 package ocpp2_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/diagnostics"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
@@ -30,6 +32,14 @@ func (suite *OcppV2TestSuite) TestSetVariableMonitoringRequestValidation() {
 		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{ID: newInt(2), Transaction: true, Value: 42.0, Type: diagnostics.MonitorUpperThreshold, Severity: -1, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
 		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{ID: newInt(2), Transaction: true, Value: 42.0, Type: diagnostics.MonitorUpperThreshold, Severity: 10, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
 		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{ID: newInt(2), Transaction: true, Value: 42.0, Type: diagnostics.MonitorUpperThreshold, Severity: 5, Component: types.Component{}, Variable: types.Variable{}}}}, false},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Value: 60.0, Type: diagnostics.MonitorPeriodic, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, true},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Value: 900.0, Type: diagnostics.MonitorPeriodicClockAligned, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, true},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Value: 0.5, Type: diagnostics.MonitorDelta, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, true},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Type: diagnostics.MonitorPeriodic, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Value: -60.0, Type: diagnostics.MonitorPeriodic, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Value: 1.5, Type: diagnostics.MonitorPeriodicClockAligned, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Type: diagnostics.MonitorDelta, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
+		{diagnostics.SetVariableMonitoringRequest{MonitoringData: []diagnostics.SetMonitoringData{{Value: -1.0, Type: diagnostics.MonitorDelta, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}}}, false},
 	}
 	ExecuteGenericTestTable(t, requestTable)
 }
@@ -113,6 +123,48 @@ func (suite *OcppV2TestSuite) TestSetVariableMonitoringE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestSetVariableMonitoringPeriodicAndDelta() {
+	t := suite.T()
+	component := types.Component{Name: "EVSE", EVSE: &types.EVSE{ID: 1}}
+	variable := types.Variable{Name: "Power"}
+	periodic := diagnostics.NewPeriodicMonitoringData(5*time.Minute, false, 7, component, variable)
+	clockAligned := diagnostics.NewPeriodicMonitoringData(15*time.Minute, true, 7, component, variable)
+	delta := diagnostics.NewDeltaMonitoringData(1000.0, 5, component, variable)
+	threshold := diagnostics.SetMonitoringData{Value: 11000.0, Type: diagnostics.MonitorUpperThreshold, Severity: 3, Component: component, Variable: variable}
+	testTable := []struct {
+		data             diagnostics.SetMonitoringData
+		expectedType     diagnostics.MonitorType
+		expectedInterval time.Duration
+		expectedJson     string
+	}{
+		{periodic, diagnostics.MonitorPeriodic, 5 * time.Minute, `{"value":300,"type":"Periodic","severity":7,"component":{"name":"EVSE","evse":{"id":1}},"variable":{"name":"Power"}}`},
+		{clockAligned, diagnostics.MonitorPeriodicClockAligned, 15 * time.Minute, `{"value":900,"type":"PeriodicClockAligned","severity":7,"component":{"name":"EVSE","evse":{"id":1}},"variable":{"name":"Power"}}`},
+		{delta, diagnostics.MonitorDelta, 0, `{"value":1000,"type":"Delta","severity":5,"component":{"name":"EVSE","evse":{"id":1}},"variable":{"name":"Power"}}`},
+		{threshold, diagnostics.MonitorUpperThreshold, 0, `{"value":11000,"type":"UpperThreshold","severity":3,"component":{"name":"EVSE","evse":{"id":1}},"variable":{"name":"Power"}}`},
+	}
+	for _, tc := range testTable {
+		assert.Equal(t, tc.expectedType, tc.data.Type)
+		assert.Equal(t, tc.expectedInterval, tc.data.Interval())
+		err := types.Validate.Struct(diagnostics.NewSetVariableMonitoringRequest([]diagnostics.SetMonitoringData{tc.data}))
+		assert.NoError(t, err)
+		rawJson, err := json.Marshal(tc.data)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expectedJson, string(rawJson))
+		// Results echo the monitor, with the status decided by the charging station
+		result := diagnostics.NewSetMonitoringResult(tc.data, diagnostics.SetMonitoringStatusUnsupportedMonitorType)
+		assert.Nil(t, result.ID)
+		assert.Equal(t, tc.data.Type, result.Type)
+		assert.Equal(t, tc.data.Severity, result.Severity)
+		assert.Equal(t, tc.data.Component, result.Component)
+		assert.Equal(t, tc.data.Variable, result.Variable)
+		err = types.Validate.Struct(diagnostics.NewSetVariableMonitoringResponse([]diagnostics.SetMonitoringResult{result}))
+		assert.NoError(t, err)
+	}
+	// Sub-second intervals are rounded to whole seconds
+	rounded := diagnostics.NewPeriodicMonitoringData(1500*time.Millisecond, false, 0, component, variable)
+	assert.Equal(t, 2*time.Second, rounded.Interval())
+}
+
 func (suite *OcppV2TestSuite) TestSetVariableMonitoringInvalidEndpoint() {
 	messageId := defaultMessageId
 	monitoringData := diagnostics.SetMonitoringData{ID: newInt(2), Transaction: false, Value: 42.0, Type: diagnostics.MonitorUpperThreshold, Severity: 5, Component: types.Component{Name: "component1"}, Variable: types.Variable{Name: "variable1"}}
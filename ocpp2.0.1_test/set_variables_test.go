@@ -2,11 +2,14 @@ package ocpp2_test
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	ocpp2 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
 )
@@ -146,3 +149,187 @@ func (suite *OcppV2TestSuite) TestSetVariablesInvalidEndpoint() {
 		messageId, provisioning.SetVariablesFeatureName, variableData.AttributeType, variableData.AttributeValue, variableData.Component.Name, variableData.Component.Instance, variableData.Component.EVSE.ID, *variableData.Component.EVSE.ConnectorID, variableData.Variable.Name, variableData.Variable.Instance)
 	testUnsupportedRequestFromChargingStation(suite, request, requestJson, messageId)
 }
+
+// A charging station provisioning handler, which stores variable values by name.
+// Writes to rejected variables fail, while writes to ignored variables are accepted without changing the value.
+type fakeVariableStore struct {
+	MockChargingStationProvisioningHandler
+	mutex    sync.Mutex
+	values   map[string]string
+	rejected map[string]bool
+	ignored  map[string]bool
+	writes   int
+}
+
+func (s *fakeVariableStore) OnGetVariables(request *provisioning.GetVariablesRequest) (*provisioning.GetVariablesResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var results []provisioning.GetVariableResult
+	for _, data := range request.GetVariableData {
+		result := provisioning.GetVariableResult{Component: data.Component, Variable: data.Variable, AttributeStatus: provisioning.GetVariableStatusUnknownVariable}
+		if value, ok := s.values[data.Variable.Name]; ok {
+			result.AttributeStatus = provisioning.GetVariableStatusAccepted
+			result.AttributeValue = value
+		}
+		results = append(results, result)
+	}
+	return provisioning.NewGetVariablesResponse(results), nil
+}
+
+func (s *fakeVariableStore) OnSetVariables(request *provisioning.SetVariablesRequest) (*provisioning.SetVariablesResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writes++
+	var results []provisioning.SetVariableResult
+	for _, data := range request.SetVariableData {
+		result := provisioning.SetVariableResult{Component: data.Component, Variable: data.Variable, AttributeStatus: provisioning.SetVariableStatusAccepted}
+		if s.rejected[data.Variable.Name] {
+			result.AttributeStatus = provisioning.SetVariableStatusRejected
+		} else if !s.ignored[data.Variable.Name] {
+			s.values[data.Variable.Name] = data.AttributeValue
+		}
+		results = append(results, result)
+	}
+	return provisioning.NewSetVariablesResponse(results), nil
+}
+
+func (s *fakeVariableStore) snapshot() (map[string]string, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	values := map[string]string{}
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values, s.writes
+}
+
+// Sets the variables atomically on a charging station backed by the given store and returns the result.
+func testSetVariablesAtomic(suite *OcppV2TestSuite, store *fakeVariableStore, data []provisioning.SetVariableData) (*ocpp2.AtomicSetVariablesResult, error) {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	channel := NewMockWebSocket(wsId)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.chargingStation.SetProvisioningHandler(store)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	type outcome struct {
+		result *ocpp2.AtomicSetVariablesResult
+		err    error
+	}
+	resultC := make(chan outcome, 1)
+	err = suite.csms.SetVariablesAtomic(wsId, func(result *ocpp2.AtomicSetVariablesResult, err error) {
+		resultC <- outcome{result: result, err: err}
+	}, data)
+	require.Nil(t, err)
+	select {
+	case o := <-resultC:
+		require.NotNil(t, o.result)
+		return o.result, o.err
+	case <-time.After(time.Second):
+		t.Fatal("callback wasn't invoked")
+		return nil, nil
+	}
+}
+
+func networkSettings(primary string, secondary string) []provisioning.SetVariableData {
+	component := types.Component{Name: "OCPPCommCtrlr"}
+	return []provisioning.SetVariableData{
+		{Component: component, Variable: types.Variable{Name: "PrimaryEndpoint"}, AttributeValue: primary},
+		{Component: component, Variable: types.Variable{Name: "SecondaryEndpoint"}, AttributeValue: secondary},
+	}
+}
+
+func (suite *OcppV2TestSuite) TestSetVariablesAtomicCommitted() {
+	t := suite.T()
+	store := &fakeVariableStore{values: map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"}}
+	result, err := testSetVariablesAtomic(suite, store, networkSettings("new1", "new2"))
+	require.Nil(t, err)
+	assert.True(t, result.Committed)
+	assert.False(t, result.RolledBack)
+	require.Len(t, result.Variables, 2)
+	for i, previous := range []string{"old1", "old2"} {
+		v := result.Variables[i]
+		assert.Equal(t, previous, v.PreviousValue)
+		assert.Equal(t, provisioning.SetVariableStatusAccepted, v.SetStatus)
+		assert.True(t, v.Verified)
+		assert.Empty(t, v.RestoreStatus)
+	}
+	values, writes := store.snapshot()
+	assert.Equal(t, map[string]string{"PrimaryEndpoint": "new1", "SecondaryEndpoint": "new2"}, values)
+	assert.Equal(t, 1, writes)
+}
+
+func (suite *OcppV2TestSuite) TestSetVariablesAtomicRollback() {
+	t := suite.T()
+	store := &fakeVariableStore{
+		values:   map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"},
+		rejected: map[string]bool{"SecondaryEndpoint": true},
+	}
+	result, err := testSetVariablesAtomic(suite, store, networkSettings("new1", "new2"))
+	require.Nil(t, err)
+	assert.False(t, result.Committed)
+	assert.True(t, result.RolledBack)
+	require.Len(t, result.Variables, 2)
+	// The accepted variable was restored, while the rejected one was never changed
+	assert.Equal(t, provisioning.SetVariableStatusAccepted, result.Variables[0].SetStatus)
+	assert.True(t, result.Variables[0].Verified)
+	assert.Equal(t, provisioning.SetVariableStatusAccepted, result.Variables[0].RestoreStatus)
+	assert.Equal(t, provisioning.SetVariableStatusRejected, result.Variables[1].SetStatus)
+	assert.Empty(t, result.Variables[1].RestoreStatus)
+	values, writes := store.snapshot()
+	assert.Equal(t, map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"}, values)
+	assert.Equal(t, 2, writes)
+}
+
+func (suite *OcppV2TestSuite) TestSetVariablesAtomicAllRejected() {
+	t := suite.T()
+	store := &fakeVariableStore{
+		values:   map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"},
+		rejected: map[string]bool{"PrimaryEndpoint": true, "SecondaryEndpoint": true},
+	}
+	result, err := testSetVariablesAtomic(suite, store, networkSettings("new1", "new2"))
+	require.Nil(t, err)
+	assert.False(t, result.Committed)
+	assert.True(t, result.RolledBack)
+	require.Len(t, result.Variables, 2)
+	for _, v := range result.Variables {
+		assert.Equal(t, provisioning.SetVariableStatusRejected, v.SetStatus)
+		assert.Empty(t, v.RestoreStatus)
+	}
+	// Nothing was applied, so no rollback request is sent
+	values, writes := store.snapshot()
+	assert.Equal(t, map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"}, values)
+	assert.Equal(t, 1, writes)
+}
+
+func (suite *OcppV2TestSuite) TestSetVariablesAtomicVerificationFailure() {
+	t := suite.T()
+	store := &fakeVariableStore{
+		values:  map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"},
+		ignored: map[string]bool{"PrimaryEndpoint": true},
+	}
+	result, err := testSetVariablesAtomic(suite, store, networkSettings("new1", "new2"))
+	require.Nil(t, err)
+	assert.False(t, result.Committed)
+	assert.True(t, result.RolledBack)
+	assert.False(t, result.Variables[0].Verified)
+	assert.True(t, result.Variables[1].Verified)
+	values, _ := store.snapshot()
+	assert.Equal(t, map[string]string{"PrimaryEndpoint": "old1", "SecondaryEndpoint": "old2"}, values)
+}
+
+func (suite *OcppV2TestSuite) TestSetVariablesAtomicUnreadableVariable() {
+	t := suite.T()
+	store := &fakeVariableStore{values: map[string]string{"PrimaryEndpoint": "old1"}}
+	result, err := testSetVariablesAtomic(suite, store, networkSettings("new1", "new2"))
+	require.Error(t, err)
+	assert.False(t, result.Committed)
+	assert.False(t, result.RolledBack)
+	values, writes := store.snapshot()
+	assert.Equal(t, map[string]string{"PrimaryEndpoint": "old1"}, values)
+	assert.Equal(t, 0, writes)
+}
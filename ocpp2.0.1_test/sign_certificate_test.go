@@ -1,6 +1,8 @@
 package ocpp2_test
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 
 	"github.com/stretchr/testify/assert"
@@ -85,3 +87,58 @@ func (suite *OcppV2TestSuite) TestSignCertificateInvalidEndpoint() {
 		messageId, security.SignCertificateFeatureName, csr, certificateType)
 	testUnsupportedRequestFromCentralSystem(suite, request, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestSignCertificateParseCSR() {
+	t := suite.T()
+	request := security.NewSignCertificateRequest(newTestCSR(t, "station1"))
+	csr, err := request.ParseCSR()
+	require.NoError(t, err)
+	assert.Equal(t, "station1", csr.Subject.CommonName)
+	// Invalid CSRs
+	request = security.NewSignCertificateRequest("deadc0de")
+	_, err = request.ParseCSR()
+	assert.EqualError(t, err, "invalid CSR, no PEM data found")
+	certificate, _ := newTestCertificate(t, "station1", false, nil, nil)
+	request = security.NewSignCertificateRequest(security.EncodeCertificateChain([]*x509.Certificate{certificate}))
+	_, err = request.ParseCSR()
+	assert.EqualError(t, err, "invalid CSR, unexpected PEM block type CERTIFICATE")
+	request = security.NewSignCertificateRequest(newTestCSR(t, "station1") + newTestCSR(t, "station2"))
+	_, err = request.ParseCSR()
+	assert.Error(t, err)
+}
+
+func (suite *OcppV2TestSuite) TestSignCertificateCSRE2EMocked() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	csr := newTestCSR(t, wsId)
+	rawCSR, _ := json.Marshal(csr)
+	status := types.GenericStatusAccepted
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"csr":%v,"certificateType":"%v"}]`,
+		messageId, security.SignCertificateFeatureName, string(rawCSR), types.ChargingStationCert)
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	channel := NewMockWebSocket(wsId)
+	// CSMS verifies the received CSR before accepting it
+	handler := &MockCSMSSecurityHandler{}
+	handler.On("OnSignCertificate", wsId, mock.Anything).Return(security.NewSignCertificateResponse(status), nil).Run(func(args mock.Arguments) {
+		request, ok := args.Get(1).(*security.SignCertificateRequest)
+		require.True(t, ok)
+		parsedCSR, err := request.ParseCSR()
+		require.NoError(t, err)
+		assert.Equal(t, wsId, parsedCSR.Subject.CommonName)
+		assert.Equal(t, types.ChargingStationCert, request.CertificateType)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	response, err := suite.chargingStation.SignCertificate(csr, func(request *security.SignCertificateRequest) {
+		request.CertificateType = types.ChargingStationCert
+	})
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, status, response.Status)
+}
@@ -0,0 +1,133 @@
+package ocpp2_test
+
+import (
+	"net"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+func (suite *OcppV2TestSuite) TestCSMSMigrateStations() {
+	t := suite.T()
+	dir, err := os.MkdirTemp("", "ocpp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "csms.sock")
+	ln, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	csms := ocpp2.NewCSMS(nil, nil)
+	connectedC := make(chan string, 2)
+	csms.SetNewChargingStationHandler(func(chargingStation ocpp2.ChargingStationConnection) {
+		connectedC <- chargingStation.ID()
+	})
+	go func() {
+		_ = csms.StartOnListener(ln, "/ws/{id}")
+	}()
+	defer csms.Stop()
+	newProfile := provisioning.NetworkConnectionProfile{
+		OCPPVersion:     provisioning.OCPPVersion20,
+		OCPPTransport:   provisioning.OCPPTransportJSON,
+		CSMSUrl:         "wss://green.csms.org/ocpp",
+		MessageTimeout:  30,
+		SecurityProfile: 2,
+		OCPPInterface:   provisioning.OCPPInterfaceWired0,
+	}
+	// Simulated charging stations, connecting through the unix socket
+	newStation := func(id string, handler *MockChargingStationProvisioningHandler) ocpp2.ChargingStation {
+		wsClient := ws.NewClient()
+		wsClient.AddOption(func(dialer *websocket.Dialer) {
+			dialer.NetDial = func(network, addr string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			}
+		})
+		chargingStation := ocpp2.NewChargingStation(id, nil, wsClient)
+		chargingStation.SetProvisioningHandler(handler)
+		err := chargingStation.Start("ws://localhost/ws")
+		require.NoError(t, err)
+		return chargingStation
+	}
+	acceptingHandler := &MockChargingStationProvisioningHandler{}
+	acceptingHandler.On("OnSetNetworkProfile", mock.Anything).Return(provisioning.NewSetNetworkProfileResponse(provisioning.SetNetworkProfileStatusAccepted), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*provisioning.SetNetworkProfileRequest)
+		assert.Equal(t, 1, request.ConfigurationSlot)
+		assert.Equal(t, newProfile, request.ConnectionData)
+	})
+	acceptingHandler.On("OnSetVariables", mock.Anything).Return(provisioning.NewSetVariablesResponse([]provisioning.SetVariableResult{
+		{AttributeStatus: provisioning.SetVariableStatusAccepted, Component: types.Component{Name: "OCPPCommCtrlr"}, Variable: types.Variable{Name: "NetworkConfigurationPriority"}},
+	}), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*provisioning.SetVariablesRequest)
+		require.Len(t, request.SetVariableData, 1)
+		assert.Equal(t, "OCPPCommCtrlr", request.SetVariableData[0].Component.Name)
+		assert.Equal(t, "NetworkConfigurationPriority", request.SetVariableData[0].Variable.Name)
+		assert.Equal(t, "1,0", request.SetVariableData[0].AttributeValue)
+	})
+	acceptingHandler.On("OnReset", mock.Anything).Return(provisioning.NewResetResponse(provisioning.ResetStatusScheduled), nil).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*provisioning.ResetRequest)
+		assert.Equal(t, provisioning.ResetTypeOnIdle, request.Type)
+	})
+	rejectingHandler := &MockChargingStationProvisioningHandler{}
+	rejectingHandler.On("OnSetNetworkProfile", mock.Anything).Return(provisioning.NewSetNetworkProfileResponse(provisioning.SetNetworkProfileStatusRejected), nil)
+	station1 := newStation("station1", acceptingHandler)
+	defer station1.Stop()
+	station2 := newStation("station2", rejectingHandler)
+	defer station2.Stop()
+	for i := 0; i < 2; i++ {
+		<-connectedC
+	}
+	// Migrate stations, including one which is not connected
+	resultC := make(chan ocpp2.StationMigrationResult, 3)
+	csms.MigrateStations([]string{"station1", "station2", "station3"}, 1, newProfile, func(result ocpp2.StationMigrationResult) {
+		resultC <- result
+	}, func(options *ocpp2.StationMigrationOptions) {
+		options.NetworkConfigurationPriority = "1,0"
+		options.Reset = true
+	})
+	var results []ocpp2.StationMigrationResult
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-resultC:
+			results = append(results, result)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for migration results")
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ChargingStationID < results[j].ChargingStationID
+	})
+	// Station 1 received the profile and was reset
+	assert.Equal(t, "station1", results[0].ChargingStationID)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, provisioning.SetNetworkProfileStatusAccepted, results[0].ProfileStatus)
+	assert.Equal(t, provisioning.SetVariableStatusAccepted, results[0].PriorityStatus)
+	assert.Equal(t, provisioning.ResetStatusScheduled, results[0].ResetStatus)
+	assert.True(t, results[0].Migrated())
+	acceptingHandler.AssertNumberOfCalls(t, "OnSetNetworkProfile", 1)
+	acceptingHandler.AssertNumberOfCalls(t, "OnSetVariables", 1)
+	acceptingHandler.AssertNumberOfCalls(t, "OnReset", 1)
+	// Station 2 rejected the profile, no further steps were executed
+	assert.Equal(t, "station2", results[1].ChargingStationID)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, provisioning.SetNetworkProfileStatusRejected, results[1].ProfileStatus)
+	assert.Empty(t, results[1].PriorityStatus)
+	assert.Empty(t, results[1].ResetStatus)
+	assert.False(t, results[1].Migrated())
+	rejectingHandler.AssertNumberOfCalls(t, "OnSetNetworkProfile", 1)
+	rejectingHandler.AssertNotCalled(t, "OnSetVariables", mock.Anything)
+	rejectingHandler.AssertNotCalled(t, "OnReset", mock.Anything)
+	// Station 3 is not connected
+	assert.Equal(t, "station3", results[2].ChargingStationID)
+	assert.Error(t, results[2].Err)
+	assert.Empty(t, results[2].ProfileStatus)
+	assert.False(t, results[2].Migrated())
+}
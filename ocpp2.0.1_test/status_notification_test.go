@@ -74,6 +74,174 @@ func (suite *OcppV2TestSuite) TestStatusNotificationE2EMocked() {
 	assert.NotNil(t, response)
 }
 
+func (suite *OcppV2TestSuite) TestStatusNotificationDebounce() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	window := 200 * time.Millisecond
+	timestamp := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	notifiedC := make(chan *availability.StatusNotificationRequest, 10)
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnStatusNotification", wsId, mock.Anything).Return(availability.NewStatusNotificationResponse(), nil).Run(func(args mock.Arguments) {
+		notifiedC <- args.Get(1).(*availability.StatusNotificationRequest)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetStatusNotificationDebounce(window)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Burst of notifications for EVSE 1, single notification for EVSE 2. All notifications are acknowledged right away.
+	start := time.Now()
+	for _, status := range []availability.ConnectorStatus{availability.ConnectorStatusOccupied, availability.ConnectorStatusReserved, availability.ConnectorStatusUnavailable} {
+		response, err := suite.chargingStation.StatusNotification(timestamp, status, 1, 1)
+		require.Nil(t, err)
+		require.NotNil(t, response)
+	}
+	response, err := suite.chargingStation.StatusNotification(timestamp, availability.ConnectorStatusAvailable, 2, 1)
+	require.Nil(t, err)
+	require.NotNil(t, response)
+	assert.Less(t, int64(time.Since(start)), int64(window))
+	handler.AssertNotCalled(t, "OnStatusNotification", mock.Anything, mock.Anything)
+	// Handler only sees the final state of each connector
+	results := map[int]availability.ConnectorStatus{}
+	for i := 0; i < 2; i++ {
+		select {
+		case request := <-notifiedC:
+			assert.GreaterOrEqual(t, int64(time.Since(start)), int64(window/2))
+			assert.Equal(t, 1, request.ConnectorID)
+			results[request.EvseID] = request.ConnectorStatus
+		case <-time.After(2 * window):
+			t.Fatal("timeout waiting for debounced status notification")
+		}
+	}
+	assert.Equal(t, map[int]availability.ConnectorStatus{1: availability.ConnectorStatusUnavailable, 2: availability.ConnectorStatusAvailable}, results)
+	time.Sleep(window)
+	handler.AssertNumberOfCalls(t, "OnStatusNotification", 2)
+}
+
+func (suite *OcppV2TestSuite) TestStatusNotificationDebounceRestartsQuietPeriod() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	window := 150 * time.Millisecond
+	timestamp := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	notifiedC := make(chan *availability.StatusNotificationRequest, 10)
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnStatusNotification", wsId, mock.Anything).Return(availability.NewStatusNotificationResponse(), nil).Run(func(args mock.Arguments) {
+		notifiedC <- args.Get(1).(*availability.StatusNotificationRequest)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetStatusNotificationDebounce(window)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// The notifications span more than one window, but each one arrives within the quiet period of the previous one
+	statuses := []availability.ConnectorStatus{availability.ConnectorStatusOccupied, availability.ConnectorStatusReserved, availability.ConnectorStatusUnavailable, availability.ConnectorStatusFaulted}
+	for i, status := range statuses {
+		if i > 0 {
+			time.Sleep(window / 2)
+		}
+		_, err = suite.chargingStation.StatusNotification(timestamp, status, 1, 1)
+		require.Nil(t, err)
+	}
+	last := time.Now()
+	select {
+	case request := <-notifiedC:
+		assert.GreaterOrEqual(t, int64(time.Since(last)), int64(window/2))
+		assert.Equal(t, availability.ConnectorStatusFaulted, request.ConnectorStatus)
+	case <-time.After(3 * window):
+		t.Fatal("timeout waiting for debounced status notification")
+	}
+	time.Sleep(window)
+	handler.AssertNumberOfCalls(t, "OnStatusNotification", 1)
+}
+
+func (suite *OcppV2TestSuite) TestStatusNotificationDebounceFlushOnDisconnect() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	window := time.Hour
+	timestamp := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	notifiedC := make(chan *availability.StatusNotificationRequest, 10)
+	handler := &MockCSMSAvailabilityHandler{}
+	handler.On("OnStatusNotification", wsId, mock.Anything).Return(availability.NewStatusNotificationResponse(), nil).Run(func(args mock.Arguments) {
+		notifiedC <- args.Get(1).(*availability.StatusNotificationRequest)
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetStatusNotificationDebounce(window)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	_, err = suite.chargingStation.StatusNotification(timestamp, availability.ConnectorStatusOccupied, 1, 1)
+	require.Nil(t, err)
+	_, err = suite.chargingStation.StatusNotification(timestamp, availability.ConnectorStatusAvailable, 2, 1)
+	require.Nil(t, err)
+	handler.AssertNotCalled(t, "OnStatusNotification", mock.Anything, mock.Anything)
+	// Pending notifications are delivered once the station disconnects, without waiting for the quiet period
+	suite.mockWsServer.DisconnectedClientHandler(channel)
+	for _, evseID := range []int{1, 2} {
+		select {
+		case request := <-notifiedC:
+			assert.Equal(t, evseID, request.EvseID)
+		default:
+			t.Fatal("pending status notification wasn't delivered")
+		}
+	}
+}
+
+type mockStatusNotificationSequenceHandler struct {
+	MockCSMSAvailabilityHandler
+	sequenceC chan []*availability.StatusNotificationRequest
+}
+
+func (handler *mockStatusNotificationSequenceHandler) OnStatusNotificationSequence(chargingStationID string, requests []*availability.StatusNotificationRequest) {
+	handler.sequenceC <- requests
+}
+
+func (suite *OcppV2TestSuite) TestStatusNotificationDebounceFullSequence() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	window := 100 * time.Millisecond
+	timestamp := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	handler := &mockStatusNotificationSequenceHandler{sequenceC: make(chan []*availability.StatusNotificationRequest, 1)}
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetAvailabilityHandler(handler)
+	suite.csms.SetStatusNotificationDebounce(window)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	statuses := []availability.ConnectorStatus{availability.ConnectorStatusOccupied, availability.ConnectorStatusUnavailable, availability.ConnectorStatusFaulted}
+	for _, status := range statuses {
+		_, err = suite.chargingStation.StatusNotification(timestamp, status, 1, 2)
+		require.Nil(t, err)
+	}
+	select {
+	case requests := <-handler.sequenceC:
+		require.Len(t, requests, len(statuses))
+		for i, request := range requests {
+			assert.Equal(t, statuses[i], request.ConnectorStatus)
+			assert.Equal(t, 1, request.EvseID)
+			assert.Equal(t, 2, request.ConnectorID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for debounced status notifications")
+	}
+	handler.AssertNotCalled(t, "OnStatusNotification", mock.Anything, mock.Anything)
+}
+
 func (suite *OcppV2TestSuite) TestStatusNotificationInvalidEndpoint() {
 	messageId := defaultMessageId
 	timestamp := types.NewDateTime(time.Now())
@@ -8,8 +8,11 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
 )
 
 // Test
@@ -77,6 +80,13 @@ func (suite *OcppV2TestSuite) TestTransactionEventResponseValidation() {
 		{transactions.TransactionEventResponse{TotalCost: newFloat(8.42), ChargingPriority: newInt(10), IDTokenInfo: types.NewIdTokenInfo(types.AuthorizationStatusAccepted), UpdatedPersonalMessage: &messageContent}, false},
 		{transactions.TransactionEventResponse{TotalCost: newFloat(8.42), ChargingPriority: newInt(2), IDTokenInfo: types.NewIdTokenInfo("invalidAuthorizationStatus"), UpdatedPersonalMessage: &messageContent}, false},
 		{transactions.TransactionEventResponse{TotalCost: newFloat(8.42), ChargingPriority: newInt(2), IDTokenInfo: types.NewIdTokenInfo(types.AuthorizationStatusAccepted), UpdatedPersonalMessage: &types.MessageContent{}}, false},
+		{transactions.TransactionEventResponse{UpdatedPersonalMessage: types.NewMessageContent(types.MessageFormatASCII, "Current cost: 8.42 EUR")}, true},
+		{transactions.TransactionEventResponse{UpdatedPersonalMessage: types.NewMessageContent(types.MessageFormatASCII, "Current cost: 8.42 €")}, false},
+		{transactions.TransactionEventResponse{UpdatedPersonalMessage: types.NewMessageContent(types.MessageFormatUTF8, "Current cost: 8.42 €")}, true},
+		{transactions.TransactionEventResponse{UpdatedPersonalMessage: types.NewMessageContent(types.MessageFormatUTF8, "invalid\xff")}, false},
+		{transactions.TransactionEventResponse{UpdatedPersonalMessage: types.NewMessageContent(types.MessageFormatURI, "https://example.com/tariff")}, true},
+		{transactions.TransactionEventResponse{UpdatedPersonalMessage: types.NewMessageContent(types.MessageFormatURI, "not a uri")}, false},
+		{transactions.TransactionEventResponse{IDTokenInfo: &types.IdTokenInfo{Status: types.AuthorizationStatusAccepted, PersonalMessage: types.NewMessageContent(types.MessageFormatASCII, "Grüezi")}}, false},
 	}
 	ExecuteGenericTestTable(t, responseTable)
 }
@@ -168,6 +178,192 @@ func (suite *OcppV2TestSuite) TestTransactionEventE2EMocked() {
 	assert.Equal(t, messageContent.Content, response.UpdatedPersonalMessage.Content)
 }
 
+func (suite *OcppV2TestSuite) TestTransactionEventActiveTransactions() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	startTime := time.Now().Add(-time.Hour)
+	evse := types.EVSE{ID: 1}
+	idToken := types.IdToken{IdToken: "1234", Type: types.IdTokenTypeKeyCode}
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSTransactionsHandler{}
+	handler.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactions.NewTransactionEventResponse(), nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	sendEvent := func(eventType transactions.TransactionEvent, seqNo int, offset time.Duration, info transactions.Transaction, props ...func(request *transactions.TransactionEventRequest)) {
+		_, err := suite.chargingStation.TransactionEvent(eventType, types.NewDateTime(startTime.Add(offset)), transactions.TriggerReasonChargingStateChanged, seqNo, info, props...)
+		require.Nil(t, err)
+	}
+	// Run Test
+	assert.Nil(t, suite.csms.ActiveTransactions(wsId))
+	suite.csms.SetTransactionTracking(true)
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	assert.Empty(t, suite.csms.ActiveTransactions(wsId))
+	// Start two transactions
+	sendEvent(transactions.TransactionEventStarted, 0, 0, transactions.Transaction{TransactionID: "tx1", ChargingState: transactions.ChargingStateEVConnected}, func(request *transactions.TransactionEventRequest) {
+		request.Evse = &evse
+		request.IDToken = &idToken
+	})
+	sendEvent(transactions.TransactionEventStarted, 0, time.Minute, transactions.Transaction{TransactionID: "tx2"})
+	active := suite.csms.ActiveTransactions(wsId)
+	require.Len(t, active, 2)
+	assert.Equal(t, "tx1", active[0].TransactionID)
+	assert.Equal(t, "tx2", active[1].TransactionID)
+	assert.Equal(t, transactions.ChargingStateEVConnected, active[0].ChargingState)
+	require.NotNil(t, active[0].Evse)
+	assert.Equal(t, evse.ID, active[0].Evse.ID)
+	require.NotNil(t, active[0].IDToken)
+	assert.Equal(t, idToken.IdToken, active[0].IDToken.IdToken)
+	// Update first transaction
+	sendEvent(transactions.TransactionEventUpdated, 2, 2*time.Minute, transactions.Transaction{TransactionID: "tx1", ChargingState: transactions.ChargingStateCharging})
+	// Stale update is ignored
+	sendEvent(transactions.TransactionEventUpdated, 1, 90*time.Second, transactions.Transaction{TransactionID: "tx1", ChargingState: transactions.ChargingStateSuspendedEV})
+	active = suite.csms.ActiveTransactions(wsId)
+	require.Len(t, active, 2)
+	assert.Equal(t, transactions.ChargingStateCharging, active[0].ChargingState)
+	assert.Equal(t, 2, active[0].SequenceNo)
+	assertDateTimeEquality(t, types.NewDateTime(startTime), active[0].StartedAt)
+	assertDateTimeEquality(t, types.NewDateTime(startTime.Add(2*time.Minute)), active[0].UpdatedAt)
+	// End first transaction
+	sendEvent(transactions.TransactionEventEnded, 3, 3*time.Minute, transactions.Transaction{TransactionID: "tx1", StoppedReason: transactions.ReasonLocal})
+	active = suite.csms.ActiveTransactions(wsId)
+	require.Len(t, active, 1)
+	assert.Equal(t, "tx2", active[0].TransactionID)
+	// Late event for an ended transaction doesn't resurrect it
+	sendEvent(transactions.TransactionEventUpdated, 1, 90*time.Second, transactions.Transaction{TransactionID: "tx1"})
+	active = suite.csms.ActiveTransactions(wsId)
+	require.Len(t, active, 1)
+	assert.Equal(t, "tx2", active[0].TransactionID)
+	// Snapshot is not affected by later events
+	sendEvent(transactions.TransactionEventEnded, 1, 4*time.Minute, transactions.Transaction{TransactionID: "tx2"})
+	assert.Len(t, active, 1)
+	assert.Empty(t, suite.csms.ActiveTransactions(wsId))
+	// Unknown charging station
+	assert.Empty(t, suite.csms.ActiveTransactions("unknown"))
+}
+
+func (suite *OcppV2TestSuite) TestTransactionEventOrderedProcessing() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	timestamp := types.NewDateTime(time.Now())
+	channel := NewMockWebSocket(wsId)
+	type processedEvent struct {
+		transactionID string
+		seqNo         int
+	}
+	processedC := make(chan processedEvent, 6)
+	handler := &MockCSMSTransactionsHandler{}
+	handler.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactions.NewTransactionEventResponse(), nil).Run(func(args mock.Arguments) {
+		request := args.Get(1).(*transactions.TransactionEventRequest)
+		if request.TransactionInfo.TransactionID == "tx1" && request.SequenceNo == 0 {
+			// Slow handler for the first event of the first transaction
+			time.Sleep(200 * time.Millisecond)
+		}
+		processedC <- processedEvent{transactionID: request.TransactionInfo.TransactionID, seqNo: request.SequenceNo}
+	})
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: false}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel})
+	suite.csms.SetOrderedTransactionEvents(true)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	// Interleave events of two transactions
+	eventTypes := []transactions.TransactionEvent{transactions.TransactionEventStarted, transactions.TransactionEventUpdated, transactions.TransactionEventEnded}
+	for seqNo, eventType := range eventTypes {
+		for _, transactionID := range []string{"tx1", "tx2"} {
+			requestJson := fmt.Sprintf(`[2,"%v-%v","%v",{"eventType":"%v","timestamp":"%v","triggerReason":"%v","seqNo":%v,"transactionInfo":{"transactionId":"%v"}}]`,
+				transactionID, seqNo, transactions.TransactionEventFeatureName, eventType, timestamp.FormatTimestamp(), transactions.TriggerReasonChargingStateChanged, seqNo, transactionID)
+			err = suite.mockWsServer.MessageHandler(channel, []byte(requestJson))
+			require.Nil(t, err)
+		}
+	}
+	// Events of the second transaction aren't blocked by the first transaction
+	var processed []processedEvent
+	for i := 0; i < 6; i++ {
+		select {
+		case event := <-processedC:
+			processed = append(processed, event)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for transaction event %v", i)
+		}
+	}
+	assert.Equal(t, processedEvent{transactionID: "tx2", seqNo: 2}, processed[2])
+	expectedSeqNo := map[string]int{}
+	for _, event := range processed {
+		assert.Equal(t, expectedSeqNo[event.transactionID], event.seqNo, "out of order event for %v", event.transactionID)
+		expectedSeqNo[event.transactionID]++
+	}
+}
+
+func (suite *OcppV2TestSuite) TestTransactionEventTriggerReasonRouting() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	timestamp := types.NewDateTime(time.Now())
+	info := transactions.Transaction{TransactionID: "tx1"}
+	channel := NewMockWebSocket(wsId)
+	routed := make(chan string, 1)
+	routeTo := func(name string) transactions.TransactionEventHandlerFunc {
+		return func(chargingStationID string, request *transactions.TransactionEventRequest) (*transactions.TransactionEventResponse, error) {
+			assert.Equal(t, wsId, chargingStationID)
+			routed <- name
+			return transactions.NewTransactionEventResponse(), nil
+		}
+	}
+	fallback := &MockCSMSTransactionsHandler{}
+	fallback.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactions.NewTransactionEventResponse(), nil).Run(func(args mock.Arguments) {
+		routed <- "fallback"
+	})
+	router := transactions.NewTransactionEventRouter(fallback)
+	router.Handle(transactions.TriggerReasonAuthorized, routeTo("authorized"))
+	router.Handle(transactions.TriggerReasonMeterValuePeriodic, routeTo("periodic"))
+	router.Handle(transactions.TriggerReasonEVCommunicationLost, routeTo("communicationLost"))
+	router.HandleEvent(transactions.TransactionEventEnded, transactions.TriggerReasonEVCommunicationLost, routeTo("endedCommunicationLost"))
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true})
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	suite.csms.SetTransactionsHandler(router)
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.Nil(t, err)
+	testTable := []struct {
+		eventType transactions.TransactionEvent
+		reason    transactions.TriggerReason
+		expected  string
+	}{
+		{transactions.TransactionEventStarted, transactions.TriggerReasonAuthorized, "authorized"},
+		{transactions.TransactionEventUpdated, transactions.TriggerReasonMeterValuePeriodic, "periodic"},
+		{transactions.TransactionEventUpdated, transactions.TriggerReasonEVCommunicationLost, "communicationLost"},
+		{transactions.TransactionEventEnded, transactions.TriggerReasonEVCommunicationLost, "endedCommunicationLost"},
+		{transactions.TransactionEventUpdated, transactions.TriggerReasonCablePluggedIn, "fallback"},
+		{transactions.TransactionEventEnded, transactions.TriggerReasonEnergyLimitReached, "fallback"},
+	}
+	for i, tc := range testTable {
+		response, err := suite.chargingStation.TransactionEvent(tc.eventType, timestamp, tc.reason, i, info)
+		require.Nil(t, err)
+		require.NotNil(t, response)
+		assert.Equal(t, tc.expected, <-routed, "unexpected handler for %v %v", tc.eventType, tc.reason)
+	}
+	// Removed handler falls back to the default handler
+	router.Handle(transactions.TriggerReasonAuthorized, nil)
+	_, err = suite.chargingStation.TransactionEvent(transactions.TransactionEventUpdated, timestamp, transactions.TriggerReasonAuthorized, len(testTable), info)
+	require.Nil(t, err)
+	assert.Equal(t, "fallback", <-routed)
+	// Without a fallback handler, unmatched events are rejected
+	suite.csms.SetTransactionsHandler(transactions.NewTransactionEventRouter(nil))
+	response, err := suite.chargingStation.TransactionEvent(transactions.TransactionEventUpdated, timestamp, transactions.TriggerReasonAuthorized, len(testTable)+1, info)
+	require.Error(t, err)
+	assert.Nil(t, response)
+	ocppErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.NotSupported, ocppErr.Code)
+}
+
 func (suite *OcppV2TestSuite) TestTransactionEventInvalidEndpoint() {
 	messageId := defaultMessageId
 	timestamp := types.NewDateTime(time.Now())
@@ -192,3 +388,180 @@ func (suite *OcppV2TestSuite) TestTransactionEventInvalidEndpoint() {
 		messageId, transactions.TransactionEventFeatureName, eventType, timestamp.FormatTimestamp(), triggerReason, seqNo, *phases, *cableMaxCurrent, *reservationID, info.TransactionID, info.ChargingState, *info.TimeSpentCharging, info.StoppedReason, *info.RemoteStartID, idToken.IdToken, idToken.Type, evse.ID, meterValue.Timestamp.FormatTimestamp(), meterValue.SampledValue[0].Value)
 	testUnsupportedRequestFromCentralSystem(suite, request, requestJson, messageId)
 }
+
+func (suite *OcppV2TestSuite) TestTransactionEventResponseCostAndTokenInfo() {
+	t := suite.T()
+	wsId := "test_id"
+	messageId := defaultMessageId
+	wsUrl := "someUrl"
+	timestamp := types.NewDateTime(time.Now())
+	info := transactions.Transaction{TransactionID: "42", ChargingState: transactions.ChargingStateCharging}
+	totalCost := newFloat(12.5)
+	idTokenInfo := types.NewIdTokenInfo(types.AuthorizationStatusBlocked)
+	idTokenInfo.GroupIdToken = &types.GroupIdToken{IdToken: "group1", Type: types.IdTokenTypeCentral}
+	idTokenInfo.PersonalMessage = types.NewMessageContent(types.MessageFormatASCII, "Card blocked")
+	personalMessage := types.NewMessageContent(types.MessageFormatUTF8, "Running cost: 12.50 €")
+	personalMessage.Language = "en"
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"eventType":"%v","timestamp":"%v","triggerReason":"%v","seqNo":%v,"transactionInfo":{"transactionId":"%v","chargingState":"%v"}}]`,
+		messageId, transactions.TransactionEventFeatureName, transactions.TransactionEventUpdated, timestamp.FormatTimestamp(), transactions.TriggerReasonMeterValuePeriodic, 2, info.TransactionID, info.ChargingState)
+	responseJson := fmt.Sprintf(`[3,"%v",{"totalCost":%v,"idTokenInfo":{"status":"%v","groupIdToken":{"idToken":"%v","type":"%v"},"personalMessage":{"format":"%v","content":"%v"}},"updatedPersonalMessage":{"format":"%v","language":"%v","content":"%v"}}]`,
+		messageId, *totalCost, idTokenInfo.Status, idTokenInfo.GroupIdToken.IdToken, idTokenInfo.GroupIdToken.Type, idTokenInfo.PersonalMessage.Format, idTokenInfo.PersonalMessage.Content, personalMessage.Format, personalMessage.Language, personalMessage.Content)
+	transactionResponse := transactions.NewTransactionEventResponse()
+	transactionResponse.TotalCost = totalCost
+	transactionResponse.IDTokenInfo = idTokenInfo
+	transactionResponse.UpdatedPersonalMessage = personalMessage
+	channel := NewMockWebSocket(wsId)
+
+	handler := &MockCSMSTransactionsHandler{}
+	handler.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactionResponse, nil)
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, rawWrittenMessage: []byte(responseJson), forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, rawWrittenMessage: []byte(requestJson), forwardWrittenMessage: true})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	response, err := suite.chargingStation.TransactionEvent(transactions.TransactionEventUpdated, timestamp, transactions.TriggerReasonMeterValuePeriodic, 2, info)
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.TotalCost)
+	assert.Equal(t, *totalCost, *response.TotalCost)
+	assert.Nil(t, response.ChargingPriority)
+	require.NotNil(t, response.IDTokenInfo)
+	assert.Equal(t, types.AuthorizationStatusBlocked, response.IDTokenInfo.Status)
+	require.NotNil(t, response.IDTokenInfo.GroupIdToken)
+	assert.Equal(t, *idTokenInfo.GroupIdToken, *response.IDTokenInfo.GroupIdToken)
+	require.NotNil(t, response.IDTokenInfo.PersonalMessage)
+	assert.Equal(t, *idTokenInfo.PersonalMessage, *response.IDTokenInfo.PersonalMessage)
+	require.NotNil(t, response.UpdatedPersonalMessage)
+	assert.Equal(t, *personalMessage, *response.UpdatedPersonalMessage)
+}
+
+func (suite *OcppV2TestSuite) TestTransactionEventTariffEngine() {
+	t := suite.T()
+	wsId := "test_id"
+	wsUrl := "someUrl"
+	startTime := time.Now().Add(-time.Hour)
+	channel := NewMockWebSocket(wsId)
+	energyValue := func(wh float64) []types.MeterValue {
+		return []types.MeterValue{{Timestamp: *types.NewDateTime(time.Now()), SampledValue: []types.SampledValue{{Value: wh}}}}
+	}
+
+	handler := &MockCSMSTransactionsHandler{}
+	for i := 0; i < 3; i++ {
+		handler.On("OnTransactionEvent", mock.AnythingOfType("string"), mock.Anything).Return(transactions.NewTransactionEventResponse(), nil).Once()
+	}
+	setupDefaultCSMSHandlers(suite, expectedCSMSOptions{clientId: wsId, forwardWrittenMessage: true}, handler)
+	setupDefaultChargingStationHandlers(suite, expectedChargingStationOptions{serverUrl: wsUrl, clientId: wsId, createChannelOnStart: true, channel: channel, forwardWrittenMessage: true})
+	// Fake engine, charging 0.5 per kWh based on the last reported register value
+	var sessions []ocpp2.SessionState
+	engine := func(event transactions.TransactionEventRequest, session ocpp2.SessionState) (float64, *types.MessageContent, error) {
+		sessions = append(sessions, session)
+		if len(session.MeterValues) == 0 {
+			return 0, nil, fmt.Errorf("no meter values")
+		}
+		last := session.MeterValues[len(session.MeterValues)-1]
+		cost := last.SampledValue[0].Value / 1000 * 0.5
+		return cost, types.NewMessageContent(types.MessageFormatASCII, fmt.Sprintf("Cost: %.2f", cost)), nil
+	}
+	sendEvent := func(eventType transactions.TransactionEvent, seqNo int, meterValues []types.MeterValue) *transactions.TransactionEventResponse {
+		response, err := suite.chargingStation.TransactionEvent(eventType, types.NewDateTime(startTime.Add(time.Duration(seqNo)*time.Minute)), transactions.TriggerReasonMeterValuePeriodic, seqNo, transactions.Transaction{TransactionID: "tx1"}, func(request *transactions.TransactionEventRequest) {
+			request.MeterValue = meterValues
+		})
+		require.NoError(t, err)
+		require.NotNil(t, response)
+		return response
+	}
+	// Run Test
+	suite.csms.SetTransactionTracking(true)
+	suite.csms.SetTariffEngine(engine)
+	errC := suite.csms.Errors()
+	suite.csms.Start(8887, "somePath")
+	err := suite.chargingStation.Start(wsUrl)
+	require.NoError(t, err)
+	// Engine error doesn't affect the response
+	response := sendEvent(transactions.TransactionEventStarted, 0, nil)
+	assert.Nil(t, response.TotalCost)
+	assert.Nil(t, response.UpdatedPersonalMessage)
+	// Running cost is only reported via personal message
+	response = sendEvent(transactions.TransactionEventUpdated, 1, energyValue(4000))
+	assert.Nil(t, response.TotalCost)
+	require.NotNil(t, response.UpdatedPersonalMessage)
+	assert.Equal(t, "Cost: 2.00", response.UpdatedPersonalMessage.Content)
+	// Total cost is sent once the transaction ended
+	response = sendEvent(transactions.TransactionEventEnded, 2, energyValue(10000))
+	require.NotNil(t, response.TotalCost)
+	assert.Equal(t, 5.0, *response.TotalCost)
+	require.NotNil(t, response.UpdatedPersonalMessage)
+	assert.Equal(t, "Cost: 5.00", response.UpdatedPersonalMessage.Content)
+	// Session state passed to the engine
+	require.Len(t, sessions, 3)
+	assert.Equal(t, wsId, sessions[2].ChargingStationID)
+	assert.Equal(t, "tx1", sessions[2].TransactionID)
+	assert.Len(t, sessions[2].MeterValues, 2)
+	require.NotNil(t, sessions[1].Transaction)
+	assert.Equal(t, 1, sessions[1].Transaction.SequenceNo)
+	assert.Nil(t, sessions[2].Transaction)
+	// Engine errors are reported on the error channel
+	select {
+	case err = <-errC:
+		assert.ErrorContains(t, err, "no meter values")
+	default:
+		t.Fatal("expected tariff engine error")
+	}
+}
+
+func (suite *OcppV2TestSuite) TestTransactionEventConsumedEnergy() {
+	t := suite.T()
+	info := transactions.Transaction{TransactionID: "1234"}
+	startTime := types.NewDateTime(time.Now().Add(-time.Hour))
+	endTime := types.NewDateTime(time.Now())
+	kWh := &types.UnitOfMeasure{Unit: "kWh"}
+	start := transactions.NewTransactionEventRequest(transactions.TransactionEventStarted, startTime, transactions.TriggerReasonCablePluggedIn, 0, info)
+	start.MeterValue = []types.MeterValue{{
+		Timestamp: *startTime,
+		SampledValue: []types.SampledValue{
+			{Value: 230, Measurand: types.MeasurandVoltage, Context: types.ReadingContextTransactionBegin},
+			{Value: 9000, Location: types.LocationInlet, Context: types.ReadingContextTransactionBegin},
+			{Value: 1.5, UnitOfMeasure: kWh, Context: types.ReadingContextTransactionBegin},
+		},
+	}}
+	end := transactions.NewTransactionEventRequest(transactions.TransactionEventEnded, endTime, transactions.TriggerReasonEVDeparted, 5, info)
+	end.MeterValue = []types.MeterValue{
+		{
+			Timestamp:    *types.NewDateTime(endTime.Add(-time.Minute)),
+			SampledValue: []types.SampledValue{{Value: 5000, Context: types.ReadingContextSamplePeriodic}},
+		},
+		{
+			// Per-phase values add up to the register, with a multiplier of 10^1
+			Timestamp: *endTime,
+			SampledValue: []types.SampledValue{
+				{Value: 200, Phase: types.PhaseL1, UnitOfMeasure: &types.UnitOfMeasure{Unit: "Wh", Multiplier: newInt(1)}, Context: types.ReadingContextTransactionEnd},
+				{Value: 200, Phase: types.PhaseL2, UnitOfMeasure: &types.UnitOfMeasure{Unit: "Wh", Multiplier: newInt(1)}, Context: types.ReadingContextTransactionEnd},
+				{Value: 250, Phase: types.PhaseL3, UnitOfMeasure: &types.UnitOfMeasure{Unit: "Wh", Multiplier: newInt(1)}, Context: types.ReadingContextTransactionEnd},
+			},
+		},
+	}
+	startReading, ok := transactions.StartEnergy(start)
+	require.True(t, ok)
+	assert.Equal(t, 1500.0, startReading.Wh)
+	assert.Equal(t, startTime.Unix(), startReading.Timestamp.Unix())
+	endReading, ok := transactions.EndEnergy(end)
+	require.True(t, ok)
+	assert.Equal(t, 6500.0, endReading.Wh)
+	assert.Equal(t, endTime.Unix(), endReading.Timestamp.Unix())
+	consumed, err := transactions.ConsumedEnergyWh(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, 5000.0, consumed)
+	// Without Transaction.End context, the last reading of the register is used
+	end.MeterValue = end.MeterValue[:1]
+	consumed, err = transactions.ConsumedEnergyWh(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, 3500.0, consumed)
+	// Missing or decreasing registers are rejected
+	end.MeterValue = nil
+	_, err = transactions.ConsumedEnergyWh(start, end)
+	assert.Error(t, err)
+	end.MeterValue = []types.MeterValue{{Timestamp: *endTime, SampledValue: []types.SampledValue{{Value: 1000}}}}
+	_, err = transactions.ConsumedEnergyWh(start, end)
+	assert.Error(t, err)
+}
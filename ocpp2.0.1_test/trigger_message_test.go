@@ -2,6 +2,7 @@ package ocpp2_test
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/remotecontrol"
 	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
@@ -82,6 +83,56 @@ func (suite *OcppV2TestSuite) TestTriggerMessageE2EMocked() {
 	assert.True(t, result)
 }
 
+func (suite *OcppV2TestSuite) TestTriggerMessageAllE2EMocked() {
+	t := suite.T()
+	messageId := defaultMessageId
+	requestedMessage := remotecontrol.MessageTriggerBootNotification
+	status := remotecontrol.TriggerMessageStatusAccepted
+	requestJson := fmt.Sprintf(`[2,"%v","%v",{"requestedMessage":"%v"}]`, messageId, remotecontrol.TriggerMessageFeatureName, requestedMessage)
+	responseJson := fmt.Sprintf(`[3,"%v",{"status":"%v"}]`, messageId, status)
+	connectedIds := []string{"station1", "station2", "station3"}
+	disconnectedId := "station4"
+
+	var mutex sync.Mutex
+	triggeredIds := map[string]bool{}
+	suite.mockWsServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockWsServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		clientId := args.String(0)
+		assert.Equal(t, requestJson, string(args.Get(1).([]byte)))
+		mutex.Lock()
+		triggeredIds[clientId] = true
+		mutex.Unlock()
+		// Reply asynchronously on behalf of the charging station
+		go func() {
+			err := suite.mockWsServer.MessageHandler(NewMockWebSocket(clientId), []byte(responseJson))
+			assert.Nil(t, err)
+		}()
+	})
+	// Run Test
+	suite.csms.Start(8887, "somePath")
+	for _, id := range append(connectedIds, disconnectedId) {
+		suite.mockWsServer.NewClientHandler(NewMockWebSocket(id))
+	}
+	suite.mockWsServer.DisconnectedClientHandler(NewMockWebSocket(disconnectedId))
+	resultChannel := make(chan string, len(connectedIds))
+	targetIds := suite.csms.TriggerMessageAll(func(clientId string, response *remotecontrol.TriggerMessageResponse, err error) {
+		require.Nil(t, err)
+		require.NotNil(t, response)
+		assert.Equal(t, status, response.Status)
+		resultChannel <- clientId
+	}, requestedMessage)
+	assert.ElementsMatch(t, connectedIds, targetIds)
+	var resultIds []string
+	for range connectedIds {
+		resultIds = append(resultIds, <-resultChannel)
+	}
+	assert.ElementsMatch(t, connectedIds, resultIds)
+	mutex.Lock()
+	assert.Len(t, triggeredIds, len(connectedIds))
+	assert.False(t, triggeredIds[disconnectedId])
+	mutex.Unlock()
+}
+
 func (suite *OcppV2TestSuite) TestTriggerMessageInvalidEndpoint() {
 	messageId := defaultMessageId
 	requestedMessage := remotecontrol.MessageTriggerStatusNotification
@@ -0,0 +1,94 @@
+package batteryswap
+
+import (
+	"reflect"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// -------------------- Battery Swap (CS -> CSMS) --------------------
+
+const BatterySwapFeatureName = "BatterySwap"
+
+// BatterySwapEvent is the type of battery swap event reported by a Charging Station.
+type BatterySwapEvent string
+
+const (
+	BatterySwapEventBatteryIn         BatterySwapEvent = "BatteryIn"
+	BatterySwapEventBatteryOut        BatterySwapEvent = "BatteryOut"
+	BatterySwapEventBatteryOutTimeout BatterySwapEvent = "BatteryOutTimeout"
+)
+
+func isValidBatterySwapEvent(fl validator.FieldLevel) bool {
+	event := BatterySwapEvent(fl.Field().String())
+	switch event {
+	case BatterySwapEventBatteryIn, BatterySwapEventBatteryOut, BatterySwapEventBatteryOutTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// BatteryData describes a battery that was inserted into or taken out of a battery swap station.
+type BatteryData struct {
+	EvseID         int             `json:"evseId" validate:"gte=0"`
+	SerialNumber   string          `json:"serialNumber" validate:"required,max=50"`
+	SoC            float64         `json:"soC" validate:"gte=0,lte=100"`
+	SoH            float64         `json:"soH" validate:"gte=0,lte=100"`
+	ProductionDate *types.DateTime `json:"productionDate,omitempty" validate:"omitempty"`
+	VendorInfo     string          `json:"vendorInfo,omitempty" validate:"omitempty,max=500"`
+}
+
+// The field definition of the BatterySwap request payload sent by the Charging Station to the CSMS.
+type BatterySwapRequest struct {
+	BatteryData []BatteryData    `json:"batteryData" validate:"required,min=1,dive"`
+	EventType   BatterySwapEvent `json:"eventType" validate:"required,batterySwapEvent21"`
+	IdToken     types.IdToken    `json:"idToken"`
+	RequestID   int              `json:"requestId" validate:"gte=0"`
+}
+
+// This field definition of the BatterySwap response payload, sent by the CSMS to the Charging Station in response to a BatterySwapRequest.
+// In case the request was invalid, or couldn't be processed, an error will be sent instead.
+type BatterySwapResponse struct {
+}
+
+// A battery swap station sends a BatterySwapRequest whenever batteries were inserted or taken out during a swap,
+// identified by the requestId of a preceding RequestBatterySwapRequest (or of a locally authorized swap).
+// The CSMS acknowledges the event with a BatterySwapResponse.
+type BatterySwapFeature struct{}
+
+func (f BatterySwapFeature) GetFeatureName() string {
+	return BatterySwapFeatureName
+}
+
+func (f BatterySwapFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(BatterySwapRequest{})
+}
+
+func (f BatterySwapFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(BatterySwapResponse{})
+}
+
+func (r BatterySwapRequest) GetFeatureName() string {
+	return BatterySwapFeatureName
+}
+
+func (c BatterySwapResponse) GetFeatureName() string {
+	return BatterySwapFeatureName
+}
+
+// Creates a new BatterySwapRequest, containing all required fields. There are no optional fields for this message.
+func NewBatterySwapRequest(requestID int, eventType BatterySwapEvent, idToken types.IdToken, batteryData []BatteryData) *BatterySwapRequest {
+	return &BatterySwapRequest{RequestID: requestID, EventType: eventType, IdToken: idToken, BatteryData: batteryData}
+}
+
+// Creates a new BatterySwapResponse, which doesn't contain any fields.
+func NewBatterySwapResponse() *BatterySwapResponse {
+	return &BatterySwapResponse{}
+}
+
+func init() {
+	_ = types.Validate.RegisterValidation("batterySwapEvent21", isValidBatterySwapEvent)
+}
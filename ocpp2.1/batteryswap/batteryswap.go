@@ -0,0 +1,30 @@
+// The Battery swap functional block contains OCPP 2.1 features for battery swap stations,
+// which exchange the batteries of EVs instead of charging them, as well as the allowed energy transfer modes of a transaction.
+package batteryswap
+
+import (
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// Needs to be implemented by a CSMS for handling messages part of the OCPP 2.1 Battery swap profile.
+type CSMSHandler interface {
+	// OnBatterySwap is called on the CSMS whenever a BatterySwapRequest is received from a charging station.
+	OnBatterySwap(chargingStationID string, request *BatterySwapRequest) (response *BatterySwapResponse, err error)
+}
+
+// Needs to be implemented by Charging stations for handling messages part of the OCPP 2.1 Battery swap profile.
+type ChargingStationHandler interface {
+	// OnRequestBatterySwap is called on a charging station whenever a RequestBatterySwapRequest is received from the CSMS.
+	OnRequestBatterySwap(request *RequestBatterySwapRequest) (response *RequestBatterySwapResponse, err error)
+	// OnNotifyAllowedEnergyTransfer is called on a charging station whenever a NotifyAllowedEnergyTransferRequest is received from the CSMS.
+	OnNotifyAllowedEnergyTransfer(request *NotifyAllowedEnergyTransferRequest) (response *NotifyAllowedEnergyTransferResponse, err error)
+}
+
+const ProfileName = "batterySwap"
+
+var Profile = ocpp.NewProfile(
+	ProfileName,
+	BatterySwapFeature{},
+	NotifyAllowedEnergyTransferFeature{},
+	RequestBatterySwapFeature{},
+)
@@ -0,0 +1,112 @@
+package batteryswap
+
+import (
+	"reflect"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// -------------------- Notify Allowed Energy Transfer (CSMS -> CS) --------------------
+
+const NotifyAllowedEnergyTransferFeatureName = "NotifyAllowedEnergyTransfer"
+
+// EnergyTransferMode is an energy transfer mode as defined by OCPP 2.1, which extends the 2.0.1 modes with bidirectional modes.
+type EnergyTransferMode string
+
+const (
+	EnergyTransferModeACSinglePhase EnergyTransferMode = "AC_single_phase"
+	EnergyTransferModeACTwoPhase    EnergyTransferMode = "AC_two_phase"
+	EnergyTransferModeACThreePhase  EnergyTransferMode = "AC_three_phase"
+	EnergyTransferModeDC            EnergyTransferMode = "DC"
+	EnergyTransferModeACBPT         EnergyTransferMode = "AC_BPT"
+	EnergyTransferModeACBPTDER      EnergyTransferMode = "AC_BPT_DER"
+	EnergyTransferModeACDER         EnergyTransferMode = "AC_DER"
+	EnergyTransferModeDCBPT         EnergyTransferMode = "DC_BPT"
+	EnergyTransferModeDCACDP        EnergyTransferMode = "DC_ACDP"
+	EnergyTransferModeDCACDPBPT     EnergyTransferMode = "DC_ACDP_BPT"
+	EnergyTransferModeWPT           EnergyTransferMode = "WPT"
+)
+
+func isValidEnergyTransferMode(fl validator.FieldLevel) bool {
+	mode := EnergyTransferMode(fl.Field().String())
+	switch mode {
+	case EnergyTransferModeACSinglePhase, EnergyTransferModeACTwoPhase, EnergyTransferModeACThreePhase, EnergyTransferModeDC,
+		EnergyTransferModeACBPT, EnergyTransferModeACBPTDER, EnergyTransferModeACDER, EnergyTransferModeDCBPT,
+		EnergyTransferModeDCACDP, EnergyTransferModeDCACDPBPT, EnergyTransferModeWPT:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status reported in NotifyAllowedEnergyTransferResponse.
+type NotifyAllowedEnergyTransferStatus string
+
+const (
+	NotifyAllowedEnergyTransferStatusAccepted NotifyAllowedEnergyTransferStatus = "Accepted"
+	NotifyAllowedEnergyTransferStatusRejected NotifyAllowedEnergyTransferStatus = "Rejected"
+)
+
+func isValidNotifyAllowedEnergyTransferStatus(fl validator.FieldLevel) bool {
+	status := NotifyAllowedEnergyTransferStatus(fl.Field().String())
+	switch status {
+	case NotifyAllowedEnergyTransferStatusAccepted, NotifyAllowedEnergyTransferStatusRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// The field definition of the NotifyAllowedEnergyTransfer request payload sent by the CSMS to the Charging Station.
+type NotifyAllowedEnergyTransferRequest struct {
+	TransactionID         string               `json:"transactionId" validate:"required,max=36"`
+	AllowedEnergyTransfer []EnergyTransferMode `json:"allowedEnergyTransfer" validate:"required,min=1,dive,energyTransferMode21"`
+}
+
+// This field definition of the NotifyAllowedEnergyTransfer response payload, sent by the Charging Station to the CSMS in response to a NotifyAllowedEnergyTransferRequest.
+// In case the request was invalid, or couldn't be processed, an error will be sent instead.
+type NotifyAllowedEnergyTransferResponse struct {
+	Status     NotifyAllowedEnergyTransferStatus `json:"status" validate:"required,notifyAllowedEnergyTransferStatus21"`
+	StatusInfo *types.StatusInfo                 `json:"statusInfo,omitempty" validate:"omitempty"`
+}
+
+// The CSMS sends a NotifyAllowedEnergyTransferRequest to update the energy transfer modes allowed for an ongoing transaction,
+// e.g. to allow bidirectional power transfer. The Charging Station responds with a NotifyAllowedEnergyTransferResponse.
+type NotifyAllowedEnergyTransferFeature struct{}
+
+func (f NotifyAllowedEnergyTransferFeature) GetFeatureName() string {
+	return NotifyAllowedEnergyTransferFeatureName
+}
+
+func (f NotifyAllowedEnergyTransferFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(NotifyAllowedEnergyTransferRequest{})
+}
+
+func (f NotifyAllowedEnergyTransferFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(NotifyAllowedEnergyTransferResponse{})
+}
+
+func (r NotifyAllowedEnergyTransferRequest) GetFeatureName() string {
+	return NotifyAllowedEnergyTransferFeatureName
+}
+
+func (c NotifyAllowedEnergyTransferResponse) GetFeatureName() string {
+	return NotifyAllowedEnergyTransferFeatureName
+}
+
+// Creates a new NotifyAllowedEnergyTransferRequest, containing all required fields. There are no optional fields for this message.
+func NewNotifyAllowedEnergyTransferRequest(transactionID string, allowedEnergyTransfer []EnergyTransferMode) *NotifyAllowedEnergyTransferRequest {
+	return &NotifyAllowedEnergyTransferRequest{TransactionID: transactionID, AllowedEnergyTransfer: allowedEnergyTransfer}
+}
+
+// Creates a new NotifyAllowedEnergyTransferResponse, containing all required fields. Optional fields may be set afterwards.
+func NewNotifyAllowedEnergyTransferResponse(status NotifyAllowedEnergyTransferStatus) *NotifyAllowedEnergyTransferResponse {
+	return &NotifyAllowedEnergyTransferResponse{Status: status}
+}
+
+func init() {
+	_ = types.Validate.RegisterValidation("energyTransferMode21", isValidEnergyTransferMode)
+	_ = types.Validate.RegisterValidation("notifyAllowedEnergyTransferStatus21", isValidNotifyAllowedEnergyTransferStatus)
+}
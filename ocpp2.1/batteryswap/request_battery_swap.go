@@ -0,0 +1,59 @@
+package batteryswap
+
+import (
+	"reflect"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// -------------------- Request Battery Swap (CSMS -> CS) --------------------
+
+const RequestBatterySwapFeatureName = "RequestBatterySwap"
+
+// The field definition of the RequestBatterySwap request payload sent by the CSMS to the Charging Station.
+type RequestBatterySwapRequest struct {
+	IdToken   types.IdToken `json:"idToken"`
+	RequestID int           `json:"requestId" validate:"gte=0"`
+}
+
+// This field definition of the RequestBatterySwap response payload, sent by the Charging Station to the CSMS in response to a RequestBatterySwapRequest.
+// In case the request was invalid, or couldn't be processed, an error will be sent instead.
+type RequestBatterySwapResponse struct {
+	Status     types.GenericStatus `json:"status" validate:"required,genericStatus"`
+	StatusInfo *types.StatusInfo   `json:"statusInfo,omitempty" validate:"omitempty"`
+}
+
+// The CSMS sends a RequestBatterySwapRequest to remotely start a battery swap for the given IdToken,
+// e.g. after a driver authorized the swap via an app. The Charging Station responds with a RequestBatterySwapResponse,
+// and reports the swap via BatterySwap messages carrying the same requestId.
+type RequestBatterySwapFeature struct{}
+
+func (f RequestBatterySwapFeature) GetFeatureName() string {
+	return RequestBatterySwapFeatureName
+}
+
+func (f RequestBatterySwapFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(RequestBatterySwapRequest{})
+}
+
+func (f RequestBatterySwapFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(RequestBatterySwapResponse{})
+}
+
+func (r RequestBatterySwapRequest) GetFeatureName() string {
+	return RequestBatterySwapFeatureName
+}
+
+func (c RequestBatterySwapResponse) GetFeatureName() string {
+	return RequestBatterySwapFeatureName
+}
+
+// Creates a new RequestBatterySwapRequest, containing all required fields. There are no optional fields for this message.
+func NewRequestBatterySwapRequest(requestID int, idToken types.IdToken) *RequestBatterySwapRequest {
+	return &RequestBatterySwapRequest{RequestID: requestID, IdToken: idToken}
+}
+
+// Creates a new RequestBatterySwapResponse, containing all required fields. Optional fields may be set afterwards.
+func NewRequestBatterySwapResponse(status types.GenericStatus) *RequestBatterySwapResponse {
+	return &RequestBatterySwapResponse{Status: status}
+}
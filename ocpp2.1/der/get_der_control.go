@@ -0,0 +1,62 @@
+package der
+
+import (
+	"reflect"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// -------------------- Get DER Control (CSMS -> CS) --------------------
+
+const GetDERControlFeatureName = "GetDERControl"
+
+// The field definition of the GetDERControl request payload sent by the CSMS to the Charging Station.
+// All filters are optional: omitting them requests all configured controls.
+type GetDERControlRequest struct {
+	RequestID   int            `json:"requestId" validate:"gte=0"`
+	IsDefault   *bool          `json:"isDefault,omitempty" validate:"omitempty"`
+	ControlType DERControlType `json:"controlType,omitempty" validate:"omitempty,derControlType21"`
+	ControlID   string         `json:"controlId,omitempty" validate:"omitempty,max=36"`
+}
+
+// This field definition of the GetDERControl response payload, sent by the Charging Station to the CSMS in response to a GetDERControlRequest.
+// In case the request was invalid, or couldn't be processed, an error will be sent instead.
+type GetDERControlResponse struct {
+	Status     DERControlStatus  `json:"status" validate:"required,derControlStatus21"`
+	StatusInfo *types.StatusInfo `json:"statusInfo,omitempty" validate:"omitempty"`
+}
+
+// The CSMS sends a GetDERControlRequest to retrieve the DER controls configured on a Charging Station.
+// The Charging Station responds with a GetDERControlResponse, and, if accepted,
+// reports the matching controls asynchronously via one or more ReportDERControl messages, carrying the same requestId.
+type GetDERControlFeature struct{}
+
+func (f GetDERControlFeature) GetFeatureName() string {
+	return GetDERControlFeatureName
+}
+
+func (f GetDERControlFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(GetDERControlRequest{})
+}
+
+func (f GetDERControlFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(GetDERControlResponse{})
+}
+
+func (r GetDERControlRequest) GetFeatureName() string {
+	return GetDERControlFeatureName
+}
+
+func (c GetDERControlResponse) GetFeatureName() string {
+	return GetDERControlFeatureName
+}
+
+// Creates a new GetDERControlRequest, containing all required fields. Optional fields may be set afterwards.
+func NewGetDERControlRequest(requestID int) *GetDERControlRequest {
+	return &GetDERControlRequest{RequestID: requestID}
+}
+
+// Creates a new GetDERControlResponse, containing all required fields. Optional fields may be set afterwards.
+func NewGetDERControlResponse(status DERControlStatus) *GetDERControlResponse {
+	return &GetDERControlResponse{Status: status}
+}
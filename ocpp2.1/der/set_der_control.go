@@ -0,0 +1,126 @@
+package der
+
+import (
+	"reflect"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// -------------------- Set DER Control (CSMS -> CS) --------------------
+
+const SetDERControlFeatureName = "SetDERControl"
+
+// The field definition of the SetDERControl request payload sent by the CSMS to the Charging Station.
+//
+// Exactly one parameter set must be provided, which must match the control type:
+// Curve for all curve-based control types, otherwise the field named after the control type
+// (Gradient for the Gradients control type).
+type SetDERControlRequest struct {
+	IsDefault         bool               `json:"isDefault"`
+	ControlID         string             `json:"controlId" validate:"required,max=36"`
+	ControlType       DERControlType     `json:"controlType" validate:"required,derControlType21"`
+	Curve             *DERCurve          `json:"curve,omitempty" validate:"omitempty"`
+	EnterService      *EnterService      `json:"enterService,omitempty" validate:"omitempty"`
+	FixedPFAbsorb     *FixedPF           `json:"fixedPFAbsorb,omitempty" validate:"omitempty"`
+	FixedPFInject     *FixedPF           `json:"fixedPFInject,omitempty" validate:"omitempty"`
+	FixedVar          *FixedVar          `json:"fixedVar,omitempty" validate:"omitempty"`
+	FreqDroop         *FreqDroop         `json:"freqDroop,omitempty" validate:"omitempty"`
+	Gradient          *Gradient          `json:"gradient,omitempty" validate:"omitempty"`
+	LimitMaxDischarge *LimitMaxDischarge `json:"limitMaxDischarge,omitempty" validate:"omitempty"`
+}
+
+// This field definition of the SetDERControl response payload, sent by the Charging Station to the CSMS in response to a SetDERControlRequest.
+// In case the request was invalid, or couldn't be processed, an error will be sent instead.
+type SetDERControlResponse struct {
+	Status        DERControlStatus  `json:"status" validate:"required,derControlStatus21"`
+	SupersededIDs []string          `json:"supersededIds,omitempty" validate:"omitempty,max=24,dive,max=36"`
+	StatusInfo    *types.StatusInfo `json:"statusInfo,omitempty" validate:"omitempty"`
+}
+
+// The CSMS sends a SetDERControlRequest to configure a DER control function on a Charging Station,
+// either as a default control or as a scheduled control.
+// The Charging Station responds with a SetDERControlResponse, listing the controls superseded by the new one.
+type SetDERControlFeature struct{}
+
+func (f SetDERControlFeature) GetFeatureName() string {
+	return SetDERControlFeatureName
+}
+
+func (f SetDERControlFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(SetDERControlRequest{})
+}
+
+func (f SetDERControlFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(SetDERControlResponse{})
+}
+
+func (r SetDERControlRequest) GetFeatureName() string {
+	return SetDERControlFeatureName
+}
+
+func (c SetDERControlResponse) GetFeatureName() string {
+	return SetDERControlFeatureName
+}
+
+// Creates a new SetDERControlRequest, containing all required fields.
+// The parameter set matching the control type must be set afterwards.
+func NewSetDERControlRequest(isDefault bool, controlID string, controlType DERControlType) *SetDERControlRequest {
+	return &SetDERControlRequest{IsDefault: isDefault, ControlID: controlID, ControlType: controlType}
+}
+
+// Creates a new SetDERControlResponse, containing all required fields. Optional fields may be set afterwards.
+func NewSetDERControlResponse(status DERControlStatus) *SetDERControlResponse {
+	return &SetDERControlResponse{Status: status}
+}
+
+// Returns the name of the field, which must contain the parameters for the passed control type.
+func parameterFieldForControlType(controlType DERControlType) string {
+	switch controlType {
+	case DERControlEnterService:
+		return "EnterService"
+	case DERControlFixedPFAbsorb:
+		return "FixedPFAbsorb"
+	case DERControlFixedPFInject:
+		return "FixedPFInject"
+	case DERControlFixedVar:
+		return "FixedVar"
+	case DERControlFreqDroop:
+		return "FreqDroop"
+	case DERControlGradients:
+		return "Gradient"
+	case DERControlLimitMaxDischarge:
+		return "LimitMaxDischarge"
+	default:
+		return "Curve"
+	}
+}
+
+func isValidSetDERControlRequest(sl validator.StructLevel) {
+	request := sl.Current().Interface().(SetDERControlRequest)
+	// Parameter sets are checked in a fixed order, for errors to be reported deterministically
+	parameters := []struct {
+		field string
+		set   bool
+	}{
+		{"Curve", request.Curve != nil},
+		{"EnterService", request.EnterService != nil},
+		{"FixedPFAbsorb", request.FixedPFAbsorb != nil},
+		{"FixedPFInject", request.FixedPFInject != nil},
+		{"FixedVar", request.FixedVar != nil},
+		{"FreqDroop", request.FreqDroop != nil},
+		{"Gradient", request.Gradient != nil},
+		{"LimitMaxDischarge", request.LimitMaxDischarge != nil},
+	}
+	expected := parameterFieldForControlType(request.ControlType)
+	for _, parameter := range parameters {
+		if (parameter.field == expected) != parameter.set {
+			sl.ReportError(nil, parameter.field, parameter.field, "derControlParameters21", string(request.ControlType))
+		}
+	}
+}
+
+func init() {
+	types.Validate.RegisterStructValidation(isValidSetDERControlRequest, SetDERControlRequest{})
+}
@@ -0,0 +1,274 @@
+package der
+
+import (
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// DERControlType identifies a DER control function.
+type DERControlType string
+
+const (
+	DERControlEnterService            DERControlType = "EnterService"
+	DERControlFreqDroop               DERControlType = "FreqDroop"
+	DERControlFreqWatt                DERControlType = "FreqWatt"
+	DERControlFixedPFAbsorb           DERControlType = "FixedPFAbsorb"
+	DERControlFixedPFInject           DERControlType = "FixedPFInject"
+	DERControlFixedVar                DERControlType = "FixedVar"
+	DERControlGradients               DERControlType = "Gradients"
+	DERControlHFMustTrip              DERControlType = "HFMustTrip"
+	DERControlHFMayTrip               DERControlType = "HFMayTrip"
+	DERControlHVMustTrip              DERControlType = "HVMustTrip"
+	DERControlHVMomCess               DERControlType = "HVMomCess"
+	DERControlHVMayTrip               DERControlType = "HVMayTrip"
+	DERControlLimitMaxDischarge       DERControlType = "LimitMaxDischarge"
+	DERControlLFMustTrip              DERControlType = "LFMustTrip"
+	DERControlLVMustTrip              DERControlType = "LVMustTrip"
+	DERControlLVMomCess               DERControlType = "LVMomCess"
+	DERControlLVMayTrip               DERControlType = "LVMayTrip"
+	DERControlPowerMonitoringMustTrip DERControlType = "PowerMonitoringMustTrip"
+	DERControlVoltVar                 DERControlType = "VoltVar"
+	DERControlVoltWatt                DERControlType = "VoltWatt"
+	DERControlWattPF                  DERControlType = "WattPF"
+	DERControlWattVar                 DERControlType = "WattVar"
+)
+
+// IsCurve returns true, if the control function is defined by a DERCurve.
+func (t DERControlType) IsCurve() bool {
+	switch t {
+	case DERControlFreqWatt, DERControlHFMustTrip, DERControlHFMayTrip, DERControlHVMustTrip, DERControlHVMomCess, DERControlHVMayTrip,
+		DERControlLFMustTrip, DERControlLVMustTrip, DERControlLVMomCess, DERControlLVMayTrip, DERControlPowerMonitoringMustTrip,
+		DERControlVoltVar, DERControlVoltWatt, DERControlWattPF, DERControlWattVar:
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidDERControlType(fl validator.FieldLevel) bool {
+	controlType := DERControlType(fl.Field().String())
+	switch controlType {
+	case DERControlEnterService, DERControlFreqDroop, DERControlFixedPFAbsorb, DERControlFixedPFInject, DERControlFixedVar,
+		DERControlGradients, DERControlLimitMaxDischarge:
+		return true
+	default:
+		return controlType.IsCurve()
+	}
+}
+
+// DERControlStatus is reported in response to a DER control request.
+type DERControlStatus string
+
+const (
+	DERControlStatusAccepted     DERControlStatus = "Accepted"
+	DERControlStatusRejected     DERControlStatus = "Rejected"
+	DERControlStatusNotSupported DERControlStatus = "NotSupported"
+	DERControlStatusNotFound     DERControlStatus = "NotFound"
+)
+
+func isValidDERControlStatus(fl validator.FieldLevel) bool {
+	status := DERControlStatus(fl.Field().String())
+	switch status {
+	case DERControlStatusAccepted, DERControlStatusRejected, DERControlStatusNotSupported, DERControlStatusNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// DERUnit is the unit of the y-axis of a DER curve, or of a fixed setpoint.
+type DERUnit string
+
+const (
+	DERUnitNotApplicable DERUnit = "Not_Applicable"
+	DERUnitPctMaxW       DERUnit = "PctMaxW"
+	DERUnitPctMaxVar     DERUnit = "PctMaxVar"
+	DERUnitPctWAvail     DERUnit = "PctWAvail"
+	DERUnitPctVarAvail   DERUnit = "PctVarAvail"
+	DERUnitPctEffectiveV DERUnit = "PctEffectiveV"
+)
+
+func isValidDERUnit(fl validator.FieldLevel) bool {
+	unit := DERUnit(fl.Field().String())
+	switch unit {
+	case DERUnitNotApplicable, DERUnitPctMaxW, DERUnitPctMaxVar, DERUnitPctWAvail, DERUnitPctVarAvail, DERUnitPctEffectiveV:
+		return true
+	default:
+		return false
+	}
+}
+
+// PowerDuringCessation defines which power is provided during a momentary cessation.
+type PowerDuringCessation string
+
+const (
+	PowerDuringCessationActive   PowerDuringCessation = "Active"
+	PowerDuringCessationReactive PowerDuringCessation = "Reactive"
+)
+
+func isValidPowerDuringCessation(fl validator.FieldLevel) bool {
+	switch PowerDuringCessation(fl.Field().String()) {
+	case PowerDuringCessationActive, PowerDuringCessationReactive:
+		return true
+	default:
+		return false
+	}
+}
+
+// DERCurvePoints is a single point of a DER curve.
+type DERCurvePoints struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Hysteresis parameters of a DER curve.
+type Hysteresis struct {
+	HysteresisHigh     *float64 `json:"hysteresisHigh,omitempty" validate:"omitempty"`
+	HysteresisLow      *float64 `json:"hysteresisLow,omitempty" validate:"omitempty"`
+	HysteresisDelay    *float64 `json:"hysteresisDelay,omitempty" validate:"omitempty"`
+	HysteresisGradient *float64 `json:"hysteresisGradient,omitempty" validate:"omitempty"`
+}
+
+// Voltage parameters of a DER curve, used by the voltage trip control functions.
+type VoltageParams struct {
+	Hv10MinMeanValue     *float64             `json:"hv10MinMeanValue,omitempty" validate:"omitempty"`
+	Hv10MinMeanTripDelay *float64             `json:"hv10MinMeanTripDelay,omitempty" validate:"omitempty"`
+	PowerDuringCessation PowerDuringCessation `json:"powerDuringCessation,omitempty" validate:"omitempty,powerDuringCessation21"`
+}
+
+// Reactive power parameters of a DER curve, used by the VoltVar control function.
+type ReactivePowerParams struct {
+	VRef                       *float64 `json:"vRef,omitempty" validate:"omitempty"`
+	AutonomousVRefEnable       *bool    `json:"autonomousVRefEnable,omitempty" validate:"omitempty"`
+	AutonomousVRefTimeConstant *float64 `json:"autonomousVRefTimeConstant,omitempty" validate:"omitempty"`
+}
+
+// DERCurve defines a curve-based DER control function, e.g. VoltVar or one of the trip settings.
+type DERCurve struct {
+	CurveData           []DERCurvePoints     `json:"curveData" validate:"required,min=1,max=10,dive"`
+	Hysteresis          *Hysteresis          `json:"hysteresis,omitempty" validate:"omitempty"`
+	Priority            int                  `json:"priority" validate:"gte=0"`
+	ReactivePowerParams *ReactivePowerParams `json:"reactivePowerParams,omitempty" validate:"omitempty"`
+	VoltageParams       *VoltageParams       `json:"voltageParams,omitempty" validate:"omitempty"`
+	YUnit               DERUnit              `json:"yUnit" validate:"required,derUnit21"`
+	ResponseTime        *float64             `json:"responseTime,omitempty" validate:"omitempty,gte=0"`
+	StartTime           *types.DateTime      `json:"startTime,omitempty" validate:"omitempty"`
+	Duration            *float64             `json:"duration,omitempty" validate:"omitempty,gte=0"`
+}
+
+// EnterService defines the conditions under which a DER may enter service.
+type EnterService struct {
+	Priority    int      `json:"priority" validate:"gte=0"`
+	HighVoltage float64  `json:"highVoltage"`
+	LowVoltage  float64  `json:"lowVoltage"`
+	HighFreq    float64  `json:"highFreq"`
+	LowFreq     float64  `json:"lowFreq"`
+	Delay       *float64 `json:"delay,omitempty" validate:"omitempty,gte=0"`
+	RandomDelay *float64 `json:"randomDelay,omitempty" validate:"omitempty,gte=0"`
+	RampRate    *float64 `json:"rampRate,omitempty" validate:"omitempty,gte=0"`
+}
+
+// FixedPF defines a fixed power factor, used by the FixedPFAbsorb and FixedPFInject control functions.
+type FixedPF struct {
+	Priority     int             `json:"priority" validate:"gte=0"`
+	Displacement float64         `json:"displacement"`
+	Excitation   bool            `json:"excitation"`
+	StartTime    *types.DateTime `json:"startTime,omitempty" validate:"omitempty"`
+	Duration     *float64        `json:"duration,omitempty" validate:"omitempty,gte=0"`
+}
+
+// FixedVar defines a fixed reactive power setpoint.
+type FixedVar struct {
+	Priority  int             `json:"priority" validate:"gte=0"`
+	Setpoint  float64         `json:"setpoint"`
+	Unit      DERUnit         `json:"unit" validate:"required,derUnit21"`
+	StartTime *types.DateTime `json:"startTime,omitempty" validate:"omitempty"`
+	Duration  *float64        `json:"duration,omitempty" validate:"omitempty,gte=0"`
+}
+
+// FreqDroop defines the frequency droop parameters.
+type FreqDroop struct {
+	Priority     int             `json:"priority" validate:"gte=0"`
+	OverFreq     float64         `json:"overFreq"`
+	UnderFreq    float64         `json:"underFreq"`
+	OverDroop    float64         `json:"overDroop"`
+	UnderDroop   float64         `json:"underDroop"`
+	ResponseTime float64         `json:"responseTime" validate:"gte=0"`
+	StartTime    *types.DateTime `json:"startTime,omitempty" validate:"omitempty"`
+	Duration     *float64        `json:"duration,omitempty" validate:"omitempty,gte=0"`
+}
+
+// Gradient defines the default and soft-start ramp rates.
+type Gradient struct {
+	Priority     int     `json:"priority" validate:"gte=0"`
+	Gradient     float64 `json:"gradient" validate:"gte=0"`
+	SoftGradient float64 `json:"softGradient" validate:"gte=0"`
+}
+
+// LimitMaxDischarge limits the maximum discharge power of a DER.
+type LimitMaxDischarge struct {
+	Priority                int             `json:"priority" validate:"gte=0"`
+	PctMaxDischargePower    *float64        `json:"pctMaxDischargePower,omitempty" validate:"omitempty,gte=0,lte=100"`
+	PowerMonitoringMustTrip *DERCurve       `json:"powerMonitoringMustTrip,omitempty" validate:"omitempty"`
+	StartTime               *types.DateTime `json:"startTime,omitempty" validate:"omitempty"`
+	Duration                *float64        `json:"duration,omitempty" validate:"omitempty,gte=0"`
+}
+
+// Fields shared by all DER controls reported via ReportDERControl.
+type DERControlInfo struct {
+	ID           string `json:"id" validate:"required,max=36"`
+	IsDefault    bool   `json:"isDefault"`
+	IsSuperseded bool   `json:"isSuperseded"`
+}
+
+type DERCurveGet struct {
+	DERControlInfo
+	CurveType DERControlType `json:"curveType" validate:"required,derControlType21"`
+	Curve     DERCurve       `json:"curve"`
+}
+
+type EnterServiceGet struct {
+	ID           string       `json:"id" validate:"required,max=36"`
+	EnterService EnterService `json:"enterService"`
+}
+
+type FixedPFGet struct {
+	DERControlInfo
+	FixedPF FixedPF `json:"fixedPF"`
+}
+
+type FixedVarGet struct {
+	DERControlInfo
+	FixedVar FixedVar `json:"fixedVar"`
+}
+
+type FreqDroopGet struct {
+	DERControlInfo
+	FreqDroop FreqDroop `json:"freqDroop"`
+}
+
+type GradientGet struct {
+	ID       string   `json:"id" validate:"required,max=36"`
+	Gradient Gradient `json:"gradient"`
+}
+
+type LimitMaxDischargeGet struct {
+	DERControlInfo
+	LimitMaxDischarge LimitMaxDischarge `json:"limitMaxDischarge"`
+}
+
+func isValidDERCurveGet(sl validator.StructLevel) {
+	curve := sl.Current().Interface().(DERCurveGet)
+	if !curve.CurveType.IsCurve() {
+		sl.ReportError(curve.CurveType, "CurveType", "curveType", "derCurveType21", "")
+	}
+}
+
+func init() {
+	_ = types.Validate.RegisterValidation("derControlType21", isValidDERControlType)
+	_ = types.Validate.RegisterValidation("derControlStatus21", isValidDERControlStatus)
+	_ = types.Validate.RegisterValidation("derUnit21", isValidDERUnit)
+	_ = types.Validate.RegisterValidation("powerDuringCessation21", isValidPowerDuringCessation)
+	types.Validate.RegisterStructValidation(isValidDERCurveGet, DERCurveGet{})
+}
@@ -0,0 +1,9 @@
+// Contains common and shared data types between OCPP 2.1 messages.
+//
+// OCPP 2.1 is backwards compatible with OCPP 2.0.1, hence all types shared by both versions
+// (e.g. IdToken, StatusInfo and DateTime), as well as the validator, are defined in the ocpp2.0.1/types package.
+package types
+
+const (
+	V21Subprotocol = "ocpp2.1"
+)
@@ -0,0 +1,107 @@
+// The package contains an implementation of the OCPP 2.1 communication protocol between a Charging Station and a Charging Station Management System in an EV charging infrastructure.
+//
+// OCPP 2.1 extends OCPP 2.0.1, hence the endpoints of this package are OCPP 2.0.1 endpoints, which negotiate the ocpp2.1 websocket subprotocol
+// and additionally support the functional blocks introduced by OCPP 2.1:
+//
+//   - Battery swap (see the batteryswap package)
+//   - DER control (see the der package)
+//
+// Messages shared with OCPP 2.0.1 (e.g. BootNotification or Heartbeat) are defined in the ocpp2.0.1 packages and handled exactly like on an OCPP 2.0.1 endpoint.
+package ocpp21
+
+import (
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	ocpp2 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/authorization"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/data"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/diagnostics"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/display"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/firmware"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/iso15118"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/localauth"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/meter"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/remotecontrol"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/reservation"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/security"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/smartcharging"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/tariffcost"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/transactions"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/batteryswap"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/der"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+// An OCPP 2.1 Charging Station. It supports all OCPP 2.0.1 messages, as well as the OCPP 2.1 battery swap and DER control messages, e.g.:
+//
+//	chargingStation.SetDERControlHandler(handler)
+//	bootConf, err := chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+//	reportConf, err := chargingStation.ReportDERControl(requestID)
+type ChargingStation = ocpp2.ChargingStation
+
+// An OCPP 2.1 CSMS. It supports all OCPP 2.0.1 messages, as well as the OCPP 2.1 battery swap and DER control messages, e.g.:
+//
+//	csms.SetProvisioningHandler(handler)
+//	csms.SetDERControlHandler(handler)
+//	err := csms.GetDERControl(chargingStationID, myCallback, requestID)
+type CSMS = ocpp2.CSMS
+
+type ChargingStationConnection = ocpp2.ChargingStationConnection
+
+type ChargingStationConnectionHandler = ocpp2.ChargingStationConnectionHandler
+
+// Returns the profiles supported by OCPP 2.1 endpoints, i.e. all OCPP 2.0.1 profiles and the profiles introduced by OCPP 2.1.
+func profiles() []*ocpp.Profile {
+	return []*ocpp.Profile{authorization.Profile, availability.Profile, data.Profile, diagnostics.Profile, display.Profile, firmware.Profile, iso15118.Profile, localauth.Profile, meter.Profile, provisioning.Profile, remotecontrol.Profile, reservation.Profile, security.Profile, smartcharging.Profile, tariffcost.Profile, transactions.Profile, batteryswap.Profile, der.Profile}
+}
+
+// Creates a new OCPP 2.1 charging station client.
+// The id parameter is required to uniquely identify the charging station.
+//
+// The endpoint and client parameters may be omitted, in order to use a default configuration:
+//
+//	chargingStation := NewChargingStation("someUniqueId", nil, nil)
+//
+// A custom endpoint must register the battery swap and DER control profiles, for the respective messages to be supported.
+// For more advanced options, or if a custom networking/occpj layer is required,
+// please refer to ocppj.Client and ws.WsClient.
+func NewChargingStation(id string, endpoint *ocppj.Client, client ws.WsClient) ChargingStation {
+	if client == nil {
+		client = ws.NewClient()
+	}
+	if endpoint == nil {
+		dispatcher := ocppj.NewDefaultClientDispatcher(ocppj.NewFIFOClientQueue(0))
+		endpoint = ocppj.NewClient(id, client, dispatcher, nil, profiles()...)
+	}
+	chargingStation := ocpp2.NewChargingStation(id, endpoint, client)
+	// Replaces the OCPP 2.0.1 subprotocol requested by default
+	client.SetRequestedSubProtocol(types.V21Subprotocol)
+	return chargingStation
+}
+
+// Creates a new OCPP 2.1 CSMS.
+//
+// The endpoint and server parameters may be omitted, in order to use a default configuration:
+//
+//	csms := NewCSMS(nil, nil)
+//
+// If you need a TLS server, you may use the following:
+//
+//	csms := NewCSMS(nil, ws.NewTLSServer("certificatePath", "privateKeyPath"))
+//
+// The CSMS accepts charging stations connecting via both the ocpp2.1 and the ocpp2.0.1 subprotocols.
+// A custom endpoint must register the battery swap and DER control profiles, for the respective messages to be supported.
+func NewCSMS(endpoint *ocppj.Server, server ws.WsServer) CSMS {
+	if server == nil {
+		server = ws.NewServer()
+	}
+	server.AddSupportedSubprotocol(types.V21Subprotocol)
+	if endpoint == nil {
+		dispatcher := ocppj.NewDefaultServerDispatcher(ocppj.NewFIFOQueueMap(0))
+		endpoint = ocppj.NewServer(server, dispatcher, nil, profiles()...)
+	}
+	return ocpp2.NewCSMS(endpoint, server)
+}
@@ -0,0 +1,114 @@
+package ocpp21_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/batteryswap"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+func TestBatterySwapMessagesValidation(t *testing.T) {
+	idToken := types.IdToken{IdToken: "1234", Type: types.IdTokenTypeISO14443}
+	battery := batteryswap.BatteryData{EvseID: 1, SerialNumber: "battery1", SoC: 20, SoH: 95}
+	var testTable = []GenericTestEntry{
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{battery}, EventType: batteryswap.BatterySwapEventBatteryIn, IdToken: idToken, RequestID: 1}, true},
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{{EvseID: 1, SerialNumber: "battery1", SoC: 20, SoH: 95, ProductionDate: types.NewDateTime(time.Now()), VendorInfo: "info"}}, EventType: batteryswap.BatterySwapEventBatteryOutTimeout, IdToken: idToken}, true},
+		{batteryswap.BatterySwapRequest{EventType: batteryswap.BatterySwapEventBatteryIn, IdToken: idToken}, false},
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{battery}, EventType: "invalidEvent", IdToken: idToken}, false},
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{battery}, EventType: batteryswap.BatterySwapEventBatteryIn, IdToken: types.IdToken{Type: "invalidType"}}, false},
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{{EvseID: 1, SerialNumber: "battery1", SoC: 101}}, EventType: batteryswap.BatterySwapEventBatteryIn, IdToken: idToken}, false},
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{{EvseID: 1, SoC: 20}}, EventType: batteryswap.BatterySwapEventBatteryIn, IdToken: idToken}, false},
+		{batteryswap.BatterySwapRequest{BatteryData: []batteryswap.BatteryData{battery}, EventType: batteryswap.BatterySwapEventBatteryIn, IdToken: idToken, RequestID: -1}, false},
+		{batteryswap.BatterySwapResponse{}, true},
+		{batteryswap.RequestBatterySwapRequest{IdToken: idToken, RequestID: 1}, true},
+		{batteryswap.RequestBatterySwapRequest{IdToken: idToken, RequestID: -1}, false},
+		{batteryswap.RequestBatterySwapResponse{Status: types.GenericStatusAccepted}, true},
+		{batteryswap.RequestBatterySwapResponse{Status: "invalidStatus"}, false},
+		{batteryswap.NotifyAllowedEnergyTransferRequest{TransactionID: "tx1", AllowedEnergyTransfer: []batteryswap.EnergyTransferMode{batteryswap.EnergyTransferModeDCBPT, batteryswap.EnergyTransferModeACThreePhase}}, true},
+		{batteryswap.NotifyAllowedEnergyTransferRequest{TransactionID: "tx1"}, false},
+		{batteryswap.NotifyAllowedEnergyTransferRequest{TransactionID: "tx1", AllowedEnergyTransfer: []batteryswap.EnergyTransferMode{"invalidMode"}}, false},
+		{batteryswap.NotifyAllowedEnergyTransferRequest{AllowedEnergyTransfer: []batteryswap.EnergyTransferMode{batteryswap.EnergyTransferModeDC}}, false},
+		{batteryswap.NotifyAllowedEnergyTransferResponse{Status: batteryswap.NotifyAllowedEnergyTransferStatusRejected}, true},
+		{batteryswap.NotifyAllowedEnergyTransferResponse{Status: "invalidStatus"}, false},
+	}
+	ExecuteGenericTestTable(t, testTable)
+}
+
+type batterySwapChargingStationHandler struct {
+	requestC chan *batteryswap.RequestBatterySwapRequest
+}
+
+func (h *batterySwapChargingStationHandler) OnRequestBatterySwap(request *batteryswap.RequestBatterySwapRequest) (*batteryswap.RequestBatterySwapResponse, error) {
+	h.requestC <- request
+	return batteryswap.NewRequestBatterySwapResponse(types.GenericStatusAccepted), nil
+}
+
+func (h *batterySwapChargingStationHandler) OnNotifyAllowedEnergyTransfer(request *batteryswap.NotifyAllowedEnergyTransferRequest) (*batteryswap.NotifyAllowedEnergyTransferResponse, error) {
+	return batteryswap.NewNotifyAllowedEnergyTransferResponse(batteryswap.NotifyAllowedEnergyTransferStatusAccepted), nil
+}
+
+type batterySwapCSMSHandler struct {
+	swapC chan *batteryswap.BatterySwapRequest
+}
+
+func (h *batterySwapCSMSHandler) OnBatterySwap(chargingStationID string, request *batteryswap.BatterySwapRequest) (*batteryswap.BatterySwapResponse, error) {
+	h.swapC <- request
+	return batteryswap.NewBatterySwapResponse(), nil
+}
+
+func TestBatterySwapRoundTrip(t *testing.T) {
+	stationID := "station1"
+	csms, chargingStation := startPair(t, stationID)
+	idToken := types.IdToken{IdToken: "1234", Type: types.IdTokenTypeISO14443}
+
+	// Without handler, requests are rejected by the charging station
+	errC := make(chan error, 1)
+	err := csms.RequestBatterySwap(stationID, func(response *batteryswap.RequestBatterySwapResponse, err error) {
+		errC <- err
+	}, 1, idToken)
+	require.NoError(t, err)
+	protoErr, ok := (<-errC).(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.NotSupported, protoErr.Code)
+
+	csmsHandler := &batterySwapCSMSHandler{swapC: make(chan *batteryswap.BatterySwapRequest, 1)}
+	csms.SetBatterySwapHandler(csmsHandler)
+	stationHandler := &batterySwapChargingStationHandler{requestC: make(chan *batteryswap.RequestBatterySwapRequest, 1)}
+	chargingStation.SetBatterySwapHandler(stationHandler)
+
+	// The CSMS requests a swap, which the charging station reports with the same request ID
+	responseC := make(chan *batteryswap.RequestBatterySwapResponse, 1)
+	err = csms.RequestBatterySwap(stationID, func(response *batteryswap.RequestBatterySwapResponse, err error) {
+		require.NoError(t, err)
+		responseC <- response
+	}, 2, idToken)
+	require.NoError(t, err)
+	request := <-stationHandler.requestC
+	assert.Equal(t, 2, request.RequestID)
+	assert.Equal(t, idToken, request.IdToken)
+	assert.Equal(t, types.GenericStatusAccepted, (<-responseC).Status)
+
+	battery := batteryswap.BatteryData{EvseID: 1, SerialNumber: "battery1", SoC: 15, SoH: 90}
+	response, err := chargingStation.BatterySwap(request.RequestID, batteryswap.BatterySwapEventBatteryIn, request.IdToken, []batteryswap.BatteryData{battery})
+	require.NoError(t, err)
+	assert.NotNil(t, response)
+	swap := <-csmsHandler.swapC
+	assert.Equal(t, 2, swap.RequestID)
+	assert.Equal(t, batteryswap.BatterySwapEventBatteryIn, swap.EventType)
+	assert.Equal(t, []batteryswap.BatteryData{battery}, swap.BatteryData)
+
+	// Bidirectional energy transfer may be allowed afterwards
+	notifyC := make(chan *batteryswap.NotifyAllowedEnergyTransferResponse, 1)
+	err = csms.NotifyAllowedEnergyTransfer(stationID, func(response *batteryswap.NotifyAllowedEnergyTransferResponse, err error) {
+		require.NoError(t, err)
+		notifyC <- response
+	}, "tx1", []batteryswap.EnergyTransferMode{batteryswap.EnergyTransferModeDCBPT})
+	require.NoError(t, err)
+	assert.Equal(t, batteryswap.NotifyAllowedEnergyTransferStatusAccepted, (<-notifyC).Status)
+}
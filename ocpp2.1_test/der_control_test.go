@@ -0,0 +1,231 @@
+package ocpp21_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.1/der"
+)
+
+func newCurve() *der.DERCurve {
+	return &der.DERCurve{
+		CurveData: []der.DERCurvePoints{{X: 90, Y: 100}, {X: 110, Y: 0}},
+		Priority:  1,
+		YUnit:     der.DERUnitPctMaxW,
+	}
+}
+
+func TestDERControlTypesValidation(t *testing.T) {
+	var testTable = []GenericTestEntry{
+		{der.DERCurve{CurveData: []der.DERCurvePoints{{X: 1, Y: 2}}, Priority: 0, YUnit: der.DERUnitPctMaxVar}, true},
+		{der.DERCurve{CurveData: []der.DERCurvePoints{{X: 1, Y: 2}}, Priority: 0, YUnit: der.DERUnitPctMaxVar, ResponseTime: newFloat(2), VoltageParams: &der.VoltageParams{PowerDuringCessation: der.PowerDuringCessationActive}}, true},
+		{der.DERCurve{CurveData: []der.DERCurvePoints{}, YUnit: der.DERUnitPctMaxVar}, false},
+		{der.DERCurve{CurveData: make([]der.DERCurvePoints, 11), YUnit: der.DERUnitPctMaxVar}, false},
+		{der.DERCurve{CurveData: []der.DERCurvePoints{{X: 1, Y: 2}}, YUnit: "invalidUnit"}, false},
+		{der.DERCurve{CurveData: []der.DERCurvePoints{{X: 1, Y: 2}}, Priority: -1, YUnit: der.DERUnitPctMaxVar}, false},
+		{der.DERCurve{CurveData: []der.DERCurvePoints{{X: 1, Y: 2}}, YUnit: der.DERUnitPctMaxVar, VoltageParams: &der.VoltageParams{PowerDuringCessation: "invalid"}}, false},
+		{der.FixedVar{Priority: 1, Setpoint: -20, Unit: der.DERUnitPctMaxVar}, true},
+		{der.FixedVar{Priority: 1, Setpoint: -20}, false},
+		{der.Gradient{Priority: 1, Gradient: 10, SoftGradient: 5}, true},
+		{der.Gradient{Priority: 1, Gradient: -10, SoftGradient: 5}, false},
+		{der.LimitMaxDischarge{Priority: 1, PctMaxDischargePower: newFloat(50)}, true},
+		{der.LimitMaxDischarge{Priority: 1, PctMaxDischargePower: newFloat(101)}, false},
+		{der.DERCurveGet{DERControlInfo: der.DERControlInfo{ID: "c1"}, CurveType: der.DERControlVoltVar, Curve: *newCurve()}, true},
+		{der.DERCurveGet{DERControlInfo: der.DERControlInfo{ID: "c1"}, CurveType: der.DERControlFixedVar, Curve: *newCurve()}, false},
+		{der.DERCurveGet{DERControlInfo: der.DERControlInfo{ID: "c1"}, CurveType: "invalidType", Curve: *newCurve()}, false},
+		{der.DERCurveGet{CurveType: der.DERControlVoltVar, Curve: *newCurve()}, false},
+	}
+	ExecuteGenericTestTable(t, testTable)
+}
+
+func TestSetDERControlRequestValidation(t *testing.T) {
+	var testTable = []GenericTestEntry{
+		{der.SetDERControlRequest{IsDefault: true, ControlID: "c1", ControlType: der.DERControlVoltVar, Curve: newCurve()}, true},
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlFixedVar, FixedVar: &der.FixedVar{Setpoint: 10, Unit: der.DERUnitPctMaxVar}}, true},
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlGradients, Gradient: &der.Gradient{Gradient: 1, SoftGradient: 1}}, true},
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlFixedPFAbsorb, FixedPFAbsorb: &der.FixedPF{Displacement: 0.9}}, true},
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlEnterService, EnterService: &der.EnterService{HighVoltage: 250, LowVoltage: 210, HighFreq: 50.1, LowFreq: 49.9}}, true},
+		// Missing parameters
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlVoltVar}, false},
+		// Parameters don't match the control type
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlFixedPFInject, FixedPFAbsorb: &der.FixedPF{Displacement: 0.9}}, false},
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlFixedVar, Curve: newCurve()}, false},
+		// Additional parameters
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlVoltVar, Curve: newCurve(), Gradient: &der.Gradient{}}, false},
+		// Invalid parameters
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlVoltVar, Curve: &der.DERCurve{YUnit: der.DERUnitPctMaxW}}, false},
+		{der.SetDERControlRequest{ControlType: der.DERControlVoltVar, Curve: newCurve()}, false},
+		{der.SetDERControlRequest{ControlID: ">36..................................", ControlType: der.DERControlVoltVar, Curve: newCurve()}, false},
+		{der.SetDERControlRequest{ControlID: "c1", ControlType: "invalidType", Curve: newCurve()}, false},
+		{der.SetDERControlRequest{ControlID: "c1", Curve: newCurve()}, false},
+	}
+	ExecuteGenericTestTable(t, testTable)
+}
+
+func TestSetDERControlRequestValidationOrder(t *testing.T) {
+	request := der.SetDERControlRequest{ControlID: "c1", ControlType: der.DERControlFixedVar, Gradient: &der.Gradient{Gradient: 1, SoftGradient: 1}, Curve: newCurve()}
+	for i := 0; i < 10; i++ {
+		err := types.Validate.Struct(request)
+		require.Error(t, err)
+		validationErrors, ok := err.(validator.ValidationErrors)
+		require.True(t, ok)
+		var fields []string
+		for _, fieldError := range validationErrors {
+			fields = append(fields, fieldError.Field())
+		}
+		assert.Equal(t, []string{"Curve", "FixedVar", "Gradient"}, fields)
+	}
+}
+
+func TestDERControlMessagesValidation(t *testing.T) {
+	isDefault := true
+	var testTable = []GenericTestEntry{
+		{der.SetDERControlResponse{Status: der.DERControlStatusAccepted, SupersededIDs: []string{"c0"}}, true},
+		{der.SetDERControlResponse{Status: der.DERControlStatusNotSupported}, true},
+		{der.SetDERControlResponse{Status: "invalidStatus"}, false},
+		{der.SetDERControlResponse{}, false},
+		{der.GetDERControlRequest{RequestID: 1, IsDefault: &isDefault, ControlType: der.DERControlVoltVar, ControlID: "c1"}, true},
+		{der.GetDERControlRequest{RequestID: 1}, true},
+		{der.GetDERControlRequest{RequestID: -1}, false},
+		{der.GetDERControlRequest{RequestID: 1, ControlType: "invalidType"}, false},
+		{der.GetDERControlResponse{Status: der.DERControlStatusNotFound}, true},
+		{der.GetDERControlResponse{}, false},
+		{der.ClearDERControlRequest{IsDefault: true, ControlType: der.DERControlFreqDroop}, true},
+		{der.ClearDERControlRequest{ControlID: "c1"}, true},
+		{der.ClearDERControlRequest{ControlType: "invalidType"}, false},
+		{der.ClearDERControlResponse{Status: der.DERControlStatusAccepted}, true},
+		{der.ClearDERControlResponse{Status: "invalidStatus"}, false},
+		{der.ReportDERControlRequest{RequestID: 1}, true},
+		{der.ReportDERControlRequest{RequestID: 1, Tbc: true, Curve: []der.DERCurveGet{{DERControlInfo: der.DERControlInfo{ID: "c1"}, CurveType: der.DERControlVoltVar, Curve: *newCurve()}}}, true},
+		{der.ReportDERControlRequest{RequestID: 1, Gradient: []der.GradientGet{{ID: "g1", Gradient: der.Gradient{Gradient: 1}}}}, true},
+		{der.ReportDERControlRequest{RequestID: 1, Gradient: []der.GradientGet{{Gradient: der.Gradient{Gradient: 1}}}}, false},
+		{der.ReportDERControlRequest{RequestID: 1, Curve: []der.DERCurveGet{{DERControlInfo: der.DERControlInfo{ID: "c1"}, CurveType: der.DERControlGradients, Curve: *newCurve()}}}, false},
+		{der.ReportDERControlRequest{RequestID: 1, FixedPFInject: make([]der.FixedPFGet, 25)}, false},
+		{der.ReportDERControlRequest{RequestID: -1}, false},
+		{der.ReportDERControlResponse{}, true},
+	}
+	ExecuteGenericTestTable(t, testTable)
+}
+
+// Keeps the DER controls configured on a charging station and reports them to the CSMS, once requested.
+type derChargingStationHandler struct {
+	controls        map[string]der.SetDERControlRequest
+	chargingStation interface {
+		ReportDERControl(requestID int, props ...func(request *der.ReportDERControlRequest)) (*der.ReportDERControlResponse, error)
+	}
+}
+
+func (h *derChargingStationHandler) OnSetDERControl(request *der.SetDERControlRequest) (*der.SetDERControlResponse, error) {
+	h.controls[request.ControlID] = *request
+	return der.NewSetDERControlResponse(der.DERControlStatusAccepted), nil
+}
+
+func (h *derChargingStationHandler) OnGetDERControl(request *der.GetDERControlRequest) (*der.GetDERControlResponse, error) {
+	var curves []der.DERCurveGet
+	for id, control := range h.controls {
+		if control.Curve != nil && (request.ControlType == "" || request.ControlType == control.ControlType) {
+			curves = append(curves, der.DERCurveGet{
+				DERControlInfo: der.DERControlInfo{ID: id, IsDefault: control.IsDefault},
+				CurveType:      control.ControlType,
+				Curve:          *control.Curve,
+			})
+		}
+	}
+	if len(curves) == 0 {
+		return der.NewGetDERControlResponse(der.DERControlStatusNotFound), nil
+	}
+	// Reports must be sent after responding
+	go func() {
+		_, _ = h.chargingStation.ReportDERControl(request.RequestID, func(report *der.ReportDERControlRequest) {
+			report.Curve = curves
+		})
+	}()
+	return der.NewGetDERControlResponse(der.DERControlStatusAccepted), nil
+}
+
+func (h *derChargingStationHandler) OnClearDERControl(request *der.ClearDERControlRequest) (*der.ClearDERControlResponse, error) {
+	if _, ok := h.controls[request.ControlID]; !ok {
+		return der.NewClearDERControlResponse(der.DERControlStatusNotFound), nil
+	}
+	delete(h.controls, request.ControlID)
+	return der.NewClearDERControlResponse(der.DERControlStatusAccepted), nil
+}
+
+type derCSMSHandler struct {
+	reportC chan *der.ReportDERControlRequest
+}
+
+func (h *derCSMSHandler) OnReportDERControl(chargingStationID string, request *der.ReportDERControlRequest) (*der.ReportDERControlResponse, error) {
+	h.reportC <- request
+	return der.NewReportDERControlResponse(), nil
+}
+
+func TestDERControlRoundTrip(t *testing.T) {
+	stationID := "station1"
+	csms, chargingStation := startPair(t, stationID)
+	csmsHandler := &derCSMSHandler{reportC: make(chan *der.ReportDERControlRequest, 1)}
+	csms.SetDERControlHandler(csmsHandler)
+	chargingStation.SetDERControlHandler(&derChargingStationHandler{controls: map[string]der.SetDERControlRequest{}, chargingStation: chargingStation})
+
+	// Set a curve
+	setC := make(chan *der.SetDERControlResponse, 1)
+	err := csms.SetDERControl(stationID, func(response *der.SetDERControlResponse, err error) {
+		require.NoError(t, err)
+		setC <- response
+	}, true, "voltVar1", der.DERControlVoltVar, func(request *der.SetDERControlRequest) {
+		request.Curve = newCurve()
+	})
+	require.NoError(t, err)
+	setResponse := <-setC
+	assert.Equal(t, der.DERControlStatusAccepted, setResponse.Status)
+
+	// Invalid requests aren't sent
+	err = csms.SetDERControl(stationID, func(response *der.SetDERControlResponse, err error) {
+		t.Fatal("callback must not be invoked")
+	}, true, "voltVar2", der.DERControlVoltVar)
+	require.Error(t, err)
+
+	// Get the configured curves, which are reported asynchronously
+	getC := make(chan *der.GetDERControlResponse, 1)
+	err = csms.GetDERControl(stationID, func(response *der.GetDERControlResponse, err error) {
+		require.NoError(t, err)
+		getC <- response
+	}, 42, func(request *der.GetDERControlRequest) {
+		request.ControlType = der.DERControlVoltVar
+	})
+	require.NoError(t, err)
+	getResponse := <-getC
+	assert.Equal(t, der.DERControlStatusAccepted, getResponse.Status)
+	select {
+	case report := <-csmsHandler.reportC:
+		assert.Equal(t, 42, report.RequestID)
+		require.Len(t, report.Curve, 1)
+		assert.Equal(t, "voltVar1", report.Curve[0].ID)
+		assert.True(t, report.Curve[0].IsDefault)
+		assert.Equal(t, der.DERControlVoltVar, report.Curve[0].CurveType)
+		assert.Equal(t, *newCurve(), report.Curve[0].Curve)
+	case <-time.After(time.Second):
+		t.Fatal("DER controls weren't reported")
+	}
+
+	// Clear the curve
+	clearC := make(chan *der.ClearDERControlResponse, 1)
+	clearControl := func() {
+		err = csms.ClearDERControl(stationID, func(response *der.ClearDERControlResponse, err error) {
+			require.NoError(t, err)
+			clearC <- response
+		}, true, func(request *der.ClearDERControlRequest) {
+			request.ControlID = "voltVar1"
+		})
+		require.NoError(t, err)
+	}
+	clearControl()
+	assert.Equal(t, der.DERControlStatusAccepted, (<-clearC).Status)
+	clearControl()
+	assert.Equal(t, der.DERControlStatusNotFound, (<-clearC).Status)
+}
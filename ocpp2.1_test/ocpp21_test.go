@@ -0,0 +1,91 @@
+package ocpp21_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	ocpp21 "github.com/lorenzodonini/ocpp-go/ocpp2.1"
+	"github.com/lorenzodonini/ocpp-go/ocpptest"
+)
+
+type GenericTestEntry struct {
+	Element       interface{}
+	ExpectedValid bool
+}
+
+func ExecuteGenericTestTable(t *testing.T, testTable []GenericTestEntry) {
+	for _, testCase := range testTable {
+		err := types.Validate.Struct(testCase.Element)
+		if err != nil {
+			assert.Equal(t, testCase.ExpectedValid, false, err.Error())
+		} else {
+			assert.Equal(t, testCase.ExpectedValid, true, "%v is valid", testCase.Element)
+		}
+	}
+}
+
+func newFloat(f float64) *float64 {
+	return &f
+}
+
+// Starts a CSMS and connects a charging station with the passed ID to it, using an in-memory transport.
+func startPair(t *testing.T, chargingStationID string) (ocpp21.CSMS, ocpp21.ChargingStation) {
+	server := ocpptest.NewMemoryServer()
+	csms := ocpp21.NewCSMS(nil, server)
+	connectedC := make(chan string, 1)
+	csms.SetNewChargingStationHandler(func(chargingStation ocpp21.ChargingStationConnection) {
+		connectedC <- chargingStation.ID()
+	})
+	go csms.Start(0, "/{ws}")
+	chargingStation := ocpp21.NewChargingStation(chargingStationID, nil, server.NewClient())
+	require.NoError(t, chargingStation.Start("ws://ocpptest"))
+	select {
+	case id := <-connectedC:
+		require.Equal(t, chargingStationID, id)
+	case <-time.After(time.Second):
+		t.Fatal("charging station didn't connect")
+	}
+	t.Cleanup(func() {
+		chargingStation.Stop()
+		csms.Stop()
+	})
+	return csms, chargingStation
+}
+
+type coreCSMSHandler struct{}
+
+func (h *coreCSMSHandler) OnBootNotification(chargingStationID string, request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
+	return provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 60, provisioning.RegistrationStatusAccepted), nil
+}
+
+func (h *coreCSMSHandler) OnNotifyReport(chargingStationID string, request *provisioning.NotifyReportRequest) (*provisioning.NotifyReportResponse, error) {
+	return provisioning.NewNotifyReportResponse(), nil
+}
+
+func (h *coreCSMSHandler) OnHeartbeat(chargingStationID string, request *availability.HeartbeatRequest) (*availability.HeartbeatResponse, error) {
+	return availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil
+}
+
+func (h *coreCSMSHandler) OnStatusNotification(chargingStationID string, request *availability.StatusNotificationRequest) (*availability.StatusNotificationResponse, error) {
+	return availability.NewStatusNotificationResponse(), nil
+}
+
+func TestOCPP201MessagesOnOCPP21Endpoints(t *testing.T) {
+	csms, chargingStation := startPair(t, "station1")
+	handler := &coreCSMSHandler{}
+	csms.SetProvisioningHandler(handler)
+	csms.SetAvailabilityHandler(handler)
+
+	bootResponse, err := chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.NoError(t, err)
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, bootResponse.Status)
+	heartbeatResponse, err := chargingStation.Heartbeat()
+	require.NoError(t, err)
+	assert.NotNil(t, heartbeatResponse)
+}
@@ -0,0 +1,156 @@
+// Package ocppcommon provides a version-agnostic view of the most common OCPP messages.
+//
+// Business logic, which only depends on information available in both OCPP 1.6 and OCPP 2.0.1
+// (e.g. registering a station on boot, tracking connector states or collecting meter values),
+// may be written once against the normalized types, and invoked from the handlers of either stack:
+//
+//	func (h *CentralSystemHandler) OnBootNotification(chargePointId string, request *core.BootNotificationRequest) (*core.BootNotificationConfirmation, error) {
+//		result := h.registry.Boot(chargePointId, ocppcommon.FromV16BootNotification(request))
+//		return result.ToV16(), nil
+//	}
+//
+//	func (h *CSMSHandler) OnBootNotification(chargingStationID string, request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
+//		result := h.registry.Boot(chargingStationID, ocppcommon.FromV201BootNotification(request))
+//		return result.ToV201(), nil
+//	}
+//
+// Normalized types only contain the intersection of both versions, plus a few version-specific fields,
+// which are documented as such. Converting a message to another version may therefore lose information.
+package ocppcommon
+
+import (
+	"math"
+	"time"
+)
+
+// Version is the OCPP version a normalized message originates from.
+type Version string
+
+const (
+	V16  Version = "1.6"
+	V201 Version = "2.0.1"
+)
+
+// RegistrationStatus is the result of a boot notification. The values are identical in both versions.
+type RegistrationStatus string
+
+const (
+	RegistrationStatusAccepted RegistrationStatus = "Accepted"
+	RegistrationStatusPending  RegistrationStatus = "Pending"
+	RegistrationStatusRejected RegistrationStatus = "Rejected"
+)
+
+// ConnectorStatus is the normalized status of a connector, following the OCPP 2.0.1 connector states.
+// The charging session states of OCPP 1.6 (Preparing, Charging, SuspendedEV, SuspendedEVSE and Finishing) are mapped to Occupied.
+type ConnectorStatus string
+
+const (
+	ConnectorStatusAvailable   ConnectorStatus = "Available"
+	ConnectorStatusOccupied    ConnectorStatus = "Occupied"
+	ConnectorStatusReserved    ConnectorStatus = "Reserved"
+	ConnectorStatusUnavailable ConnectorStatus = "Unavailable"
+	ConnectorStatusFaulted     ConnectorStatus = "Faulted"
+)
+
+// AuthorizationStatus is the result of an authorization.
+// Values only defined by OCPP 2.0.1 are mapped to Invalid when converting to OCPP 1.6.
+type AuthorizationStatus string
+
+const (
+	AuthorizationStatusAccepted     AuthorizationStatus = "Accepted"
+	AuthorizationStatusBlocked      AuthorizationStatus = "Blocked"
+	AuthorizationStatusExpired      AuthorizationStatus = "Expired"
+	AuthorizationStatusInvalid      AuthorizationStatus = "Invalid"
+	AuthorizationStatusConcurrentTx AuthorizationStatus = "ConcurrentTx"
+)
+
+// BootNotification contains the identity of a charging station, sent after each (re)boot.
+type BootNotification struct {
+	Version         Version
+	Model           string
+	Vendor          string
+	SerialNumber    string
+	FirmwareVersion string
+	Iccid           string
+	Imsi            string
+	// OCPP 1.6 only.
+	ChargeBoxSerialNumber string
+	MeterSerialNumber     string
+	MeterType             string
+	// OCPP 2.0.1 only. The reason for sending the boot notification.
+	Reason string
+}
+
+// BootResult is the response to a BootNotification.
+type BootResult struct {
+	Status      RegistrationStatus
+	CurrentTime time.Time
+	Interval    int // Heartbeat interval in seconds.
+}
+
+// HeartbeatResult is the response to a heartbeat.
+type HeartbeatResult struct {
+	CurrentTime time.Time
+}
+
+// StatusNotification contains the status of a connector.
+//
+// OCPP 1.6 only knows connectors, which are mapped to an EVSE with the same ID and a single connector with ID 1.
+// Connector 0 (the charge point as a whole) is mapped to EVSE 0, connector 0.
+type StatusNotification struct {
+	Version     Version
+	EvseID      int
+	ConnectorID int
+	Status      ConnectorStatus
+	Timestamp   *time.Time
+	// The original, version-specific status. Used when converting back to the original version.
+	VersionStatus string
+	// OCPP 1.6 only.
+	ErrorCode string
+	Info      string
+}
+
+// Authorize contains an identifier, which needs to be authorized before charging.
+type Authorize struct {
+	Version Version
+	IdToken string
+	// OCPP 2.0.1 only. The type of the identifier. Defaults to ISO14443 when converting to OCPP 2.0.1.
+	IdTokenType string
+}
+
+// AuthorizeResult is the response to an authorization.
+type AuthorizeResult struct {
+	Status     AuthorizationStatus
+	ExpiryDate *time.Time
+	GroupID    string // The parent id tag in OCPP 1.6, or the group id token in OCPP 2.0.1.
+}
+
+// SampledValue is a single normalized measurement.
+type SampledValue struct {
+	Value      float64
+	Context    string
+	Measurand  string
+	Phase      string
+	Location   string
+	Unit       string
+	Multiplier int // OCPP 2.0.1 only. The value is Value * 10^Multiplier.
+}
+
+// Returns the value with the multiplier applied.
+func (v SampledValue) scaledValue() float64 {
+	return v.Value * math.Pow10(v.Multiplier)
+}
+
+// MeterValue is a set of measurements taken at the same time.
+type MeterValue struct {
+	Timestamp    time.Time
+	SampledValue []SampledValue
+}
+
+// MeterValues contains meter values sampled by a charging station, optionally for a transaction.
+type MeterValues struct {
+	Version       Version
+	EvseID        int    // The connector ID in OCPP 1.6.
+	TransactionID string // OCPP 1.6 only. Empty if not related to a transaction. OCPP 2.0.1 sends transaction meter values via TransactionEvent instead.
+	MeterValue    []MeterValue
+}
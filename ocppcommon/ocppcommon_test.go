@@ -0,0 +1,129 @@
+package ocppcommon_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/core"
+	types16 "github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/meter"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	types201 "github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppcommon"
+)
+
+func TestBootNotificationV16(t *testing.T) {
+	request := &core.BootNotificationRequest{
+		ChargeBoxSerialNumber:   "box1",
+		ChargePointModel:        "model1",
+		ChargePointSerialNumber: "serial1",
+		ChargePointVendor:       "vendor1",
+		FirmwareVersion:         "1.0.0",
+		Iccid:                   "iccid1",
+		Imsi:                    "imsi1",
+		MeterSerialNumber:       "meter1",
+		MeterType:               "meterType1",
+	}
+	boot := ocppcommon.FromV16BootNotification(request)
+	assert.Equal(t, ocppcommon.V16, boot.Version)
+	assert.Equal(t, "model1", boot.Model)
+	assert.Equal(t, "vendor1", boot.Vendor)
+	assert.Equal(t, "serial1", boot.SerialNumber)
+	assert.Equal(t, "1.0.0", boot.FirmwareVersion)
+	assert.Equal(t, "iccid1", boot.Iccid)
+	assert.Equal(t, "imsi1", boot.Imsi)
+	// Round trip
+	assert.Equal(t, request, boot.ToV16())
+	// Conversion to the other version keeps the common fields
+	converted := boot.ToV201()
+	assert.Equal(t, provisioning.BootReasonPowerUp, converted.Reason)
+	assert.Equal(t, "model1", converted.ChargingStation.Model)
+	assert.Equal(t, "vendor1", converted.ChargingStation.VendorName)
+	assert.Equal(t, "serial1", converted.ChargingStation.SerialNumber)
+	require.NotNil(t, converted.ChargingStation.Modem)
+	assert.Equal(t, "iccid1", converted.ChargingStation.Modem.Iccid)
+	assert.NoError(t, types201.Validate.Struct(converted))
+}
+
+func TestBootNotificationV201(t *testing.T) {
+	request := provisioning.NewBootNotificationRequest(provisioning.BootReasonFirmwareUpdate, "model1", "vendor1")
+	request.ChargingStation.SerialNumber = "serial1"
+	request.ChargingStation.FirmwareVersion = "1.0.0"
+	request.ChargingStation.Modem = &provisioning.ModemType{Iccid: "iccid1", Imsi: "imsi1"}
+	boot := ocppcommon.FromV201BootNotification(request)
+	assert.Equal(t, ocppcommon.V201, boot.Version)
+	assert.Equal(t, "model1", boot.Model)
+	assert.Equal(t, "vendor1", boot.Vendor)
+	assert.Equal(t, "serial1", boot.SerialNumber)
+	assert.Equal(t, string(provisioning.BootReasonFirmwareUpdate), boot.Reason)
+	// Round trip
+	assert.Equal(t, request, boot.ToV201())
+	// Conversion to the other version keeps the common fields
+	converted := boot.ToV16()
+	assert.Equal(t, "model1", converted.ChargePointModel)
+	assert.Equal(t, "vendor1", converted.ChargePointVendor)
+	assert.Equal(t, "serial1", converted.ChargePointSerialNumber)
+	assert.Equal(t, "imsi1", converted.Imsi)
+	assert.NoError(t, types16.Validate.Struct(converted))
+}
+
+func TestBootResult(t *testing.T) {
+	currentTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	result := ocppcommon.BootResult{Status: ocppcommon.RegistrationStatusAccepted, CurrentTime: currentTime, Interval: 300}
+	confirmation := result.ToV16()
+	assert.Equal(t, core.RegistrationStatusAccepted, confirmation.Status)
+	assert.Equal(t, 300, confirmation.Interval)
+	assert.Equal(t, result, ocppcommon.FromV16BootNotificationConfirmation(confirmation))
+	response := result.ToV201()
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, response.Status)
+	assert.Equal(t, result, ocppcommon.FromV201BootNotificationResponse(response))
+}
+
+func TestStatusNotification(t *testing.T) {
+	request := core.NewStatusNotificationRequest(2, core.NoError, core.ChargePointStatusSuspendedEV)
+	status := ocppcommon.FromV16StatusNotification(request)
+	assert.Equal(t, ocppcommon.ConnectorStatusOccupied, status.Status)
+	assert.Equal(t, 2, status.EvseID)
+	assert.Equal(t, 1, status.ConnectorID)
+	// The original status is retained
+	assert.Equal(t, request, status.ToV16())
+	converted := status.ToV201()
+	assert.Equal(t, availability.ConnectorStatusOccupied, converted.ConnectorStatus)
+	assert.Equal(t, 2, converted.EvseID)
+	assert.Equal(t, 1, converted.ConnectorID)
+	// Back to 1.6 from 2.0.1
+	status = ocppcommon.FromV201StatusNotification(converted)
+	assert.Equal(t, core.ChargePointStatusCharging, status.ToV16().Status)
+}
+
+func TestMeterValues(t *testing.T) {
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	multiplier := 3
+	request := meter.NewMeterValuesRequest(1, []types201.MeterValue{
+		{
+			Timestamp: *types201.NewDateTime(timestamp),
+			SampledValue: []types201.SampledValue{
+				{Value: 1.5, Measurand: types201.MeasurandEnergyActiveImportRegister, UnitOfMeasure: &types201.UnitOfMeasure{Unit: "Wh", Multiplier: &multiplier}},
+			},
+		},
+	})
+	meterValues := ocppcommon.FromV201MeterValues(request)
+	require.Len(t, meterValues.MeterValue, 1)
+	require.Len(t, meterValues.MeterValue[0].SampledValue, 1)
+	assert.Equal(t, 1.5, meterValues.MeterValue[0].SampledValue[0].Value)
+	assert.Equal(t, 3, meterValues.MeterValue[0].SampledValue[0].Multiplier)
+	assert.Equal(t, request, meterValues.ToV201())
+	// The multiplier is applied when converting to 1.6
+	converted, err := meterValues.ToV16()
+	require.NoError(t, err)
+	assert.Equal(t, "1500", converted.MeterValue[0].SampledValue[0].Value)
+	assert.Equal(t, types16.UnitOfMeasure("Wh"), converted.MeterValue[0].SampledValue[0].Unit)
+	// Signed data can't be normalized
+	converted.MeterValue[0].SampledValue[0].Value = "signedData"
+	_, err = ocppcommon.FromV16MeterValues(converted)
+	assert.Error(t, err)
+}
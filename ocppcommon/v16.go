@@ -0,0 +1,221 @@
+package ocppcommon
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/core"
+	"github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
+)
+
+// FromV16BootNotification normalizes an OCPP 1.6 BootNotification request.
+func FromV16BootNotification(request *core.BootNotificationRequest) BootNotification {
+	return BootNotification{
+		Version:               V16,
+		Model:                 request.ChargePointModel,
+		Vendor:                request.ChargePointVendor,
+		SerialNumber:          request.ChargePointSerialNumber,
+		FirmwareVersion:       request.FirmwareVersion,
+		Iccid:                 request.Iccid,
+		Imsi:                  request.Imsi,
+		ChargeBoxSerialNumber: request.ChargeBoxSerialNumber,
+		MeterSerialNumber:     request.MeterSerialNumber,
+		MeterType:             request.MeterType,
+	}
+}
+
+// ToV16 converts the boot notification to an OCPP 1.6 BootNotification request.
+func (b BootNotification) ToV16() *core.BootNotificationRequest {
+	return &core.BootNotificationRequest{
+		ChargeBoxSerialNumber:   b.ChargeBoxSerialNumber,
+		ChargePointModel:        b.Model,
+		ChargePointSerialNumber: b.SerialNumber,
+		ChargePointVendor:       b.Vendor,
+		FirmwareVersion:         b.FirmwareVersion,
+		Iccid:                   b.Iccid,
+		Imsi:                    b.Imsi,
+		MeterSerialNumber:       b.MeterSerialNumber,
+		MeterType:               b.MeterType,
+	}
+}
+
+// FromV16BootNotificationConfirmation normalizes an OCPP 1.6 BootNotification confirmation.
+func FromV16BootNotificationConfirmation(confirmation *core.BootNotificationConfirmation) BootResult {
+	return BootResult{
+		Status:      RegistrationStatus(confirmation.Status),
+		CurrentTime: fromV16DateTime(confirmation.CurrentTime),
+		Interval:    confirmation.Interval,
+	}
+}
+
+// ToV16 converts the boot result to an OCPP 1.6 BootNotification confirmation.
+func (r BootResult) ToV16() *core.BootNotificationConfirmation {
+	return core.NewBootNotificationConfirmation(types.NewDateTime(r.CurrentTime), r.Interval, core.RegistrationStatus(r.Status))
+}
+
+// ToV16 converts the heartbeat result to an OCPP 1.6 Heartbeat confirmation.
+func (r HeartbeatResult) ToV16() *core.HeartbeatConfirmation {
+	return core.NewHeartbeatConfirmation(types.NewDateTime(r.CurrentTime))
+}
+
+// FromV16StatusNotification normalizes an OCPP 1.6 StatusNotification request.
+func FromV16StatusNotification(request *core.StatusNotificationRequest) StatusNotification {
+	status := ConnectorStatusOccupied
+	switch request.Status {
+	case core.ChargePointStatusAvailable:
+		status = ConnectorStatusAvailable
+	case core.ChargePointStatusReserved:
+		status = ConnectorStatusReserved
+	case core.ChargePointStatusUnavailable:
+		status = ConnectorStatusUnavailable
+	case core.ChargePointStatusFaulted:
+		status = ConnectorStatusFaulted
+	}
+	connectorID := 0
+	if request.ConnectorId > 0 {
+		connectorID = 1
+	}
+	var timestamp *time.Time
+	if request.Timestamp != nil {
+		t := request.Timestamp.Time
+		timestamp = &t
+	}
+	return StatusNotification{
+		Version:       V16,
+		EvseID:        request.ConnectorId,
+		ConnectorID:   connectorID,
+		Status:        status,
+		Timestamp:     timestamp,
+		VersionStatus: string(request.Status),
+		ErrorCode:     string(request.ErrorCode),
+		Info:          request.Info,
+	}
+}
+
+// ToV16 converts the status notification to an OCPP 1.6 StatusNotification request.
+// The original OCPP 1.6 status is retained, if the notification originates from OCPP 1.6. Otherwise, Occupied is mapped to Charging.
+func (n StatusNotification) ToV16() *core.StatusNotificationRequest {
+	status := core.ChargePointStatus(n.Status)
+	if n.Version == V16 && n.VersionStatus != "" {
+		status = core.ChargePointStatus(n.VersionStatus)
+	} else if n.Status == ConnectorStatusOccupied {
+		status = core.ChargePointStatusCharging
+	}
+	errorCode := core.NoError
+	if n.ErrorCode != "" {
+		errorCode = core.ChargePointErrorCode(n.ErrorCode)
+	}
+	request := core.NewStatusNotificationRequest(n.EvseID, errorCode, status)
+	request.Info = n.Info
+	if n.Timestamp != nil {
+		request.Timestamp = types.NewDateTime(*n.Timestamp)
+	}
+	return request
+}
+
+// FromV16Authorize normalizes an OCPP 1.6 Authorize request.
+func FromV16Authorize(request *core.AuthorizeRequest) Authorize {
+	return Authorize{Version: V16, IdToken: request.IdTag}
+}
+
+// ToV16 converts the authorization to an OCPP 1.6 Authorize request.
+func (a Authorize) ToV16() *core.AuthorizeRequest {
+	return core.NewAuthorizationRequest(a.IdToken)
+}
+
+// FromV16AuthorizeConfirmation normalizes an OCPP 1.6 Authorize confirmation.
+func FromV16AuthorizeConfirmation(confirmation *core.AuthorizeConfirmation) AuthorizeResult {
+	return fromV16IdTagInfo(confirmation.IdTagInfo)
+}
+
+// ToV16 converts the authorization result to an OCPP 1.6 Authorize confirmation.
+func (r AuthorizeResult) ToV16() *core.AuthorizeConfirmation {
+	status := types.AuthorizationStatus(r.Status)
+	switch r.Status {
+	case AuthorizationStatusAccepted, AuthorizationStatusBlocked, AuthorizationStatusExpired, AuthorizationStatusInvalid, AuthorizationStatusConcurrentTx:
+	default:
+		status = types.AuthorizationStatusInvalid
+	}
+	info := types.NewIdTagInfo(status)
+	info.ParentIdTag = r.GroupID
+	if r.ExpiryDate != nil {
+		info.ExpiryDate = types.NewDateTime(*r.ExpiryDate)
+	}
+	return core.NewAuthorizationConfirmation(info)
+}
+
+// FromV16MeterValues normalizes an OCPP 1.6 MeterValues request.
+// Returns an error if a sampled value isn't numeric, e.g. because it contains signed data.
+func FromV16MeterValues(request *core.MeterValuesRequest) (MeterValues, error) {
+	meterValues := MeterValues{Version: V16, EvseID: request.ConnectorId}
+	if request.TransactionId != nil {
+		meterValues.TransactionID = strconv.Itoa(*request.TransactionId)
+	}
+	for _, mv := range request.MeterValue {
+		meterValue := MeterValue{Timestamp: fromV16DateTime(mv.Timestamp)}
+		for _, sv := range mv.SampledValue {
+			value, err := strconv.ParseFloat(sv.Value, 64)
+			if err != nil {
+				return MeterValues{}, fmt.Errorf("sampled value %q is not numeric: %w", sv.Value, err)
+			}
+			meterValue.SampledValue = append(meterValue.SampledValue, SampledValue{
+				Value:     value,
+				Context:   string(sv.Context),
+				Measurand: string(sv.Measurand),
+				Phase:     string(sv.Phase),
+				Location:  string(sv.Location),
+				Unit:      string(sv.Unit),
+			})
+		}
+		meterValues.MeterValue = append(meterValues.MeterValue, meterValue)
+	}
+	return meterValues, nil
+}
+
+// ToV16 converts the meter values to an OCPP 1.6 MeterValues request. Multipliers are applied to the values.
+// Returns an error if the transaction ID isn't numeric.
+func (m MeterValues) ToV16() (*core.MeterValuesRequest, error) {
+	request := core.NewMeterValuesRequest(m.EvseID, nil)
+	if m.TransactionID != "" {
+		transactionID, err := strconv.Atoi(m.TransactionID)
+		if err != nil {
+			return nil, fmt.Errorf("transaction ID %v is not numeric: %w", m.TransactionID, err)
+		}
+		request.TransactionId = &transactionID
+	}
+	for _, mv := range m.MeterValue {
+		meterValue := types.MeterValue{Timestamp: types.NewDateTime(mv.Timestamp)}
+		for _, sv := range mv.SampledValue {
+			meterValue.SampledValue = append(meterValue.SampledValue, types.SampledValue{
+				Value:     strconv.FormatFloat(sv.scaledValue(), 'f', -1, 64),
+				Context:   types.ReadingContext(sv.Context),
+				Measurand: types.Measurand(sv.Measurand),
+				Phase:     types.Phase(sv.Phase),
+				Location:  types.Location(sv.Location),
+				Unit:      types.UnitOfMeasure(sv.Unit),
+			})
+		}
+		request.MeterValue = append(request.MeterValue, meterValue)
+	}
+	return request, nil
+}
+
+func fromV16IdTagInfo(info *types.IdTagInfo) AuthorizeResult {
+	if info == nil {
+		return AuthorizeResult{Status: AuthorizationStatusInvalid}
+	}
+	result := AuthorizeResult{Status: AuthorizationStatus(info.Status), GroupID: info.ParentIdTag}
+	if info.ExpiryDate != nil {
+		t := info.ExpiryDate.Time
+		result.ExpiryDate = &t
+	}
+	return result
+}
+
+func fromV16DateTime(dateTime *types.DateTime) time.Time {
+	if dateTime == nil {
+		return time.Time{}
+	}
+	return dateTime.Time
+}
@@ -0,0 +1,189 @@
+package ocppcommon
+
+import (
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/authorization"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/meter"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+)
+
+// FromV201BootNotification normalizes an OCPP 2.0.1 BootNotification request.
+func FromV201BootNotification(request *provisioning.BootNotificationRequest) BootNotification {
+	station := request.ChargingStation
+	boot := BootNotification{
+		Version:         V201,
+		Model:           station.Model,
+		Vendor:          station.VendorName,
+		SerialNumber:    station.SerialNumber,
+		FirmwareVersion: station.FirmwareVersion,
+		Reason:          string(request.Reason),
+	}
+	if station.Modem != nil {
+		boot.Iccid = station.Modem.Iccid
+		boot.Imsi = station.Modem.Imsi
+	}
+	return boot
+}
+
+// ToV201 converts the boot notification to an OCPP 2.0.1 BootNotification request.
+// If no reason is set, e.g. because the notification originates from OCPP 1.6, PowerUp is used.
+func (b BootNotification) ToV201() *provisioning.BootNotificationRequest {
+	reason := provisioning.BootReason(b.Reason)
+	if reason == "" {
+		reason = provisioning.BootReasonPowerUp
+	}
+	request := provisioning.NewBootNotificationRequest(reason, b.Model, b.Vendor)
+	request.ChargingStation.SerialNumber = b.SerialNumber
+	request.ChargingStation.FirmwareVersion = b.FirmwareVersion
+	if b.Iccid != "" || b.Imsi != "" {
+		request.ChargingStation.Modem = &provisioning.ModemType{Iccid: b.Iccid, Imsi: b.Imsi}
+	}
+	return request
+}
+
+// FromV201BootNotificationResponse normalizes an OCPP 2.0.1 BootNotification response.
+func FromV201BootNotificationResponse(response *provisioning.BootNotificationResponse) BootResult {
+	return BootResult{
+		Status:      RegistrationStatus(response.Status),
+		CurrentTime: fromV201DateTime(response.CurrentTime),
+		Interval:    response.Interval,
+	}
+}
+
+// ToV201 converts the boot result to an OCPP 2.0.1 BootNotification response.
+func (r BootResult) ToV201() *provisioning.BootNotificationResponse {
+	return provisioning.NewBootNotificationResponse(types.NewDateTime(r.CurrentTime), r.Interval, provisioning.RegistrationStatus(r.Status))
+}
+
+// ToV201 converts the heartbeat result to an OCPP 2.0.1 Heartbeat response.
+func (r HeartbeatResult) ToV201() *availability.HeartbeatResponse {
+	return availability.NewHeartbeatResponse(*types.NewDateTime(r.CurrentTime))
+}
+
+// FromV201StatusNotification normalizes an OCPP 2.0.1 StatusNotification request.
+func FromV201StatusNotification(request *availability.StatusNotificationRequest) StatusNotification {
+	var timestamp *time.Time
+	if request.Timestamp != nil {
+		t := request.Timestamp.Time
+		timestamp = &t
+	}
+	return StatusNotification{
+		Version:       V201,
+		EvseID:        request.EvseID,
+		ConnectorID:   request.ConnectorID,
+		Status:        ConnectorStatus(request.ConnectorStatus),
+		Timestamp:     timestamp,
+		VersionStatus: string(request.ConnectorStatus),
+	}
+}
+
+// ToV201 converts the status notification to an OCPP 2.0.1 StatusNotification request.
+// If no timestamp is set, the current time is used.
+func (n StatusNotification) ToV201() *availability.StatusNotificationRequest {
+	timestamp := time.Now()
+	if n.Timestamp != nil {
+		timestamp = *n.Timestamp
+	}
+	return availability.NewStatusNotificationRequest(types.NewDateTime(timestamp), availability.ConnectorStatus(n.Status), n.EvseID, n.ConnectorID)
+}
+
+// FromV201Authorize normalizes an OCPP 2.0.1 Authorize request.
+func FromV201Authorize(request *authorization.AuthorizeRequest) Authorize {
+	return Authorize{Version: V201, IdToken: request.IdToken.IdToken, IdTokenType: string(request.IdToken.Type)}
+}
+
+// ToV201 converts the authorization to an OCPP 2.0.1 Authorize request.
+func (a Authorize) ToV201() *authorization.AuthorizeRequest {
+	tokenType := types.IdTokenType(a.IdTokenType)
+	if tokenType == "" {
+		tokenType = types.IdTokenTypeISO14443
+	}
+	return authorization.NewAuthorizationRequest(a.IdToken, tokenType)
+}
+
+// FromV201AuthorizeResponse normalizes an OCPP 2.0.1 Authorize response.
+func FromV201AuthorizeResponse(response *authorization.AuthorizeResponse) AuthorizeResult {
+	info := response.IdTokenInfo
+	result := AuthorizeResult{Status: AuthorizationStatus(info.Status)}
+	if info.CacheExpiryDateTime != nil {
+		t := info.CacheExpiryDateTime.Time
+		result.ExpiryDate = &t
+	}
+	if info.GroupIdToken != nil {
+		result.GroupID = info.GroupIdToken.IdToken
+	}
+	return result
+}
+
+// ToV201 converts the authorization result to an OCPP 2.0.1 Authorize response.
+// The group ID is passed as a group id token of type Central.
+func (r AuthorizeResult) ToV201() *authorization.AuthorizeResponse {
+	info := types.NewIdTokenInfo(types.AuthorizationStatus(r.Status))
+	if r.ExpiryDate != nil {
+		info.CacheExpiryDateTime = types.NewDateTime(*r.ExpiryDate)
+	}
+	if r.GroupID != "" {
+		info.GroupIdToken = &types.GroupIdToken{IdToken: r.GroupID, Type: types.IdTokenTypeCentral}
+	}
+	return authorization.NewAuthorizationResponse(*info)
+}
+
+// FromV201MeterValues normalizes an OCPP 2.0.1 MeterValues request.
+func FromV201MeterValues(request *meter.MeterValuesRequest) MeterValues {
+	meterValues := MeterValues{Version: V201, EvseID: request.EvseID}
+	for _, mv := range request.MeterValue {
+		meterValue := MeterValue{Timestamp: mv.Timestamp.Time}
+		for _, sv := range mv.SampledValue {
+			sampledValue := SampledValue{
+				Value:     sv.Value,
+				Context:   string(sv.Context),
+				Measurand: string(sv.Measurand),
+				Phase:     string(sv.Phase),
+				Location:  string(sv.Location),
+			}
+			if sv.UnitOfMeasure != nil {
+				sampledValue.Unit = sv.UnitOfMeasure.Unit
+				if sv.UnitOfMeasure.Multiplier != nil {
+					sampledValue.Multiplier = *sv.UnitOfMeasure.Multiplier
+				}
+			}
+			meterValue.SampledValue = append(meterValue.SampledValue, sampledValue)
+		}
+		meterValues.MeterValue = append(meterValues.MeterValue, meterValue)
+	}
+	return meterValues
+}
+
+// ToV201 converts the meter values to an OCPP 2.0.1 MeterValues request. The transaction ID is not part of the request.
+func (m MeterValues) ToV201() *meter.MeterValuesRequest {
+	request := meter.NewMeterValuesRequest(m.EvseID, nil)
+	for _, mv := range m.MeterValue {
+		meterValue := types.MeterValue{Timestamp: *types.NewDateTime(mv.Timestamp)}
+		for _, sv := range mv.SampledValue {
+			sampledValue := types.SampledValue{
+				Value:     sv.Value,
+				Context:   types.ReadingContext(sv.Context),
+				Measurand: types.Measurand(sv.Measurand),
+				Phase:     types.Phase(sv.Phase),
+				Location:  types.Location(sv.Location),
+			}
+			if sv.Unit != "" || sv.Multiplier != 0 {
+				multiplier := sv.Multiplier
+				sampledValue.UnitOfMeasure = &types.UnitOfMeasure{Unit: sv.Unit, Multiplier: &multiplier}
+			}
+			meterValue.SampledValue = append(meterValue.SampledValue, sampledValue)
+		}
+		request.MeterValue = append(request.MeterValue, meterValue)
+	}
+	return request
+}
+
+func fromV201DateTime(dateTime *types.DateTime) time.Time {
+	if dateTime == nil {
+		return time.Time{}
+	}
+	return dateTime.Time
+}
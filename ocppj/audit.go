@@ -0,0 +1,202 @@
+package ocppj
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// AuditEntryKind indicates which part of a request lifecycle an AuditEntry refers to.
+type AuditEntryKind string
+
+const (
+	AuditRequest  AuditEntryKind = "request"  // A CALL was sent to a client.
+	AuditResponse AuditEntryKind = "response" // A CALL_RESULT was received for a previously sent CALL.
+	AuditError    AuditEntryKind = "error"    // A CALL_ERROR was received for a previously sent CALL.
+	AuditCanceled AuditEntryKind = "canceled" // A previously sent CALL was canceled, e.g. due to a timeout.
+)
+
+// AuditEntry is a single record of an AuditLog.
+//
+// Every entry contains the hash of the previous entry, and its own hash is computed over all of its fields.
+// Altering, removing or reordering entries therefore breaks the hash chain, see VerifyAuditChain.
+type AuditEntry struct {
+	Sequence     uint64         // Monotonic sequence number, starting at 1.
+	Timestamp    time.Time      // Time at which the entry was recorded.
+	ClientID     string         // The client the request was sent to, or sent by for a client-side log.
+	RequestID    string         // The unique ID of the CALL.
+	Action       string         // The feature name of the CALL.
+	Kind         AuditEntryKind // The lifecycle event the entry refers to.
+	Message      []byte         // The raw OCPP-J message as sent or received. Empty for canceled requests.
+	ErrorCode    ocpp.ErrorCode // Set for errors and canceled requests.
+	ErrorDetail  string         // Set for errors and canceled requests.
+	PreviousHash string         // Hex-encoded hash of the previous entry. Empty for the first entry.
+	Hash         string         // Hex-encoded SHA-256 hash of this entry, including the previous hash.
+}
+
+// ComputeHash returns the hex-encoded SHA-256 hash over all fields of the entry, except the Hash field itself.
+func (e *AuditEntry) ComputeHash() string {
+	h := sha256.New()
+	writeField := func(b []byte) {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	var sequence [8]byte
+	binary.BigEndian.PutUint64(sequence[:], e.Sequence)
+	h.Write(sequence[:])
+	writeField([]byte(e.Timestamp.UTC().Format(time.RFC3339Nano)))
+	writeField([]byte(e.ClientID))
+	writeField([]byte(e.RequestID))
+	writeField([]byte(e.Action))
+	writeField([]byte(e.Kind))
+	writeField(e.Message)
+	writeField([]byte(e.ErrorCode))
+	writeField([]byte(e.ErrorDetail))
+	writeField([]byte(e.PreviousHash))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditSink persists the entries of an AuditLog, e.g. to an append-only store.
+//
+// Append is invoked synchronously, in sequence order. Errors are logged, but don't affect the message exchange.
+type AuditSink interface {
+	Append(entry AuditEntry) error
+}
+
+// AuditLog records every request sent by a server or client, together with its response or cancellation,
+// as a tamper-evident chain of entries. See AuditEntry for details.
+//
+// An AuditLog is safe for concurrent use. Use NewAuditLog to create one, and Server.SetAuditLog or Client.SetAuditLog to enable it.
+type AuditLog struct {
+	sink     AuditSink
+	mutex    sync.Mutex
+	sequence uint64
+	lastHash string
+	pending  map[auditKey]auditRequest
+}
+
+type auditKey struct {
+	clientID  string
+	requestID string
+}
+
+// A recorded request, which wasn't completed yet.
+type auditRequest struct {
+	action   string
+	sequence uint64
+}
+
+// NewAuditLog creates a new audit log, which passes all recorded entries to the given sink.
+func NewAuditLog(sink AuditSink) *AuditLog {
+	return &AuditLog{sink: sink, pending: map[auditKey]auditRequest{}}
+}
+
+// Resume continues an existing hash chain, e.g. after a restart, given the last entry persisted by the sink.
+// Must be invoked before any new entry was recorded.
+func (l *AuditLog) Resume(last AuditEntry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.sequence = last.Sequence
+	l.lastHash = last.Hash
+}
+
+func (l *AuditLog) recordRequest(clientID string, call *Call, message []byte) {
+	l.record(AuditEntry{ClientID: clientID, RequestID: call.UniqueId, Action: call.Action, Kind: AuditRequest, Message: message})
+}
+
+func (l *AuditLog) recordResponse(clientID string, requestID string, message []byte) {
+	l.record(AuditEntry{ClientID: clientID, RequestID: requestID, Kind: AuditResponse, Message: message})
+}
+
+func (l *AuditLog) recordError(clientID string, requestID string, kind AuditEntryKind, message []byte, err *ocpp.Error) {
+	entry := AuditEntry{ClientID: clientID, RequestID: requestID, Kind: kind, Message: message}
+	if err != nil {
+		entry.ErrorCode = err.Code
+		entry.ErrorDetail = err.Description
+	}
+	l.record(entry)
+}
+
+// Records a cancellation for all pending requests of a client, in the order in which they were sent.
+func (l *AuditLog) recordDisconnect(clientID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var keys []auditKey
+	for key := range l.pending {
+		if key.clientID == clientID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return l.pending[keys[i]].sequence < l.pending[keys[j]].sequence
+	})
+	for _, key := range keys {
+		l.append(AuditEntry{ClientID: clientID, RequestID: key.requestID, Kind: AuditCanceled, ErrorCode: GenericError, ErrorDetail: "Client disconnected"})
+	}
+}
+
+func (l *AuditLog) record(entry AuditEntry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.append(entry)
+}
+
+// Appends an entry to the chain. Must be invoked while holding the mutex.
+func (l *AuditLog) append(entry AuditEntry) {
+	key := auditKey{clientID: entry.ClientID, requestID: entry.RequestID}
+	if entry.Kind != AuditRequest {
+		// Completion entries refer to the action of the original request
+		request, ok := l.pending[key]
+		if !ok {
+			// Not a request recorded by this log (e.g. an already completed request)
+			return
+		}
+		entry.Action = request.action
+		delete(l.pending, key)
+	}
+	l.sequence++
+	entry.Sequence = l.sequence
+	entry.Timestamp = time.Now()
+	entry.Message = append([]byte(nil), entry.Message...)
+	entry.PreviousHash = l.lastHash
+	entry.Hash = entry.ComputeHash()
+	l.lastHash = entry.Hash
+	if entry.Kind == AuditRequest {
+		l.pending[key] = auditRequest{action: entry.Action, sequence: entry.Sequence}
+	}
+	if err := l.sink.Append(entry); err != nil {
+		log.Errorf("couldn't append audit entry %d: %v", entry.Sequence, err)
+	}
+}
+
+// VerifyAuditChain verifies that the given entries form a consistent hash chain, in sequence order.
+// The first entry may be any entry of a chain, hence its previous hash isn't verified.
+//
+// Returns an error referring to the first entry which doesn't match its hash, or doesn't follow its predecessor.
+func VerifyAuditChain(entries []AuditEntry) error {
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ComputeHash() != entry.Hash {
+			return fmt.Errorf("audit entry %d was altered: hash mismatch", entry.Sequence)
+		}
+		if i == 0 {
+			continue
+		}
+		previous := &entries[i-1]
+		if entry.Sequence != previous.Sequence+1 {
+			return fmt.Errorf("audit entry %d doesn't follow entry %d", entry.Sequence, previous.Sequence)
+		}
+		if entry.PreviousHash != previous.Hash {
+			return fmt.Errorf("audit entry %d isn't chained to entry %d", entry.Sequence, previous.Sequence)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package ocppj
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+)
+
+// -------------------- Call Builder --------------------
+
+// CallBuilder assembles OCPP-J Call messages, without requiring an endpoint or a connection.
+// It is meant for tooling and tests, e.g.:
+//
+//	data, err := ocppj.NewCall().Action("BootNotification").Id("abc").Payload(request).Build()
+//
+// If no action is set, the feature name of the payload is used. If no unique ID is set, one is generated.
+// By default, the message is validated before being serialized. Use SkipValidation to build arbitrary messages.
+type CallBuilder struct {
+	uniqueId       string
+	action         string
+	payload        interface{}
+	skipValidation bool
+}
+
+// Creates a new builder for an OCPP-J Call message.
+func NewCall() *CallBuilder {
+	return &CallBuilder{}
+}
+
+// Sets the unique ID of the message.
+func (b *CallBuilder) Id(uniqueId string) *CallBuilder {
+	b.uniqueId = uniqueId
+	return b
+}
+
+// Sets the action of the message.
+func (b *CallBuilder) Action(action string) *CallBuilder {
+	b.action = action
+	return b
+}
+
+// Sets the payload of the message. The payload should be an ocpp.Request,
+// but any value may be passed if validation is skipped.
+func (b *CallBuilder) Payload(payload interface{}) *CallBuilder {
+	b.payload = payload
+	return b
+}
+
+// Disables validation of the message, allowing to build invalid messages (e.g. for fuzzing).
+func (b *CallBuilder) SkipValidation() *CallBuilder {
+	b.skipValidation = true
+	return b
+}
+
+// Returns the Call message, validating it unless validation was skipped.
+// An error is returned if the payload is not an ocpp.Request, or a nil pointer.
+func (b *CallBuilder) Message() (*Call, error) {
+	request, ok := b.payload.(ocpp.Request)
+	if !ok {
+		return nil, fmt.Errorf("invalid call payload of type %T, expected ocpp.Request", b.payload)
+	}
+	if isNilPayload(request) {
+		return nil, fmt.Errorf("invalid call payload: nil %T", b.payload)
+	}
+	call := &Call{
+		MessageTypeId: CALL,
+		UniqueId:      b.getUniqueId(),
+		Action:        b.getAction(),
+		Payload:       request,
+	}
+	if !b.skipValidation {
+		if err := Validate.Struct(call); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+// Serializes the message to an OCPP-J frame.
+func (b *CallBuilder) Build() ([]byte, error) {
+	if b.skipValidation {
+		return jsonMarshal([]interface{}{int(CALL), b.getUniqueId(), b.getAction(), b.payload})
+	}
+	call, err := b.Message()
+	if err != nil {
+		return nil, err
+	}
+	return call.MarshalJSON()
+}
+
+func (b *CallBuilder) getUniqueId() string {
+	if b.uniqueId == "" {
+		b.uniqueId = messageIdGenerator()
+	}
+	return b.uniqueId
+}
+
+// Returns the action of the message, falling back to the feature name of the payload.
+// Typed nil pointers are skipped, since GetFeatureName may be implemented on the value receiver and panic.
+func (b *CallBuilder) getAction() string {
+	if request, ok := b.payload.(ocpp.Request); ok && b.action == "" && !isNilPayload(request) {
+		return request.GetFeatureName()
+	}
+	return b.action
+}
+
+// Returns true, if the payload is nil or a nil pointer.
+func isNilPayload(payload interface{}) bool {
+	if payload == nil {
+		return true
+	}
+	v := reflect.ValueOf(payload)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// -------------------- Call Result Builder --------------------
+
+// CallResultBuilder assembles OCPP-J CallResult messages, without requiring an endpoint or a connection.
+//
+//	data, err := ocppj.NewCallResult().Id("abc").Payload(response).Build()
+//
+// By default, the message is validated before being serialized. Use SkipValidation to build arbitrary messages.
+type CallResultBuilder struct {
+	uniqueId       string
+	payload        interface{}
+	skipValidation bool
+}
+
+// Creates a new builder for an OCPP-J CallResult message.
+func NewCallResult() *CallResultBuilder {
+	return &CallResultBuilder{}
+}
+
+// Sets the unique ID of the message, which should match the ID of the corresponding Call.
+func (b *CallResultBuilder) Id(uniqueId string) *CallResultBuilder {
+	b.uniqueId = uniqueId
+	return b
+}
+
+// Sets the payload of the message. The payload should be an ocpp.Response,
+// but any value may be passed if validation is skipped.
+func (b *CallResultBuilder) Payload(payload interface{}) *CallResultBuilder {
+	b.payload = payload
+	return b
+}
+
+// Disables validation of the message, allowing to build invalid messages (e.g. for fuzzing).
+func (b *CallResultBuilder) SkipValidation() *CallResultBuilder {
+	b.skipValidation = true
+	return b
+}
+
+// Returns the CallResult message, validating it unless validation was skipped.
+// An error is returned if the payload is not an ocpp.Response.
+func (b *CallResultBuilder) Message() (*CallResult, error) {
+	response, ok := b.payload.(ocpp.Response)
+	if !ok {
+		return nil, fmt.Errorf("invalid call result payload of type %T, expected ocpp.Response", b.payload)
+	}
+	callResult := &CallResult{
+		MessageTypeId: CALL_RESULT,
+		UniqueId:      b.uniqueId,
+		Payload:       response,
+	}
+	if !b.skipValidation {
+		if err := Validate.Struct(callResult); err != nil {
+			return nil, err
+		}
+	}
+	return callResult, nil
+}
+
+// Serializes the message to an OCPP-J frame.
+func (b *CallResultBuilder) Build() ([]byte, error) {
+	if b.skipValidation {
+		return jsonMarshal([]interface{}{int(CALL_RESULT), b.uniqueId, b.payload})
+	}
+	callResult, err := b.Message()
+	if err != nil {
+		return nil, err
+	}
+	return callResult.MarshalJSON()
+}
+
+// -------------------- Call Error Builder --------------------
+
+// CallErrorBuilder assembles OCPP-J CallError messages, without requiring an endpoint or a connection.
+//
+//	data, err := ocppj.NewCallError().Id("abc").Code(ocppj.GenericError).Description("failure").Build()
+//
+// By default, the message is validated before being serialized. Use SkipValidation to build arbitrary messages.
+type CallErrorBuilder struct {
+	callError      CallError
+	skipValidation bool
+}
+
+// Creates a new builder for an OCPP-J CallError message.
+func NewCallError() *CallErrorBuilder {
+	return &CallErrorBuilder{callError: CallError{MessageTypeId: CALL_ERROR}}
+}
+
+// Sets the unique ID of the message, which should match the ID of the corresponding Call.
+func (b *CallErrorBuilder) Id(uniqueId string) *CallErrorBuilder {
+	b.callError.UniqueId = uniqueId
+	return b
+}
+
+// Sets the error code of the message.
+func (b *CallErrorBuilder) Code(code ocpp.ErrorCode) *CallErrorBuilder {
+	b.callError.ErrorCode = code
+	return b
+}
+
+// Sets the error description of the message.
+func (b *CallErrorBuilder) Description(description string) *CallErrorBuilder {
+	b.callError.ErrorDescription = description
+	return b
+}
+
+// Sets the error details of the message. If not set, an empty object is serialized.
+func (b *CallErrorBuilder) Details(details interface{}) *CallErrorBuilder {
+	b.callError.ErrorDetails = details
+	return b
+}
+
+// Disables validation of the message, allowing to build invalid messages (e.g. for fuzzing).
+func (b *CallErrorBuilder) SkipValidation() *CallErrorBuilder {
+	b.skipValidation = true
+	return b
+}
+
+// Returns the CallError message, validating it unless validation was skipped.
+func (b *CallErrorBuilder) Message() (*CallError, error) {
+	callError := b.callError
+	if !b.skipValidation {
+		if err := Validate.Struct(callError); err != nil {
+			return nil, err
+		}
+	}
+	return &callError, nil
+}
+
+// Serializes the message to an OCPP-J frame.
+func (b *CallErrorBuilder) Build() ([]byte, error) {
+	callError, err := b.Message()
+	if err != nil {
+		return nil, err
+	}
+	return callError.MarshalJSON()
+}
@@ -0,0 +1,81 @@
+package ocppj_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+// A request implementing GetFeatureName on the value receiver, like the OCPP messages.
+type valueReceiverRequest struct{}
+
+func (r valueReceiverRequest) GetFeatureName() string {
+	return "ValueReceiver"
+}
+
+func (suite *OcppJTestSuite) TestBuildCall() {
+	t := suite.T()
+	data, err := ocppj.NewCall().Action(MockFeatureName).Id("abc").Payload(newMockRequest("value")).Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[2,"abc","Mock",{"ExpectedCalls":null,"Calls":null,"mockValue":"value","mockAny":null}]`, string(data))
+	// Action is derived from the payload
+	call, err := ocppj.NewCall().Id("abc").Payload(newMockRequest("value")).Message()
+	require.NoError(t, err)
+	CheckCall(call, t, MockFeatureName, "abc")
+	// Unique ID is generated if missing
+	call, err = ocppj.NewCall().Payload(newMockRequest("value")).Message()
+	require.NoError(t, err)
+	assert.NotEmpty(t, call.UniqueId)
+	// Invalid payload is rejected
+	_, err = ocppj.NewCall().Id("abc").Payload(newMockRequest("")).Build()
+	assert.Error(t, err)
+	_, err = ocppj.NewCall().Id("abc").Action(MockFeatureName).Payload(map[string]string{"key": "value"}).Build()
+	assert.Error(t, err)
+	// Typed nil payloads are rejected instead of panicking
+	_, err = ocppj.NewCall().Id("abc").Payload((*valueReceiverRequest)(nil)).Message()
+	assert.Error(t, err)
+	data, err = ocppj.NewCall().Id("abc").Payload((*valueReceiverRequest)(nil)).SkipValidation().Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[2,"abc","",null]`, string(data))
+	// Validation may be skipped
+	data, err = ocppj.NewCall().Id("abc").Action("Invalid").Payload(map[string]int{"key": 42}).SkipValidation().Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[2,"abc","Invalid",{"key":42}]`, string(data))
+}
+
+func (suite *OcppJTestSuite) TestBuildCallResult() {
+	t := suite.T()
+	data, err := ocppj.NewCallResult().Id("abc").Payload(newMockConfirmation("value")).Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[3,"abc",{"ExpectedCalls":null,"Calls":null,"mockValue":"value"}]`, string(data))
+	// Invalid messages are rejected
+	_, err = ocppj.NewCallResult().Id("abc").Payload(newMockConfirmation("val")).Build()
+	assert.Error(t, err)
+	_, err = ocppj.NewCallResult().Payload(newMockConfirmation("value")).Build()
+	assert.Error(t, err)
+	// Validation may be skipped
+	data, err = ocppj.NewCallResult().Id("abc").Payload("raw").SkipValidation().Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[3,"abc","raw"]`, string(data))
+}
+
+func (suite *OcppJTestSuite) TestBuildCallError() {
+	t := suite.T()
+	data, err := ocppj.NewCallError().Id("abc").Code(ocppj.GenericError).Description("failure").Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[4,"abc","GenericError","failure",{}]`, string(data))
+	data, err = ocppj.NewCallError().Id("abc").Code(ocppj.InternalError).Details(map[string]string{"details": "someDetails"}).Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[4,"abc","InternalError","",{"details":"someDetails"}]`, string(data))
+	callError, err := ocppj.NewCallError().Id("abc").Code(ocppj.NotSupported).Message()
+	require.NoError(t, err)
+	CheckCallError(t, callError, "abc", ocppj.NotSupported, "", nil)
+	// Invalid error code is rejected
+	_, err = ocppj.NewCallError().Id("abc").Code("invalidCode").Build()
+	assert.Error(t, err)
+	// Validation may be skipped
+	data, err = ocppj.NewCallError().Id("abc").Code("invalidCode").SkipValidation().Build()
+	require.NoError(t, err)
+	assert.Equal(t, `[4,"abc","invalidCode","",{}]`, string(data))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -164,6 +165,129 @@ func (suite *OcppJTestSuite) TestCentralSystemInvalidMessageHook() {
 	assert.Equal(t, mockError.MessageId, ocppErr.MessageId)
 }
 
+func (suite *OcppJTestSuite) TestCentralSystemStrictJSONParsing() {
+	t := suite.T()
+	mockChargePointId := "1234"
+	mockChargePoint := NewMockWebSocket(mockChargePointId)
+	mockID := "5678"
+	duplicateKeyMessage := fmt.Sprintf(`[2,"%v","%v",{"mockValue":"first","mockAny":{"a":1,"b":2,"a":3},"mockValue":"second"}]`, mockID, MockFeatureName)
+	expectedError := fmt.Sprintf(`[4,"%v","%v","Duplicate JSON keys: [3].mockAny.a, [3].mockValue",{}]`, mockID, ocppj.FormatErrorType(suite.centralSystem))
+	var written []string
+	suite.mockServer.On("Write", mockChargePointId, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		written = append(written, string(args.Get(1).([]byte)))
+	})
+	suite.mockServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	var received []*MockRequest
+	suite.centralSystem.SetRequestHandler(func(chargePoint ws.Channel, request ocpp.Request, requestId string, action string) {
+		received = append(received, request.(*MockRequest))
+	})
+	var hookErr *ocpp.Error
+	suite.centralSystem.SetInvalidMessageHook(func(client ws.Channel, err *ocpp.Error, rawMessage string, parsedFields []interface{}) *ocpp.Error {
+		hookErr = err
+		return nil
+	})
+	suite.centralSystem.Start(8887, "/{ws}")
+	// Lenient parsing (default) accepts the message, using the last value
+	err := suite.mockServer.MessageHandler(mockChargePoint, []byte(duplicateKeyMessage))
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, "second", received[0].MockValue)
+	assert.Nil(t, hookErr)
+	assert.Empty(t, written)
+	// Strict parsing rejects the message
+	ocppj.SetStrictJSONParsing(true)
+	defer ocppj.SetStrictJSONParsing(false)
+	err = suite.mockServer.MessageHandler(mockChargePoint, []byte(duplicateKeyMessage))
+	ocppErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.FormatErrorType(suite.centralSystem), ocppErr.Code)
+	assert.Equal(t, mockID, ocppErr.MessageId)
+	require.NotNil(t, hookErr)
+	assert.Equal(t, ocppErr.Description, hookErr.Description)
+	assert.Len(t, received, 1)
+	require.Len(t, written, 1)
+	assert.Equal(t, expectedError, written[0])
+	// Strict parsing accepts messages without duplicate keys
+	err = suite.mockServer.MessageHandler(mockChargePoint, []byte(fmt.Sprintf(`[2,"%v","%v",{"mockValue":"value","mockAny":[{"a":1},{"a":2}]}]`, mockID, MockFeatureName)))
+	require.NoError(t, err)
+	require.Len(t, received, 2)
+	assert.Equal(t, "value", received[1].MockValue)
+}
+
+func (suite *OcppJTestSuite) TestCentralSystemPauseClient() {
+	t := suite.T()
+	mockChargePointId := "1234"
+	mockChargePoint := NewMockWebSocket(mockChargePointId)
+	writtenC := make(chan string, 10)
+	suite.mockServer.On("Write", mockChargePointId, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		writtenC <- string(args.Get(1).([]byte))
+	})
+	suite.mockServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	var received []string
+	suite.centralSystem.SetRequestHandler(func(chargePoint ws.Channel, request ocpp.Request, requestId string, action string) {
+		received = append(received, requestId)
+	})
+	suite.centralSystem.Start(8887, "/{ws}")
+	// Only connected clients may be paused
+	err := suite.centralSystem.PauseClient(mockChargePointId)
+	require.Error(t, err)
+	suite.mockServer.NewClientHandler(mockChargePoint)
+	err = suite.centralSystem.PauseClient(mockChargePointId)
+	require.NoError(t, err)
+	// Incoming requests are buffered
+	for _, requestID := range []string{"a", "b", "c"} {
+		err = suite.mockServer.MessageHandler(mockChargePoint, []byte(fmt.Sprintf(`[2,"%v","%v",{"mockValue":"value"}]`, requestID, MockFeatureName)))
+		require.NoError(t, err)
+	}
+	assert.Empty(t, received)
+	// Outgoing requests are queued, but not dispatched
+	err = suite.centralSystem.SendRequest(mockChargePointId, newMockRequest("somevalue"))
+	require.NoError(t, err)
+	select {
+	case written := <-writtenC:
+		t.Fatalf("unexpected message written to paused client: %v", written)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Buffered requests are processed in order, then the queued request is dispatched
+	err = suite.centralSystem.ResumeClient(mockChargePointId)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, received)
+	select {
+	case written := <-writtenC:
+		assert.True(t, strings.HasPrefix(written, "[2,"))
+	case <-time.After(time.Second):
+		t.Fatal("queued request wasn't dispatched after resume")
+	}
+	err = suite.centralSystem.ResumeClient(mockChargePointId)
+	require.Error(t, err)
+	// Requests exceeding the buffer limit are rejected
+	suite.centralSystem.SetPausedBufferLimit(1)
+	err = suite.centralSystem.PauseClient(mockChargePointId)
+	require.NoError(t, err)
+	for _, requestID := range []string{"d", "e"} {
+		err = suite.mockServer.MessageHandler(mockChargePoint, []byte(fmt.Sprintf(`[2,"%v","%v",{"mockValue":"value"}]`, requestID, MockFeatureName)))
+		require.NoError(t, err)
+	}
+	select {
+	case written := <-writtenC:
+		assert.Equal(t, fmt.Sprintf(`[4,"e","%v","Client is paused and its buffer is full",{}]`, ocppj.GenericError), written)
+	case <-time.After(time.Second):
+		t.Fatal("request exceeding the buffer limit wasn't rejected")
+	}
+	err = suite.centralSystem.ResumeClient(mockChargePointId)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, received)
+	// Buffered requests are discarded when the client disconnects
+	err = suite.centralSystem.PauseClient(mockChargePointId)
+	require.NoError(t, err)
+	err = suite.mockServer.MessageHandler(mockChargePoint, []byte(fmt.Sprintf(`[2,"f","%v",{"mockValue":"value"}]`, MockFeatureName)))
+	require.NoError(t, err)
+	suite.mockServer.DisconnectedClientHandler(mockChargePoint)
+	err = suite.centralSystem.ResumeClient(mockChargePointId)
+	require.Error(t, err)
+	assert.Len(t, received, 4)
+}
+
 func (suite *OcppJTestSuite) TestServerSendInvalidCall() {
 	mockChargePointId := "1234"
 	suite.mockServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
@@ -332,7 +456,7 @@ func (suite *OcppJTestSuite) TestCentralSystemHandleFailedResponse() {
 	require.Nil(t, callResult)
 	suite.centralSystem.HandleFailedResponseError(mockChargePointID, mockUniqueID, err, mockResponse.GetFeatureName())
 	rawResponse := <-msgC
-	expectedErr := fmt.Sprintf(`[4,"%v","%v","Field %s required but not found for feature %s",{}]`, mockUniqueID, ocppj.OccurrenceConstraintViolation, mockField, mockResponse.GetFeatureName())
+	expectedErr := fmt.Sprintf(`[4,"%v","%v","Field %s required but not found for feature %s",{}]`, mockUniqueID, ocppj.OccurrenceConstraintViolationV16, mockField, mockResponse.GetFeatureName())
 	assert.Equal(t, expectedErr, string(rawResponse))
 	// 2. property constraint validation error
 	val := "len4"
@@ -453,6 +577,57 @@ func (suite *OcppJTestSuite) TestCentralSystemRequestHandler() {
 	assert.Nil(t, err)
 }
 
+func (suite *OcppJTestSuite) TestCentralSystemBinaryMessageHandler() {
+	t := suite.T()
+	mockChargePointId := "1234"
+	mockData := []byte{0x00, 0x01, 0xfe, 0xff}
+	requestIdC := make(chan string, 1)
+	binaryC := make(chan []byte, 1)
+	suite.mockServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return()
+	suite.mockServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		arr, err := ocppj.ParseRawJsonMessage(args.Get(1).([]byte))
+		require.NoError(t, err)
+		requestIdC <- arr[1].(string)
+	})
+	suite.mockServer.On("WriteBinary", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		binaryC <- args.Get(1).([]byte)
+	})
+	suite.centralSystem.Start(8887, "somePath")
+	suite.serverDispatcher.CreateClient(mockChargePointId)
+	// Without a handler, binary messages are discarded with an error
+	channel := NewMockWebSocket(mockChargePointId)
+	err := suite.mockServer.BinaryMessageHandler(channel, mockData)
+	require.Error(t, err)
+	suite.centralSystem.SetBinaryMessageHandler(func(chargePoint ws.Channel, data []byte) {
+		assert.Equal(t, mockChargePointId, chargePoint.ID())
+		assert.Equal(t, mockData, data)
+	})
+	// Simulate binary charge point message
+	err = suite.mockServer.BinaryMessageHandler(channel, mockData)
+	require.NoError(t, err)
+	// Binary data is queued behind a pending request
+	err = suite.centralSystem.SendRequest(mockChargePointId, newMockRequest("testValue"))
+	require.NoError(t, err)
+	requestId := <-requestIdC
+	err = suite.centralSystem.SendBinary(mockChargePointId, mockData)
+	require.NoError(t, err)
+	select {
+	case <-binaryC:
+		require.Fail(t, "binary message sent before the pending request was completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Binary data is written as is, once the request was completed
+	err = suite.mockServer.MessageHandler(channel, []byte(fmt.Sprintf(`[3,"%v",{"mockValue":"someValue"}]`, requestId)))
+	require.NoError(t, err)
+	select {
+	case data := <-binaryC:
+		assert.Equal(t, mockData, data)
+	case <-time.After(time.Second):
+		require.Fail(t, "binary message wasn't sent")
+	}
+	suite.mockServer.AssertNumberOfCalls(t, "Write", 1)
+}
+
 func (suite *OcppJTestSuite) TestCentralSystemConfirmationHandler() {
 	t := suite.T()
 	mockChargePointId := "1234"
@@ -521,6 +696,113 @@ func addMockPendingRequest(suite *OcppJTestSuite, mockRequest ocpp.Request, mock
 	suite.centralSystem.RequestState.AddPendingRequest(mockChargePointID, mockUniqueID, mockRequest)
 }
 
+type memoryAuditSink struct {
+	mutex   sync.Mutex
+	entries []ocppj.AuditEntry
+}
+
+func (s *memoryAuditSink) Append(entry ocppj.AuditEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *memoryAuditSink) snapshot() []ocppj.AuditEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ocppj.AuditEntry{}, s.entries...)
+}
+
+func (suite *OcppJTestSuite) TestCentralSystemAuditLog() {
+	t := suite.T()
+	mockChargePointId := "1234"
+	sink := &memoryAuditSink{}
+	suite.centralSystem.SetAuditLog(ocppj.NewAuditLog(sink))
+	suite.serverDispatcher.SetTimeout(100 * time.Millisecond)
+	writeC := make(chan string, 3)
+	suite.mockServer.On("Start", mock.AnythingOfType("int"), mock.AnythingOfType("string")).Return(nil)
+	suite.mockServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		var fields []interface{}
+		err := json.Unmarshal(args.Get(1).([]byte), &fields)
+		require.NoError(t, err)
+		writeC <- fields[1].(string)
+	}).Return(nil)
+	canceledC := make(chan struct{}, 1)
+	suite.centralSystem.SetCanceledRequestHandler(func(clientID string, requestID string, request ocpp.Request, err *ocpp.Error) {
+		canceledC <- struct{}{}
+	})
+	suite.centralSystem.Start(8887, "somePath")
+	channel := NewMockWebSocket(mockChargePointId)
+	suite.mockServer.NewClientHandler(channel)
+	// Request with a result
+	err := suite.centralSystem.SendRequest(mockChargePointId, newMockRequest("first"))
+	require.NoError(t, err)
+	firstID := <-writeC
+	resultJson := fmt.Sprintf(`[3,"%v",{"mockValue":"someValue"}]`, firstID)
+	err = suite.mockServer.MessageHandler(channel, []byte(resultJson))
+	require.NoError(t, err)
+	// Request with an error
+	err = suite.centralSystem.SendRequest(mockChargePointId, newMockRequest("second"))
+	require.NoError(t, err)
+	secondID := <-writeC
+	err = suite.mockServer.MessageHandler(channel, []byte(fmt.Sprintf(`[4,"%v","%v","someError",{}]`, secondID, ocppj.GenericError)))
+	require.NoError(t, err)
+	// Request timing out
+	err = suite.centralSystem.SendRequest(mockChargePointId, newMockRequest("third"))
+	require.NoError(t, err)
+	thirdID := <-writeC
+	select {
+	case <-canceledC:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for request cancellation")
+	}
+	// Incoming requests aren't recorded
+	err = suite.mockServer.MessageHandler(channel, []byte(fmt.Sprintf(`[2,"5678","%v",{"mockValue":"someValue"}]`, MockFeatureName)))
+	require.NoError(t, err)
+
+	entries := sink.snapshot()
+	require.Len(t, entries, 6)
+	expected := []struct {
+		requestID string
+		kind      ocppj.AuditEntryKind
+	}{
+		{firstID, ocppj.AuditRequest},
+		{firstID, ocppj.AuditResponse},
+		{secondID, ocppj.AuditRequest},
+		{secondID, ocppj.AuditError},
+		{thirdID, ocppj.AuditRequest},
+		{thirdID, ocppj.AuditCanceled},
+	}
+	for i, e := range expected {
+		assert.Equal(t, uint64(i+1), entries[i].Sequence)
+		assert.Equal(t, mockChargePointId, entries[i].ClientID)
+		assert.Equal(t, e.requestID, entries[i].RequestID)
+		assert.Equal(t, e.kind, entries[i].Kind)
+		assert.Equal(t, MockFeatureName, entries[i].Action)
+	}
+	assert.Contains(t, string(entries[0].Message), `"first"`)
+	assert.Equal(t, resultJson, string(entries[1].Message))
+	assert.Equal(t, ocppj.GenericError, entries[3].ErrorCode)
+	assert.Equal(t, "someError", entries[3].ErrorDetail)
+	assert.Empty(t, entries[5].Message)
+	assert.Equal(t, ocppj.GenericError, entries[5].ErrorCode)
+	assert.Empty(t, entries[0].PreviousHash)
+	// Chain is consistent
+	require.NoError(t, ocppj.VerifyAuditChain(entries))
+	require.NoError(t, ocppj.VerifyAuditChain(entries[2:]))
+	// Altering an entry breaks the chain
+	altered := append([]ocppj.AuditEntry{}, entries...)
+	altered[2].Message = []byte(strings.Replace(string(altered[2].Message), "second", "other", 1))
+	assert.Error(t, ocppj.VerifyAuditChain(altered))
+	// Recomputing the hash of an altered entry breaks the link to the next entry
+	altered[2].Hash = altered[2].ComputeHash()
+	assert.Error(t, ocppj.VerifyAuditChain(altered))
+	// Removing an entry breaks the chain
+	removed := append(append([]ocppj.AuditEntry{}, entries[:3]...), entries[4:]...)
+	assert.Error(t, ocppj.VerifyAuditChain(removed))
+}
+
 // ----------------- Queue processing tests -----------------
 
 func (suite *OcppJTestSuite) TestServerEnqueueRequest() {
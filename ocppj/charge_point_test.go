@@ -306,7 +306,7 @@ func (suite *OcppJTestSuite) TestChargePointHandleFailedResponse() {
 	require.Nil(t, callResult)
 	suite.chargePoint.HandleFailedResponseError(mockUniqueID, err, mockResponse.GetFeatureName())
 	rawResponse := <-msgC
-	expectedErr := fmt.Sprintf(`[4,"%v","%v","Field %s required but not found for feature %s",{}]`, mockUniqueID, ocppj.OccurrenceConstraintViolation, mockField, mockResponse.GetFeatureName())
+	expectedErr := fmt.Sprintf(`[4,"%v","%v","Field %s required but not found for feature %s",{}]`, mockUniqueID, ocppj.OccurrenceConstraintViolationV16, mockField, mockResponse.GetFeatureName())
 	assert.Equal(t, expectedErr, string(rawResponse))
 	// 2. property constraint validation error
 	val := "len4"
@@ -377,6 +377,55 @@ func (suite *OcppJTestSuite) TestChargePointCallHandler() {
 	assert.Nil(t, err)
 }
 
+func (suite *OcppJTestSuite) TestChargePointBinaryMessageHandler() {
+	t := suite.T()
+	mockData := []byte{0x00, 0x01, 0xfe, 0xff}
+	var received []byte
+	requestIdC := make(chan string, 1)
+	binaryC := make(chan []byte, 1)
+	suite.mockClient.On("Start", mock.AnythingOfType("string")).Return(nil)
+	suite.mockClient.On("Write", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		arr, err := ocppj.ParseRawJsonMessage(args.Get(0).([]byte))
+		require.NoError(t, err)
+		requestIdC <- arr[1].(string)
+	})
+	suite.mockClient.On("WriteBinary", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		binaryC <- args.Get(0).([]byte)
+	})
+	err := suite.chargePoint.Start("somePath")
+	require.NoError(t, err)
+	// Binary message is discarded, as long as no handler is set
+	err = suite.mockClient.BinaryMessageHandler(mockData)
+	assert.Error(t, err)
+	suite.chargePoint.SetBinaryMessageHandler(func(data []byte) {
+		received = data
+	})
+	err = suite.mockClient.BinaryMessageHandler(mockData)
+	require.NoError(t, err)
+	assert.Equal(t, mockData, received)
+	// Binary data is queued behind a pending request
+	err = suite.chargePoint.SendRequest(newMockRequest("testValue"))
+	require.NoError(t, err)
+	requestId := <-requestIdC
+	err = suite.chargePoint.SendBinary(mockData)
+	require.NoError(t, err)
+	select {
+	case <-binaryC:
+		require.Fail(t, "binary message sent before the pending request was completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Binary data is written as is, once the request was completed
+	err = suite.mockClient.MessageHandler([]byte(fmt.Sprintf(`[3,"%v",{"mockValue":"someValue"}]`, requestId)))
+	require.NoError(t, err)
+	select {
+	case data := <-binaryC:
+		assert.Equal(t, mockData, data)
+	case <-time.After(time.Second):
+		require.Fail(t, "binary message wasn't sent")
+	}
+	suite.mockClient.AssertNumberOfCalls(t, "Write", 1)
+}
+
 func (suite *OcppJTestSuite) TestChargePointCallResultHandler() {
 	t := suite.T()
 	mockUniqueId := "5678"
@@ -422,6 +471,45 @@ func (suite *OcppJTestSuite) TestChargePointCallErrorHandler() {
 	assert.Nil(t, err)
 }
 
+func (suite *OcppJTestSuite) TestChargePointUnknownActionPolicy() {
+	t := suite.T()
+	mockUniqueId := "1234"
+	mockCall := fmt.Sprintf(`[2,"%v","UnknownAction",{}]`, mockUniqueId)
+	var written [][]byte
+	suite.mockClient.On("Start", mock.AnythingOfType("string")).Return(nil)
+	suite.mockClient.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(0).([]byte))
+	}).Return(nil)
+	suite.chargePoint.SetRequestHandler(func(request ocpp.Request, requestId string, action string) {
+		t.Fatalf("unexpected request %v", action)
+	})
+	err := suite.chargePoint.Start("someUrl")
+	require.NoError(t, err)
+	defer ocppj.SetUnknownActionPolicy(ocppj.RespondNotSupported)
+	testTable := []struct {
+		policy        ocppj.UnknownActionPolicy
+		expectedError ocpp.ErrorCode
+	}{
+		{ocppj.RespondNotSupported, ocppj.NotSupported},
+		{ocppj.RespondNotImplemented, ocppj.NotImplemented},
+		{ocppj.Drop, ""},
+	}
+	for _, tc := range testTable {
+		written = nil
+		ocppj.SetUnknownActionPolicy(tc.policy)
+		err = suite.mockClient.MessageHandler([]byte(mockCall))
+		if tc.expectedError == "" {
+			assert.NoError(t, err)
+			assert.Empty(t, written)
+			continue
+		}
+		require.Error(t, err)
+		require.Len(t, written, 1)
+		expected := fmt.Sprintf(`[4,"%v","%v","Unsupported feature UnknownAction",{}]`, mockUniqueId, tc.expectedError)
+		assert.Equal(t, expected, string(written[0]))
+	}
+}
+
 // ----------------- Queue processing tests -----------------
 
 func (suite *OcppJTestSuite) TestClientEnqueueRequest() {
@@ -749,6 +837,118 @@ func (suite *OcppJTestSuite) TestClientReconnected() {
 	assert.True(t, suite.chargePoint.IsConnected())
 }
 
+// TestClientQueueFlushFilter ensures that upon reconnection, the queue flush filter is applied
+// to all queued requests, before these are sent to the server.
+func (suite *OcppJTestSuite) TestClientQueueFlushFilter() {
+	t := suite.T()
+	writeC := make(chan *ocppj.Call, 4)
+	suite.mockClient.On("Start", mock.AnythingOfType("string")).Return(nil)
+	suite.mockClient.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		data := args.Get(0).([]byte)
+		call := ParseCall(&suite.chargePoint.Endpoint, suite.chargePoint.RequestState, string(data), t)
+		require.NotNil(t, call)
+		writeC <- call
+	}).Return(nil)
+	isConnectedCall := suite.mockClient.On("IsConnected").Return(true)
+	var canceled []string
+	suite.chargePoint.SetOnRequestCanceled(func(requestId string, request ocpp.Request, err *ocpp.Error) {
+		canceled = append(canceled, request.(*MockRequest).MockValue)
+		assert.Equal(t, ocppj.GenericError, err.Code)
+		assert.Equal(t, requestId, err.MessageId)
+	})
+	filtered := map[string]string{}
+	suite.chargePoint.SetQueueFlushFilter(func(clientID string, request ocpp.Request) (ocpp.Request, bool) {
+		assert.Equal(t, suite.chargePoint.Id, clientID)
+		value := request.(*MockRequest).MockValue
+		filtered[value] = clientID
+		switch value {
+		case "drop":
+			return nil, false
+		case "update":
+			return newMockRequest("updated"), true
+		case "invalid":
+			return newMockRequest("tooLongToBeValid"), true
+		default:
+			return request, true
+		}
+	})
+	err := suite.chargePoint.Start("someUrl")
+	require.NoError(t, err)
+	// Disconnect and queue some requests
+	isConnectedCall.Return(false)
+	suite.mockClient.DisconnectedHandler(fmt.Errorf("some error"))
+	values := []string{"keep", "drop", "update", "invalid"}
+	for _, value := range values {
+		err = suite.chargePoint.SendRequest(newMockRequest(value))
+		require.NoError(t, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, writeC, 0)
+	assert.Equal(t, len(values), suite.clientRequestQueue.Size())
+	bundle := suite.clientRequestQueue.Peek().(ocppj.RequestBundle)
+	firstID := bundle.Call.UniqueId
+	// Reconnect
+	isConnectedCall.Return(true)
+	suite.mockClient.ReconnectedHandler()
+	assert.Len(t, filtered, len(values))
+	assert.Equal(t, []string{"drop"}, canceled)
+	assert.Equal(t, len(values)-1, suite.clientRequestQueue.Size())
+	expected := []string{"keep", "updated", "invalid"}
+	for i, value := range expected {
+		call := <-writeC
+		assert.Equal(t, value, call.Payload.(*MockRequest).MockValue)
+		if i == 0 {
+			assert.Equal(t, firstID, call.UniqueId)
+		}
+		suite.clientDispatcher.CompleteRequest(call.UniqueId)
+	}
+	assert.True(t, suite.clientRequestQueue.IsEmpty())
+}
+
+// TestClientResumeDoesNotResendPendingRequest ensures that after resuming the dispatcher,
+// the request at the front of the queue is sent exactly once, while it awaits a response.
+//
+// Requests queued right before resuming may still trigger dispatch events, e.g. while the queue flush filter
+// blocks the dispatcher, hence the scenario is repeated.
+func (suite *OcppJTestSuite) TestClientResumeDoesNotResendPendingRequest() {
+	t := suite.T()
+	writeC := make(chan *ocppj.Call, 10)
+	suite.mockClient.On("Start", mock.AnythingOfType("string")).Return(nil)
+	suite.mockClient.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		data := args.Get(0).([]byte)
+		call := ParseCall(&suite.chargePoint.Endpoint, suite.chargePoint.RequestState, string(data), t)
+		require.NotNil(t, call)
+		writeC <- call
+	}).Return(nil)
+	isConnectedCall := suite.mockClient.On("IsConnected").Return(true)
+	suite.chargePoint.SetQueueFlushFilter(func(clientID string, request ocpp.Request) (ocpp.Request, bool) {
+		time.Sleep(time.Millisecond)
+		return request, true
+	})
+	err := suite.chargePoint.Start("someUrl")
+	require.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		// Disconnect, queue some requests and reconnect right away
+		isConnectedCall.Return(false)
+		suite.mockClient.DisconnectedHandler(fmt.Errorf("some error"))
+		values := []string{"first", "second", "third"}
+		for _, value := range values {
+			err = suite.chargePoint.SendRequest(newMockRequest(value))
+			require.NoError(t, err)
+		}
+		isConnectedCall.Return(true)
+		suite.mockClient.ReconnectedHandler()
+		for _, value := range values {
+			call := <-writeC
+			assert.Equal(t, value, call.Payload.(*MockRequest).MockValue)
+			time.Sleep(5 * time.Millisecond)
+			require.Len(t, writeC, 0, "pending request was sent again")
+			suite.clientDispatcher.CompleteRequest(call.UniqueId)
+		}
+		require.True(t, suite.clientRequestQueue.IsEmpty())
+	}
+}
+
 // TestClientResponseTimeout ensures that upon a response timeout, the client dispatcher:
 //
 //   - cancels the current pending request
@@ -793,6 +993,58 @@ func (suite *OcppJTestSuite) TestClientResponseTimeout() {
 	assert.False(t, state.HasPendingRequest())
 }
 
+func (suite *OcppJTestSuite) testClientResponseUniqueIdMatching(lenient bool) {
+	t := suite.T()
+	req := newMockRequest("test")
+	requestIdC := make(chan string, 1)
+	responseC := make(chan string, 1)
+	timeoutC := make(chan bool, 1)
+	ocppj.SetLenientUniqueIdMatching(lenient)
+	defer ocppj.SetLenientUniqueIdMatching(false)
+	suite.mockClient.On("Start", mock.AnythingOfType("string")).Return(nil)
+	suite.mockClient.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		data := args.Get(0).([]byte)
+		call := ParseCall(&suite.chargePoint.Endpoint, suite.chargePoint.RequestState, string(data), t)
+		require.NotNil(t, call)
+		requestIdC <- call.UniqueId
+	}).Return(nil)
+	suite.chargePoint.SetResponseHandler(func(confirmation ocpp.Response, requestId string) {
+		require.NotNil(t, confirmation)
+		responseC <- requestId
+	})
+	suite.clientDispatcher.SetOnRequestCanceled(func(rID string, request ocpp.Request, err *ocpp.Error) {
+		timeoutC <- true
+	})
+	suite.clientDispatcher.SetTimeout(500 * time.Millisecond)
+	err := suite.chargePoint.Start("someUrl")
+	require.NoError(t, err)
+	err = suite.chargePoint.SendRequest(req)
+	require.NoError(t, err)
+	requestId := <-requestIdC
+	// Simulate a response with surrounding whitespace in the unique ID
+	mockConfirmation := fmt.Sprintf(`[3,"  %v\t",{"mockValue":"someValue"}]`, requestId)
+	err = suite.mockClient.MessageHandler([]byte(mockConfirmation))
+	require.NoError(t, err)
+	select {
+	case rID := <-responseC:
+		require.True(t, lenient, "unexpected response match in strict mode")
+		assert.Equal(t, requestId, rID)
+	case <-timeoutC:
+		require.False(t, lenient, "unexpected timeout in lenient mode")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for response or request timeout")
+	}
+	assert.False(t, suite.chargePoint.RequestState.HasPendingRequest())
+}
+
+func (suite *OcppJTestSuite) TestClientStrictUniqueIdMatching() {
+	suite.testClientResponseUniqueIdMatching(false)
+}
+
+func (suite *OcppJTestSuite) TestClientLenientUniqueIdMatching() {
+	suite.testClientResponseUniqueIdMatching(true)
+}
+
 func (suite *OcppJTestSuite) TestStopDisconnectedClient() {
 	t := suite.T()
 	suite.mockClient.On("Start", mock.AnythingOfType("string")).Return(nil)
@@ -1,6 +1,7 @@
 package ocppj
 
 import (
+	"context"
 	"fmt"
 
 	"gopkg.in/go-playground/validator.v9"
@@ -18,9 +19,14 @@ type Client struct {
 	requestHandler        func(request ocpp.Request, requestId string, action string)
 	responseHandler       func(response ocpp.Response, requestId string)
 	errorHandler          func(err *ocpp.Error, details interface{})
+	binaryMessageHandler  func(data []byte)
 	onDisconnectedHandler func(err error)
 	onReconnectedHandler  func()
 	invalidMessageHook    func(err *ocpp.Error, rawMessage string, parsedFields []interface{}) *ocpp.Error
+	canceledHandler       func(requestId string, request ocpp.Request, err *ocpp.Error)
+	requestObserver       RequestObserver
+	auditLog              *AuditLog
+	queueFlushFilter      QueueFlushFilter
 	dispatcher            ClientDispatcher
 	RequestState          ClientState
 }
@@ -51,7 +57,9 @@ func NewClient(id string, wsClient ws.WsClient, dispatcher ClientDispatcher, sta
 	}
 	dispatcher.SetNetworkClient(wsClient)
 	dispatcher.SetPendingRequestState(stateHandler)
-	return &Client{Endpoint: endpoint, client: wsClient, Id: id, dispatcher: dispatcher, RequestState: stateHandler}
+	c := &Client{Endpoint: endpoint, client: wsClient, Id: id, dispatcher: dispatcher, RequestState: stateHandler}
+	dispatcher.SetOnRequestCanceled(c.onRequestCanceled)
+	return c
 }
 
 // Registers a handler for incoming requests.
@@ -97,7 +105,104 @@ func (c *Client) SetOnReconnectedHandler(handler func()) {
 
 // Registers the handler to be called on timeout.
 func (c *Client) SetOnRequestCanceled(handler func(requestId string, request ocpp.Request, err *ocpp.Error)) {
-	c.dispatcher.SetOnRequestCanceled(handler)
+	c.canceledHandler = handler
+}
+
+// SetRequestObserver registers an optional observer, which is notified about the lifecycle of every request
+// sent to or received from the server. See RequestObserver for more details.
+//
+// The client ID passed to the observer is always the ID of this client, while the passed context is empty.
+func (c *Client) SetRequestObserver(observer RequestObserver) {
+	c.requestObserver = observer
+}
+
+// SetAuditLog enables recording every request sent to the server in the given audit log,
+// together with its raw response or cancellation. Pass nil to disable recording.
+//
+// Incoming requests aren't recorded. The client ID of all entries is the ID of this client. See AuditLog for more details.
+func (c *Client) SetAuditLog(auditLog *AuditLog) {
+	c.auditLog = auditLog
+}
+
+// QueueFlushFilter is invoked for every request, which was queued while the client was disconnected,
+// right before the queue is flushed to the server.
+//
+// Returning false drops the request, while the returned request replaces the original one.
+// Return the passed request (or nil) to send it unmodified.
+type QueueFlushFilter func(clientID string, request ocpp.Request) (ocpp.Request, bool)
+
+// SetQueueFlushFilter registers an optional filter, which is applied to all queued requests once the client
+// reconnects to the server, before the requests are sent. This allows to discard or update stale requests.
+//
+// The canceled request handler is invoked for every dropped request, with a GenericError.
+// A replaced request keeps the message ID of the original request.
+// If a replaced request cannot be validated, the original request is sent instead.
+//
+// Pass nil to remove the filter. The filter requires the dispatcher to implement QueueFilter.
+func (c *Client) SetQueueFlushFilter(filter QueueFlushFilter) {
+	c.queueFlushFilter = filter
+}
+
+// FilterQueue applies the filter to all requests, which are currently queued and weren't sent yet.
+// See SetQueueFlushFilter for details on how the filter is applied.
+//
+// Dropped requests are reported to the request observer, but the canceled request handler isn't invoked,
+// as the caller is in charge of handling them.
+//
+// Returns the positions of the dropped requests within the queue, including a request awaiting a response.
+// Returns an error if the dispatcher doesn't implement QueueFilter.
+func (c *Client) FilterQueue(filter QueueFlushFilter) ([]int, error) {
+	positions, _, err := c.filterQueue(filter)
+	return positions, err
+}
+
+func (c *Client) filterQueue(filter QueueFlushFilter) ([]int, []RequestBundle, error) {
+	queueFilter, ok := c.dispatcher.(QueueFilter)
+	if !ok {
+		return nil, nil, fmt.Errorf("dispatcher %T doesn't support filtering queues", c.dispatcher)
+	}
+	positions, dropped := queueFilter.FilterQueue(func(bundle RequestBundle) (RequestBundle, bool) {
+		request, keep := filter(c.Id, bundle.Call.Payload)
+		if !keep {
+			return bundle, false
+		}
+		if request == nil {
+			return bundle, true
+		}
+		call, err := c.CreateCall(request)
+		if err != nil {
+			log.Errorf("invalid replacement for queued request %v, keeping original: %v", bundle.Call.UniqueId, err)
+			return bundle, true
+		}
+		call.UniqueId = bundle.Call.UniqueId
+		jsonMessage, err := call.MarshalJSON()
+		if err != nil {
+			log.Errorf("invalid replacement for queued request %v, keeping original: %v", bundle.Call.UniqueId, err)
+			return bundle, true
+		}
+		return RequestBundle{Call: call, Data: jsonMessage}, true
+	})
+	for _, bundle := range dropped {
+		droppedErr := newQueueFlushDroppedError(bundle.Call.UniqueId)
+		if c.auditLog != nil {
+			c.auditLog.recordError(c.Id, bundle.Call.UniqueId, AuditCanceled, nil, droppedErr)
+		}
+		c.notifyRequestCompleted(bundle.Call.UniqueId, RequestOutgoing, droppedErr)
+	}
+	return positions, dropped, nil
+}
+
+func newQueueFlushDroppedError(requestID string) *ocpp.Error {
+	return ocpp.NewError(GenericError, "Request dropped by queue flush filter", requestID)
+}
+
+// Registers a handler for incoming binary messages.
+//
+// Binary frames carry raw data (e.g. OCPP 2.1 binary data transfers) and are not OCPP-J messages,
+// hence they are passed to the handler as is, bypassing parsing, validation and the request state.
+// If no handler is registered, incoming binary messages are discarded and an error is reported on the errors channel.
+func (c *Client) SetBinaryMessageHandler(handler func(data []byte)) {
+	c.binaryMessageHandler = handler
 }
 
 // Connects to the given serverURL and starts running the I/O loop for the underlying connection.
@@ -112,6 +217,7 @@ func (c *Client) SetOnRequestCanceled(handler func(requestId string, request ocp
 func (c *Client) Start(serverURL string) error {
 	// Set internal message handler
 	c.client.SetMessageHandler(c.ocppMessageHandler)
+	c.client.SetBinaryMessageHandler(c.binaryMessageHandlerFunc)
 	c.client.SetDisconnectedHandler(c.onDisconnected)
 	c.client.SetReconnectedHandler(c.onReconnected)
 	// Connect & run
@@ -126,6 +232,7 @@ func (c *Client) Start(serverURL string) error {
 func (c *Client) StartWithRetries(serverURL string) {
 	// Set internal message handler
 	c.client.SetMessageHandler(c.ocppMessageHandler)
+	c.client.SetBinaryMessageHandler(c.binaryMessageHandlerFunc)
 	c.client.SetDisconnectedHandler(c.onDisconnected)
 	c.client.SetReconnectedHandler(c.onReconnected)
 	// Connect & run
@@ -183,9 +290,18 @@ func (c *Client) SendRequest(request ocpp.Request) error {
 	if err != nil {
 		return err
 	}
+	if c.auditLog != nil {
+		c.auditLog.recordRequest(c.Id, call, jsonMessage)
+	}
+	c.notifyRequestStarted(call.UniqueId, call.Action, RequestOutgoing)
 	// Message will be processed by dispatcher. A dedicated mechanism allows to delegate the message queue handling.
 	if err = c.dispatcher.SendRequest(RequestBundle{Call: call, Data: jsonMessage}); err != nil {
 		log.Errorf("error dispatching request [%s, %s]: %v", call.UniqueId, call.Action, err)
+		ocppErr := ocpp.NewError(GenericError, err.Error(), call.UniqueId)
+		if c.auditLog != nil {
+			c.auditLog.recordError(c.Id, call.UniqueId, AuditCanceled, nil, ocppErr)
+		}
+		c.notifyRequestCompleted(call.UniqueId, RequestOutgoing, ocppErr)
 		return err
 	}
 	log.Debugf("enqueued CALL [%s, %s]", call.UniqueId, call.Action)
@@ -215,6 +331,7 @@ func (c *Client) SendResponse(requestId string, response ocpp.Response) error {
 		log.Errorf("error sending response [%s]: %v", callResult.GetUniqueId(), err)
 		return ocpp.NewError(GenericError, err.Error(), requestId)
 	}
+	c.notifyRequestCompleted(requestId, RequestIncoming, nil)
 	log.Debugf("sent CALL RESULT [%s]", callResult.GetUniqueId())
 	log.Debugf("sent JSON message to server: %s", string(jsonMessage))
 	return nil
@@ -241,11 +358,40 @@ func (c *Client) SendError(requestId string, errorCode ocpp.ErrorCode, descripti
 		log.Errorf("error sending response error [%s]: %v", callError.UniqueId, err)
 		return ocpp.NewError(GenericError, err.Error(), requestId)
 	}
+	c.notifyRequestCompleted(requestId, RequestIncoming, ocpp.NewError(errorCode, description, requestId))
 	log.Debugf("sent CALL ERROR [%s]", callError.UniqueId)
 	log.Debugf("sent JSON message to server: %s", string(jsonMessage))
 	return nil
 }
 
+// Sends a binary message to the server.
+//
+// Binary messages are not OCPP-J messages, but they are queued by the dispatcher together with outgoing requests:
+// a binary message is sent once all previously queued requests were completed, and no response is awaited.
+// As the message is sent asynchronously, network errors are only logged.
+//
+// Returns an error if the client wasn't started, or the output queue is full.
+func (c *Client) SendBinary(data []byte) error {
+	if !c.dispatcher.IsRunning() {
+		return fmt.Errorf("ocppj client is not started, couldn't send binary message")
+	}
+	if err := c.dispatcher.SendRequest(RequestBundle{Data: data, Binary: true}); err != nil {
+		log.Errorf("error dispatching binary message: %v", err)
+		return err
+	}
+	log.Debugf("enqueued binary message of %d bytes", len(data))
+	return nil
+}
+
+func (c *Client) binaryMessageHandlerFunc(data []byte) error {
+	log.Debugf("received binary message of %d bytes from server", len(data))
+	if c.binaryMessageHandler == nil {
+		return fmt.Errorf("no handler for binary message from server, discarding")
+	}
+	c.binaryMessageHandler(data)
+	return nil
+}
+
 func (c *Client) ocppMessageHandler(data []byte) error {
 	parsedJson, err := ParseRawJsonMessage(data)
 	if err != nil {
@@ -253,7 +399,10 @@ func (c *Client) ocppMessageHandler(data []byte) error {
 		return err
 	}
 	log.Debugf("received JSON message from server: %s", string(data))
-	message, err := c.ParseMessage(parsedJson, c.RequestState)
+	var message Message
+	if err = c.checkDuplicateKeys(data, parsedJson); err == nil {
+		message, err = c.ParseMessage(parsedJson, c.RequestState)
+	}
 	if err != nil {
 		ocppErr := err.(*ocpp.Error)
 		messageID := ocppErr.MessageId
@@ -267,6 +416,17 @@ func (c *Client) ocppMessageHandler(data []byte) error {
 			}
 		}
 		err = ocppErr
+		if invalidResponse := newInvalidResponseError(parsedJson, data, c.RequestState, ocppErr); invalidResponse != nil {
+			// Complete the pending request, reporting the error to its sender
+			c.dispatcher.CompleteRequest(ocppErr.MessageId)
+			if c.auditLog != nil {
+				c.auditLog.recordError(c.Id, ocppErr.MessageId, AuditError, data, ocppErr)
+			}
+			c.notifyRequestCompleted(ocppErr.MessageId, RequestOutgoing, ocppErr)
+			if c.errorHandler != nil {
+				c.errorHandler(ocppErr, invalidResponse)
+			}
+		}
 		// Send error to other endpoint if a message ID is available
 		if ocppErr.MessageId != "" {
 			err2 := c.SendError(ocppErr.MessageId, ocppErr.Code, ocppErr.Description, nil)
@@ -282,11 +442,16 @@ func (c *Client) ocppMessageHandler(data []byte) error {
 		case CALL:
 			call := message.(*Call)
 			log.Debugf("handling incoming CALL [%s, %s]", call.UniqueId, call.Action)
+			c.notifyRequestStarted(call.UniqueId, call.Action, RequestIncoming)
 			c.requestHandler(call.Payload, call.UniqueId, call.Action)
 		case CALL_RESULT:
 			callResult := message.(*CallResult)
 			log.Debugf("handling incoming CALL RESULT [%s]", callResult.UniqueId)
 			c.dispatcher.CompleteRequest(callResult.GetUniqueId()) // Remove current request from queue and send next one
+			if c.auditLog != nil {
+				c.auditLog.recordResponse(c.Id, callResult.UniqueId, data)
+			}
+			c.notifyRequestCompleted(callResult.UniqueId, RequestOutgoing, nil)
 			if c.responseHandler != nil {
 				c.responseHandler(callResult.Payload, callResult.UniqueId)
 			}
@@ -294,8 +459,13 @@ func (c *Client) ocppMessageHandler(data []byte) error {
 			callError := message.(*CallError)
 			log.Debugf("handling incoming CALL ERROR [%s]", callError.UniqueId)
 			c.dispatcher.CompleteRequest(callError.GetUniqueId()) // Remove current request from queue and send next one
+			ocppErr := ocpp.NewError(callError.ErrorCode, callError.ErrorDescription, callError.UniqueId)
+			if c.auditLog != nil {
+				c.auditLog.recordError(c.Id, callError.UniqueId, AuditError, data, ocppErr)
+			}
+			c.notifyRequestCompleted(callError.UniqueId, RequestOutgoing, ocppErr)
 			if c.errorHandler != nil {
-				c.errorHandler(ocpp.NewError(callError.ErrorCode, callError.ErrorDescription, callError.UniqueId), callError.ErrorDetails)
+				c.errorHandler(ocppErr, callError.ErrorDetails)
 			}
 		}
 	}
@@ -317,7 +487,7 @@ func (c *Client) HandleFailedResponseError(requestID string, err error, featureN
 	case validator.ValidationErrors:
 		// Validation error
 		validationErr := err.(validator.ValidationErrors)
-		responseErr = errorFromValidation(validationErr, requestID, featureName)
+		responseErr = c.errorFromValidation(validationErr, requestID, featureName)
 	case *ocpp.Error:
 		// Internal OCPP error
 		responseErr = err.(*ocpp.Error)
@@ -338,8 +508,41 @@ func (c *Client) onDisconnected(err error) {
 }
 
 func (c *Client) onReconnected() {
+	if c.queueFlushFilter != nil {
+		_, dropped, err := c.filterQueue(c.queueFlushFilter)
+		if err != nil {
+			log.Errorf("couldn't apply queue flush filter: %v", err)
+		}
+		if c.canceledHandler != nil {
+			for _, bundle := range dropped {
+				c.canceledHandler(bundle.Call.UniqueId, bundle.Call.Payload, newQueueFlushDroppedError(bundle.Call.UniqueId))
+			}
+		}
+	}
 	if c.onReconnectedHandler != nil {
 		c.onReconnectedHandler()
 	}
 	c.dispatcher.Resume()
 }
+
+func (c *Client) onRequestCanceled(requestID string, request ocpp.Request, err *ocpp.Error) {
+	if c.auditLog != nil {
+		c.auditLog.recordError(c.Id, requestID, AuditCanceled, nil, err)
+	}
+	c.notifyRequestCompleted(requestID, RequestOutgoing, err)
+	if c.canceledHandler != nil {
+		c.canceledHandler(requestID, request, err)
+	}
+}
+
+func (c *Client) notifyRequestStarted(requestID string, action string, direction RequestDirection) {
+	if c.requestObserver != nil {
+		c.requestObserver.OnRequestStarted(context.Background(), c.Id, requestID, action, direction)
+	}
+}
+
+func (c *Client) notifyRequestCompleted(requestID string, direction RequestDirection, err *ocpp.Error) {
+	if c.requestObserver != nil {
+		c.requestObserver.OnRequestCompleted(c.Id, requestID, direction, err)
+	}
+}
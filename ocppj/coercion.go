@@ -0,0 +1,113 @@
+package ocppj
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Converts string values within a raw JSON payload to booleans or numbers, wherever the target type expects them.
+// The raw payload is modified in place and returned. The path is used for logging conversions, e.g. "MeterValuesRequest.connectorId".
+// Types implementing json.Unmarshaler are left untouched, since they define their own encoding.
+func coerceTypes(raw interface{}, targetType reflect.Type, path string) interface{} {
+	for targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+	if reflect.PtrTo(targetType).Implements(jsonUnmarshalerType) {
+		return raw
+	}
+	switch targetType.Kind() {
+	case reflect.Bool:
+		if s, ok := raw.(string); ok {
+			value := strings.TrimSpace(s)
+			if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+				log.Infof("Coerced string %q to boolean for field %v", s, path)
+				return strings.EqualFold(value, "true")
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s, ok := raw.(string); ok {
+			var number json.Number
+			// Only valid JSON numbers are accepted, the target type checks the range
+			if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &number); err == nil {
+				log.Infof("Coerced string %q to number for field %v", s, path)
+				return number
+			}
+		}
+	case reflect.Struct:
+		if object, ok := raw.(map[string]interface{}); ok {
+			fields := jsonFields(targetType)
+			for key, value := range object {
+				if field, ok := lookupJSONField(fields, key); ok {
+					object[key] = coerceTypes(value, field.Type, path+"."+key)
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if array, ok := raw.([]interface{}); ok {
+			for i, value := range array {
+				array[i] = coerceTypes(value, targetType.Elem(), fmt.Sprintf("%v[%v]", path, i))
+			}
+		}
+	case reflect.Map:
+		if object, ok := raw.(map[string]interface{}); ok {
+			for key, value := range object {
+				object[key] = coerceTypes(value, targetType.Elem(), path+"."+key)
+			}
+		}
+	}
+	return raw
+}
+
+// Returns the exported fields of a struct, keyed by their JSON name. Fields of embedded structs without a JSON name are promoted.
+func jsonFields(structType reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for embeddedName, embeddedField := range jsonFields(embedded) {
+					if _, ok := fields[embeddedName]; !ok {
+						fields[embeddedName] = embeddedField
+					}
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// Looks up a field by its JSON name. As in encoding/json, an exact match is preferred over a case-insensitive one.
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if field, ok := fields[key]; ok {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
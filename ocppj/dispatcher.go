@@ -38,6 +38,9 @@ type ClientDispatcher interface {
 	// Dispatches a request. Depending on the implementation, this may first queue a request
 	// and process it later, asynchronously, or write it directly to the networking layer.
 	//
+	// Binary bundles (see RequestBundle) must be written to the network without awaiting a response,
+	// once all previously dispatched requests were completed.
+	//
 	// If no network client was set, or the request couldn't be processed, an error is returned.
 	SendRequest(req RequestBundle) error
 	// Notifies the dispatcher that a request has been completed (i.e. a response was received).
@@ -127,6 +130,10 @@ func (d *DefaultClientDispatcher) Start() {
 	defer d.mutex.Unlock()
 	d.requestChannel = make(chan bool, 1)
 	d.timer = time.NewTimer(defaultTimeoutTick) // Default to 24 hours tick
+	if !d.requestQueue.IsEmpty() {
+		// Requests were queued beforehand, e.g. restored from a persistent queue
+		d.requestChannel <- true
+	}
 	go d.messagePump()
 }
 
@@ -202,7 +209,7 @@ func (d *DefaultClientDispatcher) messagePump() {
 				d.CompleteRequest(bundle.Call.UniqueId)
 				if d.onRequestCancel != nil {
 					d.onRequestCancel(bundle.Call.UniqueId, bundle.Call.Payload,
-						ocpp.NewError(GenericError, "Request timed out", bundle.Call.UniqueId))
+						ocpp.NewError(GenericError, RequestTimedOut, bundle.Call.UniqueId))
 				}
 			}
 			// No request is currently pending -> set timer to high number
@@ -217,9 +224,14 @@ func (d *DefaultClientDispatcher) messagePump() {
 			continue
 		}
 
-		// Only dispatch request if able to send and request queue isn't empty
-		if rdy && !d.requestQueue.IsEmpty() {
-			d.dispatchNextRequest()
+		// Only dispatch request if able to send and request queue isn't empty.
+		// Dispatch events queued while paused may arrive after resuming, i.e. after the front request was sent.
+		// That request awaits a response and must not be dispatched again.
+		if rdy && !d.requestQueue.IsEmpty() && !d.pendingRequestState.HasPendingRequest() {
+			if !d.dispatchNextRequest() {
+				// Only binary messages were sent, no response is awaited
+				continue
+			}
 			rdy = false
 			// Set timer
 			if !d.timer.Stop() {
@@ -230,10 +242,24 @@ func (d *DefaultClientDispatcher) messagePump() {
 	}
 }
 
-func (d *DefaultClientDispatcher) dispatchNextRequest() {
+// Sends all binary messages at the front of the queue, followed by the next request.
+// Returns false if no request was dispatched.
+func (d *DefaultClientDispatcher) dispatchNextRequest() bool {
 	// Get first element in queue
-	el := d.requestQueue.Peek()
-	bundle, _ := el.(RequestBundle)
+	bundle, ok := d.requestQueue.Peek().(RequestBundle)
+	for ok && bundle.Binary {
+		// Binary messages don't await a response, hence they are removed from the queue right away
+		d.requestQueue.Pop()
+		if err := d.network.WriteBinary(bundle.Data); err != nil {
+			log.Errorf("error while sending binary message: %v", err)
+		} else {
+			log.Debugf("sent binary message of %d bytes to server", len(bundle.Data))
+		}
+		bundle, ok = d.requestQueue.Peek().(RequestBundle)
+	}
+	if !ok {
+		return false
+	}
 	jsonMessage := bundle.Data
 	d.pendingRequestState.AddPendingRequest(bundle.Call.UniqueId, bundle.Call.Payload)
 	// Attempt to send over network
@@ -248,6 +274,7 @@ func (d *DefaultClientDispatcher) dispatchNextRequest() {
 	}
 	log.Infof("dispatched request %s to server", bundle.Call.UniqueId)
 	log.Debugf("sent JSON message to server: %s", string(jsonMessage))
+	return true
 }
 
 func (d *DefaultClientDispatcher) Pause() {
@@ -291,6 +318,49 @@ func (d *DefaultClientDispatcher) CompleteRequest(requestId string) {
 	d.readyForDispatch <- true
 }
 
+// QueueFilter is implemented by client dispatchers, which allow to rewrite the queue of outgoing requests.
+// The DefaultClientDispatcher implements this interface.
+type QueueFilter interface {
+	// FilterQueue invokes the filter on every queued request, which wasn't sent yet.
+	// Requests for which the filter returns false are removed from the queue,
+	// all other requests are replaced by the bundle returned by the filter.
+	//
+	// Returns the positions of the removed requests within the queue, as well as the removed requests.
+	FilterQueue(filter func(bundle RequestBundle) (RequestBundle, bool)) ([]int, []RequestBundle)
+}
+
+// FilterQueue invokes the filter on every queued request, which wasn't sent yet, preserving the queue order.
+// A request currently awaiting a response, as well as queued binary messages, are never passed to the filter.
+//
+// Removed requests are returned to the caller, without invoking the canceled request handler.
+// The returned positions are relative to the queue before filtering, including a request awaiting a response.
+//
+// The function is meant to be invoked while the dispatcher is paused, e.g. right before resuming it.
+func (d *DefaultClientDispatcher) FilterQueue(filter func(bundle RequestBundle) (RequestBundle, bool)) ([]int, []RequestBundle) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	var positions []int
+	var dropped []RequestBundle
+	pending := d.pendingRequestState.HasPendingRequest()
+	filterQueue(d.requestQueue, func(index int, element interface{}) (interface{}, bool) {
+		bundle, _ := element.(RequestBundle)
+		if index == 0 && pending {
+			// Request was already sent
+			return element, true
+		} else if bundle.Binary {
+			return element, true
+		}
+		filtered, keep := filter(bundle)
+		if !keep {
+			positions = append(positions, index)
+			dropped = append(dropped, bundle)
+			return element, false
+		}
+		return filtered, true
+	})
+	return positions, dropped
+}
+
 // ServerDispatcher contains the state and logic for handling outgoing messages on a server endpoint.
 // This allows the ocpp-j layer to delegate queueing and processing logic to an external entity.
 //
@@ -317,6 +387,9 @@ type ServerDispatcher interface {
 	// Dispatches a request for a specific client. Depending on the implementation, this may first queue
 	// a request and process it later (asynchronously), or write it directly to the networking layer.
 	//
+	// Binary bundles (see RequestBundle) must be written to the network without awaiting a response,
+	// once all previously dispatched requests for the client were completed.
+	//
 	// If no network server was set, or the request couldn't be processed, an error is returned.
 	SendRequest(clientID string, req RequestBundle) error
 	// Notifies the dispatcher that a request has been completed (i.e. a response was received),
@@ -367,12 +440,39 @@ type DefaultServerDispatcher struct {
 	readyForDispatch    chan string
 	pendingRequestState ServerState
 	timeout             time.Duration
+	clientTimeouts      map[string]time.Duration
+	timeoutMutex        sync.RWMutex
 	timerC              chan string
 	running             bool
 	stoppedC            chan struct{}
 	onRequestCancel     CanceledRequestHandler
 	network             ws.WsServer
 	mutex               sync.RWMutex
+	defaultPacing       time.Duration
+	pacing              map[string]time.Duration
+	flushing            map[string]bool // Clients, for which pacing is suspended until their queue is empty
+	paused              map[string]bool // Clients, for which no requests are dispatched until they are resumed
+	pacingMutex         sync.RWMutex
+	queueMutex          sync.Mutex // Guards the head of client queues, while requests are dispatched, completed or dropped
+	maxPendingAge       time.Duration
+	dispatched          map[string]dispatchedRequest // In-flight request per client, for enforcing the max pending age
+	dispatchedMutex     sync.Mutex
+	expiredC            chan dispatchedRequest
+}
+
+// RequestTimedOut is the description of the error passed to the OnRequestCanceled callback,
+// when no response to a request was received within the configured timeout.
+const RequestTimedOut = "Request timed out"
+
+// MaxPendingAgeExceeded is the description of the error passed to the OnRequestCanceled callback,
+// when a request is canceled because it exceeded the maximum pending age. See DefaultServerDispatcher.SetMaxPendingAge.
+const MaxPendingAgeExceeded = "Request exceeded maximum pending age"
+
+// An in-flight request, along with its dispatch time.
+type dispatchedRequest struct {
+	clientID  string
+	requestID string
+	sentAt    time.Time
 }
 
 // Handler function to be invoked when a request gets canceled (either due to timeout or to other external factors).
@@ -395,6 +495,11 @@ func NewDefaultServerDispatcher(queueMap ServerQueueMap) *DefaultServerDispatche
 		requestChannel:   nil,
 		readyForDispatch: make(chan string, 1),
 		timeout:          defaultMessageTimeout,
+		clientTimeouts:   map[string]time.Duration{},
+		pacing:           map[string]time.Duration{},
+		flushing:         map[string]bool{},
+		paused:           map[string]bool{},
+		dispatched:       map[string]dispatchedRequest{},
 	}
 	d.pendingRequestState = NewServerState(&d.mutex)
 	return d
@@ -406,8 +511,12 @@ func (d *DefaultServerDispatcher) Start() {
 	d.requestChannel = make(chan string, 20)
 	d.timerC = make(chan string, 10)
 	d.stoppedC = make(chan struct{}, 1)
+	d.expiredC = make(chan dispatchedRequest, 10)
 	d.running = true
 	go d.messagePump()
+	if d.maxPendingAge > 0 {
+		go d.sweepExpiredRequests(d.maxPendingAge, d.stoppedC)
+	}
 }
 
 func (d *DefaultServerDispatcher) IsRunning() bool {
@@ -427,6 +536,236 @@ func (d *DefaultServerDispatcher) SetTimeout(timeout time.Duration) {
 	d.timeout = timeout
 }
 
+// SetClientTimeout sets the timeout for requests sent to a specific client, overriding the timeout set via SetTimeout.
+// This allows to match the timeout to the processing capacity declared by a client, e.g. via its device model.
+//
+// A zero timeout falls back to the default timeout. The setting is retained across reconnections of the client.
+// The function may be called while the dispatcher is running and applies to requests dispatched afterwards.
+func (d *DefaultServerDispatcher) SetClientTimeout(clientID string, timeout time.Duration) {
+	d.timeoutMutex.Lock()
+	defer d.timeoutMutex.Unlock()
+	if timeout <= 0 {
+		delete(d.clientTimeouts, clientID)
+	} else {
+		d.clientTimeouts[clientID] = timeout
+	}
+}
+
+func (d *DefaultServerDispatcher) getTimeout(clientID string) time.Duration {
+	d.timeoutMutex.RLock()
+	defer d.timeoutMutex.RUnlock()
+	if timeout, ok := d.clientTimeouts[clientID]; ok {
+		return timeout
+	}
+	return d.timeout
+}
+
+// SetMaxPendingAge sets a hard ceiling for the time a dispatched request may remain pending,
+// regardless of the configured timeout. Requests exceeding the age are canceled by a background sweeper,
+// and the OnRequestCanceled callback is invoked with a MaxPendingAgeExceeded error.
+//
+// The max pending age is a safety net for bounding memory and detecting stuck clients, and is disabled by default.
+// Due to the sweep interval, requests may be canceled slightly after exceeding the age.
+//
+// This function must be called before starting the dispatcher, otherwise it has no effect until the next start.
+func (d *DefaultServerDispatcher) SetMaxPendingAge(maxAge time.Duration) {
+	d.maxPendingAge = maxAge
+}
+
+// Periodically looks for in-flight requests older than the max age and notifies the message pump about them.
+func (d *DefaultServerDispatcher) sweepExpiredRequests(maxAge time.Duration, stoppedC chan struct{}) {
+	interval := maxAge / 4
+	if interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var expired []dispatchedRequest
+			d.dispatchedMutex.Lock()
+			for _, request := range d.dispatched {
+				if time.Since(request.sentAt) >= maxAge {
+					expired = append(expired, request)
+				}
+			}
+			d.dispatchedMutex.Unlock()
+			for _, request := range expired {
+				select {
+				case d.expiredC <- request:
+				case <-stoppedC:
+					return
+				}
+			}
+		case <-stoppedC:
+			return
+		}
+	}
+}
+
+func (d *DefaultServerDispatcher) setDispatched(clientID string, requestID string) {
+	d.dispatchedMutex.Lock()
+	defer d.dispatchedMutex.Unlock()
+	d.dispatched[clientID] = dispatchedRequest{clientID: clientID, requestID: requestID, sentAt: time.Now()}
+}
+
+func (d *DefaultServerDispatcher) clearDispatched(clientID string) {
+	d.dispatchedMutex.Lock()
+	defer d.dispatchedMutex.Unlock()
+	delete(d.dispatched, clientID)
+}
+
+// SetOutboundPacing sets the minimum interval between two consecutive requests dispatched to a specific client.
+// If a request becomes ready for dispatch earlier, it is delayed until the interval elapsed,
+// spreading out bursts of requests for clients that cannot process them quickly enough.
+// Incoming messages and outgoing responses are not affected.
+//
+// A zero interval falls back to the default pacing. The function may be called while the dispatcher is running.
+func (d *DefaultServerDispatcher) SetOutboundPacing(clientID string, minInterval time.Duration) {
+	d.pacingMutex.Lock()
+	defer d.pacingMutex.Unlock()
+	if minInterval <= 0 {
+		delete(d.pacing, clientID)
+	} else {
+		d.pacing[clientID] = minInterval
+	}
+}
+
+// SetDefaultOutboundPacing sets the minimum interval between two consecutive requests dispatched to any client,
+// for which no specific pacing was set via SetOutboundPacing.
+//
+// By default, no pacing is enforced.
+func (d *DefaultServerDispatcher) SetDefaultOutboundPacing(minInterval time.Duration) {
+	d.pacingMutex.Lock()
+	defer d.pacingMutex.Unlock()
+	d.defaultPacing = minInterval
+}
+
+func (d *DefaultServerDispatcher) getPacing(clientID string) time.Duration {
+	d.pacingMutex.RLock()
+	defer d.pacingMutex.RUnlock()
+	if d.flushing[clientID] {
+		return 0
+	}
+	if minInterval, ok := d.pacing[clientID]; ok {
+		return minInterval
+	}
+	return d.defaultPacing
+}
+
+// FlushQueue dispatches all requests currently queued for a client as fast as possible, ignoring the outbound pacing.
+// Requests are still sent one at a time, i.e. the next request is dispatched as soon as the previous one was completed.
+// Pacing is resumed once the queue of the client is empty.
+//
+// Returns an error if the dispatcher isn't running, or no queue exists for the client.
+func (d *DefaultServerDispatcher) FlushQueue(clientID string) error {
+	if !d.IsRunning() {
+		return fmt.Errorf("cannot flush queue for %s, dispatcher is not running", clientID)
+	}
+	if _, ok := d.queueMap.Get(clientID); !ok {
+		return fmt.Errorf("cannot flush queue, no client %s exists", clientID)
+	}
+	d.pacingMutex.Lock()
+	d.flushing[clientID] = true
+	d.pacingMutex.Unlock()
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.running {
+		d.requestChannel <- clientID
+	}
+	return nil
+}
+
+// DropQueue discards all requests queued for a client, which weren't dispatched yet.
+// A request that was already sent to the client is kept, as a response may still be received for it.
+//
+// The OnRequestCanceled callback is not invoked for discarded requests. Instead, they are returned in queue order.
+// Queued binary messages are discarded as well, but aren't returned.
+// Returns an error if no queue exists for the client.
+func (d *DefaultServerDispatcher) DropQueue(clientID string) ([]RequestBundle, error) {
+	q, ok := d.queueMap.Get(clientID)
+	if !ok {
+		return nil, fmt.Errorf("cannot drop queue, no client %s exists", clientID)
+	}
+	d.queueMutex.Lock()
+	var inFlight interface{}
+	if d.pendingRequestState.HasPendingRequest(clientID) {
+		inFlight = q.Pop()
+	}
+	var dropped []RequestBundle
+	for !q.IsEmpty() {
+		if bundle, ok := q.Pop().(RequestBundle); ok && !bundle.Binary {
+			dropped = append(dropped, bundle)
+		}
+	}
+	if inFlight != nil {
+		_ = q.Push(inFlight)
+	}
+	d.queueMutex.Unlock()
+	d.stopFlushing(clientID)
+	for _, bundle := range dropped {
+		log.Infof("dropped request %v for %v", bundle.Call.UniqueId, clientID)
+	}
+	return dropped, nil
+}
+
+// PauseClient stops dispatching requests to a client, until ResumeClient is invoked.
+// New requests are still accepted and queued. A request that was already sent to the client is not affected.
+//
+// Returns an error if no queue exists for the client.
+func (d *DefaultServerDispatcher) PauseClient(clientID string) error {
+	if _, ok := d.queueMap.Get(clientID); !ok {
+		return fmt.Errorf("cannot pause client, no client %s exists", clientID)
+	}
+	d.pacingMutex.Lock()
+	defer d.pacingMutex.Unlock()
+	d.paused[clientID] = true
+	return nil
+}
+
+// ResumeClient resumes dispatching requests to a previously paused client, starting with the oldest queued request.
+//
+// Returns an error if the client isn't paused.
+func (d *DefaultServerDispatcher) ResumeClient(clientID string) error {
+	d.pacingMutex.Lock()
+	if !d.paused[clientID] {
+		d.pacingMutex.Unlock()
+		return fmt.Errorf("cannot resume client %s, client is not paused", clientID)
+	}
+	delete(d.paused, clientID)
+	d.pacingMutex.Unlock()
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.running {
+		d.requestChannel <- clientID
+	}
+	return nil
+}
+
+func (d *DefaultServerDispatcher) isPaused(clientID string) bool {
+	d.pacingMutex.RLock()
+	defer d.pacingMutex.RUnlock()
+	return d.paused[clientID]
+}
+
+func (d *DefaultServerDispatcher) stopFlushing(clientID string) {
+	d.pacingMutex.Lock()
+	defer d.pacingMutex.Unlock()
+	delete(d.flushing, clientID)
+}
+
+// Triggers a new dispatch attempt for the client, once the delay elapsed.
+func (d *DefaultServerDispatcher) retryDispatchAfter(clientID string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		d.mutex.RLock()
+		defer d.mutex.RUnlock()
+		if d.running {
+			d.requestChannel <- clientID
+		}
+	})
+}
+
 func (d *DefaultServerDispatcher) CreateClient(clientID string) {
 	if d.IsRunning() {
 		_ = d.queueMap.GetOrCreate(clientID)
@@ -435,6 +774,11 @@ func (d *DefaultServerDispatcher) CreateClient(clientID string) {
 
 func (d *DefaultServerDispatcher) DeleteClient(clientID string) {
 	d.queueMap.Remove(clientID)
+	d.stopFlushing(clientID)
+	d.pacingMutex.Lock()
+	delete(d.paused, clientID)
+	d.pacingMutex.Unlock()
+	d.clearDispatched(clientID)
 	if d.IsRunning() {
 		d.mutex.RLock()
 		d.requestChannel <- clientID
@@ -456,13 +800,16 @@ func (d *DefaultServerDispatcher) SetPendingRequestState(state ServerState) {
 
 func (d *DefaultServerDispatcher) SendRequest(clientID string, req RequestBundle) error {
 	if d.network == nil {
-		return fmt.Errorf("cannot send request %v, no network server was set", req.Call.UniqueId)
+		return fmt.Errorf("cannot send %v, no network server was set", req.description())
 	}
 	q, ok := d.queueMap.Get(clientID)
 	if !ok {
-		return fmt.Errorf("cannot send request %s, no client %s exists", req.Call.UniqueId, clientID)
+		return fmt.Errorf("cannot send %v, no client %s exists", req.description(), clientID)
 	}
-	if err := q.Push(req); err != nil {
+	d.queueMutex.Lock()
+	err := q.Push(req)
+	d.queueMutex.Unlock()
+	if err != nil {
 		return err
 	}
 	d.mutex.RLock()
@@ -480,6 +827,8 @@ func (d *DefaultServerDispatcher) messagePump() {
 	var clientCtx clientTimeoutContext
 	var clientQueue RequestQueue
 	clientContextMap := map[string]clientTimeoutContext{} // Empty at the beginning
+	lastDispatchMap := map[string]time.Time{}             // Used for pacing outgoing requests
+	pacedClients := map[string]bool{}                     // Clients with a delayed dispatch attempt
 
 	reqChan := func() chan string {
 		d.mutex.RLock()
@@ -503,11 +852,14 @@ func (d *DefaultServerDispatcher) messagePump() {
 				// Deleting and canceling the context
 				clientCtx = clientContextMap[clientID]
 				delete(clientContextMap, clientID)
+				delete(lastDispatchMap, clientID)
+				delete(pacedClients, clientID)
 				if clientCtx.ctx != nil {
 					clientCtx.cancel()
 				}
 				continue
 			}
+			delete(pacedClients, clientID)
 			// Check whether we can transmit to client
 			clientCtx, ok = clientContextMap[clientID]
 			if !ok {
@@ -537,9 +889,32 @@ func (d *DefaultServerDispatcher) messagePump() {
 				log.Infof("request %v for %v timed out", bundle.Call.UniqueId, clientID)
 				if d.onRequestCancel != nil {
 					d.onRequestCancel(clientID, bundle.Call.UniqueId, bundle.Call.Payload,
-						ocpp.NewError(GenericError, "Request timed out", bundle.Call.UniqueId))
+						ocpp.NewError(GenericError, RequestTimedOut, bundle.Call.UniqueId))
 				}
 			}
+		case expired := <-d.expiredC:
+			// Request exceeded the max pending age
+			clientID = expired.clientID
+			q, found := d.queueMap.Get(clientID)
+			if !found || !d.pendingRequestState.HasPendingRequest(clientID) {
+				continue
+			}
+			bundle, _ := q.Peek().(RequestBundle)
+			if bundle.Call == nil || bundle.Call.UniqueId != expired.requestID {
+				// Request was completed in the meantime
+				continue
+			}
+			clientCtx = clientContextMap[clientID]
+			if clientCtx.isActive() {
+				clientCtx.cancel()
+				clientContextMap[clientID] = clientTimeoutContext{}
+			}
+			d.CompleteRequest(clientID, bundle.Call.UniqueId)
+			log.Infof("request %v for %v exceeded max pending age", bundle.Call.UniqueId, clientID)
+			if d.onRequestCancel != nil {
+				d.onRequestCancel(clientID, bundle.Call.UniqueId, bundle.Call.Payload,
+					ocpp.NewError(GenericError, MaxPendingAgeExceeded, bundle.Call.UniqueId))
+			}
 		case clientID = <-d.readyForDispatch:
 			// Cancel previous timeout (if any)
 			clientCtx, ok = clientContextMap[clientID]
@@ -558,18 +933,39 @@ func (d *DefaultServerDispatcher) messagePump() {
 
 		// Only dispatch request if able to send and request queue isn't empty
 		if rdy && clientQueue != nil && !clientQueue.IsEmpty() {
+			if d.isPaused(clientID) {
+				// Dispatch is triggered again once the client is resumed
+				rdy = false
+				continue
+			}
+			// Delay the request, if the previous one was dispatched too recently
+			if minInterval := d.getPacing(clientID); minInterval > 0 {
+				if elapsed := time.Since(lastDispatchMap[clientID]); elapsed < minInterval {
+					if !pacedClients[clientID] {
+						pacedClients[clientID] = true
+						d.retryDispatchAfter(clientID, minInterval-elapsed)
+					}
+					rdy = false
+					continue
+				}
+			}
 			// Send request & set new context
 			clientCtx = d.dispatchNextRequest(clientID)
+			lastDispatchMap[clientID] = time.Now()
 			clientContextMap[clientID] = clientCtx
 			if clientCtx.isActive() {
 				go d.waitForTimeout(clientID, clientCtx)
 			}
 			// Update ready state
 			rdy = false
+		} else if clientQueue != nil && clientQueue.IsEmpty() {
+			d.stopFlushing(clientID)
 		}
 	}
 }
 
+// Sends all binary messages at the front of the client queue, followed by the next request.
+// The returned context is only active, if a request awaiting a response was dispatched.
 func (d *DefaultServerDispatcher) dispatchNextRequest(clientID string) (clientCtx clientTimeoutContext) {
 	// Get first element in queue
 	q, ok := d.queueMap.Get(clientID)
@@ -577,11 +973,32 @@ func (d *DefaultServerDispatcher) dispatchNextRequest(clientID string) (clientCt
 		log.Errorf("failed to dispatch next request for %s, no request queue available", clientID)
 		return
 	}
-	el := q.Peek()
-	bundle, _ := el.(RequestBundle)
+	d.queueMutex.Lock()
+	bundle, ok := q.Peek().(RequestBundle)
+	for ok && bundle.Binary {
+		// Binary messages don't await a response, hence they are removed from the queue right away
+		q.Pop()
+		d.queueMutex.Unlock()
+		if err := d.network.WriteBinary(clientID, bundle.Data); err != nil {
+			log.Errorf("error while sending binary message to %s: %v", clientID, err)
+		} else {
+			log.Debugf("sent binary message of %d bytes to %s", len(bundle.Data), clientID)
+		}
+		d.queueMutex.Lock()
+		bundle, ok = q.Peek().(RequestBundle)
+	}
+	if !ok {
+		// Queue was emptied in the meantime
+		d.queueMutex.Unlock()
+		return
+	}
 	jsonMessage := bundle.Data
 	callID := bundle.Call.GetUniqueId()
 	d.pendingRequestState.AddPendingRequest(clientID, callID, bundle.Call.Payload)
+	d.queueMutex.Unlock()
+	if d.maxPendingAge > 0 {
+		d.setDispatched(clientID, callID)
+	}
 	err := d.network.Write(clientID, jsonMessage)
 	if err != nil {
 		log.Errorf("error while sending message: %v", err)
@@ -594,8 +1011,8 @@ func (d *DefaultServerDispatcher) dispatchNextRequest(clientID string) (clientCt
 		return
 	}
 	// Create and return context (only if timeout is set)
-	if d.timeout > 0 {
-		ctx, cancel := context.WithTimeout(context.TODO(), d.timeout)
+	if timeout := d.getTimeout(clientID); timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
 		clientCtx = clientTimeoutContext{ctx: ctx, cancel: cancel}
 	}
 	log.Infof("dispatched request %s for %s", callID, clientID)
@@ -630,19 +1047,24 @@ func (d *DefaultServerDispatcher) CompleteRequest(clientID string, requestID str
 		log.Errorf("attempting to complete request for client %v, but no matching queue found", clientID)
 		return
 	}
+	d.queueMutex.Lock()
 	el := q.Peek()
 	if el == nil {
+		d.queueMutex.Unlock()
 		log.Errorf("attempting to pop front of queue, but queue is empty")
 		return
 	}
 	bundle, _ := el.(RequestBundle)
 	callID := bundle.Call.GetUniqueId()
 	if callID != requestID {
+		d.queueMutex.Unlock()
 		log.Errorf("internal state mismatch: processing response for %v but expected response for %v", requestID, callID)
 		return
 	}
 	q.Pop()
 	d.pendingRequestState.DeletePendingRequest(clientID, requestID)
+	d.queueMutex.Unlock()
+	d.clearDispatched(clientID)
 	log.Debugf("completed request %s for %s", callID, clientID)
 	// Signal that next message in queue may be sent
 	d.readyForDispatch <- clientID
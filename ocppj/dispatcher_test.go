@@ -82,6 +82,173 @@ func (s *ServerDispatcherTestSuite) TestServerSendRequest() {
 	time.Sleep(1300 * time.Millisecond)
 }
 
+func (s *ServerDispatcherTestSuite) TestServerOutboundPacing() {
+	t := s.T()
+	clientID := "client1"
+	otherClientID := "client2"
+	minInterval := 200 * time.Millisecond
+	requestsToSend := 3
+	type dispatchedRequest struct {
+		clientID string
+		time     time.Time
+	}
+	writeC := make(chan dispatchedRequest, requestsToSend*2)
+	s.websocketServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		id := args.String(0)
+		call := ParseCall(&s.endpoint.Endpoint, s.state.GetClientState(id), string(args.Get(1).([]byte)), t)
+		require.NotNil(t, call)
+		writeC <- dispatchedRequest{clientID: id, time: time.Now()}
+		// Respond immediately, so only the pacing delays the next request
+		go s.dispatcher.CompleteRequest(id, call.UniqueId)
+	}).Return(nil)
+	d, ok := s.dispatcher.(*ocppj.DefaultServerDispatcher)
+	require.True(t, ok)
+	d.SetOutboundPacing(clientID, minInterval)
+	s.dispatcher.Start()
+	s.dispatcher.CreateClient(clientID)
+	s.dispatcher.CreateClient(otherClientID)
+	// Queue a burst of requests for both clients
+	for i := 0; i < requestsToSend; i++ {
+		for _, id := range []string{clientID, otherClientID} {
+			call, err := s.endpoint.CreateCall(newMockRequest(fmt.Sprintf("value%v", i)))
+			require.NoError(t, err)
+			data, err := call.MarshalJSON()
+			require.NoError(t, err)
+			err = s.dispatcher.SendRequest(id, ocppj.RequestBundle{Call: call, Data: data})
+			require.NoError(t, err)
+		}
+	}
+	dispatched := map[string][]time.Time{}
+	for i := 0; i < requestsToSend*2; i++ {
+		select {
+		case r := <-writeC:
+			dispatched[r.clientID] = append(dispatched[r.clientID], r.time)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for dispatched requests")
+		}
+	}
+	// Paced client received requests no closer than the interval
+	require.Len(t, dispatched[clientID], requestsToSend)
+	for i := 1; i < requestsToSend; i++ {
+		assert.GreaterOrEqual(t, int64(dispatched[clientID][i].Sub(dispatched[clientID][i-1])), int64(minInterval))
+	}
+	// Other client isn't affected by the pacing
+	require.Len(t, dispatched[otherClientID], requestsToSend)
+	assert.Less(t, int64(dispatched[otherClientID][requestsToSend-1].Sub(dispatched[otherClientID][0])), int64(minInterval))
+	s.dispatcher.Stop()
+}
+
+func (s *ServerDispatcherTestSuite) sendMockRequests(clientID string, count int) []string {
+	t := s.T()
+	var requestIDs []string
+	for i := 0; i < count; i++ {
+		call, err := s.endpoint.CreateCall(newMockRequest(fmt.Sprintf("value%v", i)))
+		require.NoError(t, err)
+		data, err := call.MarshalJSON()
+		require.NoError(t, err)
+		err = s.dispatcher.SendRequest(clientID, ocppj.RequestBundle{Call: call, Data: data})
+		require.NoError(t, err)
+		requestIDs = append(requestIDs, call.UniqueId)
+	}
+	return requestIDs
+}
+
+func (s *ServerDispatcherTestSuite) TestServerFlushQueue() {
+	t := s.T()
+	clientID := "client1"
+	minInterval := 500 * time.Millisecond
+	requestsToSend := 3
+	writeC := make(chan time.Time, requestsToSend*2)
+	s.websocketServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		id := args.String(0)
+		call := ParseCall(&s.endpoint.Endpoint, s.state.GetClientState(id), string(args.Get(1).([]byte)), t)
+		require.NotNil(t, call)
+		writeC <- time.Now()
+		go s.dispatcher.CompleteRequest(id, call.UniqueId)
+	}).Return(nil)
+	d, ok := s.dispatcher.(*ocppj.DefaultServerDispatcher)
+	require.True(t, ok)
+	d.SetOutboundPacing(clientID, minInterval)
+	err := d.FlushQueue(clientID)
+	require.Error(t, err)
+	s.dispatcher.Start()
+	defer s.dispatcher.Stop()
+	s.dispatcher.CreateClient(clientID)
+	// Queue a burst of requests. Only the first one is sent right away, the others are paced
+	s.sendMockRequests(clientID, requestsToSend)
+	first := <-writeC
+	// Flush the remaining requests
+	err = d.FlushQueue(clientID)
+	require.NoError(t, err)
+	for i := 1; i < requestsToSend; i++ {
+		select {
+		case sent := <-writeC:
+			assert.Less(t, int64(sent.Sub(first)), int64(minInterval))
+		case <-time.After(minInterval / 2):
+			t.Fatal("timeout waiting for flushed requests")
+		}
+	}
+	// Pacing is resumed once the queue was emptied
+	time.Sleep(50 * time.Millisecond)
+	s.sendMockRequests(clientID, 2)
+	first = <-writeC
+	second := <-writeC
+	assert.GreaterOrEqual(t, int64(second.Sub(first)), int64(minInterval))
+	// Flushing unknown clients fails
+	err = d.FlushQueue("unknownClient")
+	assert.Error(t, err)
+}
+
+func (s *ServerDispatcherTestSuite) TestServerDropQueue() {
+	t := s.T()
+	clientID := "client1"
+	requestsToSend := 3
+	writeC := make(chan string, requestsToSend)
+	s.websocketServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Run(func(args mock.Arguments) {
+		id := args.String(0)
+		call := ParseCall(&s.endpoint.Endpoint, s.state.GetClientState(id), string(args.Get(1).([]byte)), t)
+		require.NotNil(t, call)
+		// Never respond, so following requests remain queued
+		writeC <- call.UniqueId
+	}).Return(nil)
+	s.dispatcher.SetOnRequestCanceled(func(cID string, rID string, request ocpp.Request, err *ocpp.Error) {
+		require.Fail(t, "unexpected OnRequestCanceled")
+	})
+	d, ok := s.dispatcher.(*ocppj.DefaultServerDispatcher)
+	require.True(t, ok)
+	s.dispatcher.Start()
+	defer s.dispatcher.Stop()
+	s.dispatcher.CreateClient(clientID)
+	requestIDs := s.sendMockRequests(clientID, requestsToSend)
+	inFlightID := <-writeC
+	assert.Equal(t, requestIDs[0], inFlightID)
+	// Drop all queued requests
+	dropped, err := d.DropQueue(clientID)
+	require.NoError(t, err)
+	var droppedIDs []string
+	for _, bundle := range dropped {
+		droppedIDs = append(droppedIDs, bundle.Call.UniqueId)
+	}
+	assert.Equal(t, requestIDs[1:], droppedIDs)
+	// The in-flight request may still be completed
+	q, ok := s.queueMap.Get(clientID)
+	require.True(t, ok)
+	assert.Equal(t, 1, q.Size())
+	assert.True(t, s.state.HasPendingRequest(clientID))
+	s.dispatcher.CompleteRequest(clientID, inFlightID)
+	assert.True(t, q.IsEmpty())
+	assert.False(t, s.state.HasPendingRequest(clientID))
+	// No dropped request is sent afterwards
+	select {
+	case id := <-writeC:
+		t.Fatalf("unexpected request %v sent after drop", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Dropping unknown clients fails
+	_, err = d.DropQueue("unknownClient")
+	assert.Error(t, err)
+}
+
 func (s *ServerDispatcherTestSuite) TestServerRequestCanceled() {
 	t := s.T()
 	// Setup
@@ -231,6 +398,105 @@ func (s *ServerDispatcherTestSuite) TestServerDispatcherTimeout() {
 	assert.True(t, clientQ.IsEmpty())
 }
 
+func (s *ServerDispatcherTestSuite) TestServerDispatcherClientTimeout() {
+	t := s.T()
+	// Setup
+	slowClientID := "slowClient"
+	fastClientID := "fastClient"
+	s.websocketServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	canceled := make(chan string, 2)
+	s.dispatcher.SetOnRequestCanceled(func(cID string, rID string, request ocpp.Request, err *ocpp.Error) {
+		assert.Equal(t, "Request timed out", err.Description)
+		canceled <- cID
+	})
+	// Slow client declares a longer timeout than the default one
+	timeoutController, ok := s.dispatcher.(ocppj.ClientTimeoutController)
+	require.True(t, ok)
+	s.dispatcher.SetTimeout(100 * time.Millisecond)
+	timeoutController.SetClientTimeout(slowClientID, 400*time.Millisecond)
+	s.dispatcher.Start()
+	s.dispatcher.CreateClient(slowClientID)
+	s.dispatcher.CreateClient(fastClientID)
+	startTime := time.Now()
+	for _, clientID := range []string{slowClientID, fastClientID} {
+		call, err := s.endpoint.CreateCall(newMockRequest("somevalue"))
+		require.NoError(t, err)
+		data, err := call.MarshalJSON()
+		require.NoError(t, err)
+		err = s.dispatcher.SendRequest(clientID, ocppj.RequestBundle{Call: call, Data: data})
+		require.NoError(t, err)
+	}
+	// Default timeout applies to the fast client
+	assert.Equal(t, fastClientID, <-canceled)
+	assert.Less(t, time.Since(startTime), 400*time.Millisecond)
+	// Client timeout applies to the slow client
+	assert.Equal(t, slowClientID, <-canceled)
+	assert.GreaterOrEqual(t, time.Since(startTime), 400*time.Millisecond)
+	// Resetting the client timeout falls back to the default timeout
+	timeoutController.SetClientTimeout(slowClientID, 0)
+	call, err := s.endpoint.CreateCall(newMockRequest("somevalue"))
+	require.NoError(t, err)
+	data, err := call.MarshalJSON()
+	require.NoError(t, err)
+	startTime = time.Now()
+	err = s.dispatcher.SendRequest(slowClientID, ocppj.RequestBundle{Call: call, Data: data})
+	require.NoError(t, err)
+	assert.Equal(t, slowClientID, <-canceled)
+	assert.Less(t, time.Since(startTime), 400*time.Millisecond)
+}
+
+func (s *ServerDispatcherTestSuite) TestServerDispatcherMaxPendingAge() {
+	t := s.T()
+	// Setup
+	clientID := "client1"
+	canceled := make(chan *ocpp.Error, 1)
+	s.websocketServer.On("Write", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	req := newMockRequest("somevalue")
+	call, err := s.endpoint.CreateCall(req)
+	require.NoError(t, err)
+	requestID := call.UniqueId
+	data, err := call.MarshalJSON()
+	require.NoError(t, err)
+	bundle := ocppj.RequestBundle{Call: call, Data: data}
+	s.dispatcher.SetOnRequestCanceled(func(cID string, rID string, request ocpp.Request, err *ocpp.Error) {
+		assert.Equal(t, clientID, cID)
+		assert.Equal(t, requestID, rID)
+		assert.Equal(t, req, request)
+		canceled <- err
+	})
+	// Timeout is longer than the max pending age, which therefore wins
+	maxAge := 300 * time.Millisecond
+	s.dispatcher.SetTimeout(10 * time.Second)
+	d, ok := s.dispatcher.(*ocppj.DefaultServerDispatcher)
+	require.True(t, ok)
+	d.SetMaxPendingAge(maxAge)
+	s.dispatcher.Start()
+	require.True(t, s.dispatcher.IsRunning())
+	s.dispatcher.CreateClient(clientID)
+	startTime := time.Now()
+	err = s.dispatcher.SendRequest(clientID, bundle)
+	require.NoError(t, err)
+	select {
+	case ocppErr := <-canceled:
+		assert.Equal(t, ocppj.GenericError, ocppErr.Code)
+		assert.Equal(t, ocppj.MaxPendingAgeExceeded, ocppErr.Description)
+	case <-time.After(2 * time.Second):
+		t.Fatal("request wasn't canceled after exceeding max pending age")
+	}
+	elapsed := time.Since(startTime)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(maxAge))
+	assert.Less(t, int64(elapsed), int64(2*maxAge))
+	assert.False(t, s.state.HasPendingRequest(clientID))
+	clientQ, _ := s.queueMap.Get(clientID)
+	assert.True(t, clientQ.IsEmpty())
+	// The request is only canceled once
+	select {
+	case <-canceled:
+		t.Fatal("unexpected second cancellation")
+	case <-time.After(maxAge):
+	}
+}
+
 type ClientDispatcherTestSuite struct {
 	suite.Suite
 	state           ocppj.ClientState
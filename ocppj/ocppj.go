@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"strings"
 
 	"github.com/lorenzodonini/ocpp-go/logging"
 
@@ -24,6 +25,18 @@ var validationEnabled bool
 // The internal verbose logger
 var log logging.Logger
 
+// The internal unique ID matching setting. Strict by default.
+var lenientUniqueIdMatching bool
+
+// The internal strict JSON parsing setting. Disabled by default.
+var strictJSONParsing bool
+
+// The internal type coercion setting for incoming payloads. Strict by default.
+var lenientTypeCoercion bool
+
+// The internal policy for incoming CALLs with an unknown action.
+var unknownActionPolicy UnknownActionPolicy
+
 var EscapeHTML = true
 
 func init() {
@@ -61,6 +74,162 @@ func SetMessageValidation(enabled bool) {
 	validationEnabled = enabled
 }
 
+// Allows to enable/disable lenient matching of unique IDs for incoming CALLRESULT and CALLERROR messages.
+// The feature may be useful when working with OCPP implementations that don't echo the unique ID of a CALL verbatim.
+//
+// When enabled, a response whose unique ID doesn't match any pending request exactly is matched again,
+// after trimming surrounding whitespace. Every time the normalization was needed, a message is logged.
+// The response is then processed using the unique ID of the original request.
+//
+// Lenient matching is disabled by default, i.e. unique IDs must match exactly.
+func SetLenientUniqueIdMatching(enabled bool) {
+	lenientUniqueIdMatching = enabled
+}
+
+// Allows to enable/disable strict parsing of incoming JSON messages.
+// The feature may be useful to detect OCPP implementations sending malformed messages.
+//
+// When enabled, every incoming message is checked for objects containing the same key more than once.
+// Such messages are rejected like messages failing validation, i.e. the invalid message hook is invoked
+// and a format violation CALLERROR (see FormatErrorType) is returned to the sender. The duplicate keys are logged.
+//
+// Strict parsing is disabled by default: in case of duplicate keys, the last value is used silently.
+func SetStrictJSONParsing(enabled bool) {
+	strictJSONParsing = enabled
+}
+
+// Allows to enable/disable lenient type coercion for incoming payloads.
+// The feature may be useful when working with OCPP implementations that encode booleans or numbers as strings,
+// e.g. "true" instead of true, or "16" instead of 16.
+//
+// When enabled, string values are converted before parsing a payload, if the target field is a boolean or a number
+// and the string contains a valid value of that type. Every conversion is logged. Strings that cannot be converted
+// are left untouched, hence the message is still rejected.
+//
+// Type coercion is disabled by default, i.e. messages containing such values are rejected with a format violation.
+func SetLenientTypeCoercion(enabled bool) {
+	lenientTypeCoercion = enabled
+}
+
+// Looks up the pending request for the unique ID of an incoming response.
+// Returns the request along with the matching unique ID, which may differ from the received one in lenient mode.
+func getPendingRequest(pendingRequestState ClientState, uniqueId string) (ocpp.Request, string, bool) {
+	if request, ok := pendingRequestState.GetPendingRequest(uniqueId); ok {
+		return request, uniqueId, true
+	}
+	if !lenientUniqueIdMatching {
+		return nil, uniqueId, false
+	}
+	normalizedId := strings.TrimSpace(uniqueId)
+	if normalizedId == uniqueId {
+		return nil, uniqueId, false
+	}
+	request, ok := pendingRequestState.GetPendingRequest(normalizedId)
+	if ok {
+		log.Infof("Matched response unique ID %q to pending request %v after normalization", uniqueId, normalizedId)
+		return request, normalizedId, true
+	}
+	return nil, uniqueId, false
+}
+
+// UnknownActionPolicy defines how an endpoint treats an incoming CALL, whose action is not supported by any of its profiles.
+type UnknownActionPolicy int
+
+const (
+	RespondNotSupported   UnknownActionPolicy = iota // Respond with a NotSupported CALLERROR (default).
+	RespondNotImplemented                            // Respond with a NotImplemented CALLERROR.
+	Drop                                             // Discard the message without responding. Intended for integration testing only.
+)
+
+// Sets the policy for incoming CALLs with an unknown action.
+// The OCPP-J specification allows both NotImplemented and NotSupported error codes for such messages.
+//
+// By default, a NotSupported CALLERROR is returned.
+//
+// ⚠️ When dropping messages, the other endpoint never receives a response and will wait until its request times out.
+func SetUnknownActionPolicy(policy UnknownActionPolicy) {
+	unknownActionPolicy = policy
+}
+
+// ValidationError describes a constraint violation of a single field within an OCPP message.
+//
+// The value of the violating field is intentionally not included, to avoid echoing sensitive data back to the sender.
+type ValidationError struct {
+	Feature   string // The feature of the message, may be empty
+	Namespace string // The struct namespace of the field, e.g. "Call.Payload.IdToken.IdToken"
+	Path      string // The JSON path of the field within the payload, e.g. "idToken.idToken"
+	Tag       string // The violated constraint, e.g. "max"
+	Param     string // The parameter of the violated constraint, e.g. "36"
+}
+
+// ValidationErrorFormatter creates the description of the CALLERROR, which is returned for a message that failed validation.
+type ValidationErrorFormatter func(err ValidationError) string
+
+// The custom formatter for validation errors. If nil, the default descriptions are used.
+var validationErrorFormatter ValidationErrorFormatter
+
+// Sets a custom formatter for the description of CALLERRORs, which are returned for messages that failed validation.
+// The error code is still chosen based on the violated constraint.
+//
+// By default (or when passing nil), a description is generated for common constraints,
+// while the raw validator error is used for all other constraints.
+func SetValidationErrorFormatter(formatter ValidationErrorFormatter) {
+	validationErrorFormatter = formatter
+}
+
+// FieldConstraintFormatter is a ValidationErrorFormatter describing which field failed which constraint,
+// e.g. "field 'idToken.idToken' failed 'max' constraint for feature Authorize".
+func FieldConstraintFormatter(err ValidationError) string {
+	description := fmt.Sprintf("field '%s' failed '%s' constraint", err.Path, err.Tag)
+	if err.Feature != "" {
+		description = fmt.Sprintf("%s for feature %s", description, err.Feature)
+	}
+	return description
+}
+
+// InvalidResponseError is reported in place of CALLERROR details to the ErrorHandler of a Server,
+// when a CALLRESULT for a pending request couldn't be parsed or didn't pass validation,
+// e.g. because its payload doesn't match the response type expected for the original request.
+//
+// The pending request is completed, so the error can be forwarded to the sender of the request.
+type InvalidResponseError struct {
+	Err     *ocpp.Error // The parsing or validation error.
+	Action  string      // The feature name of the original request.
+	payload []byte
+}
+
+func (e *InvalidResponseError) Error() string {
+	return fmt.Sprintf("invalid %v response: %v", e.Action, e.Err.Description)
+}
+
+func (e *InvalidResponseError) Unwrap() error {
+	return e.Err
+}
+
+// RawPayload returns the payload of the CALLRESULT exactly as it was received, for debugging purposes.
+func (e *InvalidResponseError) RawPayload() []byte {
+	return e.payload
+}
+
+// Returns an InvalidResponseError, if the rejected message is a CALLRESULT for a pending request. Returns nil otherwise.
+func newInvalidResponseError(arr []interface{}, dataJson []byte, pendingRequestState ClientState, err *ocpp.Error) *InvalidResponseError {
+	if len(arr) < 3 || err.MessageId == "" {
+		return nil
+	}
+	if typeId, ok := arr[0].(float64); !ok || MessageType(typeId) != CALL_RESULT {
+		return nil
+	}
+	request, ok := pendingRequestState.GetPendingRequest(err.MessageId)
+	if !ok {
+		return nil
+	}
+	var elements []json.RawMessage
+	if json.Unmarshal(dataJson, &elements) != nil || len(elements) < 3 {
+		return nil
+	}
+	return &InvalidResponseError{Err: err, Action: request.GetFeatureName(), payload: append([]byte(nil), elements[2]...)}
+}
+
 // MessageType identifies the type of message exchanged between two OCPP endpoints.
 type MessageType int
 
@@ -196,6 +365,9 @@ const (
 	GenericError                  ocpp.ErrorCode = "GenericError"                  // Any other error not covered by the previous ones.
 	FormatViolationV2             ocpp.ErrorCode = "FormatViolation"               // Payload for Action is syntactically incorrect. This is only valid for OCPP 2.0.1
 	FormatViolationV16            ocpp.ErrorCode = "FormationViolation"            // Payload for Action is syntactically incorrect or not conform the PDU structure for Action. This is only valid for OCPP 1.6
+	RpcFrameworkError             ocpp.ErrorCode = "RpcFrameworkError"             // Content of the call is not a valid RPC Request, e.g. the MessageId could not be read. This is only valid for OCPP 2.0.1
+	// Same as OccurrenceConstraintViolation, spelled as in the OCPP-J 1.6 specification. This is only valid for OCPP 1.6
+	OccurrenceConstraintViolationV16 ocpp.ErrorCode = "OccurenceConstraintViolation"
 )
 
 type dialector interface {
@@ -216,12 +388,54 @@ func FormatErrorType(d dialector) ocpp.ErrorCode {
 func IsErrorCodeValid(fl validator.FieldLevel) bool {
 	code := ocpp.ErrorCode(fl.Field().String())
 	switch code {
-	case NotImplemented, NotSupported, InternalError, MessageTypeNotSupported, ProtocolError, SecurityError, FormatViolationV16, FormatViolationV2, PropertyConstraintViolation, OccurrenceConstraintViolation, TypeConstraintViolation, GenericError:
+	case NotImplemented, NotSupported, InternalError, MessageTypeNotSupported, ProtocolError, SecurityError, FormatViolationV16, FormatViolationV2, PropertyConstraintViolation, OccurrenceConstraintViolation, OccurrenceConstraintViolationV16, TypeConstraintViolation, GenericError, RpcFrameworkError:
 		return true
 	}
 	return false
 }
 
+// IsErrorCodeValidForDialect returns true, if the error code is defined by the OCPP-J specification of the given OCPP version.
+// For an unknown dialect, all error codes of any version are considered valid.
+func IsErrorCodeValidForDialect(code ocpp.ErrorCode, dialect ocpp.Dialect) bool {
+	switch code {
+	case NotImplemented, NotSupported, InternalError, ProtocolError, SecurityError, PropertyConstraintViolation, TypeConstraintViolation, GenericError:
+		return true
+	case FormatViolationV16, OccurrenceConstraintViolationV16:
+		return dialect != ocpp.V2
+	case FormatViolationV2, OccurrenceConstraintViolation, MessageTypeNotSupported, RpcFrameworkError:
+		return dialect != ocpp.V16
+	}
+	return false
+}
+
+// ErrorCodeForDialect converts an error code to its equivalent in the OCPP-J specification of the given OCPP version:
+//   - FormatViolationV2 and FormatViolationV16 are converted to the format violation code of the version
+//   - OccurrenceConstraintViolation and OccurrenceConstraintViolationV16 are converted to the spelling of the version
+//   - error codes that only exist in OCPP 2.0.1 are converted to GenericError for OCPP 1.6
+//
+// Other error codes, as well as all error codes for an unknown dialect, are returned as is.
+func ErrorCodeForDialect(code ocpp.ErrorCode, dialect ocpp.Dialect) ocpp.ErrorCode {
+	switch dialect {
+	case ocpp.V16:
+		switch code {
+		case FormatViolationV2:
+			return FormatViolationV16
+		case OccurrenceConstraintViolation:
+			return OccurrenceConstraintViolationV16
+		case MessageTypeNotSupported, RpcFrameworkError:
+			return GenericError
+		}
+	case ocpp.V2:
+		switch code {
+		case FormatViolationV16:
+			return FormatViolationV2
+		case OccurrenceConstraintViolationV16:
+			return OccurrenceConstraintViolation
+		}
+	}
+	return code
+}
+
 // -------------------- Logic --------------------
 
 // Unmarshals an OCPP-J json object from a byte array.
@@ -235,6 +449,76 @@ func ParseRawJsonMessage(dataJson []byte) ([]interface{}, error) {
 	return arr, nil
 }
 
+// Checks a raw message for duplicate object keys, if strict JSON parsing is enabled.
+// The array of elements is used for retrieving the unique ID of the message.
+func (endpoint *Endpoint) checkDuplicateKeys(dataJson []byte, arr []interface{}) error {
+	if !strictJSONParsing {
+		return nil
+	}
+	duplicates, err := findDuplicateKeys(dataJson)
+	if err != nil || len(duplicates) == 0 {
+		return err
+	}
+	var uniqueId string
+	if len(arr) > 1 {
+		uniqueId, _ = arr[1].(string)
+	}
+	return ocpp.NewError(FormatErrorType(endpoint), fmt.Sprintf("Duplicate JSON keys: %v", strings.Join(duplicates, ", ")), uniqueId)
+}
+
+// Returns the path of every object key, which occurs more than once within the same object, e.g. "[3].idToken.type".
+func findDuplicateKeys(dataJson []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(dataJson))
+	decoder.UseNumber()
+	var duplicates []string
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := token.(json.Delim)
+		if !ok {
+			return nil
+		}
+		switch delim {
+		case '{':
+			keys := map[string]bool{}
+			for decoder.More() {
+				token, err = decoder.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := token.(string)
+				keyPath := key
+				if path != "" {
+					keyPath = path + "." + key
+				}
+				if keys[key] {
+					duplicates = append(duplicates, keyPath)
+				}
+				keys[key] = true
+				if err = walk(keyPath); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for i := 0; decoder.More(); i++ {
+				if err = walk(fmt.Sprintf("%v[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		// Consume closing delimiter
+		_, err = decoder.Token()
+		return err
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
 // Unmarshals an OCPP-J json object from a JSON string.
 // Returns the array of elements contained in the message.
 func ParseJsonMessage(dataJson string) ([]interface{}, error) {
@@ -283,7 +567,25 @@ func propertyConstraintViolation(fieldError validator.FieldError, details, messa
 	)
 }
 
-func errorFromValidation(validationErrors validator.ValidationErrors, messageId string, feature string) *ocpp.Error {
+func (endpoint *Endpoint) errorFromValidation(validationErrors validator.ValidationErrors, messageId string, feature string) *ocpp.Error {
+	if validationErrorFormatter != nil && len(validationErrors) > 0 {
+		el := validationErrors[0]
+		code := GenericError
+		switch el.ActualTag() {
+		case "required":
+			code = OccurrenceConstraintViolation
+		case "max", "min", "gte", "gt", "lte", "lt":
+			code = PropertyConstraintViolation
+		}
+		description := validationErrorFormatter(ValidationError{
+			Feature:   feature,
+			Namespace: el.Namespace(),
+			Path:      endpoint.fieldPath(el, feature),
+			Tag:       el.ActualTag(),
+			Param:     el.Param(),
+		})
+		return ocpp.NewError(code, description, messageId)
+	}
 	for _, el := range validationErrors {
 		switch el.ActualTag() {
 		case "required":
@@ -305,6 +607,66 @@ func errorFromValidation(validationErrors validator.ValidationErrors, messageId
 	return ocpp.NewError(GenericError, fmt.Sprintf("%v", validationErrors.Error()), messageId)
 }
 
+// Returns the JSON path of a field within the message payload (e.g. "idToken.idToken"),
+// by resolving the struct namespace of the validation error against the payload type of the feature.
+// If the path cannot be resolved, the struct namespace is returned instead.
+func (endpoint *Endpoint) fieldPath(fieldError validator.FieldError, feature string) string {
+	namespace := fieldError.StructNamespace()
+	segments := strings.Split(namespace, ".")
+	if len(segments) < 3 || segments[1] != "Payload" {
+		return namespace
+	}
+	profile, ok := endpoint.GetProfileForFeature(feature)
+	if !ok {
+		return namespace
+	}
+	var t reflect.Type
+	switch segments[0] {
+	case "Call":
+		t = profile.GetFeature(feature).GetRequestType()
+	case "CallResult":
+		t = profile.GetFeature(feature).GetResponseType()
+	default:
+		return namespace
+	}
+	path := make([]string, 0, len(segments)-2)
+	for _, segment := range segments[2:] {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return namespace
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return namespace
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		path = append(path, jsonName+index)
+		t = field.Type
+		// Every index accesses the elements of a slice, array or map
+		for i := strings.Count(index, "["); i > 0; i-- {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			default:
+				return namespace
+			}
+		}
+	}
+	return strings.Join(path, ".")
+}
+
 // Marshals data by manipulating EscapeHTML property of encoder
 func jsonMarshal(t interface{}) ([]byte, error) {
 	buffer := &bytes.Buffer{}
@@ -319,8 +681,27 @@ func jsonMarshal(t interface{}) ([]byte, error) {
 // An OCPP-J endpoint is one of the two entities taking part in the communication.
 // The endpoint keeps state for supported OCPP profiles and current pending requests.
 type Endpoint struct {
-	dialect  ocpp.Dialect
-	Profiles []*ocpp.Profile
+	dialect     ocpp.Dialect
+	Profiles    []*ocpp.Profile
+	normalizers map[string]RequestNormalizer
+}
+
+// RequestNormalizer may adjust an incoming request after it was parsed, but before it is validated,
+// e.g. to tolerate minor spec violations of a known implementation.
+// It returns a description of every adjustment, each of which is logged by the endpoint.
+type RequestNormalizer func(request ocpp.Request) []string
+
+// Registers a normalizer for incoming requests of the given feature, replacing any previous one.
+// Passing nil removes the normalizer. Normalizers must be set before the endpoint starts processing messages.
+func (endpoint *Endpoint) SetRequestNormalizer(featureName string, normalizer RequestNormalizer) {
+	if normalizer == nil {
+		delete(endpoint.normalizers, featureName)
+		return
+	}
+	if endpoint.normalizers == nil {
+		endpoint.normalizers = map[string]RequestNormalizer{}
+	}
+	endpoint.normalizers[featureName] = normalizer
 }
 
 // Sets endpoint dialect.
@@ -364,6 +745,9 @@ func parseRawJsonRequest(raw interface{}, requestType reflect.Type) (ocpp.Reques
 	if raw == nil {
 		raw = &struct{}{}
 	}
+	if lenientTypeCoercion {
+		raw = coerceTypes(raw, requestType, requestType.Name())
+	}
 	bytes, err := json.Marshal(raw)
 	if err != nil {
 		return nil, err
@@ -381,6 +765,9 @@ func parseRawJsonConfirmation(raw interface{}, confirmationType reflect.Type) (o
 	if raw == nil {
 		raw = &struct{}{}
 	}
+	if lenientTypeCoercion {
+		raw = coerceTypes(raw, confirmationType, confirmationType.Name())
+	}
 	bytes, err := json.Marshal(raw)
 	if err != nil {
 		return nil, err
@@ -426,12 +813,25 @@ func (endpoint *Endpoint) ParseMessage(arr []interface{}, pendingRequestState Cl
 
 		profile, ok := endpoint.GetProfileForFeature(action)
 		if !ok {
-			return nil, ocpp.NewError(NotSupported, fmt.Sprintf("Unsupported feature %v", action), uniqueId)
+			switch unknownActionPolicy {
+			case Drop:
+				log.Infof("Unsupported feature %v for message %v. Discarding message", action, uniqueId)
+				return nil, nil
+			case RespondNotImplemented:
+				return nil, ocpp.NewError(NotImplemented, fmt.Sprintf("Unsupported feature %v", action), uniqueId)
+			default:
+				return nil, ocpp.NewError(NotSupported, fmt.Sprintf("Unsupported feature %v", action), uniqueId)
+			}
 		}
 		request, err := profile.ParseRequest(action, arr[3], parseRawJsonRequest)
 		if err != nil {
 			return nil, ocpp.NewError(FormatErrorType(endpoint), err.Error(), uniqueId)
 		}
+		if normalizer, ok := endpoint.normalizers[action]; ok {
+			for _, adjustment := range normalizer(request) {
+				log.Infof("Tolerated nonconformant %v request %v: %v", action, uniqueId, adjustment)
+			}
+		}
 		call := Call{
 			MessageTypeId: CALL,
 			UniqueId:      uniqueId,
@@ -440,11 +840,11 @@ func (endpoint *Endpoint) ParseMessage(arr []interface{}, pendingRequestState Cl
 		}
 		err = Validate.Struct(call)
 		if err != nil {
-			return nil, errorFromValidation(err.(validator.ValidationErrors), uniqueId, action)
+			return nil, endpoint.errorFromValidation(err.(validator.ValidationErrors), uniqueId, action)
 		}
 		return &call, nil
 	} else if typeId == CALL_RESULT {
-		request, ok := pendingRequestState.GetPendingRequest(uniqueId)
+		request, uniqueId, ok := getPendingRequest(pendingRequestState, uniqueId)
 		if !ok {
 			log.Infof("No previous request %v sent. Discarding response message", uniqueId)
 			return nil, nil
@@ -461,11 +861,11 @@ func (endpoint *Endpoint) ParseMessage(arr []interface{}, pendingRequestState Cl
 		}
 		err = Validate.Struct(callResult)
 		if err != nil {
-			return nil, errorFromValidation(err.(validator.ValidationErrors), uniqueId, request.GetFeatureName())
+			return nil, endpoint.errorFromValidation(err.(validator.ValidationErrors), uniqueId, request.GetFeatureName())
 		}
 		return &callResult, nil
 	} else if typeId == CALL_ERROR {
-		_, ok := pendingRequestState.GetPendingRequest(uniqueId)
+		_, uniqueId, ok := getPendingRequest(pendingRequestState, uniqueId)
 		if !ok {
 			log.Infof("No previous request %v sent. Discarding error message", uniqueId)
 			return nil, nil
@@ -495,7 +895,7 @@ func (endpoint *Endpoint) ParseMessage(arr []interface{}, pendingRequestState Cl
 		}
 		err := Validate.Struct(callError)
 		if err != nil {
-			return nil, errorFromValidation(err.(validator.ValidationErrors), uniqueId, "")
+			return nil, endpoint.errorFromValidation(err.(validator.ValidationErrors), uniqueId, "")
 		}
 		return &callError, nil
 	} else {
@@ -554,7 +954,11 @@ func (endpoint *Endpoint) CreateCallResult(confirmation ocpp.Response, uniqueId
 }
 
 // Creates a CallError message, given the message's unique ID and the error.
+//
+// The error code is converted to its equivalent for the dialect of the endpoint, see ErrorCodeForDialect.
+// If validation is enabled, an error is returned for codes not defined by the OCPP-J specification of that dialect.
 func (endpoint *Endpoint) CreateCallError(uniqueId string, code ocpp.ErrorCode, description string, details interface{}) (*CallError, error) {
+	code = ErrorCodeForDialect(code, endpoint.dialect)
 	callError := CallError{
 		MessageTypeId:    CALL_ERROR,
 		UniqueId:         uniqueId,
@@ -567,6 +971,9 @@ func (endpoint *Endpoint) CreateCallError(uniqueId string, code ocpp.ErrorCode,
 		if err != nil {
 			return nil, err
 		}
+		if !IsErrorCodeValidForDialect(code, endpoint.dialect) {
+			return nil, fmt.Errorf("error code %v is not defined for the OCPP version of the endpoint", code)
+		}
 	}
 	return &callError, nil
 }
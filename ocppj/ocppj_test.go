@@ -1,10 +1,12 @@
 package ocppj_test
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 
@@ -42,6 +44,24 @@ func (websocket MockWebSocket) TLSConnectionState() *tls.ConnectionState {
 	return nil
 }
 
+func (websocket MockWebSocket) Set(key string, value interface{}) {
+}
+
+func (websocket MockWebSocket) Get(key string) (interface{}, bool) {
+	return nil, false
+}
+
+func (websocket MockWebSocket) Delete(key string) {
+}
+
+func (websocket MockWebSocket) Context() context.Context {
+	return context.Background()
+}
+
+func (websocket MockWebSocket) QueryParams() url.Values {
+	return url.Values{}
+}
+
 func NewMockWebSocket(id string) MockWebSocket {
 	return MockWebSocket{id: id}
 }
@@ -52,6 +72,7 @@ type MockWebsocketServer struct {
 	mock.Mock
 	ws.WsServer
 	MessageHandler            func(ws ws.Channel, data []byte) error
+	BinaryMessageHandler      func(ws ws.Channel, data []byte) error
 	NewClientHandler          func(ws ws.Channel)
 	CheckClientHandler        ws.CheckClientHandler
 	DisconnectedClientHandler func(ws ws.Channel)
@@ -75,6 +96,15 @@ func (websocketServer *MockWebsocketServer) SetMessageHandler(handler func(ws ws
 	websocketServer.MessageHandler = handler
 }
 
+func (websocketServer *MockWebsocketServer) WriteBinary(webSocketId string, data []byte) error {
+	args := websocketServer.MethodCalled("WriteBinary", webSocketId, data)
+	return args.Error(0)
+}
+
+func (websocketServer *MockWebsocketServer) SetBinaryMessageHandler(handler func(ws ws.Channel, data []byte) error) {
+	websocketServer.BinaryMessageHandler = handler
+}
+
 func (websocketServer *MockWebsocketServer) SetNewClientHandler(handler func(ws ws.Channel)) {
 	websocketServer.NewClientHandler = handler
 }
@@ -112,10 +142,11 @@ func (websocketServer *MockWebsocketServer) SetCheckClientHandler(handler func(i
 type MockWebsocketClient struct {
 	mock.Mock
 	ws.WsClient
-	MessageHandler      func(data []byte) error
-	ReconnectedHandler  func()
-	DisconnectedHandler func(err error)
-	errC                chan error
+	MessageHandler       func(data []byte) error
+	BinaryMessageHandler func(data []byte) error
+	ReconnectedHandler   func()
+	DisconnectedHandler  func(err error)
+	errC                 chan error
 }
 
 func (websocketClient *MockWebsocketClient) Start(url string) error {
@@ -131,6 +162,15 @@ func (websocketClient *MockWebsocketClient) SetMessageHandler(handler func(data
 	websocketClient.MessageHandler = handler
 }
 
+func (websocketClient *MockWebsocketClient) WriteBinary(data []byte) error {
+	args := websocketClient.MethodCalled("WriteBinary", data)
+	return args.Error(0)
+}
+
+func (websocketClient *MockWebsocketClient) SetBinaryMessageHandler(handler func(data []byte) error) {
+	websocketClient.BinaryMessageHandler = handler
+}
+
 func (websocketClient *MockWebsocketClient) SetReconnectedHandler(handler func()) {
 	websocketClient.ReconnectedHandler = handler
 }
@@ -230,6 +270,48 @@ func (m *MockUnsupportedResponse) GetFeatureName() string {
 	return "SomeRandomFeature"
 }
 
+const MockTypedFeatureName = "MockTypedFeature"
+
+type MockTypedNested struct {
+	Enabled bool `json:"enabled"`
+}
+
+type MockTypedRequest struct {
+	Flag    bool              `json:"flag"`
+	Count   int               `json:"count"`
+	Ratio   *float64          `json:"ratio,omitempty"`
+	Values  []int             `json:"values,omitempty"`
+	Nested  *MockTypedNested  `json:"nested,omitempty"`
+	Label   string            `json:"label,omitempty"`
+	Options map[string]uint16 `json:"options,omitempty"`
+}
+
+type MockTypedConfirmation struct {
+	Accepted bool `json:"accepted"`
+}
+
+type MockTypedFeature struct{}
+
+func (f *MockTypedFeature) GetFeatureName() string {
+	return MockTypedFeatureName
+}
+
+func (f *MockTypedFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(MockTypedRequest{})
+}
+
+func (f *MockTypedFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(MockTypedConfirmation{})
+}
+
+func (r *MockTypedRequest) GetFeatureName() string {
+	return MockTypedFeatureName
+}
+
+func (c *MockTypedConfirmation) GetFeatureName() string {
+	return MockTypedFeatureName
+}
+
 // ---------------------- COMMON UTILITY METHODS ----------------------
 
 func NewWebsocketServer(t *testing.T, onMessage func(data []byte) ([]byte, error)) *ws.Server {
@@ -522,6 +604,51 @@ func (suite *OcppJTestSuite) TestCreateCallError() {
 	CheckCallError(t, callError, mockUniqueId, ocppj.GenericError, mockDescription, mockDetails)
 }
 
+func (suite *OcppJTestSuite) TestCreateCallErrorDialect() {
+	t := suite.T()
+	mockUniqueId := "123456"
+	var testTable = []struct {
+		dialect  ocpp.Dialect
+		code     ocpp.ErrorCode
+		expected string
+	}{
+		{ocpp.V16, ocppj.FormatViolationV16, "FormationViolation"},
+		{ocpp.V16, ocppj.FormatViolationV2, "FormationViolation"},
+		{ocpp.V16, ocppj.MessageTypeNotSupported, "GenericError"},
+		{ocpp.V16, ocppj.RpcFrameworkError, "GenericError"},
+		{ocpp.V16, ocppj.OccurrenceConstraintViolation, "OccurenceConstraintViolation"},
+		{ocpp.V16, ocppj.OccurrenceConstraintViolationV16, "OccurenceConstraintViolation"},
+		{ocpp.V2, ocppj.FormatViolationV16, "FormatViolation"},
+		{ocpp.V2, ocppj.FormatViolationV2, "FormatViolation"},
+		{ocpp.V2, ocppj.MessageTypeNotSupported, "MessageTypeNotSupported"},
+		{ocpp.V2, ocppj.RpcFrameworkError, "RpcFrameworkError"},
+		{ocpp.V2, ocppj.SecurityError, "SecurityError"},
+		{ocpp.V2, ocppj.OccurrenceConstraintViolationV16, "OccurrenceConstraintViolation"},
+		{ocpp.V2, ocppj.OccurrenceConstraintViolation, "OccurrenceConstraintViolation"},
+	}
+	defer suite.chargePoint.SetDialect(suite.chargePoint.Dialect())
+	for _, tc := range testTable {
+		suite.chargePoint.SetDialect(tc.dialect)
+		callError, err := suite.chargePoint.CreateCallError(mockUniqueId, tc.code, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, ocpp.ErrorCode(tc.expected), callError.ErrorCode)
+		assert.True(t, ocppj.IsErrorCodeValidForDialect(callError.ErrorCode, tc.dialect))
+		jsonData, err := callError.MarshalJSON()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`[4,"%v","%v","",{}]`, mockUniqueId, tc.expected), string(jsonData))
+	}
+	// Codes of another version aren't valid
+	assert.False(t, ocppj.IsErrorCodeValidForDialect(ocppj.FormatViolationV2, ocpp.V16))
+	assert.False(t, ocppj.IsErrorCodeValidForDialect(ocppj.RpcFrameworkError, ocpp.V16))
+	assert.False(t, ocppj.IsErrorCodeValidForDialect(ocppj.OccurrenceConstraintViolation, ocpp.V16))
+	assert.False(t, ocppj.IsErrorCodeValidForDialect(ocppj.FormatViolationV16, ocpp.V2))
+	assert.False(t, ocppj.IsErrorCodeValidForDialect(ocppj.OccurrenceConstraintViolationV16, ocpp.V2))
+	// Codes not defined by the specification are rejected
+	callError, err := suite.chargePoint.CreateCallError(mockUniqueId, "CustomError", "", nil)
+	assert.Error(t, err)
+	assert.Nil(t, callError)
+}
+
 func (suite *OcppJTestSuite) TestParseMessageInvalidLength() {
 	t := suite.T()
 	mockMessage := make([]interface{}, 2)
@@ -665,6 +792,30 @@ func (suite *OcppJTestSuite) TestParseMessageInvalidActionCall() {
 	assert.Equal(t, "Invalid element 42 at 2, expected action (string)", protoErr.Description)
 }
 
+func (suite *OcppJTestSuite) TestParseMessageRequestNormalizer() {
+	t := suite.T()
+	messageId := "12345"
+	mockMessage := []interface{}{float64(ocppj.CALL), messageId, MockFeatureName, map[string]interface{}{"mockValue": "someTooLongValue"}}
+	// The request is normalized before validation
+	suite.chargePoint.SetRequestNormalizer(MockFeatureName, func(request ocpp.Request) []string {
+		mockRequest := request.(*MockRequest)
+		mockRequest.MockValue = mockRequest.MockValue[:10]
+		return []string{"truncated mockValue"}
+	})
+	message, err := suite.chargePoint.ParseMessage(mockMessage, suite.chargePoint.RequestState)
+	require.NoError(t, err)
+	call, ok := message.(*ocppj.Call)
+	require.True(t, ok)
+	assert.Equal(t, "someTooLon", call.Payload.(*MockRequest).MockValue)
+	// Without normalizer, the request fails validation
+	suite.chargePoint.SetRequestNormalizer(MockFeatureName, nil)
+	message, err = suite.chargePoint.ParseMessage(mockMessage, suite.chargePoint.RequestState)
+	require.Nil(t, message)
+	require.Error(t, err)
+	protoErr := err.(*ocpp.Error)
+	assert.Equal(t, ocppj.PropertyConstraintViolation, protoErr.Code)
+}
+
 func (suite *OcppJTestSuite) TestParseMessageInvalidCallResult() {
 	t := suite.T()
 	mockMessage := make([]interface{}, 3)
@@ -803,6 +954,56 @@ func (suite *OcppJTestSuite) TestParseCall() {
 	assert.Equal(t, mockValue, mockRequest.MockValue)
 }
 
+func (suite *OcppJTestSuite) TestParseMessageLenientTypeCoercion() {
+	t := suite.T()
+	endpoint := &ocppj.Endpoint{}
+	endpoint.SetDialect(ocpp.V16)
+	endpoint.AddProfile(ocpp.NewProfile("typed", &MockTypedFeature{}))
+	state := ocppj.NewClientState()
+	requestJson := fmt.Sprintf(`[2,"1234","%v",{"flag":"TRUE","count":" 16 ","ratio":"0.5","values":["1",2],"nested":{"enabled":"false"},"label":"true","options":{"a":"7"}}]`, MockTypedFeatureName)
+	// Strict parsing (default) rejects string-encoded values
+	parsedData, err := ocppj.ParseJsonMessage(requestJson)
+	require.NoError(t, err)
+	_, err = endpoint.ParseMessage(parsedData, state)
+	require.Error(t, err)
+	ocppErr, ok := err.(*ocpp.Error)
+	require.True(t, ok)
+	assert.Equal(t, ocppj.FormatErrorType(endpoint), ocppErr.Code)
+	// Lenient parsing coerces them
+	ocppj.SetLenientTypeCoercion(true)
+	defer ocppj.SetLenientTypeCoercion(false)
+	call := ParseCall(endpoint, state, requestJson, t)
+	request, ok := call.Payload.(*MockTypedRequest)
+	require.True(t, ok)
+	assert.True(t, request.Flag)
+	assert.Equal(t, 16, request.Count)
+	require.NotNil(t, request.Ratio)
+	assert.Equal(t, 0.5, *request.Ratio)
+	assert.Equal(t, []int{1, 2}, request.Values)
+	require.NotNil(t, request.Nested)
+	assert.False(t, request.Nested.Enabled)
+	assert.Equal(t, "true", request.Label)
+	assert.Equal(t, map[string]uint16{"a": 7}, request.Options)
+	// Responses are coerced as well
+	state.AddPendingRequest("5678", &MockTypedRequest{})
+	callResult := ParseCallResult(endpoint, state, `[3,"5678",{"accepted":"true"}]`, t)
+	confirmation, ok := callResult.Payload.(*MockTypedConfirmation)
+	require.True(t, ok)
+	assert.True(t, confirmation.Accepted)
+	// Values that aren't valid booleans or numbers are still rejected
+	for _, invalidJson := range []string{
+		fmt.Sprintf(`[2,"1234","%v",{"flag":"yes","count":1}]`, MockTypedFeatureName),
+		fmt.Sprintf(`[2,"1234","%v",{"flag":true,"count":"16A"}]`, MockTypedFeatureName),
+		fmt.Sprintf(`[2,"1234","%v",{"flag":true,"count":"1.5"}]`, MockTypedFeatureName),
+		fmt.Sprintf(`[2,"1234","%v",{"flag":true,"count":1,"options":{"a":"-1"}}]`, MockTypedFeatureName),
+	} {
+		parsedData, err = ocppj.ParseJsonMessage(invalidJson)
+		require.NoError(t, err)
+		_, err = endpoint.ParseMessage(parsedData, state)
+		assert.Error(t, err, invalidJson)
+	}
+}
+
 // TODO: implement further ocpp-j protocol tests
 type testLogger struct {
 	c chan string
@@ -0,0 +1,158 @@
+package ocppj
+
+import (
+	"fmt"
+
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+// Maximum number of incoming requests buffered for a paused client, unless set via Server.SetPausedBufferLimit.
+const defaultPausedBufferLimit = 100
+
+// An incoming message, which was received while the client was paused.
+type bufferedMessage struct {
+	data       []byte
+	parsedJson []interface{}
+}
+
+// State of a paused client.
+type pausedClient struct {
+	channel  ws.Channel
+	buffer   []bufferedMessage
+	resuming bool
+}
+
+// PauseController is implemented by dispatchers, which allow to pause dispatching requests to a client.
+// The DefaultServerDispatcher implements this interface.
+type PauseController interface {
+	PauseClient(clientID string) error
+	ResumeClient(clientID string) error
+}
+
+// SetPausedBufferLimit sets the maximum number of incoming requests, which are buffered for a paused client.
+// Further requests are rejected with a GenericError, until the client is resumed.
+// A limit of zero or less disables the limit.
+//
+// By default, up to 100 requests are buffered per client.
+func (s *Server) SetPausedBufferLimit(limit int) {
+	s.pausedMutex.Lock()
+	defer s.pausedMutex.Unlock()
+	s.pausedBufferLimit = limit
+}
+
+// PauseClient temporarily stops processing messages exchanged with a connected client, without closing the connection.
+// While paused:
+//   - incoming requests are buffered instead of being passed to the request handler, see SetPausedBufferLimit
+//   - no requests are dispatched to the client, although new requests are still queued
+//   - responses to requests dispatched before pausing are processed as usual, so they don't time out
+//   - the websocket connection is kept alive, i.e. pings are still exchanged
+//
+// The client remains paused until ResumeClient is invoked, or the client disconnects.
+// Buffered requests of a client that disconnects are discarded.
+//
+// Returns an error if the client isn't connected, or the dispatcher doesn't implement PauseController.
+func (s *Server) PauseClient(clientID string) error {
+	value, ok := s.connections.Load(clientID)
+	if !ok {
+		return fmt.Errorf("cannot pause client %s, client is not connected", clientID)
+	}
+	controller, ok := s.dispatcher.(PauseController)
+	if !ok {
+		return fmt.Errorf("dispatcher %T doesn't support pausing clients", s.dispatcher)
+	}
+	s.pausedMutex.Lock()
+	defer s.pausedMutex.Unlock()
+	if _, ok = s.paused[clientID]; ok {
+		// Already paused
+		return nil
+	}
+	if err := controller.PauseClient(clientID); err != nil {
+		return err
+	}
+	s.paused[clientID] = &pausedClient{channel: value.(ws.Channel)}
+	log.Infof("paused client %s", clientID)
+	return nil
+}
+
+// ResumeClient resumes processing messages exchanged with a previously paused client.
+//
+// Buffered requests are passed to the request handler in the order in which they were received,
+// before the function returns. Requests received in the meantime are processed after the buffered ones.
+// Afterwards, the dispatcher resumes sending queued requests to the client.
+//
+// Returns an error if the client isn't paused.
+func (s *Server) ResumeClient(clientID string) error {
+	s.pausedMutex.Lock()
+	client, ok := s.paused[clientID]
+	if !ok || client.resuming {
+		s.pausedMutex.Unlock()
+		return fmt.Errorf("cannot resume client %s, client is not paused", clientID)
+	}
+	client.resuming = true
+	s.pausedMutex.Unlock()
+	for {
+		s.pausedMutex.Lock()
+		if s.paused[clientID] != client {
+			// Client disconnected in the meantime
+			s.pausedMutex.Unlock()
+			return nil
+		}
+		if len(client.buffer) == 0 {
+			delete(s.paused, clientID)
+			s.pausedMutex.Unlock()
+			break
+		}
+		message := client.buffer[0]
+		client.buffer = client.buffer[1:]
+		s.pausedMutex.Unlock()
+		_ = s.handleMessage(client.channel, message.data, message.parsedJson)
+	}
+	log.Infof("resumed client %s", clientID)
+	if controller, ok := s.dispatcher.(PauseController); ok {
+		return controller.ResumeClient(clientID)
+	}
+	return nil
+}
+
+// Buffers an incoming request, if the client is paused. Returns true if the message must not be processed any further.
+func (s *Server) bufferIfPaused(wsChannel ws.Channel, data []byte, parsedJson []interface{}) bool {
+	if len(parsedJson) < 3 {
+		// Invalid messages are always processed, so they are rejected right away
+		return false
+	}
+	if typeId, ok := parsedJson[0].(float64); !ok || MessageType(typeId) != CALL {
+		// Responses are always processed
+		return false
+	}
+	s.pausedMutex.Lock()
+	client, ok := s.paused[wsChannel.ID()]
+	if !ok {
+		s.pausedMutex.Unlock()
+		return false
+	}
+	if s.pausedBufferLimit > 0 && len(client.buffer) >= s.pausedBufferLimit {
+		s.pausedMutex.Unlock()
+		uniqueId, _ := parsedJson[1].(string)
+		log.Errorf("buffer of paused client %s is full, rejecting request %s", wsChannel.ID(), uniqueId)
+		if uniqueId != "" {
+			_ = s.SendError(wsChannel.ID(), uniqueId, GenericError, "Client is paused and its buffer is full", nil)
+		}
+		return true
+	}
+	client.buffer = append(client.buffer, bufferedMessage{data: data, parsedJson: parsedJson})
+	s.pausedMutex.Unlock()
+	log.Debugf("buffered message from paused client %s", wsChannel.ID())
+	return true
+}
+
+// Discards the state of a paused client, e.g. after it disconnected.
+func (s *Server) clearPaused(clientID string) {
+	s.pausedMutex.Lock()
+	defer s.pausedMutex.Unlock()
+	if client, ok := s.paused[clientID]; ok {
+		if len(client.buffer) > 0 {
+			log.Infof("discarded %d buffered messages of paused client %s", len(client.buffer), clientID)
+		}
+		delete(s.paused, clientID)
+	}
+}
@@ -0,0 +1,190 @@
+package ocppj
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// QueueStore is a durable storage for outgoing requests, used by a PersistentQueue.
+// Implementations must be thread-safe.
+type QueueStore interface {
+	// Save persists the raw data of a request, identified by its unique ID.
+	Save(requestID string, data []byte) error
+	// Delete removes a previously persisted request. Deleting an unknown request is not an error.
+	Delete(requestID string) error
+	// Load returns the raw data of all persisted requests, in the order they were saved.
+	Load() ([][]byte, error)
+}
+
+// PersistentQueue is a RequestQueue, which mirrors all queued requests to a QueueStore.
+// Requests are persisted when pushed to the queue and deleted once they are popped, i.e. after they were completed or canceled.
+// Requests persisted by a previous process can be restored via Load.
+//
+// Callbacks may be set for observing the persistence lifecycle of every request, e.g. for auditing purposes.
+//
+// Init only clears the in-memory queue, while persisted requests are retained.
+type PersistentQueue struct {
+	RequestQueue
+	store       QueueStore
+	endpoint    *Endpoint
+	onPersisted func(requestID string, action string)
+	onLoaded    func(requestID string, action string)
+	onCompleted func(requestID string, action string)
+}
+
+// NewPersistentQueue creates a new PersistentQueue on top of an in-memory queue.
+// The endpoint is needed for parsing the persisted requests on Load, and must support all of their features.
+func NewPersistentQueue(queue RequestQueue, store QueueStore, endpoint *Endpoint) *PersistentQueue {
+	return &PersistentQueue{RequestQueue: queue, store: store, endpoint: endpoint}
+}
+
+// SetOnRequestPersisted sets a callback, which is invoked after a pushed request was persisted.
+func (q *PersistentQueue) SetOnRequestPersisted(callback func(requestID string, action string)) {
+	q.onPersisted = callback
+}
+
+// SetOnRequestLoaded sets a callback, which is invoked after a persisted request was restored via Load.
+func (q *PersistentQueue) SetOnRequestLoaded(callback func(requestID string, action string)) {
+	q.onLoaded = callback
+}
+
+// SetOnRequestCompleted sets a callback, which is invoked after a popped request was removed from the store.
+func (q *PersistentQueue) SetOnRequestCompleted(callback func(requestID string, action string)) {
+	q.onCompleted = callback
+}
+
+// Push persists the request, before appending it to the in-memory queue.
+// The element must be a RequestBundle.
+func (q *PersistentQueue) Push(element interface{}) error {
+	bundle, ok := element.(RequestBundle)
+	if ok && bundle.Binary {
+		// Binary frames are transient and aren't restored
+		return q.RequestQueue.Push(bundle)
+	}
+	if !ok || bundle.Call == nil {
+		return fmt.Errorf("invalid element %T, expected request bundle", element)
+	}
+	if err := q.store.Save(bundle.Call.UniqueId, bundle.Data); err != nil {
+		return fmt.Errorf("couldn't persist request %v: %w", bundle.Call.UniqueId, err)
+	}
+	if err := q.RequestQueue.Push(bundle); err != nil {
+		// Request wasn't queued, hence it shouldn't be restored either
+		if err2 := q.store.Delete(bundle.Call.UniqueId); err2 != nil {
+			log.Errorf("couldn't delete request %v from store: %v", bundle.Call.UniqueId, err2)
+		}
+		return err
+	}
+	if q.onPersisted != nil {
+		q.onPersisted(bundle.Call.UniqueId, bundle.Call.Action)
+	}
+	return nil
+}
+
+// Pop removes the first request from the in-memory queue and deletes it from the store.
+func (q *PersistentQueue) Pop() interface{} {
+	element := q.RequestQueue.Pop()
+	bundle, ok := element.(RequestBundle)
+	if !ok || bundle.Call == nil {
+		return element
+	}
+	if err := q.store.Delete(bundle.Call.UniqueId); err != nil {
+		log.Errorf("couldn't delete request %v from store: %v", bundle.Call.UniqueId, err)
+		return element
+	}
+	if q.onCompleted != nil {
+		q.onCompleted(bundle.Call.UniqueId, bundle.Call.Action)
+	}
+	return element
+}
+
+// Filter removes or replaces queued requests in place, without persisting them again.
+// Removed requests are deleted from the store, like popped ones.
+// Replaced requests are only saved to the store if their data changed.
+func (q *PersistentQueue) Filter(filter func(index int, element interface{}) (interface{}, bool)) []interface{} {
+	var replaced []RequestBundle
+	var replacedIDs []string
+	removed := filterQueue(q.RequestQueue, func(index int, element interface{}) (interface{}, bool) {
+		filtered, keep := filter(index, element)
+		if !keep {
+			return filtered, false
+		}
+		original, ok1 := element.(RequestBundle)
+		bundle, ok2 := filtered.(RequestBundle)
+		if ok1 && ok2 && original.Call != nil && bundle.Call != nil && !bytes.Equal(original.Data, bundle.Data) {
+			replaced = append(replaced, bundle)
+			replacedIDs = append(replacedIDs, original.Call.UniqueId)
+		}
+		return filtered, true
+	})
+	for i, bundle := range replaced {
+		if replacedIDs[i] != bundle.Call.UniqueId {
+			if err := q.store.Delete(replacedIDs[i]); err != nil {
+				log.Errorf("couldn't delete request %v from store: %v", replacedIDs[i], err)
+			}
+		}
+		if err := q.store.Save(bundle.Call.UniqueId, bundle.Data); err != nil {
+			log.Errorf("couldn't persist replaced request %v: %v", bundle.Call.UniqueId, err)
+		}
+	}
+	for _, element := range removed {
+		bundle, ok := element.(RequestBundle)
+		if !ok || bundle.Call == nil {
+			continue
+		}
+		if err := q.store.Delete(bundle.Call.UniqueId); err != nil {
+			log.Errorf("couldn't delete request %v from store: %v", bundle.Call.UniqueId, err)
+			continue
+		}
+		if q.onCompleted != nil {
+			q.onCompleted(bundle.Call.UniqueId, bundle.Call.Action)
+		}
+	}
+	return removed
+}
+
+// Load restores all persisted requests into the in-memory queue, in the order they were persisted.
+// Load should be invoked once on startup, before any new request is pushed.
+//
+// Endpoints matching responses to their callbacks in queue order, such as the OCPP 2.0.1 charging station,
+// don't know the restored requests. For such endpoints, requests must be restored via the endpoint instead
+// (e.g. ChargingStation.RestoreRequests), which relies on Restore.
+//
+// Returns an error if the store couldn't be read, or if a persisted request couldn't be parsed.
+// Requests restored before the error occurred remain in the queue.
+func (q *PersistentQueue) Load() error {
+	return q.Restore(nil)
+}
+
+// Restore works like Load, but additionally invokes the restored function for every request,
+// right after it was appended to the in-memory queue. The function is invoked in queue order,
+// allowing endpoints to register per-request state for the restored requests.
+func (q *PersistentQueue) Restore(restored func(call *Call)) error {
+	entries, err := q.store.Load()
+	if err != nil {
+		return fmt.Errorf("couldn't load persisted requests: %w", err)
+	}
+	for _, data := range entries {
+		parsedJson, err := ParseRawJsonMessage(data)
+		if err != nil {
+			return fmt.Errorf("couldn't parse persisted request: %w", err)
+		}
+		message, err := q.endpoint.ParseMessage(parsedJson, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't parse persisted request: %w", err)
+		}
+		call, ok := message.(*Call)
+		if !ok {
+			return fmt.Errorf("persisted message %v is not a request", message)
+		}
+		if err = q.RequestQueue.Push(RequestBundle{Call: call, Data: data}); err != nil {
+			return fmt.Errorf("couldn't restore persisted request %v: %w", call.UniqueId, err)
+		}
+		if restored != nil {
+			restored(call)
+		}
+		if q.onLoaded != nil {
+			q.onLoaded(call.UniqueId, call.Action)
+		}
+	}
+	return nil
+}
@@ -7,9 +7,21 @@ import (
 
 // RequestBundle is a convenience struct for passing a call object struct and the
 // raw byte data into the queue containing outgoing requests.
+//
+// Binary bundles carry a raw binary frame instead of a request, hence their Call is nil.
+// They are sent once all previously queued requests were completed, without awaiting a response.
 type RequestBundle struct {
-	Call *Call
-	Data []byte
+	Call   *Call
+	Data   []byte
+	Binary bool
+}
+
+// Describes the bundle for log and error messages.
+func (bundle RequestBundle) description() string {
+	if bundle.Binary {
+		return "binary message"
+	}
+	return fmt.Sprintf("request %v", bundle.Call.UniqueId)
 }
 
 // RequestQueue can be arbitrarily implemented, as long as it conforms to the Queue interface.
@@ -34,6 +46,38 @@ type RequestQueue interface {
 	IsEmpty() bool
 }
 
+// FilterableQueue is implemented by request queues, which allow to remove or replace queued elements in place.
+// The FIFOClientQueue and the PersistentQueue implement this interface.
+type FilterableQueue interface {
+	// Filter invokes the filter on every element of the queue, preserving the queue order.
+	// Elements for which the filter returns false are removed from the queue,
+	// all other elements are replaced by the element returned by the filter.
+	// Returns the removed elements.
+	Filter(filter func(index int, element interface{}) (interface{}, bool)) []interface{}
+}
+
+// Filters the queue in place if it implements FilterableQueue.
+// Otherwise, all elements are popped and the kept ones are pushed again.
+func filterQueue(queue RequestQueue, filter func(index int, element interface{}) (interface{}, bool)) []interface{} {
+	if filterable, ok := queue.(FilterableQueue); ok {
+		return filterable.Filter(filter)
+	}
+	var elements []interface{}
+	for !queue.IsEmpty() {
+		elements = append(elements, queue.Pop())
+	}
+	var removed []interface{}
+	for i, element := range elements {
+		filtered, keep := filter(i, element)
+		if !keep {
+			removed = append(removed, element)
+			continue
+		}
+		_ = queue.Push(filtered)
+	}
+	return removed
+}
+
 // FIFOClientQueue is a default queue implementation. The queue is thread-safe.
 type FIFOClientQueue struct {
 	elements []interface{}
@@ -95,6 +139,26 @@ func (q *FIFOClientQueue) IsEmpty() bool {
 	return len(q.elements) == 0
 }
 
+func (q *FIFOClientQueue) Filter(filter func(index int, element interface{}) (interface{}, bool)) []interface{} {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var removed []interface{}
+	kept := q.elements[:0]
+	for i, element := range q.elements {
+		filtered, keep := filter(i, element)
+		if !keep {
+			removed = append(removed, element)
+			continue
+		}
+		kept = append(kept, filtered)
+	}
+	for i := len(kept); i < len(q.elements); i++ {
+		q.elements[i] = nil
+	}
+	q.elements = kept
+	return removed
+}
+
 // NewFIFOClientQueue creates a new FIFOClientQueue with the given capacity.
 //
 // A FIFOQueue is backed by a slice, and the capacity represents the maximum capacity of the queue.
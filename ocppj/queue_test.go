@@ -1,6 +1,8 @@
 package ocppj_test
 
 import (
+	"sync"
+
 	"github.com/lorenzodonini/ocpp-go/ocppj"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -171,3 +173,156 @@ func (suite *ServerQueueMapTestSuite) TestRemove() {
 	assert.False(t, ok)
 	assert.Nil(t, q)
 }
+
+type mockQueueStore struct {
+	mutex   sync.Mutex
+	ids     []string
+	entries map[string][]byte
+}
+
+func newMockQueueStore() *mockQueueStore {
+	return &mockQueueStore{entries: map[string][]byte{}}
+}
+
+func (s *mockQueueStore) Save(requestID string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.entries[requestID]; !ok {
+		s.ids = append(s.ids, requestID)
+	}
+	s.entries[requestID] = data
+	return nil
+}
+
+func (s *mockQueueStore) Delete(requestID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, requestID)
+	for i, id := range s.ids {
+		if id == requestID {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *mockQueueStore) Load() ([][]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make([][]byte, 0, len(s.ids))
+	for _, id := range s.ids {
+		result = append(result, s.entries[id])
+	}
+	return result, nil
+}
+
+func (suite *OcppJTestSuite) TestPersistentQueueLifecycle() {
+	t := suite.T()
+	store := newMockQueueStore()
+	var events []string
+	newQueue := func() *ocppj.PersistentQueue {
+		q := ocppj.NewPersistentQueue(ocppj.NewFIFOClientQueue(queueCapacity), store, &suite.chargePoint.Endpoint)
+		q.SetOnRequestPersisted(func(requestID string, action string) {
+			assert.Equal(t, MockFeatureName, action)
+			events = append(events, "persisted:"+requestID)
+		})
+		q.SetOnRequestLoaded(func(requestID string, action string) {
+			assert.Equal(t, MockFeatureName, action)
+			events = append(events, "loaded:"+requestID)
+		})
+		q.SetOnRequestCompleted(func(requestID string, action string) {
+			assert.Equal(t, MockFeatureName, action)
+			events = append(events, "completed:"+requestID)
+		})
+		return q
+	}
+	// Persist two requests
+	queue := newQueue()
+	var ids []string
+	for _, value := range []string{"first", "second"} {
+		call, err := suite.chargePoint.CreateCall(newMockRequest(value))
+		require.NoError(t, err)
+		data, err := call.MarshalJSON()
+		require.NoError(t, err)
+		err = queue.Push(ocppj.RequestBundle{Call: call, Data: data})
+		require.NoError(t, err)
+		ids = append(ids, call.UniqueId)
+	}
+	// Invalid elements are rejected
+	assert.Error(t, queue.Push(newMockRequest("invalid")))
+	// Simulate restart: in-memory state is lost, persisted requests are restored in order
+	queue.Init()
+	assert.True(t, queue.IsEmpty())
+	queue = newQueue()
+	err := queue.Load()
+	require.NoError(t, err)
+	require.Equal(t, 2, queue.Size())
+	bundle, ok := queue.Peek().(ocppj.RequestBundle)
+	require.True(t, ok)
+	assert.Equal(t, ids[0], bundle.Call.UniqueId)
+	assert.Equal(t, "first", bundle.Call.Payload.(*MockRequest).MockValue)
+	// Complete both requests
+	queue.Pop()
+	queue.Pop()
+	assert.True(t, queue.IsEmpty())
+	persisted, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, persisted)
+	assert.Equal(t, []string{
+		"persisted:" + ids[0], "persisted:" + ids[1],
+		"loaded:" + ids[0], "loaded:" + ids[1],
+		"completed:" + ids[0], "completed:" + ids[1],
+	}, events)
+}
+
+func (suite *OcppJTestSuite) TestPersistentQueueFilter() {
+	t := suite.T()
+	store := newMockQueueStore()
+	var events []string
+	queue := ocppj.NewPersistentQueue(ocppj.NewFIFOClientQueue(queueCapacity), store, &suite.chargePoint.Endpoint)
+	queue.SetOnRequestPersisted(func(requestID string, action string) {
+		events = append(events, "persisted:"+requestID)
+	})
+	queue.SetOnRequestCompleted(func(requestID string, action string) {
+		events = append(events, "completed:"+requestID)
+	})
+	var ids []string
+	for _, value := range []string{"first", "second", "third"} {
+		call, err := suite.chargePoint.CreateCall(newMockRequest(value))
+		require.NoError(t, err)
+		data, err := call.MarshalJSON()
+		require.NoError(t, err)
+		err = queue.Push(ocppj.RequestBundle{Call: call, Data: data})
+		require.NoError(t, err)
+		ids = append(ids, call.UniqueId)
+	}
+	events = nil
+	// Drop the second request and replace the third one
+	replacement, err := suite.chargePoint.CreateCall(newMockRequest("replaced"))
+	require.NoError(t, err)
+	replacement.UniqueId = ids[2]
+	replacementData, err := replacement.MarshalJSON()
+	require.NoError(t, err)
+	removed := queue.Filter(func(index int, element interface{}) (interface{}, bool) {
+		switch index {
+		case 1:
+			return element, false
+		case 2:
+			return ocppj.RequestBundle{Call: replacement, Data: replacementData}, true
+		}
+		return element, true
+	})
+	require.Len(t, removed, 1)
+	assert.Equal(t, ids[1], removed[0].(ocppj.RequestBundle).Call.UniqueId)
+	// Kept requests aren't persisted again, only the dropped request is completed
+	assert.Equal(t, []string{"completed:" + ids[1]}, events)
+	require.Equal(t, 2, queue.Size())
+	bundle, ok := queue.Peek().(ocppj.RequestBundle)
+	require.True(t, ok)
+	assert.Equal(t, ids[0], bundle.Call.UniqueId)
+	persisted, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, persisted, 2)
+	assert.Equal(t, replacementData, persisted[1])
+}
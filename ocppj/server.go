@@ -1,7 +1,11 @@
 package ocppj
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"sync"
+	"time"
 
 	"gopkg.in/go-playground/validator.v9"
 
@@ -20,7 +24,15 @@ type Server struct {
 	requestHandler            RequestHandler
 	responseHandler           ResponseHandler
 	errorHandler              ErrorHandler
+	binaryMessageHandler      BinaryMessageHandler
 	invalidMessageHook        InvalidMessageHook
+	canceledRequestHandler    CanceledRequestHandler
+	requestObserver           RequestObserver
+	connections               sync.Map
+	auditLog                  *AuditLog
+	paused                    map[string]*pausedClient
+	pausedBufferLimit         int
+	pausedMutex               sync.Mutex
 	dispatcher                ServerDispatcher
 	RequestState              ServerState
 }
@@ -28,8 +40,37 @@ type Server struct {
 type ClientHandler func(client ws.Channel)
 type RequestHandler func(client ws.Channel, request ocpp.Request, requestId string, action string)
 type ResponseHandler func(client ws.Channel, response ocpp.Response, requestId string)
+
+// ErrorHandler is invoked for incoming CALLERROR messages, passing the error details sent by the client.
+// It is also invoked for invalid CALLRESULT messages, passing an *InvalidResponseError as details.
 type ErrorHandler func(client ws.Channel, err *ocpp.Error, details interface{})
 type InvalidMessageHook func(client ws.Channel, err *ocpp.Error, rawJson string, parsedFields []interface{}) *ocpp.Error
+type BinaryMessageHandler func(client ws.Channel, data []byte)
+
+// RequestDirection indicates whether a request was sent or received by an endpoint.
+type RequestDirection string
+
+const (
+	RequestOutgoing RequestDirection = "outgoing"
+	RequestIncoming RequestDirection = "incoming"
+)
+
+// RequestObserver is notified about the lifecycle of requests exchanged between a server and its clients.
+// It allows to plug in cross-cutting concerns, such as metrics or tracing, without wrapping the OCPP handlers.
+//
+// OnRequestStarted is invoked when a request is enqueued for a client, or received from a client.
+// The passed context is the context of the client connection (see ws.Channel).
+//
+// OnRequestCompleted is invoked once a response to the request was received or sent.
+// The passed error is nil, if the response was a CALL_RESULT.
+// Outgoing requests are also completed when they are canceled, e.g. due to a timeout.
+// The function may also be invoked for requests that were never started, e.g. when responding to an invalid message.
+//
+// Both functions are invoked synchronously and must return as soon as possible.
+type RequestObserver interface {
+	OnRequestStarted(ctx context.Context, clientID string, requestID string, action string, direction RequestDirection)
+	OnRequestCompleted(clientID string, requestID string, direction RequestDirection, err *ocpp.Error)
+}
 
 // Creates a new Server endpoint.
 // Requires a a websocket server. Optionally a structure for queueing/dispatching requests,
@@ -59,11 +100,12 @@ func NewServer(wsServer ws.WsServer, dispatcher ServerDispatcher, stateHandler S
 	dispatcher.SetPendingRequestState(stateHandler)
 
 	// Create server and add profiles
-	s := Server{Endpoint: Endpoint{}, server: wsServer, RequestState: stateHandler, dispatcher: dispatcher}
+	s := &Server{Endpoint: Endpoint{}, server: wsServer, RequestState: stateHandler, dispatcher: dispatcher, paused: map[string]*pausedClient{}, pausedBufferLimit: defaultPausedBufferLimit}
+	dispatcher.SetOnRequestCanceled(s.onRequestCanceled)
 	for _, profile := range profiles {
 		s.AddProfile(profile)
 	}
-	return &s
+	return s
 }
 
 // Registers a handler for incoming requests.
@@ -81,6 +123,15 @@ func (s *Server) SetErrorHandler(handler ErrorHandler) {
 	s.errorHandler = handler
 }
 
+// Registers a handler for incoming binary messages.
+//
+// Binary frames carry raw data (e.g. OCPP 2.1 binary data transfers) and are not OCPP-J messages,
+// hence they are passed to the handler as is, bypassing parsing, validation and the request state.
+// If no handler is registered, incoming binary messages are discarded and an error is reported on the errors channel.
+func (s *Server) SetBinaryMessageHandler(handler BinaryMessageHandler) {
+	s.binaryMessageHandler = handler
+}
+
 // SetInvalidMessageHook registers an optional hook for incoming messages that couldn't be parsed.
 // This hook is called when a message is received but cannot be parsed to the target OCPP message struct.
 //
@@ -101,7 +152,22 @@ func (s *Server) SetInvalidMessageHook(hook InvalidMessageHook) {
 
 // Registers a handler for canceled request messages.
 func (s *Server) SetCanceledRequestHandler(handler CanceledRequestHandler) {
-	s.dispatcher.SetOnRequestCanceled(handler)
+	s.canceledRequestHandler = handler
+}
+
+// SetRequestObserver registers an optional observer, which is notified about the lifecycle of every request
+// sent to or received from a client. See RequestObserver for more details.
+func (s *Server) SetRequestObserver(observer RequestObserver) {
+	s.requestObserver = observer
+}
+
+// SetAuditLog enables recording every request sent to a client in the given audit log,
+// together with the received response, error or cancellation. Requests are recorded once they were enqueued.
+// Incoming requests aren't recorded. See AuditLog for more details.
+//
+// Pass nil to disable the audit log (default).
+func (s *Server) SetAuditLog(auditLog *AuditLog) {
+	s.auditLog = auditLog
 }
 
 // Registers a handler for incoming client connections.
@@ -126,15 +192,119 @@ func (s *Server) SetDisconnectedClientHandler(handler ClientHandler) {
 //
 // An error may be returned, if the websocket server couldn't be started.
 func (s *Server) Start(listenPort int, listenPath string) {
+	s.setNetworkHandlers()
+	s.dispatcher.Start()
+	// Serve & run
+	s.server.Start(listenPort, listenPath)
+	// TODO: return error?
+}
+
+// Starts the underlying Websocket server on a specified listenPort and listenPath, until the passed context is done.
+// Once the context is canceled, the server is shut down gracefully and all pending requests are cleared.
+//
+// The function blocks until the server stopped. An error is returned if the websocket server couldn't be started,
+// while nil is returned after a graceful shutdown.
+func (s *Server) StartWithContext(ctx context.Context, listenPort int, listenPath string) error {
+	s.setNetworkHandlers()
+	s.dispatcher.Start()
+	// Serve & run
+	err := s.server.StartWithContext(ctx, listenPort, listenPath)
+	if s.dispatcher.IsRunning() {
+		s.dispatcher.Stop()
+	}
+	return err
+}
+
+// Starts the underlying Websocket server on a pre-created listener, such as a Unix domain socket, on the specified listenPath.
+// See ws.WsServer.StartOnListener for more details.
+//
+// The function blocks until the server stopped. Once stopped, all pending requests are cleared.
+func (s *Server) StartOnListener(listener net.Listener, listenPath string) error {
+	s.setNetworkHandlers()
+	s.dispatcher.Start()
+	// Serve & run
+	err := s.server.StartOnListener(listener, listenPath)
+	if s.dispatcher.IsRunning() {
+		s.dispatcher.Stop()
+	}
+	return err
+}
+
+// Registers an additional URL pattern, on which charge points may connect to the underlying websocket server.
+// See ws.WsServer.AddListenPath for more details.
+func (s *Server) AddListenPath(listenPath string) {
+	s.server.AddListenPath(listenPath)
+}
+
+// QueueController is implemented by dispatchers, which allow to manage the queue of a client on demand.
+// The DefaultServerDispatcher implements this interface.
+type QueueController interface {
+	FlushQueue(clientID string) error
+	DropQueue(clientID string) ([]RequestBundle, error)
+}
+
+// ClientTimeoutController is implemented by dispatchers, which support a request timeout per client.
+// The DefaultServerDispatcher implements this interface.
+type ClientTimeoutController interface {
+	SetClientTimeout(clientID string, timeout time.Duration)
+}
+
+// SetClientTimeout sets the timeout for requests sent to a specific client. A zero timeout falls back to the default timeout.
+// See DefaultServerDispatcher.SetClientTimeout for more details.
+//
+// Returns an error if the dispatcher doesn't implement ClientTimeoutController.
+func (s *Server) SetClientTimeout(clientID string, timeout time.Duration) error {
+	controller, ok := s.dispatcher.(ClientTimeoutController)
+	if !ok {
+		return fmt.Errorf("dispatcher %T doesn't support client timeouts", s.dispatcher)
+	}
+	controller.SetClientTimeout(clientID, timeout)
+	return nil
+}
+
+// FlushQueue dispatches all requests queued for a client as fast as possible, ignoring any outbound pacing.
+// See DefaultServerDispatcher.FlushQueue for more details.
+//
+// Returns an error if the dispatcher doesn't implement QueueController.
+func (s *Server) FlushQueue(clientID string) error {
+	controller, ok := s.dispatcher.(QueueController)
+	if !ok {
+		return fmt.Errorf("dispatcher %T doesn't support flushing queues", s.dispatcher)
+	}
+	return controller.FlushQueue(clientID)
+}
+
+// DropQueue discards all requests queued for a client, which weren't sent yet, and returns them in queue order.
+// See DefaultServerDispatcher.DropQueue for more details.
+//
+// The canceled request handler is not invoked for dropped requests, as the caller is in charge of handling them.
+// Returns an error if the dispatcher doesn't implement QueueController.
+func (s *Server) DropQueue(clientID string) ([]RequestBundle, error) {
+	controller, ok := s.dispatcher.(QueueController)
+	if !ok {
+		return nil, fmt.Errorf("dispatcher %T doesn't support dropping queues", s.dispatcher)
+	}
+	dropped, err := controller.DropQueue(clientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, bundle := range dropped {
+		dropErr := ocpp.NewError(GenericError, "Request dropped", bundle.Call.UniqueId)
+		if s.auditLog != nil {
+			s.auditLog.recordError(clientID, bundle.Call.UniqueId, AuditCanceled, nil, dropErr)
+		}
+		s.notifyRequestCompleted(clientID, bundle.Call.UniqueId, RequestOutgoing, dropErr)
+	}
+	return dropped, nil
+}
+
+func (s *Server) setNetworkHandlers() {
 	// Set internal message handler
 	s.server.SetCheckClientHandler(s.checkClientHandler)
 	s.server.SetNewClientHandler(s.onClientConnected)
 	s.server.SetDisconnectedClientHandler(s.onClientDisconnected)
 	s.server.SetMessageHandler(s.ocppMessageHandler)
-	s.dispatcher.Start()
-	// Serve & run
-	s.server.Start(listenPort, listenPath)
-	// TODO: return error?
+	s.server.SetBinaryMessageHandler(s.binaryMessageHandlerFunc)
 }
 
 // Stops the server.
@@ -167,9 +337,19 @@ func (s *Server) SendRequest(clientID string, request ocpp.Request) error {
 	if err != nil {
 		return err
 	}
+	// The request may be completed as soon as it was enqueued, hence it is marked as started beforehand
+	s.notifyRequestStarted(s.connectionContext(clientID), clientID, call.UniqueId, call.Action, RequestOutgoing)
+	if s.auditLog != nil {
+		s.auditLog.recordRequest(clientID, call, jsonMessage)
+	}
 	// Will not send right away. Queuing message and let it be processed by dedicated requestPump routine
-	if err = s.dispatcher.SendRequest(clientID, RequestBundle{call, jsonMessage}); err != nil {
+	if err = s.dispatcher.SendRequest(clientID, RequestBundle{Call: call, Data: jsonMessage}); err != nil {
 		log.Errorf("error dispatching request [%s, %s] to %s: %v", call.UniqueId, call.Action, clientID, err)
+		ocppErr := ocpp.NewError(GenericError, err.Error(), call.UniqueId)
+		if s.auditLog != nil {
+			s.auditLog.recordError(clientID, call.UniqueId, AuditCanceled, nil, ocppErr)
+		}
+		s.notifyRequestCompleted(clientID, call.UniqueId, RequestOutgoing, ocppErr)
 		return err
 	}
 	log.Debugf("enqueued CALL [%s, %s] for %s", call.UniqueId, call.Action, clientID)
@@ -201,6 +381,7 @@ func (s *Server) SendResponse(clientID string, requestId string, response ocpp.R
 	}
 	log.Debugf("sent CALL RESULT [%s] for %s", callResult.GetUniqueId(), clientID)
 	log.Debugf("sent JSON message to %s: %s", clientID, string(jsonMessage))
+	s.notifyRequestCompleted(clientID, requestId, RequestIncoming, nil)
 	return nil
 }
 
@@ -221,6 +402,8 @@ func (s *Server) SendError(clientID string, requestId string, errorCode ocpp.Err
 	if err != nil {
 		return ocpp.NewError(GenericError, err.Error(), requestId)
 	}
+	// The request is completed even if the error couldn't be sent, since no further response will be attempted
+	defer s.notifyRequestCompleted(clientID, requestId, RequestIncoming, ocpp.NewError(errorCode, description, requestId))
 	if err = s.server.Write(clientID, jsonMessage); err != nil {
 		log.Errorf("error sending response error [%s] to %s: %v", callError.UniqueId, clientID, err)
 		return ocpp.NewError(GenericError, err.Error(), requestId)
@@ -229,6 +412,34 @@ func (s *Server) SendError(clientID string, requestId string, errorCode ocpp.Err
 	return nil
 }
 
+// Sends a binary message to a client, identified by the clientID parameter.
+//
+// Binary messages are not OCPP-J messages, but they are queued by the dispatcher together with outgoing requests:
+// a binary message is sent once all previously queued requests were completed, and no response is awaited.
+// As the message is sent asynchronously, network errors are only logged.
+//
+// Returns an error if the server wasn't started, or the output queue is full.
+func (s *Server) SendBinary(clientID string, data []byte) error {
+	if !s.dispatcher.IsRunning() {
+		return fmt.Errorf("ocppj server is not started, couldn't send binary message")
+	}
+	if err := s.dispatcher.SendRequest(clientID, RequestBundle{Data: data, Binary: true}); err != nil {
+		log.Errorf("error dispatching binary message to %s: %v", clientID, err)
+		return err
+	}
+	log.Debugf("enqueued binary message of %d bytes for %s", len(data), clientID)
+	return nil
+}
+
+func (s *Server) binaryMessageHandlerFunc(wsChannel ws.Channel, data []byte) error {
+	log.Debugf("received binary message of %d bytes from %s", len(data), wsChannel.ID())
+	if s.binaryMessageHandler == nil {
+		return fmt.Errorf("no handler for binary message from %s, discarding", wsChannel.ID())
+	}
+	s.binaryMessageHandler(wsChannel, data)
+	return nil
+}
+
 func (s *Server) ocppMessageHandler(wsChannel ws.Channel, data []byte) error {
 	parsedJson, err := ParseRawJsonMessage(data)
 	if err != nil {
@@ -236,9 +447,20 @@ func (s *Server) ocppMessageHandler(wsChannel ws.Channel, data []byte) error {
 		return err
 	}
 	log.Debugf("received JSON message from %s: %s", wsChannel.ID(), string(data))
+	if s.bufferIfPaused(wsChannel, data, parsedJson) {
+		return nil
+	}
+	return s.handleMessage(wsChannel, data, parsedJson)
+}
+
+func (s *Server) handleMessage(wsChannel ws.Channel, data []byte, parsedJson []interface{}) error {
 	// Get pending requests for client
 	pending := s.RequestState.GetClientState(wsChannel.ID())
-	message, err := s.ParseMessage(parsedJson, pending)
+	var message Message
+	var err error
+	if err = s.checkDuplicateKeys(data, parsedJson); err == nil {
+		message, err = s.ParseMessage(parsedJson, pending)
+	}
 	if err != nil {
 		ocppErr := err.(*ocpp.Error)
 		messageID := ocppErr.MessageId
@@ -252,6 +474,17 @@ func (s *Server) ocppMessageHandler(wsChannel ws.Channel, data []byte) error {
 			}
 		}
 		err = ocppErr
+		if invalidResponse := newInvalidResponseError(parsedJson, data, pending, ocppErr); invalidResponse != nil {
+			// Complete the pending request, reporting the error to its sender
+			s.dispatcher.CompleteRequest(wsChannel.ID(), ocppErr.MessageId)
+			if s.auditLog != nil {
+				s.auditLog.recordError(wsChannel.ID(), ocppErr.MessageId, AuditError, data, ocppErr)
+			}
+			s.notifyRequestCompleted(wsChannel.ID(), ocppErr.MessageId, RequestOutgoing, ocppErr)
+			if s.errorHandler != nil {
+				s.errorHandler(wsChannel, ocppErr, invalidResponse)
+			}
+		}
 		// Send error to other endpoint if a message ID is available
 		if ocppErr.MessageId != "" {
 			err2 := s.SendError(wsChannel.ID(), ocppErr.MessageId, ocppErr.Code, ocppErr.Description, nil)
@@ -267,6 +500,7 @@ func (s *Server) ocppMessageHandler(wsChannel ws.Channel, data []byte) error {
 		case CALL:
 			call := message.(*Call)
 			log.Debugf("handling incoming CALL [%s, %s] from %s", call.UniqueId, call.Action, wsChannel.ID())
+			s.notifyRequestStarted(wsChannel.Context(), wsChannel.ID(), call.UniqueId, call.Action, RequestIncoming)
 			if s.requestHandler != nil {
 				s.requestHandler(wsChannel, call.Payload, call.UniqueId, call.Action)
 			}
@@ -274,6 +508,10 @@ func (s *Server) ocppMessageHandler(wsChannel ws.Channel, data []byte) error {
 			callResult := message.(*CallResult)
 			log.Debugf("handling incoming CALL RESULT [%s] from %s", callResult.UniqueId, wsChannel.ID())
 			s.dispatcher.CompleteRequest(wsChannel.ID(), callResult.GetUniqueId())
+			if s.auditLog != nil {
+				s.auditLog.recordResponse(wsChannel.ID(), callResult.UniqueId, data)
+			}
+			s.notifyRequestCompleted(wsChannel.ID(), callResult.UniqueId, RequestOutgoing, nil)
 			if s.responseHandler != nil {
 				s.responseHandler(wsChannel, callResult.Payload, callResult.UniqueId)
 			}
@@ -281,8 +519,13 @@ func (s *Server) ocppMessageHandler(wsChannel ws.Channel, data []byte) error {
 			callError := message.(*CallError)
 			log.Debugf("handling incoming CALL RESULT [%s] from %s", callError.UniqueId, wsChannel.ID())
 			s.dispatcher.CompleteRequest(wsChannel.ID(), callError.GetUniqueId())
+			ocppErr := ocpp.NewError(callError.ErrorCode, callError.ErrorDescription, callError.UniqueId)
+			if s.auditLog != nil {
+				s.auditLog.recordError(wsChannel.ID(), callError.UniqueId, AuditError, data, ocppErr)
+			}
+			s.notifyRequestCompleted(wsChannel.ID(), callError.UniqueId, RequestOutgoing, ocppErr)
 			if s.errorHandler != nil {
-				s.errorHandler(wsChannel, ocpp.NewError(callError.ErrorCode, callError.ErrorDescription, callError.UniqueId), callError.ErrorDetails)
+				s.errorHandler(wsChannel, ocppErr, callError.ErrorDetails)
 			}
 		}
 	}
@@ -304,7 +547,7 @@ func (s *Server) HandleFailedResponseError(clientID string, requestID string, er
 	case validator.ValidationErrors:
 		// Validation error
 		validationErr := err.(validator.ValidationErrors)
-		responseErr = errorFromValidation(validationErr, requestID, featureName)
+		responseErr = s.errorFromValidation(validationErr, requestID, featureName)
 	case *ocpp.Error:
 		// Internal OCPP error
 		responseErr = err.(*ocpp.Error)
@@ -318,6 +561,7 @@ func (s *Server) HandleFailedResponseError(clientID string, requestID string, er
 
 func (s *Server) onClientConnected(ws ws.Channel) {
 	// Create state for connected client
+	s.connections.Store(ws.ID(), ws)
 	s.dispatcher.CreateClient(ws.ID())
 	// Invoke callback
 	if s.newClientHandler != nil {
@@ -328,9 +572,44 @@ func (s *Server) onClientConnected(ws ws.Channel) {
 func (s *Server) onClientDisconnected(ws ws.Channel) {
 	// Clear state for disconnected client
 	s.dispatcher.DeleteClient(ws.ID())
+	s.clearPaused(ws.ID())
 	s.RequestState.ClearClientPendingRequest(ws.ID())
+	s.connections.Delete(ws.ID())
+	if s.auditLog != nil {
+		s.auditLog.recordDisconnect(ws.ID())
+	}
 	// Invoke callback
 	if s.disconnectedClientHandler != nil {
 		s.disconnectedClientHandler(ws)
 	}
 }
+
+func (s *Server) onRequestCanceled(clientID string, requestID string, request ocpp.Request, err *ocpp.Error) {
+	if s.auditLog != nil {
+		s.auditLog.recordError(clientID, requestID, AuditCanceled, nil, err)
+	}
+	s.notifyRequestCompleted(clientID, requestID, RequestOutgoing, err)
+	if s.canceledRequestHandler != nil {
+		s.canceledRequestHandler(clientID, requestID, request, err)
+	}
+}
+
+// Returns the context of a connected client, or an empty context if the client is unknown.
+func (s *Server) connectionContext(clientID string) context.Context {
+	if c, ok := s.connections.Load(clientID); ok {
+		return c.(ws.Channel).Context()
+	}
+	return context.Background()
+}
+
+func (s *Server) notifyRequestStarted(ctx context.Context, clientID string, requestID string, action string, direction RequestDirection) {
+	if s.requestObserver != nil {
+		s.requestObserver.OnRequestStarted(ctx, clientID, requestID, action, direction)
+	}
+}
+
+func (s *Server) notifyRequestCompleted(clientID string, requestID string, direction RequestDirection, err *ocpp.Error) {
+	if s.requestObserver != nil {
+		s.requestObserver.OnRequestCompleted(clientID, requestID, direction, err)
+	}
+}
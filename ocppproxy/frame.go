@@ -0,0 +1,98 @@
+package ocppproxy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+// Direction indicates in which direction a frame travels through the proxy.
+type Direction int
+
+const (
+	Upstream   Direction = iota // From the charging station to the upstream CSMS.
+	Downstream                  // From the upstream CSMS to the charging station.
+)
+
+func (d Direction) String() string {
+	if d == Upstream {
+		return "upstream"
+	}
+	return "downstream"
+}
+
+// Frame is a single OCPP-J message passing through the proxy. The payload is kept in its raw JSON encoding,
+// so frames of any OCPP version may be forwarded without knowing their schema.
+type Frame struct {
+	MessageType      ocppj.MessageType
+	UniqueID         string
+	Action           string          // The action of a CALL. For CALLRESULT and CALLERROR frames, the action of the matching CALL, if known.
+	Payload          json.RawMessage // The payload of a CALL or CALLRESULT, or the error details of a CALLERROR.
+	ErrorCode        string          // Only set for CALLERROR frames.
+	ErrorDescription string          // Only set for CALLERROR frames.
+}
+
+// Parses a raw OCPP-J message. The action of responses is not contained in the message and must be set by the caller.
+func parseFrame(data []byte) (*Frame, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, fmt.Errorf("invalid OCPP-J message: %w", err)
+	}
+	if len(elements) < 3 {
+		return nil, fmt.Errorf("invalid OCPP-J message: expected at least 3 elements, got %v", len(elements))
+	}
+	frame := &Frame{}
+	if err := json.Unmarshal(elements[0], &frame.MessageType); err != nil {
+		return nil, fmt.Errorf("invalid message type: %w", err)
+	}
+	if err := json.Unmarshal(elements[1], &frame.UniqueID); err != nil {
+		return nil, fmt.Errorf("invalid unique ID: %w", err)
+	}
+	switch frame.MessageType {
+	case ocppj.CALL:
+		if len(elements) != 4 {
+			return nil, fmt.Errorf("invalid CALL: expected 4 elements, got %v", len(elements))
+		}
+		if err := json.Unmarshal(elements[2], &frame.Action); err != nil {
+			return nil, fmt.Errorf("invalid action: %w", err)
+		}
+		frame.Payload = elements[3]
+	case ocppj.CALL_RESULT:
+		frame.Payload = elements[2]
+	case ocppj.CALL_ERROR:
+		if len(elements) < 4 {
+			return nil, fmt.Errorf("invalid CALLERROR: expected at least 4 elements, got %v", len(elements))
+		}
+		if err := json.Unmarshal(elements[2], &frame.ErrorCode); err != nil {
+			return nil, fmt.Errorf("invalid error code: %w", err)
+		}
+		if err := json.Unmarshal(elements[3], &frame.ErrorDescription); err != nil {
+			return nil, fmt.Errorf("invalid error description: %w", err)
+		}
+		if len(elements) > 4 {
+			frame.Payload = elements[4]
+		}
+	default:
+		return nil, fmt.Errorf("invalid message type %v", frame.MessageType)
+	}
+	return frame, nil
+}
+
+// Encodes the frame as OCPP-J message.
+func (f *Frame) marshal() ([]byte, error) {
+	payload := f.Payload
+	if len(payload) == 0 {
+		payload = json.RawMessage("{}")
+	}
+	switch f.MessageType {
+	case ocppj.CALL:
+		return json.Marshal([]interface{}{f.MessageType, f.UniqueID, f.Action, payload})
+	case ocppj.CALL_RESULT:
+		return json.Marshal([]interface{}{f.MessageType, f.UniqueID, payload})
+	case ocppj.CALL_ERROR:
+		return json.Marshal([]interface{}{f.MessageType, f.UniqueID, f.ErrorCode, f.ErrorDescription, payload})
+	default:
+		return nil, fmt.Errorf("invalid message type %v", f.MessageType)
+	}
+}
@@ -0,0 +1,348 @@
+// Package ocppproxy provides the building blocks for transparent OCPP proxies, which sit between charging stations
+// and an upstream CSMS, e.g. for routing stations to multiple backends or translating between protocol versions.
+//
+// A Proxy acts as websocket server towards charging stations. For every connected station, it opens a dedicated
+// connection to the upstream CSMS, using the same station ID, and forwards OCPP-J frames in both directions.
+// Frames are forwarded without being parsed into OCPP messages, hence any OCPP version is supported:
+//
+//	proxy := ocppproxy.NewProxy(nil, "ws://csms.example.com:8887/ocpp")
+//	proxy.SetInterceptor(func(stationID string, direction ocppproxy.Direction, frame *ocppproxy.Frame) bool {
+//		log.Printf("%v %v %v %v", stationID, direction, frame.Action, string(frame.Payload))
+//		return true
+//	})
+//	proxy.Start(8887, "/{ws}")
+//
+// The unique IDs of CALLs are remapped by the proxy and restored in the corresponding responses, so the proxy may inject
+// its own CALLs into a connection via Proxy.Call, without colliding with the IDs chosen by the endpoints.
+package ocppproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+
+	ocpp16types "github.com/lorenzodonini/ocpp-go/ocpp1.6/types"
+	ocpp2types "github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+// Interceptor is invoked for every frame forwarded by the proxy, after the unique ID was remapped.
+// The frame may be modified in place, e.g. for translating payloads, except for its message type and unique ID.
+// Returning false drops the frame.
+//
+// Frames are passed to the interceptor in the order in which they were received on a connection.
+// Interceptors may be invoked concurrently for different stations.
+type Interceptor func(stationID string, direction Direction, frame *Frame) bool
+
+// A CALL forwarded or sent by the proxy, which is waiting for a response.
+type pendingCall struct {
+	originalID string              // The unique ID chosen by the sender. Empty for CALLs injected by the proxy.
+	action     string              // The action of the CALL.
+	callback   func(*Frame, error) // Receives the response of a CALL injected by the proxy.
+}
+
+// session pairs the connection of a charging station with its upstream connection.
+type session struct {
+	stationID string
+	client    ws.WsClient
+	ready     chan struct{} // Closed once the upstream connection was established or failed.
+	err       error         // The error of the upstream connection attempt, valid once ready was closed.
+	mutex     sync.Mutex
+	pending   map[Direction]map[string]pendingCall // CALLs by the direction they were sent in, keyed by remapped unique ID.
+	closeOnce sync.Once
+}
+
+func (s *session) addPending(direction Direction, uniqueID string, call pendingCall) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending[direction][uniqueID] = call
+}
+
+func (s *session) takePending(direction Direction, uniqueID string) (pendingCall, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	call, ok := s.pending[direction][uniqueID]
+	delete(s.pending[direction], uniqueID)
+	return call, ok
+}
+
+// Proxy forwards OCPP-J frames between charging stations and an upstream CSMS. See the package documentation for details.
+type Proxy struct {
+	server        ws.WsServer
+	upstreamURL   string
+	clientFactory func(stationID string) ws.WsClient
+	interceptor   Interceptor
+	mutex         sync.Mutex
+	sessions      map[string]*session
+	nextID        uint64
+	errMutex      sync.RWMutex
+	errC          chan error
+}
+
+// NewProxy creates a proxy, which accepts charging stations on the given websocket server
+// and connects them to the upstream CSMS at upstreamURL. The ID of a station is appended to the upstream URL as final path element.
+// If no server is passed, a default websocket server is created.
+//
+// The proxy supports the OCPP 1.6, 2.0 and 2.0.1 subprotocols. The upstream connection requests the subprotocol
+// negotiated with the charging station.
+func NewProxy(server ws.WsServer, upstreamURL string) *Proxy {
+	if server == nil {
+		server = ws.NewServer()
+	}
+	server.AddSupportedSubprotocol(ocpp16types.V16Subprotocol)
+	server.AddSupportedSubprotocol(ocpp2types.V2Subprotocol)
+	server.AddSupportedSubprotocol(ocpp2types.V201Subprotocol)
+	p := &Proxy{
+		server:      server,
+		upstreamURL: strings.TrimSuffix(upstreamURL, "/"),
+		sessions:    map[string]*session{},
+	}
+	server.SetNewClientHandler(p.onStationConnected)
+	server.SetDisconnectedClientHandler(p.onStationDisconnected)
+	server.SetMessageHandler(p.onStationMessage)
+	return p
+}
+
+// SetUpstreamClientFactory sets a function creating the websocket client used for the upstream connection of a station,
+// e.g. for configuring TLS or basic auth credentials. By default, a client with the default configuration is created.
+func (p *Proxy) SetUpstreamClientFactory(factory func(stationID string) ws.WsClient) {
+	p.clientFactory = factory
+}
+
+// SetInterceptor sets a function, which may inspect, modify or drop every forwarded frame.
+func (p *Proxy) SetInterceptor(interceptor Interceptor) {
+	p.interceptor = interceptor
+}
+
+// Errors returns a channel for errors occurring while forwarding frames, e.g. malformed frames or failed upstream connections.
+// Errors are dropped, while nobody is receiving.
+func (p *Proxy) Errors() <-chan error {
+	p.errMutex.Lock()
+	defer p.errMutex.Unlock()
+	if p.errC == nil {
+		p.errC = make(chan error, 1)
+	}
+	return p.errC
+}
+
+func (p *Proxy) error(err error) {
+	p.errMutex.RLock()
+	defer p.errMutex.RUnlock()
+	if p.errC != nil {
+		select {
+		case p.errC <- err:
+		default:
+		}
+	}
+}
+
+// Start accepts charging stations on the given port and path. The function blocks until the proxy is stopped.
+func (p *Proxy) Start(listenPort int, listenPath string) {
+	p.server.Start(listenPort, listenPath)
+}
+
+// StartOnListener accepts charging stations on a pre-created listener. The function blocks until the proxy is stopped.
+func (p *Proxy) StartOnListener(listener net.Listener, listenPath string) error {
+	return p.server.StartOnListener(listener, listenPath)
+}
+
+// Stop disconnects all charging stations along with their upstream connections.
+func (p *Proxy) Stop() {
+	p.server.Stop()
+	p.mutex.Lock()
+	sessions := make([]*session, 0, len(p.sessions))
+	for _, s := range p.sessions {
+		sessions = append(sessions, s)
+	}
+	p.mutex.Unlock()
+	for _, s := range sessions {
+		p.closeSession(s)
+	}
+}
+
+// Call sends a CALL on behalf of the proxy to the charging station (Downstream) or to the upstream CSMS (Upstream).
+// The response isn't forwarded, but passed to the callback. If the connection is closed before a response was received,
+// the callback is invoked with an error.
+//
+// Returns an error, if the station isn't connected or the payload cannot be encoded.
+func (p *Proxy) Call(stationID string, direction Direction, action string, payload interface{}, callback func(response *Frame, err error)) error {
+	p.mutex.Lock()
+	s, ok := p.sessions[stationID]
+	p.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("station %v is not connected", stationID)
+	}
+	<-s.ready
+	if s.err != nil {
+		return fmt.Errorf("station %v has no upstream connection: %w", stationID, s.err)
+	}
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("couldn't encode payload of %v: %w", action, err)
+	}
+	frame := &Frame{MessageType: ocppj.CALL, UniqueID: p.newID(), Action: action, Payload: rawPayload}
+	s.addPending(direction, frame.UniqueID, pendingCall{action: action, callback: callback})
+	if err = p.send(s, direction, frame); err != nil {
+		s.takePending(direction, frame.UniqueID)
+		return err
+	}
+	return nil
+}
+
+func (p *Proxy) newID() string {
+	return strconv.FormatUint(atomic.AddUint64(&p.nextID, 1), 10)
+}
+
+func (p *Proxy) onStationConnected(channel ws.Channel) {
+	// The session is registered before connecting upstream, so frames received in the meantime wait for the connection
+	s := &session{
+		stationID: channel.ID(),
+		ready:     make(chan struct{}),
+		pending:   map[Direction]map[string]pendingCall{Upstream: {}, Downstream: {}},
+	}
+	p.mutex.Lock()
+	p.sessions[s.stationID] = s
+	p.mutex.Unlock()
+	var client ws.WsClient
+	if p.clientFactory != nil {
+		client = p.clientFactory(s.stationID)
+	} else {
+		client = ws.NewClient()
+	}
+	if c, ok := channel.(interface{ Subprotocol() string }); ok && c.Subprotocol() != "" {
+		client.SetRequestedSubProtocol(c.Subprotocol())
+	}
+	client.SetMessageHandler(func(data []byte) error {
+		p.forward(s, Downstream, data)
+		return nil
+	})
+	client.SetDisconnectedHandler(func(err error) {
+		go p.closeSession(s)
+	})
+	s.client = client
+	url := fmt.Sprintf("%v/%v", p.upstreamURL, s.stationID)
+	if query := channel.QueryParams().Encode(); query != "" {
+		url = fmt.Sprintf("%v?%v", url, query)
+	}
+	s.err = client.Start(url)
+	close(s.ready)
+	if s.err != nil {
+		p.error(fmt.Errorf("couldn't connect station %v to upstream: %w", s.stationID, s.err))
+		p.closeSession(s)
+	}
+}
+
+func (p *Proxy) onStationDisconnected(channel ws.Channel) {
+	p.mutex.Lock()
+	s, ok := p.sessions[channel.ID()]
+	p.mutex.Unlock()
+	if ok {
+		p.closeSession(s)
+	}
+}
+
+func (p *Proxy) onStationMessage(channel ws.Channel, data []byte) error {
+	p.mutex.Lock()
+	s, ok := p.sessions[channel.ID()]
+	p.mutex.Unlock()
+	if !ok {
+		// The session was already closed, or the station isn't registered yet
+		p.error(fmt.Errorf("dropping %v frame of station %v without session", Upstream, channel.ID()))
+		return nil
+	}
+	<-s.ready
+	if s.err != nil {
+		return nil
+	}
+	p.forward(s, Upstream, data)
+	return nil
+}
+
+// Closes both connections of a session. CALLs injected by the proxy, which are still pending, fail.
+func (p *Proxy) closeSession(s *session) {
+	s.closeOnce.Do(func() {
+		p.mutex.Lock()
+		if p.sessions[s.stationID] == s {
+			delete(p.sessions, s.stationID)
+		}
+		p.mutex.Unlock()
+		if s.client != nil {
+			s.client.Stop()
+		}
+		_ = p.server.StopConnection(s.stationID, websocket.CloseError{Code: websocket.CloseNormalClosure})
+		s.mutex.Lock()
+		var callbacks []func(*Frame, error)
+		for _, calls := range s.pending {
+			for _, call := range calls {
+				if call.callback != nil {
+					callbacks = append(callbacks, call.callback)
+				}
+			}
+		}
+		s.pending = map[Direction]map[string]pendingCall{Upstream: {}, Downstream: {}}
+		s.mutex.Unlock()
+		for _, callback := range callbacks {
+			callback(nil, fmt.Errorf("connection of station %v closed", s.stationID))
+		}
+	})
+}
+
+// Forwards a frame received from one endpoint of a session to the other one, remapping its unique ID.
+func (p *Proxy) forward(s *session, direction Direction, data []byte) {
+	frame, err := parseFrame(data)
+	if err != nil {
+		p.error(fmt.Errorf("dropping %v frame of station %v: %w", direction, s.stationID, err))
+		return
+	}
+	if frame.MessageType == ocppj.CALL {
+		originalID := frame.UniqueID
+		frame.UniqueID = p.newID()
+		if p.interceptor != nil && !p.interceptor(s.stationID, direction, frame) {
+			return
+		}
+		s.addPending(direction, frame.UniqueID, pendingCall{originalID: originalID, action: frame.Action})
+	} else {
+		// Responses travel in the opposite direction of their CALL
+		opposite := Upstream
+		if direction == Upstream {
+			opposite = Downstream
+		}
+		if call, ok := s.takePending(opposite, frame.UniqueID); ok {
+			frame.Action = call.action
+			if call.callback != nil {
+				call.callback(frame, nil)
+				return
+			}
+			frame.UniqueID = call.originalID
+		}
+		if p.interceptor != nil && !p.interceptor(s.stationID, direction, frame) {
+			return
+		}
+	}
+	if err = p.send(s, direction, frame); err != nil {
+		p.error(err)
+	}
+}
+
+func (p *Proxy) send(s *session, direction Direction, frame *Frame) error {
+	data, err := frame.marshal()
+	if err != nil {
+		return fmt.Errorf("couldn't encode %v frame for station %v: %w", direction, s.stationID, err)
+	}
+	if direction == Upstream {
+		err = s.client.Write(data)
+	} else {
+		err = p.server.Write(s.stationID, data)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't forward %v frame of station %v: %w", direction, s.stationID, err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package ocppproxy_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/remotecontrol"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ocppproxy"
+)
+
+type csmsProvisioningHandler struct {
+	provisioning.CSMSHandler
+	bootC chan string
+}
+
+func (h *csmsProvisioningHandler) OnBootNotification(chargingStationID string, request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
+	h.bootC <- chargingStationID
+	return provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 60, provisioning.RegistrationStatusAccepted), nil
+}
+
+type csmsAvailabilityHandler struct {
+	availability.CSMSHandler
+	heartbeatC chan string
+}
+
+func (h *csmsAvailabilityHandler) OnHeartbeat(chargingStationID string, request *availability.HeartbeatRequest) (*availability.HeartbeatResponse, error) {
+	h.heartbeatC <- chargingStationID
+	return availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil
+}
+
+type stationHandler struct {
+	remotecontrol.ChargingStationHandler
+}
+
+func (h *stationHandler) OnRequestStartTransaction(request *remotecontrol.RequestStartTransactionRequest) (*remotecontrol.RequestStartTransactionResponse, error) {
+	return remotecontrol.NewRequestStartTransactionResponse(remotecontrol.RequestStartStopStatusAccepted), nil
+}
+
+type recordedFrame struct {
+	stationID string
+	direction ocppproxy.Direction
+	frame     ocppproxy.Frame
+}
+
+type frameRecorder struct {
+	mutex  sync.Mutex
+	frames []recordedFrame
+}
+
+func (r *frameRecorder) intercept(stationID string, direction ocppproxy.Direction, frame *ocppproxy.Frame) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.frames = append(r.frames, recordedFrame{stationID: stationID, direction: direction, frame: *frame})
+	return true
+}
+
+func (r *frameRecorder) recorded() []recordedFrame {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]recordedFrame(nil), r.frames...)
+}
+
+type proxyEnvironment struct {
+	csms            ocpp2.CSMS
+	proxy           *ocppproxy.Proxy
+	chargingStation ocpp2.ChargingStation
+	provisioning    *csmsProvisioningHandler
+	availability    *csmsAvailabilityHandler
+	recorder        *frameRecorder
+}
+
+func listenLoopback(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return listener
+}
+
+// Starts a CSMS, a proxy forwarding to it and a charging station connected to the proxy.
+func newProxyEnvironment(t *testing.T, stationID string) *proxyEnvironment {
+	env := &proxyEnvironment{
+		csms:            ocpp2.NewCSMS(nil, nil),
+		provisioning:    &csmsProvisioningHandler{bootC: make(chan string, 1)},
+		availability:    &csmsAvailabilityHandler{heartbeatC: make(chan string, 1)},
+		recorder:        &frameRecorder{},
+		chargingStation: ocpp2.NewChargingStation(stationID, nil, nil),
+	}
+	env.csms.SetProvisioningHandler(env.provisioning)
+	env.csms.SetAvailabilityHandler(env.availability)
+	connectedC := make(chan string, 1)
+	env.csms.SetNewChargingStationHandler(func(chargingStation ocpp2.ChargingStationConnection) {
+		connectedC <- chargingStation.ID()
+	})
+	csmsListener := listenLoopback(t)
+	go func() { _ = env.csms.StartOnListener(csmsListener, "/{ws}") }()
+
+	env.proxy = ocppproxy.NewProxy(nil, fmt.Sprintf("ws://%v", csmsListener.Addr()))
+	env.proxy.SetInterceptor(env.recorder.intercept)
+	proxyListener := listenLoopback(t)
+	go func() { _ = env.proxy.StartOnListener(proxyListener, "/{ws}") }()
+
+	env.chargingStation.SetRemoteControlHandler(&stationHandler{})
+	require.NoError(t, env.chargingStation.Start(fmt.Sprintf("ws://%v", proxyListener.Addr())))
+	select {
+	case id := <-connectedC:
+		assert.Equal(t, stationID, id)
+	case <-time.After(3 * time.Second):
+		t.Fatal("proxy didn't connect the charging station upstream")
+	}
+	t.Cleanup(func() {
+		env.chargingStation.Stop()
+		env.proxy.Stop()
+		env.csms.Stop()
+	})
+	return env
+}
+
+func TestProxyForwardsBootNotification(t *testing.T) {
+	env := newProxyEnvironment(t, "station1")
+	response, err := env.chargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.NoError(t, err)
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, response.Status)
+	assert.Equal(t, 60, response.Interval)
+	select {
+	case id := <-env.provisioning.bootC:
+		assert.Equal(t, "station1", id)
+	case <-time.After(time.Second):
+		t.Fatal("CSMS didn't receive the boot notification")
+	}
+	frames := env.recorder.recorded()
+	require.Len(t, frames, 2)
+	request, result := frames[0], frames[1]
+	assert.Equal(t, "station1", request.stationID)
+	assert.Equal(t, ocppproxy.Upstream, request.direction)
+	assert.Equal(t, ocppj.CALL, request.frame.MessageType)
+	assert.Equal(t, provisioning.BootNotificationFeatureName, request.frame.Action)
+	var payload provisioning.BootNotificationRequest
+	require.NoError(t, json.Unmarshal(request.frame.Payload, &payload))
+	assert.Equal(t, "model1", payload.ChargingStation.Model)
+	assert.Equal(t, ocppproxy.Downstream, result.direction)
+	assert.Equal(t, ocppj.CALL_RESULT, result.frame.MessageType)
+	assert.Equal(t, provisioning.BootNotificationFeatureName, result.frame.Action)
+}
+
+func TestProxyForwardsCSMSRequest(t *testing.T) {
+	env := newProxyEnvironment(t, "station1")
+	resultC := make(chan *remotecontrol.RequestStartTransactionResponse, 1)
+	err := env.csms.RequestStartTransaction("station1", func(response *remotecontrol.RequestStartTransactionResponse, err error) {
+		assert.NoError(t, err)
+		resultC <- response
+	}, 1, types.IdToken{IdToken: "1234", Type: types.IdTokenTypeISO14443})
+	require.NoError(t, err)
+	select {
+	case response := <-resultC:
+		require.NotNil(t, response)
+		assert.Equal(t, remotecontrol.RequestStartStopStatusAccepted, response.Status)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no response to remote start received")
+	}
+	frames := env.recorder.recorded()
+	require.Len(t, frames, 2)
+	assert.Equal(t, ocppproxy.Downstream, frames[0].direction)
+	assert.Equal(t, remotecontrol.RequestStartTransactionFeatureName, frames[0].frame.Action)
+	assert.Equal(t, ocppproxy.Upstream, frames[1].direction)
+	assert.Equal(t, remotecontrol.RequestStartTransactionFeatureName, frames[1].frame.Action)
+}
+
+func TestProxyCallInjection(t *testing.T) {
+	env := newProxyEnvironment(t, "station1")
+	resultC := make(chan *ocppproxy.Frame, 1)
+	err := env.proxy.Call("station1", ocppproxy.Upstream, availability.HeartbeatFeatureName, availability.NewHeartbeatRequest(), func(response *ocppproxy.Frame, err error) {
+		assert.NoError(t, err)
+		resultC <- response
+	})
+	require.NoError(t, err)
+	select {
+	case id := <-env.availability.heartbeatC:
+		assert.Equal(t, "station1", id)
+	case <-time.After(time.Second):
+		t.Fatal("CSMS didn't receive the injected heartbeat")
+	}
+	select {
+	case response := <-resultC:
+		require.NotNil(t, response)
+		assert.Equal(t, ocppj.CALL_RESULT, response.MessageType)
+		assert.Equal(t, availability.HeartbeatFeatureName, response.Action)
+	case <-time.After(time.Second):
+		t.Fatal("no response to injected heartbeat received")
+	}
+	// Injected frames and their responses aren't forwarded, hence never reach the interceptor
+	assert.Empty(t, env.recorder.recorded())
+	err = env.proxy.Call("unknown", ocppproxy.Upstream, availability.HeartbeatFeatureName, availability.NewHeartbeatRequest(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,807 @@
+package ocpptest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+// memoryAddr is the address of both ends of an in-memory connection.
+type memoryAddr struct{}
+
+func (memoryAddr) Network() string { return "memory" }
+func (memoryAddr) String() string  { return "memory" }
+
+type memoryFrame struct {
+	binary bool
+	data   []byte
+}
+
+// memoryPipe delivers the frames of one direction of an in-memory connection in order, on a dedicated goroutine.
+// Writing to a pipe never blocks, as frames are queued until they are delivered.
+type memoryPipe struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queue   []memoryFrame
+	closed  bool
+	deliver func(frame memoryFrame)
+}
+
+func newMemoryPipe(deliver func(frame memoryFrame)) *memoryPipe {
+	pipe := &memoryPipe{deliver: deliver}
+	pipe.cond = sync.NewCond(&pipe.mutex)
+	go pipe.run()
+	return pipe
+}
+
+func (p *memoryPipe) send(frame memoryFrame) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return fmt.Errorf("in-memory connection is closed")
+	}
+	frame.data = append([]byte(nil), frame.data...)
+	p.queue = append(p.queue, frame)
+	p.cond.Signal()
+	return nil
+}
+
+func (p *memoryPipe) run() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for {
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if p.closed {
+			return
+		}
+		frame := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mutex.Unlock()
+		p.deliver(frame)
+		p.mutex.Lock()
+	}
+}
+
+// Stops the delivery goroutine. Frames that weren't delivered yet are dropped.
+func (p *memoryPipe) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	p.queue = nil
+	p.cond.Signal()
+}
+
+// memoryConnection is an established in-memory connection between a MemoryServer and a MemoryClient.
+// On the server side, it is passed to the handlers as the ws.Channel of the client.
+type memoryConnection struct {
+	id          string
+	queryParams url.Values
+	ctx         context.Context
+	cancel      context.CancelFunc
+	dataMutex   sync.RWMutex
+	data        map[string]interface{}
+	client      *MemoryClient
+	toServer    *memoryPipe
+	toClient    *memoryPipe
+	closeOnce   sync.Once
+}
+
+func (c *memoryConnection) ID() string {
+	return c.id
+}
+
+func (c *memoryConnection) RemoteAddr() net.Addr {
+	return memoryAddr{}
+}
+
+func (c *memoryConnection) TLSConnectionState() *tls.ConnectionState {
+	return nil
+}
+
+func (c *memoryConnection) Set(key string, value interface{}) {
+	c.dataMutex.Lock()
+	defer c.dataMutex.Unlock()
+	c.data[key] = value
+}
+
+func (c *memoryConnection) Get(key string) (interface{}, bool) {
+	c.dataMutex.RLock()
+	defer c.dataMutex.RUnlock()
+	value, ok := c.data[key]
+	return value, ok
+}
+
+func (c *memoryConnection) Delete(key string) {
+	c.dataMutex.Lock()
+	defer c.dataMutex.Unlock()
+	delete(c.data, key)
+}
+
+func (c *memoryConnection) Context() context.Context {
+	return c.ctx
+}
+
+func (c *memoryConnection) QueryParams() url.Values {
+	return c.queryParams
+}
+
+// Closes both directions of the connection. Returns false, if the connection was already closed.
+func (c *memoryConnection) close() bool {
+	closed := false
+	c.closeOnce.Do(func() {
+		c.toServer.close()
+		c.toClient.close()
+		c.cancel()
+		closed = true
+	})
+	return closed
+}
+
+// MemoryServer is a ws.WsServer, which accepts connections from MemoryClient instances created via NewClient.
+// Messages are passed in-process, without involving the network stack, hence no port or listener is required.
+//
+// Incoming connections are checked by the basic auth, check origin, check client and connection authorizer handlers,
+// which receive a synthetic upgrade request. The charging station ID is the final element of the path of the URL
+// passed to MemoryClient.Start, while listen paths are ignored.
+type MemoryServer struct {
+	mutex                 sync.RWMutex
+	startedC              chan struct{}
+	stopC                 chan struct{}
+	connections           map[string]*memoryConnection
+	supportedSubprotocols []string
+	messageHandler        func(ws ws.Channel, data []byte) error
+	binaryHandler         func(ws ws.Channel, data []byte) error
+	newClientHandler      func(ws ws.Channel)
+	disconnectedHandler   func(ws ws.Channel)
+	basicAuthHandler      func(username string, password string) bool
+	checkOriginHandler    func(r *http.Request) bool
+	checkClientHandler    func(id string, r *http.Request) bool
+	authorizer            ws.ConnectionAuthorizer
+	errC                  chan error
+}
+
+// NewMemoryServer creates a new in-memory websocket server. The server accepts connections once started.
+func NewMemoryServer() *MemoryServer {
+	return &MemoryServer{
+		startedC:    make(chan struct{}),
+		connections: map[string]*memoryConnection{},
+	}
+}
+
+// NewClient creates a new in-memory websocket client, which connects to the server when started.
+func (s *MemoryServer) NewClient() *MemoryClient {
+	return &MemoryClient{
+		server:        s,
+		timeoutConfig: ws.NewClientTimeoutConfig(),
+		header:        http.Header{},
+	}
+}
+
+func (s *MemoryServer) Start(port int, listenPath string) {
+	_ = s.StartWithContext(context.Background(), port, listenPath)
+}
+
+func (s *MemoryServer) StartWithContext(ctx context.Context, port int, listenPath string) error {
+	s.mutex.Lock()
+	if s.stopC != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("in-memory server is already running")
+	}
+	stopC := make(chan struct{})
+	s.stopC = stopC
+	close(s.startedC)
+	s.mutex.Unlock()
+	select {
+	case <-ctx.Done():
+		s.Stop()
+	case <-stopC:
+	}
+	return nil
+}
+
+// StartOnListener starts the server without using the listener, which is left untouched.
+func (s *MemoryServer) StartOnListener(listener net.Listener, listenPath string) error {
+	return s.StartWithContext(context.Background(), 0, listenPath)
+}
+
+// AddListenPath is a no-op, as the in-memory server accepts connections on any path.
+func (s *MemoryServer) AddListenPath(listenPath string) {
+}
+
+// Stop closes all connections and stops accepting new ones. Connected clients are notified of the disconnection.
+func (s *MemoryServer) Stop() {
+	s.mutex.Lock()
+	if s.stopC == nil {
+		s.mutex.Unlock()
+		return
+	}
+	close(s.stopC)
+	s.stopC = nil
+	s.startedC = make(chan struct{})
+	connections := s.connections
+	s.connections = map[string]*memoryConnection{}
+	s.mutex.Unlock()
+	for _, c := range connections {
+		s.disconnect(c, &websocket.CloseError{Code: websocket.CloseNormalClosure})
+	}
+	s.mutex.Lock()
+	if s.errC != nil {
+		close(s.errC)
+		s.errC = nil
+	}
+	s.mutex.Unlock()
+}
+
+func (s *MemoryServer) StopConnection(id string, closeError websocket.CloseError) error {
+	s.mutex.Lock()
+	c, ok := s.connections[id]
+	if ok {
+		delete(s.connections, id)
+	}
+	s.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("couldn't stop in-memory connection. No connection with id %s is open", id)
+	}
+	s.disconnect(c, &closeError)
+	return nil
+}
+
+// Closes a connection, which was already removed from the connections, and notifies both sides.
+func (s *MemoryServer) disconnect(c *memoryConnection, closeError *websocket.CloseError) {
+	if !c.close() {
+		return
+	}
+	s.mutex.RLock()
+	handler := s.disconnectedHandler
+	s.mutex.RUnlock()
+	if handler != nil {
+		handler(c)
+	}
+	c.client.connectionClosed(c, closeError)
+}
+
+// Admits a new connection from the client, as the websocket server would during the upgrade.
+// Waits for the server to be started, at most for the handshake timeout.
+func (s *MemoryServer) accept(client *MemoryClient, u *url.URL, header http.Header, subProtocol string, handshakeTimeout time.Duration) (*memoryConnection, error) {
+	s.mutex.RLock()
+	startedC := s.startedC
+	s.mutex.RUnlock()
+	select {
+	case <-startedC:
+	case <-time.After(handshakeTimeout):
+		return nil, fmt.Errorf("in-memory server wasn't started within %v", handshakeTimeout)
+	}
+	id := path.Base(u.Path)
+	r, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header
+	r.RemoteAddr = memoryAddr{}.String()
+	s.mutex.RLock()
+	subProtocols := s.supportedSubprotocols
+	basicAuthHandler := s.basicAuthHandler
+	checkOriginHandler := s.checkOriginHandler
+	checkClientHandler := s.checkClientHandler
+	authorizer := s.authorizer
+	s.mutex.RUnlock()
+	if basicAuthHandler != nil {
+		username, password, ok := r.BasicAuth()
+		if !ok || !basicAuthHandler(username, password) {
+			s.error(fmt.Errorf("basic auth failed: credentials invalid"))
+			return nil, ws.HttpConnectionError{Message: "basic auth failed", HttpStatus: "401 Unauthorized", HttpCode: http.StatusUnauthorized}
+		}
+	}
+	if checkOriginHandler != nil && !checkOriginHandler(r) {
+		s.error(fmt.Errorf("origin check failed for %s", id))
+		return nil, ws.HttpConnectionError{Message: "origin check failed", HttpStatus: "403 Forbidden", HttpCode: http.StatusForbidden}
+	}
+	if checkClientHandler != nil && !checkClientHandler(id, r) {
+		s.error(fmt.Errorf("client validation: invalid client"))
+		return nil, ws.HttpConnectionError{Message: "client validation failed", HttpStatus: "401 Unauthorized", HttpCode: http.StatusUnauthorized}
+	}
+	ctx := context.Background()
+	if authorizer != nil {
+		accept, authorizedCtx, err := authorizer(r)
+		if err != nil {
+			s.error(fmt.Errorf("connection authorization failed for %s: %w", id, err))
+			return nil, ws.HttpConnectionError{Message: err.Error(), HttpStatus: "500 Internal Server Error", HttpCode: http.StatusInternalServerError}
+		}
+		if !accept {
+			s.error(fmt.Errorf("connection authorization: connection denied for %s", id))
+			return nil, ws.HttpConnectionError{Message: "connection denied", HttpStatus: "403 Forbidden", HttpCode: http.StatusForbidden}
+		}
+		if authorizedCtx != nil {
+			ctx = authorizedCtx
+		}
+	}
+	if len(subProtocols) > 0 && !containsString(subProtocols, subProtocol) {
+		s.error(fmt.Errorf("unsupported subprotocol %v for new client %v", subProtocol, id))
+		return nil, fmt.Errorf("invalid or unsupported subprotocol %v", subProtocol)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := &memoryConnection{
+		id:          id,
+		queryParams: u.Query(),
+		ctx:         ctx,
+		cancel:      cancel,
+		data:        map[string]interface{}{},
+		client:      client,
+	}
+	c.toServer = newMemoryPipe(func(frame memoryFrame) { s.handle(c, frame) })
+	c.toClient = newMemoryPipe(func(frame memoryFrame) { client.handle(frame) })
+	s.mutex.Lock()
+	if s.stopC == nil {
+		s.mutex.Unlock()
+		c.close()
+		return nil, fmt.Errorf("in-memory server was stopped")
+	}
+	if _, exists := s.connections[id]; exists {
+		s.mutex.Unlock()
+		c.close()
+		s.error(fmt.Errorf("client %s already exists, closing duplicate client", id))
+		return nil, fmt.Errorf("a connection with ID %s already exists", id)
+	}
+	s.connections[id] = c
+	newClientHandler := s.newClientHandler
+	s.mutex.Unlock()
+	if newClientHandler != nil {
+		newClientHandler(c)
+	}
+	return c, nil
+}
+
+// Invokes the message handler for a frame received from a client.
+func (s *MemoryServer) handle(c *memoryConnection, frame memoryFrame) {
+	s.mutex.RLock()
+	handler := s.messageHandler
+	if frame.binary && s.binaryHandler != nil {
+		handler = s.binaryHandler
+	}
+	s.mutex.RUnlock()
+	if handler != nil {
+		if err := handler(c, frame.data); err != nil {
+			s.error(fmt.Errorf("handling failed for %s: %w", c.ID(), err))
+		}
+	}
+}
+
+// Removes a connection, which was closed by the client.
+func (s *MemoryServer) clientClosed(c *memoryConnection) {
+	s.mutex.Lock()
+	if s.connections[c.id] == c {
+		delete(s.connections, c.id)
+	}
+	handler := s.disconnectedHandler
+	s.mutex.Unlock()
+	if c.close() && handler != nil {
+		handler(c)
+	}
+}
+
+// Errors returns a channel for errors occurring on the server. Errors are dropped, while nobody is receiving.
+func (s *MemoryServer) Errors() <-chan error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.errC == nil {
+		s.errC = make(chan error, 1)
+	}
+	return s.errC
+}
+
+func (s *MemoryServer) error(err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.errC != nil {
+		select {
+		case s.errC <- err:
+		default:
+		}
+	}
+}
+
+func (s *MemoryServer) SetMessageHandler(handler func(ws ws.Channel, data []byte) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messageHandler = handler
+}
+
+func (s *MemoryServer) SetBinaryMessageHandler(handler func(ws ws.Channel, data []byte) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.binaryHandler = handler
+}
+
+func (s *MemoryServer) SetNewClientHandler(handler func(ws ws.Channel)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.newClientHandler = handler
+}
+
+func (s *MemoryServer) SetDisconnectedClientHandler(handler func(ws ws.Channel)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.disconnectedHandler = handler
+}
+
+// SetTimeoutConfig is a no-op, as in-memory connections don't time out.
+func (s *MemoryServer) SetTimeoutConfig(config ws.ServerTimeoutConfig) {
+}
+
+// SetCloseTimeout is a no-op, as in-memory connections are closed immediately.
+func (s *MemoryServer) SetCloseTimeout(d time.Duration) {
+}
+
+// SetHandshakeTimeout is a no-op. The handshake timeout is set on the client instead.
+func (s *MemoryServer) SetHandshakeTimeout(d time.Duration) {
+}
+
+func (s *MemoryServer) Write(webSocketId string, data []byte) error {
+	return s.write(webSocketId, memoryFrame{data: data})
+}
+
+func (s *MemoryServer) WriteBinary(webSocketId string, data []byte) error {
+	return s.write(webSocketId, memoryFrame{binary: true, data: data})
+}
+
+func (s *MemoryServer) write(webSocketId string, frame memoryFrame) error {
+	s.mutex.RLock()
+	c, ok := s.connections[webSocketId]
+	s.mutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("couldn't write to websocket. No socket with id %v is open", webSocketId)
+	}
+	return c.toClient.send(frame)
+}
+
+func (s *MemoryServer) AddSupportedSubprotocol(subProto string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, sub := range s.supportedSubprotocols {
+		if sub == subProto {
+			return
+		}
+	}
+	s.supportedSubprotocols = append(s.supportedSubprotocols, subProto)
+}
+
+func (s *MemoryServer) SetBasicAuthHandler(handler func(username string, password string) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.basicAuthHandler = handler
+}
+
+func (s *MemoryServer) SetCheckOriginHandler(handler func(r *http.Request) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.checkOriginHandler = handler
+}
+
+func (s *MemoryServer) SetCheckClientHandler(handler func(id string, r *http.Request) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.checkClientHandler = handler
+}
+
+func (s *MemoryServer) SetConnectionAuthorizer(handler ws.ConnectionAuthorizer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.authorizer = handler
+}
+
+// Addr always returns nil, as the in-memory server doesn't listen on a TCP port.
+func (s *MemoryServer) Addr() *net.TCPAddr {
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryClient is a ws.WsClient, which connects to the MemoryServer it was created by.
+// Messages are passed in-process, without involving the network stack.
+//
+// As a regular websocket client, it attempts to reconnect automatically if the server closes the connection,
+// waiting RetryBackOffWaitMinimum of the timeout config between attempts.
+type MemoryClient struct {
+	server             *MemoryServer
+	mutex              sync.RWMutex
+	connection         *memoryConnection
+	url                *url.URL
+	stopC              chan struct{}
+	messageHandler     func(data []byte) error
+	binaryHandler      func(data []byte) error
+	onDisconnected     func(err error)
+	onReconnected      func()
+	onReconnectAttempt func(attempt int, nextDelay time.Duration)
+	timeoutConfig      ws.ClientTimeoutConfig
+	subProtocol        string
+	header             http.Header
+	errC               chan error
+}
+
+func (c *MemoryClient) Start(urlStr string) error {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	if c.stopC == nil {
+		c.stopC = make(chan struct{})
+	}
+	c.url = u
+	c.mutex.Unlock()
+	return c.connect()
+}
+
+// StartWithRetries attempts to connect until it succeeds or the client is stopped.
+func (c *MemoryClient) StartWithRetries(urlStr string) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		c.error(err)
+		return
+	}
+	c.mutex.Lock()
+	if c.stopC == nil {
+		c.stopC = make(chan struct{})
+	}
+	c.url = u
+	stopC := c.stopC
+	c.mutex.Unlock()
+	for {
+		err = c.connect()
+		if err == nil {
+			return
+		}
+		c.error(err)
+		select {
+		case <-time.After(c.retryDelay()):
+		case <-stopC:
+			return
+		}
+	}
+}
+
+func (c *MemoryClient) connect() error {
+	c.mutex.RLock()
+	u := *c.url
+	header := c.header.Clone()
+	subProtocol := c.subProtocol
+	handshakeTimeout := c.timeoutConfig.HandshakeTimeout
+	c.mutex.RUnlock()
+	if subProtocol != "" {
+		header.Set("Sec-WebSocket-Protocol", subProtocol)
+	}
+	connection, err := c.server.accept(c, &u, header, subProtocol, handshakeTimeout)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.connection = connection
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *MemoryClient) retryDelay() time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.timeoutConfig.RetryBackOffWaitMinimum
+}
+
+// Invoked by the server, after it closed the connection. Starts the reconnection, unless the client was stopped.
+func (c *MemoryClient) connectionClosed(connection *memoryConnection, closeError *websocket.CloseError) {
+	c.mutex.Lock()
+	if c.connection != connection {
+		c.mutex.Unlock()
+		return
+	}
+	c.connection = nil
+	stopC := c.stopC
+	onDisconnected := c.onDisconnected
+	c.mutex.Unlock()
+	if onDisconnected != nil {
+		onDisconnected(closeError)
+	}
+	go c.reconnect(stopC)
+}
+
+func (c *MemoryClient) reconnect(stopC chan struct{}) {
+	for attempt := 1; ; attempt++ {
+		delay := c.retryDelay()
+		c.mutex.RLock()
+		onReconnectAttempt := c.onReconnectAttempt
+		c.mutex.RUnlock()
+		if onReconnectAttempt != nil {
+			onReconnectAttempt(attempt, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-stopC:
+			return
+		}
+		if err := c.connect(); err != nil {
+			c.error(fmt.Errorf("reconnection failed: %w", err))
+			continue
+		}
+		c.mutex.RLock()
+		onReconnected := c.onReconnected
+		c.mutex.RUnlock()
+		if onReconnected != nil {
+			onReconnected()
+		}
+		return
+	}
+}
+
+// Invokes the message handler for a frame received from the server.
+func (c *MemoryClient) handle(frame memoryFrame) {
+	c.mutex.RLock()
+	handler := c.messageHandler
+	if frame.binary && c.binaryHandler != nil {
+		handler = c.binaryHandler
+	}
+	c.mutex.RUnlock()
+	if handler != nil {
+		if err := handler(frame.data); err != nil {
+			c.error(fmt.Errorf("handle failed: %w", err))
+		}
+	}
+}
+
+// Stop closes the connection and stops any reconnection attempts.
+// As for the websocket client, the disconnected handler is invoked with a nil error, if the client was connected.
+func (c *MemoryClient) Stop() {
+	c.mutex.Lock()
+	connection := c.connection
+	c.connection = nil
+	if c.stopC != nil {
+		close(c.stopC)
+		c.stopC = nil
+	}
+	onDisconnected := c.onDisconnected
+	c.mutex.Unlock()
+	if connection != nil {
+		c.server.clientClosed(connection)
+		if onDisconnected != nil {
+			onDisconnected(nil)
+		}
+	}
+	c.mutex.Lock()
+	if c.errC != nil {
+		close(c.errC)
+		c.errC = nil
+	}
+	c.mutex.Unlock()
+}
+
+// Errors returns a channel for errors occurring on the client. Errors are dropped, while nobody is receiving.
+func (c *MemoryClient) Errors() <-chan error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.errC == nil {
+		c.errC = make(chan error, 1)
+	}
+	return c.errC
+}
+
+func (c *MemoryClient) error(err error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.errC != nil {
+		select {
+		case c.errC <- err:
+		default:
+		}
+	}
+}
+
+func (c *MemoryClient) SetMessageHandler(handler func(data []byte) error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.messageHandler = handler
+}
+
+func (c *MemoryClient) SetBinaryMessageHandler(handler func(data []byte) error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.binaryHandler = handler
+}
+
+// SetTimeoutConfig sets the handshake timeout and the delay between reconnection attempts. Other values are ignored.
+func (c *MemoryClient) SetTimeoutConfig(config ws.ClientTimeoutConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timeoutConfig = config
+}
+
+// SetCloseTimeout is a no-op, as in-memory connections are closed immediately.
+func (c *MemoryClient) SetCloseTimeout(d time.Duration) {
+}
+
+func (c *MemoryClient) SetHandshakeTimeout(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timeoutConfig.HandshakeTimeout = d
+}
+
+func (c *MemoryClient) SetDisconnectedHandler(handler func(err error)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onDisconnected = handler
+}
+
+func (c *MemoryClient) SetReconnectedHandler(handler func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onReconnected = handler
+}
+
+func (c *MemoryClient) SetReconnectAttemptHandler(handler func(attempt int, nextDelay time.Duration)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onReconnectAttempt = handler
+}
+
+func (c *MemoryClient) IsConnected() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.connection != nil
+}
+
+func (c *MemoryClient) Write(data []byte) error {
+	return c.write(memoryFrame{data: data})
+}
+
+func (c *MemoryClient) WriteBinary(data []byte) error {
+	return c.write(memoryFrame{binary: true, data: data})
+}
+
+func (c *MemoryClient) write(frame memoryFrame) error {
+	c.mutex.RLock()
+	connection := c.connection
+	c.mutex.RUnlock()
+	if connection == nil {
+		return fmt.Errorf("client is currently not connected, cannot send data")
+	}
+	return connection.toServer.send(frame)
+}
+
+// AddOption is a no-op, as there is no dialer to configure.
+func (c *MemoryClient) AddOption(option interface{}) {
+}
+
+func (c *MemoryClient) SetRequestedSubProtocol(subProto string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.subProtocol = subProto
+}
+
+func (c *MemoryClient) SetBasicAuth(username string, password string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	r := http.Request{Header: c.header}
+	r.SetBasicAuth(username, password)
+}
+
+func (c *MemoryClient) SetHeaderValue(key string, value string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.header.Set(key, value)
+}
@@ -0,0 +1,86 @@
+package ocpptest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpptest"
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+func TestMemoryTransportMessagesAndReconnection(t *testing.T) {
+	server := ocpptest.NewMemoryServer()
+	connectedC := make(chan ws.Channel, 2)
+	serverMessageC := make(chan string, 1)
+	server.SetNewClientHandler(func(channel ws.Channel) {
+		connectedC <- channel
+	})
+	server.SetMessageHandler(func(channel ws.Channel, data []byte) error {
+		serverMessageC <- string(data)
+		return nil
+	})
+	server.SetBasicAuthHandler(func(username string, password string) bool {
+		return username == "station1" && password == "secret"
+	})
+	go server.Start(0, "/{ws}")
+	defer server.Stop()
+	// Invalid credentials are rejected
+	client := server.NewClient()
+	client.SetBasicAuth("station1", "wrong")
+	err := client.Start("ws://ocpptest/station1")
+	require.Error(t, err)
+	httpErr, ok := err.(ws.HttpConnectionError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, httpErr.HttpCode)
+	// Valid connection
+	client = server.NewClient()
+	config := ws.NewClientTimeoutConfig()
+	config.RetryBackOffWaitMinimum = 10 * time.Millisecond
+	client.SetTimeoutConfig(config)
+	client.SetBasicAuth("station1", "secret")
+	clientMessageC := make(chan string, 1)
+	client.SetMessageHandler(func(data []byte) error {
+		clientMessageC <- string(data)
+		return nil
+	})
+	disconnectedC := make(chan error, 1)
+	client.SetDisconnectedHandler(func(err error) {
+		disconnectedC <- err
+	})
+	reconnectedC := make(chan struct{}, 1)
+	client.SetReconnectedHandler(func() {
+		reconnectedC <- struct{}{}
+	})
+	require.NoError(t, client.Start("ws://ocpptest/station1?foo=bar"))
+	channel := <-connectedC
+	assert.Equal(t, "station1", channel.ID())
+	assert.Equal(t, "bar", channel.QueryParams().Get("foo"))
+	assert.True(t, client.IsConnected())
+	// Messages are delivered in both directions
+	require.NoError(t, client.Write([]byte("ping")))
+	assert.Equal(t, "ping", <-serverMessageC)
+	require.NoError(t, server.Write("station1", []byte("pong")))
+	assert.Equal(t, "pong", <-clientMessageC)
+	// The client reconnects after the server closed the connection
+	require.NoError(t, server.StopConnection("station1", websocket.CloseError{Code: websocket.CloseGoingAway}))
+	closeErr, ok := (<-disconnectedC).(*websocket.CloseError)
+	require.True(t, ok)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+	assert.Error(t, channel.Context().Err())
+	select {
+	case <-reconnectedC:
+	case <-time.After(time.Second):
+		t.Fatal("client didn't reconnect")
+	}
+	assert.Equal(t, "station1", (<-connectedC).ID())
+	// Stopping the client doesn't trigger a reconnection
+	client.Stop()
+	assert.Nil(t, <-disconnectedC)
+	assert.False(t, client.IsConnected())
+	assert.Error(t, server.Write("station1", []byte("pong")))
+}
@@ -0,0 +1,147 @@
+package ocpptest
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+// NetworkConditions describes the simulated network between the CSMS and the charging station of a Pair.
+// The conditions are applied to every message, in both directions. Messages are never reordered.
+//
+// The zero value doesn't alter the message delivery.
+type NetworkConditions struct {
+	Latency   time.Duration // Delay added to every message.
+	Jitter    time.Duration // Maximum random delay added to the latency of every message.
+	LossRate  float64       // Probability between 0 and 1, with which a message is dropped silently.
+	Bandwidth int           // Maximum throughput in bytes per second. Zero means unlimited.
+	Seed      int64         // Seed of the random source used for jitter and loss, making scenarios reproducible.
+}
+
+func (c NetworkConditions) isZero() bool {
+	return c.Latency <= 0 && c.Jitter <= 0 && c.LossRate <= 0 && c.Bandwidth <= 0
+}
+
+type delayedMessage struct {
+	deliverAt time.Time
+	deliver   func()
+}
+
+// networkLink delivers the messages of one direction according to the configured network conditions.
+// Delayed messages are delivered in order by a dedicated goroutine.
+type networkLink struct {
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	conditions NetworkConditions
+	random     *rand.Rand
+	busyUntil  time.Time // Time at which the previous message was fully transmitted, when the bandwidth is limited.
+	queue      []delayedMessage
+	delivering bool
+	closed     bool
+}
+
+func newNetworkLink() *networkLink {
+	link := &networkLink{random: rand.New(rand.NewSource(0))}
+	link.cond = sync.NewCond(&link.mutex)
+	go link.run()
+	return link
+}
+
+// Applies new conditions. The random source is reset to the given seed, which may differ from the one in the conditions,
+// so both directions of a pair don't drop the same messages.
+func (l *networkLink) setConditions(conditions NetworkConditions, seed int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.conditions = conditions
+	l.random = rand.New(rand.NewSource(seed))
+	l.busyUntil = time.Time{}
+}
+
+// Sends a message of the given size over the link. If the message isn't dropped, deliver is invoked at the simulated arrival time.
+// Returns the result of deliver, if the message was delivered immediately.
+func (l *networkLink) send(size int, deliver func() error) error {
+	l.mutex.Lock()
+	conditions := l.conditions
+	// Messages may only bypass the queue, if no delayed messages are left, so they aren't reordered
+	if l.closed || (conditions.isZero() && len(l.queue) == 0 && !l.delivering) {
+		l.mutex.Unlock()
+		return deliver()
+	}
+	defer l.mutex.Unlock()
+	if conditions.LossRate > 0 && l.random.Float64() < conditions.LossRate {
+		return nil
+	}
+	sentAt := time.Now()
+	if conditions.Bandwidth > 0 {
+		if l.busyUntil.After(sentAt) {
+			sentAt = l.busyUntil
+		}
+		sentAt = sentAt.Add(time.Duration(size) * time.Second / time.Duration(conditions.Bandwidth))
+		l.busyUntil = sentAt
+	}
+	delay := conditions.Latency
+	if conditions.Jitter > 0 {
+		delay += time.Duration(l.random.Int63n(int64(conditions.Jitter) + 1))
+	}
+	l.queue = append(l.queue, delayedMessage{deliverAt: sentAt.Add(delay), deliver: func() { _ = deliver() }})
+	l.cond.Signal()
+	return nil
+}
+
+func (l *networkLink) run() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for {
+		for len(l.queue) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if l.closed {
+			return
+		}
+		message := l.queue[0]
+		l.queue = l.queue[1:]
+		l.delivering = true
+		l.mutex.Unlock()
+		if wait := time.Until(message.deliverAt); wait > 0 {
+			time.Sleep(wait)
+		}
+		message.deliver()
+		l.mutex.Lock()
+		l.delivering = false
+	}
+}
+
+// Stops the delivery goroutine. Messages that weren't delivered yet are dropped, messages sent afterwards are delivered immediately.
+func (l *networkLink) close() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.closed = true
+	l.queue = nil
+	l.cond.Signal()
+}
+
+// networkServer applies network conditions to all messages written by the CSMS.
+type networkServer struct {
+	ws.WsServer
+	link *networkLink
+}
+
+func (s *networkServer) Write(webSocketId string, data []byte) error {
+	return s.link.send(len(data), func() error {
+		return s.WsServer.Write(webSocketId, data)
+	})
+}
+
+// networkClient applies network conditions to all messages written by the charging station.
+type networkClient struct {
+	ws.WsClient
+	link *networkLink
+}
+
+func (c *networkClient) Write(data []byte) error {
+	return c.link.send(len(data), func() error {
+		return c.WsClient.Write(data)
+	})
+}
@@ -0,0 +1,108 @@
+// Package ocpptest provides utilities for end-to-end testing of OCPP 2.0.1 handlers.
+//
+// A Pair consists of a CSMS and a charging station, which run in-process and are connected to each other
+// via an in-memory transport (see MemoryServer and MemoryClient), without opening any port.
+// Both sides are real endpoints, hence handlers for both sides may be attached and full message flows
+// may be tested in a few lines:
+//
+//	pair, err := ocpptest.NewPair("station1")
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer pair.Close()
+//	pair.CSMS.SetProvisioningHandler(csmsProvisioningHandler)
+//	response, err := pair.ChargingStation.BootNotification(provisioning.BootReasonPowerUp, "model", "vendor")
+//
+// Handlers should be attached before sending the first message that requires them.
+//
+// Flaky networks may be simulated via Pair.SetNetworkConditions, e.g. for testing timeout and retry logic:
+//
+//	pair.SetNetworkConditions(ocpptest.NetworkConditions{Latency: 200 * time.Millisecond, LossRate: 0.1, Seed: 1})
+package ocpptest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1"
+)
+
+// URL the charging station of a Pair connects to. The in-memory transport only evaluates the charging station ID.
+const pairURL = "ws://ocpptest"
+
+// Maximum time to wait for the charging station to be connected to the CSMS.
+const connectTimeout = 5 * time.Second
+
+// Pair is a CSMS and a charging station, connected to each other.
+type Pair struct {
+	CSMS            ocpp2.CSMS
+	ChargingStation ocpp2.ChargingStation
+	StationID       string
+	URL             string // The URL the charging station connects to, without the charging station ID.
+	serverDoneC     chan struct{}
+	csmsLink        *networkLink // Messages sent by the CSMS.
+	stationLink     *networkLink // Messages sent by the charging station.
+}
+
+// NewPair creates a CSMS and a charging station with the given ID, which is connected to the CSMS
+// via an in-memory transport. The function returns once the CSMS accepted the connection.
+//
+// Both endpoints use the default configuration. Close should be invoked once the pair isn't needed anymore.
+func NewPair(stationID string) (*Pair, error) {
+	server := NewMemoryServer()
+	csmsLink := newNetworkLink()
+	stationLink := newNetworkLink()
+	pair := &Pair{
+		CSMS:            ocpp2.NewCSMS(nil, &networkServer{WsServer: server, link: csmsLink}),
+		ChargingStation: ocpp2.NewChargingStation(stationID, nil, &networkClient{WsClient: server.NewClient(), link: stationLink}),
+		StationID:       stationID,
+		URL:             pairURL,
+		serverDoneC:     make(chan struct{}),
+		csmsLink:        csmsLink,
+		stationLink:     stationLink,
+	}
+	connectedC := make(chan struct{}, 1)
+	pair.CSMS.SetNewChargingStationHandler(func(chargingStation ocpp2.ChargingStationConnection) {
+		if chargingStation.ID() == stationID {
+			connectedC <- struct{}{}
+		}
+	})
+	go func() {
+		pair.CSMS.Start(0, "/{ws}")
+		close(pair.serverDoneC)
+	}()
+	if err := pair.ChargingStation.Start(pair.URL); err != nil {
+		pair.CSMS.Stop()
+		pair.closeLinks()
+		return nil, fmt.Errorf("couldn't connect charging station %v: %w", stationID, err)
+	}
+	select {
+	case <-connectedC:
+	case <-time.After(connectTimeout):
+		pair.Close()
+		return nil, fmt.Errorf("charging station %v wasn't connected within %v", stationID, connectTimeout)
+	}
+	pair.CSMS.SetNewChargingStationHandler(nil)
+	return pair, nil
+}
+
+// SetNetworkConditions simulates the given network conditions between the CSMS and the charging station.
+// The conditions apply to all messages sent afterwards, in both directions. Messages that are already delayed aren't affected.
+// Setting the conditions resets the random sources of both directions, which are derived from the configured seed.
+func (p *Pair) SetNetworkConditions(conditions NetworkConditions) {
+	p.csmsLink.setConditions(conditions, conditions.Seed)
+	p.stationLink.setConditions(conditions, ^conditions.Seed)
+}
+
+// Close disconnects the charging station and stops the CSMS.
+func (p *Pair) Close() {
+	p.ChargingStation.Stop()
+	p.CSMS.Stop()
+	<-p.serverDoneC
+	p.closeLinks()
+}
+
+func (p *Pair) closeLinks() {
+	p.csmsLink.close()
+	p.stationLink.close()
+}
@@ -0,0 +1,147 @@
+package ocpptest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/availability"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/provisioning"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/remotecontrol"
+	"github.com/lorenzodonini/ocpp-go/ocpp2.0.1/types"
+	"github.com/lorenzodonini/ocpp-go/ocpptest"
+)
+
+type csmsProvisioningHandler struct {
+	provisioning.CSMSHandler
+}
+
+func (h *csmsProvisioningHandler) OnBootNotification(chargingStationID string, request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
+	return provisioning.NewBootNotificationResponse(types.NewDateTime(time.Now()), 1, provisioning.RegistrationStatusAccepted), nil
+}
+
+type csmsAvailabilityHandler struct {
+	availability.CSMSHandler
+	heartbeatC chan string
+}
+
+func (h *csmsAvailabilityHandler) OnHeartbeat(chargingStationID string, request *availability.HeartbeatRequest) (*availability.HeartbeatResponse, error) {
+	select {
+	case h.heartbeatC <- chargingStationID:
+	default:
+	}
+	return availability.NewHeartbeatResponse(*types.NewDateTime(time.Now())), nil
+}
+
+type stationHandler struct {
+	remotecontrol.ChargingStationHandler
+}
+
+func (h *stationHandler) OnRequestStartTransaction(request *remotecontrol.RequestStartTransactionRequest) (*remotecontrol.RequestStartTransactionResponse, error) {
+	response := remotecontrol.NewRequestStartTransactionResponse(remotecontrol.RequestStartStopStatusAccepted)
+	response.TransactionID = "tx-" + request.IDToken.IdToken
+	return response, nil
+}
+
+func TestPairBootHeartbeatAndRemoteStart(t *testing.T) {
+	pair, err := ocpptest.NewPair("station1")
+	require.NoError(t, err)
+	defer pair.Close()
+	handler := &csmsAvailabilityHandler{heartbeatC: make(chan string, 1)}
+	pair.CSMS.SetProvisioningHandler(&csmsProvisioningHandler{})
+	pair.CSMS.SetAvailabilityHandler(handler)
+	pair.ChargingStation.SetRemoteControlHandler(&stationHandler{})
+	// Boot: the station adopts the heartbeat interval sent by the CSMS
+	pair.ChargingStation.SetAutoApplyBootInterval(true)
+	bootResponse, err := pair.ChargingStation.BootNotification(provisioning.BootReasonPowerUp, "model1", "vendor1")
+	require.NoError(t, err)
+	assert.Equal(t, provisioning.RegistrationStatusAccepted, bootResponse.Status)
+	select {
+	case stationID := <-handler.heartbeatC:
+		assert.Equal(t, pair.StationID, stationID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no heartbeat received within interval")
+	}
+	// Remote command
+	resultC := make(chan *remotecontrol.RequestStartTransactionResponse, 1)
+	err = pair.CSMS.RequestStartTransaction(pair.StationID, func(response *remotecontrol.RequestStartTransactionResponse, err error) {
+		assert.NoError(t, err)
+		resultC <- response
+	}, 1, types.IdToken{IdToken: "1234", Type: types.IdTokenTypeISO14443})
+	require.NoError(t, err)
+	select {
+	case response := <-resultC:
+		require.NotNil(t, response)
+		assert.Equal(t, remotecontrol.RequestStartStopStatusAccepted, response.Status)
+		assert.Equal(t, "tx-1234", response.TransactionID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no response to remote start received")
+	}
+}
+
+// Sends a RequestStartTransaction from the CSMS and waits for the result.
+func requestStartTransaction(t *testing.T, pair *ocpptest.Pair, remoteStartID int) (*remotecontrol.RequestStartTransactionResponse, error) {
+	type result struct {
+		response *remotecontrol.RequestStartTransactionResponse
+		err      error
+	}
+	resultC := make(chan result, 1)
+	err := pair.CSMS.RequestStartTransaction(pair.StationID, func(response *remotecontrol.RequestStartTransactionResponse, err error) {
+		resultC <- result{response: response, err: err}
+	}, remoteStartID, types.IdToken{IdToken: "1234", Type: types.IdTokenTypeISO14443})
+	require.NoError(t, err)
+	select {
+	case r := <-resultC:
+		return r.response, r.err
+	case <-time.After(3 * time.Second):
+		t.Fatal("no result for remote start received")
+		return nil, nil
+	}
+}
+
+func TestPairNetworkLatencyTimeout(t *testing.T) {
+	pair, err := ocpptest.NewPair("station1")
+	require.NoError(t, err)
+	defer pair.Close()
+	pair.ChargingStation.SetRemoteControlHandler(&stationHandler{})
+	require.NoError(t, pair.CSMS.SetStationTimeout(pair.StationID, 200*time.Millisecond))
+	// The round trip takes longer than the timeout
+	pair.SetNetworkConditions(ocpptest.NetworkConditions{Latency: 150 * time.Millisecond, Jitter: 20 * time.Millisecond, Seed: 1})
+	start := time.Now()
+	_, err = requestStartTransaction(t, pair, 1)
+	require.Error(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(200*time.Millisecond))
+	// Within the timeout, the request succeeds
+	pair.SetNetworkConditions(ocpptest.NetworkConditions{Latency: 50 * time.Millisecond})
+	response, err := requestStartTransaction(t, pair, 2)
+	require.NoError(t, err)
+	assert.Equal(t, remotecontrol.RequestStartStopStatusAccepted, response.Status)
+	// Limited bandwidth delays messages as well
+	pair.SetNetworkConditions(ocpptest.NetworkConditions{Bandwidth: 200})
+	_, err = requestStartTransaction(t, pair, 3)
+	require.Error(t, err)
+}
+
+func TestPairNetworkLossRetry(t *testing.T) {
+	pair, err := ocpptest.NewPair("station1")
+	require.NoError(t, err)
+	defer pair.Close()
+	pair.ChargingStation.SetRemoteControlHandler(&stationHandler{})
+	require.NoError(t, pair.CSMS.SetStationTimeout(pair.StationID, 100*time.Millisecond))
+	pair.SetNetworkConditions(ocpptest.NetworkConditions{LossRate: 0.5, Seed: 8})
+	var response *remotecontrol.RequestStartTransactionResponse
+	attempts := 0
+	for attempts < 10 && response == nil {
+		attempts++
+		response, err = requestStartTransaction(t, pair, attempts)
+		if err != nil {
+			response = nil
+		}
+	}
+	require.NotNil(t, response)
+	assert.Equal(t, remotecontrol.RequestStartStopStatusAccepted, response.Status)
+	// The seed makes the scenario reproducible: the first attempt is lost, the retry succeeds
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,14 @@
+module github.com/lorenzodonini/ocpp-go/ocpptrace/ocppotel
+
+go 1.16
+
+require (
+	github.com/google/go-cmp v0.5.8 // indirect
+	github.com/lorenzodonini/ocpp-go v0.0.0
+	github.com/stretchr/testify v1.8.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
+
+replace github.com/lorenzodonini/ocpp-go => ../../
@@ -0,0 +1,94 @@
+// Package ocppotel provides an OpenTelemetry adapter for the ocpptrace package.
+//
+// The package is a separate module, which keeps the OpenTelemetry dependency out of the core module.
+// The returned tracer is passed to an ocpptrace.Observer, which is then registered on the OCPP-J server:
+//
+//	endpoint := ocppj.NewServer(ws.NewServer(), nil, nil)
+//	endpoint.SetRequestObserver(ocpptrace.NewObserver(ocppotel.NewTracer(otel.Tracer("ocpp"))))
+//	csms := ocpp2.NewCSMS(endpoint, nil)
+package ocppotel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ocpptrace"
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns an ocpptrace.Tracer, which creates spans via the passed OpenTelemetry tracer.
+//
+// Spans of incoming requests are of kind server, while spans of outgoing requests are of kind client.
+// Errors recorded on a span also set the span status to codes.Error.
+func NewTracer(t trace.Tracer) ocpptrace.Tracer {
+	return &tracer{tracer: t}
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attributes ...ocpptrace.Attribute) (context.Context, ocpptrace.Span) {
+	options := []trace.SpanStartOption{trace.WithAttributes(toKeyValues(attributes)...)}
+	for _, a := range attributes {
+		if a.Key != ocpptrace.AttributeDirection {
+			continue
+		}
+		switch a.Value {
+		case string(ocppj.RequestIncoming):
+			options = append(options, trace.WithSpanKind(trace.SpanKindServer))
+		case string(ocppj.RequestOutgoing):
+			options = append(options, trace.WithSpanKind(trace.SpanKindClient))
+		}
+	}
+	ctx, span := t.tracer.Start(ctx, name, options...)
+	return ctx, &otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attributes ...ocpptrace.Attribute) {
+	s.span.SetAttributes(toKeyValues(attributes)...)
+}
+
+func (s *otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+// Converts attributes to their OpenTelemetry representation. Values of unsupported types are formatted as strings.
+func toKeyValues(attributes []ocpptrace.Attribute) []attribute.KeyValue {
+	keyValues := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		key := attribute.Key(a.Key)
+		switch value := a.Value.(type) {
+		case string:
+			keyValues = append(keyValues, key.String(value))
+		case bool:
+			keyValues = append(keyValues, key.Bool(value))
+		case int:
+			keyValues = append(keyValues, key.Int(value))
+		case int64:
+			keyValues = append(keyValues, key.Int64(value))
+		case float64:
+			keyValues = append(keyValues, key.Float64(value))
+		case []string:
+			keyValues = append(keyValues, key.StringSlice(value))
+		case fmt.Stringer:
+			keyValues = append(keyValues, key.String(value.String()))
+		default:
+			keyValues = append(keyValues, key.String(fmt.Sprint(value)))
+		}
+	}
+	return keyValues
+}
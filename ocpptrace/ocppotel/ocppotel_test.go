@@ -0,0 +1,98 @@
+package ocppotel_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ocpptrace"
+	"github.com/lorenzodonini/ocpp-go/ocpptrace/ocppotel"
+)
+
+func newObserver() (*ocpptrace.Observer, *tracetest.SpanRecorder, trace.Tracer) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("ocpp")
+	return ocpptrace.NewObserver(ocppotel.NewTracer(tracer)), recorder, tracer
+}
+
+func TestSpans(t *testing.T) {
+	observer, recorder, tracer := newObserver()
+	// The trace context of the connection is propagated to all spans
+	ctx, parent := tracer.Start(context.Background(), "connection")
+	observer.OnRequestStarted(ctx, "cs1", "1234", "Heartbeat", ocppj.RequestIncoming)
+	observer.OnRequestCompleted("cs1", "1234", ocppj.RequestIncoming, nil)
+	observer.OnRequestStarted(ctx, "cs1", "5678", "Reset", ocppj.RequestOutgoing)
+	observer.OnRequestCompleted("cs1", "5678", ocppj.RequestOutgoing, ocpp.NewError(ocppj.InternalError, "someError", "5678"))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	incoming := spans[0]
+	assert.Equal(t, "Heartbeat", incoming.Name())
+	assert.Equal(t, trace.SpanKindServer, incoming.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), incoming.Parent().SpanID())
+	assert.Equal(t, parent.SpanContext().TraceID(), incoming.SpanContext().TraceID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String(ocpptrace.AttributeAction, "Heartbeat"),
+		attribute.String(ocpptrace.AttributeMessageID, "1234"),
+		attribute.String(ocpptrace.AttributeClientID, "cs1"),
+		attribute.String(ocpptrace.AttributeDirection, string(ocppj.RequestIncoming)),
+	}, incoming.Attributes())
+	assert.Equal(t, codes.Unset, incoming.Status().Code)
+	assert.Empty(t, incoming.Events())
+
+	outgoing := spans[1]
+	assert.Equal(t, "Reset", outgoing.Name())
+	assert.Equal(t, trace.SpanKindClient, outgoing.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), outgoing.Parent().SpanID())
+	assert.Contains(t, outgoing.Attributes(), attribute.String(ocpptrace.AttributeErrorCode, string(ocppj.InternalError)))
+	assert.Contains(t, outgoing.Attributes(), attribute.String(ocpptrace.AttributeErrorDescription, "someError"))
+	assert.Equal(t, codes.Error, outgoing.Status().Code)
+	require.Len(t, outgoing.Events(), 1)
+	assert.Equal(t, "exception", outgoing.Events()[0].Name)
+}
+
+type stringer struct{}
+
+func (stringer) String() string {
+	return "stringValue"
+}
+
+func TestAttributeTypes(t *testing.T) {
+	_, recorder, tracer := newObserver()
+	_, span := ocppotel.NewTracer(tracer).Start(context.Background(), "test",
+		ocpptrace.Attribute{Key: "string", Value: "value"},
+		ocpptrace.Attribute{Key: "bool", Value: true},
+		ocpptrace.Attribute{Key: "int", Value: 42},
+		ocpptrace.Attribute{Key: "int64", Value: int64(43)},
+		ocpptrace.Attribute{Key: "float64", Value: 1.5},
+		ocpptrace.Attribute{Key: "strings", Value: []string{"a", "b"}})
+	span.SetAttributes(
+		ocpptrace.Attribute{Key: "stringer", Value: stringer{}},
+		ocpptrace.Attribute{Key: "other", Value: uint8(7)})
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("string", "value"),
+		attribute.Bool("bool", true),
+		attribute.Int("int", 42),
+		attribute.Int64("int64", 43),
+		attribute.Float64("float64", 1.5),
+		attribute.StringSlice("strings", []string{"a", "b"}),
+		attribute.String("stringer", "stringValue"),
+		attribute.String("other", "7"),
+	}, spans[0].Attributes())
+}
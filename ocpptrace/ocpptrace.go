@@ -0,0 +1,173 @@
+// Package ocpptrace provides request tracing for OCPP-J servers.
+//
+// An Observer creates a span for every request exchanged between a server and its clients.
+// Spans are started as children of the context attached to the client connection (see ws.Channel),
+// which allows to propagate a trace context, e.g. extracted from the HTTP upgrade request via ws.ConnectionAuthorizer.
+// A span is ended once the response to the request was sent or received, or when the request times out.
+//
+// The package doesn't depend on any tracing library. Spans are created through the Tracer adapter interface.
+// An OpenTelemetry adapter is provided by the ocppotel module, which keeps the dependency out of the core module:
+//
+//	import "github.com/lorenzodonini/ocpp-go/ocpptrace/ocppotel"
+//
+// The observer is registered on the OCPP-J server, before passing the server to a central system:
+//
+//	endpoint := ocppj.NewServer(ws.NewServer(), nil, nil)
+//	endpoint.SetRequestObserver(ocpptrace.NewObserver(ocppotel.NewTracer(otel.Tracer("ocpp"))))
+//	csms := ocpp2.NewCSMS(endpoint, nil)
+package ocpptrace
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+)
+
+// Attribute keys set on every span created by an Observer.
+const (
+	AttributeAction           = "ocpp.action"
+	AttributeMessageID        = "ocpp.message_id"
+	AttributeClientID         = "ocpp.client_id"
+	AttributeDirection        = "ocpp.direction"
+	AttributeErrorCode        = "ocpp.error_code"
+	AttributeErrorDescription = "ocpp.error_description"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// ErrRequestTimeout is recorded on spans of incoming requests, which weren't responded to within the observer timeout.
+var ErrRequestTimeout = errors.New("request timed out")
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is the adapter interface for a single traced operation.
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer is the adapter interface that needs to be implemented, in order to hook up a tracing library of choice.
+//
+// Start creates a new span as a child of the span contained in the passed context (if any),
+// returning the span along with a context containing it.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+type spanKey struct {
+	clientID  string
+	requestID string
+	direction ocppj.RequestDirection
+}
+
+type activeSpan struct {
+	span  Span
+	doneC chan struct{}
+}
+
+// Observer implements the ocppj.RequestObserver interface, creating a span for every request.
+// The span is named after the request action.
+type Observer struct {
+	tracer  Tracer
+	timeout time.Duration
+	mutex   sync.Mutex
+	spans   map[spanKey]*activeSpan
+}
+
+// NewObserver creates a new Observer, which starts spans via the passed tracer.
+func NewObserver(tracer Tracer) *Observer {
+	return &Observer{
+		tracer:  tracer,
+		timeout: defaultTimeout,
+		spans:   map[spanKey]*activeSpan{},
+	}
+}
+
+// SetTimeout sets the maximum duration of a span. By default, spans are ended after 30 seconds.
+//
+// Outgoing requests are typically completed earlier by the dispatcher timeout,
+// while incoming requests which are never responded to are ended with ErrRequestTimeout.
+func (o *Observer) SetTimeout(timeout time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.timeout = timeout
+}
+
+func (o *Observer) OnRequestStarted(ctx context.Context, clientID string, requestID string, action string, direction ocppj.RequestDirection) {
+	_, span := o.tracer.Start(ctx, action,
+		Attribute{Key: AttributeAction, Value: action},
+		Attribute{Key: AttributeMessageID, Value: requestID},
+		Attribute{Key: AttributeClientID, Value: clientID},
+		Attribute{Key: AttributeDirection, Value: string(direction)})
+	key := spanKey{clientID: clientID, requestID: requestID, direction: direction}
+	active := &activeSpan{span: span, doneC: make(chan struct{})}
+	o.mutex.Lock()
+	if previous, ok := o.spans[key]; ok {
+		// Duplicate message ID, the previous span will never be completed
+		close(previous.doneC)
+		previous.span.End()
+	}
+	o.spans[key] = active
+	timeout := o.timeout
+	o.mutex.Unlock()
+	// Spans are ended on timeout, or when the client connection is closed
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-active.doneC:
+			return
+		case <-timer.C:
+			o.end(key, active, ErrRequestTimeout)
+		case <-ctx.Done():
+			o.end(key, active, ctx.Err())
+		}
+	}()
+}
+
+func (o *Observer) OnRequestCompleted(clientID string, requestID string, direction ocppj.RequestDirection, err *ocpp.Error) {
+	key := spanKey{clientID: clientID, requestID: requestID, direction: direction}
+	o.mutex.Lock()
+	active, ok := o.spans[key]
+	o.mutex.Unlock()
+	if !ok {
+		return
+	}
+	// Avoid passing a typed nil pointer as error
+	if err != nil {
+		o.end(key, active, err)
+	} else {
+		o.end(key, active, nil)
+	}
+}
+
+// Ends a span exactly once, recording the error that caused it to end (if any).
+func (o *Observer) end(key spanKey, active *activeSpan, err error) {
+	o.mutex.Lock()
+	if o.spans[key] != active {
+		o.mutex.Unlock()
+		return
+	}
+	delete(o.spans, key)
+	close(active.doneC)
+	o.mutex.Unlock()
+	if err != nil {
+		var ocppErr *ocpp.Error
+		if errors.As(err, &ocppErr) {
+			active.span.SetAttributes(
+				Attribute{Key: AttributeErrorCode, Value: string(ocppErr.Code)},
+				Attribute{Key: AttributeErrorDescription, Value: ocppErr.Description})
+		}
+		active.span.RecordError(err)
+	}
+	active.span.End()
+}
@@ -0,0 +1,216 @@
+package ocpptrace_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lorenzodonini/ocpp-go/ocpp"
+	"github.com/lorenzodonini/ocpp-go/ocppj"
+	"github.com/lorenzodonini/ocpp-go/ocpptest"
+	"github.com/lorenzodonini/ocpp-go/ocpptrace"
+	"github.com/lorenzodonini/ocpp-go/ws"
+)
+
+const testFeatureName = "TestFeature"
+
+type testRequest struct {
+	Value string `json:"value"`
+}
+
+func (r testRequest) GetFeatureName() string {
+	return testFeatureName
+}
+
+type testResponse struct {
+	Value string `json:"value"`
+}
+
+func (r testResponse) GetFeatureName() string {
+	return testFeatureName
+}
+
+type testFeature struct{}
+
+func (f testFeature) GetFeatureName() string {
+	return testFeatureName
+}
+
+func (f testFeature) GetRequestType() reflect.Type {
+	return reflect.TypeOf(testRequest{})
+}
+
+func (f testFeature) GetResponseType() reflect.Type {
+	return reflect.TypeOf(testResponse{})
+}
+
+var testProfile = ocpp.NewProfile("test", testFeature{})
+
+// Starts a traced server and connects a client with the passed ID to it, using an in-memory transport.
+func startPair(t *testing.T, clientID string, observer *ocpptrace.Observer, dispatcher ocppj.ServerDispatcher) (*ocppj.Server, *ocppj.Client, ws.Channel) {
+	wsServer := ocpptest.NewMemoryServer()
+	server := ocppj.NewServer(wsServer, dispatcher, nil, testProfile)
+	server.SetRequestObserver(observer)
+	channelC := make(chan ws.Channel, 1)
+	server.SetNewClientHandler(func(channel ws.Channel) {
+		channelC <- channel
+	})
+	go server.Start(0, "/{ws}")
+	client := ocppj.NewClient(clientID, wsServer.NewClient(), nil, nil, testProfile)
+	require.NoError(t, client.Start("ws://ocpptest"))
+	t.Cleanup(func() {
+		client.Stop()
+		server.Stop()
+	})
+	select {
+	case channel := <-channelC:
+		return server, client, channel
+	case <-time.After(time.Second):
+		t.Fatal("client didn't connect")
+		return nil, nil, nil
+	}
+}
+
+func TestRequestTracing(t *testing.T) {
+	clientID := "1234"
+	recorder := newSpanRecorder()
+	server, client, channel := startPair(t, clientID, ocpptrace.NewObserver(recorder), nil)
+	client.SetRequestHandler(func(request ocpp.Request, requestId string, action string) {
+		_ = client.SendResponse(requestId, testResponse{Value: "someValue"})
+	})
+	serverResponseC := make(chan ocpp.Response, 1)
+	server.SetResponseHandler(func(client ws.Channel, response ocpp.Response, requestId string) {
+		serverResponseC <- response
+	})
+	clientResultC := make(chan struct{}, 1)
+	client.SetResponseHandler(func(response ocpp.Response, requestId string) {
+		clientResultC <- struct{}{}
+	})
+	client.SetErrorHandler(func(err *ocpp.Error, details interface{}) {
+		clientResultC <- struct{}{}
+	})
+	incomingC := make(chan string, 1)
+	server.SetRequestHandler(func(client ws.Channel, request ocpp.Request, requestId string, action string) {
+		incomingC <- requestId
+	})
+	// Outgoing request, completed by a CALL_RESULT
+	require.NoError(t, server.SendRequest(clientID, testRequest{Value: "someValue"}))
+	<-serverResponseC
+	// Incoming request, completed by a CALL_RESULT
+	require.NoError(t, client.SendRequest(testRequest{Value: "someValue"}))
+	resultID := <-incomingC
+	require.NoError(t, server.SendResponse(clientID, resultID, testResponse{Value: "someValue"}))
+	<-clientResultC
+	// Incoming request, completed by a CALL_ERROR
+	require.NoError(t, client.SendRequest(testRequest{Value: "someValue"}))
+	errorID := <-incomingC
+	require.NoError(t, server.SendError(clientID, errorID, ocppj.InternalError, "someError", nil))
+	<-clientResultC
+	// One span per request was created and ended
+	assert.Eventually(t, func() bool {
+		spans := recorder.recorded()
+		return len(spans) == 3 && spans[0].Ended && spans[1].Ended && spans[2].Ended
+	}, time.Second, 10*time.Millisecond)
+	spans := recorder.recorded()
+	require.Len(t, spans, 3)
+	expectedSpans := []struct {
+		messageID interface{}
+		direction ocppj.RequestDirection
+		errorCode interface{}
+	}{
+		{spans[0].Attributes[ocpptrace.AttributeMessageID], ocppj.RequestOutgoing, nil},
+		{resultID, ocppj.RequestIncoming, nil},
+		{errorID, ocppj.RequestIncoming, string(ocppj.InternalError)},
+	}
+	for i, expected := range expectedSpans {
+		span := spans[i]
+		assert.Equal(t, testFeatureName, span.Name)
+		assert.True(t, span.Ended)
+		assert.Equal(t, channel.Context(), span.Parent)
+		assert.Equal(t, testFeatureName, span.Attributes[ocpptrace.AttributeAction])
+		assert.Equal(t, expected.messageID, span.Attributes[ocpptrace.AttributeMessageID])
+		assert.Equal(t, clientID, span.Attributes[ocpptrace.AttributeClientID])
+		assert.Equal(t, string(expected.direction), span.Attributes[ocpptrace.AttributeDirection])
+		assert.Equal(t, expected.errorCode, span.Attributes[ocpptrace.AttributeErrorCode])
+		if expected.errorCode == nil {
+			assert.Empty(t, span.Errors)
+		} else {
+			assert.Len(t, span.Errors, 1)
+		}
+	}
+	assert.NotEmpty(t, spans[0].Attributes[ocpptrace.AttributeMessageID])
+}
+
+func TestRequestTracingTimeout(t *testing.T) {
+	clientID := "1234"
+	recorder := newSpanRecorder()
+	observer := ocpptrace.NewObserver(recorder)
+	observer.SetTimeout(300 * time.Millisecond)
+	dispatcher := ocppj.NewDefaultServerDispatcher(ocppj.NewFIFOQueueMap(0))
+	dispatcher.SetTimeout(100 * time.Millisecond)
+	server, client, _ := startPair(t, clientID, observer, dispatcher)
+	canceledC := make(chan struct{}, 1)
+	server.SetCanceledRequestHandler(func(clientID string, requestID string, request ocpp.Request, err *ocpp.Error) {
+		canceledC <- struct{}{}
+	})
+	incomingC := make(chan struct{}, 1)
+	server.SetRequestHandler(func(client ws.Channel, request ocpp.Request, requestId string, action string) {
+		incomingC <- struct{}{}
+	})
+	// Outgoing request is canceled by the dispatcher, incoming request is never responded to
+	client.SetRequestHandler(func(request ocpp.Request, requestId string, action string) {})
+	require.NoError(t, server.SendRequest(clientID, testRequest{Value: "someValue"}))
+	require.NoError(t, client.SendRequest(testRequest{Value: "someValue"}))
+	<-incomingC
+	select {
+	case <-canceledC:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for request cancellation")
+	}
+	assert.Eventually(t, func() bool {
+		spans := recorder.recorded()
+		return len(spans) == 2 && spans[0].Ended && spans[1].Ended
+	}, time.Second, 10*time.Millisecond)
+	spans := recorder.recorded()
+	require.Len(t, spans, 2)
+	assert.Equal(t, string(ocppj.RequestOutgoing), spans[0].Attributes[ocpptrace.AttributeDirection])
+	assert.Equal(t, string(ocppj.GenericError), spans[0].Attributes[ocpptrace.AttributeErrorCode])
+	assert.Equal(t, string(ocppj.RequestIncoming), spans[1].Attributes[ocpptrace.AttributeDirection])
+	assert.Equal(t, []error{ocpptrace.ErrRequestTimeout}, spans[1].Errors)
+}
+
+func TestDuplicateMessageID(t *testing.T) {
+	recorder := newSpanRecorder()
+	observer := ocpptrace.NewObserver(recorder)
+	observer.OnRequestStarted(context.Background(), "1234", "5678", testFeatureName, ocppj.RequestIncoming)
+	observer.OnRequestStarted(context.Background(), "1234", "5678", testFeatureName, ocppj.RequestIncoming)
+	// The previous span is ended right away, the new one once completed
+	spans := recorder.recorded()
+	require.Len(t, spans, 2)
+	assert.True(t, spans[0].Ended)
+	assert.False(t, spans[1].Ended)
+	observer.OnRequestCompleted("1234", "5678", ocppj.RequestIncoming, nil)
+	spans = recorder.recorded()
+	assert.True(t, spans[1].Ended)
+	assert.Empty(t, spans[1].Errors)
+}
+
+func TestConnectionClosed(t *testing.T) {
+	recorder := newSpanRecorder()
+	observer := ocpptrace.NewObserver(recorder)
+	ctx, cancel := context.WithCancel(context.Background())
+	observer.OnRequestStarted(ctx, "1234", "5678", testFeatureName, ocppj.RequestOutgoing)
+	cancel()
+	assert.Eventually(t, func() bool {
+		spans := recorder.recorded()
+		return len(spans) == 1 && spans[0].Ended
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []error{context.Canceled}, recorder.recorded()[0].Errors)
+	// Completing the request afterwards has no effect
+	observer.OnRequestCompleted("1234", "5678", ocppj.RequestOutgoing, nil)
+	assert.Len(t, recorder.recorded()[0].Errors, 1)
+}
@@ -0,0 +1,79 @@
+package ocpptrace_test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lorenzodonini/ocpp-go/ocpptrace"
+)
+
+// A snapshot of a span created by a spanRecorder.
+type recordedSpan struct {
+	Name       string
+	Parent     context.Context
+	Attributes map[string]interface{}
+	Errors     []error
+	Ended      bool
+}
+
+// An in-memory tracer, which keeps track of all created spans.
+type spanRecorder struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordingSpan struct {
+	recorder *spanRecorder
+	span     *recordedSpan
+}
+
+func newSpanRecorder() *spanRecorder {
+	return &spanRecorder{}
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, attributes ...ocpptrace.Attribute) (context.Context, ocpptrace.Span) {
+	span := &recordedSpan{Name: name, Parent: ctx, Attributes: map[string]interface{}{}}
+	for _, a := range attributes {
+		span.Attributes[a.Key] = a.Value
+	}
+	r.mutex.Lock()
+	r.spans = append(r.spans, span)
+	r.mutex.Unlock()
+	return ctx, &recordingSpan{recorder: r, span: span}
+}
+
+// Returns a snapshot of all recorded spans, in the order they were started.
+func (r *spanRecorder) recorded() []recordedSpan {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	spans := make([]recordedSpan, len(r.spans))
+	for i, s := range r.spans {
+		spans[i] = *s
+		spans[i].Attributes = map[string]interface{}{}
+		for k, v := range s.Attributes {
+			spans[i].Attributes[k] = v
+		}
+		spans[i].Errors = append([]error(nil), s.Errors...)
+	}
+	return spans
+}
+
+func (s *recordingSpan) SetAttributes(attributes ...ocpptrace.Attribute) {
+	s.recorder.mutex.Lock()
+	defer s.recorder.mutex.Unlock()
+	for _, a := range attributes {
+		s.span.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.recorder.mutex.Lock()
+	defer s.recorder.mutex.Unlock()
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *recordingSpan) End() {
+	s.recorder.mutex.Lock()
+	defer s.recorder.mutex.Unlock()
+	s.span.Ended = true
+}
@@ -0,0 +1,71 @@
+package ws
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func (server *Server) SetHandshakeTimeout(d time.Duration) {
+	server.handshakeTimeout = d
+}
+
+// Bounds reading the upgrade request and writing the upgrade response, if a handshake timeout was set.
+// The read header timeout also covers the TLS handshake, if the server uses TLS.
+func (server *Server) applyHandshakeTimeout() {
+	if server.handshakeTimeout <= 0 {
+		return
+	}
+	server.httpServer.ReadHeaderTimeout = server.handshakeTimeout
+	server.upgrader.HandshakeTimeout = server.handshakeTimeout
+}
+
+func (client *Client) SetHandshakeTimeout(d time.Duration) {
+	client.handshakeTimeout = d
+}
+
+// Bounds the websocket handshake separately from establishing the TCP connection, if a handshake timeout was set.
+//
+// The dialer applies its HandshakeTimeout to the entire dial, overriding any connection deadline.
+// It is therefore replaced by an equivalent deadline, enforced by the dial function itself.
+func (client *Client) applyHandshakeTimeout(dialer *websocket.Dialer) {
+	if client.handshakeTimeout <= 0 {
+		return
+	}
+	handshakeTimeout := client.handshakeTimeout
+	connectTimeout := dialer.HandshakeTimeout
+	dialer.HandshakeTimeout = 0
+	dial := dialer.NetDialContext
+	if dial == nil && dialer.NetDial != nil {
+		netDial := dialer.NetDial
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return netDial(network, addr)
+		}
+	} else if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var deadline time.Time
+		if connectTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, connectTimeout)
+			defer cancel()
+			deadline, _ = ctx.Deadline()
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		// The deadline is cleared by the dialer once the handshake completed
+		if handshakeDeadline := time.Now().Add(handshakeTimeout); deadline.IsZero() || handshakeDeadline.Before(deadline) {
+			deadline = handshakeDeadline
+		}
+		if err = conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// Weight of a new sample in the smoothed round-trip time, as suggested by RFC 6298.
+const rttSmoothingFactor = 0.125
+
+// rttStats measures round-trip times of a connection, by matching pongs to the last sent ping.
+// Each ping carries a unique payload, which is echoed back by the peer in its pong.
+// Pongs not matching the outstanding ping (e.g. late, unsolicited or without payload) are ignored.
+//
+// All methods may be safely invoked on a nil receiver, in which case no measurement is performed.
+type rttStats struct {
+	mutex       sync.Mutex
+	sequence    uint64
+	pendingPing []byte
+	pingSentAt  time.Time
+	last        time.Duration
+	average     time.Duration
+}
+
+// Returns the payload for the next ping and marks it as outstanding.
+func (r *rttStats) newPing() []byte {
+	if r == nil {
+		return []byte{}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sequence++
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint64(payload, r.sequence)
+	r.pendingPing = payload
+	r.pingSentAt = time.Now()
+	return payload
+}
+
+// Records the round-trip time for a received pong. Returns false if the pong doesn't match the outstanding ping.
+func (r *rttStats) pongReceived(payload []byte) (time.Duration, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.pendingPing == nil || !bytes.Equal(r.pendingPing, payload) {
+		return 0, false
+	}
+	rtt := time.Since(r.pingSentAt)
+	r.pendingPing = nil
+	r.last = rtt
+	if r.average == 0 {
+		r.average = rtt
+	} else {
+		r.average = time.Duration((1-rttSmoothingFactor)*float64(r.average) + rttSmoothingFactor*float64(rtt))
+	}
+	return rtt, true
+}
+
+func (r *rttStats) lastRTT() time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.last
+}
+
+func (r *rttStats) averageRTT() time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.average
+}
@@ -0,0 +1,106 @@
+package ws
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLS versions below this one are reported as weak by CheckTLSConfig.
+const minRecommendedTLSVersion = tls.VersionTLS12
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// CheckTLSConfig returns a human-readable warning for every weak setting in the given TLS configuration, e.g.:
+//
+//   - a minimum version lower than TLS 1.2 (including the default minimum version, if unset)
+//   - insecure cipher suites, as listed by tls.InsecureCipherSuites
+//   - disabled certificate verification
+//
+// Returns nil if no weak settings were found.
+func CheckTLSConfig(config *tls.Config) []string {
+	var warnings []string
+	if config == nil {
+		return []string{"no TLS configuration set, default minimum version is used"}
+	}
+	if config.MinVersion == 0 {
+		warnings = append(warnings, "no minimum TLS version set, default minimum version is used")
+	} else if config.MinVersion < minRecommendedTLSVersion {
+		warnings = append(warnings, fmt.Sprintf("minimum TLS version %v is lower than TLS 1.2", tlsVersionName(config.MinVersion)))
+	}
+	insecure := map[uint16]string{}
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.ID] = suite.Name
+	}
+	for _, id := range config.CipherSuites {
+		if name, ok := insecure[id]; ok {
+			warnings = append(warnings, fmt.Sprintf("insecure cipher suite %v is allowed", name))
+		}
+	}
+	if config.InsecureSkipVerify {
+		warnings = append(warnings, "certificate verification is disabled")
+	}
+	return warnings
+}
+
+// Validates a TLS policy, returning an error for unsupported versions or unknown cipher suites.
+func validateTLSPolicy(minVersion uint16, cipherSuites []uint16) error {
+	if _, ok := tlsVersionNames[minVersion]; !ok {
+		return fmt.Errorf("unsupported minimum TLS version %#04x", minVersion)
+	}
+	known := map[uint16]bool{}
+	for _, suite := range tls.CipherSuites() {
+		known[suite.ID] = true
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.ID] = true
+	}
+	for _, id := range cipherSuites {
+		if !known[id] {
+			return fmt.Errorf("unknown cipher suite %#04x", id)
+		}
+	}
+	return nil
+}
+
+// Applies a TLS policy to a copy of the given configuration. The original configuration is not modified.
+func applyTLSPolicy(config *tls.Config, minVersion uint16, cipherSuites []uint16) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	config.MinVersion = minVersion
+	if len(cipherSuites) > 0 {
+		config.CipherSuites = append([]uint16{}, cipherSuites...)
+	}
+	return config
+}
+
+// Implemented by loggers supporting a warning level, e.g. logrus.
+type warnLogger interface {
+	Warnf(format string, args ...interface{})
+}
+
+// Logs the weak settings of a TLS configuration, see CheckTLSConfig.
+// Warnings are logged at info level, if the logger doesn't support a warning level.
+func logTLSWarnings(config *tls.Config) {
+	for _, warning := range CheckTLSConfig(config) {
+		if logger, ok := log.(warnLogger); ok {
+			logger.Warnf("weak TLS configuration: %v", warning)
+		} else {
+			log.Infof("weak TLS configuration: %v", warning)
+		}
+	}
+}
+
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#04x", version)
+}
@@ -16,6 +16,7 @@ import (
 	"net/url"
 	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -34,6 +35,9 @@ const (
 	defaultPingPeriod = (defaultPongWait * 9) / 10
 	// Time allowed for the initial handshake to complete.
 	defaultHandshakeTimeout = 30 * time.Second
+	// Time allowed for the peer to acknowledge a close frame, before the connection is forcefully closed.
+	// By default, connections are closed right after sending the close frame.
+	defaultCloseTimeout = 0
 	// When the Charging Station is reconnecting, after a connection loss, it will use this variable for the amount of time
 	// it will double the previous back-off time. When the maximum number of increments is reached, the Charging
 	// Station keeps connecting with the same back-off time.
@@ -115,6 +119,19 @@ type Channel interface {
 	ID() string
 	RemoteAddr() net.Addr
 	TLSConnectionState() *tls.ConnectionState
+	// Stores a custom value for the lifetime of the connection. Safe for concurrent use.
+	Set(key string, value interface{})
+	// Retrieves a custom value, previously stored via Set.
+	// Values are cleared automatically once the connection was closed.
+	Get(key string) (interface{}, bool)
+	// Removes a custom value, previously stored via Set.
+	Delete(key string)
+	// Returns the context of the connection, which is cancelled once the connection was closed.
+	// On servers, the context is derived from the one returned by the connection authorizer, if any.
+	Context() context.Context
+	// Returns the query parameters of the URL, on which the client connected (e.g. a firmware version or a session token).
+	// The returned values are a copy and may be modified. Empty for client-side connections.
+	QueryParams() url.Values
 }
 
 // WebSocket is a wrapper for a single websocket channel.
@@ -124,11 +141,23 @@ type Channel interface {
 type WebSocket struct {
 	connection         *websocket.Conn
 	id                 string
-	outQueue           chan []byte
+	outQueue           chan outMessage
 	closeC             chan websocket.CloseError // used to gracefully close a websocket connection.
 	forceCloseC        chan error                // used by the readPump to notify a forcefully closed connection to the writePump.
 	pingMessage        chan []byte
 	tlsConnectionState *tls.ConnectionState
+	queryParams        url.Values
+	data               map[string]interface{} // custom values, cleared when the connection is closed.
+	dataMutex          sync.RWMutex
+	rtt                *rttStats // nil if round-trip times are not measured on this connection.
+	ctx                context.Context
+	cancel             context.CancelFunc
+}
+
+// A message queued for sending, along with its websocket frame type (text or binary).
+type outMessage struct {
+	messageType int
+	data        []byte
 }
 
 // Retrieves the unique Identifier of the websocket (typically, the URL suffix).
@@ -142,10 +171,101 @@ func (websocket *WebSocket) RemoteAddr() net.Addr {
 }
 
 // Returns the TLS connection state of the connection, if any.
+// The state is captured during the handshake and is nil for non-TLS connections.
+//
+// When the server requires client certificates, the verified peer certificate can be read from the returned
+// state (e.g. PeerCertificates[0].Subject), for binding the certificate identity to the client ID.
 func (websocket *WebSocket) TLSConnectionState() *tls.ConnectionState {
 	return websocket.tlsConnectionState
 }
 
+// Stores a custom value for the lifetime of the connection.
+// Once the connection was closed, the value is discarded.
+func (websocket *WebSocket) Set(key string, value interface{}) {
+	websocket.dataMutex.Lock()
+	defer websocket.dataMutex.Unlock()
+	if websocket.data != nil {
+		websocket.data[key] = value
+	}
+}
+
+// Retrieves a custom value, previously stored via Set.
+func (websocket *WebSocket) Get(key string) (interface{}, bool) {
+	websocket.dataMutex.RLock()
+	defer websocket.dataMutex.RUnlock()
+	value, ok := websocket.data[key]
+	return value, ok
+}
+
+// Removes a custom value, previously stored via Set.
+func (websocket *WebSocket) Delete(key string) {
+	websocket.dataMutex.Lock()
+	defer websocket.dataMutex.Unlock()
+	delete(websocket.data, key)
+}
+
+// Returns the context of the connection, which is cancelled once the connection was closed.
+// Values attached to the context by a connection authorizer are available for the lifetime of the connection.
+func (websocket *WebSocket) Context() context.Context {
+	if websocket.ctx == nil {
+		return context.Background()
+	}
+	return websocket.ctx
+}
+
+// Returns the query parameters of the URL, on which the client connected.
+// The parameters are parsed from the upgrade request. Empty for client-side connections.
+func (websocket *WebSocket) QueryParams() url.Values {
+	params := url.Values{}
+	for key, values := range websocket.queryParams {
+		params[key] = append([]string(nil), values...)
+	}
+	return params
+}
+
+// Returns the subprotocol negotiated during the websocket handshake, e.g. "ocpp1.6".
+func (websocket *WebSocket) Subprotocol() string {
+	return websocket.connection.Subprotocol()
+}
+
+// Returns the round-trip time measured for the most recent ping/pong exchange.
+// Returns 0 if round-trip times aren't measured on the connection, or no pong was received yet.
+// See Server.SetRTTMeasurement and Client.SetRTTMeasurement.
+func (websocket *WebSocket) LastRTT() time.Duration {
+	return websocket.rtt.lastRTT()
+}
+
+// Returns the smoothed round-trip time of the connection, computed as an exponentially weighted moving average
+// of all measured round-trip times.
+// Returns 0 if round-trip times aren't measured on the connection, or no pong was received yet.
+func (websocket *WebSocket) AverageRTT() time.Duration {
+	return websocket.rtt.averageRTT()
+}
+
+// Waits for the peer to acknowledge a close frame, which was previously written on the connection.
+// The acknowledgement is received by the read pump, which notifies it via the forceCloseC channel.
+// Returns false, if the acknowledgement was not received within the timeout.
+func waitForCloseAck(ws *WebSocket, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ws.forceCloseC:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Discards all custom values. Any further values set on the websocket are ignored.
+func (websocket *WebSocket) clearData() {
+	websocket.dataMutex.Lock()
+	defer websocket.dataMutex.Unlock()
+	websocket.data = nil
+}
+
 // ConnectionError is a websocket
 type HttpConnectionError struct {
 	Message    string
@@ -162,6 +282,14 @@ func (e HttpConnectionError) Error() string {
 
 type CheckClientHandler func(id string, r *http.Request) bool
 
+// ConnectionAuthorizer decides whether an incoming websocket connection is admitted, based on the complete upgrade request
+// (path, headers, TLS state, remote address).
+//
+// If the connection is accepted, the returned context seeds the context of the connection, e.g. to attach the authenticated identity.
+// A nil context is replaced by an empty one.
+// If the connection is denied, or an error is returned, the upgrade is rejected.
+type ConnectionAuthorizer func(r *http.Request) (accept bool, ctx context.Context, err error)
+
 // WsServer defines a websocket server, which passively listens for incoming connections on ws or wss protocol.
 // The offered API are of asynchronous nature, and each incoming connection/message is handled using callbacks.
 //
@@ -204,6 +332,27 @@ type WsServer interface {
 	//
 	// To stop a running server, call the Stop function.
 	Start(port int, listenPath string)
+	// Starts and runs the websocket server, like Start, until the passed context is done.
+	// Once the context is canceled, the server is shut down gracefully, as if Stop was called.
+	//
+	// The function blocks until the server stopped. It returns an error if the server couldn't listen
+	// on the specified port, or failed while serving. After a graceful shutdown, nil is returned.
+	StartWithContext(ctx context.Context, port int, listenPath string) error
+	// Runs the websocket server on a pre-created listener, like Start, instead of binding a TCP port itself.
+	// This allows serving connections e.g. on a Unix domain socket, or on a listener passed via systemd socket activation.
+	// If a TLS certificate was configured, TLS is served on top of the listener.
+	//
+	// The function blocks until the server stopped and closes the listener before returning.
+	// It returns an error if the server failed while serving. After a graceful shutdown via Stop, nil is returned.
+	StartOnListener(listener net.Listener, listenPath string) error
+	// Registers an additional URL pattern, on which incoming websocket connections are accepted.
+	// Connections on all registered paths are handled identically and share the same set of handlers and connections,
+	// hence client IDs must be unique across all paths. The client ID is always the last path element.
+	//
+	// Additional paths may be added before or after starting the server, e.g.:
+	//	server.AddListenPath("/legacy/{id}")
+	//	go server.Start(8887, "/ocpp/{id}")
+	AddListenPath(listenPath string)
 	// Shuts down a running websocket server.
 	// All open channels will be forcefully closed, and the previously called Start function will return.
 	Stop()
@@ -226,11 +375,31 @@ type WsServer interface {
 	//
 	// This function must be called before starting the server, otherwise it may lead to unexpected behavior.
 	SetTimeoutConfig(config ServerTimeoutConfig)
+	// Sets the maximum time to wait for a client to acknowledge a close frame, when a connection is being closed.
+	// Once the timeout expires, the underlying TCP connection is closed forcefully.
+	// A zero duration disables waiting for the acknowledgement altogether.
+	//
+	// By default, the acknowledgement isn't awaited and the connection is closed right after sending the close frame.
+	SetCloseTimeout(d time.Duration)
+	// Sets the maximum time a client may take for the websocket handshake, i.e. for sending the upgrade request
+	// (and completing the TLS handshake, if any) after opening the TCP connection. Connections exceeding the timeout are closed,
+	// protecting the server against clients stalling the handshake.
+	//
+	// This function must be called before starting the server. By default, the handshake isn't bounded.
+	SetHandshakeTimeout(d time.Duration)
 	// Sends a message on a specific Channel, identifier by the webSocketId parameter.
 	// If the passed ID is invalid, an error is returned.
 	//
 	// The data is queued and will be sent asynchronously in the background.
 	Write(webSocketId string, data []byte) error
+	// Sends a binary message on a specific Channel, identified by the webSocketId parameter.
+	// Binary frames are queued together with text messages, hence the order of all messages is preserved.
+	//
+	// The data is queued and will be sent asynchronously in the background.
+	WriteBinary(webSocketId string, data []byte) error
+	// Sets a callback function for all incoming binary messages.
+	// If no binary handler is set, binary messages are passed to the regular message handler.
+	SetBinaryMessageHandler(handler func(ws Channel, data []byte) error)
 	// Adds support for a specified subprotocol.
 	// This is recommended in order to communicate the capabilities to the client during the handshake.
 	// If left empty, any subprotocol will be accepted.
@@ -250,8 +419,15 @@ type WsServer interface {
 	// SetCheckClientHandler sets a handler for validate incoming websocket connections, allowing to perform
 	// custom client connection checks.
 	SetCheckClientHandler(handler func(id string, r *http.Request) bool)
+	// SetConnectionAuthorizer sets a handler, which is invoked with the complete HTTP request before upgrading a new connection.
+	// The handler is run after the basic auth and check client handlers, if set.
+	//
+	// Denied connections are rejected with a 403 Forbidden response, while a 500 Internal Server Error is returned
+	// if the authorizer fails. The context returned for accepted connections is available via the Context method of the Channel.
+	SetConnectionAuthorizer(handler ConnectionAuthorizer)
 	// Addr gives the address on which the server is listening, useful if, for
 	// example, the port is system-defined (set to 0).
+	// Returns nil, if the server was started on a non-TCP listener.
 	Addr() *net.TCPAddr
 }
 
@@ -262,28 +438,41 @@ type Server struct {
 	connections         map[string]*WebSocket
 	httpServer          *http.Server
 	messageHandler      func(ws Channel, data []byte) error
+	binaryHandler       func(ws Channel, data []byte) error
 	checkClientHandler  func(id string, r *http.Request) bool
+	authorizer          ConnectionAuthorizer
 	newClientHandler    func(ws Channel)
 	disconnectedHandler func(ws Channel)
 	basicAuthHandler    func(username string, password string) bool
 	tlsCertificatePath  string
 	tlsCertificateKey   string
 	timeoutConfig       ServerTimeoutConfig
+	closeTimeout        time.Duration
+	handshakeTimeout    time.Duration
 	upgrader            websocket.Upgrader
+	compressionMinSize  int
+	rttPingPeriod       time.Duration
 	errC                chan error
 	connMutex           sync.RWMutex
 	addr                *net.TCPAddr
-	httpHandler         *mux.Router
+	routesMutex         sync.Mutex
+	routes              []httpRoute
+	router              atomic.Value // *mux.Router, replaced whenever a route is added
+}
+
+// httpRoute is a handler registered on the HTTP server for a path pattern.
+type httpRoute struct {
+	path    string
+	handler func(w http.ResponseWriter, r *http.Request)
 }
 
 // Creates a new simple websocket server (the websockets are not secured).
 func NewServer() *Server {
-	router := mux.NewRouter()
 	return &Server{
 		httpServer:    &http.Server{},
 		timeoutConfig: NewServerTimeoutConfig(),
+		closeTimeout:  defaultCloseTimeout,
 		upgrader:      websocket.Upgrader{Subprotocols: []string{}},
-		httpHandler:   router,
 	}
 }
 
@@ -301,8 +490,10 @@ func NewServer() *Server {
 //
 // If no tlsConfig parameter is passed, the server will by default
 // not perform any client certificate verification.
+//
+// The tlsConfig is used as is, hence policy settings such as MinVersion and CipherSuites are preserved.
+// Use SetTLSPolicy for enforcing and validating such settings conveniently.
 func NewTLSServer(certificatePath string, certificateKey string, tlsConfig *tls.Config) *Server {
-	router := mux.NewRouter()
 	return &Server{
 		tlsCertificatePath: certificatePath,
 		tlsCertificateKey:  certificateKey,
@@ -310,8 +501,8 @@ func NewTLSServer(certificatePath string, certificateKey string, tlsConfig *tls.
 			TLSConfig: tlsConfig,
 		},
 		timeoutConfig: NewServerTimeoutConfig(),
+		closeTimeout:  defaultCloseTimeout,
 		upgrader:      websocket.Upgrader{Subprotocols: []string{}},
-		httpHandler:   router,
 	}
 }
 
@@ -319,10 +510,18 @@ func (server *Server) SetMessageHandler(handler func(ws Channel, data []byte) er
 	server.messageHandler = handler
 }
 
+func (server *Server) SetBinaryMessageHandler(handler func(ws Channel, data []byte) error) {
+	server.binaryHandler = handler
+}
+
 func (server *Server) SetCheckClientHandler(handler func(id string, r *http.Request) bool) {
 	server.checkClientHandler = handler
 }
 
+func (server *Server) SetConnectionAuthorizer(handler ConnectionAuthorizer) {
+	server.authorizer = handler
+}
+
 func (server *Server) SetNewClientHandler(handler func(ws Channel)) {
 	server.newClientHandler = handler
 }
@@ -335,6 +534,10 @@ func (server *Server) SetTimeoutConfig(config ServerTimeoutConfig) {
 	server.timeoutConfig = config
 }
 
+func (server *Server) SetCloseTimeout(d time.Duration) {
+	server.closeTimeout = d
+}
+
 func (server *Server) AddSupportedSubprotocol(subProto string) {
 	for _, sub := range server.upgrader.Subprotocols {
 		if sub == subProto {
@@ -353,6 +556,55 @@ func (server *Server) SetCheckOriginHandler(handler func(r *http.Request) bool)
 	server.upgrader.CheckOrigin = handler
 }
 
+// SetCompression enables or disables the negotiation of the permessage-deflate extension (RFC 7692) with clients.
+// Outgoing messages are only compressed on connections, for which the client also requested the extension.
+// The extension is always negotiated without context takeover, the only mode supported by the underlying websocket implementation.
+//
+// This function must be called before starting the server.
+func (server *Server) SetCompression(enabled bool) {
+	server.upgrader.EnableCompression = enabled
+}
+
+// SetCompressionThreshold sets the minimum size in bytes, from which outgoing messages are compressed.
+// Smaller messages (e.g. heartbeats) are sent uncompressed, even if compression was negotiated,
+// as compressing them costs more CPU than it saves bandwidth.
+//
+// A threshold <= 0 compresses all messages, once compression was negotiated (default).
+func (server *Server) SetCompressionThreshold(bytes int) {
+	server.compressionMinSize = bytes
+}
+
+// SetRTTMeasurement enables the measurement of round-trip times on all connections, by sending a ping to every
+// client at the given period. Every ping carries a sequence number, which the client echoes back in its pong.
+// The measured values may be retrieved via the LastRTT and AverageRTT methods of each connection.
+//
+// Servers don't send any pings by default. A period <= 0 disables the measurement.
+// This function must be called before starting the server.
+func (server *Server) SetRTTMeasurement(pingPeriod time.Duration) {
+	server.rttPingPeriod = pingPeriod
+}
+
+// SetTLSPolicy enforces a minimum TLS version and, optionally, an allowlist of cipher suites on a TLS server.
+// Clients which don't support the policy are rejected during the TLS handshake.
+//
+// The policy is applied to a copy of the TLS configuration passed to NewTLSServer, while all other settings are kept as is.
+// Cipher suites only apply to TLS 1.2 and lower, as TLS 1.3 cipher suites aren't configurable.
+// Weak settings are logged, see CheckTLSConfig.
+//
+// Returns an error if the server doesn't use TLS, or if the version or a cipher suite are unknown.
+// Must be invoked before starting the server.
+func (server *Server) SetTLSPolicy(minVersion uint16, cipherSuites []uint16) error {
+	if server.tlsCertificatePath == "" {
+		return fmt.Errorf("cannot set TLS policy on a server without TLS")
+	}
+	if err := validateTLSPolicy(minVersion, cipherSuites); err != nil {
+		return err
+	}
+	server.httpServer.TLSConfig = applyTLSPolicy(server.httpServer.TLSConfig, minVersion, cipherSuites)
+	logTLSWarnings(server.httpServer.TLSConfig)
+	return nil
+}
+
 func (server *Server) error(err error) {
 	log.Error(err)
 	if server.errC != nil {
@@ -371,39 +623,109 @@ func (server *Server) Addr() *net.TCPAddr {
 	return server.addr
 }
 
+// AddHttpHandler registers a handler for the given path pattern. Handlers may be added before or after starting the server.
 func (server *Server) AddHttpHandler(listenPath string, handler func(w http.ResponseWriter, r *http.Request)) {
-	server.httpHandler.HandleFunc(listenPath, handler)
+	server.routesMutex.Lock()
+	defer server.routesMutex.Unlock()
+	server.routes = append(server.routes, httpRoute{path: listenPath, handler: handler})
+	// The router mustn't be modified while serving requests, hence a new one is built and swapped in
+	router := mux.NewRouter()
+	for _, route := range server.routes {
+		router.HandleFunc(route.path, route.handler)
+	}
+	server.router.Store(router)
+}
+
+// Dispatches an HTTP request to the handler registered for its path.
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	router, ok := server.router.Load().(*mux.Router)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	router.ServeHTTP(w, r)
 }
 
 func (server *Server) Start(port int, listenPath string) {
-	server.connMutex.Lock()
-	server.connections = make(map[string]*WebSocket)
-	server.connMutex.Unlock()
+	ln, err := server.listen(port, listenPath)
+	if err != nil {
+		server.error(err)
+		return
+	}
+	if err = server.serve(ln); err != nil {
+		server.error(err)
+	}
+}
 
-	if server.httpServer == nil {
-		server.httpServer = &http.Server{}
+func (server *Server) StartWithContext(ctx context.Context, port int, listenPath string) error {
+	ln, err := server.listen(port, listenPath)
+	if err != nil {
+		return err
 	}
+	// Stop the server as soon as the context is done
+	doneC := make(chan struct{})
+	defer close(doneC)
+	go func() {
+		select {
+		case <-ctx.Done():
+			server.Stop()
+		case <-doneC:
+		}
+	}()
+	return server.serve(ln)
+}
 
-	addr := fmt.Sprintf(":%v", port)
-	server.httpServer.Addr = addr
+func (server *Server) StartOnListener(listener net.Listener, listenPath string) error {
+	server.prepare(listenPath)
+	server.addr, _ = listener.Addr().(*net.TCPAddr)
+	log.Infof("listening on %v network %v", listener.Addr().Network(), listener.Addr())
+	return server.serve(listener)
+}
 
+func (server *Server) AddListenPath(listenPath string) {
 	server.AddHttpHandler(listenPath, func(w http.ResponseWriter, r *http.Request) {
 		server.wsHandler(w, r)
 	})
-	server.httpServer.Handler = server.httpHandler
+}
+
+// Prepares the HTTP server and opens the TCP listener.
+func (server *Server) listen(port int, listenPath string) (net.Listener, error) {
+	server.prepare(listenPath)
+	addr := fmt.Sprintf(":%v", port)
+	server.httpServer.Addr = addr
 
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
-		server.error(fmt.Errorf("failed to listen: %w", err))
-		return
+		return nil, fmt.Errorf("failed to listen: %w", err)
 	}
 
 	server.addr = ln.Addr().(*net.TCPAddr)
+	log.Infof("listening on tcp network %v", addr)
+	return ln, nil
+}
+
+// Resets the connections and prepares the HTTP server for handling websocket requests on the listen path.
+func (server *Server) prepare(listenPath string) {
+	server.connMutex.Lock()
+	server.connections = make(map[string]*WebSocket)
+	server.connMutex.Unlock()
+
+	if server.httpServer == nil {
+		server.httpServer = &http.Server{}
+	}
 
+	server.AddListenPath(listenPath)
+	server.httpServer.Handler = http.HandlerFunc(server.serveHTTP)
+	server.applyHandshakeTimeout()
+}
+
+// Serves incoming connections on the listener, until the server is stopped.
+// Returns nil if the server was stopped gracefully.
+func (server *Server) serve(ln net.Listener) error {
 	defer ln.Close()
 
-	log.Infof("listening on tcp network %v", addr)
 	server.httpServer.RegisterOnShutdown(server.stopConnections)
+	var err error
 	if server.tlsCertificatePath != "" && server.tlsCertificateKey != "" {
 		err = server.httpServer.ServeTLS(ln, server.tlsCertificatePath, server.tlsCertificateKey)
 	} else {
@@ -411,8 +733,9 @@ func (server *Server) Start(port int, listenPath string) {
 	}
 
 	if err != http.ErrServerClosed {
-		server.error(fmt.Errorf("failed to listen: %w", err))
+		return fmt.Errorf("failed to listen: %w", err)
 	}
+	return nil
 }
 
 func (server *Server) Stop() {
@@ -429,15 +752,19 @@ func (server *Server) Stop() {
 }
 
 func (server *Server) StopConnection(id string, closeError websocket.CloseError) error {
+	// The lock is held while signaling, as the connection cleanup closes the channel
 	server.connMutex.RLock()
+	defer server.connMutex.RUnlock()
 	ws, ok := server.connections[id]
-	server.connMutex.RUnlock()
-
 	if !ok {
 		return fmt.Errorf("couldn't stop websocket connection. No connection with id %s is open", id)
 	}
 	log.Debugf("sending stop signal for websocket %s", ws.ID())
-	ws.closeC <- closeError
+	select {
+	case ws.closeC <- closeError:
+	default:
+		// A stop signal is already pending
+	}
 	return nil
 }
 
@@ -450,6 +777,14 @@ func (server *Server) stopConnections() {
 }
 
 func (server *Server) Write(webSocketId string, data []byte) error {
+	return server.write(webSocketId, outMessage{messageType: websocket.TextMessage, data: data})
+}
+
+func (server *Server) WriteBinary(webSocketId string, data []byte) error {
+	return server.write(webSocketId, outMessage{messageType: websocket.BinaryMessage, data: data})
+}
+
+func (server *Server) write(webSocketId string, message outMessage) error {
 	server.connMutex.RLock()
 	defer server.connMutex.RUnlock()
 	ws, ok := server.connections[webSocketId]
@@ -457,35 +792,40 @@ func (server *Server) Write(webSocketId string, data []byte) error {
 		return fmt.Errorf("couldn't write to websocket. No socket with id %v is open", webSocketId)
 	}
 	log.Debugf("queuing data for websocket %s", webSocketId)
-	ws.outQueue <- data
+	ws.outQueue <- message
 	return nil
 }
 
-func (server *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
-	responseHeader := http.Header{}
-	url := r.URL
-	id := path.Base(url.Path)
-	log.Debugf("handling new connection for %s from %s", id, r.RemoteAddr)
-	// Negotiate sub-protocol
-	clientSubprotocols := websocket.Subprotocols(r)
-	negotiatedSuprotocol := ""
-out:
+// Returns the first subprotocol requested by the client, which is also supported by the server.
+// If the server has no configured subprotocols, the first requested subprotocol is accepted.
+// An empty string is returned, if no common subprotocol exists.
+func (server *Server) negotiateSubprotocol(clientSubprotocols []string) string {
 	for _, requestedProto := range clientSubprotocols {
 		if len(server.upgrader.Subprotocols) == 0 {
 			// All subProtocols are accepted, pick first
-			negotiatedSuprotocol = requestedProto
-			break
+			return requestedProto
 		}
 		// Check if requested suprotocol is supported by server
 		for _, supportedProto := range server.upgrader.Subprotocols {
 			if requestedProto == supportedProto {
-				negotiatedSuprotocol = requestedProto
-				break out
+				return requestedProto
 			}
 		}
 	}
+	return ""
+}
+
+func (server *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
+	responseHeader := http.Header{}
+	url := r.URL
+	id := path.Base(url.Path)
+	log.Debugf("handling new connection for %s from %s", id, r.RemoteAddr)
+	// Negotiate sub-protocol. The negotiated subprotocol is always echoed in the handshake response,
+	// as some clients refuse to complete the handshake otherwise.
+	clientSubprotocols := websocket.Subprotocols(r)
+	negotiatedSuprotocol := server.negotiateSubprotocol(clientSubprotocols)
 	if negotiatedSuprotocol != "" {
-		responseHeader.Add("Sec-WebSocket-Protocol", negotiatedSuprotocol)
+		responseHeader.Set("Sec-WebSocket-Protocol", negotiatedSuprotocol)
 	}
 	// Handle client authentication
 	if server.basicAuthHandler != nil {
@@ -510,22 +850,51 @@ out:
 		}
 	}
 
-	// Upgrade websocket
-	conn, err := server.upgrader.Upgrade(w, r, responseHeader)
+	ctx := context.Background()
+	if server.authorizer != nil {
+		accept, authorizedCtx, err := server.authorizer(r)
+		if err != nil {
+			server.error(fmt.Errorf("connection authorization failed for %s: %w", id, err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !accept {
+			server.error(fmt.Errorf("connection authorization: connection denied for %s", id))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if authorizedCtx != nil {
+			ctx = authorizedCtx
+		}
+	}
+
+	// Upgrade websocket. Subprotocol selection is disabled on the upgrader,
+	// so that the previously negotiated subprotocol is the one echoed to the client.
+	upgrader := server.upgrader
+	upgrader.Subprotocols = nil
+	conn, err := upgrader.Upgrade(w, r, responseHeader)
 	if err != nil {
 		server.error(fmt.Errorf("upgrade failed: %w", err))
 		return
 	}
 
 	// The id of the charge point is the final path element
+	ctx, cancel := context.WithCancel(ctx)
 	ws := WebSocket{
 		connection:         conn,
 		id:                 id,
-		outQueue:           make(chan []byte, 1),
+		outQueue:           make(chan outMessage, 1),
 		closeC:             make(chan websocket.CloseError, 1),
 		forceCloseC:        make(chan error, 1),
 		pingMessage:        make(chan []byte, 1),
 		tlsConnectionState: r.TLS,
+		queryParams:        r.URL.Query(),
+		data:               map[string]interface{}{},
+		ctx:                ctx,
+		cancel:             cancel,
+	}
+	if server.rttPingPeriod > 0 {
+		ws.rtt = &rttStats{}
 	}
 	log.Debugf("upgraded websocket connection for %s from %s", id, conn.RemoteAddr().String())
 	// If unsupported subprotocol, terminate the connection immediately
@@ -535,6 +904,7 @@ out:
 			websocket.FormatCloseMessage(websocket.CloseProtocolError, "invalid or unsupported subprotocol"),
 			time.Now().Add(server.timeoutConfig.WriteWait))
 		_ = conn.Close()
+		cancel()
 		return
 	}
 	// Check whether client exists
@@ -547,6 +917,7 @@ out:
 			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "a connection with this ID already exists"),
 			time.Now().Add(server.timeoutConfig.WriteWait))
 		_ = conn.Close()
+		cancel()
 		return
 	}
 	// Add new client
@@ -577,10 +948,17 @@ func (server *Server) readPump(ws *WebSocket) {
 		err := conn.SetReadDeadline(server.getReadTimeout())
 		return err
 	})
+	conn.SetPongHandler(func(appData string) error {
+		log.Debugf("pong received from %s", ws.ID())
+		if rtt, ok := ws.rtt.pongReceived([]byte(appData)); ok {
+			log.Debugf("measured round-trip time %v for %s", rtt, ws.ID())
+		}
+		return nil
+	})
 	_ = conn.SetReadDeadline(server.getReadTimeout())
 
 	for {
-		_, message, err := conn.ReadMessage()
+		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
 				server.error(fmt.Errorf("read failed unexpectedly for %s: %w", ws.ID(), err))
@@ -591,9 +969,13 @@ func (server *Server) readPump(ws *WebSocket) {
 			return
 		}
 
-		if server.messageHandler != nil {
+		handler := server.messageHandler
+		if messageType == websocket.BinaryMessage && server.binaryHandler != nil {
+			handler = server.binaryHandler
+		}
+		if handler != nil {
 			var channel Channel = ws
-			err = server.messageHandler(channel, message)
+			err = handler(channel, message)
 			if err != nil {
 				server.error(fmt.Errorf("handling failed for %s: %w", ws.ID(), err))
 				continue
@@ -605,10 +987,17 @@ func (server *Server) readPump(ws *WebSocket) {
 
 func (server *Server) writePump(ws *WebSocket) {
 	conn := ws.connection
+	// Pings are only sent for measuring round-trip times
+	var pingC <-chan time.Time
+	if ws.rtt != nil {
+		ticker := time.NewTicker(server.rttPingPeriod)
+		defer ticker.Stop()
+		pingC = ticker.C
+	}
 
 	for {
 		select {
-		case data, ok := <-ws.outQueue:
+		case message, ok := <-ws.outQueue:
 			_ = conn.SetWriteDeadline(time.Now().Add(server.timeoutConfig.WriteWait))
 			if !ok {
 				// Unexpected closed queue, should never happen
@@ -617,14 +1006,15 @@ func (server *Server) writePump(ws *WebSocket) {
 				return
 			}
 			// Send data
-			err := conn.WriteMessage(websocket.TextMessage, data)
+			applyCompressionThreshold(conn, server.compressionMinSize, len(message.data))
+			err := conn.WriteMessage(message.messageType, message.data)
 			if err != nil {
 				server.error(fmt.Errorf("write failed for %s: %w", ws.ID(), err))
 				// Invoking cleanup, as socket was forcefully closed
 				server.cleanupConnection(ws)
 				return
 			}
-			log.Debugf("written %d bytes to %s", len(data), ws.ID())
+			log.Debugf("written %d bytes to %s", len(message.data), ws.ID())
 		case ping := <-ws.pingMessage:
 			_ = conn.SetWriteDeadline(time.Now().Add(server.timeoutConfig.WriteWait))
 			err := conn.WriteMessage(websocket.PongMessage, ping)
@@ -635,6 +1025,15 @@ func (server *Server) writePump(ws *WebSocket) {
 				return
 			}
 			log.Debugf("pong sent to %s", ws.ID())
+		case <-pingC:
+			_ = conn.SetWriteDeadline(time.Now().Add(server.timeoutConfig.WriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, ws.rtt.newPing()); err != nil {
+				server.error(fmt.Errorf("failed to send ping to %s: %w", ws.ID(), err))
+				// Invoking cleanup, as socket was forcefully closed
+				server.cleanupConnection(ws)
+				return
+			}
+			log.Debugf("ping sent to %s", ws.ID())
 		case closeErr := <-ws.closeC:
 			log.Debugf("closing connection to %s", ws.ID())
 			// Closing connection gracefully
@@ -644,6 +1043,8 @@ func (server *Server) writePump(ws *WebSocket) {
 				time.Now().Add(server.timeoutConfig.WriteWait),
 			); err != nil {
 				server.error(fmt.Errorf("failed to write close message for connection %s: %w", ws.id, err))
+			} else if !waitForCloseAck(ws, server.closeTimeout) {
+				log.Infof("close handshake with %s timed out, forcefully closing connection", ws.ID())
 			}
 			// Invoking cleanup
 			server.cleanupConnection(ws)
@@ -672,6 +1073,9 @@ func (server *Server) cleanupConnection(ws *WebSocket) {
 	if server.disconnectedHandler != nil {
 		server.disconnectedHandler(ws)
 	}
+	// Custom values and the connection context are available to the disconnected handler, but not afterwards
+	ws.clearData()
+	ws.cancel()
 }
 
 // ---------------------- CLIENT ----------------------
@@ -744,6 +1148,18 @@ type WsClient interface {
 	//
 	// This function must be called before connecting to the server, otherwise it may lead to unexpected behavior.
 	SetTimeoutConfig(config ClientTimeoutConfig)
+	// Sets the maximum time to wait for the server to acknowledge a close frame, when the client is stopped.
+	// Once the timeout expires, the underlying TCP connection is closed forcefully.
+	// A zero duration disables waiting for the acknowledgement altogether.
+	//
+	// By default, the acknowledgement isn't awaited and the connection is closed right after sending the close frame.
+	SetCloseTimeout(d time.Duration)
+	// Sets the maximum time to wait for the websocket handshake to complete (i.e. for the server's 101 response),
+	// once the TCP connection was established. Connecting is still bounded by the HandshakeTimeout of the ClientTimeoutConfig,
+	// which covers the entire connection attempt.
+	//
+	// This function must be called before connecting to the server. By default, only the ClientTimeoutConfig applies.
+	SetHandshakeTimeout(d time.Duration)
 	// Sets a callback function for receiving notifications about an unexpected disconnection from the server.
 	// The callback is invoked even if the automatic reconnection mechanism is active.
 	//
@@ -754,6 +1170,13 @@ type WsClient interface {
 	//
 	// If set, the DisconnectedHandler will always be invoked before the Reconnected callback is invoked.
 	SetReconnectedHandler(handler func())
+	// Sets a callback function for receiving notifications about every automatic reconnection attempt.
+	// The callback is invoked before waiting for the next attempt, passing the attempt number (starting at 1)
+	// and the delay after which the attempt will be performed, as computed by the backoff policy.
+	//
+	// The callbacks of a reconnection cycle are invoked in order: DisconnectedHandler, ReconnectAttemptHandler
+	// (once per attempt) and finally ReconnectedHandler, once the connection was re-established.
+	SetReconnectAttemptHandler(handler func(attempt int, nextDelay time.Duration))
 	// IsConnected Returns information about the current connection status.
 	// If the client is currently attempting to auto-reconnect to the server, the function returns false.
 	IsConnected() bool
@@ -761,6 +1184,14 @@ type WsClient interface {
 	//
 	// The data is queued and will be sent asynchronously in the background.
 	Write(data []byte) error
+	// Sends a binary message to the server over the websocket.
+	// Binary frames are queued together with text messages, hence the order of all messages is preserved.
+	//
+	// The data is queued and will be sent asynchronously in the background.
+	WriteBinary(data []byte) error
+	// Sets a callback function for all incoming binary messages.
+	// If no binary handler is set, binary messages are passed to the regular message handler.
+	SetBinaryMessageHandler(handler func(data []byte) error)
 	// Adds a websocket option to the client.
 	AddOption(option interface{})
 	// SetRequestedSubProtocol will negotiate the specified sub-protocol during the websocket handshake.
@@ -781,18 +1212,29 @@ type WsClient interface {
 //
 // Use the NewClient or NewTLSClient functions to create a new client.
 type Client struct {
-	webSocket      WebSocket
-	url            url.URL
-	messageHandler func(data []byte) error
-	dialOptions    []func(*websocket.Dialer)
-	header         http.Header
-	timeoutConfig  ClientTimeoutConfig
-	connected      bool
-	onDisconnected func(err error)
-	onReconnected  func()
-	mutex          sync.Mutex
-	errC           chan error
-	reconnectC     chan struct{} // used for signaling, that a reconnection attempt should be interrupted
+	webSocket          WebSocket
+	url                url.URL
+	messageHandler     func(data []byte) error
+	binaryHandler      func(data []byte) error
+	dialOptions        []func(*websocket.Dialer)
+	header             http.Header
+	timeoutConfig      ClientTimeoutConfig
+	closeTimeout       time.Duration
+	handshakeTimeout   time.Duration
+	connected          bool
+	onDisconnected     func(err error)
+	onReconnected      func()
+	onReconnectAttempt func(attempt int, nextDelay time.Duration)
+	measureRTT         bool
+	compression        bool
+	compressionMinSize int
+	tlsMinVersion      uint16
+	tlsCipherSuites    []uint16
+	tlsWarningsOnce    sync.Once
+	rtt                *rttStats
+	mutex              sync.Mutex
+	errC               chan error
+	reconnectC         chan struct{} // used for signaling, that a reconnection attempt should be interrupted
 }
 
 // Creates a new simple websocket client (the channel is not secured).
@@ -807,6 +1249,7 @@ func NewClient() *Client {
 	return &Client{
 		dialOptions:   []func(*websocket.Dialer){},
 		timeoutConfig: NewClientTimeoutConfig(),
+		closeTimeout:  defaultCloseTimeout,
 		header:        http.Header{},
 	}
 }
@@ -831,7 +1274,7 @@ func NewClient() *Client {
 //
 //	InsecureSkipVerify: true
 func NewTLSClient(tlsConfig *tls.Config) *Client {
-	client := &Client{dialOptions: []func(*websocket.Dialer){}, timeoutConfig: NewClientTimeoutConfig(), header: http.Header{}}
+	client := &Client{dialOptions: []func(*websocket.Dialer){}, timeoutConfig: NewClientTimeoutConfig(), closeTimeout: defaultCloseTimeout, header: http.Header{}}
 	client.dialOptions = append(client.dialOptions, func(dialer *websocket.Dialer) {
 		dialer.TLSClientConfig = tlsConfig
 	})
@@ -842,10 +1285,18 @@ func (client *Client) SetMessageHandler(handler func(data []byte) error) {
 	client.messageHandler = handler
 }
 
+func (client *Client) SetBinaryMessageHandler(handler func(data []byte) error) {
+	client.binaryHandler = handler
+}
+
 func (client *Client) SetTimeoutConfig(config ClientTimeoutConfig) {
 	client.timeoutConfig = config
 }
 
+func (client *Client) SetCloseTimeout(d time.Duration) {
+	client.closeTimeout = d
+}
+
 func (client *Client) SetDisconnectedHandler(handler func(err error)) {
 	client.onDisconnected = handler
 }
@@ -854,6 +1305,69 @@ func (client *Client) SetReconnectedHandler(handler func()) {
 	client.onReconnected = handler
 }
 
+func (client *Client) SetReconnectAttemptHandler(handler func(attempt int, nextDelay time.Duration)) {
+	client.onReconnectAttempt = handler
+}
+
+// SetRTTMeasurement enables or disables the measurement of round-trip times, using the periodic pings sent to the server.
+// When enabled, every ping carries a sequence number, which the server echoes back in the pong.
+// The measured values may be retrieved via LastRTT and AverageRTT, and are reset on every new connection.
+//
+// This function must be called before connecting to the server.
+func (client *Client) SetRTTMeasurement(enabled bool) {
+	client.measureRTT = enabled
+}
+
+// SetCompression enables or disables requesting the permessage-deflate extension (RFC 7692) from the server.
+// Outgoing messages are only compressed, if the server accepted the extension.
+// The extension is always negotiated without context takeover, the only mode supported by the underlying websocket implementation.
+//
+// This function must be called before connecting to the server.
+func (client *Client) SetCompression(enabled bool) {
+	client.compression = enabled
+}
+
+// SetCompressionThreshold sets the minimum size in bytes, from which outgoing messages are compressed.
+// Smaller messages (e.g. heartbeats) are sent uncompressed, even if compression was negotiated,
+// as compressing them costs more CPU than it saves bandwidth.
+//
+// A threshold <= 0 compresses all messages, once compression was negotiated (default).
+func (client *Client) SetCompressionThreshold(bytes int) {
+	client.compressionMinSize = bytes
+}
+
+// SetTLSPolicy enforces a minimum TLS version and, optionally, an allowlist of cipher suites when connecting to a server.
+//
+// The policy is applied on top of the TLS configuration resulting from NewTLSClient and all dial options,
+// so it cannot be overridden accidentally. Cipher suites only apply to TLS 1.2 and lower.
+// Weak settings are logged once, on the first connection attempt, see CheckTLSConfig.
+//
+// Returns an error if the version or a cipher suite are unknown.
+func (client *Client) SetTLSPolicy(minVersion uint16, cipherSuites []uint16) error {
+	if err := validateTLSPolicy(minVersion, cipherSuites); err != nil {
+		return err
+	}
+	client.tlsMinVersion = minVersion
+	client.tlsCipherSuites = append([]uint16{}, cipherSuites...)
+	return nil
+}
+
+// Returns the round-trip time measured for the most recent ping/pong exchange on the current connection.
+// Returns 0 if round-trip times aren't measured, or no pong was received yet.
+func (client *Client) LastRTT() time.Duration {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.rtt.lastRTT()
+}
+
+// Returns the smoothed round-trip time of the current connection.
+// Returns 0 if round-trip times aren't measured, or no pong was received yet.
+func (client *Client) AverageRTT() time.Duration {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.rtt.averageRTT()
+}
+
 func (client *Client) AddOption(option interface{}) {
 	dialOption, ok := option.(func(*websocket.Dialer))
 	if ok {
@@ -903,26 +1417,30 @@ func (client *Client) writePump() {
 		if client.onDisconnected != nil {
 			client.onDisconnected(err)
 		}
+		// Custom values and the connection context are available to the disconnected handler, but not afterwards
+		client.webSocket.clearData()
+		client.webSocket.cancel()
 	}
 
 	for {
 		select {
-		case data := <-client.webSocket.outQueue:
+		case message := <-client.webSocket.outQueue:
 			// Send data
 			log.Debugf("sending data")
 			_ = conn.SetWriteDeadline(time.Now().Add(client.timeoutConfig.WriteWait))
-			err := conn.WriteMessage(websocket.TextMessage, data)
+			applyCompressionThreshold(conn, client.compressionMinSize, len(message.data))
+			err := conn.WriteMessage(message.messageType, message.data)
 			if err != nil {
 				client.error(fmt.Errorf("write failed: %w", err))
 				closure(err)
 				client.handleReconnection()
 				return
 			}
-			log.Debugf("written %d bytes", len(data))
+			log.Debugf("written %d bytes", len(message.data))
 		case <-ticker.C:
 			// Send periodic ping
 			_ = conn.SetWriteDeadline(time.Now().Add(client.timeoutConfig.WriteWait))
-			if err := conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			if err := conn.WriteMessage(websocket.PingMessage, client.webSocket.rtt.newPing()); err != nil {
 				client.error(fmt.Errorf("failed to send ping message: %w", err))
 				closure(err)
 				client.handleReconnection()
@@ -938,6 +1456,8 @@ func (client *Client) writePump() {
 				time.Now().Add(client.timeoutConfig.WriteWait),
 			); err != nil {
 				client.error(fmt.Errorf("failed to write close message: %w", err))
+			} else if !waitForCloseAck(&client.webSocket, client.closeTimeout) {
+				log.Info("close handshake timed out, forcefully closing connection")
 			}
 			// Disconnected by user command. Not calling auto-reconnect.
 			// Passing nil will also not call onDisconnected.
@@ -958,12 +1478,15 @@ func (client *Client) writePump() {
 func (client *Client) readPump() {
 	conn := client.webSocket.connection
 	_ = conn.SetReadDeadline(client.getReadTimeout())
-	conn.SetPongHandler(func(string) error {
+	conn.SetPongHandler(func(appData string) error {
 		log.Debugf("pong received")
+		if rtt, ok := client.webSocket.rtt.pongReceived([]byte(appData)); ok {
+			log.Debugf("measured round-trip time %v", rtt)
+		}
 		return conn.SetReadDeadline(client.getReadTimeout())
 	})
 	for {
-		_, message, err := conn.ReadMessage()
+		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
 				client.error(fmt.Errorf("read failed: %w", err))
@@ -974,8 +1497,12 @@ func (client *Client) readPump() {
 		}
 
 		log.Debugf("received %v bytes", len(message))
-		if client.messageHandler != nil {
-			err = client.messageHandler(message)
+		handler := client.messageHandler
+		if messageType == websocket.BinaryMessage && client.binaryHandler != nil {
+			handler = client.binaryHandler
+		}
+		if handler != nil {
+			err = handler(message)
 			if err != nil {
 				client.error(fmt.Errorf("handle failed: %w", err))
 				continue
@@ -988,7 +1515,7 @@ func (client *Client) readPump() {
 // From this moment onwards, no new messages may be sent.
 func (client *Client) cleanup() {
 	client.setConnected(false)
-	ws := client.webSocket
+	ws := &client.webSocket
 	_ = ws.connection.Close()
 	client.mutex.Lock()
 	defer client.mutex.Unlock()
@@ -1001,6 +1528,9 @@ func (client *Client) handleReconnection() {
 	delay := client.timeoutConfig.RetryBackOffWaitMinimum + time.Duration(rand.Intn(client.timeoutConfig.RetryBackOffRandomRange+1))*time.Second
 	reconnectionAttempts := 1
 	for {
+		if client.onReconnectAttempt != nil {
+			client.onReconnectAttempt(reconnectionAttempts, delay)
+		}
 		// Wait before reconnecting
 		select {
 		case <-time.After(delay):
@@ -1042,11 +1572,19 @@ func (client *Client) IsConnected() bool {
 }
 
 func (client *Client) Write(data []byte) error {
+	return client.write(outMessage{messageType: websocket.TextMessage, data: data})
+}
+
+func (client *Client) WriteBinary(data []byte) error {
+	return client.write(outMessage{messageType: websocket.BinaryMessage, data: data})
+}
+
+func (client *Client) write(message outMessage) error {
 	if !client.IsConnected() {
 		return fmt.Errorf("client is currently not connected, cannot send data")
 	}
 	log.Debugf("queuing data for server")
-	client.webSocket.outQueue <- data
+	client.webSocket.outQueue <- message
 	return nil
 }
 
@@ -1066,14 +1604,23 @@ func (client *Client) Start(urlStr string) error {
 	}
 
 	dialer := websocket.Dialer{
-		ReadBufferSize:   1024,
-		WriteBufferSize:  1024,
-		HandshakeTimeout: client.timeoutConfig.HandshakeTimeout,
-		Subprotocols:     []string{},
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		HandshakeTimeout:  client.timeoutConfig.HandshakeTimeout,
+		Subprotocols:      []string{},
+		EnableCompression: client.compression,
 	}
 	for _, option := range client.dialOptions {
 		option(&dialer)
 	}
+	client.applyHandshakeTimeout(&dialer)
+	if client.tlsMinVersion != 0 {
+		dialer.TLSClientConfig = applyTLSPolicy(dialer.TLSClientConfig, client.tlsMinVersion, client.tlsCipherSuites)
+		// Weak settings are logged on the first connection attempt only, not on every reconnection
+		client.tlsWarningsOnce.Do(func() {
+			logTLSWarnings(dialer.TLSClientConfig)
+		})
+	}
 	// Connect
 	log.Info("connecting to server")
 	ws, resp, err := dialer.Dial(urlStr, client.header)
@@ -1094,14 +1641,25 @@ func (client *Client) Start(urlStr string) error {
 	// The id of the charge point is the final path element
 	id := path.Base(url.Path)
 
+	ctx, cancel := context.WithCancel(context.Background())
 	client.webSocket = WebSocket{
 		connection:         ws,
 		id:                 id,
-		outQueue:           make(chan []byte, 1),
+		outQueue:           make(chan outMessage, 1),
 		closeC:             make(chan websocket.CloseError, 1),
 		forceCloseC:        make(chan error, 1),
 		tlsConnectionState: resp.TLS,
+		data:               map[string]interface{}{},
+		ctx:                ctx,
+		cancel:             cancel,
 	}
+	client.mutex.Lock()
+	client.rtt = nil
+	if client.measureRTT {
+		client.rtt = &rttStats{}
+	}
+	client.webSocket.rtt = client.rtt
+	client.mutex.Unlock()
 	log.Infof("connected to server as %s", id)
 	client.reconnectC = make(chan struct{})
 	client.setConnected(true)
@@ -1151,3 +1709,11 @@ func (client *Client) Errors() <-chan error {
 func init() {
 	log = &logging.VoidLogger{}
 }
+
+// Enables compression for the next message written to the connection, only if the message reaches the threshold.
+// Compression only takes effect, if it was negotiated for the connection.
+func applyCompressionThreshold(conn *websocket.Conn, threshold int, size int) {
+	if threshold > 0 {
+		conn.EnableWriteCompression(size >= threshold)
+	}
+}
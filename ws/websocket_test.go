@@ -2,6 +2,7 @@ package ws
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -9,7 +10,9 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
 	"net/http"
@@ -17,12 +20,14 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/gorilla/websocket"
+	"github.com/lorenzodonini/ocpp-go/logging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -166,6 +171,521 @@ func TestWebsocketEcho(t *testing.T) {
 	wsServer.Stop()
 }
 
+func TestWebsocketBinaryEcho(t *testing.T) {
+	textMessage := []byte("Hello WebSocket!")
+	binaryMessage := []byte{0x00, 0xff, 0x10, 0x80, 0x7f, 0x00}
+	textC := make(chan []byte, 2)
+	binaryC := make(chan []byte, 2)
+	wsServer := newWebsocketServer(t, func(data []byte) ([]byte, error) {
+		textC <- data
+		return nil, nil
+	})
+	wsServer.SetBinaryMessageHandler(func(ws Channel, data []byte) error {
+		binaryC <- data
+		// Echo binary data back to the client
+		return wsServer.WriteBinary(ws.ID(), data)
+	})
+	wsClient := newWebsocketClient(t, func(data []byte) ([]byte, error) {
+		textC <- data
+		return nil, nil
+	})
+	wsClient.SetBinaryMessageHandler(func(data []byte) error {
+		binaryC <- data
+		return nil
+	})
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(200 * time.Millisecond)
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	// Binary frame reaches the binary handler intact, and is echoed back as binary frame
+	err = wsClient.WriteBinary(binaryMessage)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		select {
+		case data := <-binaryC:
+			assert.Equal(t, binaryMessage, data)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for binary message")
+		}
+	}
+	// Text frames still reach the regular message handler
+	err = wsClient.Write(textMessage)
+	require.NoError(t, err)
+	select {
+	case data := <-textC:
+		assert.Equal(t, textMessage, data)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for text message")
+	}
+	assert.Len(t, binaryC, 0)
+}
+
+func TestWebsocketMultipleListenPaths(t *testing.T) {
+	connectedC := make(chan string, 2)
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- ws.ID()
+	})
+	wsServer.AddListenPath("/legacy/{id}")
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(200 * time.Millisecond)
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	// Connect a client on each path
+	for _, clientPath := range []string{"/ws/client1", "/legacy/client2"} {
+		wsClient := newWebsocketClient(t, nil)
+		u := url.URL{Scheme: "ws", Host: host, Path: clientPath}
+		err := wsClient.Start(u.String())
+		require.NoError(t, err)
+		defer wsClient.Stop()
+		select {
+		case id := <-connectedC:
+			assert.Equal(t, path.Base(clientPath), id)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for client on path %v", clientPath)
+		}
+	}
+	// Both clients share the same connection registry
+	assert.NoError(t, wsServer.Write("client1", []byte("hello")))
+	assert.NoError(t, wsServer.Write("client2", []byte("hello")))
+	// Unregistered paths are still rejected
+	wsClient := newWebsocketClient(t, nil)
+	u := url.URL{Scheme: "ws", Host: host, Path: "/unknown/client3"}
+	err := wsClient.Start(u.String())
+	assert.Error(t, err)
+}
+
+func TestWebsocketAddListenPathAfterStart(t *testing.T) {
+	connectedC := make(chan string, 1)
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- ws.ID()
+	})
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(200 * time.Millisecond)
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	// Paths are added while the server is handling incoming connections
+	doneC := make(chan struct{})
+	go func() {
+		defer close(doneC)
+		for i := 0; i < 10; i++ {
+			wsServer.AddListenPath(fmt.Sprintf("/late%d/{id}", i))
+		}
+	}()
+	wsClient := newWebsocketClient(t, nil)
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws/client1"}
+	require.NoError(t, wsClient.Start(u.String()))
+	defer wsClient.Stop()
+	assert.Equal(t, "client1", <-connectedC)
+	<-doneC
+	// Clients may connect on the added paths
+	lateClient := newWebsocketClient(t, nil)
+	u = url.URL{Scheme: "ws", Host: host, Path: "/late9/client2"}
+	require.NoError(t, lateClient.Start(u.String()))
+	defer lateClient.Stop()
+	assert.Equal(t, "client2", <-connectedC)
+}
+
+// recordingConn records all raw bytes read from the underlying connection.
+type recordingConn struct {
+	net.Conn
+	mutex sync.Mutex
+	data  []byte
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mutex.Lock()
+	c.data = append(c.data, b[:n]...)
+	c.mutex.Unlock()
+	return n, err
+}
+
+// Returns the RSV1 (i.e. compressed) bit of every websocket frame received after the HTTP handshake.
+func (c *recordingConn) compressedFrames(t *testing.T) []bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	headerEnd := bytes.Index(c.data, []byte("\r\n\r\n"))
+	require.True(t, headerEnd >= 0)
+	frames := c.data[headerEnd+4:]
+	var compressed []bool
+	for len(frames) >= 2 {
+		length := int(frames[1] & 0x7f)
+		offset := 2
+		switch length {
+		case 126:
+			length = int(frames[2])<<8 | int(frames[3])
+			offset = 4
+		case 127:
+			t.Fatal("unexpected frame size")
+		}
+		compressed = append(compressed, frames[0]&0x40 != 0)
+		require.True(t, len(frames) >= offset+length)
+		frames = frames[offset+length:]
+	}
+	return compressed
+}
+
+func TestWebsocketCompressionThreshold(t *testing.T) {
+	smallMessage := []byte(`[2,"1234","Heartbeat",{}]`)
+	largeMessage := []byte(fmt.Sprintf(`[2,"5678","DataTransfer",{"vendorId":"vendor","data":"%v"}]`, strings.Repeat("a", 4096)))
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.SetCompression(true)
+	wsServer.SetCompressionThreshold(1024)
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(200 * time.Millisecond)
+	receivedC := make(chan []byte, 2)
+	wsClient := newWebsocketClient(t, func(data []byte) ([]byte, error) {
+		receivedC <- data
+		return nil, nil
+	})
+	wsClient.SetCompression(true)
+	var conn *recordingConn
+	wsClient.AddOption(func(dialer *websocket.Dialer) {
+		dialer.NetDial = func(network, addr string) (net.Conn, error) {
+			c, err := net.Dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+			conn = &recordingConn{Conn: c}
+			return conn, nil
+		}
+	})
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	// Send both messages and wait for the client to receive them
+	for _, message := range [][]byte{smallMessage, largeMessage} {
+		require.NoError(t, wsServer.Write(path.Base(testPath), message))
+		select {
+		case data := <-receivedC:
+			assert.Equal(t, message, data)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+	// Only the large message was compressed, and it was smaller than the original on the wire
+	require.NotNil(t, conn)
+	assert.Equal(t, []bool{false, true}, conn.compressedFrames(t))
+	conn.mutex.Lock()
+	assert.Less(t, len(conn.data), len(largeMessage))
+	conn.mutex.Unlock()
+}
+
+func TestWebsocketStartWithContext(t *testing.T) {
+	wsServer := newWebsocketServer(t, nil)
+	connectedC := make(chan struct{}, 1)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- struct{}{}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	resultC := make(chan error, 1)
+	go func() {
+		resultC <- wsServer.StartWithContext(ctx, serverPort, serverPath)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	// Starting a second server on the same port returns the listen error
+	err := newWebsocketServer(t, nil).StartWithContext(context.Background(), serverPort, serverPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to listen")
+	// Connect client
+	disconnectedC := make(chan struct{}, 1)
+	wsClient := newWebsocketClient(t, nil)
+	wsClient.SetDisconnectedHandler(func(err error) {
+		disconnectedC <- struct{}{}
+	})
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	err = wsClient.Start(u.String())
+	require.NoError(t, err)
+	<-connectedC
+	// Cancel context, the server shuts down gracefully and the function returns
+	cancel()
+	select {
+	case err = <-resultC:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server didn't stop after context cancellation")
+	}
+	select {
+	case <-disconnectedC:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client wasn't disconnected after context cancellation")
+	}
+	wsClient.Stop()
+	// The port is released
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%v", serverPort))
+	require.NoError(t, err)
+	_ = ln.Close()
+}
+
+func TestWebsocketStartOnUnixListener(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ocpp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "ws.sock")
+	ln, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	message := []byte("Hello WebSocket!")
+	connectedC := make(chan Channel, 1)
+	wsServer := newWebsocketServer(t, func(data []byte) ([]byte, error) {
+		return data, nil
+	})
+	wsServer.AddSupportedSubprotocol(defaultSubProtocol)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- ws
+	})
+	resultC := make(chan error, 1)
+	go func() {
+		resultC <- wsServer.StartOnListener(ln, serverPath)
+	}()
+	// Client dials the unix socket, regardless of the host in the URL
+	echoC := make(chan []byte, 1)
+	wsClient := newWebsocketClient(t, func(data []byte) ([]byte, error) {
+		echoC <- data
+		return nil, nil
+	})
+	wsClient.AddOption(func(dialer *websocket.Dialer) {
+		dialer.NetDial = func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		}
+	})
+	u := url.URL{Scheme: "ws", Host: "localhost", Path: testPath}
+	err = wsClient.Start(u.String())
+	require.NoError(t, err)
+	select {
+	case ws := <-connectedC:
+		assert.Equal(t, path.Base(testPath), ws.ID())
+		assert.Equal(t, "unix", ws.RemoteAddr().Network())
+	case <-time.After(2 * time.Second):
+		t.Fatal("client didn't connect over unix socket")
+	}
+	assert.Nil(t, wsServer.Addr())
+	err = wsClient.Write(message)
+	require.NoError(t, err)
+	select {
+	case data := <-echoC:
+		assert.Equal(t, message, data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("echo not received")
+	}
+	wsClient.Stop()
+	wsServer.Stop()
+	select {
+	case err = <-resultC:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
+
+func TestWebsocketConnectionData(t *testing.T) {
+	message := []byte("Hello WebSocket!")
+	key := "session"
+	session := struct{ Counter int }{Counter: 1}
+	receivedC := make(chan interface{}, 1)
+	disconnectedC := make(chan Channel, 1)
+	wsServer := NewServer()
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		_, ok := ws.Get(key)
+		assert.False(t, ok)
+		ws.Set(key, session)
+	})
+	wsServer.SetMessageHandler(func(ws Channel, data []byte) error {
+		// Value set in previous handler is available
+		value, ok := ws.Get(key)
+		assert.True(t, ok)
+		receivedC <- value
+		return nil
+	})
+	wsServer.SetDisconnectedClientHandler(func(ws Channel) {
+		// Values are still available while handling the disconnection
+		_, ok := ws.Get(key)
+		assert.True(t, ok)
+		disconnectedC <- ws
+	})
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	defer wsServer.Stop()
+	// Connect client and send message
+	wsClient := newWebsocketClient(t, nil)
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	err = wsClient.Write(message)
+	require.NoError(t, err)
+	value := <-receivedC
+	assert.Equal(t, session, value)
+	// Disconnect, values are cleared afterwards
+	wsClient.Stop()
+	ws := <-disconnectedC
+	assert.Eventually(t, func() bool {
+		_, ok := ws.Get(key)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	// Values set after disconnection are discarded
+	ws.Set(key, session)
+	_, ok := ws.Get(key)
+	assert.False(t, ok)
+	ws.Delete(key)
+}
+
+func TestWebsocketClientConnectionDataAndContext(t *testing.T) {
+	key := "session"
+	session := struct{ Counter int }{Counter: 1}
+	wsServer := NewServer()
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	defer wsServer.Stop()
+	disconnectedC := make(chan struct{}, 1)
+	wsClient := newWebsocketClient(t, nil)
+	wsClient.SetDisconnectedHandler(func(err error) {
+		disconnectedC <- struct{}{}
+	})
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	// Values can be stored on client-side connections as well
+	ws := &wsClient.webSocket
+	ws.Set(key, session)
+	value, ok := ws.Get(key)
+	require.True(t, ok)
+	assert.Equal(t, session, value)
+	ctx := ws.Context()
+	assert.NoError(t, ctx.Err())
+	// Values are cleared and the context is cancelled once the connection was lost
+	wsServer.Stop()
+	<-disconnectedC
+	assert.Eventually(t, func() bool {
+		_, ok := ws.Get(key)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "connection context wasn't cancelled")
+	}
+	wsClient.Stop()
+}
+
+func TestWebsocketQueryParams(t *testing.T) {
+	connectedC := make(chan Channel, 1)
+	wsServer := NewServer()
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- ws
+	})
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	defer wsServer.Stop()
+	// Connect client with query parameters
+	wsClient := newWebsocketClient(t, nil)
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath, RawQuery: "firmware=1.2.3&token=abc&tag=a&tag=b"}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	ws := <-connectedC
+	// The query string doesn't affect the ID
+	assert.Equal(t, path.Base(testPath), ws.ID())
+	params := ws.QueryParams()
+	assert.Equal(t, "1.2.3", params.Get("firmware"))
+	assert.Equal(t, "abc", params.Get("token"))
+	assert.Equal(t, []string{"a", "b"}, params["tag"])
+	// Returned values are a copy
+	params.Set("token", "modified")
+	assert.Equal(t, "abc", ws.QueryParams().Get("token"))
+}
+
+func TestWebsocketRTTMeasurement(t *testing.T) {
+	latency := 50 * time.Millisecond
+	upgrader := websocket.Upgrader{Subprotocols: []string{defaultSubProtocol}}
+	// Custom server, delaying every pong by the injected latency
+	httpServer := &http.Server{Addr: fmt.Sprintf(":%v", serverPort), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		// Unmatched pong, must be ignored by the client
+		_ = conn.WriteControl(websocket.PongMessage, []byte("unsolicited"), time.Now().Add(time.Second))
+		conn.SetPingHandler(func(appData string) error {
+			go func() {
+				time.Sleep(latency)
+				_ = conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(time.Second))
+			}()
+			return nil
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})}
+	go func() {
+		_ = httpServer.ListenAndServe()
+	}()
+	defer httpServer.Close()
+	time.Sleep(100 * time.Millisecond)
+	wsClient := newWebsocketClient(t, nil)
+	config := NewClientTimeoutConfig()
+	config.PingPeriod = 100 * time.Millisecond
+	wsClient.SetTimeoutConfig(config)
+	wsClient.SetRTTMeasurement(true)
+	assert.Equal(t, time.Duration(0), wsClient.LastRTT())
+	u := url.URL{Scheme: "ws", Host: fmt.Sprintf("localhost:%v", serverPort), Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	time.Sleep(500 * time.Millisecond)
+	lastRTT := wsClient.LastRTT()
+	averageRTT := wsClient.AverageRTT()
+	assert.GreaterOrEqual(t, int64(lastRTT), int64(latency))
+	assert.Less(t, int64(lastRTT), int64(latency+100*time.Millisecond))
+	assert.GreaterOrEqual(t, int64(averageRTT), int64(latency))
+	assert.Less(t, int64(averageRTT), int64(latency+100*time.Millisecond))
+	// Measurement disabled by default
+	wsClient2 := newWebsocketClient(t, nil)
+	wsClient2.SetTimeoutConfig(config)
+	err = wsClient2.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient2.Stop()
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), wsClient2.LastRTT())
+	assert.Equal(t, time.Duration(0), wsClient2.AverageRTT())
+}
+
+func TestWebsocketServerRTTMeasurement(t *testing.T) {
+	connectedC := make(chan *WebSocket, 1)
+	wsServer := NewServer()
+	wsServer.SetRTTMeasurement(50 * time.Millisecond)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- ws.(*WebSocket)
+	})
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	defer wsServer.Stop()
+	wsClient := newWebsocketClient(t, nil)
+	u := url.URL{Scheme: "ws", Host: fmt.Sprintf("localhost:%v", serverPort), Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	ws := <-connectedC
+	// The client answers the pings sent by the server
+	assert.Eventually(t, func() bool {
+		return ws.LastRTT() > 0 && ws.AverageRTT() > 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Less(t, int64(ws.AverageRTT()), int64(50*time.Millisecond))
+	// No pings are sent by the client in the meantime, so it doesn't measure anything
+	assert.Equal(t, time.Duration(0), wsClient.LastRTT())
+}
+
 func TestWebsocketBootRetries(t *testing.T) {
 	verifyConnection := func(client *Client, connected bool) {
 		maxAttempts := 20
@@ -398,6 +918,91 @@ func TestServerStopConnection(t *testing.T) {
 	wsServer.Stop()
 }
 
+func TestWebsocketCloseHandshakeTimeout(t *testing.T) {
+	closeTimeout := 300 * time.Millisecond
+	connectedC := make(chan struct{}, 1)
+	disconnectedC := make(chan time.Time, 1)
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.SetCloseTimeout(closeTimeout)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- struct{}{}
+	})
+	wsServer.SetDisconnectedClientHandler(func(ws Channel) {
+		disconnectedC <- time.Now()
+	})
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(100 * time.Millisecond)
+	// Unresponsive peer, which never reads from the connection and therefore never acknowledges the close frame
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	dialer := websocket.Dialer{Subprotocols: []string{defaultSubProtocol}}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, ok := <-connectedC
+	require.True(t, ok)
+	// Close connection and wait for the server to forcefully close it
+	start := time.Now()
+	err = wsServer.StopConnection(path.Base(testPath), websocket.CloseError{Code: websocket.CloseNormalClosure})
+	require.NoError(t, err)
+	select {
+	case closedAt := <-disconnectedC:
+		assert.GreaterOrEqual(t, int64(closedAt.Sub(start)), int64(closeTimeout))
+		assert.Less(t, int64(closedAt.Sub(start)), int64(closeTimeout+500*time.Millisecond))
+	case <-time.After(closeTimeout + time.Second):
+		t.Fatal("connection was not closed within the close timeout")
+	}
+	// The underlying TCP connection was closed as well
+	_ = conn.UnderlyingConn().SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadAll(conn.UnderlyingConn())
+	assert.NoError(t, err)
+	// A responsive client acknowledges the close frame right away
+	wsClient := newWebsocketClient(t, nil)
+	err = wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	_, ok = <-connectedC
+	require.True(t, ok)
+	start = time.Now()
+	err = wsServer.StopConnection(path.Base(testPath), websocket.CloseError{Code: websocket.CloseNormalClosure})
+	require.NoError(t, err)
+	closedAt := <-disconnectedC
+	assert.Less(t, int64(closedAt.Sub(start)), int64(closeTimeout))
+}
+
+func TestWebsocketClientCloseHandshakeTimeout(t *testing.T) {
+	closeTimeout := 300 * time.Millisecond
+	upgrader := websocket.Upgrader{Subprotocols: []string{defaultSubProtocol}}
+	closedC := make(chan time.Time, 1)
+	// Unresponsive server, which consumes raw bytes but never acknowledges the close frame
+	httpServer := &http.Server{Addr: fmt.Sprintf(":%v", serverPort), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, conn.UnderlyingConn())
+		closedC <- time.Now()
+	})}
+	go func() {
+		_ = httpServer.ListenAndServe()
+	}()
+	defer httpServer.Close()
+	time.Sleep(100 * time.Millisecond)
+	wsClient := newWebsocketClient(t, nil)
+	wsClient.SetCloseTimeout(closeTimeout)
+	u := url.URL{Scheme: "ws", Host: fmt.Sprintf("localhost:%v", serverPort), Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	start := time.Now()
+	wsClient.Stop()
+	select {
+	case closedAt := <-closedC:
+		assert.GreaterOrEqual(t, int64(closedAt.Sub(start)), int64(closeTimeout))
+		assert.Less(t, int64(closedAt.Sub(start)), int64(closeTimeout+500*time.Millisecond))
+	case <-time.After(closeTimeout + time.Second):
+		t.Fatal("connection was not closed within the close timeout")
+	}
+}
+
 func TestWebsocketServerStopAllConnections(t *testing.T) {
 	triggerC := make(chan struct{}, 1)
 	numClients := 5
@@ -487,6 +1092,54 @@ func TestWebsocketClientConnectionBreak(t *testing.T) {
 	wsServer.Stop()
 }
 
+func TestWebsocketReconnectionHandlers(t *testing.T) {
+	wsServer := newWebsocketServer(t, nil)
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	events := make(chan string, 10)
+	wsClient := newWebsocketClient(t, nil)
+	config := NewClientTimeoutConfig()
+	config.RetryBackOffWaitMinimum = 200 * time.Millisecond
+	config.RetryBackOffRandomRange = 0
+	config.RetryBackOffRepeatTimes = 5
+	wsClient.SetTimeoutConfig(config)
+	wsClient.SetDisconnectedHandler(func(err error) {
+		events <- "disconnected"
+	})
+	wsClient.SetReconnectAttemptHandler(func(attempt int, nextDelay time.Duration) {
+		events <- fmt.Sprintf("attempt %v in %v", attempt, nextDelay)
+	})
+	wsClient.SetReconnectedHandler(func() {
+		events <- "reconnected"
+	})
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	err := wsClient.Start(u.String())
+	require.NoError(t, err)
+	// Simulate server restart
+	wsServer.Stop()
+	nextEvent := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for reconnection event")
+			return ""
+		}
+	}
+	assert.Equal(t, "disconnected", nextEvent())
+	assert.Equal(t, "attempt 1 in 200ms", nextEvent())
+	// First attempt fails, since the server is still down
+	assert.Equal(t, "attempt 2 in 400ms", nextEvent())
+	wsServer = newWebsocketServer(t, nil)
+	go wsServer.Start(serverPort, serverPath)
+	assert.Equal(t, "reconnected", nextEvent())
+	assert.True(t, wsClient.IsConnected())
+	// Cleanup
+	wsClient.Stop()
+	wsServer.Stop()
+}
+
 func TestWebsocketServerConnectionBreak(t *testing.T) {
 	disconnected := make(chan bool)
 	wsServer := newWebsocketServer(t, nil)
@@ -628,6 +1281,75 @@ func TestInvalidBasicAuth(t *testing.T) {
 	wsServer.Stop()
 }
 
+type authorizedIdentityKey struct{}
+
+func TestConnectionAuthorizer(t *testing.T) {
+	token := "secretToken"
+	authorizedC := make(chan *http.Request, 1)
+	newClientC := make(chan Channel, 1)
+	disconnectedC := make(chan context.Context, 1)
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.SetConnectionAuthorizer(func(r *http.Request) (bool, context.Context, error) {
+		authorizedC <- r
+		switch r.Header.Get("X-Token") {
+		case token:
+			return true, context.WithValue(context.Background(), authorizedIdentityKey{}, "operator1"), nil
+		case "":
+			return false, nil, fmt.Errorf("token lookup failed")
+		default:
+			return false, nil, nil
+		}
+	})
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		newClientC <- ws
+	})
+	wsServer.SetDisconnectedClientHandler(func(ws Channel) {
+		disconnectedC <- ws.Context()
+	})
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(100 * time.Millisecond)
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "ws", Host: host, Path: testPath}
+	// Denied connection
+	wsClient := newWebsocketClient(t, nil)
+	wsClient.SetHeaderValue("X-Token", "invalidToken")
+	err := wsClient.Start(u.String())
+	require.Error(t, err)
+	httpErr, ok := err.(HttpConnectionError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, httpErr.HttpCode)
+	r := <-authorizedC
+	assert.Equal(t, testPath, r.URL.Path)
+	assert.NotEmpty(t, r.RemoteAddr)
+	// Failing authorizer
+	wsClient = newWebsocketClient(t, nil)
+	err = wsClient.Start(u.String())
+	require.Error(t, err)
+	httpErr, ok = err.(HttpConnectionError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, httpErr.HttpCode)
+	<-authorizedC
+	// Accepted connection, with the authorizer context attached to the channel
+	wsClient = newWebsocketClient(t, nil)
+	wsClient.SetHeaderValue("X-Token", token)
+	err = wsClient.Start(u.String())
+	require.NoError(t, err)
+	<-authorizedC
+	channel := <-newClientC
+	assert.Equal(t, "operator1", channel.Context().Value(authorizedIdentityKey{}))
+	assert.NoError(t, channel.Context().Err())
+	// Connection context is cancelled once the connection was closed
+	wsClient.Stop()
+	ctx := <-disconnectedC
+	assert.Equal(t, "operator1", ctx.Value(authorizedIdentityKey{}))
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection context was not cancelled")
+	}
+}
+
 func TestInvalidOriginHeader(t *testing.T) {
 	wsServer := newWebsocketServer(t, func(data []byte) ([]byte, error) {
 		assert.Fail(t, "no message should be received from client!")
@@ -806,6 +1528,80 @@ func TestValidClientTLSCertificate(t *testing.T) {
 	wsServer.Stop()
 }
 
+func TestClientTLSCertificateInConnectionHandler(t *testing.T) {
+	// Create self-signed TLS certificate, bound to the client identity
+	clientId := path.Base(testPath)
+	clientCertFilename := "/tmp/client.pem"
+	clientKeyFilename := "/tmp/client_key.pem"
+	err := createTLSCertificate(clientCertFilename, clientKeyFilename, clientId, nil, nil)
+	defer os.Remove(clientCertFilename)
+	defer os.Remove(clientKeyFilename)
+	require.Nil(t, err)
+	serverCertFilename := "/tmp/cert.pem"
+	serverKeyFilename := "/tmp/key.pem"
+	err = createTLSCertificate(serverCertFilename, serverKeyFilename, "localhost", nil, nil)
+	require.Nil(t, err)
+	defer os.Remove(serverCertFilename)
+	defer os.Remove(serverKeyFilename)
+
+	// Create TLS server with self-signed certificate
+	certPool := x509.NewCertPool()
+	data, err := os.ReadFile(clientCertFilename)
+	require.Nil(t, err)
+	ok := certPool.AppendCertsFromPEM(data)
+	require.True(t, ok)
+	wsServer := NewTLSServer(serverCertFilename, serverKeyFilename, &tls.Config{
+		ClientCAs:  certPool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	})
+	// The verified client certificate is readable in the handler and passed on for inspection
+	type connectionInfo struct {
+		id       string
+		tlsState *tls.ConnectionState
+	}
+	connected := make(chan connectionInfo, 1)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connected <- connectionInfo{id: ws.ID(), tlsState: ws.TLSConnectionState()}
+	})
+	// Run server
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(1 * time.Second)
+
+	// Create TLS client
+	certPool = x509.NewCertPool()
+	data, err = os.ReadFile(serverCertFilename)
+	require.Nil(t, err)
+	ok = certPool.AppendCertsFromPEM(data)
+	require.True(t, ok)
+	loadedCert, err := tls.LoadX509KeyPair(clientCertFilename, clientKeyFilename)
+	require.Nil(t, err)
+	wsClient := NewTLSClient(&tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{loadedCert},
+	})
+	wsClient.SetRequestedSubProtocol(defaultSubProtocol)
+	// Test connection
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "wss", Host: host, Path: testPath}
+	err = wsClient.Start(u.String())
+	require.Nil(t, err)
+	defer wsClient.Stop()
+	var info connectionInfo
+	select {
+	case info = <-connected:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "connection handler wasn't invoked")
+	}
+	require.NotNil(t, info.tlsState)
+	require.Len(t, info.tlsState.PeerCertificates, 1)
+	require.Len(t, info.tlsState.VerifiedChains, 1)
+	peerCertificate := info.tlsState.PeerCertificates[0]
+	assert.Equal(t, info.id, peerCertificate.Subject.CommonName)
+	assert.Equal(t, []string{"ocpp-go"}, peerCertificate.Subject.Organization)
+	assert.Equal(t, []string{clientId}, peerCertificate.DNSNames)
+}
+
 func TestInvalidClientTLSCertificate(t *testing.T) {
 	// Create self-signed TLS certificate
 	clientCertFilename := "/tmp/client.pem"
@@ -865,6 +1661,132 @@ func TestInvalidClientTLSCertificate(t *testing.T) {
 	wsServer.Stop()
 }
 
+func TestTLSPolicyMinVersion(t *testing.T) {
+	serverCertFilename := "/tmp/cert.pem"
+	serverKeyFilename := "/tmp/key.pem"
+	err := createTLSCertificate(serverCertFilename, serverKeyFilename, "localhost", nil, nil)
+	require.Nil(t, err)
+	defer os.Remove(serverCertFilename)
+	defer os.Remove(serverKeyFilename)
+	certPool := x509.NewCertPool()
+	data, err := os.ReadFile(serverCertFilename)
+	require.Nil(t, err)
+	ok := certPool.AppendCertsFromPEM(data)
+	require.True(t, ok)
+
+	// Policy is validated
+	assert.Error(t, NewServer().SetTLSPolicy(tls.VersionTLS12, nil))
+	wsServer := NewTLSServer(serverCertFilename, serverKeyFilename, &tls.Config{})
+	assert.Error(t, wsServer.SetTLSPolicy(0x0200, nil))
+	assert.Error(t, wsServer.SetTLSPolicy(tls.VersionTLS12, []uint16{0xffff}))
+	err = wsServer.SetTLSPolicy(tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256})
+	require.NoError(t, err)
+	assert.Empty(t, CheckTLSConfig(wsServer.httpServer.TLSConfig))
+	connectedC := make(chan string, 1)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- tlsVersionName(ws.TLSConnectionState().Version)
+	})
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	defer wsServer.Stop()
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	u := url.URL{Scheme: "wss", Host: host, Path: testPath}
+
+	// TLS 1.1 client is rejected
+	wsClient := NewTLSClient(&tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11})
+	wsClient.SetRequestedSubProtocol(defaultSubProtocol)
+	err = wsClient.Start(u.String())
+	require.Error(t, err)
+	assert.Len(t, connectedC, 0)
+	// TLS 1.2 client is accepted
+	wsClient = NewTLSClient(&tls.Config{RootCAs: certPool, MaxVersion: tls.VersionTLS12})
+	wsClient.SetRequestedSubProtocol(defaultSubProtocol)
+	err = wsClient.Start(u.String())
+	require.NoError(t, err)
+	assert.Equal(t, "TLS 1.2", <-connectedC)
+	wsClient.Stop()
+}
+
+func TestClientTLSPolicy(t *testing.T) {
+	client := NewTLSClient(&tls.Config{MinVersion: tls.VersionTLS10})
+	assert.Error(t, client.SetTLSPolicy(0x0200, nil))
+	require.NoError(t, client.SetTLSPolicy(tls.VersionTLS13, nil))
+	// Policy takes precedence over dial options
+	client.AddOption(func(dialer *websocket.Dialer) {
+		dialer.TLSClientConfig.MinVersion = tls.VersionTLS10
+	})
+	dialer := websocket.Dialer{}
+	for _, option := range client.dialOptions {
+		option(&dialer)
+	}
+	config := applyTLSPolicy(dialer.TLSClientConfig, client.tlsMinVersion, client.tlsCipherSuites)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS10), dialer.TLSClientConfig.MinVersion)
+	// Weak settings are reported
+	warnings := CheckTLSConfig(&tls.Config{MinVersion: tls.VersionTLS11, CipherSuites: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA}, InsecureSkipVerify: true})
+	assert.Len(t, warnings, 3)
+	assert.Len(t, CheckTLSConfig(&tls.Config{}), 1)
+	// Weak settings are logged as warnings, if supported by the logger
+	logger := &warnRecordingLogger{}
+	SetLogger(logger)
+	defer SetLogger(&logging.VoidLogger{})
+	logTLSWarnings(&tls.Config{MinVersion: tls.VersionTLS11})
+	assert.Equal(t, []string{"weak TLS configuration: minimum TLS version TLS 1.1 is lower than TLS 1.2"}, logger.warnings)
+}
+
+type warnRecordingLogger struct {
+	logging.VoidLogger
+	warnings []string
+}
+
+func (l *warnRecordingLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func TestSubProtocolEcho(t *testing.T) {
+	ocpp16 := "ocpp1.6"
+	ocpp201 := "ocpp2.0.1"
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.AddSupportedSubprotocol(ocpp16)
+	wsServer.AddSupportedSubprotocol(ocpp201)
+	// Start server
+	go wsServer.Start(serverPort, serverPath)
+	time.Sleep(200 * time.Millisecond)
+	defer wsServer.Stop()
+
+	host := fmt.Sprintf("localhost:%v", serverPort)
+	testTable := []struct {
+		requested []string
+		expected  string
+	}{
+		{[]string{ocpp16}, ocpp16},
+		{[]string{ocpp201}, ocpp201},
+		{[]string{"ocpp2.0", ocpp201}, ocpp201},
+		{[]string{ocpp201, ocpp16}, ocpp201},
+	}
+	for i, tc := range testTable {
+		dialer := websocket.Dialer{Subprotocols: tc.requested}
+		u := url.URL{Scheme: "ws", Host: host, Path: fmt.Sprintf("/ws/echo%d", i)}
+		conn, resp, err := dialer.Dial(u.String(), nil)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Equal(t, []string{tc.expected}, resp.Header.Values("Sec-WebSocket-Protocol"))
+		assert.Equal(t, tc.expected, conn.Subprotocol())
+		_ = conn.Close()
+	}
+	// No common subprotocol: no header is echoed and the connection is closed
+	dialer := websocket.Dialer{Subprotocols: []string{"unsupportedSubProto"}}
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws/echoUnsupported"}
+	conn, resp, err := dialer.Dial(u.String(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Header.Get("Sec-WebSocket-Protocol"))
+	_, _, err = conn.ReadMessage()
+	require.IsType(t, &websocket.CloseError{}, err)
+	assert.Equal(t, websocket.CloseProtocolError, err.(*websocket.CloseError).Code)
+	_ = conn.Close()
+}
+
 func TestUnsupportedSubProtocol(t *testing.T) {
 	wsServer := newWebsocketServer(t, nil)
 	wsServer.SetNewClientHandler(func(ws Channel) {
@@ -990,6 +1912,78 @@ func TestSetClientTimeoutConfig(t *testing.T) {
 	wsServer.Stop()
 }
 
+func TestServerHandshakeTimeout(t *testing.T) {
+	handshakeTimeout := 300 * time.Millisecond
+	connectedC := make(chan struct{}, 1)
+	wsServer := newWebsocketServer(t, nil)
+	wsServer.SetHandshakeTimeout(handshakeTimeout)
+	wsServer.SetNewClientHandler(func(ws Channel) {
+		connectedC <- struct{}{}
+	})
+	go wsServer.Start(serverPort, serverPath)
+	defer wsServer.Stop()
+	time.Sleep(100 * time.Millisecond)
+	// Slow peer, which never completes the upgrade request
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%v", serverPort))
+	require.NoError(t, err)
+	defer conn.Close()
+	start := time.Now()
+	_, err = conn.Write([]byte(fmt.Sprintf("GET %v HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\n", testPath)))
+	require.NoError(t, err)
+	// The server closes the connection once the handshake timeout expires
+	_ = conn.SetReadDeadline(time.Now().Add(handshakeTimeout + time.Second))
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(handshakeTimeout))
+	assert.Less(t, int64(elapsed), int64(handshakeTimeout+500*time.Millisecond))
+	// Regular clients complete the handshake in time
+	wsClient := newWebsocketClient(t, nil)
+	u := url.URL{Scheme: "ws", Host: fmt.Sprintf("localhost:%v", serverPort), Path: testPath}
+	err = wsClient.Start(u.String())
+	require.NoError(t, err)
+	defer wsClient.Stop()
+	select {
+	case <-connectedC:
+	case <-time.After(time.Second):
+		t.Fatal("client didn't connect")
+	}
+}
+
+func TestClientHandshakeTimeout(t *testing.T) {
+	handshakeTimeout := 300 * time.Millisecond
+	// Slow peer, which accepts TCP connections but never responds to the upgrade request
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%v", serverPort))
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(io.Discard, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	wsClient := newWebsocketClient(t, nil)
+	wsClient.SetHandshakeTimeout(handshakeTimeout)
+	u := url.URL{Scheme: "ws", Host: fmt.Sprintf("localhost:%v", serverPort), Path: testPath}
+	start := time.Now()
+	err = wsClient.Start(u.String())
+	require.Error(t, err)
+	elapsed := time.Since(start)
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	assert.True(t, netErr.Timeout())
+	// The handshake timeout fires well before the connect timeout of the timeout config
+	assert.GreaterOrEqual(t, int64(elapsed), int64(handshakeTimeout))
+	assert.Less(t, int64(elapsed), int64(handshakeTimeout+500*time.Millisecond))
+	assert.False(t, wsClient.IsConnected())
+}
+
 func TestServerErrors(t *testing.T) {
 	triggerC := make(chan bool, 1)
 	finishC := make(chan bool, 1)